@@ -0,0 +1,71 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/google/subcommands"
+)
+
+// CutoverCmd struct with flags.
+type CutoverCmd struct {
+	stateFile string
+}
+
+// Name returns the name of operation.
+func (cmd *CutoverCmd) Name() string {
+	return "cutover"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *CutoverCmd) Synopsis() string {
+	return "stop MySQL binlog or SQL Server CDC streaming once all changes made so far are applied"
+}
+
+// Usage returns usage info of the command.
+func (cmd *CutoverCmd) Usage() string {
+	return fmt.Sprintf(`%v cutover -state-file=[cdc_state_file]
+
+Request the cutover of a streaming migration from MySQL started with
+cdc=binlog, or from SQL Server started with cdc=changetables, in the
+source-profile. Stop writes to the source database first: the running
+migration reads the current binlog position or LSN, applies all changes up
+to it to Spanner and then exits. The flags are:
+`, path.Base(os.Args[0]))
+}
+
+// SetFlags sets the flags.
+func (cmd *CutoverCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&cmd.stateFile, "state-file", "", "State file of the streaming migration, set with cdcStateFile in the source-profile (defaults to <dbName>_binlog_position.json for MySQL and <dbName>_cdc_lsn.json for SQL Server)")
+}
+
+func (cmd *CutoverCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if cmd.stateFile == "" {
+		fmt.Fprintln(os.Stderr, "Please specify the cdc state file with -state-file")
+		return subcommands.ExitUsageError
+	}
+	if err := common.RequestCutover(cmd.stateFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Can't request cutover: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	fmt.Println("Cutover requested. The streaming migration will exit once it has applied all changes made to the source database so far.")
+	return subcommands.ExitSuccess
+}
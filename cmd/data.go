@@ -49,6 +49,8 @@ type DataCmd struct {
 	dryRun          bool
 	logLevel        string
 	SkipForeignKeys bool
+	progressFormat  string
+	metricsPushURL  string
 }
 
 // Name returns the name of operation.
@@ -83,6 +85,8 @@ func (cmd *DataCmd) SetFlags(f *flag.FlagSet) {
 	f.Int64Var(&cmd.WriteLimit, "write-limit", DefaultWritersLimit, "Write limit for writes to spanner")
 	f.BoolVar(&cmd.dryRun, "dry-run", false, "Flag for generating DDL and schema conversion report without creating a spanner database")
 	f.StringVar(&cmd.logLevel, "log-level", "INFO", "Configure the logging level for the command (INFO, DEBUG), defaults to INFO")
+	f.StringVar(&cmd.progressFormat, "progress-format", "text", "Format of the progress of the migration: text, or json to also write progress events as JSON lines to stderr")
+	f.StringVar(&cmd.metricsPushURL, "metrics-push-url", "", "URL of a Prometheus Pushgateway to push migration metrics to during the migration")
 	f.BoolVar(&cmd.SkipForeignKeys, "skip-foreign-keys", false, "Skip creating foreign keys after data migration is complete (ddl statements for foreign keys can still be found in the downloaded schema.ddl.txt file and the same can be applied separately)")
 }
 
@@ -101,6 +105,9 @@ func (cmd *DataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface
 		return subcommands.ExitFailure
 	}
 	defer logger.Log.Sync()
+	if err = validateProgressFormat(cmd.progressFormat); err != nil {
+		return subcommands.ExitUsageError
+	}
 
 	conv := internal.MakeConv()
 	sourceProfile, targetProfile, ioHelper, dbName, err := PrepareMigrationPrerequisites(cmd.sourceProfile, cmd.targetProfile, cmd.source)
@@ -129,12 +136,23 @@ func (cmd *DataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface
 		}
 	}
 
+	// If filePrefix not explicitly set, use dbName as prefix.
+	if cmd.filePrefix == "" {
+		cmd.filePrefix = targetProfile.Conn.Sp.Dbname
+	}
+	closeDeadLetters := openDeadLetters(cmd.filePrefix+deadLetterFile, sourceProfile.Driver, conv, ioHelper.Out)
+	defer closeDeadLetters()
+
 	var (
 		dbURI string
 	)
 	if !cmd.dryRun {
 		now := time.Now()
+		stopProgress := watchProgress(cmd.progressFormat, conv, os.Stderr)
+		stopMetrics := pushMetrics(cmd.metricsPushURL, conv)
 		bw, err = MigrateDatabase(ctx, targetProfile, sourceProfile, dbName, &ioHelper, cmd, conv, nil)
+		stopMetrics()
+		stopProgress()
 		if err != nil {
 			err = fmt.Errorf("can't finish database migration for db %s: %v", dbName, err)
 			return subcommands.ExitFailure
@@ -153,10 +171,6 @@ func (cmd *DataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface
 	dataCoversionDuration := dataCoversionEndTime.Sub(dataCoversionStartTime)
 	conv.Audit.DataConversionDuration = dataCoversionDuration
 
-	// If filePrefix not explicitly set, use dbName as prefix.
-	if cmd.filePrefix == "" {
-		cmd.filePrefix = targetProfile.Conn.Sp.Dbname
-	}
 	conversion.Report(sourceProfile.Driver, bw.DroppedRowsByTable(), ioHelper.BytesRead, banner, conv, cmd.filePrefix, dbName, ioHelper.Out)
 	conversion.WriteBadData(bw, conv, banner, cmd.filePrefix+badDataFile, ioHelper.Out)
 	// Cleanup hb tmp data directory.
@@ -0,0 +1,113 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/conversion"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/google/subcommands"
+)
+
+// PostDataCmd struct with flags.
+type PostDataCmd struct {
+	sessionJSON     string
+	targetProfile   string
+	SkipForeignKeys bool
+	logLevel        string
+}
+
+// Name returns the name of operation.
+func (cmd *PostDataCmd) Name() string {
+	return "post-data"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *PostDataCmd) Synopsis() string {
+	return "create the secondary indexes and foreign keys of a database after its data is loaded"
+}
+
+// Usage returns usage info of the command.
+func (cmd *PostDataCmd) Usage() string {
+	return fmt.Sprintf(`%v post-data -session=[session_file] -target-profile="instance=my-instance,dbName=my-db"
+
+Create the secondary indexes and foreign keys of the session file in the
+target database, e.g. after a schema-and-data migration run with
+-defer-indexes. Indexes are created before foreign keys, in batches of DDL
+statements. Indexes and foreign keys that already exist in the database are
+skipped, so the command can be run again to resume after a failure.
+The flags are:
+`, path.Base(os.Args[0]))
+}
+
+// SetFlags sets the flags.
+func (cmd *PostDataCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&cmd.sessionJSON, "session", "", "Specifies the file we restore session state from")
+	f.StringVar(&cmd.targetProfile, "target-profile", "", "Flag for specifying connection profile for target database e.g., \"instance=my-instance,dbName=my-db\"")
+	f.BoolVar(&cmd.SkipForeignKeys, "skip-foreign-keys", false, "Skip creating foreign keys, only create the secondary indexes")
+	f.StringVar(&cmd.logLevel, "log-level", "INFO", "Configure the logging level for the command (INFO, DEBUG), defaults to INFO")
+}
+
+func (cmd *PostDataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if err := logger.InitializeLogger(cmd.logLevel); err != nil {
+		fmt.Println("Error initialising logger, did you specify a valid log-level? [DEBUG, INFO, WARN, ERROR, FATAL]", err)
+		return subcommands.ExitFailure
+	}
+	defer logger.Log.Sync()
+	targetProfile, err := profiles.NewTargetProfile(cmd.targetProfile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid target-profile: %v\n", err)
+		return subcommands.ExitUsageError
+	}
+	if targetProfile.Conn.Sp.Dbname == "" {
+		fmt.Fprintln(os.Stderr, "Please specify the database to create the indexes and foreign keys in with dbName in the target-profile")
+		return subcommands.ExitUsageError
+	}
+	conv := internal.MakeConv()
+	if err := conversion.ReadSessionFile(conv, cmd.sessionJSON); err != nil {
+		fmt.Fprintf(os.Stderr, "Can't read session file %s: %v\n", cmd.sessionJSON, err)
+		return subcommands.ExitUsageError
+	}
+
+	project, instance, dbName, err := targetProfile.GetResourceIds(ctx, time.Now(), "", os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't get the target database: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	dbURI := fmt.Sprintf("projects/%s/instances/%s/databases/%s", project, instance, dbName)
+	adminClient, err := utils.NewDatabaseAdminClient(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't create admin client: %v\n", utils.AnalyzeError(err, dbURI))
+		return subcommands.ExitFailure
+	}
+	defer adminClient.Close()
+
+	now := time.Now()
+	if err := conversion.CreatePostDataDDL(ctx, adminClient, dbURI, conv, !cmd.SkipForeignKeys, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Can't create indexes and foreign keys on db %s: %v\n", dbURI, err)
+		return subcommands.ExitFailure
+	}
+	fmt.Printf("Created indexes and foreign keys of %s in %s.\n", dbURI, time.Since(now).Round(time.Second))
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,135 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/conversion"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/cloudspannerecosystem/harbourbridge/proto/migration"
+	"github.com/google/subcommands"
+	"github.com/google/uuid"
+)
+
+// ReplayCmd struct with flags.
+type ReplayCmd struct {
+	sessionJSON   string
+	targetProfile string
+	filePrefix    string
+	WriteLimit    int64
+	logLevel      string
+}
+
+// Name returns the name of operation.
+func (cmd *ReplayCmd) Name() string {
+	return "replay"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *ReplayCmd) Synopsis() string {
+	return "replay the rows rejected by a migration from its dead-letter file"
+}
+
+// Usage returns usage info of the command.
+func (cmd *ReplayCmd) Usage() string {
+	return fmt.Sprintf(`%v replay -session=[session_file] -target-profile="instance=my-instance,dbName=my-db" [dead_letter_file]
+
+Convert and write the rows of the dead-letter file (<prefix>%s) of a
+data migration to the target database again, once the schema, the session
+or the rules are fixed. Rows are converted with the mappings of the session
+file. Rows that are rejected again are written to a new dead-letter file.
+The flags are:
+`, path.Base(os.Args[0]), deadLetterFile)
+}
+
+// SetFlags sets the flags.
+func (cmd *ReplayCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&cmd.sessionJSON, "session", "", "Specifies the file we restore session state from")
+	f.StringVar(&cmd.targetProfile, "target-profile", "", "Flag for specifying connection profile for target database e.g., \"instance=my-instance,dbName=my-db\"")
+	f.StringVar(&cmd.filePrefix, "prefix", "", "File prefix for the dead-letter file of the rows rejected again, defaults to <dbName>.replay")
+	f.Int64Var(&cmd.WriteLimit, "write-limit", DefaultWritersLimit, "Write limit for writes to spanner")
+	f.StringVar(&cmd.logLevel, "log-level", "INFO", "Configure the logging level for the command (INFO, DEBUG), defaults to INFO")
+}
+
+func (cmd *ReplayCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Please specify the dead-letter file to replay")
+		return subcommands.ExitUsageError
+	}
+	if err := logger.InitializeLogger(cmd.logLevel); err != nil {
+		fmt.Println("Error initialising logger, did you specify a valid log-level? [DEBUG, INFO, WARN, ERROR, FATAL]", err)
+		return subcommands.ExitFailure
+	}
+	defer logger.Log.Sync()
+	targetProfile, err := profiles.NewTargetProfile(cmd.targetProfile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid target-profile: %v\n", err)
+		return subcommands.ExitUsageError
+	}
+	if targetProfile.Conn.Sp.Dbname == "" {
+		fmt.Fprintln(os.Stderr, "Please specify the database to replay the rows to with dbName in the target-profile")
+		return subcommands.ExitUsageError
+	}
+	if cmd.filePrefix == "" {
+		cmd.filePrefix = targetProfile.Conn.Sp.Dbname + ".replay"
+	}
+	input, output := f.Arg(0), cmd.filePrefix+deadLetterFile
+	if filepath.Clean(input) == filepath.Clean(output) {
+		fmt.Fprintln(os.Stderr, "The dead-letter file of the replay would overwrite the file replayed, please specify another -prefix")
+		return subcommands.ExitUsageError
+	}
+
+	conv := internal.MakeConv()
+	if err := conversion.ReadSessionFile(conv, cmd.sessionJSON); err != nil {
+		fmt.Fprintf(os.Stderr, "Can't read session file %s: %v\n", cmd.sessionJSON, err)
+		return subcommands.ExitUsageError
+	}
+	conv.Audit.MigrationRequestId = "HB-" + uuid.New().String()
+	conv.Audit.MigrationType = migration.MigrationData_DATA_ONLY.Enum()
+	conv.Audit.SkipMetricsPopulation = os.Getenv("SKIP_METRICS_POPULATION") == "true"
+
+	project, instance, dbName, err := targetProfile.GetResourceIds(ctx, time.Now(), "", os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't get the target database: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	dbURI := fmt.Sprintf("projects/%s/instances/%s/databases/%s", project, instance, dbName)
+	client, err := utils.GetClient(ctx, dbURI)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't create client for db %s: %v\n", dbURI, err)
+		return subcommands.ExitFailure
+	}
+	defer client.Close()
+
+	closeDeadLetters := openDeadLetters(output, "", conv, os.Stdout)
+	defer closeDeadLetters()
+	bw, err := conversion.ReplayDeadLetters(ctx, input, targetProfile, client, conv, cmd.WriteLimit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't replay %s: %v\n", input, err)
+		return subcommands.ExitFailure
+	}
+	fmt.Printf("Replayed %s to %s: %d rows written, %d rows rejected again\n", input, dbURI, bw.WriteStats().Rows, conv.Audit.DeadLetters.Count())
+	return subcommands.ExitSuccess
+}
@@ -36,13 +36,15 @@ import (
 
 // SchemaCmd struct with flags.
 type SchemaCmd struct {
-	source        string
-	sourceProfile string
-	target        string
-	targetProfile string
-	filePrefix    string // TODO: move filePrefix to global flags
-	logLevel      string
-	dryRun        bool
+	source         string
+	sourceProfile  string
+	target         string
+	targetProfile  string
+	filePrefix     string // TODO: move filePrefix to global flags
+	logLevel       string
+	dryRun         bool
+	progressFormat string
+	metricsPushURL string
 }
 
 // Name returns the name of operation.
@@ -74,6 +76,8 @@ func (cmd *SchemaCmd) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&cmd.targetProfile, "target-profile", "", "Flag for specifying connection profile for target database e.g., \"dialect=postgresql\"")
 	f.StringVar(&cmd.filePrefix, "prefix", "", "File prefix for generated files")
 	f.StringVar(&cmd.logLevel, "log-level", "INFO", "Configure the logging level for the command (INFO, DEBUG), defaults to INFO")
+	f.StringVar(&cmd.progressFormat, "progress-format", "text", "Format of the progress of the migration: text, or json to also write progress events as JSON lines to stderr")
+	f.StringVar(&cmd.metricsPushURL, "metrics-push-url", "", "URL of a Prometheus Pushgateway to push migration metrics to during the migration")
 	f.BoolVar(&cmd.dryRun, "dry-run", false, "Flag for generating DDL and schema conversion report without creating a spanner database")
 }
 
@@ -92,6 +96,9 @@ func (cmd *SchemaCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interfa
 		return subcommands.ExitFailure
 	}
 	defer logger.Log.Sync()
+	if err = validateProgressFormat(cmd.progressFormat); err != nil {
+		return subcommands.ExitUsageError
+	}
 
 	sourceProfile, targetProfile, ioHelper, dbName, err := PrepareMigrationPrerequisites(cmd.sourceProfile, cmd.targetProfile, cmd.source)
 	if err != nil {
@@ -119,7 +126,11 @@ func (cmd *SchemaCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interfa
 	conv.Audit.MigrationType = migration.MigrationData_SCHEMA_ONLY.Enum()
 	conv.Audit.SkipMetricsPopulation = os.Getenv("SKIP_METRICS_POPULATION") == "true"
 	if !cmd.dryRun {
+		stopProgress := watchProgress(cmd.progressFormat, conv, os.Stderr)
+		stopMetrics := pushMetrics(cmd.metricsPushURL, conv)
 		_, err = MigrateDatabase(ctx, targetProfile, sourceProfile, dbName, &ioHelper, cmd, conv, nil)
+		stopMetrics()
+		stopProgress()
 		if err != nil {
 			err = fmt.Errorf("can't finish database migration for db %s: %v", dbName, err)
 			return subcommands.ExitFailure
@@ -42,10 +42,13 @@ type SchemaAndDataCmd struct {
 	target          string
 	targetProfile   string
 	SkipForeignKeys bool
+	DeferIndexes    bool
 	filePrefix      string // TODO: move filePrefix to global flags
 	WriteLimit      int64
 	dryRun          bool
 	logLevel        string
+	progressFormat  string
+	metricsPushURL  string
 }
 
 // Name returns the name of operation.
@@ -76,10 +79,13 @@ func (cmd *SchemaAndDataCmd) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&cmd.target, "target", "Spanner", "Specifies the target DB, defaults to Spanner (accepted values: `Spanner`)")
 	f.StringVar(&cmd.targetProfile, "target-profile", "", "Flag for specifying connection profile for target database e.g., \"dialect=postgresql\"")
 	f.BoolVar(&cmd.SkipForeignKeys, "skip-foreign-keys", false, "Skip creating foreign keys after data migration is complete (ddl statements for foreign keys can still be found in the downloaded schema.ddl.txt file and the same can be applied separately)")
+	f.BoolVar(&cmd.DeferIndexes, "defer-indexes", false, "Create only tables and primary keys before the data migration, and the secondary indexes and foreign keys once the data is loaded (they can also be created with the post-data command)")
 	f.StringVar(&cmd.filePrefix, "prefix", "", "File prefix for generated files")
 	f.Int64Var(&cmd.WriteLimit, "write-limit", DefaultWritersLimit, "Write limit for writes to spanner")
 	f.BoolVar(&cmd.dryRun, "dry-run", false, "Flag for generating DDL and schema conversion report without creating a spanner database")
 	f.StringVar(&cmd.logLevel, "log-level", "INFO", "Configure the logging level for the command (INFO, DEBUG), defaults to INFO")
+	f.StringVar(&cmd.progressFormat, "progress-format", "text", "Format of the progress of the migration: text, or json to also write progress events as JSON lines to stderr")
+	f.StringVar(&cmd.metricsPushURL, "metrics-push-url", "", "URL of a Prometheus Pushgateway to push migration metrics to during the migration")
 }
 
 func (cmd *SchemaAndDataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
@@ -97,6 +103,9 @@ func (cmd *SchemaAndDataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...
 		return subcommands.ExitFailure
 	}
 	defer logger.Log.Sync()
+	if err = validateProgressFormat(cmd.progressFormat); err != nil {
+		return subcommands.ExitUsageError
+	}
 
 	sourceProfile, targetProfile, ioHelper, dbName, err := PrepareMigrationPrerequisites(cmd.sourceProfile, cmd.targetProfile, cmd.source)
 	if err != nil {
@@ -130,10 +139,16 @@ func (cmd *SchemaAndDataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...
 	conversion.WriteSchemaFile(conv, schemaConversionStartTime, cmd.filePrefix+schemaFile, ioHelper.Out)
 	conversion.WriteSessionFile(conv, cmd.filePrefix+sessionFile, ioHelper.Out)
 	conv.Audit.SkipMetricsPopulation = os.Getenv("SKIP_METRICS_POPULATION") == "true"
+	closeDeadLetters := openDeadLetters(cmd.filePrefix+deadLetterFile, sourceProfile.Driver, conv, ioHelper.Out)
+	defer closeDeadLetters()
 
 	if !cmd.dryRun {
 		conversion.Report(sourceProfile.Driver, nil, ioHelper.BytesRead, "", conv, cmd.filePrefix, dbName, ioHelper.Out)
+		stopProgress := watchProgress(cmd.progressFormat, conv, os.Stderr)
+		stopMetrics := pushMetrics(cmd.metricsPushURL, conv)
 		bw, err = MigrateDatabase(ctx, targetProfile, sourceProfile, dbName, &ioHelper, cmd, conv, nil)
+		stopMetrics()
+		stopProgress()
 		if err != nil {
 			err = fmt.Errorf("can't finish database migration for db %s: %v", dbName, err)
 			return subcommands.ExitFailure
@@ -0,0 +1,124 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+
+	"cloud.google.com/go/spanner"
+	"github.com/cloudspannerecosystem/harbourbridge/conversion"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/helpers"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/session"
+	"github.com/google/subcommands"
+)
+
+// SessionDiffCmd struct with flags.
+type SessionDiffCmd struct {
+	project  string
+	instance string
+	format   string
+}
+
+// Name returns the name of operation.
+func (cmd *SessionDiffCmd) Name() string {
+	return "session-diff"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *SessionDiffCmd) Synopsis() string {
+	return "show how two versions of a schema conversion session differ"
+}
+
+// Usage returns usage info of the command.
+func (cmd *SessionDiffCmd) Usage() string {
+	return fmt.Sprintf(`%v session-diff [flags] <from> <to>
+
+Show the changes to tables, columns, indexes, foreign keys, type mappings
+and rules between two versions of a schema conversion session, and the
+Spanner DDL that applies them. Each version is either a session file, or
+the version id of a session saved in the metadata database of the web UI,
+given with -project and -instance. The flags are:
+`, path.Base(os.Args[0]))
+}
+
+// SetFlags sets the flags.
+func (cmd *SessionDiffCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&cmd.project, "project", "", "Project of the Spanner instance of the metadata database, to load sessions by version id")
+	f.StringVar(&cmd.instance, "instance", "", "Spanner instance of the metadata database, to load sessions by version id")
+	f.StringVar(&cmd.format, "format", "text", "Output format: text or json")
+}
+
+func (cmd *SessionDiffCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "Please specify the two session versions to compare")
+		return subcommands.ExitUsageError
+	}
+	if cmd.format != "text" && cmd.format != "json" {
+		fmt.Fprintf(os.Stderr, "Unknown format %q, expected text or json\n", cmd.format)
+		return subcommands.ExitUsageError
+	}
+	var store session.SessionStore
+	if cmd.project != "" && cmd.instance != "" {
+		client, err := spanner.NewClient(ctx, helpers.GetSpannerUri(cmd.project, cmd.instance))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't connect to the metadata database: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		defer client.Close()
+		store = session.NewRemoteSessionStore(client)
+	}
+	var convs [2]*internal.Conv
+	for i, version := range f.Args() {
+		conv, err := loadSessionVersion(ctx, store, version)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't load session %s: %v\n", version, err)
+			return subcommands.ExitFailure
+		}
+		convs[i] = conv
+	}
+	d := internal.DiffConv(convs[0], convs[1])
+	if cmd.format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(d)
+	} else {
+		internal.WriteConvDiff(os.Stdout, d)
+	}
+	return subcommands.ExitSuccess
+}
+
+// loadSessionVersion loads the Conv of the session file version, or of
+// the session with version id version from store if there is no such
+// file.
+func loadSessionVersion(ctx context.Context, store session.SessionStore, version string) (*internal.Conv, error) {
+	if _, err := os.Stat(version); err == nil || store == nil {
+		conv := internal.MakeConv()
+		if err := conversion.ReadSessionFile(conv, version); err != nil {
+			return nil, err
+		}
+		return conv, nil
+	}
+	convm, err := store.GetConvWithMetadata(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	return &convm.Conv, nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/jobs"
+	"github.com/google/subcommands"
+)
+
+// StatusCmd struct with flags.
+type StatusCmd struct {
+	server string
+	token  string
+	cancel bool
+	format string
+}
+
+// Name returns the name of operation.
+func (cmd *StatusCmd) Name() string {
+	return "status"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *StatusCmd) Synopsis() string {
+	return "show the status of the migration jobs of a web server"
+}
+
+// Usage returns usage info of the command.
+func (cmd *StatusCmd) Usage() string {
+	return fmt.Sprintf(`%v status [flags] [job-id]
+
+List the migration jobs of a HarbourBridge web server, or show the status,
+per-table progress and log of job job-id. The flags are:
+`, path.Base(os.Args[0]))
+}
+
+// SetFlags sets the flags.
+func (cmd *StatusCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&cmd.server, "server", "http://localhost:8080", "URL of the HarbourBridge web server")
+	f.StringVar(&cmd.token, "token", "", "Bearer token to authenticate to the web server with")
+	f.BoolVar(&cmd.cancel, "cancel", false, "Cancel the job")
+	f.StringVar(&cmd.format, "format", "text", "Output format: text or json")
+}
+
+func (cmd *StatusCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() > 1 || (cmd.cancel && f.NArg() == 0) {
+		fmt.Fprintln(os.Stderr, "Please specify at most one job id, and a job id to cancel")
+		return subcommands.ExitUsageError
+	}
+	if cmd.format != "text" && cmd.format != "json" {
+		fmt.Fprintf(os.Stderr, "Unknown format %q, expected text or json\n", cmd.format)
+		return subcommands.ExitUsageError
+	}
+	method, p := "GET", "/jobs"
+	if f.NArg() == 1 {
+		p += "/" + url.PathEscape(f.Arg(0))
+		if cmd.cancel {
+			method, p = "POST", p+"/cancel"
+		}
+	}
+	body, err := cmd.request(ctx, method, p)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't get job status: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	if cmd.format == "json" {
+		os.Stdout.Write(body)
+		return subcommands.ExitSuccess
+	}
+	if f.NArg() == 0 {
+		var l []jobs.Job
+		if err := json.Unmarshal(body, &l); err != nil {
+			fmt.Fprintf(os.Stderr, "Can't parse job status: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		writeJobList(os.Stdout, l)
+		return subcommands.ExitSuccess
+	}
+	var j jobs.Job
+	if err := json.Unmarshal(body, &j); err != nil {
+		fmt.Fprintf(os.Stderr, "Can't parse job status: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	writeJob(os.Stdout, j)
+	return subcommands.ExitSuccess
+}
+
+// request sends a request to path p of the web server, and returns the
+// body of the response.
+func (cmd *StatusCmd) request(ctx context.Context, method, p string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(cmd.server, "/")+p, nil)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.token != "" {
+		req.Header.Set("Authorization", "Bearer "+cmd.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+func writeJobList(w io.Writer, l []jobs.Job) {
+	if len(l) == 0 {
+		fmt.Fprintln(w, "No jobs.")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATE\tPROGRESS\tCREATED\tDESCRIPTION")
+	for _, j := range l {
+		fmt.Fprintf(tw, "%s\t%s\t%d%%\t%s\t%s\n", j.Id, j.State, j.Progress, j.CreatedAt.Format(time.RFC3339), j.Description)
+	}
+	tw.Flush()
+}
+
+func writeJob(w io.Writer, j jobs.Job) {
+	fmt.Fprintf(w, "Job %s: %s\n", j.Id, j.Description)
+	fmt.Fprintf(w, "State: %s (%d%%)\n", j.State, j.Progress)
+	if j.Phase != "" {
+		fmt.Fprintf(w, "Phase: %s\n", j.Phase)
+	}
+	if j.Error != "" {
+		fmt.Fprintf(w, "Error: %s\n", j.Error)
+	}
+	if len(j.Tables) > 0 {
+		fmt.Fprintln(w, "Tables:")
+		var tables []string
+		for t := range j.Tables {
+			tables = append(tables, t)
+		}
+		sort.Strings(tables)
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, t := range tables {
+			rows := j.Tables[t]
+			fmt.Fprintf(tw, "  %s\t%d/%d rows\t%d bad\t%d dropped\n", t, rows.Written, rows.Total, rows.BadRows, rows.DroppedRows)
+		}
+		tw.Flush()
+	}
+	fmt.Fprintln(w, "Log:")
+	for _, e := range j.Log {
+		fmt.Fprintf(w, "  %s %s\n", e.Time.Format(time.RFC3339), e.Message)
+	}
+}
@@ -16,11 +16,16 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"sync"
 	"time"
 
 	sp "cloud.google.com/go/spanner"
 	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"github.com/cloudspannerecosystem/harbourbridge/common/telemetry"
 	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
 	"github.com/cloudspannerecosystem/harbourbridge/conversion"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
@@ -29,14 +34,19 @@ import (
 )
 
 var (
-	badDataFile = ".dropped.txt"
-	schemaFile  = ".schema.txt"
-	sessionFile = ".session.json"
+	badDataFile    = ".dropped.txt"
+	deadLetterFile = ".dead_letters.jsonl"
+	schemaFile     = ".schema.txt"
+	sessionFile    = ".session.json"
 )
 
 const (
 	DefaultWritersLimit  = 40
 	completionPercentage = 100
+	// How often progress events are emitted with -progress-format=json.
+	progressEventInterval = time.Second
+	// How often metrics are pushed with -metrics-push-url.
+	metricsPushInterval = 15 * time.Second
 )
 
 // CreateDatabaseClient creates new database client and admin client.
@@ -99,6 +109,72 @@ func PrepareMigrationPrerequisites(sourceProfileString, targetProfileString, sou
 	return sourceProfile, targetProfile, ioHelper, dbName, nil
 }
 
+// openDeadLetters writes the rows rejected by the migration of conv from a
+// source database of driver to the dead-letter file name, until the
+// returned function is called.
+func openDeadLetters(name, driver string, conv *internal.Conv, out io.Writer) func() {
+	os.Remove(name) // Cleanup dead-letter file from previous run.
+	conv.Audit.DeadLetters = internal.NewDeadLetterQueue(name, driver)
+	return func() {
+		q := conv.Audit.DeadLetters
+		if err := q.Close(); err != nil {
+			fmt.Fprintf(out, "Can't write out dead-letter file %s: %v\n", name, err)
+		} else if n := q.Count(); n > 0 {
+			fmt.Fprintf(out, "See file '%s' for the %d rejected rows, which can be replayed with the replay command\n", name, n)
+		}
+	}
+}
+
+// validateProgressFormat checks the value of the -progress-format flag.
+func validateProgressFormat(format string) error {
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unknown progress format %q, expected text or json", format)
+	}
+	return nil
+}
+
+// watchProgress writes the progress events of the migration of conv to w
+// as JSON lines if format is json, until the returned function is called.
+func watchProgress(format string, conv *internal.Conv, w io.Writer) func() {
+	if format != "json" {
+		return func() {}
+	}
+	conv.Audit.MigrationProgress = internal.NewMigrationProgress()
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		enc := json.NewEncoder(w)
+		internal.WatchProgress(ctx, conv.Audit.MigrationProgress, progressEventInterval, func(e internal.ProgressEvent) {
+			enc.Encode(e)
+		})
+	}()
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// pushMetrics records the metrics of the migration of conv and pushes them
+// to the Prometheus Pushgateway at url if it is set, until the returned
+// function is called.
+func pushMetrics(url string, conv *internal.Conv) func() {
+	if url == "" {
+		return func() {}
+	}
+	telemetry.Enable()
+	grouping := map[string]string{"migration_request_id": conv.Audit.MigrationRequestId}
+	stop := telemetry.StartPush(url, "harbourbridge", grouping, metricsPushInterval, func(err error) {
+		fmt.Fprintf(os.Stderr, "Can't push metrics: %v\n", err)
+	})
+	return func() {
+		if err := stop(); err != nil {
+			fmt.Fprintf(os.Stderr, "Can't push metrics: %v\n", err)
+		}
+	}
+}
+
 // MigrateData creates database and populates data in it.
 func MigrateDatabase(ctx context.Context, targetProfile profiles.TargetProfile, sourceProfile profiles.SourceProfile, dbName string, ioHelper *utils.IOStreams, cmd interface{}, conv *internal.Conv, migrationError *error) (*writer.BatchWriter, error) {
 	var (
@@ -125,16 +201,33 @@ func MigrateDatabase(ctx context.Context, targetProfile profiles.TargetProfile,
 	case *SchemaAndDataCmd:
 		bw, err = migrateSchemaAndData(ctx, targetProfile, sourceProfile, ioHelper, conv, dbURI, adminClient, client, v)
 	}
+	if err == nil && ctx.Err() != nil {
+		// The migration was cancelled: the data written so far is incomplete.
+		err = ctx.Err()
+	}
 	if err != nil {
 		err = fmt.Errorf("can't migrate database: %v", err)
 		return nil, err
 	}
+	conv.Audit.MigrationProgress.SetPhase(internal.PhaseDone)
+	if bw != nil {
+		s := bw.WriteStats()
+		conv.Audit.WriteStats = &internal.WriteStats{
+			Commits:     s.Commits,
+			Rows:        s.Rows,
+			Mutations:   s.Mutations,
+			WriteTime:   s.WriteTime,
+			Elapsed:     s.Elapsed,
+			Concurrency: s.Concurrency,
+		}
+	}
 	return bw, nil
 }
 
 func migrateSchema(ctx context.Context, targetProfile profiles.TargetProfile, sourceProfile profiles.SourceProfile,
 	ioHelper *utils.IOStreams, conv *internal.Conv, dbURI string, adminClient *database.DatabaseAdminClient) error {
-	err := conversion.CreateOrUpdateDatabase(ctx, adminClient, dbURI, sourceProfile.Driver, conv, ioHelper.Out)
+	conv.Audit.MigrationProgress.SetPhase(internal.PhaseSchema)
+	err := conversion.CreateOrUpdateDatabase(ctx, adminClient, dbURI, sourceProfile.Driver, conv, false, ioHelper.Out)
 	if err != nil {
 		err = fmt.Errorf("can't create/update database: %v", err)
 		return err
@@ -156,6 +249,7 @@ func migrateData(ctx context.Context, targetProfile profiles.TargetProfile, sour
 			return nil, err
 		}
 	}
+	conv.Audit.MigrationProgress.SetPhase(internal.PhaseData)
 	bw, err = conversion.DataConv(ctx, sourceProfile, targetProfile, ioHelper, client, conv, true, cmd.WriteLimit)
 	if err != nil {
 		err = fmt.Errorf("can't finish data conversion for db %s: %v", dbURI, err)
@@ -163,6 +257,7 @@ func migrateData(ctx context.Context, targetProfile profiles.TargetProfile, sour
 	}
 	conv.Audit.Progress.UpdateProgress("Data migration complete.", completionPercentage, internal.DataMigrationComplete)
 	if !cmd.SkipForeignKeys {
+		conv.Audit.MigrationProgress.SetPhase(internal.PhaseForeignKeys)
 		if err = conversion.UpdateDDLForeignKeys(ctx, adminClient, dbURI, conv, ioHelper.Out); err != nil {
 			err = fmt.Errorf("can't perform update schema on db %s with foreign keys: %v", dbURI, err)
 			return bw, err
@@ -173,12 +268,14 @@ func migrateData(ctx context.Context, targetProfile profiles.TargetProfile, sour
 
 func migrateSchemaAndData(ctx context.Context, targetProfile profiles.TargetProfile, sourceProfile profiles.SourceProfile,
 	ioHelper *utils.IOStreams, conv *internal.Conv, dbURI string, adminClient *database.DatabaseAdminClient, client *sp.Client, cmd *SchemaAndDataCmd) (*writer.BatchWriter, error) {
-	err := conversion.CreateOrUpdateDatabase(ctx, adminClient, dbURI, sourceProfile.Driver, conv, ioHelper.Out)
+	conv.Audit.MigrationProgress.SetPhase(internal.PhaseSchema)
+	err := conversion.CreateOrUpdateDatabase(ctx, adminClient, dbURI, sourceProfile.Driver, conv, cmd.DeferIndexes, ioHelper.Out)
 	if err != nil {
 		err = fmt.Errorf("can't create/update database: %v", err)
 		return nil, err
 	}
 	conv.Audit.Progress.UpdateProgress("Schema migration complete.", completionPercentage, internal.SchemaMigrationComplete)
+	conv.Audit.MigrationProgress.SetPhase(internal.PhaseData)
 	bw, err := conversion.DataConv(ctx, sourceProfile, targetProfile, ioHelper, client, conv, true, cmd.WriteLimit)
 	if err != nil {
 		err = fmt.Errorf("can't finish data conversion for db %s: %v", dbURI, err)
@@ -186,7 +283,13 @@ func migrateSchemaAndData(ctx context.Context, targetProfile profiles.TargetProf
 	}
 
 	conv.Audit.Progress.UpdateProgress("Data migration complete.", completionPercentage, internal.DataMigrationComplete)
-	if !cmd.SkipForeignKeys {
+	if cmd.DeferIndexes {
+		if err = conversion.CreatePostDataDDL(ctx, adminClient, dbURI, conv, !cmd.SkipForeignKeys, ioHelper.Out); err != nil {
+			err = fmt.Errorf("can't create indexes and foreign keys on db %s: %v", dbURI, err)
+			return bw, err
+		}
+	} else if !cmd.SkipForeignKeys {
+		conv.Audit.MigrationProgress.SetPhase(internal.PhaseForeignKeys)
 		if err = conversion.UpdateDDLForeignKeys(ctx, adminClient, dbURI, conv, ioHelper.Out); err != nil {
 			err = fmt.Errorf("can't perform update schema on db %s with foreign keys: %v", dbURI, err)
 			return bw, err
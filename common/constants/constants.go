@@ -33,6 +33,10 @@ const (
 	// SQLSERVER is the driver name for sqlserver.
 	SQLSERVER string = "sqlserver"
 
+	// SQLSERVERDUMP is the driver name for T-SQL scripts of a SQL Server
+	// database, e.g. those produced by the "Generate Scripts" task.
+	SQLSERVERDUMP string = "sqlserverdump"
+
 	// DYNAMODB is the driver name for AWS DynamoDB.
 	// This is an experimental driver; implementation in progress.
 	DYNAMODB string = "dynamodb"
@@ -44,6 +48,12 @@ const (
 	// This is an experimental driver; implementation in progress.
 	ORACLE string = "oracle"
 
+	// SQLITE is the driver name for SQLite.
+	SQLITE string = "sqlite"
+
+	// SPANNER is the driver name for Cloud Spanner, when used as the source.
+	SPANNER string = "spanner"
+
 	// Target db for which schema is being generated.
 	// This can be removed once the support for global flags is removed.
 	TargetSpanner              string = "spanner"
@@ -71,4 +81,8 @@ const (
 	// Rule types
 	GlobalDataTypeChange = "global_datatype_change"
 	AddIndex             = "add_index"
+
+	// DynamoDBMaxScanSegments is the maximum number of segments (the
+	// TotalSegments parameter) of a DynamoDB parallel scan.
+	DynamoDBMaxScanSegments int64 = 1000000
 )
@@ -0,0 +1,248 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package telemetry exposes Prometheus metrics of migrations: the rows
+// read, converted, bad, written and dropped per table, the writes to
+// Spanner, and the lag of streaming migrations. Metrics are only recorded
+// once Enable is called.
+package telemetry
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+const namespace = "harbourbridge"
+
+var (
+	enabled  int32
+	registry = prometheus.NewRegistry()
+
+	rowsRead = newCounterVec("rows_read_total",
+		"Rows read from each source table.")
+	rowsConverted = newCounterVec("rows_converted_total",
+		"Rows of each source table converted to Spanner rows.")
+	rowsBad = newCounterVec("rows_bad_total",
+		"Rows of each source table that couldn't be converted.")
+	rowsWritten = newCounterVec("rows_written_total",
+		"Rows written to each Spanner table.")
+	rowsDropped = newCounterVec("rows_dropped_total",
+		"Rows of each Spanner table that couldn't be written.")
+	writesInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "writes_in_flight",
+		Help:      "Writes of batches of rows to Spanner in progress.",
+	})
+	writeConcurrency = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "write_concurrency_limit",
+		Help:      "Limit on the writes to Spanner in progress, when it is adapted to their latency.",
+	})
+	mutationsWritten = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mutations_written_total",
+		Help:      "Mutations written to Spanner, as counted in the commit stats of Spanner.",
+	})
+	writeRetries = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "write_retries_total",
+		Help:      "Writes to Spanner retried with smaller batches after a failed write.",
+	})
+	batchRows = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "batch_rows",
+		Help:      "Rows in each batch written to Spanner.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+	})
+	batchBytes = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "batch_bytes",
+		Help:      "Estimated size in bytes of each batch written to Spanner.",
+		Buckets:   prometheus.ExponentialBuckets(1<<10, 4, 9),
+	})
+	applyLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "spanner_apply_latency_seconds",
+		Help:      "Latency of the Spanner Apply calls that write batches of rows.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+	})
+	streamingLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "streaming_lag_seconds",
+		Help:      "Time between the change of a row of each source table and its processing by a streaming migration.",
+	}, []string{"table"})
+)
+
+func newCounterVec(name, help string) *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: namespace, Name: name, Help: help}, []string{"table"})
+}
+
+func init() {
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		rowsRead, rowsConverted, rowsBad, rowsWritten, rowsDropped,
+		writesInFlight, writeConcurrency, mutationsWritten, writeRetries, batchRows, batchBytes, applyLatency, streamingLag)
+}
+
+// Enable starts recording metrics.
+func Enable() {
+	atomic.StoreInt32(&enabled, 1)
+}
+
+// Enabled returns whether metrics are recorded.
+func Enabled() bool {
+	return atomic.LoadInt32(&enabled) == 1
+}
+
+// Handler returns a handler that serves the metrics in the Prometheus
+// exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Push pushes the metrics to the Prometheus Pushgateway at url, under job
+// and the grouping labels.
+func Push(url, job string, grouping map[string]string) error {
+	p := push.New(url, job).Gatherer(registry)
+	for name, value := range grouping {
+		p = p.Grouping(name, value)
+	}
+	return p.Push()
+}
+
+// StartPush pushes the metrics like Push every interval, until the
+// returned function is called. That function pushes the metrics a last
+// time, and returns the error of that push. Errors of the other pushes are
+// passed to onError.
+func StartPush(url, job string, grouping map[string]string, interval time.Duration, onError func(error)) func() error {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := Push(url, job, grouping); err != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+	return func() error {
+		close(done)
+		wg.Wait()
+		return Push(url, job, grouping)
+	}
+}
+
+// AddRowsRead records that n rows of source table were read.
+func AddRowsRead(table string, n int64) {
+	if Enabled() {
+		rowsRead.WithLabelValues(table).Add(float64(n))
+	}
+}
+
+// AddRowsConverted records that n rows of source table were converted.
+func AddRowsConverted(table string, n int64) {
+	if Enabled() {
+		rowsConverted.WithLabelValues(table).Add(float64(n))
+	}
+}
+
+// AddRowsBad records that n rows of source table couldn't be converted.
+func AddRowsBad(table string, n int64) {
+	if Enabled() {
+		rowsBad.WithLabelValues(table).Add(float64(n))
+	}
+}
+
+// AddRowsWritten records that n rows of Spanner table were written.
+func AddRowsWritten(table string, n int64) {
+	if Enabled() {
+		rowsWritten.WithLabelValues(table).Add(float64(n))
+	}
+}
+
+// AddRowsDropped records that n rows of Spanner table couldn't be written.
+func AddRowsDropped(table string, n int64) {
+	if Enabled() {
+		rowsDropped.WithLabelValues(table).Add(float64(n))
+	}
+}
+
+// WriteStarted records that the write of a batch of rows with the given
+// estimated size in bytes started. WriteFinished must be called once it
+// finishes.
+func WriteStarted(rows int, bytes int64) {
+	if Enabled() {
+		writesInFlight.Inc()
+		batchRows.Observe(float64(rows))
+		batchBytes.Observe(float64(bytes))
+	}
+}
+
+// WriteFinished records that a write started with WriteStarted finished.
+func WriteFinished() {
+	if Enabled() {
+		writesInFlight.Dec()
+	}
+}
+
+// SetWriteConcurrency records the limit n on the writes in progress.
+func SetWriteConcurrency(n int64) {
+	if Enabled() {
+		writeConcurrency.Set(float64(n))
+	}
+}
+
+// AddMutationsWritten records that n mutations were written to Spanner.
+func AddMutationsWritten(n int64) {
+	if Enabled() {
+		mutationsWritten.Add(float64(n))
+	}
+}
+
+// AddWriteRetry records that a write to Spanner was retried.
+func AddWriteRetry() {
+	if Enabled() {
+		writeRetries.Inc()
+	}
+}
+
+// ObserveApplyLatency records the latency d of a Spanner Apply call.
+func ObserveApplyLatency(d time.Duration) {
+	if Enabled() {
+		applyLatency.Observe(d.Seconds())
+	}
+}
+
+// SetStreamingLag records that a change of source table made d ago was
+// processed.
+func SetStreamingLag(table string, d time.Duration) {
+	if Enabled() {
+		streamingLag.WithLabelValues(table).Set(d.Seconds())
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	// Nothing is recorded until metrics are enabled.
+	AddRowsRead("t1", 5)
+	assert.False(t, Enabled())
+	assert.Equal(t, 0.0, testutil.ToFloat64(rowsRead.WithLabelValues("t1")))
+
+	Enable()
+	assert.True(t, Enabled())
+	AddRowsRead("t1", 5)
+	AddRowsConverted("t1", 4)
+	AddRowsBad("t1", 1)
+	AddRowsWritten("T1", 3)
+	AddRowsDropped("T1", 1)
+	WriteStarted(4, 100)
+	WriteStarted(2, 50)
+	WriteFinished()
+	AddWriteRetry()
+	ObserveApplyLatency(20 * time.Millisecond)
+	SetStreamingLag("t1", 3*time.Second)
+	assert.Equal(t, 5.0, testutil.ToFloat64(rowsRead.WithLabelValues("t1")))
+	assert.Equal(t, 4.0, testutil.ToFloat64(rowsConverted.WithLabelValues("t1")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(rowsBad.WithLabelValues("t1")))
+	assert.Equal(t, 3.0, testutil.ToFloat64(rowsWritten.WithLabelValues("T1")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(rowsDropped.WithLabelValues("T1")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(writesInFlight))
+	assert.Equal(t, 1.0, testutil.ToFloat64(writeRetries))
+	assert.Equal(t, 3.0, testutil.ToFloat64(streamingLag.WithLabelValues("t1")))
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	body := rr.Body.String()
+	for _, line := range []string{
+		`harbourbridge_rows_read_total{table="t1"} 5`,
+		`harbourbridge_batch_rows_count 2`,
+		`harbourbridge_batch_bytes_sum 150`,
+		`harbourbridge_spanner_apply_latency_seconds_count 1`,
+	} {
+		assert.Contains(t, body, line)
+	}
+}
+
+func TestPush(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		assert.True(t, len(body) > 0)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	grouping := map[string]string{"migration_request_id": "HB-1"}
+
+	assert.Nil(t, Push(server.URL, "harbourbridge", grouping))
+	stop := StartPush(server.URL, "harbourbridge", grouping, time.Hour, func(err error) {
+		t.Errorf("unexpected push error: %v", err)
+	})
+	// Stopping pushes the metrics a last time.
+	assert.Nil(t, stop())
+	assert.Equal(t, []string{
+		"PUT /metrics/job/harbourbridge/migration_request_id/HB-1",
+		"PUT /metrics/job/harbourbridge/migration_request_id/HB-1",
+	}, requests)
+
+	server.Close()
+	assert.NotNil(t, Push(server.URL, "harbourbridge", grouping))
+}
@@ -63,7 +63,8 @@ type ManifestTable struct {
 }
 
 // NewIOStreams returns a new IOStreams struct such that input stream is set
-// to open file descriptor for dumpFile if driver is PGDUMP or MYSQLDUMP.
+// to open file descriptor for dumpFile if driver is PGDUMP, MYSQLDUMP or
+// SQLSERVERDUMP.
 // Input stream defaults to stdin. Output stream is always set to stdout.
 func NewIOStreams(driver string, dumpFile string) IOStreams {
 	io := IOStreams{In: os.Stdin, Out: os.Stdout}
@@ -72,7 +73,7 @@ func NewIOStreams(driver string, dumpFile string) IOStreams {
 		fmt.Printf("parseFilePath: unable parse file path for dumpfile %s", dumpFile)
 		log.Fatal(err)
 	}
-	if (driver == constants.PGDUMP || driver == constants.MYSQLDUMP) && dumpFile != "" {
+	if (driver == constants.PGDUMP || driver == constants.MYSQLDUMP || driver == constants.SQLSERVERDUMP) && dumpFile != "" {
 		fmt.Printf("\nLoading dump file from path: %s\n", dumpFile)
 		var f *os.File
 		var err error
@@ -541,6 +542,7 @@ func GetValidDrivers() []string {
 		constants.DYNAMODB,
 
 		constants.SQLSERVER,
+		constants.SQLSERVERDUMP,
 	}
 }
 
@@ -566,21 +568,13 @@ func ReadSpannerSchema(ctx context.Context, conv *internal.Conv, client *sp.Clie
 	if err != nil {
 		return fmt.Errorf("error trying to read and convert spanner schema: %v", err)
 	}
-	parentTables, err := infoSchema.GetInterleaveTables()
+	err = infoSchema.SetParentTables(conv)
 	if err != nil {
 		// We should ideally throw an error here as it could potentially cause a lot of failed writes.
 		// We raise an unexpected error for now to make it compatible with the integration tests.
 		// In the emulator, the interleave_type column in not supported hence the query fails.
 		conv.Unexpected(fmt.Sprintf("error trying to fetch interleave table info from schema: %v", err))
 	}
-	// Assign parents if any.
-	for tableName, parentName := range parentTables {
-		tableId, _ := internal.GetTableIdFromSpName(conv.SpSchema, tableName)
-		parentTableId, _ := internal.GetTableIdFromSpName(conv.SpSchema, parentName)
-		spTable := conv.SpSchema[tableId]
-		spTable.ParentId = parentTableId
-		conv.SpSchema[tableId] = spTable
-	}
 	return nil
 }
 
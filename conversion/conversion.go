@@ -33,6 +33,8 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -41,6 +43,7 @@ import (
 
 	sp "cloud.google.com/go/spanner"
 	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	dydb "github.com/aws/aws-sdk-go/service/dynamodb"
@@ -59,12 +62,14 @@ import (
 	"github.com/cloudspannerecosystem/harbourbridge/sources/oracle"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/postgres"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/spanner"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/sqlite"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/sqlserver"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/writer"
 	"go.uber.org/zap"
 	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -80,10 +85,10 @@ var (
 // The SourceProfile param provides the connection details to use the go SQL library.
 func SchemaConv(sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile, ioHelper *utils.IOStreams) (*internal.Conv, error) {
 	switch sourceProfile.Driver {
-	case constants.POSTGRES, constants.MYSQL, constants.DYNAMODB, constants.SQLSERVER, constants.ORACLE:
+	case constants.POSTGRES, constants.MYSQL, constants.DYNAMODB, constants.SQLSERVER, constants.ORACLE, constants.SQLITE, constants.SPANNER:
 		return schemaFromDatabase(sourceProfile, targetProfile)
-	case constants.PGDUMP, constants.MYSQLDUMP:
-		return schemaFromDump(sourceProfile.Driver, targetProfile.Conn.Sp.Dialect, ioHelper)
+	case constants.PGDUMP, constants.MYSQLDUMP, constants.SQLSERVERDUMP:
+		return schemaFromDump(sourceProfile, targetProfile.Conn.Sp.Dialect, ioHelper)
 	default:
 		return nil, fmt.Errorf("schema conversion for driver %s not supported", sourceProfile.Driver)
 	}
@@ -92,20 +97,18 @@ func SchemaConv(sourceProfile profiles.SourceProfile, targetProfile profiles.Tar
 // DataConv performs the data conversion
 // The SourceProfile param provides the connection details to use the go SQL library.
 func DataConv(ctx context.Context, sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile, ioHelper *utils.IOStreams, client *sp.Client, conv *internal.Conv, dataOnly bool, writeLimit int64) (*writer.BatchWriter, error) {
-	config := writer.BatchWriterConfig{
-		BytesLimit: 100 * 1000 * 1000,
-		WriteLimit: writeLimit,
-		RetryLimit: 1000,
-		Verbose:    internal.Verbose(),
+	config, err := dataWriterConfig(ctx, targetProfile, conv, writeLimit)
+	if err != nil {
+		return nil, err
 	}
 	switch sourceProfile.Driver {
-	case constants.POSTGRES, constants.MYSQL, constants.DYNAMODB, constants.SQLSERVER, constants.ORACLE:
+	case constants.POSTGRES, constants.MYSQL, constants.DYNAMODB, constants.SQLSERVER, constants.ORACLE, constants.SQLITE, constants.SPANNER:
 		return dataFromDatabase(ctx, sourceProfile, targetProfile, config, conv, client)
-	case constants.PGDUMP, constants.MYSQLDUMP:
+	case constants.PGDUMP, constants.MYSQLDUMP, constants.SQLSERVERDUMP:
 		if conv.SpSchema.CheckInterleaved() {
 			return nil, fmt.Errorf("harbourBridge does not currently support data conversion from dump files\nif the schema contains interleaved tables. Suggest using direct access to source database\ni.e. using drivers postgres and mysql")
 		}
-		return dataFromDump(sourceProfile.Driver, config, ioHelper, client, conv, dataOnly)
+		return dataFromDump(ctx, sourceProfile, config, ioHelper, client, conv, dataOnly)
 	case constants.CSV:
 		return dataFromCSV(ctx, sourceProfile, targetProfile, config, conv, client)
 	default:
@@ -142,6 +145,11 @@ func connectionConfig(sourceProfile profiles.SourceProfile) (interface{}, error)
 		return profiles.GetSQLConnectionStr(sourceProfile), nil
 	case constants.ORACLE:
 		return profiles.GetSQLConnectionStr(sourceProfile), nil
+	case constants.SQLITE:
+		return profiles.GetSQLConnectionStr(sourceProfile), nil
+	case constants.SPANNER:
+		spConn := sourceProfile.Conn.Sp
+		return fmt.Sprintf("projects/%s/instances/%s/databases/%s", spConn.Project, spConn.Instance, spConn.Dbname), nil
 	default:
 		return "", fmt.Errorf("driver %s not supported", sourceProfile.Driver)
 	}
@@ -162,6 +170,11 @@ func getDbNameFromSQLConnectionStr(driver, sqlConnectionStr string) string {
 		substr := sqlConnectionStr[9:]
 		dbName := strings.Split(substr, ":")[0]
 		return dbName
+	case constants.SQLITE:
+		// connection string format : "file:/path/to/edge.db?mode=ro"
+		path := strings.TrimPrefix(strings.Split(sqlConnectionStr, "?")[0], "file:")
+		base := filepath.Base(path)
+		return strings.TrimSuffix(base, filepath.Ext(base))
 	}
 	return ""
 }
@@ -173,28 +186,76 @@ func schemaFromDatabase(sourceProfile profiles.SourceProfile, targetProfile prof
 	if err != nil {
 		return conv, err
 	}
-	return conv, common.ProcessSchema(conv, infoSchema, common.DefaultWorkers)
+	defer closeInfoSchema(infoSchema)
+	err = common.ProcessSchema(conv, infoSchema, common.DefaultWorkers)
+	if err != nil {
+		return conv, err
+	}
+	if sourceProfile.Conn.SampleRows > 0 {
+		if err := common.ProfileData(conv, infoSchema, sourceProfile.Conn.SampleRows); err != nil {
+			return conv, err
+		}
+	}
+	// Spanner sources keep the interleaving of their tables.
+	if isi, ok := infoSchema.(spanner.InfoSchemaImpl); ok {
+		if err := isi.SetParentTables(conv); err != nil {
+			return conv, fmt.Errorf("error trying to fetch interleave table info from schema: %v", err)
+		}
+	}
+	return conv, nil
 }
 
-func performSnapshotMigration(config writer.BatchWriterConfig, conv *internal.Conv, client *sp.Client, infoSchema common.InfoSchema) *writer.BatchWriter {
+func performSnapshotMigration(ctx context.Context, config writer.BatchWriterConfig, conv *internal.Conv, client *sp.Client, infoSchema common.InfoSchema) *writer.BatchWriter {
 	common.SetRowStats(conv, infoSchema)
 	totalRows := conv.Rows()
 	if !conv.Audit.DryRun {
 		conv.Audit.Progress = *internal.NewProgress(totalRows, "Writing data to Spanner", internal.Verbose(), false, int(internal.DataWriteInProgress))
 	}
-	batchWriter := populateDataConv(conv, config, client)
-	common.ProcessData(conv, infoSchema)
+	batchWriter := populateDataConv(ctx, conv, config, client)
+	common.ProcessData(ctx, conv, infoSchema)
 	batchWriter.Flush()
 	return batchWriter
 }
 
-func snapshotMigrationHandler(sourceProfile profiles.SourceProfile, config writer.BatchWriterConfig, conv *internal.Conv, client *sp.Client, infoSchema common.InfoSchema) (*writer.BatchWriter, error) {
+func snapshotMigrationHandler(ctx context.Context, sourceProfile profiles.SourceProfile, config writer.BatchWriterConfig, conv *internal.Conv, client *sp.Client, infoSchema common.InfoSchema, streamInfo map[string]interface{}) (*writer.BatchWriter, error) {
 	switch sourceProfile.Driver {
-	// Skip snapshot migration via harbourbridge for mysql and oracle since dataflow job will job will handle this from backfilled data.
-	case constants.MYSQL, constants.ORACLE, constants.POSTGRES:
+	case constants.MYSQL:
+		// With binlog CDC, harbourbridge does the snapshot migration itself,
+		// unless streaming is resumed after the snapshot was done.
+		if sourceProfile.Conn.Mysql.Cdc == profiles.BinlogCdc {
+			if skip, _ := streamInfo[mysql.SkipSnapshotKey].(bool); skip {
+				return &writer.BatchWriter{}, nil
+			}
+			return performSnapshotMigration(ctx, config, conv, client, infoSchema), nil
+		}
+		return &writer.BatchWriter{}, nil
+	case constants.POSTGRES:
+		// With pgoutput CDC, harbourbridge does the snapshot migration itself,
+		// reading the snapshot exported by the replication slot.
+		if sourceProfile.Conn.Pg.Cdc == profiles.PgoutputCdc {
+			if skip, _ := streamInfo[postgres.SkipSnapshotKey].(bool); skip {
+				return &writer.BatchWriter{}, nil
+			}
+			isi, ok := infoSchema.(postgres.InfoSchemaImpl)
+			if !ok {
+				return &writer.BatchWriter{}, fmt.Errorf("unexpected info schema for driver %s", sourceProfile.Driver)
+			}
+			isi.Snapshot, _ = streamInfo[postgres.SnapshotKey].(string)
+			return performSnapshotMigration(ctx, config, conv, client, isi), nil
+		}
+		return &writer.BatchWriter{}, nil
+	case constants.SQLSERVER:
+		// With change table CDC, harbourbridge does the snapshot migration
+		// itself, unless streaming is resumed after the snapshot was done.
+		if skip, _ := streamInfo[sqlserver.SkipSnapshotKey].(bool); skip {
+			return &writer.BatchWriter{}, nil
+		}
+		return performSnapshotMigration(ctx, config, conv, client, infoSchema), nil
+	// Skip snapshot migration via harbourbridge for oracle and for postgres with Datastream since dataflow job will job will handle this from backfilled data.
+	case constants.ORACLE:
 		return &writer.BatchWriter{}, nil
 	case constants.DYNAMODB:
-		return performSnapshotMigration(config, conv, client, infoSchema), nil
+		return performSnapshotMigration(ctx, config, conv, client, infoSchema), nil
 	default:
 		return &writer.BatchWriter{}, fmt.Errorf("streaming migration not supported for driver %s", sourceProfile.Driver)
 	}
@@ -205,13 +266,14 @@ func dataFromDatabase(ctx context.Context, sourceProfile profiles.SourceProfile,
 	if err != nil {
 		return nil, err
 	}
+	defer closeInfoSchema(infoSchema)
 	var streamInfo map[string]interface{}
 	if sourceProfile.Conn.Streaming {
 		streamInfo, err = infoSchema.StartChangeDataCapture(ctx, conv)
 		if err != nil {
 			return nil, err
 		}
-		bw, err := snapshotMigrationHandler(sourceProfile, config, conv, client, infoSchema)
+		bw, err := snapshotMigrationHandler(ctx, sourceProfile, config, conv, client, infoSchema, streamInfo)
 		if err != nil {
 			return nil, err
 		}
@@ -221,7 +283,7 @@ func dataFromDatabase(ctx context.Context, sourceProfile profiles.SourceProfile,
 		}
 		return bw, nil
 	}
-	return performSnapshotMigration(config, conv, client, infoSchema), nil
+	return performSnapshotMigration(ctx, config, conv, client, infoSchema), nil
 }
 
 func getDynamoDBClientConfig() (*aws.Config, error) {
@@ -233,10 +295,10 @@ func getDynamoDBClientConfig() (*aws.Config, error) {
 	return &cfg, nil
 }
 
-func schemaFromDump(driver string, spDialect string, ioHelper *utils.IOStreams) (*internal.Conv, error) {
+func schemaFromDump(sourceProfile profiles.SourceProfile, spDialect string, ioHelper *utils.IOStreams) (*internal.Conv, error) {
 	f, n, err := getSeekable(ioHelper.In)
 	if err != nil {
-		utils.PrintSeekError(driver, err, ioHelper.Out)
+		utils.PrintSeekError(sourceProfile.Driver, err, ioHelper.Out)
 		return nil, fmt.Errorf("can't get seekable input file")
 	}
 	ioHelper.SeekableIn = f
@@ -247,7 +309,7 @@ func schemaFromDump(driver string, spDialect string, ioHelper *utils.IOStreams)
 	r := internal.NewReader(bufio.NewReader(f), p)
 	conv.SetSchemaMode() // Build schema and ignore data in dump.
 	conv.SetDataSink(nil)
-	err = ProcessDump(driver, conv, r)
+	err = ProcessDump(context.Background(), sourceProfile, conv, r)
 	if err != nil {
 		fmt.Fprintf(ioHelper.Out, "Failed to parse the data file: %v", err)
 		return nil, fmt.Errorf("failed to parse the data file")
@@ -256,7 +318,7 @@ func schemaFromDump(driver string, spDialect string, ioHelper *utils.IOStreams)
 	return conv, nil
 }
 
-func dataFromDump(driver string, config writer.BatchWriterConfig, ioHelper *utils.IOStreams, client *sp.Client, conv *internal.Conv, dataOnly bool) (*writer.BatchWriter, error) {
+func dataFromDump(ctx context.Context, sourceProfile profiles.SourceProfile, config writer.BatchWriterConfig, ioHelper *utils.IOStreams, client *sp.Client, conv *internal.Conv, dataOnly bool) (*writer.BatchWriter, error) {
 	// TODO: refactor of the way we handle getSeekable
 	// to avoid the code duplication here
 	if !dataOnly {
@@ -270,7 +332,7 @@ func dataFromDump(driver string, config writer.BatchWriterConfig, ioHelper *util
 		// changes in showing progress for data migration.
 		f, n, err := getSeekable(ioHelper.In)
 		if err != nil {
-			utils.PrintSeekError(driver, err, ioHelper.Out)
+			utils.PrintSeekError(sourceProfile.Driver, err, ioHelper.Out)
 			return nil, fmt.Errorf("can't get seekable input file")
 		}
 		ioHelper.SeekableIn = f
@@ -280,8 +342,8 @@ func dataFromDump(driver string, config writer.BatchWriterConfig, ioHelper *util
 
 	conv.Audit.Progress = *internal.NewProgress(totalRows, "Writing data to Spanner", internal.Verbose(), false, int(internal.DataWriteInProgress))
 	r := internal.NewReader(bufio.NewReader(ioHelper.SeekableIn), nil)
-	batchWriter := populateDataConv(conv, config, client)
-	ProcessDump(driver, conv, r)
+	batchWriter := populateDataConv(ctx, conv, config, client)
+	ProcessDump(ctx, sourceProfile, conv, r)
 	batchWriter.Flush()
 	conv.Audit.Progress.Done()
 
@@ -330,8 +392,8 @@ func dataFromCSV(ctx context.Context, sourceProfile profiles.SourceProfile, targ
 
 	totalRows := conv.Rows()
 	conv.Audit.Progress = *internal.NewProgress(totalRows, "Writing data to Spanner", internal.Verbose(), false, int(internal.DataWriteInProgress))
-	batchWriter := populateDataConv(conv, config, client)
-	err = csv.ProcessCSV(conv, tables, sourceProfile.Csv.NullStr, delimiter)
+	batchWriter := populateDataConv(ctx, conv, config, client)
+	err = csv.ProcessCSV(ctx, conv, tables, sourceProfile.Csv.NullStr, delimiter)
 	if err != nil {
 		return nil, fmt.Errorf("can't process csv: %v", err)
 	}
@@ -340,17 +402,44 @@ func dataFromCSV(ctx context.Context, sourceProfile profiles.SourceProfile, targ
 	return batchWriter, nil
 }
 
-func populateDataConv(conv *internal.Conv, config writer.BatchWriterConfig, client *sp.Client) *writer.BatchWriter {
+// dataWriterConfig returns the configuration of the BatchWriter that
+// writes the data of conv to the database of targetProfile.
+func dataWriterConfig(ctx context.Context, targetProfile profiles.TargetProfile, conv *internal.Conv, writeLimit int64) (writer.BatchWriterConfig, error) {
+	config := writer.BatchWriterConfig{
+		BytesLimit: 100 * 1000 * 1000,
+		WriteLimit: writeLimit,
+		RetryLimit: 1000,
+		Verbose:    internal.Verbose(),
+		Context:    ctx,
+	}
+	oversized, err := writer.ParseOversizedValuePolicy(targetProfile.Conn.Sp.OversizedValues)
+	if err != nil {
+		return config, err
+	}
+	config.OversizedValues = oversized
+	config.WriteStrategy, err = writer.ParseWriteStrategy(targetProfile.Conn.Sp.WriteStrategy)
+	if err != nil {
+		return config, err
+	}
+	if targetProfile.Conn.Sp.KeyOrdered {
+		config.KeyColumns = func(table string) []string { return keyColumns(conv, table) }
+	}
+	config.AdaptiveConcurrency = targetProfile.Conn.Sp.AdaptiveConcurrency
+	config.TargetLatency = targetProfile.Conn.Sp.TargetWriteLatency
+	conv.Audit.CommitStats = targetProfile.Conn.Sp.CommitStats
+	if oversized == writer.OversizedOffload && !conv.Audit.DryRun {
+		config.ValueStore, err = writer.NewValueStore(ctx, targetProfile.Conn.Sp.OffloadLocation)
+		if err != nil {
+			return config, fmt.Errorf("can't access offload location %s: %v", targetProfile.Conn.Sp.OffloadLocation, err)
+		}
+	}
+	return config, nil
+}
+
+func populateDataConv(ctx context.Context, conv *internal.Conv, config writer.BatchWriterConfig, client *sp.Client) *writer.BatchWriter {
 	rows := int64(0)
 	config.Write = func(m []*sp.Mutation) error {
-		ctx := context.Background()
-		if !conv.Audit.SkipMetricsPopulation {
-			migrationData := metrics.GetMigrationData(conv, "", constants.DataConv)
-			serializedMigrationData, _ := proto.Marshal(migrationData)
-			migrationMetadataValue := base64.StdEncoding.EncodeToString(serializedMigrationData)
-			ctx = metadata.AppendToOutgoingContext(context.Background(), constants.MigrationMetadataKey, migrationMetadataValue)
-		}
-		_, err := client.Apply(ctx, m)
+		_, err := client.Apply(writeContext(ctx, conv), m)
 		if err != nil {
 			return err
 		}
@@ -358,6 +447,71 @@ func populateDataConv(conv *internal.Conv, config writer.BatchWriterConfig, clie
 		conv.Audit.Progress.MaybeReport(atomic.LoadInt64(&rows))
 		return nil
 	}
+	if conv.Audit.CommitStats {
+		// Batches are committed in a read-write transaction, like
+		// client.Apply does, to get the commit stats of Spanner.
+		config.Commit = func(m []*sp.Mutation) (int64, error) {
+			resp, err := client.ReadWriteTransactionWithOptions(writeContext(ctx, conv), func(ctx context.Context, txn *sp.ReadWriteTransaction) error {
+				return txn.BufferWrite(m)
+			}, sp.TransactionOptions{CommitOptions: sp.CommitOptions{ReturnCommitStats: true}})
+			if err != nil {
+				return 0, err
+			}
+			atomic.AddInt64(&rows, int64(len(m)))
+			conv.Audit.Progress.MaybeReport(atomic.LoadInt64(&rows))
+			return resp.CommitStats.GetMutationCount(), nil
+		}
+	}
+	config.BatchWrite = func(groups [][]*sp.Mutation) []error {
+		mgs := make([]*sp.MutationGroup, len(groups))
+		for i, m := range groups {
+			mgs[i] = &sp.MutationGroup{Mutations: m}
+		}
+		errs := make([]error, len(groups))
+		applied := make([]bool, len(groups))
+		err := client.BatchWrite(writeContext(ctx, conv), mgs).Do(func(r *sppb.BatchWriteResponse) error {
+			err := status.ErrorProto(r.GetStatus())
+			for _, i := range r.GetIndexes() {
+				if int(i) >= len(groups) {
+					continue
+				}
+				errs[i], applied[i] = err, true
+				if err == nil {
+					atomic.AddInt64(&rows, int64(len(groups[i])))
+				}
+			}
+			return nil
+		})
+		if err == nil {
+			err = fmt.Errorf("no result for mutation group")
+		}
+		// Groups without a result failed with the request.
+		for i := range errs {
+			if !applied[i] {
+				errs[i] = err
+			}
+		}
+		conv.Audit.Progress.MaybeReport(atomic.LoadInt64(&rows))
+		return errs
+	}
+	config.OnOversizedValue = func(table, col string, policy writer.OversizedValuePolicy) {
+		conv.StatsAddOversizedValue(table, col, policy == writer.OversizedTruncate)
+	}
+	if mp := conv.Audit.MigrationProgress; mp != nil {
+		for tableId, srcTable := range conv.SrcSchema {
+			if spTable, ok := conv.SpSchema[tableId]; ok {
+				mp.SetTable(spTable.Name, srcTable.Name, conv.Stats.Rows[srcTable.Name])
+			}
+		}
+		config.OnWritten = mp.AddWritten
+		config.OnDropped = mp.AddDropped
+	}
+	if q := conv.Audit.DeadLetters; q != nil {
+		config.OnBadRow = func(table string, cols []string, vals []interface{}, source interface{}, attempts int64, err error) {
+			src, _ := source.(internal.SourceRow)
+			q.Add(writeDeadLetter(conv, table, cols, vals, src, attempts, err))
+		}
+	}
 	batchWriter := writer.NewBatchWriter(config)
 	conv.SetDataMode()
 	if !conv.Audit.DryRun {
@@ -365,6 +519,14 @@ func populateDataConv(conv *internal.Conv, config writer.BatchWriterConfig, clie
 			func(table string, cols []string, vals []interface{}) {
 				batchWriter.AddRow(table, cols, vals)
 			})
+		// Rows rejected by Spanner keep their source values in the
+		// dead-letter file, so that they are converted again on replay.
+		if conv.Audit.DeadLetters != nil {
+			conv.SetSourceDataSink(
+				func(src internal.SourceRow, table string, cols []string, vals []interface{}) {
+					batchWriter.AddSourceRow(table, cols, vals, src, 0)
+				})
+		}
 		conv.DataFlush = func() {
 			batchWriter.Flush()
 		}
@@ -373,6 +535,66 @@ func populateDataConv(conv *internal.Conv, config writer.BatchWriterConfig, clie
 	return batchWriter
 }
 
+// writeDeadLetter returns the dead letter of a row of the Spanner table
+// table that couldn't be written, converted from the source row src if it
+// has values.
+func writeDeadLetter(conv *internal.Conv, table string, cols []string, vals []interface{}, src internal.SourceRow, attempts int64, err error) internal.DeadLetter {
+	dl := internal.DeadLetter{
+		Stage:          internal.StageWrite,
+		Table:          table,
+		SourceColumns:  src.Columns,
+		SourceValues:   src.Values,
+		SpannerTable:   table,
+		SpannerColumns: cols,
+		Error:          err.Error(),
+		Attempts:       attempts,
+	}
+	if src.Table != "" {
+		dl.Table = src.Table
+	} else if tableId, err := internal.GetTableIdFromSpName(conv.SpSchema, table); err == nil {
+		if srcTable, ok := conv.SrcSchema[tableId]; ok {
+			dl.Table = srcTable.Name
+		}
+	}
+	encoded, encErr := writer.EncodeValues(cols, vals)
+	if encErr != nil {
+		dl.Error = fmt.Sprintf("%s (values can't be replayed: %v)", dl.Error, encErr)
+	}
+	dl.ConvertedValues = encoded
+	return dl
+}
+
+// writeContext returns the context of the writes of the data of conv,
+// which carries the migration metadata unless its population is skipped.
+func writeContext(ctx context.Context, conv *internal.Conv) context.Context {
+	if conv.Audit.SkipMetricsPopulation {
+		return ctx
+	}
+	migrationData := metrics.GetMigrationData(conv, "", constants.DataConv)
+	serializedMigrationData, _ := proto.Marshal(migrationData)
+	migrationMetadataValue := base64.StdEncoding.EncodeToString(serializedMigrationData)
+	return metadata.AppendToOutgoingContext(ctx, constants.MigrationMetadataKey, migrationMetadataValue)
+}
+
+// keyColumns returns the primary key columns of the Spanner table named
+// table, in key order.
+func keyColumns(conv *internal.Conv, table string) []string {
+	for _, ct := range conv.SpSchema {
+		if ct.Name != table {
+			continue
+		}
+		pks := make([]ddl.IndexKey, len(ct.PrimaryKeys))
+		copy(pks, ct.PrimaryKeys)
+		sort.Slice(pks, func(i, j int) bool { return pks[i].Order < pks[j].Order })
+		var cols []string
+		for _, pk := range pks {
+			cols = append(cols, ct.ColDefs[pk.ColId].Name)
+		}
+		return cols
+	}
+	return nil
+}
+
 // Report generates a report of schema and data conversion.
 func Report(driver string, badWrites map[string]int64, BytesRead int64, banner string, conv *internal.Conv, reportFileName string, dbName string, out *os.File) {
 
@@ -526,7 +748,9 @@ func ValidateDDL(ctx context.Context, adminClient *database.DatabaseAdminClient,
 }
 
 // CreatesOrUpdatesDatabase updates an existing Spanner database or creates a new one if one does not exist.
-func CreateOrUpdateDatabase(ctx context.Context, adminClient *database.DatabaseAdminClient, dbURI, driver string, conv *internal.Conv, out *os.File) error {
+// If deferIndexes is set, only tables and their primary keys are created: the
+// secondary indexes are created after the data load by CreatePostDataDDL.
+func CreateOrUpdateDatabase(ctx context.Context, adminClient *database.DatabaseAdminClient, dbURI, driver string, conv *internal.Conv, deferIndexes bool, out *os.File) error {
 	dbExists, err := VerifyDb(ctx, adminClient, dbURI)
 	if err != nil {
 		return err
@@ -539,12 +763,12 @@ func CreateOrUpdateDatabase(ctx context.Context, adminClient *database.DatabaseA
 		ctx = metadata.AppendToOutgoingContext(ctx, constants.MigrationMetadataKey, migrationMetadataValue)
 	}
 	if dbExists {
-		err := UpdateDatabase(ctx, adminClient, dbURI, conv, out)
+		err := UpdateDatabase(ctx, adminClient, dbURI, conv, deferIndexes, out)
 		if err != nil {
 			return fmt.Errorf("can't update database schema: %v", err)
 		}
 	} else {
-		err := CreateDatabase(ctx, adminClient, dbURI, conv, out)
+		err := CreateDatabase(ctx, adminClient, dbURI, conv, deferIndexes, out)
 		if err != nil {
 			return fmt.Errorf("can't create database: %v", err)
 		}
@@ -556,7 +780,8 @@ func CreateOrUpdateDatabase(ctx context.Context, adminClient *database.DatabaseA
 // It automatically determines an appropriate project, selects a
 // Spanner instance to use, generates a new Spanner DB name,
 // and call into the Spanner admin interface to create the new DB.
-func CreateDatabase(ctx context.Context, adminClient *database.DatabaseAdminClient, dbURI string, conv *internal.Conv, out *os.File) error {
+// If deferIndexes is set, secondary indexes are left out of the schema.
+func CreateDatabase(ctx context.Context, adminClient *database.DatabaseAdminClient, dbURI string, conv *internal.Conv, deferIndexes bool, out *os.File) error {
 	project, instance, dbName := utils.ParseDbURI(dbURI)
 	fmt.Fprintf(out, "Creating new database %s in instance %s with default permissions ... \n", dbName, instance)
 	// The schema we send to Spanner excludes comments (since Cloud
@@ -575,7 +800,7 @@ func CreateDatabase(ctx context.Context, adminClient *database.DatabaseAdminClie
 		req.DatabaseDialect = adminpb.DatabaseDialect_POSTGRESQL
 	} else {
 		req.CreateStatement = "CREATE DATABASE `" + dbName + "`"
-		req.ExtraStatements = conv.SpSchema.GetDDL(ddl.Config{Comments: false, ProtectIds: true, Tables: true, ForeignKeys: false, SpDialect: conv.SpDialect})
+		req.ExtraStatements = conv.SpSchema.GetDDL(ddl.Config{Comments: false, ProtectIds: true, Tables: true, ForeignKeys: false, SkipIndexes: deferIndexes, SpDialect: conv.SpDialect})
 	}
 
 	op, err := adminClient.CreateDatabase(ctx, req)
@@ -589,19 +814,20 @@ func CreateDatabase(ctx context.Context, adminClient *database.DatabaseAdminClie
 
 	if conv.SpDialect == constants.DIALECT_POSTGRESQL {
 		// Update schema separately for PG databases.
-		return UpdateDatabase(ctx, adminClient, dbURI, conv, out)
+		return UpdateDatabase(ctx, adminClient, dbURI, conv, deferIndexes, out)
 	}
 	return nil
 }
 
 // UpdateDatabase updates an existing spanner database.
-func UpdateDatabase(ctx context.Context, adminClient *database.DatabaseAdminClient, dbURI string, conv *internal.Conv, out *os.File) error {
+// If deferIndexes is set, secondary indexes are left out of the schema.
+func UpdateDatabase(ctx context.Context, adminClient *database.DatabaseAdminClient, dbURI string, conv *internal.Conv, deferIndexes bool, out *os.File) error {
 	fmt.Fprintf(out, "Updating schema for %s with default permissions ... \n", dbURI)
 	// The schema we send to Spanner excludes comments (since Cloud
 	// Spanner DDL doesn't accept them), and protects table and col names
 	// using backticks (to avoid any issues with Spanner reserved words).
 	// Foreign Keys are set to false since we create them post data migration.
-	schema := conv.SpSchema.GetDDL(ddl.Config{Comments: false, ProtectIds: true, Tables: true, ForeignKeys: false, SpDialect: conv.SpDialect})
+	schema := conv.SpSchema.GetDDL(ddl.Config{Comments: false, ProtectIds: true, Tables: true, ForeignKeys: false, SkipIndexes: deferIndexes, SpDialect: conv.SpDialect})
 	req := &adminpb.UpdateDatabaseDdlRequest{
 		Database:   dbURI,
 		Statements: schema,
@@ -932,14 +1158,16 @@ func writeBadStreamingData(conv *internal.Conv, f *os.File) error {
 }
 
 // ProcessDump invokes process dump function from a sql package based on driver selected.
-func ProcessDump(driver string, conv *internal.Conv, r *internal.Reader) error {
-	switch driver {
+func ProcessDump(ctx context.Context, sourceProfile profiles.SourceProfile, conv *internal.Conv, r *internal.Reader) error {
+	switch sourceProfile.Driver {
 	case constants.MYSQLDUMP:
-		return common.ProcessDbDump(conv, r, mysql.DbDumpImpl{})
+		return common.ProcessDbDump(ctx, conv, r, mysql.DbDumpImpl{SpatialFormat: sourceProfile.File.SpatialFormat})
 	case constants.PGDUMP:
-		return common.ProcessDbDump(conv, r, postgres.DbDumpImpl{})
+		return common.ProcessDbDump(ctx, conv, r, postgres.DbDumpImpl{})
+	case constants.SQLSERVERDUMP:
+		return common.ProcessDbDump(ctx, conv, r, sqlserver.DbDumpImpl{})
 	default:
-		return fmt.Errorf("process dump for driver %s not supported", driver)
+		return fmt.Errorf("process dump for driver %s not supported", sourceProfile.Driver)
 	}
 }
 
@@ -969,6 +1197,9 @@ func GetInfoSchema(sourceProfile profiles.SourceProfile, targetProfile profiles.
 		}
 		return postgres.InfoSchemaImpl{Db: db, SourceProfile: sourceProfile, TargetProfile: targetProfile}, nil
 	case constants.DYNAMODB:
+		if exportDir := sourceProfile.Conn.Dydb.ExportDir; exportDir != "" {
+			return dynamodb.NewExportInfoSchemaImpl(exportDir, profiles.GetSchemaSampleSize(sourceProfile))
+		}
 		mySession := session.Must(session.NewSession())
 		dydbClient := dydb.New(mySession, connectionConfig.(*aws.Config))
 		var dydbStreamsClient *dynamodbstreams.DynamoDBStreams
@@ -980,6 +1211,8 @@ func GetInfoSchema(sourceProfile profiles.SourceProfile, targetProfile profiles.
 			DynamoClient:        dydbClient,
 			SampleSize:          profiles.GetSchemaSampleSize(sourceProfile),
 			DynamoStreamsClient: dydbStreamsClient,
+			ScanSegments:        sourceProfile.Conn.Dydb.ScanSegments,
+			ReadCapacityPercent: sourceProfile.Conn.Dydb.ReadCapacityPercent,
 		}, nil
 	case constants.SQLSERVER:
 		db, err := sql.Open(driver, connectionConfig.(string))
@@ -987,7 +1220,7 @@ func GetInfoSchema(sourceProfile profiles.SourceProfile, targetProfile profiles.
 		if err != nil {
 			return nil, err
 		}
-		return sqlserver.InfoSchemaImpl{DbName: dbName, Db: db}, nil
+		return sqlserver.InfoSchemaImpl{DbName: dbName, Db: db, SourceProfile: sourceProfile}, nil
 	case constants.ORACLE:
 		db, err := sql.Open(driver, connectionConfig.(string))
 		dbName := getDbNameFromSQLConnectionStr(driver, connectionConfig.(string))
@@ -995,7 +1228,49 @@ func GetInfoSchema(sourceProfile profiles.SourceProfile, targetProfile profiles.
 			return nil, err
 		}
 		return oracle.InfoSchemaImpl{DbName: strings.ToUpper(dbName), Db: db, SourceProfile: sourceProfile, TargetProfile: targetProfile}, nil
+	case constants.SQLITE:
+		db, err := sql.Open("sqlite3", connectionConfig.(string))
+		dbName := getDbNameFromSQLConnectionStr(driver, connectionConfig.(string))
+		if err != nil {
+			return nil, err
+		}
+		return sqlite.InfoSchemaImpl{DbName: dbName, Db: db}, nil
+	case constants.SPANNER:
+		return getSpannerInfoSchema(sourceProfile, targetProfile, connectionConfig.(string))
 	default:
 		return nil, fmt.Errorf("driver %s not supported", driver)
 	}
 }
+
+// getSpannerInfoSchema connects to a source Spanner database. All tables are
+// read at the same timestamp, which is the read timestamp of the source
+// profile or else the time at which the migration starts.
+func getSpannerInfoSchema(sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile, dbURI string) (common.InfoSchema, error) {
+	trg := targetProfile.Conn.Sp
+	if dbURI == fmt.Sprintf("projects/%s/instances/%s/databases/%s", trg.Project, trg.Instance, trg.Dbname) {
+		return nil, fmt.Errorf("source and target can't be the same database %s", dbURI)
+	}
+	ctx := context.Background()
+	client, err := utils.NewSpannerClient(ctx, dbURI)
+	if err != nil {
+		return nil, fmt.Errorf("can't create client for source database %s: %v", dbURI, err)
+	}
+	spDialect, err := spanner.FetchDialect(ctx, client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	readTimestamp := sourceProfile.Conn.Sp.ReadTimestamp
+	if readTimestamp.IsZero() {
+		readTimestamp = time.Now()
+	}
+	return spanner.InfoSchemaImpl{Client: client, Ctx: ctx, SpDialect: spDialect, ReadTimestamp: readTimestamp}, nil
+}
+
+// closeInfoSchema closes the client of a source Spanner database, which
+// GetInfoSchema opens for each step of the migration.
+func closeInfoSchema(infoSchema common.InfoSchema) {
+	if isi, ok := infoSchema.(spanner.InfoSchemaImpl); ok {
+		isi.Client.Close()
+	}
+}
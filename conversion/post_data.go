@@ -0,0 +1,144 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conversion
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"go.uber.org/zap"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+)
+
+var (
+	// PostDataBatchSize is the maximum number of statements sent in one
+	// UpdateDatabaseDdl operation when creating the deferred indexes and
+	// foreign keys. Spanner backfills the indexes of a batch together, but
+	// a large batch delays the report of the statements that failed.
+	PostDataBatchSize = 10
+	// PostDataPollInterval is how often the progress of a DDL operation is
+	// polled.
+	PostDataPollInterval = 10 * time.Second
+)
+
+// postDataNameRe matches the name of the indexes and constraints in the DDL
+// statements returned by Spanner.
+var postDataNameRe = regexp.MustCompile("(?i)\\b(?:INDEX|CONSTRAINT)\\s+[`\"]?([^`\"\\s(]+)")
+
+// CreatePostDataDDL creates the secondary indexes and, if foreignKeys is set,
+// the foreign keys of conv's Spanner schema in the existing database dbURI.
+// It is the last step of a load-then-index migration, run once the data is
+// loaded in tables created with deferIndexes. Indexes are created before
+// foreign keys, in batches of PostDataBatchSize statements; the progress of
+// each batch is polled from its long-running operation. Indexes and foreign
+// keys that already exist in the database are skipped, so that a failed run
+// can be resumed.
+func CreatePostDataDDL(ctx context.Context, adminClient *database.DatabaseAdminClient, dbURI string, conv *internal.Conv, foreignKeys bool, out *os.File) error {
+	dbDdl, err := adminClient.GetDatabaseDdl(ctx, &adminpb.GetDatabaseDdlRequest{Database: dbURI})
+	if err != nil {
+		return fmt.Errorf("can't fetch database ddl: %w", utils.AnalyzeError(err, dbURI))
+	}
+	exists := make(map[string]bool)
+	for _, stmt := range dbDdl.Statements {
+		for _, m := range postDataNameRe.FindAllStringSubmatch(stmt, -1) {
+			exists[strings.ToLower(m[1])] = true
+		}
+	}
+	// The schema we send to Spanner excludes comments (since Cloud
+	// Spanner DDL doesn't accept them), and protects table and col names
+	// using backticks (to avoid any issues with Spanner reserved words).
+	indexes, fks := conv.SpSchema.GetPostDataDDL(ddl.Config{Comments: false, ProtectIds: true, ForeignKeys: foreignKeys, SpDialect: conv.SpDialect}, exists)
+	total := len(indexes) + len(fks)
+	if total == 0 {
+		fmt.Fprintf(out, "No indexes or foreign keys to create in %s.\n", dbURI)
+		return nil
+	}
+	msg := fmt.Sprintf("Creating indexes and foreign keys of database %s ...", dbURI)
+	conv.Audit.Progress = *internal.NewProgress(int64(total), msg, internal.Verbose(), true, int(internal.PostDataDDLInProgress))
+	done := 0
+	stages := []struct {
+		phase internal.Phase
+		stmts []string
+	}{
+		{internal.PhaseIndexes, indexes},
+		{internal.PhaseForeignKeys, fks},
+	}
+	for _, stage := range stages {
+		if len(stage.stmts) > 0 {
+			conv.Audit.MigrationProgress.SetPhase(stage.phase)
+		}
+		for stmts := stage.stmts; len(stmts) > 0; {
+			n := PostDataBatchSize
+			if n <= 0 || n > len(stmts) {
+				n = len(stmts)
+			}
+			batch := stmts[:n]
+			stmts = stmts[n:]
+			committed := 0
+			if err := updatePostDataDDL(ctx, adminClient, dbURI, batch, func(n int) {
+				committed = n
+				conv.Audit.Progress.MaybeReport(int64(done + committed))
+			}); err != nil {
+				conv.Audit.Progress.Done()
+				return fmt.Errorf("can't create indexes and foreign keys (%d of %d statements applied, run the post-data command to resume): %w", done+committed, total, utils.AnalyzeError(err, dbURI))
+			}
+			done += len(batch)
+			conv.Audit.Progress.MaybeReport(int64(done))
+		}
+	}
+	conv.Audit.Progress.UpdateProgress("Index and foreign key creation complete.", 100, internal.PostDataDDLComplete)
+	conv.Audit.Progress.Done()
+	return nil
+}
+
+// updatePostDataDDL applies stmts in one UpdateDatabaseDdl operation and
+// waits for it, calling report with the number of statements committed so
+// far each time the operation is polled.
+func updatePostDataDDL(ctx context.Context, adminClient *database.DatabaseAdminClient, dbURI string, stmts []string, report func(committed int)) error {
+	logger.Log.Debug("Submitting post-data DDL batch", zap.Strings("stmts", stmts))
+	op, err := adminClient.UpdateDatabaseDdl(ctx, &adminpb.UpdateDatabaseDdlRequest{
+		Database:   dbURI,
+		Statements: stmts,
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		// Poll returns the error of the operation once it is done.
+		if err := op.Poll(ctx); err != nil {
+			return err
+		}
+		if md, err := op.Metadata(); err == nil && md != nil {
+			report(len(md.CommitTimestamps))
+		}
+		if op.Done() {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(PostDataPollInterval):
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conversion
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+
+	sp "cloud.google.com/go/spanner"
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/mysql"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/oracle"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/postgres"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/sqlite"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/sqlserver"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/writer"
+)
+
+// ReplayDeadLetters converts and writes the rows of the dead-letter file
+// name again, with the current mappings of conv, to the database of
+// targetProfile. Rows that are rejected again are written to
+// conv.Audit.DeadLetters.
+//
+// Rows are converted from their source values, as for dump files, which
+// is supported for MySQL, PostgreSQL, SQL Server, Oracle and SQLite. Rows
+// that failed to be written without source values are written with their
+// converted values.
+func ReplayDeadLetters(ctx context.Context, name string, targetProfile profiles.TargetProfile, client *sp.Client, conv *internal.Conv, writeLimit int64) (*writer.BatchWriter, error) {
+	total, err := countDeadLetters(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	config, err := dataWriterConfig(ctx, targetProfile, conv, writeLimit)
+	if err != nil {
+		return nil, err
+	}
+	conv.Audit.Progress = *internal.NewProgress(total, "Replaying rows", internal.Verbose(), false, int(internal.DataWriteInProgress))
+	batchWriter := populateDataConv(ctx, conv, config, client)
+	// Rows are written synchronously by WriteRow, so they keep the
+	// attempts of their dead letter.
+	var attempts int64
+	conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+		batchWriter.RetryRow(table, cols, vals, attempts)
+	})
+	conv.SetSourceDataSink(func(src internal.SourceRow, table string, cols []string, vals []interface{}) {
+		batchWriter.AddSourceRow(table, cols, vals, src, attempts)
+	})
+	err = internal.ReadDeadLetters(bufio.NewReader(f), func(dl internal.DeadLetter) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		attempts = dl.Attempts
+		replayDeadLetter(conv, dl)
+		return nil
+	})
+	batchWriter.Flush()
+	conv.Audit.Progress.Done()
+	return batchWriter, err
+}
+
+// countDeadLetters returns the number of rows of the dead-letter file name.
+func countDeadLetters(name string) (int64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	n := int64(0)
+	err = internal.ReadDeadLetters(f, func(internal.DeadLetter) error {
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// replayDeadLetter converts and writes the row of dl, or writes it to the
+// dead-letter queue again if it is rejected.
+func replayDeadLetter(conv *internal.Conv, dl internal.DeadLetter) {
+	switch dl.Stage {
+	case internal.StageConversion:
+		convertDeadLetter(conv, dl)
+	case internal.StageWrite:
+		// Rows are converted again, so that they are written with the
+		// current mappings of the session.
+		if len(dl.SourceValues) > 0 {
+			convertDeadLetter(conv, dl)
+			return
+		}
+		vals, err := writer.DecodeValues(dl.ConvertedValues)
+		if err == nil && len(vals) != len(dl.SpannerColumns) {
+			err = fmt.Errorf("found %d values for %d columns", len(vals), len(dl.SpannerColumns))
+		}
+		if err != nil {
+			rejectDeadLetter(conv, dl, err)
+			return
+		}
+		conv.WriteRow(dl.Table, dl.SpannerTable, dl.SpannerColumns, vals)
+	default:
+		rejectDeadLetter(conv, dl, fmt.Errorf("unknown stage %q", dl.Stage))
+	}
+}
+
+// convertDeadLetter converts the source values of dl with the mappings of
+// conv, and writes the converted row.
+func convertDeadLetter(conv *internal.Conv, dl internal.DeadLetter) {
+	tableId, err := internal.GetTableIdFromSrcName(conv.SrcSchema, dl.Table)
+	if err != nil {
+		rejectDeadLetter(conv, dl, fmt.Errorf("source table %s not found in the session", dl.Table))
+		return
+	}
+	srcSchema, spSchema := conv.SrcSchema[tableId], conv.SpSchema[tableId]
+	var colIds []string
+	for _, col := range dl.SourceColumns {
+		colId, err := internal.GetColIdFromSrcName(srcSchema.ColDefs, col)
+		if err != nil {
+			rejectDeadLetter(conv, dl, fmt.Errorf("source column %s of table %s not found in the session", col, dl.Table))
+			return
+		}
+		colIds = append(colIds, colId)
+	}
+	var process func()
+	switch dl.Driver {
+	case constants.MYSQL, constants.MYSQLDUMP:
+		process = func() { mysql.ProcessDataRow(conv, tableId, colIds, srcSchema, spSchema, dl.SourceValues) }
+	case constants.POSTGRES, constants.PGDUMP:
+		process = func() { postgres.ProcessDataRow(conv, tableId, colIds, dl.SourceValues) }
+	case constants.SQLSERVER, constants.SQLSERVERDUMP:
+		process = func() { sqlserver.ProcessDataRow(conv, tableId, colIds, srcSchema, spSchema, dl.SourceValues) }
+	case constants.ORACLE:
+		process = func() { oracle.ProcessDataRow(conv, tableId, colIds, srcSchema, spSchema, dl.SourceValues) }
+	case constants.SQLITE:
+		process = func() { sqlite.ProcessDataRow(conv, tableId, colIds, srcSchema, spSchema, dl.SourceValues) }
+	default:
+		rejectDeadLetter(conv, dl, fmt.Errorf("converting replayed rows isn't supported for %s", dl.Driver))
+		return
+	}
+	// Rows rejected again by process are counted as further attempts.
+	conv.Audit.DeadLetters.Replaying(dl.Driver, dl.Attempts)
+	process()
+	conv.Audit.DeadLetters.Replaying(dl.Driver, 0)
+}
+
+// rejectDeadLetter writes dl, which couldn't be replayed because of err,
+// to the dead-letter queue again.
+func rejectDeadLetter(conv *internal.Conv, dl internal.DeadLetter, err error) {
+	conv.StatsAddBadRow(dl.Table, conv.DataMode())
+	dl.Error = err.Error()
+	conv.Audit.DeadLetters.Add(dl)
+}
@@ -0,0 +1,340 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// Kinds of changes reported by DiffConv.
+const (
+	DiffAdded    = "added"
+	DiffDropped  = "dropped"
+	DiffModified = "modified"
+)
+
+// ConvDiff describes how the Spanner schema and rules of two versions of a
+// schema conversion differ.
+type ConvDiff struct {
+	Tables []TableDiff
+	Rules  []RuleDiff
+	// DDL statements that change a database created with the old
+	// schema into one with the new schema.
+	DDL []string
+}
+
+// TableDiff describes how a table differs between two versions. Fields
+// that didn't change are left empty, as are the columns, indexes and
+// foreign keys of added and dropped tables.
+type TableDiff struct {
+	TableId       string
+	Name          string // Name in the new version, or in the old one if dropped.
+	Change        string
+	OldName       string `json:",omitempty"`
+	OldPrimaryKey string `json:",omitempty"`
+	NewPrimaryKey string `json:",omitempty"`
+	OldParent     string `json:",omitempty"`
+	NewParent     string `json:",omitempty"`
+	Columns       []ColumnDiff
+	Indexes       []ObjectDiff
+	ForeignKeys   []ObjectDiff
+}
+
+// ColumnDiff describes how a column differs between two versions. SrcType
+// is the source type of the column, so that changes of OldType to NewType
+// describe changes of the type mapping.
+type ColumnDiff struct {
+	ColId   string
+	Name    string
+	Change  string
+	OldName string `json:",omitempty"`
+	SrcType string `json:",omitempty"`
+	OldType string `json:",omitempty"`
+	NewType string `json:",omitempty"`
+}
+
+// ObjectDiff describes how an index or foreign key differs between two
+// versions, by its DDL in each.
+type ObjectDiff struct {
+	Id     string
+	Name   string
+	Change string
+	OldDDL string `json:",omitempty"`
+	NewDDL string `json:",omitempty"`
+}
+
+// RuleDiff describes how a rule differs between two versions.
+type RuleDiff struct {
+	Id     string
+	Name   string
+	Change string
+}
+
+// DiffConv returns how the Spanner schema and rules of conv differ from
+// those of old. Tables, columns, indexes, foreign keys and rules are
+// matched by id.
+func DiffConv(old, conv *Conv) ConvDiff {
+	c := ddl.Config{SpDialect: conv.SpDialect}
+	var d ConvDiff
+	ids := make(map[string]bool)
+	for id := range old.SpSchema {
+		ids[id] = true
+	}
+	for id := range conv.SpSchema {
+		ids[id] = true
+	}
+	for _, id := range sortedKeys(ids) {
+		if td, ok := diffTable(old, conv, id, c); ok {
+			d.Tables = append(d.Tables, td)
+		}
+	}
+	d.Rules = diffRules(old.Rules, conv.Rules)
+	d.DDL = ddl.GetDDLDelta(old.SpSchema, conv.SpSchema, c)
+	return d
+}
+
+func diffTable(old, conv *Conv, id string, c ddl.Config) (TableDiff, bool) {
+	ot, inOld := old.SpSchema[id]
+	nt, inNew := conv.SpSchema[id]
+	srcTable := conv.SrcSchema[id]
+	td := TableDiff{TableId: id, Name: nt.Name}
+	switch {
+	case !inOld:
+		td.Change = DiffAdded
+		return td, true
+	case !inNew:
+		td.Change, td.Name = DiffDropped, ot.Name
+		return td, true
+	default:
+		td.Change = DiffModified
+		if ot.Name != nt.Name {
+			td.OldName = ot.Name
+		}
+		if o, n := printPrimaryKey(ot, c), printPrimaryKey(nt, c); o != n {
+			td.OldPrimaryKey, td.NewPrimaryKey = o, n
+		}
+		if o, n := old.SpSchema[ot.ParentId].Name, conv.SpSchema[nt.ParentId].Name; o != n {
+			td.OldParent, td.NewParent = o, n
+		}
+	}
+	colIds := make(map[string]bool)
+	for colId := range ot.ColDefs {
+		colIds[colId] = true
+	}
+	for colId := range nt.ColDefs {
+		colIds[colId] = true
+	}
+	for _, colId := range sortedKeys(colIds) {
+		ocd, inOld := ot.ColDefs[colId]
+		ncd, inNew := nt.ColDefs[colId]
+		cd := ColumnDiff{ColId: colId, Name: ncd.Name, SrcType: srcTable.ColDefs[colId].Type.Print()}
+		if inOld {
+			cd.OldType = printColumnType(ocd, c)
+		}
+		if inNew {
+			cd.NewType = printColumnType(ncd, c)
+		}
+		switch {
+		case !inOld:
+			cd.Change = DiffAdded
+		case !inNew:
+			cd.Change, cd.Name = DiffDropped, ocd.Name
+		case ocd.Name != ncd.Name || cd.OldType != cd.NewType:
+			cd.Change = DiffModified
+			if ocd.Name != ncd.Name {
+				cd.OldName = ocd.Name
+			}
+			if cd.OldType == cd.NewType {
+				cd.OldType, cd.NewType = "", ""
+			}
+		default:
+			continue
+		}
+		td.Columns = append(td.Columns, cd)
+	}
+	oldIndexes, newIndexes := make(map[string]string), make(map[string]string)
+	names := make(map[string]string)
+	for _, idx := range ot.Indexes {
+		oldIndexes[idx.Id], names[idx.Id] = idx.PrintCreateIndex(ot, c), idx.Name
+	}
+	for _, idx := range nt.Indexes {
+		newIndexes[idx.Id], names[idx.Id] = idx.PrintCreateIndex(nt, c), idx.Name
+	}
+	td.Indexes = diffObjects(oldIndexes, newIndexes, names)
+	oldFks, newFks := make(map[string]string), make(map[string]string)
+	names = make(map[string]string)
+	for _, fk := range ot.ForeignKeys {
+		oldFks[fk.Id], names[fk.Id] = fk.PrintForeignKeyAlterTable(old.SpSchema, c, id), fk.Name
+	}
+	for _, fk := range nt.ForeignKeys {
+		newFks[fk.Id], names[fk.Id] = fk.PrintForeignKeyAlterTable(conv.SpSchema, c, id), fk.Name
+	}
+	td.ForeignKeys = diffObjects(oldFks, newFks, names)
+	if td.OldName == "" && td.OldPrimaryKey == "" && td.OldParent == "" && td.NewParent == "" &&
+		len(td.Columns) == 0 && len(td.Indexes) == 0 && len(td.ForeignKeys) == 0 {
+		return td, false
+	}
+	return td, true
+}
+
+// diffObjects compares objects by their DDL, keyed by object id.
+func diffObjects(old, new, names map[string]string) []ObjectDiff {
+	ids := make(map[string]bool)
+	for id := range old {
+		ids[id] = true
+	}
+	for id := range new {
+		ids[id] = true
+	}
+	var l []ObjectDiff
+	for _, id := range sortedKeys(ids) {
+		o, inOld := old[id]
+		n, inNew := new[id]
+		od := ObjectDiff{Id: id, Name: names[id], OldDDL: o, NewDDL: n}
+		switch {
+		case !inOld:
+			od.Change = DiffAdded
+		case !inNew:
+			od.Change = DiffDropped
+		case o != n:
+			od.Change = DiffModified
+		default:
+			continue
+		}
+		l = append(l, od)
+	}
+	return l
+}
+
+func diffRules(old, new []Rule) []RuleDiff {
+	oldRules := make(map[string]string)
+	for i := range old {
+		b, _ := json.Marshal(&old[i])
+		oldRules[old[i].Id] = string(b)
+	}
+	var l []RuleDiff
+	newRules := make(map[string]bool)
+	for i := range new {
+		r := &new[i]
+		newRules[r.Id] = true
+		b, _ := json.Marshal(r)
+		if o, ok := oldRules[r.Id]; !ok {
+			l = append(l, RuleDiff{Id: r.Id, Name: r.Name, Change: DiffAdded})
+		} else if o != string(b) {
+			l = append(l, RuleDiff{Id: r.Id, Name: r.Name, Change: DiffModified})
+		}
+	}
+	for i := range old {
+		if !newRules[old[i].Id] {
+			l = append(l, RuleDiff{Id: old[i].Id, Name: old[i].Name, Change: DiffDropped})
+		}
+	}
+	return l
+}
+
+// WriteConvDiff writes d in human-readable form to w.
+func WriteConvDiff(w io.Writer, d ConvDiff) {
+	if len(d.Tables) == 0 && len(d.Rules) == 0 {
+		fmt.Fprintln(w, "No changes.")
+		return
+	}
+	for _, td := range d.Tables {
+		fmt.Fprintf(w, "Table %s: %s\n", td.Name, td.Change)
+		if td.OldName != "" {
+			fmt.Fprintf(w, "  renamed from %s\n", td.OldName)
+		}
+		if td.OldPrimaryKey != "" || td.NewPrimaryKey != "" {
+			fmt.Fprintf(w, "  primary key: (%s) -> (%s)\n", td.OldPrimaryKey, td.NewPrimaryKey)
+		}
+		if td.OldParent != "" || td.NewParent != "" {
+			fmt.Fprintf(w, "  interleaved in: %s -> %s\n", orNone(td.OldParent), orNone(td.NewParent))
+		}
+		for _, cd := range td.Columns {
+			var details []string
+			if cd.OldName != "" {
+				details = append(details, "renamed from "+cd.OldName)
+			}
+			switch {
+			case cd.Change == DiffAdded:
+				details = append(details, cd.NewType)
+			case cd.Change == DiffDropped:
+				details = append(details, cd.OldType)
+			case cd.OldType != "":
+				details = append(details, fmt.Sprintf("%s -> %s", cd.OldType, cd.NewType))
+			}
+			if cd.SrcType != "" && cd.Change != DiffDropped {
+				details = append(details, "source type "+cd.SrcType)
+			}
+			fmt.Fprintf(w, "  column %s: %s (%s)\n", cd.Name, cd.Change, strings.Join(details, ", "))
+		}
+		for _, od := range td.Indexes {
+			fmt.Fprintf(w, "  index %s: %s\n", od.Name, od.Change)
+		}
+		for _, od := range td.ForeignKeys {
+			fmt.Fprintf(w, "  foreign key %s: %s\n", od.Name, od.Change)
+		}
+	}
+	for _, rd := range d.Rules {
+		fmt.Fprintf(w, "Rule %s: %s\n", rd.Name, rd.Change)
+	}
+	if len(d.DDL) > 0 {
+		fmt.Fprintln(w, "\nSpanner DDL:")
+		for _, s := range d.DDL {
+			fmt.Fprintf(w, "%s;\n", s)
+		}
+	}
+}
+
+func printPrimaryKey(ct ddl.CreateTable, c ddl.Config) string {
+	pks := append([]ddl.IndexKey{}, ct.PrimaryKeys...)
+	sort.Slice(pks, func(i, j int) bool { return pks[i].Order < pks[j].Order })
+	var keys []string
+	for _, pk := range pks {
+		keys = append(keys, pk.PrintPkOrIndexKey(ct, c))
+	}
+	return strings.Join(keys, ", ")
+}
+
+// printColumnType returns the Spanner type of cd, with its constraints.
+func printColumnType(cd ddl.ColumnDef, c ddl.Config) string {
+	s, _ := cd.PrintColumnDef(c)
+	s = strings.TrimPrefix(s, cd.Name+" ")
+	for _, check := range cd.PrintChecks(c) {
+		s += " " + check
+	}
+	return s
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+func sortedKeys(m map[string]bool) []string {
+	var l []string
+	for k := range m {
+		l = append(l, k)
+	}
+	sort.Strings(l)
+	return l
+}
@@ -0,0 +1,136 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+func makeDiffConv() *Conv {
+	conv := MakeConv()
+	conv.SrcSchema["t1"] = schema.Table{
+		Name: "singers",
+		Id:   "t1",
+		ColDefs: map[string]schema.Column{
+			"c1": {Name: "id", Id: "c1", Type: schema.Type{Name: "int"}},
+			"c2": {Name: "name", Id: "c2", Type: schema.Type{Name: "varchar", Mods: []int64{20}}},
+		},
+	}
+	conv.SpSchema["t1"] = ddl.CreateTable{
+		Name:   "singers",
+		Id:     "t1",
+		ColIds: []string{"c1", "c2"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"c1": {Name: "id", Id: "c1", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+			"c2": {Name: "name", Id: "c2", T: ddl.Type{Name: ddl.String, Len: 20}},
+		},
+		PrimaryKeys: []ddl.IndexKey{{ColId: "c1", Order: 1}},
+	}
+	conv.SpSchema["t2"] = ddl.CreateTable{
+		Name:        "labels",
+		Id:          "t2",
+		ColIds:      []string{"c3"},
+		ColDefs:     map[string]ddl.ColumnDef{"c3": {Name: "id", Id: "c3", T: ddl.Type{Name: ddl.Int64}, NotNull: true}},
+		PrimaryKeys: []ddl.IndexKey{{ColId: "c3", Order: 1}},
+	}
+	conv.Rules = []Rule{{Id: "r1", Name: "rule1", Enabled: true}, {Id: "r2", Name: "rule2", Enabled: true}}
+	return conv
+}
+
+func TestDiffConv(t *testing.T) {
+	old := makeDiffConv()
+	assert.Equal(t, ConvDiff{}, DiffConv(old, makeDiffConv()))
+
+	conv := makeDiffConv()
+	t1 := conv.SpSchema["t1"]
+	t1.ColDefs["c2"] = ddl.ColumnDef{Name: "full_name", Id: "c2", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}}
+	t1.Indexes = []ddl.CreateIndex{{Name: "singers_name", TableId: "t1", Id: "i1", Keys: []ddl.IndexKey{{ColId: "c2", Order: 1}}}}
+	conv.SpSchema["t1"] = t1
+	delete(conv.SpSchema, "t2")
+	conv.Rules = []Rule{{Id: "r1", Name: "rule1", Enabled: false}, {Id: "r3", Name: "rule3"}}
+
+	expected := ConvDiff{
+		Tables: []TableDiff{
+			{
+				TableId: "t1",
+				Name:    "singers",
+				Change:  DiffModified,
+				Columns: []ColumnDiff{
+					{ColId: "c2", Name: "full_name", Change: DiffModified, OldName: "name", SrcType: "varchar(20)", OldType: "STRING(20)", NewType: "STRING(MAX)"},
+				},
+				Indexes: []ObjectDiff{
+					{Id: "i1", Name: "singers_name", Change: DiffAdded, NewDDL: "CREATE INDEX singers_name ON singers (full_name)"},
+				},
+			},
+			{TableId: "t2", Name: "labels", Change: DiffDropped},
+		},
+		Rules: []RuleDiff{
+			{Id: "r1", Name: "rule1", Change: DiffModified},
+			{Id: "r3", Name: "rule3", Change: DiffAdded},
+			{Id: "r2", Name: "rule2", Change: DiffDropped},
+		},
+		DDL: []string{
+			"ALTER TABLE singers DROP COLUMN name",
+			"DROP TABLE labels",
+			"ALTER TABLE singers ADD COLUMN full_name STRING(MAX)",
+			"CREATE INDEX singers_name ON singers (full_name)",
+		},
+	}
+	d := DiffConv(old, conv)
+	assert.Equal(t, expected, d)
+
+	var b bytes.Buffer
+	WriteConvDiff(&b, d)
+	assert.Equal(t, `Table singers: modified
+  column full_name: modified (renamed from name, STRING(20) -> STRING(MAX), source type varchar(20))
+  index singers_name: added
+Table labels: dropped
+Rule rule1: modified
+Rule rule3: added
+Rule rule2: dropped
+
+Spanner DDL:
+ALTER TABLE singers DROP COLUMN name;
+DROP TABLE labels;
+ALTER TABLE singers ADD COLUMN full_name STRING(MAX);
+CREATE INDEX singers_name ON singers (full_name);
+`, b.String())
+}
+
+func TestDiffConvInterleave(t *testing.T) {
+	old := makeDiffConv()
+	conv := makeDiffConv()
+	t2 := conv.SpSchema["t2"]
+	t2.ParentId = "t1"
+	t2.PrimaryKeys = []ddl.IndexKey{{ColId: "c3", Order: 1, Desc: true}}
+	conv.SpSchema["t2"] = t2
+
+	d := DiffConv(old, conv)
+	assert.Equal(t, []TableDiff{{TableId: "t2", Name: "labels", Change: DiffModified, OldPrimaryKey: "id", NewPrimaryKey: "id DESC", NewParent: "singers"}}, d.Tables)
+	assert.Equal(t, []string{
+		"DROP TABLE labels",
+		"CREATE TABLE labels (\n\tid INT64 NOT NULL,\n) PRIMARY KEY (id DESC),\nINTERLEAVE IN PARENT singers",
+	}, d.DDL)
+
+	var b bytes.Buffer
+	WriteConvDiff(&b, d)
+	assert.Contains(t, b.String(), "  primary key: (id) -> (id DESC)\n  interleaved in: (none) -> singers\n")
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/cloudspannerecosystem/harbourbridge/common/telemetry"
 	"github.com/cloudspannerecosystem/harbourbridge/logger"
 	"github.com/cloudspannerecosystem/harbourbridge/proto/migration"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
@@ -37,6 +38,7 @@ type Conv struct {
 	ToSource       map[string]NameAndCols              `json:"-"` // Maps from Spanner table name to source-DB table name and column mapping.
 	UsedNames      map[string]bool                     `json:"-"` // Map storing the names that are already assigned to tables, indices or foreign key contraints.
 	dataSink       func(table string, cols []string, values []interface{})
+	sourceSink     func(src SourceRow, table string, cols []string, values []interface{})
 	DataFlush      func()              `json:"-"` // Data flush is used to flush out remaining writes and wait for them to complete.
 	Location       *time.Location      // Timezone (for timestamp conversion).
 	sampleBadRows  rowSamples          // Rows that generated errors during conversion.
@@ -46,6 +48,10 @@ type Conv struct {
 	UniquePKey     map[string][]string // Maps Spanner table name to unique column name being used as primary key (if needed).
 	Audit          Audit               `json:"-"` // Stores the audit information for the database conversion
 	Rules          []Rule              // Stores applied rules during schema conversion
+	// Maps table/col ids to Spanner types suggested by profiling the data of
+	// the source database (nil unless profiled).
+	TypeSuggestions map[string]map[string]TypeSuggestion
+	EditHistory     EditHistory // Schema edits made in the web UI, which can be undone.
 }
 
 type mode int
@@ -63,6 +69,13 @@ type SyntheticPKey struct {
 	Sequence int64
 }
 
+// TypeSuggestion is a Spanner type suggested for a column by profiling its
+// data, because the data doesn't need the type the column is mapped to.
+type TypeSuggestion struct {
+	T      ddl.Type
+	Reason string // e.g. "all 100 sampled values are integers between 1 and 42"
+}
+
 // SchemaIssue specifies a schema conversion issue.
 type SchemaIssue int
 
@@ -96,6 +109,10 @@ const (
 	InterleavedAddColumn
 	IllegalName
 	InterleavedRenameColumn
+	CheckConstraint
+	SpatialGeoJSON
+	NarrowerType
+	AllowedValues
 )
 
 // NameAndCols contains the name of a table and its columns.
@@ -137,6 +154,9 @@ type stats struct {
 	Statement  map[string]*statementStat // Count of processed statements, broken down by statement type.
 	Unexpected map[string]int64          // Count of unexpected conditions, broken down by condition description.
 	Reparsed   int64                     // Count of times we re-parse dump data looking for end-of-statement.
+
+	TruncatedValues map[string]map[string]int64 // Count of values truncated to fit Spanner's size limits, broken down by Spanner table and column.
+	OffloadedValues map[string]map[string]int64 // Count of values offloaded since they exceeded Spanner's size limits, broken down by Spanner table and column.
 }
 
 type statementStat struct {
@@ -156,9 +176,23 @@ type Audit struct {
 	DryRun                   bool                                   `json:"-"` // Flag to identify if the migration is a dry run.
 	StreamingStats           streamingStats                         `json:"-"` // Stores information related to streaming migration process.
 	Progress                 Progress                               `json:"-"` // Stores information related to progress of the migration progress
+	MigrationProgress        *MigrationProgress                     `json:"-"` // If set, tracks the phase of the migration and the rows of each Spanner table.
+	WriteStats               *WriteStats                            `json:"-"` // Statistics of the writes to Spanner, if data was written.
+	CommitStats              bool                                   `json:"-"` // If set, batches are committed with the commit stats of Spanner.
+	DeadLetters              *DeadLetterQueue                       `json:"-"` // If set, rows rejected by the migration are written to it.
 	SkipMetricsPopulation    bool                                   `json:"-"` // Flag to identify if outgoing metrics metadata needs to skipped
 }
 
+// WriteStats are statistics of the writes of data to Spanner.
+type WriteStats struct {
+	Commits     int64         // Successful commits.
+	Rows        int64         // Rows written.
+	Mutations   int64         // Mutations counted by Spanner in the commit stats, if requested.
+	WriteTime   time.Duration // Total time of the writes.
+	Elapsed     time.Duration // From the start of the first write to the end of the last one.
+	Concurrency int64         // Limit on in-progress writes at the end.
+}
+
 // Stores information related to the streaming migration process.
 type streamingStats struct {
 	Streaming        bool                        // Flag for confirmation of streaming migration.
@@ -227,6 +261,21 @@ func (conv *Conv) SetDataSink(ds func(table string, cols []string, values []inte
 	conv.dataSink = ds
 }
 
+// SourceRow is a row of a source table, as strings, from which a Spanner
+// row was converted.
+type SourceRow struct {
+	Table   string
+	Columns []string
+	Values  []string
+}
+
+// SetSourceDataSink configures conv to write the rows written with
+// WriteSourceRow to ds, along with the source row they were converted
+// from. Without it, they are written to the data sink.
+func (conv *Conv) SetSourceDataSink(ds func(src SourceRow, table string, cols []string, values []interface{})) {
+	conv.sourceSink = ds
+}
+
 // Note on modes.
 // We process the dump output twice. In the first pass (schema mode) we
 // build the schema, and the second pass (data mode) we write data to
@@ -249,6 +298,17 @@ func (conv *Conv) SetDataMode() {
 	conv.mode = dataOnly
 }
 
+// WriteSourceRow is like WriteRow, for a row converted from the source row
+// src, which is passed to the source data sink if there is one.
+func (conv *Conv) WriteSourceRow(src SourceRow, spTable string, spCols []string, spVals []interface{}) {
+	if conv.Audit.DryRun || conv.sourceSink == nil {
+		conv.WriteRow(src.Table, spTable, spCols, spVals)
+		return
+	}
+	conv.sourceSink(src, spTable, spCols, spVals)
+	conv.statsAddGoodRow(src.Table, conv.DataMode())
+}
+
 // WriteRow calls dataSink and updates row stats.
 func (conv *Conv) WriteRow(srcTable, spTable string, spCols []string, spVals []interface{}) {
 	if conv.Audit.DryRun {
@@ -310,8 +370,14 @@ func (conv *Conv) Unexpecteds() int64 {
 }
 
 // CollectBadRow updates the list of bad rows, while respecting
-// the byte limit for bad rows.
-func (conv *Conv) CollectBadRow(srcTable string, srcCols, vals []string) {
+// the byte limit for bad rows, and writes the row that failed conversion
+// with err to the dead-letter queue.
+func (conv *Conv) CollectBadRow(srcTable string, srcCols, vals []string, err error) {
+	dl := DeadLetter{Stage: StageConversion, Table: srcTable, SourceColumns: srcCols, SourceValues: vals, Attempts: 1}
+	if err != nil {
+		dl.Error = err.Error()
+	}
+	conv.Audit.DeadLetters.Add(dl)
 	r := &row{table: srcTable, cols: srcCols, vals: vals}
 	bytes := byteSize(r)
 	// Cap storage used by badRows. Keep at least one bad row.
@@ -424,6 +490,7 @@ func (conv *Conv) Unexpected(u string) {
 func (conv *Conv) StatsAddRow(srcTable string, b bool) {
 	if b {
 		conv.Stats.Rows[srcTable]++
+		telemetry.AddRowsRead(srcTable, 1)
 	}
 }
 
@@ -432,6 +499,7 @@ func (conv *Conv) StatsAddRow(srcTable string, b bool) {
 func (conv *Conv) statsAddGoodRow(srcTable string, b bool) {
 	if b {
 		conv.Stats.GoodRows[srcTable]++
+		telemetry.AddRowsConverted(srcTable, 1)
 	}
 }
 
@@ -440,7 +508,26 @@ func (conv *Conv) statsAddGoodRow(srcTable string, b bool) {
 func (conv *Conv) StatsAddBadRow(srcTable string, b bool) {
 	if b {
 		conv.Stats.BadRows[srcTable]++
+		conv.Audit.MigrationProgress.AddBadRows(srcTable, 1)
+		telemetry.AddRowsBad(srcTable, 1)
+	}
+}
+
+// StatsAddOversizedValue records that a value of column 'col' of Spanner
+// table 'spTable' exceeded Spanner's size limits, and was truncated if
+// truncated is true, or offloaded otherwise.
+func (conv *Conv) StatsAddOversizedValue(spTable, col string, truncated bool) {
+	m := &conv.Stats.OffloadedValues
+	if truncated {
+		m = &conv.Stats.TruncatedValues
+	}
+	if *m == nil {
+		*m = make(map[string]map[string]int64)
+	}
+	if (*m)[spTable] == nil {
+		(*m)[spTable] = make(map[string]int64)
 	}
+	(*m)[spTable][col]++
 }
 
 func (conv *Conv) getStatementStat(s string) *statementStat {
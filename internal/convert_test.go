@@ -15,6 +15,7 @@
 package internal
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -79,6 +80,24 @@ func TestUnexpecteds(t *testing.T) {
 	assert.Equal(t, int64(1), conv.Unexpecteds())
 }
 
+func TestWriteSourceRow(t *testing.T) {
+	conv := MakeConv()
+	conv.SetDataMode()
+	var rows []string
+	conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+		rows = append(rows, fmt.Sprintf("%s %v", table, vals))
+	})
+	src := SourceRow{Table: "src", Columns: []string{"a"}, Values: []string{"1"}}
+	// Without a source data sink, rows are written to the data sink.
+	conv.WriteSourceRow(src, "t", []string{"a"}, []interface{}{int64(1)})
+	conv.SetSourceDataSink(func(src SourceRow, table string, cols []string, vals []interface{}) {
+		rows = append(rows, fmt.Sprintf("%s %v from %s %v", table, vals, src.Table, src.Values))
+	})
+	conv.WriteSourceRow(src, "t", []string{"a"}, []interface{}{int64(1)})
+	assert.Equal(t, []string{"t [1]", "t [1] from src [1]"}, rows)
+	assert.Equal(t, int64(2), conv.Stats.GoodRows["src"])
+}
+
 func TestGetBadRows(t *testing.T) {
 	conv := MakeConv()
 	row1 := row{"table", []string{"col1", "col2"}, []string{"a", "1"}}
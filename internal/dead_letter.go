@@ -0,0 +1,177 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Stages of a migration at which a row can be rejected.
+const (
+	StageConversion = "conversion" // The row couldn't be converted to a Spanner row.
+	StageWrite      = "write"      // The converted row couldn't be written to Spanner.
+)
+
+// DeadLetter is a row rejected by a migration, as written to a dead-letter
+// file (one JSON object per line) so that it can be replayed.
+type DeadLetter struct {
+	Stage  string `json:"stage"`
+	Driver string `json:"driver"` // Driver of the source database.
+	Table  string `json:"table"`  // Source table.
+	// Source columns and values of rows rejected at StageConversion, as
+	// strings, and of rows rejected at StageWrite that were converted
+	// from them.
+	SourceColumns []string `json:"sourceColumns,omitempty"`
+	SourceValues  []string `json:"sourceValues,omitempty"`
+	// Spanner table, columns and values of rows rejected at StageWrite.
+	// Each value is encoded as a JSON object with the type and value of
+	// Spanner's wire format, which is lossless.
+	SpannerTable    string            `json:"spannerTable,omitempty"`
+	SpannerColumns  []string          `json:"spannerColumns,omitempty"`
+	ConvertedValues []json.RawMessage `json:"convertedValues,omitempty"`
+	Error           string            `json:"error"`
+	// Attempts to convert or write the row, including those of earlier
+	// runs when the row is replayed.
+	Attempts int64 `json:"attempts"`
+}
+
+// DeadLetterQueue writes the rows rejected by a migration to a dead-letter
+// file. The file is only created once a row is rejected. Its methods are
+// safe for concurrent use, and do nothing on a nil *DeadLetterQueue.
+type DeadLetterQueue struct {
+	mu     sync.Mutex
+	name   string
+	driver string
+	f      *os.File
+	w      *bufio.Writer
+	count  int64
+	prior  int64 // Attempts of the row being replayed.
+	err    error // First error writing the file.
+}
+
+// NewDeadLetterQueue returns a queue writing the rows rejected by a
+// migration from a source database of driver to the file name.
+func NewDeadLetterQueue(name, driver string) *DeadLetterQueue {
+	return &DeadLetterQueue{name: name, driver: driver}
+}
+
+// Name returns the name of the dead-letter file.
+func (q *DeadLetterQueue) Name() string {
+	if q == nil {
+		return ""
+	}
+	return q.name
+}
+
+// Add writes dl to the dead-letter file, setting its driver if it isn't
+// set. Rows rejected at StageConversion while a row is replayed are
+// counted as further attempts of that row.
+func (q *DeadLetterQueue) Add(dl DeadLetter) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.err != nil {
+		return
+	}
+	if q.f == nil {
+		if q.f, q.err = os.Create(q.name); q.err != nil {
+			return
+		}
+		q.w = bufio.NewWriter(q.f)
+	}
+	if dl.Driver == "" {
+		dl.Driver = q.driver
+	}
+	if dl.Stage == StageConversion {
+		dl.Attempts += q.prior
+	}
+	b, err := json.Marshal(dl)
+	if err != nil {
+		q.err = err
+		return
+	}
+	if _, q.err = q.w.Write(append(b, '\n')); q.err == nil {
+		q.count++
+	}
+}
+
+// Replaying sets the source driver and attempts of the row being replayed,
+// which must be converted in the same go routine.
+func (q *DeadLetterQueue) Replaying(driver string, attempts int64) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.driver, q.prior = driver, attempts
+}
+
+// Count returns the number of rows written to the dead-letter file.
+func (q *DeadLetterQueue) Count() int64 {
+	if q == nil {
+		return 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.count
+}
+
+// Close flushes and closes the dead-letter file, and returns the first
+// error writing it.
+func (q *DeadLetterQueue) Close() error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.f == nil {
+		return q.err
+	}
+	if err := q.w.Flush(); q.err == nil {
+		q.err = err
+	}
+	if err := q.f.Close(); q.err == nil {
+		q.err = err
+	}
+	q.f = nil
+	return q.err
+}
+
+// ReadDeadLetters calls f with each dead letter read from r, stopping at
+// the first error.
+func ReadDeadLetters(r io.Reader, f func(DeadLetter) error) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 128<<20)
+	for line := 1; s.Scan(); line++ {
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		var dl DeadLetter
+		if err := json.Unmarshal(s.Bytes(), &dl); err != nil {
+			return fmt.Errorf("can't parse dead letter at line %d: %v", line, err)
+		}
+		if err := f(dl); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
@@ -0,0 +1,81 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadLetterQueue(t *testing.T) {
+	var q *DeadLetterQueue
+	q.Add(DeadLetter{Table: "t"}) // A nil DeadLetterQueue ignores rows.
+	assert.Equal(t, int64(0), q.Count())
+	assert.Nil(t, q.Close())
+
+	name := filepath.Join(t.TempDir(), "db.dead_letters.jsonl")
+	q = NewDeadLetterQueue(name, "mysql")
+	assert.Nil(t, q.Close())
+	_, err := os.Stat(name)
+	assert.True(t, os.IsNotExist(err), "the file is only created once a row is rejected")
+
+	q = NewDeadLetterQueue(name, "mysql")
+	conv := MakeConv()
+	conv.Audit.DeadLetters = q
+	conv.CollectBadRow("src", []string{"a", "b"}, []string{"1", "x"}, errors.New("can't convert x"))
+	q.Add(DeadLetter{
+		Stage:           StageWrite,
+		Table:           "src",
+		SpannerTable:    "t",
+		SpannerColumns:  []string{"a"},
+		ConvertedValues: []json.RawMessage{json.RawMessage(`{"type":{"code":"INT64"},"value":"1"}`)},
+		Error:           "already exists",
+		Attempts:        3,
+	})
+	// Rows rejected again while a row is replayed add up its attempts.
+	q.Replaying("postgres", 2)
+	conv.CollectBadRow("src", []string{"a"}, []string{"y"}, errors.New("can't convert y"))
+	q.Replaying("postgres", 0)
+	assert.Equal(t, int64(3), q.Count())
+	assert.Nil(t, q.Close())
+
+	f, err := os.Open(name)
+	assert.Nil(t, err)
+	defer f.Close()
+	var got []DeadLetter
+	assert.Nil(t, ReadDeadLetters(f, func(dl DeadLetter) error {
+		got = append(got, dl)
+		return nil
+	}))
+	assert.Equal(t, []DeadLetter{
+		{Stage: StageConversion, Driver: "mysql", Table: "src", SourceColumns: []string{"a", "b"}, SourceValues: []string{"1", "x"}, Error: "can't convert x", Attempts: 1},
+		{Stage: StageWrite, Driver: "mysql", Table: "src", SpannerTable: "t", SpannerColumns: []string{"a"},
+			ConvertedValues: []json.RawMessage{json.RawMessage(`{"type":{"code":"INT64"},"value":"1"}`)}, Error: "already exists", Attempts: 3},
+		{Stage: StageConversion, Driver: "postgres", Table: "src", SourceColumns: []string{"a"}, SourceValues: []string{"y"}, Error: "can't convert y", Attempts: 3},
+	}, got)
+}
+
+func TestReadDeadLettersError(t *testing.T) {
+	err := ReadDeadLetters(strings.NewReader("{\"table\":\"t\"}\n\nnot json\n"), func(DeadLetter) error { return nil })
+	assert.EqualError(t, err, "can't parse dead letter at line 3: invalid character 'o' in literal null (expecting 'u')")
+	err = ReadDeadLetters(strings.NewReader("{\"table\":\"t\"}\n"), func(DeadLetter) error { return errors.New("stop") })
+	assert.EqualError(t, err, "stop")
+}
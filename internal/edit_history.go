@@ -0,0 +1,256 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// maxEdits is the number of edits kept in the edit history of a Conv.
+// Older edits are dropped and can't be undone.
+const maxEdits = 100
+
+// EditHistory is the journal of the schema edits made to a Conv, e.g. in
+// the web UI. Edits[:Applied] are applied, and the rest have been undone
+// and can be redone. A new edit drops the edits that can be redone.
+type EditHistory struct {
+	Edits   []Edit
+	Applied int
+}
+
+// Edit is a reversible schema edit. It stores the state of each table the
+// edit changed before and after the edit, and of the rules if they changed,
+// so that it can be undone and redone.
+type Edit struct {
+	Id          int
+	Description string
+	Time        time.Time
+	Tables      []TableEdit
+	RulesBefore json.RawMessage `json:",omitempty"`
+	RulesAfter  json.RawMessage `json:",omitempty"`
+}
+
+// TableEdit is the state of a table before and after an edit. The state
+// is null if the table has no Spanner schema or issues.
+type TableEdit struct {
+	TableId string
+	Before  json.RawMessage
+	After   json.RawMessage
+}
+
+// tableState is the part of a Conv that schema edits change, for one table.
+type tableState struct {
+	SpSchema      *ddl.CreateTable         `json:",omitempty"`
+	SyntheticPKey *SyntheticPKey           `json:",omitempty"`
+	UniquePKey    []string                 `json:",omitempty"`
+	SchemaIssues  map[string][]SchemaIssue // An empty map is kept, unlike nil.
+}
+
+// noTable is the state of a table that isn't in the Conv.
+var noTable = json.RawMessage("null")
+
+// SchemaSnapshot is the schema state of a Conv, taken before an edit so
+// that the edit can be recorded by RecordEdit.
+type SchemaSnapshot struct {
+	tables map[string]json.RawMessage
+	rules  json.RawMessage
+}
+
+// TakeSchemaSnapshot returns the schema state of conv.
+func (conv *Conv) TakeSchemaSnapshot() (SchemaSnapshot, error) {
+	ids := make(map[string]bool)
+	for id := range conv.SpSchema {
+		ids[id] = true
+	}
+	for id := range conv.SyntheticPKeys {
+		ids[id] = true
+	}
+	for id := range conv.UniquePKey {
+		ids[id] = true
+	}
+	for id := range conv.SchemaIssues {
+		ids[id] = true
+	}
+	s := SchemaSnapshot{tables: make(map[string]json.RawMessage)}
+	for id := range ids {
+		var ts tableState
+		if spTable, ok := conv.SpSchema[id]; ok {
+			ts.SpSchema = &spTable
+		}
+		if pk, ok := conv.SyntheticPKeys[id]; ok {
+			ts.SyntheticPKey = &pk
+		}
+		ts.UniquePKey = conv.UniquePKey[id]
+		ts.SchemaIssues = conv.SchemaIssues[id]
+		b, err := json.Marshal(ts)
+		if err != nil {
+			return SchemaSnapshot{}, fmt.Errorf("can't encode state of table %s: %v", id, err)
+		}
+		s.tables[id] = b
+	}
+	rules, err := json.Marshal(conv.Rules)
+	if err != nil {
+		return SchemaSnapshot{}, fmt.Errorf("can't encode rules: %v", err)
+	}
+	s.rules = rules
+	return s, nil
+}
+
+// RecordEdit records the changes made to conv since the snapshot before
+// as an edit in the edit history of conv. It returns nil if nothing
+// changed.
+func (conv *Conv) RecordEdit(description string, before SchemaSnapshot) (*Edit, error) {
+	after, err := conv.TakeSchemaSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	edit := Edit{Description: description, Time: time.Now()}
+	for id, b := range before.tables {
+		a, ok := after.tables[id]
+		if !ok {
+			a = noTable
+		}
+		if !bytes.Equal(a, b) {
+			edit.Tables = append(edit.Tables, TableEdit{TableId: id, Before: b, After: a})
+		}
+	}
+	for id, a := range after.tables {
+		if _, ok := before.tables[id]; !ok {
+			edit.Tables = append(edit.Tables, TableEdit{TableId: id, Before: noTable, After: a})
+		}
+	}
+	sort.Slice(edit.Tables, func(i, j int) bool { return edit.Tables[i].TableId < edit.Tables[j].TableId })
+	if !bytes.Equal(before.rules, after.rules) {
+		edit.RulesBefore, edit.RulesAfter = before.rules, after.rules
+	}
+	if len(edit.Tables) == 0 && edit.RulesBefore == nil {
+		return nil, nil
+	}
+	h := &conv.EditHistory
+	edit.Id = 1
+	if len(h.Edits) > 0 {
+		edit.Id = h.Edits[len(h.Edits)-1].Id + 1
+	}
+	h.Edits = append(h.Edits[:h.Applied], edit)
+	if len(h.Edits) > maxEdits {
+		h.Edits = h.Edits[len(h.Edits)-maxEdits:]
+	}
+	h.Applied = len(h.Edits)
+	return &h.Edits[len(h.Edits)-1], nil
+}
+
+// UndoEdit reverts the last applied edit of conv, and returns it.
+func (conv *Conv) UndoEdit() (*Edit, error) {
+	h := &conv.EditHistory
+	if h.Applied == 0 {
+		return nil, fmt.Errorf("no edit to undo")
+	}
+	edit := &h.Edits[h.Applied-1]
+	if err := conv.applyEdit(edit, true); err != nil {
+		return nil, fmt.Errorf("can't undo edit %d: %v", edit.Id, err)
+	}
+	h.Applied--
+	return edit, nil
+}
+
+// RedoEdit reapplies the last undone edit of conv, and returns it.
+func (conv *Conv) RedoEdit() (*Edit, error) {
+	h := &conv.EditHistory
+	if h.Applied == len(h.Edits) {
+		return nil, fmt.Errorf("no edit to redo")
+	}
+	edit := &h.Edits[h.Applied]
+	if err := conv.applyEdit(edit, false); err != nil {
+		return nil, fmt.Errorf("can't redo edit %d: %v", edit.Id, err)
+	}
+	h.Applied++
+	return edit, nil
+}
+
+// applyEdit sets the tables and rules changed by edit to their state
+// before the edit if undo is true, and after it otherwise.
+func (conv *Conv) applyEdit(edit *Edit, undo bool) error {
+	// Decode everything first, so that conv is left unchanged on error.
+	states := make([]*tableState, len(edit.Tables))
+	for i, t := range edit.Tables {
+		b := t.After
+		if undo {
+			b = t.Before
+		}
+		if err := json.Unmarshal(b, &states[i]); err != nil {
+			return fmt.Errorf("can't decode state of table %s: %v", t.TableId, err)
+		}
+	}
+	rules := conv.Rules
+	if b := edit.RulesAfter; b != nil {
+		if undo {
+			b = edit.RulesBefore
+		}
+		rules = nil
+		if err := json.Unmarshal(b, &rules); err != nil {
+			return fmt.Errorf("can't decode rules: %v", err)
+		}
+	}
+	if conv.SpSchema == nil {
+		conv.SpSchema = ddl.NewSchema()
+	}
+	if conv.SyntheticPKeys == nil {
+		conv.SyntheticPKeys = make(map[string]SyntheticPKey)
+	}
+	if conv.UniquePKey == nil {
+		conv.UniquePKey = make(map[string][]string)
+	}
+	if conv.SchemaIssues == nil {
+		conv.SchemaIssues = make(map[string]map[string][]SchemaIssue)
+	}
+	for i, t := range edit.Tables {
+		conv.setTableState(t.TableId, states[i])
+	}
+	conv.Rules = rules
+	conv.UsedNames = ComputeUsedNames(conv)
+	return nil
+}
+
+func (conv *Conv) setTableState(id string, ts *tableState) {
+	if ts == nil {
+		ts = &tableState{}
+	}
+	if ts.SpSchema != nil {
+		conv.SpSchema[id] = *ts.SpSchema
+	} else {
+		delete(conv.SpSchema, id)
+	}
+	if ts.SyntheticPKey != nil {
+		conv.SyntheticPKeys[id] = *ts.SyntheticPKey
+	} else {
+		delete(conv.SyntheticPKeys, id)
+	}
+	if ts.UniquePKey != nil {
+		conv.UniquePKey[id] = ts.UniquePKey
+	} else {
+		delete(conv.UniquePKey, id)
+	}
+	if ts.SchemaIssues != nil {
+		conv.SchemaIssues[id] = ts.SchemaIssues
+	} else {
+		delete(conv.SchemaIssues, id)
+	}
+}
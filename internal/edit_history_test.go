@@ -0,0 +1,171 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+func makeEditConv() *Conv {
+	conv := MakeConv()
+	conv.SpSchema["t1"] = ddl.CreateTable{
+		Name:   "t1",
+		Id:     "t1",
+		ColIds: []string{"c1"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"c1": {Name: "a", Id: "c1", T: ddl.Type{Name: ddl.Int64}},
+		},
+		PrimaryKeys: []ddl.IndexKey{{ColId: "c1"}},
+	}
+	conv.SpSchema["t2"] = ddl.CreateTable{Name: "t2", Id: "t2", ColIds: []string{}, ColDefs: map[string]ddl.ColumnDef{}}
+	conv.SchemaIssues["t1"] = map[string][]SchemaIssue{"c1": {Widened}}
+	conv.SchemaIssues["t2"] = map[string][]SchemaIssue{}
+	conv.UsedNames = ComputeUsedNames(conv)
+	return conv
+}
+
+// edit applies f to conv and records it as an edit.
+func edit(t *testing.T, conv *Conv, description string, f func()) *Edit {
+	before, err := conv.TakeSchemaSnapshot()
+	assert.Nil(t, err)
+	f()
+	e, err := conv.RecordEdit(description, before)
+	assert.Nil(t, err)
+	return e
+}
+
+func TestRecordEdit(t *testing.T) {
+	conv := makeEditConv()
+	e := edit(t, conv, "Drop table t2", func() { delete(conv.SpSchema, "t2") })
+	assert.Equal(t, 1, e.Id)
+	assert.Equal(t, "Drop table t2", e.Description)
+	assert.Len(t, e.Tables, 1)
+	assert.Equal(t, "t2", e.Tables[0].TableId)
+	assert.Nil(t, e.RulesBefore)
+	assert.Equal(t, 1, conv.EditHistory.Applied)
+
+	// Nothing changed.
+	assert.Nil(t, edit(t, conv, "Noop", func() {}))
+	assert.Len(t, conv.EditHistory.Edits, 1)
+
+	e = edit(t, conv, "Apply rule", func() { conv.Rules = append(conv.Rules, Rule{Id: "r1", Name: "rule1"}) })
+	assert.Equal(t, 2, e.Id)
+	assert.Empty(t, e.Tables)
+	assert.Equal(t, "[]", string(e.RulesBefore))
+	assert.NotNil(t, e.RulesAfter)
+}
+
+func TestUndoRedoEdit(t *testing.T) {
+	conv := makeEditConv()
+	_, err := conv.UndoEdit()
+	assert.NotNil(t, err)
+
+	edit(t, conv, "Rename column", func() {
+		t1 := conv.SpSchema["t1"]
+		t1.ColDefs["c1"] = ddl.ColumnDef{Name: "b", Id: "c1", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}}
+		conv.SchemaIssues["t1"]["c1"] = nil
+	})
+	edit(t, conv, "Drop table", func() {
+		delete(conv.SpSchema, "t2")
+		delete(conv.SchemaIssues, "t2")
+	})
+	edit(t, conv, "Restore table", func() {
+		conv.SpSchema["t3"] = ddl.CreateTable{Name: "t3", Id: "t3"}
+		conv.UsedNames["t3"] = true
+	})
+	afterEdits, err := json.Marshal(conv)
+	assert.Nil(t, err)
+
+	e, err := conv.UndoEdit()
+	assert.Nil(t, err)
+	assert.Equal(t, "Restore table", e.Description)
+	_, ok := conv.SpSchema["t3"]
+	assert.False(t, ok)
+	assert.False(t, conv.UsedNames["t3"])
+
+	e, err = conv.UndoEdit()
+	assert.Nil(t, err)
+	assert.Equal(t, "Drop table", e.Description)
+	assert.Equal(t, map[string][]SchemaIssue{}, conv.SchemaIssues["t2"])
+
+	_, err = conv.UndoEdit()
+	assert.Nil(t, err)
+	assert.Equal(t, makeEditConv().SpSchema, conv.SpSchema)
+	assert.Equal(t, makeEditConv().SchemaIssues, conv.SchemaIssues)
+	assert.Equal(t, makeEditConv().UsedNames, conv.UsedNames)
+	_, err = conv.UndoEdit()
+	assert.NotNil(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err = conv.RedoEdit()
+		assert.Nil(t, err)
+	}
+	_, err = conv.RedoEdit()
+	assert.NotNil(t, err)
+	redone, err := json.Marshal(conv)
+	assert.Nil(t, err)
+	assert.JSONEq(t, string(afterEdits), string(redone))
+	assert.Equal(t, map[string]bool{"t1": true, "t3": true}, conv.UsedNames)
+
+	// A new edit drops the edits that were undone. Edit ids aren't reused.
+	conv.UndoEdit()
+	edit(t, conv, "Drop table t1", func() { delete(conv.SpSchema, "t1") })
+	assert.Len(t, conv.EditHistory.Edits, 3)
+	assert.Equal(t, 3, conv.EditHistory.Applied)
+	assert.Equal(t, "Drop table t1", conv.EditHistory.Edits[2].Description)
+	assert.Equal(t, 4, conv.EditHistory.Edits[2].Id)
+}
+
+func TestEditHistoryJSON(t *testing.T) {
+	conv := makeEditConv()
+	edit(t, conv, "Apply rule", func() {
+		conv.Rules = append(conv.Rules, Rule{Id: "r1", Name: "rule1", Data: map[string]string{"a": "b"}})
+		delete(conv.SpSchema, "t2")
+	})
+
+	// The edit history is saved with the Conv, e.g. in session files.
+	b, err := json.Marshal(conv)
+	assert.Nil(t, err)
+	loaded := MakeConv()
+	assert.Nil(t, json.Unmarshal(b, loaded))
+	_, err = loaded.UndoEdit()
+	assert.Nil(t, err)
+	assert.Equal(t, makeEditConv().SpSchema, loaded.SpSchema)
+	assert.Empty(t, loaded.Rules)
+	_, err = loaded.RedoEdit()
+	assert.Nil(t, err)
+	assert.Len(t, loaded.Rules, 1)
+	assert.Equal(t, map[string]interface{}{"a": "b"}, loaded.Rules[0].Data)
+}
+
+func TestEditHistoryLimit(t *testing.T) {
+	conv := makeEditConv()
+	for i := 0; i < maxEdits+10; i++ {
+		edit(t, conv, "Rename table", func() {
+			t1 := conv.SpSchema["t1"]
+			t1.Name = fmt.Sprintf("t1_%d", i)
+			conv.SpSchema["t1"] = t1
+		})
+	}
+	assert.Len(t, conv.EditHistory.Edits, maxEdits)
+	assert.Equal(t, maxEdits, conv.EditHistory.Applied)
+	assert.Equal(t, 11, conv.EditHistory.Edits[0].Id)
+}
@@ -0,0 +1,198 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"sort"
+	"sync"
+)
+
+// Phase of a migration.
+type Phase string
+
+const (
+	PhaseSchema      Phase = "schema"
+	PhaseData        Phase = "data"
+	PhaseIndexes     Phase = "indexes"
+	PhaseForeignKeys Phase = "foreign_keys"
+	PhaseDone        Phase = "done"
+)
+
+// maxErrorSamples is how many distinct error messages a MigrationProgress
+// keeps. Further messages are only counted in DroppedErrors.
+const maxErrorSamples = 20
+
+// MigrationProgress tracks the phase of a migration and, during data
+// migration, the rows of each Spanner table that were written, found bad
+// or dropped. It is safe for concurrent use, and its methods do nothing on
+// a nil MigrationProgress.
+type MigrationProgress struct {
+	mu            sync.Mutex
+	phase         Phase
+	tables        map[string]*TableRows
+	aliases       map[string]string // Source table name to Spanner table name.
+	errors        map[string]int64
+	droppedErrors int64
+}
+
+// TableRows is the progress of the data migration of a table.
+type TableRows struct {
+	Total       int64 // Rows to write, if known.
+	Written     int64 // Rows written so far.
+	BadRows     int64 `json:",omitempty"` // Rows that couldn't be converted.
+	DroppedRows int64 `json:",omitempty"` // Rows that Spanner refused.
+}
+
+// ErrorSample is an error seen while writing data, and how many times it
+// was seen.
+type ErrorSample struct {
+	Message string
+	Count   int64
+}
+
+// MigrationSnapshot is a copy of the state of a MigrationProgress.
+type MigrationSnapshot struct {
+	Phase  Phase
+	Tables map[string]TableRows
+	Errors []ErrorSample // Ordered by message.
+	// Number of errors whose message wasn't sampled.
+	DroppedErrors int64
+}
+
+// Written returns the number of rows written to all tables.
+func (s MigrationSnapshot) Written() int64 {
+	var n int64
+	for _, t := range s.Tables {
+		n += t.Written
+	}
+	return n
+}
+
+// NewMigrationProgress returns a MigrationProgress with no tables.
+func NewMigrationProgress() *MigrationProgress {
+	return &MigrationProgress{
+		tables:  make(map[string]*TableRows),
+		aliases: make(map[string]string),
+		errors:  make(map[string]int64),
+	}
+}
+
+func (mp *MigrationProgress) get(table string) *TableRows {
+	t, ok := mp.tables[table]
+	if !ok {
+		t = &TableRows{}
+		mp.tables[table] = t
+	}
+	return t
+}
+
+// SetPhase records that the migration entered phase p.
+func (mp *MigrationProgress) SetPhase(p Phase) {
+	if mp == nil {
+		return
+	}
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.phase = p
+}
+
+// SetTable sets the number of rows of Spanner table to write, and the name
+// of its source table srcTable, under which bad rows are recorded.
+func (mp *MigrationProgress) SetTable(table, srcTable string, total int64) {
+	if mp == nil {
+		return
+	}
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.get(table).Total = total
+	mp.aliases[srcTable] = table
+}
+
+// AddWritten records that n more rows of table were written.
+func (mp *MigrationProgress) AddWritten(table string, n int64) {
+	if mp == nil {
+		return
+	}
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.get(table).Written += n
+}
+
+// AddBadRows records that n rows of source table srcTable couldn't be
+// converted.
+func (mp *MigrationProgress) AddBadRows(srcTable string, n int64) {
+	if mp == nil {
+		return
+	}
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	table, ok := mp.aliases[srcTable]
+	if !ok {
+		table = srcTable
+	}
+	mp.get(table).BadRows += n
+}
+
+// AddDropped records that n rows of table were dropped because writing
+// them failed with err.
+func (mp *MigrationProgress) AddDropped(table string, n int64, err error) {
+	if mp == nil {
+		return
+	}
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.get(table).DroppedRows += n
+	if err == nil {
+		return
+	}
+	if _, ok := mp.errors[err.Error()]; ok || len(mp.errors) < maxErrorSamples {
+		mp.errors[err.Error()]++
+	} else {
+		mp.droppedErrors++
+	}
+}
+
+// Tables returns a copy of the progress of each table.
+func (mp *MigrationProgress) Tables() map[string]TableRows {
+	if mp == nil {
+		return nil
+	}
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	return mp.tablesLocked()
+}
+
+func (mp *MigrationProgress) tablesLocked() map[string]TableRows {
+	m := make(map[string]TableRows, len(mp.tables))
+	for table, t := range mp.tables {
+		m[table] = *t
+	}
+	return m
+}
+
+// Snapshot returns a copy of the state of mp.
+func (mp *MigrationProgress) Snapshot() MigrationSnapshot {
+	if mp == nil {
+		return MigrationSnapshot{}
+	}
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	s := MigrationSnapshot{Phase: mp.phase, Tables: mp.tablesLocked(), DroppedErrors: mp.droppedErrors}
+	for msg, n := range mp.errors {
+		s.Errors = append(s.Errors, ErrorSample{Message: msg, Count: n})
+	}
+	sort.Slice(s.Errors, func(i, j int) bool { return s.Errors[i].Message < s.Errors[j].Message })
+	return s
+}
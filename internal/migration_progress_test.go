@@ -0,0 +1,62 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrationProgress(t *testing.T) {
+	var mp *MigrationProgress
+	mp.AddWritten("t1", 1) // A nil MigrationProgress ignores updates.
+	assert.Nil(t, mp.Tables())
+	assert.Equal(t, MigrationSnapshot{}, mp.Snapshot())
+
+	mp = NewMigrationProgress()
+	mp.SetPhase(PhaseData)
+	mp.SetTable("t1", "src_t1", 10)
+	mp.AddWritten("t1", 4)
+	mp.AddWritten("t1", 3)
+	mp.AddWritten("t2", 5)
+	mp.AddBadRows("src_t1", 2)
+	mp.AddBadRows("src_t3", 1)
+	mp.AddDropped("t2", 2, errors.New("b"))
+	mp.AddDropped("t2", 1, errors.New("a"))
+	mp.AddDropped("t2", 1, errors.New("b"))
+	s := mp.Snapshot()
+	assert.Equal(t, PhaseData, s.Phase)
+	assert.Equal(t, map[string]TableRows{
+		"t1":     {Total: 10, Written: 7, BadRows: 2},
+		"t2":     {Written: 5, DroppedRows: 4},
+		"src_t3": {BadRows: 1},
+	}, s.Tables)
+	assert.Equal(t, s.Tables, mp.Tables())
+	assert.Equal(t, []ErrorSample{{Message: "a", Count: 1}, {Message: "b", Count: 2}}, s.Errors)
+	assert.Equal(t, int64(12), s.Written())
+
+	// Only the first maxErrorSamples messages are sampled.
+	for i := 0; i < maxErrorSamples; i++ {
+		mp.AddDropped("t2", 1, fmt.Errorf("error %d", i))
+	}
+	mp.AddDropped("t2", 1, errors.New("b"))
+	s = mp.Snapshot()
+	assert.Equal(t, maxErrorSamples, len(s.Errors))
+	assert.Equal(t, int64(2), s.DroppedErrors)
+	assert.Equal(t, ErrorSample{Message: "b", Count: 3}, s.Errors[1])
+}
@@ -50,6 +50,8 @@ const (
 	DataWriteInProgress
 	ForeignKeyUpdateInProgress
 	ForeignKeyUpdateComplete
+	PostDataDDLInProgress
+	PostDataDDLComplete
 )
 
 // NewProgress creates and returns a Progress instance.
@@ -0,0 +1,120 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// EventType is the type of a ProgressEvent.
+type EventType string
+
+const (
+	EventPhase      EventType = "phase"      // The migration entered Phase.
+	EventTable      EventType = "table"      // The Rows of Table changed.
+	EventThroughput EventType = "throughput" // Rows were written at RowsPerSecond.
+	EventError      EventType = "error"      // Error was seen (again).
+)
+
+// ProgressEvent is a structured progress update of a migration. Only the
+// fields of its Type are set.
+type ProgressEvent struct {
+	Type          EventType
+	Time          time.Time
+	Phase         Phase        `json:",omitempty"`
+	Table         string       `json:",omitempty"`
+	Rows          *TableRows   `json:",omitempty"`
+	RowsPerSecond float64      `json:",omitempty"`
+	Written       int64        `json:",omitempty"` // Rows written to all tables so far.
+	Error         *ErrorSample `json:",omitempty"`
+}
+
+// ProgressEvents computes the events of a migration from successive
+// snapshots of its MigrationProgress.
+type ProgressEvents struct {
+	mp       *MigrationProgress
+	last     MigrationSnapshot
+	lastTime time.Time
+	now      func() time.Time
+}
+
+// NewProgressEvents returns a ProgressEvents for mp, whose first call to
+// Next returns the events of the current state of mp.
+func NewProgressEvents(mp *MigrationProgress) *ProgressEvents {
+	return &ProgressEvents{mp: mp, now: time.Now}
+}
+
+// Next returns the events since the last call to Next.
+func (pe *ProgressEvents) Next() []ProgressEvent {
+	now, s := pe.now(), pe.mp.Snapshot()
+	var events []ProgressEvent
+	if s.Phase != pe.last.Phase {
+		events = append(events, ProgressEvent{Type: EventPhase, Time: now, Phase: s.Phase})
+	}
+	var tables []string
+	for t, rows := range s.Tables {
+		if last, ok := pe.last.Tables[t]; !ok || rows != last {
+			tables = append(tables, t)
+		}
+	}
+	sort.Strings(tables)
+	for _, t := range tables {
+		rows := s.Tables[t]
+		events = append(events, ProgressEvent{Type: EventTable, Time: now, Table: t, Rows: &rows})
+	}
+	written, lastWritten := s.Written(), pe.last.Written()
+	if !pe.lastTime.IsZero() && written > lastWritten && now.After(pe.lastTime) {
+		events = append(events, ProgressEvent{
+			Type:          EventThroughput,
+			Time:          now,
+			RowsPerSecond: float64(written-lastWritten) / now.Sub(pe.lastTime).Seconds(),
+			Written:       written,
+		})
+	}
+	lastErrors := make(map[string]int64)
+	for _, e := range pe.last.Errors {
+		lastErrors[e.Message] = e.Count
+	}
+	for i := range s.Errors {
+		if s.Errors[i].Count > lastErrors[s.Errors[i].Message] {
+			events = append(events, ProgressEvent{Type: EventError, Time: now, Error: &s.Errors[i]})
+		}
+	}
+	pe.last, pe.lastTime = s, now
+	return events
+}
+
+// WatchProgress calls emit with the events of mp every interval until ctx
+// is done, and then a last time.
+func WatchProgress(ctx context.Context, mp *MigrationProgress, interval time.Duration, emit func(ProgressEvent)) {
+	pe := NewProgressEvents(mp)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, e := range pe.Next() {
+			emit(e)
+		}
+		select {
+		case <-ctx.Done():
+			for _, e := range pe.Next() {
+				emit(e)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressEvents(t *testing.T) {
+	mp := NewMigrationProgress()
+	pe := NewProgressEvents(mp)
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	pe.now = func() time.Time { return now }
+	assert.Nil(t, pe.Next())
+
+	mp.SetPhase(PhaseData)
+	mp.SetTable("t1", "t1", 100)
+	mp.SetTable("t2", "t2", 0)
+	assert.Equal(t, []ProgressEvent{
+		{Type: EventPhase, Time: now, Phase: PhaseData},
+		{Type: EventTable, Time: now, Table: "t1", Rows: &TableRows{Total: 100}},
+		{Type: EventTable, Time: now, Table: "t2", Rows: &TableRows{}},
+	}, pe.Next())
+
+	now = start.Add(2 * time.Second)
+	mp.AddWritten("t1", 50)
+	mp.AddDropped("t1", 1, errors.New("bad row"))
+	assert.Equal(t, []ProgressEvent{
+		{Type: EventTable, Time: now, Table: "t1", Rows: &TableRows{Total: 100, Written: 50, DroppedRows: 1}},
+		{Type: EventThroughput, Time: now, RowsPerSecond: 25, Written: 50},
+		{Type: EventError, Time: now, Error: &ErrorSample{Message: "bad row", Count: 1}},
+	}, pe.Next())
+
+	now = start.Add(3 * time.Second)
+	assert.Nil(t, pe.Next())
+}
+
+func TestWatchProgress(t *testing.T) {
+	mp := NewMigrationProgress()
+	mp.SetPhase(PhaseSchema)
+	ctx, cancel := context.WithCancel(context.Background())
+	var mu sync.Mutex
+	var events []ProgressEvent
+	done := make(chan struct{})
+	go func() {
+		WatchProgress(ctx, mp, time.Hour, func(e ProgressEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, e)
+		})
+		close(done)
+	}()
+	mp.SetPhase(PhaseDone)
+	cancel()
+	<-done
+	// The last phase is always emitted once the watch stops.
+	assert.Equal(t, PhaseDone, events[len(events)-1].Phase)
+}
@@ -93,10 +93,40 @@ func buildTableReport(conv *internal.Conv, tableId string, badWrites map[string]
 	}
 	if !conv.SchemaMode() {
 		fillRowStats(conv, tableId, badWrites, &tr)
+		fillOversizedValues(conv, spSchema, &tr)
 	}
 	return tr
 }
 
+// fillOversizedValues adds a warning for each column of spSchema with values
+// that were truncated or offloaded since they exceeded Spanner's size limits.
+func fillOversizedValues(conv *internal.Conv, spSchema ddl.CreateTable, tr *tableReport) {
+	var l []string
+	for _, colId := range spSchema.ColIds {
+		col := spSchema.ColDefs[colId].Name
+		if n := conv.Stats.TruncatedValues[spSchema.Name][col]; n > 0 {
+			l = append(l, fmt.Sprintf("Column '%s': %d values exceeded Spanner's size limits and were truncated", col, n))
+		}
+		if n := conv.Stats.OffloadedValues[spSchema.Name][col]; n > 0 {
+			l = append(l, fmt.Sprintf("Column '%s': %d values exceeded Spanner's size limits and were offloaded. The column stores their URI instead", col, n))
+		}
+	}
+	if len(l) == 0 {
+		return
+	}
+	tr.Warnings += int64(len(l))
+	if len(tr.Body) > 0 && strings.HasPrefix(tr.Body[0].Heading, "Warning") {
+		l = append(tr.Body[0].Lines, l...)
+	} else {
+		tr.Body = append([]tableReportBody{{}}, tr.Body...)
+	}
+	heading := "Warning"
+	if len(l) > 1 {
+		heading = heading + "s"
+	}
+	tr.Body[0] = tableReportBody{Heading: heading, Lines: l}
+}
+
 func buildTableReportBody(conv *internal.Conv, tableId string, issues map[string][]internal.SchemaIssue, spSchema ddl.CreateTable, srcSchema schema.Table, syntheticPK *string, uniquePK []string) []tableReportBody {
 	var body []tableReportBody
 	for _, p := range []struct {
@@ -158,6 +188,11 @@ func buildTableReportBody(conv *internal.Conv, tableId string, issues map[string
 				l = append(l, fmt.Sprintf("%s, Table '%s' is mapped to '%s'", IssueDB[internal.IllegalName].Brief, srcSchema.Name, spSchema.Name))
 			}
 		}
+		if p.severity == note && srcSchema.PartitionBy != "" {
+			// Spanner has no partitioned tables: rows of all partitions
+			// are migrated into a single table.
+			l = append(l, fmt.Sprintf("Table '%s' is partitioned by %s and the data of its %d partitions is migrated into this table. Consider whether the partition key should lead the primary key", srcSchema.Name, srcSchema.PartitionBy, len(srcSchema.Partitions)))
+		}
 
 		issueBatcher := make(map[internal.SchemaIssue]bool)
 		for _, colName := range colNames {
@@ -269,6 +304,17 @@ func buildTableReportBody(conv *internal.Conv, tableId string, issues map[string
 
 				case internal.IllegalName:
 					l = append(l, fmt.Sprintf("%s, Column '%s' is mapped to '%s'", IssueDB[i].Brief, srcColName, spColName))
+				case internal.CheckConstraint:
+					l = append(l, fmt.Sprintf("Column '%s': %s", spColName, IssueDB[i].Brief))
+				case internal.NarrowerType:
+					suggestion := conv.TypeSuggestions[tableId][colId]
+					suggestedType := suggestion.T.PrintColumnDefType()
+					if conv.SpDialect == constants.DIALECT_POSTGRESQL {
+						suggestedType = suggestion.T.PGPrintColumnDefType()
+					}
+					l = append(l, fmt.Sprintf("Column '%s': type %s is mapped to %s. %s: %s, since %s", spColName, srcColType, spColType, IssueDB[i].Brief, strings.ToLower(suggestedType), suggestion.Reason))
+				case internal.AllowedValues:
+					l = append(l, fmt.Sprintf("Column '%s': type %s is mapped to %s. %s. Allowed values: %s", spColName, srcColType, spColType, IssueDB[i].Brief, strings.Join(srcSchema.ColDefs[colId].Type.EnumValues, ", ")))
 				default:
 					l = append(l, fmt.Sprintf("Column '%s': type %s is mapped to %s. %s", spColName, srcColType, spColType, IssueDB[i].Brief))
 				}
@@ -389,6 +435,10 @@ var IssueDB = map[internal.SchemaIssue]struct {
 	internal.InterleavedAddColumn:    {Brief: "Candidate for Interleaved Table", severity: suggestion},
 	internal.IllegalName:             {Brief: "Names must adhere to the spanner regular expression {a-z|A-Z}[{a-z|A-Z|0-9|_}+]", severity: warning},
 	internal.InterleavedRenameColumn: {Brief: "Candidate for Interleaved Table", severity: suggestion},
+	internal.CheckConstraint:         {Brief: "Spanner can't enforce some CHECK constraints of this column, and they are dropped", severity: warning},
+	internal.SpatialGeoJSON:          {Brief: "Spanner has no spatial types, so geometries are converted to GeoJSON with the SRID as a named CRS (e.g. EPSG:4326). Spatial indexes and functions aren't available", severity: note},
+	internal.NarrowerType:            {Brief: "Sampled data suggests a narrower Spanner type", severity: suggestion},
+	internal.AllowedValues:           {Brief: "Enum values are enforced with a CHECK constraint, and set values are split on commas into an array", severity: note},
 }
 
 type severity int
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
 )
@@ -43,10 +44,30 @@ func GenerateTextReport(structuredReport StructuredReport, w *bufio.Writer) {
 	}
 	writeNameChanges(structuredReport, w)
 	writeTableReports(structuredReport, w)
+	writeWriteThroughput(structuredReport, w)
 	writeUnexpectedConditionsv2(structuredReport, w)
 
 }
 
+func writeWriteThroughput(structuredReport StructuredReport, w *bufio.Writer) {
+	wt := structuredReport.WriteThroughput
+	if wt == nil {
+		return
+	}
+	writeHeading(w, "Write Throughput")
+	fmt.Fprintf(w, "%d rows were written to Spanner in %d commits over %s.\n", wt.Rows, wt.Commits, wt.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "Rows per second:           %.1f\n", wt.RowsPerSecond)
+	if wt.Mutations > 0 {
+		// Spanner counts a mutation for each column written, including
+		// the columns of indexes.
+		fmt.Fprintf(w, "Mutations:                 %d\n", wt.Mutations)
+		fmt.Fprintf(w, "Mutations per second:      %.1f\n", wt.MutationsPerSecond)
+	}
+	fmt.Fprintf(w, "Average commit latency:    %s\n", wt.AvgCommitLatency.Round(time.Millisecond))
+	fmt.Fprintf(w, "Final write concurrency:   %d\n", wt.Concurrency)
+	w.WriteString("\n")
+}
+
 func writeUnexpectedConditionsv2(structuredReport StructuredReport, w *bufio.Writer) {
 	reparseInfo := func() {
 		if structuredReport.UnexpectedConditions.Reparsed > 0 {
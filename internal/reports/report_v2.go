@@ -92,6 +92,19 @@ type UnexpectedCondition struct {
 	Condition string `json:"condition"`
 }
 
+// WriteThroughput is the effective throughput of the writes of data to
+// Spanner.
+type WriteThroughput struct {
+	Commits            int64         `json:"commits"`
+	Rows               int64         `json:"rows"`
+	Mutations          int64         `json:"mutations"`
+	Elapsed            time.Duration `json:"elapsed"`
+	RowsPerSecond      float64       `json:"rowsPerSecond"`
+	MutationsPerSecond float64       `json:"mutationsPerSecond"`
+	AvgCommitLatency   time.Duration `json:"avgCommitLatency"`
+	Concurrency        int64         `json:"concurrency"`
+}
+
 type UnexpectedConditions struct {
 	Reparsed             int64
 	UnexpectedConditions []UnexpectedCondition `json:"unexpectedConditions"`
@@ -106,9 +119,32 @@ type StructuredReport struct {
 	NameChanges          []NameChange         `json:"nameChanges"`
 	TableReports         []TableReport        `json:"tableReports"`
 	UnexpectedConditions UnexpectedConditions `json:"unexpectedConditions"`
+	WriteThroughput      *WriteThroughput     `json:"writeThroughput,omitempty"`
 	SchemaOnly           bool                 `json:"-"`
 }
 
+// fetchWriteThroughput returns the write throughput of the data migration,
+// or nil if no data was written.
+func fetchWriteThroughput(conv *internal.Conv) *WriteThroughput {
+	ws := conv.Audit.WriteStats
+	if ws == nil || ws.Commits == 0 {
+		return nil
+	}
+	wt := &WriteThroughput{
+		Commits:          ws.Commits,
+		Rows:             ws.Rows,
+		Mutations:        ws.Mutations,
+		Elapsed:          ws.Elapsed,
+		AvgCommitLatency: ws.WriteTime / time.Duration(ws.Commits),
+		Concurrency:      ws.Concurrency,
+	}
+	if secs := ws.Elapsed.Seconds(); secs > 0 {
+		wt.RowsPerSecond = float64(ws.Rows) / secs
+		wt.MutationsPerSecond = float64(ws.Mutations) / secs
+	}
+	return wt
+}
+
 // A report consists of the following parts:
 // 1. Summary (overall quality of conversion)
 // 2. Ignored statements
@@ -118,6 +154,7 @@ type StructuredReport struct {
 // 6. Name changes
 // 7. Individual table reports (Detailed + Quality of conversion for each)
 // 8. Unexpected conditions
+// 9. Write throughput (if data was written)
 //
 // This method the RAW structured report in JSON format. Several utilities can be built on top of
 // this raw, nested JSON data to output the reports in different user and machine friendly formats
@@ -163,6 +200,9 @@ func GenerateStructuredReport(driverName string, dbName string, conv *internal.C
 		hbReport.UnexpectedConditions = fetchUnexceptedConditions(driverName, conv)
 	}
 
+	//9. Write throughput
+	hbReport.WriteThroughput = fetchWriteThroughput(conv)
+
 	return hbReport
 }
 
@@ -52,6 +52,11 @@ func main() {
 	subcommands.Register(&cmd.SchemaCmd{}, "")
 	subcommands.Register(&cmd.DataCmd{}, "")
 	subcommands.Register(&cmd.SchemaAndDataCmd{}, "")
+	subcommands.Register(&cmd.CutoverCmd{}, "")
+	subcommands.Register(&cmd.SessionDiffCmd{}, "")
+	subcommands.Register(&cmd.StatusCmd{}, "")
+	subcommands.Register(&cmd.ReplayCmd{}, "")
+	subcommands.Register(&cmd.PostDataCmd{}, "")
 	subcommands.Register(&webv2.WebCmd{DistDir: distDir}, "")
 	flag.Parse()
 	os.Exit(int(subcommands.Execute(ctx)))
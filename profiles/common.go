@@ -67,7 +67,11 @@ func GetSQLConnectionStr(sourceProfile SourceProfile) string {
 		switch sourceProfile.Conn.Ty {
 		case SourceProfileConnectionTypeMySQL:
 			connParams := sourceProfile.Conn.Mysql
-			return getMYSQLConnectionStr(connParams.Host, connParams.Port, connParams.User, connParams.Pwd, connParams.Db)
+			connStr := getMYSQLConnectionStr(connParams.Host, connParams.Port, connParams.User, connParams.Pwd, connParams.Db)
+			if connParams.Tls != "" {
+				connStr += "?tls=" + connParams.Tls
+			}
+			return connStr
 		case SourceProfileConnectionTypePostgreSQL:
 			connParams := sourceProfile.Conn.Pg
 			return getPGSQLConnectionStr(connParams.Host, connParams.Port, connParams.User, connParams.Pwd, connParams.Db)
@@ -82,6 +86,8 @@ func GetSQLConnectionStr(sourceProfile SourceProfile) string {
 		case SourceProfileConnectionTypeOracle:
 			connParams := sourceProfile.Conn.Oracle
 			return getORACLEConnectionStr(connParams.Host, connParams.Port, connParams.User, connParams.Pwd, connParams.Db)
+		case SourceProfileConnectionTypeSQLite:
+			return getSQLITEConnectionStr(sourceProfile.Conn.SQLite.File)
 		}
 	}
 	return sqlConnectionStr
@@ -147,3 +153,9 @@ func getORACLEConnectionStr(server, port, user, password, dbName string) string
 	portNumber, _ := strconv.Atoi(port)
 	return go_ora.BuildUrl(server, portNumber, dbName, user, password, nil)
 }
+
+// getSQLITEConnectionStr returns a read-only connection string for a SQLite
+// database file, so that the migration can never modify the source.
+func getSQLITEConnectionStr(file string) string {
+	return fmt.Sprintf("file:%s?mode=ro", file)
+}
@@ -17,8 +17,10 @@ package profiles
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
 	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
@@ -35,8 +37,31 @@ const (
 )
 
 type SourceProfileFile struct {
-	Path   string
-	Format string
+	Path          string
+	Format        string
+	SpatialFormat string // Format of migrated MySQL spatial data, SpatialWKT if empty or SpatialGeoJSON.
+}
+
+// Values of the spatial source-profile param, which sets how MySQL spatial
+// columns are migrated: as WKT in STRING columns, or as GeoJSON in JSON
+// columns.
+const (
+	SpatialWKT     = "wkt"
+	SpatialGeoJSON = "geojson"
+)
+
+// parseSpatialFormat returns the value of the spatial param in params, or
+// the empty string if it isn't set, which means SpatialWKT.
+func parseSpatialFormat(params map[string]string) (string, error) {
+	format, ok := params["spatial"]
+	if !ok {
+		return "", nil
+	}
+	format = strings.ToLower(format)
+	if format != SpatialWKT && format != SpatialGeoJSON {
+		return "", fmt.Errorf("unsupported spatial format %q, use %q or %q", params["spatial"], SpatialWKT, SpatialGeoJSON)
+	}
+	return format, nil
 }
 
 func NewSourceProfileFile(params map[string]string) SourceProfileFile {
@@ -63,6 +88,8 @@ const (
 	SourceProfileConnectionTypeDynamoDB
 	SourceProfileConnectionTypeSqlServer
 	SourceProfileConnectionTypeOracle
+	SourceProfileConnectionTypeSQLite
+	SourceProfileConnectionTypeSpanner
 )
 
 type SourceProfileConnectionMySQL struct {
@@ -72,8 +99,24 @@ type SourceProfileConnectionMySQL struct {
 	Db              string // Same as MYSQLDATABASE environment variable
 	Pwd             string // Same as MYSQLPWD environment variable
 	StreamingConfig string
+	Cdc             string // Change data capture mode: BinlogCdc streams changes from the binlog in-process.
+	CdcStateFile    string // File where the binlog position is persisted, for restarts and cutover.
+	CdcServerId     uint32 // Replica server id used to read the binlog, random if unset.
+	SpatialFormat   string // Format of migrated spatial data, SpatialWKT if empty or SpatialGeoJSON.
+	Tls             string // TLS mode of the connections to the database, MySQLTlsVerify or MySQLTlsSkipVerify; plain text if empty.
 }
 
+// Values of the tls source-profile param of MySQL, with the same meaning as
+// the tls param of the MySQL driver.
+const (
+	MySQLTlsVerify     = "true"
+	MySQLTlsSkipVerify = "skip-verify"
+)
+
+// BinlogCdc is the value of the cdc source-profile param for streaming
+// changes by reading the MySQL binlog directly.
+const BinlogCdc = "binlog"
+
 func NewSourceProfileConnectionMySQL(params map[string]string) (SourceProfileConnectionMySQL, error) {
 	mysql := SourceProfileConnectionMySQL{}
 
@@ -89,6 +132,49 @@ func NewSourceProfileConnectionMySQL(params map[string]string) (SourceProfileCon
 	}
 	mysql.StreamingConfig = streamingConfig
 
+	if cdc, ok := params["cdc"]; ok {
+		if cdc != BinlogCdc {
+			return mysql, fmt.Errorf("unsupported cdc mode %q, only %q is supported", cdc, BinlogCdc)
+		}
+		if streamingConfig != "" {
+			return mysql, fmt.Errorf("cdc=%s and streamingCfg can't be used together", BinlogCdc)
+		}
+		mysql.Cdc = cdc
+	}
+	if stateFile, ok := params["cdcStateFile"]; ok {
+		if mysql.Cdc == "" {
+			return mysql, fmt.Errorf("cdcStateFile can only be used with cdc=%s", BinlogCdc)
+		}
+		if stateFile == "" {
+			return mysql, fmt.Errorf("specify a non-empty cdc state file path")
+		}
+		mysql.CdcStateFile = stateFile
+	}
+	if serverId, ok := params["cdcServerId"]; ok {
+		if mysql.Cdc == "" {
+			return mysql, fmt.Errorf("cdcServerId can only be used with cdc=%s", BinlogCdc)
+		}
+		id, err := strconv.ParseUint(serverId, 10, 32)
+		if err != nil || id == 0 {
+			return mysql, fmt.Errorf("cdcServerId must be a positive integer, got %q", serverId)
+		}
+		mysql.CdcServerId = uint32(id)
+	}
+	spatial, err := parseSpatialFormat(params)
+	if err != nil {
+		return mysql, err
+	}
+	mysql.SpatialFormat = spatial
+	if tlsMode, ok := params["tls"]; ok {
+		switch tlsMode {
+		case MySQLTlsVerify, MySQLTlsSkipVerify:
+			mysql.Tls = tlsMode
+		case "false":
+		default:
+			return mysql, fmt.Errorf("unsupported tls mode %q, use %q, %q or \"false\"", tlsMode, MySQLTlsVerify, MySQLTlsSkipVerify)
+		}
+	}
+
 	// We don't users to mix and match params from source-profile and environment variables.
 	// We either try to get all params from the source-profile and if none are set, we read from the env variables.
 	if !(hostOk || userOk || dbOk || portOk || pwdOk) {
@@ -129,6 +215,9 @@ func NewSourceProfileConnectionMySQL(params map[string]string) (SourceProfileCon
 	if mysql.Pwd == "" {
 		mysql.Pwd = utils.GetPassword()
 	}
+	if mysql.Cdc != "" && mysql.CdcStateFile == "" {
+		mysql.CdcStateFile = fmt.Sprintf("%s_binlog_position.json", mysql.Db)
+	}
 
 	return mysql, nil
 }
@@ -140,8 +229,23 @@ type SourceProfileConnectionPostgreSQL struct {
 	Db              string // Same as PGDATABASE environment variable
 	Pwd             string // Same as PGPASSWORD environment variable
 	StreamingConfig string
+	Cdc             string // Change data capture mode: PgoutputCdc streams changes with logical replication in-process.
+	CdcStateFile    string // File where the confirmed flush LSN is persisted, for restarts.
+	CdcSlot         string // Logical replication slot, created if it doesn't exist.
+	CdcPublication  string // Publication of the migrated tables, created if it doesn't exist.
 }
 
+// PgoutputCdc is the value of the cdc source-profile param for streaming
+// changes from PostgreSQL with logical replication and the pgoutput plugin.
+const PgoutputCdc = "pgoutput"
+
+// Default names of the replication slot and publication used with
+// cdc=pgoutput.
+const (
+	DefaultPgCdcSlot        = "harbourbridge_slot"
+	DefaultPgCdcPublication = "harbourbridge_publication"
+)
+
 func NewSourceProfileConnectionPostgreSQL(params map[string]string) (SourceProfileConnectionPostgreSQL, error) {
 	pg := SourceProfileConnectionPostgreSQL{}
 	host, hostOk := params["host"]
@@ -156,6 +260,46 @@ func NewSourceProfileConnectionPostgreSQL(params map[string]string) (SourceProfi
 	}
 	pg.StreamingConfig = streamingConfig
 
+	if cdc, ok := params["cdc"]; ok {
+		if cdc != PgoutputCdc {
+			return pg, fmt.Errorf("unsupported cdc mode %q, only %q is supported", cdc, PgoutputCdc)
+		}
+		if streamingConfig != "" {
+			return pg, fmt.Errorf("cdc=%s and streamingCfg can't be used together", PgoutputCdc)
+		}
+		pg.Cdc = cdc
+		pg.CdcSlot, pg.CdcPublication = DefaultPgCdcSlot, DefaultPgCdcPublication
+	}
+	if stateFile, ok := params["cdcStateFile"]; ok {
+		if pg.Cdc == "" {
+			return pg, fmt.Errorf("cdcStateFile can only be used with cdc=%s", PgoutputCdc)
+		}
+		if stateFile == "" {
+			return pg, fmt.Errorf("specify a non-empty cdc state file path")
+		}
+		pg.CdcStateFile = stateFile
+	}
+	if slot, ok := params["cdcSlot"]; ok {
+		if pg.Cdc == "" {
+			return pg, fmt.Errorf("cdcSlot can only be used with cdc=%s", PgoutputCdc)
+		}
+		// PostgreSQL only allows lower case letters, numbers and underscores
+		// in slot names.
+		if !regexp.MustCompile(`^[a-z0-9_]{1,63}$`).MatchString(slot) {
+			return pg, fmt.Errorf("invalid cdcSlot %q: use lower case letters, numbers and underscores", slot)
+		}
+		pg.CdcSlot = slot
+	}
+	if publication, ok := params["cdcPublication"]; ok {
+		if pg.Cdc == "" {
+			return pg, fmt.Errorf("cdcPublication can only be used with cdc=%s", PgoutputCdc)
+		}
+		if publication == "" {
+			return pg, fmt.Errorf("specify a non-empty publication name")
+		}
+		pg.CdcPublication = publication
+	}
+
 	// We don't users to mix and match params from source-profile and environment variables.
 	// We either try to get all params from the source-profile and if none are set, we read from the env variables.
 	if !(hostOk || userOk || dbOk || portOk || pwdOk) {
@@ -190,20 +334,60 @@ func NewSourceProfileConnectionPostgreSQL(params map[string]string) (SourceProfi
 	if pg.Pwd == "" {
 		pg.Pwd = utils.GetPassword()
 	}
+	if pg.Cdc != "" && pg.CdcStateFile == "" {
+		pg.CdcStateFile = fmt.Sprintf("%s_replication_state.json", pg.Db)
+	}
 
 	return pg, nil
 }
 
 type SourceProfileConnectionSqlServer struct {
-	Host string
-	Port string
-	User string
-	Db   string
-	Pwd  string
+	Host            string
+	Port            string
+	User            string
+	Db              string
+	Pwd             string
+	Cdc             string        // Change data capture mode: ChangeTablesCdc polls the SQL Server change tables in-process.
+	CdcStateFile    string        // File where the last applied LSN is persisted, for restarts and cutover.
+	CdcPollInterval time.Duration // Time between polls of the change tables.
 }
 
+// ChangeTablesCdc is the value of the cdc source-profile param for streaming
+// changes by polling the change tables of SQL Server change data capture.
+const ChangeTablesCdc = "changetables"
+
+// DefaultSqlServerCdcPollInterval is the default time between polls of the
+// change tables with cdc=changetables.
+const DefaultSqlServerCdcPollInterval = 5 * time.Second
+
 func NewSourceProfileConnectionSqlServer(params map[string]string) (SourceProfileConnectionSqlServer, error) {
 	ss := SourceProfileConnectionSqlServer{}
+	if cdc, ok := params["cdc"]; ok {
+		if cdc != ChangeTablesCdc {
+			return ss, fmt.Errorf("unsupported cdc mode %q, only %q is supported", cdc, ChangeTablesCdc)
+		}
+		ss.Cdc = cdc
+		ss.CdcPollInterval = DefaultSqlServerCdcPollInterval
+	}
+	if stateFile, ok := params["cdcStateFile"]; ok {
+		if ss.Cdc == "" {
+			return ss, fmt.Errorf("cdcStateFile can only be used with cdc=%s", ChangeTablesCdc)
+		}
+		if stateFile == "" {
+			return ss, fmt.Errorf("specify a non-empty cdc state file path")
+		}
+		ss.CdcStateFile = stateFile
+	}
+	if pollInterval, ok := params["cdcPollInterval"]; ok {
+		if ss.Cdc == "" {
+			return ss, fmt.Errorf("cdcPollInterval can only be used with cdc=%s", ChangeTablesCdc)
+		}
+		d, err := time.ParseDuration(pollInterval)
+		if err != nil || d <= 0 {
+			return ss, fmt.Errorf("cdcPollInterval must be a positive duration such as 5s, got %q", pollInterval)
+		}
+		ss.CdcPollInterval = d
+	}
 	host, hostOk := params["host"]
 	user, userOk := params["user"]
 	db, dbOk := params["dbName"]
@@ -256,6 +440,9 @@ func NewSourceProfileConnectionSqlServer(params map[string]string) (SourceProfil
 	if ss.Pwd == "" {
 		ss.Pwd = utils.GetPassword()
 	}
+	if ss.Cdc != "" && ss.CdcStateFile == "" {
+		ss.CdcStateFile = fmt.Sprintf("%s_cdc_lsn.json", ss.Db)
+	}
 
 	return ss, nil
 }
@@ -270,6 +457,13 @@ type SourceProfileConnectionDynamoDB struct {
 	DydbEndpoint       string // Same as DYNAMODB_ENDPOINT_OVERRIDE environment variable
 	SchemaSampleSize   int64  // Number of rows to use for inferring schema (default 100,000)
 	enableStreaming    string // Used for confirming streaming migration (valid options: `yes`,`no`,`true`,`false`)
+	// Number of segments used to scan each table in parallel (default 1, i.e. sequential scan)
+	ScanSegments int64
+	// Maximum percentage of each table's provisioned read capacity used by scans (default 0, i.e. no limit)
+	ReadCapacityPercent float64
+	// Local directory or GCS path (gs://bucket/path) with DynamoDB "Export to S3" output. If set, tables
+	// are read from the exports instead of DynamoDB.
+	ExportDir string
 }
 
 func NewSourceProfileConnectionDynamoDB(params map[string]string) (SourceProfileConnectionDynamoDB, error) {
@@ -281,6 +475,20 @@ func NewSourceProfileConnectionDynamoDB(params map[string]string) (SourceProfile
 		}
 		dydb.SchemaSampleSize = int64(schemaSampleSizeInt)
 	}
+	if scanSegments, ok := params["scan-segments"]; ok {
+		scanSegmentsInt, err := strconv.ParseInt(scanSegments, 10, 64)
+		if err != nil || scanSegmentsInt < 1 || scanSegmentsInt > constants.DynamoDBMaxScanSegments {
+			return dydb, fmt.Errorf("could not parse scan-segments = %v as a valid int64 between 1 and %d", scanSegments, constants.DynamoDBMaxScanSegments)
+		}
+		dydb.ScanSegments = scanSegmentsInt
+	}
+	if readCapacityPercent, ok := params["read-capacity-percent"]; ok {
+		readCapacityPercentFloat, err := strconv.ParseFloat(readCapacityPercent, 64)
+		if err != nil || readCapacityPercentFloat <= 0 || readCapacityPercentFloat > 100 {
+			return dydb, fmt.Errorf("could not parse read-capacity-percent = %v as a valid percentage in (0, 100]", readCapacityPercent)
+		}
+		dydb.ReadCapacityPercent = readCapacityPercentFloat
+	}
 	// For DynamoDB, the preferred way to provide connection params is through env variables.
 	// Unlike postgres and mysql, there may not be deprecation of env variables, hence it
 	// is better to override env variables optionally via source profile params.
@@ -307,6 +515,14 @@ func NewSourceProfileConnectionDynamoDB(params map[string]string) (SourceProfile
 			return dydb, fmt.Errorf("please specify a valid choice for enableStreaming: available choices(yes, no, true, false)")
 		}
 	}
+	if dydb.ExportDir, ok = params["export-dir"]; ok {
+		if dydb.ExportDir == "" {
+			return dydb, fmt.Errorf("export-dir can't be empty")
+		}
+		if dydb.enableStreaming == "yes" {
+			return dydb, fmt.Errorf("streaming migration is not supported with export-dir")
+		}
+	}
 	return dydb, nil
 }
 
@@ -356,6 +572,60 @@ func NewSourceProfileConnectionOracle(params map[string]string) (SourceProfileCo
 	return ss, nil
 }
 
+type SourceProfileConnectionSQLite struct {
+	File string // Path to the SQLite database file.
+}
+
+func NewSourceProfileConnectionSQLite(params map[string]string) (SourceProfileConnectionSQLite, error) {
+	sqlite := SourceProfileConnectionSQLite{}
+	file, ok := params["file"]
+	if !ok || file == "" {
+		return sqlite, fmt.Errorf("please specify the SQLite database file using the file param in the source-profile")
+	}
+	// Opening a non-existent file with the SQLite driver silently creates an
+	// empty database, so we check that the file exists upfront.
+	if _, err := os.Stat(file); err != nil {
+		return sqlite, fmt.Errorf("can't access SQLite database file %s: %v", file, err)
+	}
+	sqlite.File = file
+	return sqlite, nil
+}
+
+type SourceProfileConnectionSpanner struct {
+	Project  string // Same as GCLOUD_PROJECT environment variable
+	Instance string
+	Dbname   string
+	// Timestamp at which all tables are read. If not set, the tables are read
+	// at the time the data migration starts.
+	ReadTimestamp time.Time
+}
+
+func NewSourceProfileConnectionSpanner(params map[string]string) (SourceProfileConnectionSpanner, error) {
+	sp := SourceProfileConnectionSpanner{}
+	instance, ok1 := params["instance"]
+	dbName, ok2 := params["dbName"]
+	if !ok1 || !ok2 || instance == "" || dbName == "" {
+		return sp, fmt.Errorf("please specify instance and dbName in the source-profile")
+	}
+	sp.Instance, sp.Dbname = instance, dbName
+	if readTimestamp, ok := params["readTimestamp"]; ok {
+		ts, err := time.Parse(time.RFC3339Nano, readTimestamp)
+		if err != nil {
+			return sp, fmt.Errorf("could not parse readTimestamp = %v as a RFC 3339 timestamp: %v", readTimestamp, err)
+		}
+		sp.ReadTimestamp = ts
+	}
+	sp.Project = params["project"]
+	if sp.Project == "" {
+		project, err := utils.GetProject()
+		if err != nil {
+			return sp, fmt.Errorf("can't get project: %v", err)
+		}
+		sp.Project = project
+	}
+	return sp, nil
+}
+
 type SourceProfileConnection struct {
 	Ty        SourceProfileConnectionType
 	Streaming bool
@@ -364,6 +634,12 @@ type SourceProfileConnection struct {
 	Dydb      SourceProfileConnectionDynamoDB
 	SqlServer SourceProfileConnectionSqlServer
 	Oracle    SourceProfileConnectionOracle
+	SQLite    SourceProfileConnectionSQLite
+	Sp        SourceProfileConnectionSpanner
+
+	// SampleRows, if positive, is the number of rows of each table sampled
+	// to suggest narrower Spanner types (see common.ProfileData).
+	SampleRows int64
 }
 
 func NewSourceProfileConnection(source string, params map[string]string) (SourceProfileConnection, error) {
@@ -377,7 +653,7 @@ func NewSourceProfileConnection(source string, params map[string]string) (Source
 			if err != nil {
 				return conn, err
 			}
-			if conn.Mysql.StreamingConfig != "" {
+			if conn.Mysql.StreamingConfig != "" || conn.Mysql.Cdc != "" {
 				conn.Streaming = true
 			}
 		}
@@ -388,7 +664,7 @@ func NewSourceProfileConnection(source string, params map[string]string) (Source
 			if err != nil {
 				return conn, err
 			}
-			if conn.Pg.StreamingConfig != "" {
+			if conn.Pg.StreamingConfig != "" || conn.Pg.Cdc != "" {
 				conn.Streaming = true
 			}
 		}
@@ -411,6 +687,9 @@ func NewSourceProfileConnection(source string, params map[string]string) (Source
 			if err != nil {
 				return conn, err
 			}
+			if conn.SqlServer.Cdc != "" {
+				conn.Streaming = true
+			}
 		}
 	case "oracle":
 		{
@@ -423,9 +702,35 @@ func NewSourceProfileConnection(source string, params map[string]string) (Source
 				conn.Streaming = true
 			}
 		}
+	case "sqlite", "sqlite3":
+		{
+			conn.Ty = SourceProfileConnectionTypeSQLite
+			conn.SQLite, err = NewSourceProfileConnectionSQLite(params)
+			if err != nil {
+				return conn, err
+			}
+		}
+	case "spanner":
+		{
+			conn.Ty = SourceProfileConnectionTypeSpanner
+			conn.Sp, err = NewSourceProfileConnectionSpanner(params)
+			if err != nil {
+				return conn, err
+			}
+		}
 	default:
 		return conn, fmt.Errorf("please specify a valid source database using -source flag, received source = %v", source)
 	}
+	if sampleRows, ok := params["sampleRows"]; ok {
+		if conn.Ty == SourceProfileConnectionTypeDynamoDB || conn.Ty == SourceProfileConnectionTypeSpanner {
+			return conn, fmt.Errorf("sampleRows is not supported for %s", source)
+		}
+		n, err := strconv.ParseInt(sampleRows, 10, 64)
+		if err != nil || n <= 0 {
+			return conn, fmt.Errorf("sampleRows must be a positive integer, got %q", sampleRows)
+		}
+		conn.SampleRows = n
+	}
 	return conn, nil
 }
 
@@ -485,6 +790,8 @@ func (src SourceProfile) ToLegacyDriver(source string) (string, error) {
 				return constants.MYSQLDUMP, nil
 			case "postgresql", "postgres", "pg":
 				return constants.PGDUMP, nil
+			case "sqlserver", "mssql":
+				return constants.SQLSERVERDUMP, nil
 			case "dynamodb":
 				return "", fmt.Errorf("dump files are not supported with DynamoDB")
 			default:
@@ -505,6 +812,10 @@ func (src SourceProfile) ToLegacyDriver(source string) (string, error) {
 				return constants.SQLSERVER, nil
 			case "oracle":
 				return constants.ORACLE, nil
+			case "sqlite", "sqlite3":
+				return constants.SQLITE, nil
+			case "spanner":
+				return constants.SPANNER, nil
 			default:
 				return "", fmt.Errorf("please specify a valid source database using -source flag, received source = %v", source)
 			}
@@ -534,6 +845,11 @@ func (src SourceProfile) ToLegacyDriver(source string) (string, error) {
 // from envrironment variables.
 //
 // Format 3. Specify a config file that specifies source connection profile.
+//
+// SQLite databases are files, but they are accessed through a connection, so
+// for -source=sqlite the "file" param is a connection parameter.
+//
+// Example: -source=sqlite -source-profile="file=/tmp/edge.db"
 func NewSourceProfile(s string, source string) (SourceProfile, error) {
 	if source == "" {
 		return SourceProfile{}, fmt.Errorf("cannot leave -source flag empty, please specify source databases e.g., -source=postgres etc")
@@ -545,9 +861,21 @@ func NewSourceProfile(s string, source string) (SourceProfile, error) {
 	if strings.ToLower(source) == constants.CSV {
 		return SourceProfile{Ty: SourceProfileTypeCsv, Csv: NewSourceProfileCsv(params)}, nil
 	}
+	if src := strings.ToLower(source); src == constants.SQLITE || src == "sqlite3" {
+		conn, err := NewSourceProfileConnection(source, params)
+		return SourceProfile{Ty: SourceProfileTypeConnection, Conn: conn}, err
+	}
 
 	if _, ok := params["file"]; ok || filePipedToStdin() {
 		profile := NewSourceProfileFile(params)
+		if _, ok := params["spatial"]; ok {
+			if strings.ToLower(source) != "mysql" {
+				return SourceProfile{Ty: SourceProfileTypeFile, File: profile}, fmt.Errorf("spatial can only be used with MySQL sources")
+			}
+			if profile.SpatialFormat, err = parseSpatialFormat(params); err != nil {
+				return SourceProfile{Ty: SourceProfileTypeFile, File: profile}, err
+			}
+		}
 		return SourceProfile{Ty: SourceProfileTypeFile, File: profile}, nil
 	} else if format, ok := params["format"]; ok {
 		// File is not passed in from stdin or specified using "file" flag.
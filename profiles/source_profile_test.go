@@ -15,7 +15,10 @@
 package profiles
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -120,6 +123,275 @@ func TestNewSourceProfileConnectionSQL(t *testing.T) {
 	}
 }
 
+func TestNewSourceProfileConnectionMySQLCdc(t *testing.T) {
+	base := map[string]string{"host": "a", "user": "b", "dbName": "c", "password": "e"}
+	testCases := []struct {
+		name          string
+		params        map[string]string
+		want          SourceProfileConnectionMySQL
+		errorExpected bool
+	}{
+		{
+			name:   "binlog cdc with default state file",
+			params: map[string]string{"cdc": "binlog"},
+			want:   SourceProfileConnectionMySQL{Cdc: BinlogCdc, CdcStateFile: "c_binlog_position.json"},
+		},
+		{
+			name:   "binlog cdc with state file and server id",
+			params: map[string]string{"cdc": "binlog", "cdcStateFile": "pos.json", "cdcServerId": "1234"},
+			want:   SourceProfileConnectionMySQL{Cdc: BinlogCdc, CdcStateFile: "pos.json", CdcServerId: 1234},
+		},
+		{
+			name:          "unsupported cdc mode",
+			params:        map[string]string{"cdc": "datastream"},
+			errorExpected: true,
+		},
+		{
+			name:          "cdc with streaming config",
+			params:        map[string]string{"cdc": "binlog", "streamingCfg": "cfg.json"},
+			errorExpected: true,
+		},
+		{
+			name:          "state file without cdc",
+			params:        map[string]string{"cdcStateFile": "pos.json"},
+			errorExpected: true,
+		},
+		{
+			name:          "empty state file",
+			params:        map[string]string{"cdc": "binlog", "cdcStateFile": ""},
+			errorExpected: true,
+		},
+		{
+			name:          "invalid server id",
+			params:        map[string]string{"cdc": "binlog", "cdcServerId": "0"},
+			errorExpected: true,
+		},
+		{
+			name:   "binlog cdc over tls",
+			params: map[string]string{"cdc": "binlog", "tls": "true"},
+			want:   SourceProfileConnectionMySQL{Cdc: BinlogCdc, CdcStateFile: "c_binlog_position.json", Tls: MySQLTlsVerify},
+		},
+		{
+			name:   "tls without certificate verification",
+			params: map[string]string{"tls": "skip-verify"},
+			want:   SourceProfileConnectionMySQL{Tls: MySQLTlsSkipVerify},
+		},
+		{
+			name:          "invalid tls mode",
+			params:        map[string]string{"tls": "required"},
+			errorExpected: true,
+		},
+	}
+	for _, tc := range testCases {
+		params := map[string]string{}
+		for k, v := range base {
+			params[k] = v
+		}
+		for k, v := range tc.params {
+			params[k] = v
+		}
+		got, err := NewSourceProfileConnectionMySQL(params)
+		assert.Equal(t, tc.errorExpected, err != nil, tc.name)
+		if !tc.errorExpected {
+			assert.Equal(t, tc.want.Cdc, got.Cdc, tc.name)
+			assert.Equal(t, tc.want.CdcStateFile, got.CdcStateFile, tc.name)
+			assert.Equal(t, tc.want.CdcServerId, got.CdcServerId, tc.name)
+			assert.Equal(t, tc.want.Tls, got.Tls, tc.name)
+		}
+	}
+	conn, err := NewSourceProfileConnection("mysql", map[string]string{"host": "a", "user": "b", "dbName": "c", "password": "e", "cdc": "binlog"})
+	assert.Nil(t, err)
+	assert.True(t, conn.Streaming)
+}
+
+func TestSpatialFormat(t *testing.T) {
+	filePipedToStdin = func() bool { return false }
+	testCases := []struct {
+		name          string
+		source        string
+		profile       string
+		want          string
+		errorExpected bool
+	}{
+		{name: "mysql connection default", source: "mysql", profile: "host=a,user=b,dbName=c,password=e", want: ""},
+		{name: "mysql connection geojson", source: "mysql", profile: "host=a,user=b,dbName=c,password=e,spatial=GeoJSON", want: SpatialGeoJSON},
+		{name: "mysql connection wkt", source: "mysql", profile: "host=a,user=b,dbName=c,password=e,spatial=wkt", want: SpatialWKT},
+		{name: "mysql connection invalid", source: "mysql", profile: "host=a,user=b,dbName=c,password=e,spatial=wkb", errorExpected: true},
+		{name: "mysqldump geojson", source: "mysql", profile: "file=a.sql,spatial=geojson", want: SpatialGeoJSON},
+		{name: "mysqldump invalid", source: "mysql", profile: "file=a.sql,spatial=wkb", errorExpected: true},
+		{name: "pg_dump", source: "postgres", profile: "file=a.sql,spatial=geojson", errorExpected: true},
+	}
+	for _, tc := range testCases {
+		profile, err := NewSourceProfile(tc.profile, tc.source)
+		assert.Equal(t, tc.errorExpected, err != nil, tc.name)
+		if tc.errorExpected {
+			continue
+		}
+		if profile.Ty == SourceProfileTypeFile {
+			assert.Equal(t, tc.want, profile.File.SpatialFormat, tc.name)
+		} else {
+			assert.Equal(t, tc.want, profile.Conn.Mysql.SpatialFormat, tc.name)
+		}
+	}
+}
+
+func TestSampleRows(t *testing.T) {
+	filePipedToStdin = func() bool { return false }
+	testCases := []struct {
+		name          string
+		source        string
+		profile       string
+		want          int64
+		errorExpected bool
+	}{
+		{name: "mysql default", source: "mysql", profile: "host=a,user=b,dbName=c,password=e", want: 0},
+		{name: "mysql", source: "mysql", profile: "host=a,user=b,dbName=c,password=e,sampleRows=1000", want: 1000},
+		{name: "postgres", source: "postgres", profile: "host=a,user=b,dbName=c,password=e,sampleRows=10", want: 10},
+		{name: "zero", source: "mysql", profile: "host=a,user=b,dbName=c,password=e,sampleRows=0", errorExpected: true},
+		{name: "not a number", source: "mysql", profile: "host=a,user=b,dbName=c,password=e,sampleRows=all", errorExpected: true},
+		{name: "dynamodb", source: "dynamodb", profile: "sampleRows=10", errorExpected: true},
+	}
+	for _, tc := range testCases {
+		profile, err := NewSourceProfile(tc.profile, tc.source)
+		assert.Equal(t, tc.errorExpected, err != nil, tc.name)
+		if tc.errorExpected {
+			continue
+		}
+		assert.Equal(t, tc.want, profile.Conn.SampleRows, tc.name)
+	}
+}
+
+func TestNewSourceProfileConnectionPostgreSQLCdc(t *testing.T) {
+	base := map[string]string{"host": "a", "user": "b", "dbName": "c", "password": "e"}
+	testCases := []struct {
+		name          string
+		params        map[string]string
+		want          SourceProfileConnectionPostgreSQL
+		errorExpected bool
+	}{
+		{
+			name:   "pgoutput cdc with defaults",
+			params: map[string]string{"cdc": "pgoutput"},
+			want:   SourceProfileConnectionPostgreSQL{Cdc: PgoutputCdc, CdcStateFile: "c_replication_state.json", CdcSlot: DefaultPgCdcSlot, CdcPublication: DefaultPgCdcPublication},
+		},
+		{
+			name:   "pgoutput cdc with state file, slot and publication",
+			params: map[string]string{"cdc": "pgoutput", "cdcStateFile": "lsn.json", "cdcSlot": "my_slot_1", "cdcPublication": "My Pub"},
+			want:   SourceProfileConnectionPostgreSQL{Cdc: PgoutputCdc, CdcStateFile: "lsn.json", CdcSlot: "my_slot_1", CdcPublication: "My Pub"},
+		},
+		{
+			name:          "unsupported cdc mode",
+			params:        map[string]string{"cdc": "wal2json"},
+			errorExpected: true,
+		},
+		{
+			name:          "cdc with streaming config",
+			params:        map[string]string{"cdc": "pgoutput", "streamingCfg": "cfg.json"},
+			errorExpected: true,
+		},
+		{
+			name:          "slot without cdc",
+			params:        map[string]string{"cdcSlot": "my_slot"},
+			errorExpected: true,
+		},
+		{
+			name:          "invalid slot name",
+			params:        map[string]string{"cdc": "pgoutput", "cdcSlot": "My-Slot"},
+			errorExpected: true,
+		},
+		{
+			name:          "empty publication",
+			params:        map[string]string{"cdc": "pgoutput", "cdcPublication": ""},
+			errorExpected: true,
+		},
+		{
+			name:          "empty state file",
+			params:        map[string]string{"cdc": "pgoutput", "cdcStateFile": ""},
+			errorExpected: true,
+		},
+	}
+	for _, tc := range testCases {
+		params := map[string]string{}
+		for k, v := range base {
+			params[k] = v
+		}
+		for k, v := range tc.params {
+			params[k] = v
+		}
+		got, err := NewSourceProfileConnectionPostgreSQL(params)
+		assert.Equal(t, tc.errorExpected, err != nil, tc.name)
+		if !tc.errorExpected {
+			assert.Equal(t, tc.want.Cdc, got.Cdc, tc.name)
+			assert.Equal(t, tc.want.CdcStateFile, got.CdcStateFile, tc.name)
+			assert.Equal(t, tc.want.CdcSlot, got.CdcSlot, tc.name)
+			assert.Equal(t, tc.want.CdcPublication, got.CdcPublication, tc.name)
+		}
+	}
+	conn, err := NewSourceProfileConnection("pg", map[string]string{"host": "a", "user": "b", "dbName": "c", "password": "e", "cdc": "pgoutput"})
+	assert.Nil(t, err)
+	assert.True(t, conn.Streaming)
+}
+
+func TestNewSourceProfileConnectionSqlServerCdc(t *testing.T) {
+	base := map[string]string{"host": "a", "user": "b", "dbName": "c", "password": "e"}
+	testCases := []struct {
+		name          string
+		params        map[string]string
+		want          SourceProfileConnectionSqlServer
+		errorExpected bool
+	}{
+		{
+			name:   "change tables cdc with defaults",
+			params: map[string]string{"cdc": "changetables"},
+			want:   SourceProfileConnectionSqlServer{Cdc: ChangeTablesCdc, CdcStateFile: "c_cdc_lsn.json", CdcPollInterval: DefaultSqlServerCdcPollInterval},
+		},
+		{
+			name:   "change tables cdc with state file and poll interval",
+			params: map[string]string{"cdc": "changetables", "cdcStateFile": "lsn.json", "cdcPollInterval": "500ms"},
+			want:   SourceProfileConnectionSqlServer{Cdc: ChangeTablesCdc, CdcStateFile: "lsn.json", CdcPollInterval: 500 * time.Millisecond},
+		},
+		{
+			name:          "unsupported cdc mode",
+			params:        map[string]string{"cdc": "binlog"},
+			errorExpected: true,
+		},
+		{
+			name:          "state file without cdc",
+			params:        map[string]string{"cdcStateFile": "lsn.json"},
+			errorExpected: true,
+		},
+		{
+			name:          "empty state file",
+			params:        map[string]string{"cdc": "changetables", "cdcStateFile": ""},
+			errorExpected: true,
+		},
+		{
+			name:          "invalid poll interval",
+			params:        map[string]string{"cdc": "changetables", "cdcPollInterval": "5"},
+			errorExpected: true,
+		},
+	}
+	for _, tc := range testCases {
+		params := map[string]string{}
+		for k, v := range base {
+			params[k] = v
+		}
+		for k, v := range tc.params {
+			params[k] = v
+		}
+		got, err := NewSourceProfileConnectionSqlServer(params)
+		assert.Equal(t, tc.errorExpected, err != nil, tc.name)
+		if !tc.errorExpected {
+			assert.Equal(t, tc.want.Cdc, got.Cdc, tc.name)
+			assert.Equal(t, tc.want.CdcStateFile, got.CdcStateFile, tc.name)
+			assert.Equal(t, tc.want.CdcPollInterval, got.CdcPollInterval, tc.name)
+		}
+	}
+	conn, err := NewSourceProfileConnection("sqlserver", map[string]string{"host": "a", "user": "b", "dbName": "c", "password": "e", "cdc": "changetables"})
+	assert.Nil(t, err)
+	assert.True(t, conn.Streaming)
+}
+
 func TestNewSourceProfileConnectionDynamoDB(t *testing.T) {
 	// Avoid getting/settinng env variables in the unit tests.
 	testCases := []struct {
@@ -142,6 +414,36 @@ func TestNewSourceProfileConnectionDynamoDB(t *testing.T) {
 			params:        map[string]string{"schema-sample-size": "a"},
 			errorExpected: true,
 		},
+		{
+			name:          "valid scan segments and read capacity percent",
+			params:        map[string]string{"scan-segments": "8", "read-capacity-percent": "50"},
+			errorExpected: false,
+		},
+		{
+			name:          "invalid scan segments",
+			params:        map[string]string{"scan-segments": "0"},
+			errorExpected: true,
+		},
+		{
+			name:          "non-numeric scan segments",
+			params:        map[string]string{"scan-segments": "many"},
+			errorExpected: true,
+		},
+		{
+			name:          "read capacity percent out of range",
+			params:        map[string]string{"read-capacity-percent": "150"},
+			errorExpected: true,
+		},
+		{
+			name:          "export dir",
+			params:        map[string]string{"export-dir": "gs://bucket/exports"},
+			errorExpected: false,
+		},
+		{
+			name:          "export dir with streaming",
+			params:        map[string]string{"export-dir": "/tmp/exports", "enableStreaming": "yes"},
+			errorExpected: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -149,3 +451,84 @@ func TestNewSourceProfileConnectionDynamoDB(t *testing.T) {
 		assert.Equal(t, tc.errorExpected, err != nil)
 	}
 }
+
+func TestNewSourceProfileConnectionSQLite(t *testing.T) {
+	dbFile := filepath.Join(t.TempDir(), "test.db")
+	assert.Nil(t, os.WriteFile(dbFile, nil, 0644))
+	testCases := []struct {
+		name          string
+		params        map[string]string
+		want          SourceProfileConnectionSQLite
+		errorExpected bool
+	}{
+		{
+			name:          "no params",
+			params:        map[string]string{},
+			errorExpected: true,
+		},
+		{
+			name:          "file does not exist",
+			params:        map[string]string{"file": filepath.Join(t.TempDir(), "missing.db")},
+			errorExpected: true,
+		},
+		{
+			name:          "valid file",
+			params:        map[string]string{"file": dbFile},
+			want:          SourceProfileConnectionSQLite{File: dbFile},
+			errorExpected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		res, err := NewSourceProfileConnectionSQLite(tc.params)
+		assert.Equal(t, tc.errorExpected, err != nil, tc.name)
+		if err == nil {
+			assert.Equal(t, tc.want, res, tc.name)
+		}
+	}
+}
+
+func TestNewSourceProfileConnectionSpanner(t *testing.T) {
+	testCases := []struct {
+		name          string
+		params        map[string]string
+		want          SourceProfileConnectionSpanner
+		errorExpected bool
+	}{
+		{
+			name:          "no params",
+			params:        map[string]string{},
+			errorExpected: true,
+		},
+		{
+			name:          "no dbName",
+			params:        map[string]string{"project": "p", "instance": "i"},
+			errorExpected: true,
+		},
+		{
+			name:          "valid params",
+			params:        map[string]string{"project": "p", "instance": "i", "dbName": "d"},
+			want:          SourceProfileConnectionSpanner{Project: "p", Instance: "i", Dbname: "d"},
+			errorExpected: false,
+		},
+		{
+			name:          "read timestamp",
+			params:        map[string]string{"project": "p", "instance": "i", "dbName": "d", "readTimestamp": "2022-11-01T10:00:00.5Z"},
+			want:          SourceProfileConnectionSpanner{Project: "p", Instance: "i", Dbname: "d", ReadTimestamp: time.Date(2022, 11, 1, 10, 0, 0, 500000000, time.UTC)},
+			errorExpected: false,
+		},
+		{
+			name:          "invalid read timestamp",
+			params:        map[string]string{"project": "p", "instance": "i", "dbName": "d", "readTimestamp": "yesterday"},
+			errorExpected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		res, err := NewSourceProfileConnectionSpanner(tc.params)
+		assert.Equal(t, tc.errorExpected, err != nil, tc.name)
+		if err == nil {
+			assert.Equal(t, tc.want, res, tc.name)
+		}
+	}
+}
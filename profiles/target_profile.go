@@ -17,6 +17,7 @@ package profiles
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -46,8 +47,32 @@ type TargetProfileConnectionSpanner struct {
 	Instance string
 	Dbname   string
 	Dialect  string
+
+	OversizedValues string // What to do with values exceeding Spanner's limits: OversizedFail if empty, OversizedTruncate or OversizedOffload.
+	OffloadLocation string // Local directory or GCS path (gs://bucket/path) where values are offloaded with OversizedOffload.
+
+	WriteStrategy       string        // How batches of rows are written: WriteStrategyApply if empty, or WriteStrategyMutationGroups.
+	KeyOrdered          bool          // Whether rows are batched by table and primary key order.
+	AdaptiveConcurrency bool          // Whether the number of concurrent writes adapts to their latency.
+	TargetWriteLatency  time.Duration // Latency of writes that AdaptiveConcurrency aims for, if set.
+	CommitStats         bool          // Whether the commit stats of Spanner are requested, to count the mutations written.
 }
 
+// Values of the oversizedValues target-profile param, which sets what is
+// done with STRING and BYTES values that exceed Spanner's size limits.
+const (
+	OversizedFail     = "fail"
+	OversizedTruncate = "truncate"
+	OversizedOffload  = "offload"
+)
+
+// Values of the writeStrategy target-profile param, which sets how batches
+// of rows are written to Spanner.
+const (
+	WriteStrategyApply          = "apply"
+	WriteStrategyMutationGroups = "mutationgroups"
+)
+
 type TargetProfileConnection struct {
 	Ty TargetProfileConnectionType
 	Sp TargetProfileConnectionSpanner
@@ -122,6 +147,20 @@ func (targetProfile *TargetProfile) GetResourceIds(ctx context.Context, now time
 //
 // Example: -target-profile="instance=my-instance1,dbName=my-new-db1"
 // Example: -target-profile="instance=my-instance1,dbName=my-new-db1,dialect=PostgreSQL"
+//
+// STRING and BYTES values that exceed Spanner's size limits fail their row,
+// unless oversizedValues is set to truncate them, or to offload them to
+// offloadLocation and store their URI instead.
+//
+// Example: -target-profile="instance=my-instance1,oversizedValues=offload,offloadLocation=gs://my-bucket/blobs"
+//
+// Batches of rows are committed atomically, unless writeStrategy is set to
+// mutationGroups to commit groups of rows of each batch independently.
+// keyOrdered batches rows by table and primary key order, and
+// adaptiveConcurrency adapts the number of concurrent writes (up to
+// -write-limit) to their latency, aiming for targetWriteLatency.
+//
+// Example: -target-profile="instance=my-instance1,writeStrategy=mutationGroups,keyOrdered=true,adaptiveConcurrency=true"
 func NewTargetProfile(s string) (TargetProfile, error) {
 	params, err := ParseMap(s)
 	if err != nil {
@@ -150,6 +189,42 @@ func NewTargetProfile(s string) (TargetProfile, error) {
 		return TargetProfile{}, fmt.Errorf("dialect not supported %v", sp.Dialect)
 	}
 
+	if oversized, ok := params["oversizedValues"]; ok {
+		sp.OversizedValues = strings.ToLower(oversized)
+		if sp.OversizedValues != OversizedFail && sp.OversizedValues != OversizedTruncate && sp.OversizedValues != OversizedOffload {
+			return TargetProfile{}, fmt.Errorf("unsupported oversizedValues %q, use %q, %q or %q", oversized, OversizedFail, OversizedTruncate, OversizedOffload)
+		}
+	}
+	if location, ok := params["offloadLocation"]; ok {
+		sp.OffloadLocation = location
+	}
+	if (sp.OversizedValues == OversizedOffload) != (sp.OffloadLocation != "") {
+		return TargetProfile{}, fmt.Errorf("offloadLocation must be set if and only if oversizedValues=%s", OversizedOffload)
+	}
+
+	if strategy, ok := params["writeStrategy"]; ok {
+		sp.WriteStrategy = strings.ToLower(strategy)
+		if sp.WriteStrategy != WriteStrategyApply && sp.WriteStrategy != WriteStrategyMutationGroups {
+			return TargetProfile{}, fmt.Errorf("unsupported writeStrategy %q, use %q or %q", strategy, WriteStrategyApply, "mutationGroups")
+		}
+	}
+	for name, v := range map[string]*bool{"keyOrdered": &sp.KeyOrdered, "adaptiveConcurrency": &sp.AdaptiveConcurrency, "commitStats": &sp.CommitStats} {
+		if s, ok := params[name]; ok {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return TargetProfile{}, fmt.Errorf("invalid %s %q, use true or false", name, s)
+			}
+			*v = b
+		}
+	}
+	if latency, ok := params["targetWriteLatency"]; ok {
+		d, err := time.ParseDuration(latency)
+		if err != nil || d <= 0 {
+			return TargetProfile{}, fmt.Errorf("invalid targetWriteLatency %q, use a positive duration such as 500ms", latency)
+		}
+		sp.TargetWriteLatency = d
+	}
+
 	conn := TargetProfileConnection{Ty: TargetProfileConnectionTypeSpanner, Sp: sp}
 	return TargetProfile{Ty: TargetProfileTypeConnection, Conn: conn}, nil
 }
@@ -0,0 +1,72 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profiles
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTargetProfileOversizedValues(t *testing.T) {
+	testCases := []struct {
+		name          string
+		profile       string
+		want          TargetProfileConnectionSpanner
+		errorExpected bool
+	}{
+		{name: "default", profile: "instance=a", want: TargetProfileConnectionSpanner{Instance: "a", Dialect: constants.DIALECT_GOOGLESQL}},
+		{name: "truncate", profile: "instance=a,oversizedValues=Truncate", want: TargetProfileConnectionSpanner{Instance: "a", Dialect: constants.DIALECT_GOOGLESQL, OversizedValues: OversizedTruncate}},
+		{name: "offload", profile: "instance=a,oversizedValues=offload,offloadLocation=gs://b/p", want: TargetProfileConnectionSpanner{Instance: "a", Dialect: constants.DIALECT_GOOGLESQL, OversizedValues: OversizedOffload, OffloadLocation: "gs://b/p"}},
+		{name: "offload without location", profile: "instance=a,oversizedValues=offload", errorExpected: true},
+		{name: "location without offload", profile: "instance=a,offloadLocation=/tmp/blobs", errorExpected: true},
+		{name: "invalid", profile: "instance=a,oversizedValues=skip", errorExpected: true},
+	}
+	for _, tc := range testCases {
+		profile, err := NewTargetProfile(tc.profile)
+		assert.Equal(t, tc.errorExpected, err != nil, tc.name)
+		if tc.errorExpected {
+			continue
+		}
+		assert.Equal(t, tc.want, profile.Conn.Sp, tc.name)
+	}
+}
+
+func TestNewTargetProfileWriteOptions(t *testing.T) {
+	testCases := []struct {
+		name          string
+		profile       string
+		want          TargetProfileConnectionSpanner
+		errorExpected bool
+	}{
+		{name: "mutation groups", profile: "instance=a,writeStrategy=mutationGroups", want: TargetProfileConnectionSpanner{Instance: "a", Dialect: constants.DIALECT_GOOGLESQL, WriteStrategy: WriteStrategyMutationGroups}},
+		{name: "apply", profile: "instance=a,writeStrategy=apply", want: TargetProfileConnectionSpanner{Instance: "a", Dialect: constants.DIALECT_GOOGLESQL, WriteStrategy: WriteStrategyApply}},
+		{name: "key ordered and adaptive", profile: "instance=a,keyOrdered=true,adaptiveConcurrency=true,targetWriteLatency=500ms", want: TargetProfileConnectionSpanner{Instance: "a", Dialect: constants.DIALECT_GOOGLESQL, KeyOrdered: true, AdaptiveConcurrency: true, TargetWriteLatency: 500 * time.Millisecond}},
+		{name: "commit stats", profile: "instance=a,commitStats=true", want: TargetProfileConnectionSpanner{Instance: "a", Dialect: constants.DIALECT_GOOGLESQL, CommitStats: true}},
+		{name: "invalid strategy", profile: "instance=a,writeStrategy=dml", errorExpected: true},
+		{name: "invalid bool", profile: "instance=a,keyOrdered=yes please", errorExpected: true},
+		{name: "invalid latency", profile: "instance=a,targetWriteLatency=-1s", errorExpected: true},
+	}
+	for _, tc := range testCases {
+		profile, err := NewTargetProfile(tc.profile)
+		assert.Equal(t, tc.errorExpected, err != nil, tc.name)
+		if tc.errorExpected {
+			continue
+		}
+		assert.Equal(t, tc.want, profile.Conn.Sp, tc.name)
+	}
+}
@@ -42,6 +42,8 @@ type Table struct {
 	ForeignKeys  []ForeignKey
 	Indexes      []Index
 	Id           string
+	PartitionBy  string   `json:",omitempty"` // Partition key of a partitioned table e.g. "RANGE (logdate)".
+	Partitions   []string `json:",omitempty"` // Partitions whose data is migrated into this table.
 }
 
 // Column represents a database column.
@@ -52,6 +54,7 @@ type Column struct {
 	NotNull bool
 	Ignored Ignored
 	Id      string
+	Checks  []string `json:",omitempty"` // CHECK constraints of the column's type, with the column written as VALUE.
 }
 
 // ForeignKey represents a foreign key.
@@ -103,8 +106,10 @@ type Index struct {
 // Type represents the type of a column.
 type Type struct {
 	Name        string
-	Mods        []int64 // List of modifiers (aka type parameters e.g. varchar(8) or numeric(6, 4).
-	ArrayBounds []int64 // Empty for scalar types.
+	Mods        []int64  // List of modifiers (aka type parameters e.g. varchar(8) or numeric(6, 4).
+	ArrayBounds []int64  // Empty for scalar types.
+	EnumValues  []string `json:",omitempty"` // Allowed values of an enum type.
+	Fields      []Column `json:",omitempty"` // Fields of a composite type.
 }
 
 // Ignored represents column properties/constraints that are not
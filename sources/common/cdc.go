@@ -0,0 +1,291 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	sp "cloud.google.com/go/spanner"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/common/metrics"
+	"github.com/cloudspannerecosystem/harbourbridge/common/telemetry"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+)
+
+// This file holds the parts of the in-process change data capture shared
+// by the MySQL binlog, PostgreSQL logical replication and SQL Server CDC
+// sources: stats, writing changes to Spanner and stopping on Ctrl+C.
+
+// Number of times a write failing because of missing parent data is retried.
+const retryLimit = 100
+
+// StreamingInfo contains information related to processing of the changes
+// streamed from a source database.
+type StreamingInfo struct {
+	Records          map[string]map[string]int64   // Tablewise count of records read from the source, broken down by record type i.e. INSERT, UPDATE & DELETE.
+	BadRecords       map[string]map[string]int64   // Tablewise count of records not converted successfully, broken down by record type.
+	DroppedRecords   map[string]map[string]int64   // Tablewise count of records successfully converted but failed to written on Spanner, broken down by record type.
+	recordsProcessed int64                         // Count of total records processed to Cloud Spanner(includes records which generated error as well).
+	userExit         bool                          // Flag confirming if customer wants to exit or not, (false until user presses Ctrl+C).
+	Unexpecteds      map[string]int64              // Count of unexpected conditions, broken down by condition description.
+	Write            func(ms []*sp.Mutation) error // Writes the given mutations to Cloud Spanner atomically.
+	SampleBadRecords []string                      // Records that generated errors during conversion.
+	SampleBadWrites  []string                      // Records that faced errors while writing to Cloud Spanner.
+	lock             sync.Mutex
+}
+
+func MakeStreamingInfo() *StreamingInfo {
+	return &StreamingInfo{
+		Records:        make(map[string]map[string]int64),
+		BadRecords:     make(map[string]map[string]int64),
+		DroppedRecords: make(map[string]map[string]int64),
+		Unexpecteds:    make(map[string]int64),
+	}
+}
+
+func (info *StreamingInfo) statsAdd(stats map[string]map[string]int64, srcTable, recordType string) {
+	info.lock.Lock()
+	if _, ok := stats[srcTable]; !ok {
+		stats[srcTable] = make(map[string]int64)
+	}
+	stats[srcTable][recordType]++
+	info.lock.Unlock()
+}
+
+// StatsAddRecord increases the count of records read from the source
+// based on the table name and record type.
+func (info *StreamingInfo) StatsAddRecord(srcTable, recordType string) {
+	info.statsAdd(info.Records, srcTable, recordType)
+}
+
+// StatsAddBadRecord increases the count of records which are not successfully converted to
+// Cloud Spanner supported data types based on the table name and record type.
+func (info *StreamingInfo) StatsAddBadRecord(srcTable, recordType string) {
+	info.statsAdd(info.BadRecords, srcTable, recordType)
+}
+
+// StatsAddDroppedRecord increases the count of records which failed while writing to Cloud Spanner
+// based on the table name and record type.
+func (info *StreamingInfo) StatsAddDroppedRecord(srcTable, recordType string) {
+	info.statsAdd(info.DroppedRecords, srcTable, recordType)
+}
+
+// StatsAddRecordProcessed increases the count of total records processed to Cloud Spanner.
+func (info *StreamingInfo) StatsAddRecordProcessed() {
+	info.lock.Lock()
+	info.recordsProcessed++
+	info.lock.Unlock()
+}
+
+// Processed returns the count of total records processed to Cloud Spanner.
+func (info *StreamingInfo) Processed() int64 {
+	info.lock.Lock()
+	defer info.lock.Unlock()
+	return info.recordsProcessed
+}
+
+// Unexpected records stats about corner-cases and conditions
+// that were not expected.
+func (info *StreamingInfo) Unexpected(u string) {
+	info.lock.Lock()
+	internal.VerbosePrintf("Unexpected condition: %s\n", u)
+	// Limit size of unexpected map. If over limit, then only
+	// update existing entries.
+	if _, ok := info.Unexpecteds[u]; ok || len(info.Unexpecteds) < 1000 {
+		info.Unexpecteds[u]++
+	}
+	info.lock.Unlock()
+}
+
+// CollectBadRecord collects a record if record is not successfully converted to Cloud Spanner
+// supported data types.
+func (info *StreamingInfo) CollectBadRecord(recordType, srcTable string, srcCols []string, vals []string) {
+	info.lock.Lock()
+	badRecord := fmt.Sprintf("type=%s table=%s cols=%v data=%v", recordType, srcTable, srcCols, vals)
+	// Cap storage used by sampleBadRecords. Keep at least one bad record and at max 100.
+	if len(info.SampleBadRecords) < 100 {
+		info.SampleBadRecords = append(info.SampleBadRecords, badRecord)
+	}
+	info.lock.Unlock()
+}
+
+// CollectDroppedRecord collects a record if record faces an error while writing to Cloud Spanner.
+func (info *StreamingInfo) CollectDroppedRecord(recordType, spTable string, spCols []string, spVals []interface{}, err error) {
+	info.lock.Lock()
+	droppedRecord := fmt.Sprintf("type=%s table=%s cols=%v data=%v error=%v", recordType, spTable, spCols, spVals, err)
+	// Cap storage used by sampleBadWrites. Keep at least one dropped record and at max 100.
+	if len(info.SampleBadWrites) < 100 {
+		info.SampleBadWrites = append(info.SampleBadWrites, droppedRecord)
+	}
+	info.lock.Unlock()
+}
+
+// Exit records that the user asked to stop streaming.
+func (info *StreamingInfo) Exit() {
+	info.lock.Lock()
+	info.userExit = true
+	info.lock.Unlock()
+}
+
+// Exited reports whether the user asked to stop streaming.
+func (info *StreamingInfo) Exited() bool {
+	info.lock.Lock()
+	defer info.lock.Unlock()
+	return info.userExit
+}
+
+// SetWriter initializes the write function used to write mutations to Cloud Spanner.
+func (info *StreamingInfo) SetWriter(client *sp.Client, conv *internal.Conv) {
+	info.Write = func(ms []*sp.Mutation) error {
+		migrationData := metrics.GetMigrationData(conv, "", constants.DataConv)
+		serializedMigrationData, _ := proto.Marshal(migrationData)
+		migrationMetadataValue := base64.StdEncoding.EncodeToString(serializedMigrationData)
+		_, err := client.Apply(metadata.AppendToOutgoingContext(context.Background(), constants.MigrationMetadataKey, migrationMetadataValue), ms)
+		return err
+	}
+}
+
+// parentDataMissingError is used to track errors where insertions fail because of missing parent data.
+func parentDataMissingError(err error) bool {
+	return strings.Contains(err.Error(), "NotFound") && strings.Contains(err.Error(), "Parent row") && strings.Contains(err.Error(), "is missing")
+}
+
+// writeMutations writes mutations to Cloud Spanner. To handle insertions
+// failing because the parent row is written by a transaction we haven't
+// applied yet, a retryLimit is set.
+func (info *StreamingInfo) writeMutations(ms []*sp.Mutation) error {
+	var err error
+	for tryNum := 0; tryNum < retryLimit; tryNum++ {
+		err = info.Write(ms)
+		if err == nil || !parentDataMissingError(err) {
+			break
+		}
+		time.Sleep(4 * time.Second)
+	}
+	return err
+}
+
+// PendingMutation is a change of the source database converted to a
+// Spanner mutation, waiting for the end of its transaction to be written.
+type PendingMutation struct {
+	M          *sp.Mutation
+	SrcTable   string
+	SpTable    string
+	RecordType string
+	Cols       []string
+	Vals       []interface{}
+	// CommitTime is when the change was made to the source database, used
+	// to report the streaming lag. It's ignored if zero.
+	CommitTime time.Time
+}
+
+// Flush writes the changes of a transaction to Spanner, at most batchSize
+// mutations at a time. Small transactions are written atomically; if a
+// write fails, its mutations are retried one at a time so that only the
+// failing records are dropped. The rows written and dropped, and the lag of
+// the changes written, are recorded with telemetry.
+func (info *StreamingInfo) Flush(pending []PendingMutation, batchSize int) {
+	for start := 0; start < len(pending); start += batchSize {
+		end := start + batchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batch := pending[start:end]
+		if info.Write == nil {
+			info.Unexpected("Internal error: flush called but writer not configured")
+			for _, p := range batch {
+				info.StatsAddBadRecord(p.SrcTable, p.RecordType)
+			}
+			continue
+		}
+		var ms []*sp.Mutation
+		for _, p := range batch {
+			ms = append(ms, p.M)
+		}
+		if err := info.writeMutations(ms); err == nil {
+			for _, p := range batch {
+				recordWritten(p)
+			}
+			continue
+		}
+		for _, p := range batch {
+			if err := info.writeMutations([]*sp.Mutation{p.M}); err != nil {
+				info.StatsAddDroppedRecord(p.SrcTable, p.RecordType)
+				info.CollectDroppedRecord(p.RecordType, p.SpTable, p.Cols, p.Vals, err)
+				telemetry.AddRowsDropped(p.SpTable, 1)
+				continue
+			}
+			recordWritten(p)
+		}
+	}
+}
+
+func recordWritten(p PendingMutation) {
+	telemetry.AddRowsWritten(p.SpTable, 1)
+	if !p.CommitTime.IsZero() {
+		telemetry.SetStreamingLag(p.SrcTable, time.Since(p.CommitTime))
+	}
+}
+
+// CatchCtrlC calls info.Exit and then stop when the user presses Ctrl+C.
+// stop should interrupt a pending read of the change stream.
+func (info *StreamingInfo) CatchCtrlC(stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ch
+		info.Exit()
+		stop()
+	}()
+}
+
+// FillConv passes the information related to processing of the changes
+// to conv object for report and bad data file.
+func (info *StreamingInfo) FillConv(conv *internal.Conv) {
+	// Pass Unexpected Conditions
+	for unexpectedCondition, count := range info.Unexpecteds {
+		conv.Unexpected(unexpectedCondition)
+		if _, ok := conv.Stats.Unexpected[unexpectedCondition]; ok {
+			conv.Stats.Unexpected[unexpectedCondition] += (count - 1)
+		}
+	}
+	conv.Audit.StreamingStats.Streaming = true
+	conv.Audit.StreamingStats.TotalRecords = info.Records
+	conv.Audit.StreamingStats.BadRecords = info.BadRecords
+	conv.Audit.StreamingStats.DroppedRecords = info.DroppedRecords
+	conv.Audit.StreamingStats.SampleBadRecords = info.SampleBadRecords
+	conv.Audit.StreamingStats.SampleBadWrites = info.SampleBadWrites
+}
+
+// AbsPath returns the absolute path of path, or path if it can't be
+// determined.
+func AbsPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"testing"
+
+	sp "cloud.google.com/go/spanner"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+)
+
+func TestFlush(t *testing.T) {
+	pending := func(ids ...int64) []PendingMutation {
+		var ps []PendingMutation
+		for _, id := range ids {
+			vals := []interface{}{id}
+			ps = append(ps, PendingMutation{M: sp.InsertOrUpdate("t", []string{"id"}, vals), SrcTable: "t", SpTable: "t", RecordType: "INSERT", Cols: []string{"id"}, Vals: vals})
+		}
+		return ps
+	}
+	tests := []struct {
+		name    string
+		fail    int // Size of the writes that fail, 0 if none does.
+		writes  []int
+		dropped map[string]map[string]int64
+	}{
+		{"all written", 0, []int{2, 1}, map[string]map[string]int64{}},
+		{"batch retried one at a time", 2, []int{2, 1, 1, 1}, map[string]map[string]int64{}},
+		{"records dropped", 1, []int{2, 1, 1}, map[string]map[string]int64{"t": {"INSERT": 1}}},
+	}
+	for _, tc := range tests {
+		info := MakeStreamingInfo()
+		var writes []int
+		info.Write = func(ms []*sp.Mutation) error {
+			writes = append(writes, len(ms))
+			if len(ms) == tc.fail {
+				return fmt.Errorf("write failed")
+			}
+			return nil
+		}
+		info.Flush(pending(1, 2, 3), 2)
+		assert.Equal(t, tc.writes, writes, tc.name)
+		assert.Equal(t, tc.dropped, info.DroppedRecords, tc.name)
+		assert.Equal(t, int(tc.dropped["t"]["INSERT"]), len(info.SampleBadWrites), tc.name)
+	}
+}
+
+func TestFlushWithoutWriter(t *testing.T) {
+	info := MakeStreamingInfo()
+	info.Flush([]PendingMutation{{M: sp.Delete("t", sp.AllKeys()), SrcTable: "t", SpTable: "t", RecordType: "TRUNCATE"}}, 10)
+	assert.Equal(t, map[string]map[string]int64{"t": {"TRUNCATE": 1}}, info.BadRecords)
+	assert.Equal(t, 1, len(info.Unexpecteds))
+}
+
+func TestFillConv(t *testing.T) {
+	info := MakeStreamingInfo()
+	info.StatsAddRecord("t", "INSERT")
+	info.Unexpected("u")
+	info.Unexpected("u")
+	conv := internal.MakeConv()
+	info.FillConv(conv)
+	assert.True(t, conv.Audit.StreamingStats.Streaming)
+	assert.Equal(t, map[string]map[string]int64{"t": {"INSERT": 1}}, conv.Audit.StreamingStats.TotalRecords)
+	assert.Equal(t, int64(2), conv.Stats.Unexpected["u"])
+}
@@ -0,0 +1,36 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// CutoverFile returns the path of the file used to request a cutover of
+// the in-process change data capture persisting its position in stateFile.
+func CutoverFile(stateFile string) string {
+	return stateFile + ".cutover"
+}
+
+// RequestCutover asks the change data capture using stateFile to stop once
+// it has applied all changes made to the source database so far.
+func RequestCutover(stateFile string) error {
+	if _, err := os.Stat(stateFile); err != nil {
+		return fmt.Errorf("can't find cdc state file %s, is streaming running? %v", stateFile, err)
+	}
+	return os.WriteFile(CutoverFile(stateFile), []byte(time.Now().Format(time.RFC3339)), 0644)
+}
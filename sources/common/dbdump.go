@@ -15,22 +15,24 @@
 package common
 
 import (
+	"context"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 )
 
 // DbDump common interface for database dump functions.
 type DbDump interface {
 	GetToDdl() ToDdl
-	ProcessDump(conv *internal.Conv, r *internal.Reader) error
+	ProcessDump(ctx context.Context, conv *internal.Conv, r *internal.Reader) error
 }
 
 // ProcessDbDump reads dump data from r and does schema or data conversion,
 // depending on whether conv is configured for schema mode or data mode.
 // In schema mode, this method incrementally builds a schema (updating conv).
 // In data mode, this method uses this schema to convert data and writes it
-// to Spanner, using the data sink specified in conv.
-func ProcessDbDump(conv *internal.Conv, r *internal.Reader, dbDump DbDump) error {
-	if err := dbDump.ProcessDump(conv, r); err != nil {
+// to Spanner, using the data sink specified in conv. Processing stops with
+// an error once ctx is done.
+func ProcessDbDump(ctx context.Context, conv *internal.Conv, r *internal.Reader, dbDump DbDump) error {
+	if err := dbDump.ProcessDump(ctx, conv, r); err != nil {
 		return err
 	}
 	if conv.SchemaMode() {
@@ -39,11 +39,18 @@ type InfoSchema interface {
 	GetConstraints(conv *internal.Conv, table SchemaAndName) ([]string, map[string][]string, error)
 	GetForeignKeys(conv *internal.Conv, table SchemaAndName) (foreignKeys []schema.ForeignKey, err error)
 	GetIndexes(conv *internal.Conv, table SchemaAndName, colNameIdMp map[string]string) ([]schema.Index, error)
-	ProcessData(conv *internal.Conv, tableId string, srcSchema schema.Table, spCols []string, spSchema ddl.CreateTable) error
+	ProcessData(ctx context.Context, conv *internal.Conv, tableId string, srcSchema schema.Table, spCols []string, spSchema ddl.CreateTable) error
 	StartChangeDataCapture(ctx context.Context, conv *internal.Conv) (map[string]interface{}, error)
 	StartStreamingMigration(ctx context.Context, client *sp.Client, conv *internal.Conv, streamInfo map[string]interface{}) error
 }
 
+// PartitionedInfoSchema is implemented by sources that support partitioned
+// tables. Partitions are not returned by GetTables: they are collapsed into
+// their parent table, and the data of all partitions is read from the parent.
+type PartitionedInfoSchema interface {
+	GetPartitions(table SchemaAndName) (partitionBy string, partitions []string, err error)
+}
+
 // SchemaAndName contains the schema and name for a table
 type SchemaAndName struct {
 	Schema string
@@ -105,13 +112,16 @@ func GenerateSrcSchema(conv *internal.Conv, infoSchema InfoSchema, numWorkers in
 // 'db'. For each table, we extract and convert the data to Spanner data
 // (based on the source and Spanner schemas), and write it to Spanner.
 // If we can't get/process data for a table, we skip that table and process
-// the remaining tables.
-func ProcessData(conv *internal.Conv, infoSchema InfoSchema) {
+// the remaining tables. Once ctx is done, reading stops.
+func ProcessData(ctx context.Context, conv *internal.Conv, infoSchema InfoSchema) {
 	// Tables are ordered in alphabetical order with one exception: interleaved
 	// tables appear after the population of their parent table.
 	tableIds := ddl.GetSortedTableIdsBySpName(conv.SpSchema)
 
 	for _, tableId := range tableIds {
+		if ctx.Err() != nil {
+			return
+		}
 		srcSchema := conv.SrcSchema[tableId]
 		spSchema, ok := conv.SpSchema[tableId]
 		if !ok {
@@ -122,7 +132,7 @@ func ProcessData(conv *internal.Conv, infoSchema InfoSchema) {
 		}
 		// Extract spColds without synthetic primary key columnn id.
 		colIds := RemoveSynthId(conv, tableId, spSchema.ColIds)
-		err := infoSchema.ProcessData(conv, tableId, srcSchema, colIds, spSchema)
+		err := infoSchema.ProcessData(ctx, conv, tableId, srcSchema, colIds, spSchema)
 		if err != nil {
 			return
 		}
@@ -177,6 +187,15 @@ func processTable(conv *internal.Conv, table SchemaAndName, infoSchema InfoSchem
 		return t, fmt.Errorf("couldn't get indexes for table %s.%s: %s", table.Schema, table.Name, err)
 	}
 
+	var partitionBy string
+	var partitions []string
+	if pisi, ok := infoSchema.(PartitionedInfoSchema); ok {
+		partitionBy, partitions, err = pisi.GetPartitions(table)
+		if err != nil {
+			return t, fmt.Errorf("couldn't get partitions for table %s.%s: %s", table.Schema, table.Name, err)
+		}
+	}
+
 	name := infoSchema.GetTableName(table.Schema, table.Name)
 	var schemaPKeys []schema.Key
 	for _, k := range primaryKeys {
@@ -191,6 +210,8 @@ func processTable(conv *internal.Conv, table SchemaAndName, infoSchema InfoSchem
 		ColDefs:      colDefs,
 		PrimaryKeys:  schemaPKeys,
 		Indexes:      indexes,
-		ForeignKeys:  foreignKeys}
+		ForeignKeys:  foreignKeys,
+		PartitionBy:  partitionBy,
+		Partitions:   partitions}
 	return t, nil
 }
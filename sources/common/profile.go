@@ -0,0 +1,259 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// SamplingInfoSchema is implemented by sources that can read a sample of
+// the rows of a table, which is used to profile the data of its columns
+// (see ProfileData).
+type SamplingInfoSchema interface {
+	// GetSampleRows returns up to n rows of the columns colIds of a table,
+	// with values read as for data migration.
+	GetSampleRows(conv *internal.Conv, tableId string, colIds []string, n int64) (*sql.Rows, error)
+}
+
+// maxShortStringLength is the maximum length of the values of a STRING(MAX)
+// column for which a shorter STRING type is suggested.
+const maxShortStringLength = 255
+
+var uuidRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ProfileData reads up to n rows of each table and suggests narrower
+// Spanner types for columns whose data doesn't need the type they are
+// mapped to, e.g. INT64 for NUMERIC columns that only hold integers, or
+// JSON for STRING columns that only hold JSON documents. Suggestions are
+// stored in conv.TypeSuggestions and reported as NarrowerType issues.
+func ProfileData(conv *internal.Conv, infoSchema InfoSchema, n int64) error {
+	sampler, ok := infoSchema.(SamplingInfoSchema)
+	if !ok {
+		return fmt.Errorf("data profiling is not supported for this source")
+	}
+	if n <= 0 {
+		return fmt.Errorf("number of rows to sample must be positive, got %d", n)
+	}
+	var tableIds []string
+	for tableId := range conv.SpSchema {
+		if _, ok := conv.SrcSchema[tableId]; ok {
+			tableIds = append(tableIds, tableId)
+		}
+	}
+	sort.Strings(tableIds)
+	asyncProfileTable := func(tableId string, mutex *sync.Mutex) TaskResult[string] {
+		suggestions, err := profileTable(conv, sampler, tableId, n)
+		mutex.Lock()
+		defer mutex.Unlock()
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Couldn't profile data of table %s: %v", conv.SrcSchema[tableId].Name, err))
+			return TaskResult[string]{tableId, nil}
+		}
+		setTypeSuggestions(conv, tableId, suggestions)
+		return TaskResult[string]{tableId, nil}
+	}
+	_, err := RunParallelTasks(tableIds, DefaultWorkers, asyncProfileTable, false)
+	return err
+}
+
+// profileTable samples the data of the columns of a table whose type
+// could be narrowed, and returns the suggested types by column id.
+func profileTable(conv *internal.Conv, sampler SamplingInfoSchema, tableId string, n int64) (map[string]internal.TypeSuggestion, error) {
+	spTable := conv.SpSchema[tableId]
+	var colIds []string
+	for _, colId := range spTable.ColIds {
+		if _, ok := conv.SrcSchema[tableId].ColDefs[colId]; !ok {
+			continue
+		}
+		if t := spTable.ColDefs[colId].T; !t.IsArray && (t.Name == ddl.Numeric || t.Name == ddl.String) {
+			colIds = append(colIds, colId)
+		}
+	}
+	if len(colIds) == 0 {
+		return nil, nil
+	}
+	rows, err := sampler.GetSampleRows(conv, tableId, colIds, n)
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := profileRows(rows, len(colIds))
+	if err != nil {
+		return nil, err
+	}
+	suggestions := make(map[string]internal.TypeSuggestion)
+	for i, colId := range colIds {
+		if s, ok := profiles[i].suggestType(spTable.ColDefs[colId].T); ok {
+			suggestions[colId] = s
+		}
+	}
+	return suggestions, nil
+}
+
+// setTypeSuggestions replaces the type suggestions of a table.
+func setTypeSuggestions(conv *internal.Conv, tableId string, suggestions map[string]internal.TypeSuggestion) {
+	if conv.TypeSuggestions == nil {
+		conv.TypeSuggestions = make(map[string]map[string]internal.TypeSuggestion)
+	}
+	for colId, issues := range conv.SchemaIssues[tableId] {
+		for i, issue := range issues {
+			if issue == internal.NarrowerType {
+				conv.SchemaIssues[tableId][colId] = append(issues[:i:i], issues[i+1:]...)
+				break
+			}
+		}
+	}
+	delete(conv.TypeSuggestions, tableId)
+	if len(suggestions) == 0 {
+		return
+	}
+	conv.TypeSuggestions[tableId] = suggestions
+	if conv.SchemaIssues[tableId] == nil {
+		conv.SchemaIssues[tableId] = make(map[string][]internal.SchemaIssue)
+	}
+	for colId := range suggestions {
+		conv.SchemaIssues[tableId][colId] = append(conv.SchemaIssues[tableId][colId], internal.NarrowerType)
+	}
+}
+
+func profileRows(rows *sql.Rows, n int) ([]*columnProfile, error) {
+	defer rows.Close()
+	profiles := make([]*columnProfile, n)
+	vals := make([]sql.NullString, n)
+	ptrs := make([]interface{}, n)
+	for i := range vals {
+		profiles[i] = &columnProfile{integers: true, json: true, uuids: true}
+		ptrs[i] = &vals[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		for i, v := range vals {
+			if v.Valid {
+				profiles[i].add(v.String)
+			}
+		}
+	}
+	return profiles, rows.Err()
+}
+
+// columnProfile summarizes the sampled (non-NULL) values of a column.
+type columnProfile struct {
+	values         int64
+	minLen, maxLen int64 // In characters.
+	integers       bool  // All values are integers that fit in INT64.
+	min, max       int64 // Range of the values, if integers.
+	json           bool  // All values are JSON objects or arrays.
+	uuids          bool  // All values are UUIDs.
+}
+
+func (p *columnProfile) add(v string) {
+	l := int64(utf8.RuneCountInString(v))
+	if p.values == 0 || l < p.minLen {
+		p.minLen = l
+	}
+	if l > p.maxLen {
+		p.maxLen = l
+	}
+	if p.integers {
+		i, ok := parseInteger(v)
+		switch {
+		case !ok:
+			p.integers = false
+		case p.values == 0:
+			p.min, p.max = i, i
+		case i < p.min:
+			p.min = i
+		case i > p.max:
+			p.max = i
+		}
+	}
+	if p.json {
+		t := strings.TrimSpace(v)
+		p.json = (strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[")) && json.Valid([]byte(t))
+	}
+	if p.uuids {
+		p.uuids = uuidRegexp.MatchString(v)
+	}
+	p.values++
+}
+
+// parseInteger returns the value of the number v if it's an integer that
+// fits in INT64, e.g. "42" or "42.000".
+func parseInteger(v string) (int64, bool) {
+	v = strings.TrimSpace(v)
+	// Exponents are rejected, since they can make big.Rat very large.
+	if strings.ContainsAny(v, "eE/") {
+		return 0, false
+	}
+	r, ok := new(big.Rat).SetString(v)
+	if !ok || !r.IsInt() || !r.Num().IsInt64() {
+		return 0, false
+	}
+	return r.Num().Int64(), true
+}
+
+// suggestType returns a narrower type than t for the profiled values, if
+// there is one.
+func (p *columnProfile) suggestType(t ddl.Type) (internal.TypeSuggestion, bool) {
+	if p.values == 0 {
+		return internal.TypeSuggestion{}, false
+	}
+	switch t.Name {
+	case ddl.Numeric:
+		if p.integers {
+			return internal.TypeSuggestion{
+				T:      ddl.Type{Name: ddl.Int64},
+				Reason: fmt.Sprintf("all %d sampled values are integers between %d and %d", p.values, p.min, p.max),
+			}, true
+		}
+	case ddl.String:
+		switch {
+		case p.json:
+			return internal.TypeSuggestion{
+				T:      ddl.Type{Name: ddl.JSON},
+				Reason: fmt.Sprintf("all %d sampled values are JSON objects or arrays", p.values),
+			}, true
+		case p.uuids && t.Len > 36:
+			return internal.TypeSuggestion{
+				T:      ddl.Type{Name: ddl.String, Len: 36},
+				Reason: fmt.Sprintf("all %d sampled values are UUIDs", p.values),
+			}, true
+		case p.maxLen == 0:
+		case p.minLen == p.maxLen && p.maxLen < t.Len:
+			return internal.TypeSuggestion{
+				T:      ddl.Type{Name: ddl.String, Len: p.maxLen},
+				Reason: fmt.Sprintf("all %d sampled values have %d characters", p.values, p.maxLen),
+			}, true
+		case t.Len == ddl.MaxLength && p.maxLen <= maxShortStringLength:
+			return internal.TypeSuggestion{
+				T:      ddl.Type{Name: ddl.String, Len: p.maxLen},
+				Reason: fmt.Sprintf("the longest of %d sampled values has %d characters", p.values, p.maxLen),
+			}, true
+		}
+	}
+	return internal.TypeSuggestion{}, false
+}
@@ -0,0 +1,140 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// mockSamplingInfoSchema returns the same sample rows for every table.
+type mockSamplingInfoSchema struct {
+	InfoSchema
+	db     *sql.DB
+	colIds [][]string
+}
+
+func (isi *mockSamplingInfoSchema) GetSampleRows(conv *internal.Conv, tableId string, colIds []string, n int64) (*sql.Rows, error) {
+	isi.colIds = append(isi.colIds, colIds)
+	return isi.db.Query("SELECT sample")
+}
+
+func TestSuggestType(t *testing.T) {
+	tc := []struct {
+		name   string
+		t      ddl.Type
+		values []string
+		want   *ddl.Type
+	}{
+		{"no values", ddl.Type{Name: ddl.Numeric}, nil, nil},
+		{"integers", ddl.Type{Name: ddl.Numeric}, []string{"1", "-20", "300.000"}, &ddl.Type{Name: ddl.Int64}},
+		{"fractions", ddl.Type{Name: ddl.Numeric}, []string{"1", "2.5"}, nil},
+		{"exponents", ddl.Type{Name: ddl.Numeric}, []string{"1e3"}, nil},
+		{"int64 overflow", ddl.Type{Name: ddl.Numeric}, []string{"9223372036854775808"}, nil},
+		{"json", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []string{`{"a": 1}`, ` [1, 2]`}, &ddl.Type{Name: ddl.JSON}},
+		{"json scalars", ddl.Type{Name: ddl.String, Len: 10}, []string{`{"a": 1}`, `"abc"`}, nil},
+		{"uuids", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []string{"123e4567-e89b-12d3-a456-426614174000", "123E4567-E89B-12D3-A456-426614174001"}, &ddl.Type{Name: ddl.String, Len: 36}},
+		{"uuids in STRING(36)", ddl.Type{Name: ddl.String, Len: 36}, []string{"123e4567-e89b-12d3-a456-426614174000"}, nil},
+		{"fixed length", ddl.Type{Name: ddl.String, Len: 50}, []string{"US", "FR", "ÜK"}, &ddl.Type{Name: ddl.String, Len: 2}},
+		{"short strings", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []string{"a", "abc"}, &ddl.Type{Name: ddl.String, Len: 3}},
+		{"short strings in STRING(50)", ddl.Type{Name: ddl.String, Len: 50}, []string{"a", "abc"}, nil},
+		{"empty strings", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []string{""}, nil},
+		{"other types", ddl.Type{Name: ddl.Float64}, []string{"1"}, nil},
+	}
+	for _, tc := range tc {
+		p := &columnProfile{integers: true, json: true, uuids: true}
+		for _, v := range tc.values {
+			p.add(v)
+		}
+		s, ok := p.suggestType(tc.t)
+		if tc.want == nil {
+			assert.False(t, ok, tc.name)
+			continue
+		}
+		assert.True(t, ok, tc.name)
+		assert.Equal(t, *tc.want, s.T, tc.name)
+		assert.NotEmpty(t, s.Reason, tc.name)
+	}
+}
+
+func TestProfileData(t *testing.T) {
+	conv := internal.MakeConv()
+	conv.SrcSchema = map[string]schema.Table{
+		"t1": {
+			Name:   "orders",
+			Id:     "t1",
+			ColIds: []string{"c1", "c2", "c3", "c4"},
+			ColDefs: map[string]schema.Column{
+				"c1": {Name: "id", Id: "c1", Type: schema.Type{Name: "decimal"}},
+				"c2": {Name: "code", Id: "c2", Type: schema.Type{Name: "text"}},
+				"c3": {Name: "price", Id: "c3", Type: schema.Type{Name: "decimal"}},
+				"c4": {Name: "created", Id: "c4", Type: schema.Type{Name: "timestamp"}},
+			},
+		},
+	}
+	conv.SpSchema = map[string]ddl.CreateTable{
+		"t1": {
+			Name:   "orders",
+			Id:     "t1",
+			ColIds: []string{"c1", "c2", "c3", "c4"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"c1": {Name: "id", Id: "c1", T: ddl.Type{Name: ddl.Numeric}},
+				"c2": {Name: "code", Id: "c2", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+				"c3": {Name: "price", Id: "c3", T: ddl.Type{Name: ddl.Numeric}},
+				"c4": {Name: "created", Id: "c4", T: ddl.Type{Name: ddl.Timestamp}},
+			},
+		},
+	}
+	conv.SchemaIssues["t1"] = map[string][]internal.SchemaIssue{"c3": {internal.Widened, internal.NarrowerType}}
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	mock.ExpectQuery("SELECT sample").WillReturnRows(sqlmock.NewRows([]string{"id", "code", "price"}).
+		AddRow("1", "AB", "1.5").
+		AddRow("2", nil, "2").
+		AddRow("3", "CD", nil))
+	isi := &mockSamplingInfoSchema{db: db}
+
+	assert.Nil(t, ProfileData(conv, isi, 10))
+	assert.Equal(t, [][]string{{"c1", "c2", "c3"}}, isi.colIds)
+	assert.Equal(t, ddl.Type{Name: ddl.Int64}, conv.TypeSuggestions["t1"]["c1"].T)
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: 2}, conv.TypeSuggestions["t1"]["c2"].T)
+	assert.NotContains(t, conv.TypeSuggestions["t1"], "c3")
+	assert.Equal(t, map[string][]internal.SchemaIssue{
+		"c1": {internal.NarrowerType},
+		"c2": {internal.NarrowerType},
+		"c3": {internal.Widened},
+	}, conv.SchemaIssues["t1"])
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	mock.ExpectQuery("SELECT sample").WillReturnError(driver.ErrBadConn)
+	assert.Nil(t, ProfileData(conv, isi, 10))
+	assert.Equal(t, int64(1), conv.Unexpecteds())
+	// Suggestions from the previous run are kept.
+	assert.Contains(t, conv.TypeSuggestions["t1"], "c1")
+}
+
+func TestProfileDataUnsupported(t *testing.T) {
+	conv := internal.MakeConv()
+	assert.NotNil(t, ProfileData(conv, nil, 10))
+	assert.NotNil(t, ProfileData(conv, &mockSamplingInfoSchema{}, 0))
+}
@@ -47,6 +47,14 @@ type ToDdl interface {
 	ToSpannerType(conv *internal.Conv, spType string, srcType schema.Type) (ddl.Type, []internal.SchemaIssue)
 }
 
+// ToDdlChecks is implemented by sources whose column types can carry CHECK
+// constraints, such as enums and domains. ToSpannerChecks returns the
+// Spanner check expressions of a column (see ddl.ColumnDef.Checks) of Spanner
+// type spType, and issues for constraints that can't be converted.
+type ToDdlChecks interface {
+	ToSpannerChecks(conv *internal.Conv, srcCol schema.Column, spType ddl.Type) ([]string, []internal.SchemaIssue)
+}
+
 // SchemaToSpannerDDL performs schema conversion from the source DB schema to
 // Spanner. It uses the source schema in conv.SrcSchema, and writes
 // the Spanner schema to conv.SpSchema.
@@ -79,6 +87,12 @@ func SchemaToSpannerDDLHelper(conv *internal.Conv, toddl ToDdl, srcTable schema.
 		}
 		spColIds = append(spColIds, srcColId)
 		ty, issues := toddl.ToSpannerType(conv, "", srcCol.Type)
+		var checks []string
+		if tc, ok := toddl.(ToDdlChecks); ok {
+			var checkIssues []internal.SchemaIssue
+			checks, checkIssues = tc.ToSpannerChecks(conv, srcCol, ty)
+			issues = append(issues, checkIssues...)
+		}
 
 		// TODO(hengfeng): add issues for all elements of srcCol.Ignored.
 		if srcCol.Ignored.ForeignKey {
@@ -103,6 +117,7 @@ func SchemaToSpannerDDLHelper(conv *internal.Conv, toddl ToDdl, srcTable schema.
 			NotNull: srcCol.NotNull,
 			Comment: "From: " + quoteIfNeeded(srcCol.Name) + " " + srcCol.Type.Print(),
 			Id:      srcColId,
+			Checks:  checks,
 		}
 	}
 	comment := "Spanner schema for source table " + quoteIfNeeded(srcTable.Name)
@@ -17,6 +17,7 @@ package common
 import (
 	"fmt"
 	"sort"
+	"strings"
 	"sync"
 
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
@@ -218,3 +219,11 @@ func ToPGDialectType(standardType ddl.Type) ddl.Type {
 	}
 	return standardType
 }
+
+// SpannerLiteral returns s as a string literal of the given Spanner dialect.
+func SpannerLiteral(s string, pg bool) string {
+	if pg {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -15,6 +15,7 @@
 package csv
 
 import (
+	"context"
 	csvReader "encoding/csv"
 	"encoding/json"
 	"fmt"
@@ -184,7 +185,8 @@ func getCSVDataRowCount(r *csvReader.Reader, colNames []string) (int64, error) {
 
 // ProcessCSV writes data across the tables provided in the manifest file. Each table's data can be provided
 // across multiple CSV files hence, the manifest accepts a list of file paths in the input.
-func ProcessCSV(conv *internal.Conv, tables []utils.ManifestTable, nullStr string, delimiter rune) error {
+// Processing stops with an error once ctx is done.
+func ProcessCSV(ctx context.Context, conv *internal.Conv, tables []utils.ManifestTable, nullStr string, delimiter rune) error {
 	tableIds := ddl.GetSortedTableIdsBySpName(conv.SpSchema)
 	nameToFiles := map[string][]string{}
 	for _, table := range tables {
@@ -232,6 +234,10 @@ func ProcessCSV(conv *internal.Conv, tables []utils.ManifestTable, nullStr strin
 			}
 
 			for {
+				if err := ctx.Err(); err != nil {
+					csvFile.Close()
+					return err
+				}
 				values, err := r.Read()
 				if err == io.EOF {
 					break
@@ -256,7 +262,7 @@ func processDataRow(conv *internal.Conv, nullStr, tableName string, srcCols []st
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
 		conv.StatsAddBadRow(tableName, conv.DataMode())
-		conv.CollectBadRow(tableName, srcCols, values)
+		conv.CollectBadRow(tableName, srcCols, values, err)
 	} else {
 		conv.WriteRow(tableName, tableName, cvtCols, cvtVals)
 	}
@@ -15,6 +15,7 @@
 package csv
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"os"
@@ -122,7 +123,7 @@ func TestProcessCSV(t *testing.T) {
 		func(table string, cols []string, vals []interface{}) {
 			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
 		})
-	err := ProcessCSV(conv, tables, "", ',')
+	err := ProcessCSV(context.Background(), conv, tables, "", ',')
 	assert.Nil(t, err)
 	assert.Equal(t, []spannerData{
 		{
@@ -135,6 +136,25 @@ func TestProcessCSV(t *testing.T) {
 	}, rows)
 }
 
+func TestProcessCSV_Cancelled(t *testing.T) {
+	writeCSVs(t)
+	defer cleanupCSVs()
+	tables := getManifestTables()
+
+	conv := buildConv(getCreateTable())
+	ctx, cancel := context.WithCancel(context.Background())
+	rows := 0
+	conv.SetDataMode()
+	conv.SetDataSink(
+		func(table string, cols []string, vals []interface{}) {
+			rows++
+			cancel()
+		})
+	err := ProcessCSV(ctx, conv, tables, "", ',')
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 1, rows)
+}
+
 func TestConvertData(t *testing.T) {
 	singleColTests := []struct {
 		name string
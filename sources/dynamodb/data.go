@@ -40,7 +40,7 @@ func ProcessDataRow(m map[string]*dynamodb.AttributeValue, conv *internal.Conv,
 	} else {
 		conv.Unexpected(fmt.Sprintf("Data conversion error for table %s in column(s) %s\n", srcTableName, badCols))
 		conv.StatsAddBadRow(srcTableName, conv.DataMode())
-		conv.CollectBadRow(srcTableName, srcColNames, srcStrVals)
+		conv.CollectBadRow(srcTableName, srcColNames, srcStrVals, fmt.Errorf("can't convert column(s) %s", badCols))
 	}
 }
 
@@ -152,6 +152,19 @@ func convScalar(attrVal *dynamodb.AttributeValue, srcType string, spType string)
 			}
 			return string(b), nil
 		}
+	case ddl.JSON:
+		switch srcType {
+		case typeMap, typeList:
+			val, err := toJSONValue(attrVal)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert %v to a go struct: %w", attrVal.GoString(), err)
+			}
+			b, err := json.Marshal(val)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert %v to a json string: %w", attrVal.GoString(), err)
+			}
+			return string(b), nil
+		}
 	case ddl.Numeric:
 		switch srcType {
 		case typeNumber:
@@ -216,3 +229,61 @@ func stripNull(a *dynamodb.AttributeValue) (interface{}, error) {
 		return nil, fmt.Errorf("unknown type of AttributeValue: %v", a)
 	}
 }
+
+// toJSONValue converts a dynamodb.AttributeValue to a Go value whose json
+// encoding is the natural JSON representation of the attribute: maps become
+// objects, lists and sets become arrays and NULL becomes null. Numbers are
+// encoded using json.Number so that they are written out exactly as
+// DynamoDB returned them, without a round-trip through float64 (json.Marshal
+// rejects numbers that aren't valid JSON). Binary values are encoded as
+// base64 strings, the default json encoding of []byte.
+func toJSONValue(a *dynamodb.AttributeValue) (interface{}, error) {
+	switch {
+	case a.M != nil:
+		cvtMap := make(map[string]interface{}, len(a.M))
+		for k, v := range a.M {
+			c, err := toJSONValue(v)
+			if err != nil {
+				return nil, err
+			}
+			cvtMap[k] = c
+		}
+		return cvtMap, nil
+	case a.L != nil:
+		cvtList := make([]interface{}, 0, len(a.L))
+		for _, v := range a.L {
+			c, err := toJSONValue(v)
+			if err != nil {
+				return nil, err
+			}
+			cvtList = append(cvtList, c)
+		}
+		return cvtList, nil
+	case a.B != nil:
+		return a.B, nil
+	case a.BOOL != nil:
+		return *a.BOOL, nil
+	case a.BS != nil:
+		return a.BS, nil
+	case a.N != nil:
+		return json.Number(*a.N), nil
+	case a.NS != nil:
+		nums := make([]json.Number, 0, len(a.NS))
+		for _, n := range a.NS {
+			nums = append(nums, json.Number(*n))
+		}
+		return nums, nil
+	case a.NULL != nil:
+		return nil, nil
+	case a.S != nil:
+		return *a.S, nil
+	case a.SS != nil:
+		strs := make([]string, 0, len(a.SS))
+		for _, s := range a.SS {
+			strs = append(strs, *s)
+		}
+		return strs, nil
+	default:
+		return nil, fmt.Errorf("unknown type of AttributeValue: %v", a)
+	}
+}
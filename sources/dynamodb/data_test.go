@@ -184,6 +184,8 @@ func TestConvScalar(t *testing.T) {
 		{"binary set", typeBinarySet, ddl.String, &dynamodb.AttributeValue{BS: binarySetVal}, "[\"ABC\"]"},
 		{"map", typeMap, ddl.String, &dynamodb.AttributeValue{M: mapVal}, "{\"list\":[\"str-1\",\"1234.56789\"]}"},
 		{"list", typeList, ddl.String, &dynamodb.AttributeValue{L: listVal}, "[\"str-1\",\"1234.56789\"]"},
+		{"map to json", typeMap, ddl.JSON, &dynamodb.AttributeValue{M: mapVal}, "{\"list\":[\"str-1\",1234.56789]}"},
+		{"list to json", typeList, ddl.JSON, &dynamodb.AttributeValue{L: listVal}, "[\"str-1\",1234.56789]"},
 		{"string", typeString, ddl.String, &dynamodb.AttributeValue{S: &str}, str},
 		{"string set", typeStringSet, ddl.String, &dynamodb.AttributeValue{SS: stringSetVal}, "[\"str-1\"]"},
 		{"number string", typeNumberString, ddl.String, &dynamodb.AttributeValue{N: &numStr}, numStr},
@@ -255,6 +257,69 @@ func TestStripNull(t *testing.T) {
 	}
 }
 
+func TestToJSONValue(t *testing.T) {
+	str := "str-1"
+	numStr := "1234.56789"
+	bigNumStr := "12345678901234567890123456789012345678"
+	boolTrue := true
+	binaryVal := []byte("ABC")
+	binarySetVal := [][]byte{binaryVal}
+	stringSetVal := []*string{&str}
+	numberSetVal := []*string{&numStr, &bigNumStr}
+	listVal1 := []*dynamodb.AttributeValue{
+		{S: &str},
+	}
+	mapVal1 := map[string]*dynamodb.AttributeValue{
+		"list": {L: listVal1},
+	}
+	listVal2 := []*dynamodb.AttributeValue{
+		{B: binaryVal},
+		{S: &str},
+		{N: &numStr},
+		{BOOL: &boolTrue},
+		{SS: stringSetVal},
+		{BS: binarySetVal},
+		{NS: numberSetVal},
+		{NULL: &boolTrue},
+		{L: listVal1},
+		{M: mapVal1},
+	}
+	mapVal2 := map[string]*dynamodb.AttributeValue{
+		"list": {L: listVal2},
+	}
+	badNumStr := "abc"
+
+	testcases := []struct {
+		name string
+		in   *dynamodb.AttributeValue // Input value for conversion.
+		want string                   // Expected result.
+	}{
+		{"binary", &dynamodb.AttributeValue{B: binaryVal}, "\"QUJD\""},
+		{"string", &dynamodb.AttributeValue{S: &str}, "\"str-1\""},
+		{"number", &dynamodb.AttributeValue{N: &numStr}, "1234.56789"},
+		{"large number", &dynamodb.AttributeValue{N: &bigNumStr}, bigNumStr},
+		{"bool", &dynamodb.AttributeValue{BOOL: &boolTrue}, "true"},
+		{"string set", &dynamodb.AttributeValue{SS: stringSetVal}, "[\"str-1\"]"},
+		{"binary set", &dynamodb.AttributeValue{BS: binarySetVal}, "[\"QUJD\"]"},
+		{"number set", &dynamodb.AttributeValue{NS: numberSetVal}, "[1234.56789," + bigNumStr + "]"},
+		{"null", &dynamodb.AttributeValue{NULL: &boolTrue}, "null"},
+		{"empty list", &dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{}}, "[]"},
+		{"empty map", &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{}}, "{}"},
+		{"list", &dynamodb.AttributeValue{L: listVal2}, "[\"QUJD\",\"str-1\",1234.56789,true,[\"str-1\"],[\"QUJD\"],[1234.56789," + bigNumStr + "],null,[\"str-1\"],{\"list\":[\"str-1\"]}]"},
+		{"map", &dynamodb.AttributeValue{M: mapVal2}, "{\"list\":[\"QUJD\",\"str-1\",1234.56789,true,[\"str-1\"],[\"QUJD\"],[1234.56789," + bigNumStr + "],null,[\"str-1\"],{\"list\":[\"str-1\"]}]}"},
+	}
+	for _, tc := range testcases {
+		v, err := toJSONValue(tc.in)
+		assert.Nil(t, err, fmt.Sprintf("Failed to convert an attribute value: %v", tc.in))
+		b, err := json.Marshal(v)
+		assert.Nil(t, err, fmt.Sprintf("Failed to marshal to a json string: %v", v))
+		assert.Equal(t, tc.want, string(b), tc.name)
+	}
+
+	_, err := convScalar(&dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{{N: &badNumStr}}}, typeList, ddl.JSON)
+	assert.NotNil(t, err)
+}
+
 func buildConv(spTable ddl.CreateTable, srcTable schema.Table) *internal.Conv {
 	conv := internal.MakeConv()
 	conv.SpSchema[spTable.Id] = spTable
@@ -0,0 +1,474 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	sp "cloud.google.com/go/spanner"
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+const (
+	exportManifestSummaryFile = "manifest-summary.json"
+	exportManifestFilesFile   = "manifest-files.json"
+	// exportTableDescriptionFile is an optional file, not written by
+	// DynamoDB, with the output of `aws dynamodb describe-table` for the
+	// exported table. It provides the key schema and secondary indexes,
+	// which are not part of the export.
+	exportTableDescriptionFile = "describe-table.json"
+
+	exportFormatDynamoDBJSON = "DYNAMODB_JSON"
+	exportFormatIon          = "ION"
+)
+
+// ExportInfoSchemaImpl reads DynamoDB tables from the output of DynamoDB's
+// "Export to S3" feature, copied to a local directory or a GCS bucket. It
+// lets us migrate tables without AWS credentials and without consuming the
+// read capacity of the source tables.
+type ExportInfoSchemaImpl struct {
+	SampleSize int64
+	storage    exportStorage
+	exports    map[string]*tableExport
+}
+
+// exportManifestSummary is the content of manifest-summary.json.
+type exportManifestSummary struct {
+	ExportArn    string `json:"exportArn"`
+	TableArn     string `json:"tableArn"`
+	ExportTime   string `json:"exportTime"`
+	ItemCount    int64  `json:"itemCount"`
+	OutputFormat string `json:"outputFormat"`
+}
+
+// exportManifestFile is an entry of manifest-files.json.
+type exportManifestFile struct {
+	ItemCount     int64  `json:"itemCount"`
+	DataFileS3Key string `json:"dataFileS3Key"`
+}
+
+// exportTableDescription is the subset of the output of `aws dynamodb
+// describe-table` that we use.
+type exportTableDescription struct {
+	Table struct {
+		KeySchema              []*dynamodb.KeySchemaElement
+		GlobalSecondaryIndexes []exportIndexDescription
+		LocalSecondaryIndexes  []exportIndexDescription
+	}
+}
+
+type exportIndexDescription struct {
+	IndexName string
+	KeySchema []*dynamodb.KeySchemaElement
+}
+
+// tableExport describes the export of a single table.
+type tableExport struct {
+	table       string
+	summary     exportManifestSummary
+	dataFiles   []string
+	description *exportTableDescription
+}
+
+// NewExportInfoSchemaImpl finds all DynamoDB exports under exportDir, which
+// is either a local directory or a GCS path (gs://bucket/path). Each export
+// is a directory containing manifest-summary.json, manifest-files.json and
+// the data/ directory. If a table was exported more than once, the most
+// recent export is used.
+func NewExportInfoSchemaImpl(exportDir string, sampleSize int64) (ExportInfoSchemaImpl, error) {
+	st, err := newExportStorage(context.Background(), exportDir)
+	if err != nil {
+		return ExportInfoSchemaImpl{}, err
+	}
+	exports, err := findExports(st)
+	if err != nil {
+		return ExportInfoSchemaImpl{}, err
+	}
+	if len(exports) == 0 {
+		return ExportInfoSchemaImpl{}, fmt.Errorf("no DynamoDB export (%s) found in %s", exportManifestSummaryFile, exportDir)
+	}
+	return ExportInfoSchemaImpl{SampleSize: sampleSize, storage: st, exports: exports}, nil
+}
+
+func (isi ExportInfoSchemaImpl) GetToDdl() common.ToDdl {
+	return ToDdlImpl{}
+}
+
+func (isi ExportInfoSchemaImpl) GetTableName(schema string, tableName string) string {
+	return tableName
+}
+
+func (isi ExportInfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
+	var tables []common.SchemaAndName
+	for t := range isi.exports {
+		tables = append(tables, common.SchemaAndName{Name: t})
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+	return tables, nil
+}
+
+func (isi ExportInfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAndName, constraints map[string][]string, primaryKeys []string) (map[string]schema.Column, []string, error) {
+	stats := make(map[string]map[string]int64)
+	var count int64
+	err := isi.readItems(table.Name, func(attrsMap map[string]*dynamodb.AttributeValue) bool {
+		if count >= isi.SampleSize {
+			return false
+		}
+		addItemStats(attrsMap, stats)
+		count++
+		return true
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return inferDataTypes(stats, count, primaryKeys)
+}
+
+func (isi ExportInfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, srcTable string) (interface{}, error) {
+	var rows []map[string]*dynamodb.AttributeValue
+	err := isi.readItems(conv.SrcSchema[srcTable].Name, func(attrsMap map[string]*dynamodb.AttributeValue) bool {
+		rows = append(rows, attrsMap)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// GetRowCount returns the number of items in the export of the table.
+func (isi ExportInfoSchemaImpl) GetRowCount(table common.SchemaAndName) (int64, error) {
+	exp, ok := isi.exports[table.Name]
+	if !ok {
+		return 0, fmt.Errorf("no export found for table %s", table.Name)
+	}
+	return exp.summary.ItemCount, nil
+}
+
+// GetConstraints returns the primary key of the table. Exports don't include
+// the key schema, so we read it from the optional describe-table.json file
+// of the export. Without it, the table has no primary key and a synthetic
+// primary key is added during schema conversion.
+func (isi ExportInfoSchemaImpl) GetConstraints(conv *internal.Conv, table common.SchemaAndName) (primaryKeys []string, constraints map[string][]string, err error) {
+	exp, ok := isi.exports[table.Name]
+	if !ok {
+		return nil, nil, fmt.Errorf("no export found for table %s", table.Name)
+	}
+	if exp.description == nil {
+		conv.Unexpected(fmt.Sprintf("No %s found in the export of table %s: can't determine the primary key", exportTableDescriptionFile, table.Name))
+		return nil, nil, nil
+	}
+	for _, k := range exp.description.Table.KeySchema {
+		primaryKeys = append(primaryKeys, *k.AttributeName)
+	}
+	return primaryKeys, constraints, nil
+}
+
+func (isi ExportInfoSchemaImpl) GetForeignKeys(conv *internal.Conv, table common.SchemaAndName) (foreignKeys []schema.ForeignKey, err error) {
+	return foreignKeys, err
+}
+
+// GetIndexes returns the secondary indexes of the table listed in the
+// optional describe-table.json file of the export.
+func (isi ExportInfoSchemaImpl) GetIndexes(conv *internal.Conv, table common.SchemaAndName, colNameIdMap map[string]string) (indexes []schema.Index, err error) {
+	exp, ok := isi.exports[table.Name]
+	if !ok {
+		return nil, fmt.Errorf("no export found for table %s", table.Name)
+	}
+	if exp.description == nil {
+		return nil, nil
+	}
+	for _, i := range exp.description.Table.GlobalSecondaryIndexes {
+		indexes = append(indexes, getSchemaIndexStruct(i.IndexName, i.KeySchema, colNameIdMap))
+	}
+	for _, i := range exp.description.Table.LocalSecondaryIndexes {
+		indexes = append(indexes, getSchemaIndexStruct(i.IndexName, i.KeySchema, colNameIdMap))
+	}
+	return indexes, nil
+}
+
+// ProcessData performs data conversion for a table of a DynamoDB export. The
+// data files of the export are read one item at a time, so tables are never
+// loaded in memory as a whole. Reading stops once ctx is done.
+func (isi ExportInfoSchemaImpl) ProcessData(ctx context.Context, conv *internal.Conv, tableId string, srcSchema schema.Table, colIds []string, spSchema ddl.CreateTable) error {
+	srcTableName := conv.SrcSchema[tableId].Name
+	err := isi.readItems(srcTableName, func(attrsMap map[string]*dynamodb.AttributeValue) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		ProcessDataRow(attrsMap, conv, tableId, srcSchema, colIds, spSchema)
+		return true
+	})
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTableName, err))
+		return err
+	}
+	return nil
+}
+
+// StartChangeDataCapture is a no-op: exports are static snapshots, so
+// streaming migration isn't supported for them.
+func (isi ExportInfoSchemaImpl) StartChangeDataCapture(ctx context.Context, conv *internal.Conv) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+func (isi ExportInfoSchemaImpl) StartStreamingMigration(ctx context.Context, client *sp.Client, conv *internal.Conv, streamingInfo map[string]interface{}) error {
+	return nil
+}
+
+// readItems calls handle for each item in the export of table, until handle
+// returns false.
+func (isi ExportInfoSchemaImpl) readItems(table string, handle func(map[string]*dynamodb.AttributeValue) bool) error {
+	exp, ok := isi.exports[table]
+	if !ok {
+		return fmt.Errorf("no export found for table %s", table)
+	}
+	for _, f := range exp.dataFiles {
+		more, err := readDataFile(isi.storage, f, exp.summary.OutputFormat, handle)
+		if err != nil {
+			return fmt.Errorf("can't read export data file %s: %v", f, err)
+		}
+		if !more {
+			return nil
+		}
+	}
+	return nil
+}
+
+// readDataFile reads the items of a gzip-compressed export data file. It
+// returns false if handle stopped the iteration.
+func readDataFile(st exportStorage, file, format string, handle func(map[string]*dynamodb.AttributeValue) bool) (bool, error) {
+	rc, err := st.open(file)
+	if err != nil {
+		return false, err
+	}
+	defer rc.Close()
+	gz, err := gzip.NewReader(rc)
+	if err != nil {
+		return false, err
+	}
+	defer gz.Close()
+	var next func() (map[string]*dynamodb.AttributeValue, error)
+	switch format {
+	case exportFormatDynamoDBJSON:
+		dec := json.NewDecoder(gz)
+		next = func() (map[string]*dynamodb.AttributeValue, error) {
+			var line struct {
+				Item map[string]*dynamodb.AttributeValue
+			}
+			if err := dec.Decode(&line); err != nil {
+				return nil, err
+			}
+			return line.Item, nil
+		}
+	case exportFormatIon:
+		next = newIonReader(gz).next
+	default:
+		return false, fmt.Errorf("unsupported export format %s", format)
+	}
+	for {
+		item, err := next()
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if !handle(item) {
+			return false, nil
+		}
+	}
+}
+
+// findExports reads the manifests of all exports in st and returns the
+// exports by table name.
+func findExports(st exportStorage) (map[string]*tableExport, error) {
+	files, err := st.list()
+	if err != nil {
+		return nil, err
+	}
+	exports := make(map[string]*tableExport)
+	for _, f := range files {
+		if path.Base(f) != exportManifestSummaryFile {
+			continue
+		}
+		exp, err := readExport(st, path.Dir(f))
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := exports[exp.table]; ok {
+			if prev.summary.ExportTime > exp.summary.ExportTime {
+				exp, prev = prev, exp
+			}
+			logger.Log.Warn("Ignoring older export of table", zap.String("table", exp.table), zap.String("exportArn", prev.summary.ExportArn))
+		}
+		exports[exp.table] = exp
+	}
+	return exports, nil
+}
+
+// readExport reads the manifests of the export in dir.
+func readExport(st exportStorage, dir string) (*tableExport, error) {
+	exp := &tableExport{}
+	if err := readJSONFile(st, path.Join(dir, exportManifestSummaryFile), &exp.summary); err != nil {
+		return nil, err
+	}
+	// Table ARNs have the form arn:aws:dynamodb:region:account:table/name.
+	i := strings.LastIndex(exp.summary.TableArn, "table/")
+	if i < 0 {
+		return nil, fmt.Errorf("can't get table name from tableArn %q in %s", exp.summary.TableArn, path.Join(dir, exportManifestSummaryFile))
+	}
+	exp.table = exp.summary.TableArn[i+len("table/"):]
+
+	rc, err := st.open(path.Join(dir, exportManifestFilesFile))
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	// manifest-files.json has one JSON object per line.
+	dec := json.NewDecoder(bufio.NewReader(rc))
+	for {
+		var mf exportManifestFile
+		if err := dec.Decode(&mf); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("can't parse %s: %v", path.Join(dir, exportManifestFilesFile), err)
+		}
+		// Data file keys are relative to the S3 bucket; the files are in the
+		// data directory of the export.
+		exp.dataFiles = append(exp.dataFiles, path.Join(dir, "data", path.Base(mf.DataFileS3Key)))
+	}
+
+	var desc exportTableDescription
+	err = readJSONFile(st, path.Join(dir, exportTableDescriptionFile), &desc)
+	switch {
+	case err == nil:
+		exp.description = &desc
+	case !os.IsNotExist(err) && err != storage.ErrObjectNotExist:
+		return nil, err
+	}
+	return exp, nil
+}
+
+func readJSONFile(st exportStorage, file string, v interface{}) error {
+	rc, err := st.open(file)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := json.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("can't parse %s: %v", file, err)
+	}
+	return nil
+}
+
+// exportStorage provides access to the files of DynamoDB exports. Paths are
+// slash-separated and relative to the root directory of the exports.
+type exportStorage interface {
+	// list returns the paths of all files.
+	list() ([]string, error)
+	open(file string) (io.ReadCloser, error)
+}
+
+func newExportStorage(ctx context.Context, exportDir string) (exportStorage, error) {
+	if strings.HasPrefix(exportDir, constants.GCS_SCHEME+"://") {
+		u, err := utils.ParseGCSFilePath(exportDir)
+		if err != nil {
+			return nil, err
+		}
+		client, err := storage.NewClient(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GCS client: %v", err)
+		}
+		return &gcsExportStorage{ctx: ctx, bucket: client.Bucket(u.Host), prefix: strings.TrimPrefix(u.Path, "/")}, nil
+	}
+	return localExportStorage{root: exportDir}, nil
+}
+
+type localExportStorage struct {
+	root string
+}
+
+func (l localExportStorage) list() ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(l.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			rel, err := filepath.Rel(l.root, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return files, err
+}
+
+func (l localExportStorage) open(file string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(l.root, filepath.FromSlash(file)))
+}
+
+type gcsExportStorage struct {
+	ctx    context.Context
+	bucket *storage.BucketHandle
+	prefix string
+}
+
+func (g *gcsExportStorage) list() ([]string, error) {
+	var files []string
+	it := g.bucket.Objects(g.ctx, &storage.Query{Prefix: g.prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't list objects in GCS: %v", err)
+		}
+		files = append(files, strings.TrimPrefix(attrs.Name, g.prefix))
+	}
+}
+
+func (g *gcsExportStorage) open(file string) (io.ReadCloser, error) {
+	return g.bucket.Object(g.prefix + file).NewReader(g.ctx)
+}
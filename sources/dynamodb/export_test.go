@@ -0,0 +1,246 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+const cartDescription = `{
+  "Table": {
+    "TableName": "cart",
+    "KeySchema": [
+      {"AttributeName": "a", "KeyType": "HASH"}
+    ],
+    "GlobalSecondaryIndexes": [
+      {"IndexName": "by_d", "KeySchema": [{"AttributeName": "d", "KeyType": "HASH"}], "IndexStatus": "ACTIVE"}
+    ],
+    "CreationDateTime": "2023-03-22T12:00:00.000000+00:00"
+  }
+}`
+
+// writeExport writes an export of table in the layout used by DynamoDB:
+// dir/manifest-summary.json, dir/manifest-files.json and one gzip-compressed
+// data file per element of dataFiles.
+func writeExport(t *testing.T, dir, table, format, exportTime, description string, dataFiles ...string) {
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "data"), os.ModePerm))
+	var manifestFiles []string
+	var total int
+	for i, data := range dataFiles {
+		name := fmt.Sprintf("file%d.gz", i)
+		f, err := os.Create(filepath.Join(dir, "data", name))
+		assert.Nil(t, err)
+		gz := gzip.NewWriter(f)
+		_, err = gz.Write([]byte(data))
+		assert.Nil(t, err)
+		assert.Nil(t, gz.Close())
+		assert.Nil(t, f.Close())
+		count := strings.Count(data, "Item")
+		total += count
+		manifestFiles = append(manifestFiles, fmt.Sprintf(`{"itemCount":%d,"md5Checksum":"x","etag":"y","dataFileS3Key":"AWSDynamoDB/01234/data/%s"}`, count, name))
+	}
+	summary := fmt.Sprintf(`{"version":"2020-06-30","exportArn":"arn:aws:dynamodb:us-east-1:123:table/%s/export/%s","tableArn":"arn:aws:dynamodb:us-east-1:123:table/%s","exportTime":"%s","manifestFilesS3Key":"AWSDynamoDB/01234/manifest-files.json","itemCount":%d,"outputFormat":"%s"}`,
+		table, exportTime, table, exportTime, total, format)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, exportManifestSummaryFile), []byte(summary), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, exportManifestFilesFile), []byte(strings.Join(manifestFiles, "\n")+"\n"), 0644))
+	if description != "" {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, exportTableDescriptionFile), []byte(description), 0644))
+	}
+}
+
+func TestExportInfoSchemaImpl_ProcessSchema(t *testing.T) {
+	root := t.TempDir()
+	writeExport(t, filepath.Join(root, "AWSDynamoDB", "01234"), "cart", exportFormatDynamoDBJSON, "2023-03-22T12:00:00.000Z", cartDescription,
+		`{"Item":{"a":{"S":"str-1"},"b":{"N":"10.1"},"d":{"BOOL":true}}}`+"\n",
+		`{"Item":{"a":{"S":"str-2"},"b":{"N":"12"},"d":{"BOOL":false}}}`+"\n")
+	writeExport(t, filepath.Join(root, "AWSDynamoDB", "56789"), "product", exportFormatIon, "2023-03-22T12:00:00.000Z", "",
+		`$ion_1_0 {Item:{id:"p1",tags:$dynamodb_SS::["x","y"]}}`+"\n")
+
+	isi, err := NewExportInfoSchemaImpl(root, 10)
+	assert.Nil(t, err)
+	tables, err := isi.GetTables()
+	assert.Nil(t, err)
+	assert.Equal(t, []common.SchemaAndName{{Name: "cart"}, {Name: "product"}}, tables)
+
+	rowCount, err := isi.GetRowCount(common.SchemaAndName{Name: "cart"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), rowCount)
+
+	conv := internal.MakeConv()
+	err = common.ProcessSchema(conv, isi, 1)
+	assert.Nil(t, err)
+	cartId, err := internal.GetTableIdFromSpName(conv.SpSchema, "cart")
+	assert.Nil(t, err)
+	cart := conv.SpSchema[cartId]
+	// Columns are in no particular order.
+	assert.ElementsMatch(t, []string{"a", "b", "d"}, spColNames(cart))
+	assert.Equal(t, ddl.Type{Name: ddl.Numeric}, spColDef(cart, "b").T)
+	assert.Equal(t, []ddl.IndexKey{{ColId: spColDef(cart, "a").Id, Order: 1}}, cart.PrimaryKeys)
+	assert.Equal(t, 1, len(cart.Indexes))
+	assert.Equal(t, "by_d", cart.Indexes[0].Name)
+
+	// Without describe-table.json, the key schema is unknown and a synthetic
+	// primary key is added.
+	productId, err := internal.GetTableIdFromSpName(conv.SpSchema, "product")
+	assert.Nil(t, err)
+	product := conv.SpSchema[productId]
+	assert.ElementsMatch(t, []string{"id", "tags", "synth_id"}, spColNames(product))
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: ddl.MaxLength, IsArray: true}, spColDef(product, "tags").T)
+	assert.Equal(t, int64(1), conv.Unexpecteds())
+}
+
+func TestExportInfoSchemaImpl_ProcessData(t *testing.T) {
+	tableName := "cart"
+	tableId := "t1"
+	cols := []string{"a", "b", "c"}
+	colIds := []string{"c1", "c2", "c3"}
+	spSchema := ddl.CreateTable{
+		Name:   tableName,
+		Id:     tableId,
+		ColIds: colIds,
+		ColDefs: map[string]ddl.ColumnDef{
+			"c1": {Name: "a", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			"c2": {Name: "b", T: ddl.Type{Name: ddl.Numeric}},
+			"c3": {Name: "c", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength, IsArray: true}},
+		},
+		PrimaryKeys: []ddl.IndexKey{{ColId: "c1"}},
+	}
+	srcSchema := schema.Table{
+		Name:   tableName,
+		Id:     tableId,
+		ColIds: colIds,
+		ColDefs: map[string]schema.Column{
+			"c1": {Name: "a", Type: schema.Type{Name: typeString}},
+			"c2": {Name: "b", Type: schema.Type{Name: typeNumber}},
+			"c3": {Name: "c", Type: schema.Type{Name: typeStringSet, ArrayBounds: []int64{-1}}},
+		},
+		PrimaryKeys: []schema.Key{{ColId: "c1"}},
+	}
+	tests := []struct {
+		format string
+		data   []string
+	}{
+		{
+			exportFormatDynamoDBJSON,
+			[]string{
+				`{"Item":{"a":{"S":"str-1"},"b":{"N":"10.1"},"c":{"SS":["x","y"]}}}` + "\n",
+				`{"Item":{"a":{"S":"str-2"}}}` + "\n" + `{"Item":{"a":{"S":"str-3"},"b":{"N":"5"}}}` + "\n",
+			},
+		},
+		{
+			exportFormatIon,
+			[]string{
+				`$ion_1_0 {Item:{a:"str-1",b:10.1,c:$dynamodb_SS::["x","y"]}}` + "\n",
+				`$ion_1_0 {Item:{a:"str-2"}}` + "\n" + `{Item:{a:"str-3",b:5.}}` + "\n",
+			},
+		},
+	}
+	for _, tc := range tests {
+		root := t.TempDir()
+		writeExport(t, root, tableName, tc.format, "2023-03-22T12:00:00.000Z", cartDescription, tc.data...)
+		isi, err := NewExportInfoSchemaImpl(root, 10)
+		assert.Nil(t, err, tc.format)
+		conv := buildConv(spSchema, srcSchema)
+		var rows []spannerData
+		conv.SetDataSink(
+			func(table string, cols []string, vals []interface{}) {
+				rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
+			})
+		err = isi.ProcessData(context.Background(), conv, tableId, conv.SrcSchema[tableId], colIds, spSchema)
+		assert.Nil(t, err, tc.format)
+		assert.Equal(t,
+			[]spannerData{
+				{table: tableName, cols: cols, vals: []interface{}{"str-1", *big.NewRat(101, 10), []string{"x", "y"}}},
+				{table: tableName, cols: cols, vals: []interface{}{"str-2", nil, nil}},
+				{table: tableName, cols: cols, vals: []interface{}{"str-3", *big.NewRat(5, 1), nil}},
+			},
+			rows, tc.format)
+
+		items, err := isi.GetRowsFromTable(conv, tableId)
+		assert.Nil(t, err, tc.format)
+		assert.Equal(t, 3, len(items.([]map[string]*dynamodb.AttributeValue)), tc.format)
+	}
+}
+
+func TestExportInfoSchemaImpl_SampleSize(t *testing.T) {
+	root := t.TempDir()
+	writeExport(t, root, "cart", exportFormatDynamoDBJSON, "2023-03-22T12:00:00.000Z", "",
+		`{"Item":{"a":{"S":"x"}}}`+"\n"+`{"Item":{"a":{"S":"y"}}}`+"\n",
+		`{"Item":{"a":{"N":"1"}}}`+"\n"+`{"Item":{"a":{"N":"2"}}}`+"\n")
+	isi, err := NewExportInfoSchemaImpl(root, 2)
+	assert.Nil(t, err)
+	// Only the first data file is sampled, so the column is a string.
+	colDefs, colIds, err := isi.GetColumns(internal.MakeConv(), common.SchemaAndName{Name: "cart"}, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(colIds))
+	assert.Equal(t, schema.Type{Name: typeString}, colDefs[colIds[0]].Type)
+	assert.True(t, colDefs[colIds[0]].NotNull)
+}
+
+func TestNewExportInfoSchemaImpl(t *testing.T) {
+	// The most recent export of a table is used.
+	root := t.TempDir()
+	writeExport(t, filepath.Join(root, "old"), "cart", exportFormatDynamoDBJSON, "2023-03-21T12:00:00.000Z", "",
+		`{"Item":{"a":{"S":"x"}}}`+"\n")
+	writeExport(t, filepath.Join(root, "new"), "cart", exportFormatDynamoDBJSON, "2023-03-22T12:00:00.000Z", "",
+		`{"Item":{"a":{"S":"x"}}}`+"\n"+`{"Item":{"a":{"S":"y"}}}`+"\n")
+	isi, err := NewExportInfoSchemaImpl(root, 10)
+	assert.Nil(t, err)
+	rowCount, err := isi.GetRowCount(common.SchemaAndName{Name: "cart"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), rowCount)
+
+	// No exports.
+	_, err = NewExportInfoSchemaImpl(t.TempDir(), 10)
+	assert.NotNil(t, err)
+
+	// Invalid manifest.
+	root = t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(root, exportManifestSummaryFile), []byte("{"), 0644))
+	_, err = NewExportInfoSchemaImpl(root, 10)
+	assert.NotNil(t, err)
+}
+
+func spColDef(ct ddl.CreateTable, name string) ddl.ColumnDef {
+	for _, colDef := range ct.ColDefs {
+		if colDef.Name == name {
+			return colDef
+		}
+	}
+	return ddl.ColumnDef{}
+}
+
+func spColNames(ct ddl.CreateTable) []string {
+	var names []string
+	for _, colId := range ct.ColIds {
+		names = append(names, ct.ColDefs[colId].Name)
+	}
+	return names
+}
@@ -0,0 +1,221 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Annotations used by DynamoDB exports to distinguish sets from lists.
+const (
+	ionStringSetAnnotation = "$dynamodb_SS"
+	ionNumberSetAnnotation = "$dynamodb_NS"
+	ionBinarySetAnnotation = "$dynamodb_BS"
+)
+
+// ionReader reads items from a DynamoDB export in Amazon Ion format.
+type ionReader struct {
+	r ion.Reader
+}
+
+func newIonReader(r io.Reader) *ionReader {
+	return &ionReader{r: ion.NewReader(r)}
+}
+
+// next returns the next item of the export, or io.EOF if there are no more
+// items. Each item is stored as a top-level struct of the form {Item:{...}}.
+func (ir *ionReader) next() (map[string]*dynamodb.AttributeValue, error) {
+	for ir.r.Next() {
+		// Skip the Ion version marker ($ion_1_0), which the text reader
+		// returns as a symbol.
+		if ir.r.Type() != ion.StructType {
+			continue
+		}
+		v, err := ir.readValue()
+		if err != nil {
+			return nil, err
+		}
+		item, ok := v.M["Item"]
+		if !ok || item.M == nil {
+			return nil, fmt.Errorf("invalid Ion export item: missing Item struct")
+		}
+		return item.M, nil
+	}
+	if err := ir.r.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// readValue converts the Ion value the reader is positioned on to the
+// corresponding DynamoDB attribute value.
+func (ir *ionReader) readValue() (*dynamodb.AttributeValue, error) {
+	r := ir.r
+	if r.IsNull() {
+		return &dynamodb.AttributeValue{NULL: aws.Bool(true)}, nil
+	}
+	switch r.Type() {
+	case ion.BoolType:
+		b, err := r.BoolValue()
+		if err != nil {
+			return nil, err
+		}
+		return &dynamodb.AttributeValue{BOOL: b}, nil
+	case ion.IntType:
+		n, err := r.BigIntValue()
+		if err != nil {
+			return nil, err
+		}
+		return &dynamodb.AttributeValue{N: aws.String(n.String())}, nil
+	case ion.DecimalType:
+		d, err := r.DecimalValue()
+		if err != nil {
+			return nil, err
+		}
+		return &dynamodb.AttributeValue{N: aws.String(ionDecimalToNumber(d))}, nil
+	case ion.FloatType:
+		f, err := r.FloatValue()
+		if err != nil {
+			return nil, err
+		}
+		if math.IsInf(*f, 0) || math.IsNaN(*f) {
+			return nil, fmt.Errorf("number %v not supported by DynamoDB", *f)
+		}
+		return &dynamodb.AttributeValue{N: aws.String(strconv.FormatFloat(*f, 'g', -1, 64))}, nil
+	case ion.StringType:
+		s, err := r.StringValue()
+		if err != nil {
+			return nil, err
+		}
+		return &dynamodb.AttributeValue{S: s}, nil
+	case ion.SymbolType:
+		sym, err := r.SymbolValue()
+		if err != nil {
+			return nil, err
+		}
+		if sym.Text == nil {
+			return nil, fmt.Errorf("symbol without text")
+		}
+		return &dynamodb.AttributeValue{S: sym.Text}, nil
+	case ion.BlobType:
+		b, err := r.ByteValue()
+		if err != nil {
+			return nil, err
+		}
+		return &dynamodb.AttributeValue{B: b}, nil
+	case ion.StructType:
+		return ir.readStruct()
+	case ion.ListType:
+		return ir.readList()
+	}
+	return nil, fmt.Errorf("unsupported Ion %s value", r.Type())
+}
+
+func (ir *ionReader) readStruct() (*dynamodb.AttributeValue, error) {
+	r := ir.r
+	if err := r.StepIn(); err != nil {
+		return nil, err
+	}
+	m := make(map[string]*dynamodb.AttributeValue)
+	for r.Next() {
+		name, err := r.FieldName()
+		if err != nil {
+			return nil, err
+		}
+		if name == nil || name.Text == nil {
+			return nil, fmt.Errorf("struct field without a name")
+		}
+		v, err := ir.readValue()
+		if err != nil {
+			return nil, fmt.Errorf("can't convert attribute %s: %v", *name.Text, err)
+		}
+		m[*name.Text] = v
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.StepOut(); err != nil {
+		return nil, err
+	}
+	return &dynamodb.AttributeValue{M: m}, nil
+}
+
+// readList reads a list, which is a set if it's annotated with one of the
+// set annotations, e.g. $dynamodb_SS::["a","b"].
+func (ir *ionReader) readList() (*dynamodb.AttributeValue, error) {
+	r := ir.r
+	annotations, err := r.Annotations()
+	if err != nil {
+		return nil, err
+	}
+	var annotation string
+	if len(annotations) > 0 && annotations[0].Text != nil {
+		annotation = *annotations[0].Text
+	}
+	a := &dynamodb.AttributeValue{}
+	switch annotation {
+	case ionStringSetAnnotation:
+		a.SS = []*string{}
+	case ionNumberSetAnnotation:
+		a.NS = []*string{}
+	case ionBinarySetAnnotation:
+		a.BS = [][]byte{}
+	default:
+		a.L = []*dynamodb.AttributeValue{}
+	}
+	if err := r.StepIn(); err != nil {
+		return nil, err
+	}
+	for r.Next() {
+		e, err := ir.readValue()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case a.SS != nil && e.S != nil:
+			a.SS = append(a.SS, e.S)
+		case a.NS != nil && e.N != nil:
+			a.NS = append(a.NS, e.N)
+		case a.BS != nil && e.B != nil:
+			a.BS = append(a.BS, e.B)
+		case a.L != nil:
+			a.L = append(a.L, e)
+		default:
+			return nil, fmt.Errorf("invalid element in %s set", annotation)
+		}
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.StepOut(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// ionDecimalToNumber formats an Ion decimal in a format accepted by
+// DynamoDB (and big.Rat), e.g. 1.5d-3 -> 1.5e-3 and 12. -> 12.
+func ionDecimalToNumber(d *ion.Decimal) string {
+	s := strings.TrimSuffix(d.String(), ".")
+	return strings.Replace(s, "d", "e", 1)
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIonReader(t *testing.T) {
+	data := `$ion_1_0 $ion_symbol_table::{symbols:["Extra"]} {Item:{Id:103.,Title:"Book \"103\"é",Authors:$dynamodb_SS::["Author1","Author2"],
+	Ratings:$dynamodb_NS::[4.5,3d1],Covers:$dynamodb_BS::[{{aGVsbG8=}}],InPublication:false,
+	Dimensions:{Width:8.5,'Height (in)':11.},Tags:["a",1,null],Note:null.string,Thumb:{{ aGk= }}}}
+	// A comment between items.
+	{Item:{Id:-0x1F,Text:'''multi'''
+	'''line'''}}
+	/* trailing
+	   comment */`
+	r := newIonReader(strings.NewReader(data))
+	item, err := r.next()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]*dynamodb.AttributeValue{
+		"Id":            {N: aws.String("103")},
+		"Title":         {S: aws.String("Book \"103\"é")},
+		"Authors":       {SS: []*string{aws.String("Author1"), aws.String("Author2")}},
+		"Ratings":       {NS: []*string{aws.String("4.5"), aws.String("3e1")}},
+		"Covers":        {BS: [][]byte{[]byte("hello")}},
+		"InPublication": {BOOL: aws.Bool(false)},
+		"Dimensions": {M: map[string]*dynamodb.AttributeValue{
+			"Width":       {N: aws.String("8.5")},
+			"Height (in)": {N: aws.String("11")},
+		}},
+		"Tags": {L: []*dynamodb.AttributeValue{
+			{S: aws.String("a")},
+			{N: aws.String("1")},
+			{NULL: aws.Bool(true)},
+		}},
+		"Note":  {NULL: aws.Bool(true)},
+		"Thumb": {B: []byte("hi")},
+	}, item)
+
+	item, err = r.next()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]*dynamodb.AttributeValue{
+		"Id":   {N: aws.String("-31")},
+		"Text": {S: aws.String("multiline")},
+	}, item)
+
+	_, err = r.next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestIonReaderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"missing Item", `{Foo:{a:1}}`},
+		{"unterminated struct", `{Item:{a:1`},
+		{"unterminated string", `{Item:{a:"abc}}`},
+		{"missing comma", `{Item:{a:1 b:2}}`},
+		{"bad number", `{Item:{a:1.2.3}}`},
+		{"infinity", `{Item:{a:+inf}}`},
+		{"bad set element", `{Item:{a:$dynamodb_SS::[1]}}`},
+		{"bad blob", `{Item:{a:{{!!}}}}`},
+	}
+	for _, tc := range tests {
+		_, err := newIonReader(strings.NewReader(tc.data)).next()
+		assert.NotNil(t, err, tc.name)
+	}
+}
+
+func TestIonDecimalToNumber(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"12", "12"},
+		{"12.", "12"},
+		{"-12.50", "-12.50"},
+		{"1.5d-3", "1.5e-3"},
+		{"2.D2", "2e2"},
+		{"-0.", "-0"},
+	}
+	for _, tc := range tests {
+		d, err := ion.ParseDecimal(tc.in)
+		assert.Nil(t, err, tc.in)
+		assert.Equal(t, tc.want, ionDecimalToNumber(d), tc.in)
+	}
+}
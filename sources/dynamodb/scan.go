@@ -0,0 +1,255 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"go.uber.org/zap"
+
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
+)
+
+const (
+	// maxScanRetries is the number of times a throttled Scan request is
+	// retried before giving up.
+	maxScanRetries = 10
+	initialBackoff = 100 * time.Millisecond
+	maxBackoff     = 20 * time.Second
+)
+
+// tableScanner scans a DynamoDB table using parallel scan: the table is
+// split into totalSegments segments which are scanned concurrently. Scan
+// requests that are throttled by DynamoDB are retried with exponential
+// backoff. If limiter is set, it caps the read capacity consumed by the scan.
+type tableScanner struct {
+	client        dynamodbiface.DynamoDBAPI
+	table         string
+	totalSegments int64
+	limiter       *capacityLimiter
+	sleep         func(time.Duration)
+}
+
+// newTableScanner returns a tableScanner for table. If readCapacityPercent
+// is non-zero, the scan is limited to that percentage of the table's
+// provisioned read capacity units. Tables using on-demand capacity don't
+// have provisioned RCUs, so the budget is ignored for them.
+func newTableScanner(client dynamodbiface.DynamoDBAPI, table string, totalSegments int64, readCapacityPercent float64) (*tableScanner, error) {
+	if totalSegments < 1 {
+		totalSegments = 1
+	}
+	s := &tableScanner{client: client, table: table, totalSegments: totalSegments, sleep: time.Sleep}
+	if readCapacityPercent <= 0 {
+		return s, nil
+	}
+	result, err := client.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(table)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to make a DescribeTable API call for table %v: %v", table, err)
+	}
+	var rcu int64
+	if pt := result.Table.ProvisionedThroughput; pt != nil && pt.ReadCapacityUnits != nil {
+		rcu = *pt.ReadCapacityUnits
+	}
+	if rcu == 0 {
+		logger.Log.Warn("Ignoring read capacity budget for table with on-demand capacity", zap.String("table", table))
+		return s, nil
+	}
+	s.limiter = newCapacityLimiter(float64(rcu) * readCapacityPercent / 100)
+	return s, nil
+}
+
+// scan reads all items of the table and calls handle for each page of
+// results. Segments are scanned concurrently, but handle is always called
+// from the calling goroutine, so it doesn't need to be thread-safe. scan
+// stops at the first error, or once ctx is done.
+func (s *tableScanner) scan(ctx context.Context, handle func(items []map[string]*dynamodb.AttributeValue)) error {
+	return s.scanSegments(ctx, 0, handle)
+}
+
+// scanSegments is like scan, but reads at most limit items from each
+// segment. A limit of 0 means no limit.
+func (s *tableScanner) scanSegments(parent context.Context, limit int64, handle func(items []map[string]*dynamodb.AttributeValue)) error {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	pages := make(chan []map[string]*dynamodb.AttributeValue)
+	errs := make(chan error, s.totalSegments)
+	wg := &sync.WaitGroup{}
+	for segment := int64(0); segment < s.totalSegments; segment++ {
+		wg.Add(1)
+		go func(segment int64) {
+			defer wg.Done()
+			if err := s.scanSegment(ctx, segment, limit, pages); err != nil {
+				errs <- err
+				cancel()
+			}
+		}(segment)
+	}
+	go func() {
+		wg.Wait()
+		close(pages)
+	}()
+	for items := range pages {
+		handle(items)
+	}
+	close(errs)
+	if err := <-errs; err != nil {
+		return err
+	}
+	// Segments stop without an error when parent is done.
+	return parent.Err()
+}
+
+// scanSegment scans a single segment of the table and sends each page of
+// results to pages. When the table is scanned with a single segment, the
+// Segment and TotalSegments parameters are omitted.
+func (s *tableScanner) scanSegment(ctx context.Context, segment, limit int64, pages chan<- []map[string]*dynamodb.AttributeValue) error {
+	params := &dynamodb.ScanInput{
+		TableName: aws.String(s.table),
+	}
+	if s.totalSegments > 1 {
+		params.Segment = aws.Int64(segment)
+		params.TotalSegments = aws.Int64(s.totalSegments)
+	}
+	if s.limiter != nil {
+		params.ReturnConsumedCapacity = aws.String(dynamodb.ReturnConsumedCapacityTotal)
+	}
+	var count int64
+	for {
+		result, err := s.scanWithRetry(ctx, params)
+		if err != nil {
+			return err
+		}
+		items := result.Items
+		if limit > 0 && count+int64(len(items)) > limit {
+			items = items[:limit-count]
+		}
+		count += int64(len(items))
+		select {
+		case pages <- items:
+		case <-ctx.Done():
+			return nil
+		}
+		if result.LastEvaluatedKey == nil || (limit > 0 && count >= limit) {
+			return nil
+		}
+		// If there are more rows, then continue.
+		params.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// scanWithRetry makes a Scan API call, waiting for read capacity to be
+// available first if the scan has a read capacity budget. Throttled
+// requests are retried with exponential backoff and jitter.
+func (s *tableScanner) scanWithRetry(ctx context.Context, params *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	backoff := initialBackoff
+	for attempt := 0; ; attempt++ {
+		if s.limiter != nil {
+			s.limiter.wait(s.sleep)
+		}
+		result, err := s.client.Scan(params)
+		if err == nil {
+			if s.limiter != nil && result.ConsumedCapacity != nil && result.ConsumedCapacity.CapacityUnits != nil {
+				s.limiter.consume(*result.ConsumedCapacity.CapacityUnits)
+			}
+			return result, nil
+		}
+		if !isThrottlingError(err) || attempt >= maxScanRetries {
+			return nil, fmt.Errorf("failed to make Scan API call for table %v: %v", s.table, err)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// Sleep for a random duration in [backoff/2, backoff) so that
+		// throttled segments don't retry in lockstep.
+		s.sleep(backoff/2 + time.Duration(rand.Int63n(int64(backoff/2))))
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// isThrottlingError returns true if err indicates that DynamoDB rejected the
+// request because the table's (or the account's) capacity was exceeded.
+func isThrottlingError(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case dynamodb.ErrCodeProvisionedThroughputExceededException, dynamodb.ErrCodeRequestLimitExceeded, "ThrottlingException":
+			return true
+		}
+	}
+	return false
+}
+
+// capacityLimiter limits the rate at which read capacity units are consumed.
+// Since the capacity consumed by a Scan request is only known once it
+// completes, the limiter works as a token bucket that is allowed to go into
+// debt: requests wait until the bucket is no longer in debt, and the
+// capacity they consumed is charged once they return.
+type capacityLimiter struct {
+	mu        sync.Mutex
+	rate      float64 // Capacity units per second.
+	available float64
+	last      time.Time
+	now       func() time.Time
+}
+
+func newCapacityLimiter(rate float64) *capacityLimiter {
+	l := &capacityLimiter{rate: rate, now: time.Now}
+	l.last = l.now()
+	return l
+}
+
+// wait blocks until capacity is available.
+func (l *capacityLimiter) wait(sleep func(time.Duration)) {
+	for {
+		l.mu.Lock()
+		l.refill()
+		available := l.available
+		l.mu.Unlock()
+		if available >= 0 {
+			return
+		}
+		sleep(time.Duration(-available / l.rate * float64(time.Second)))
+	}
+}
+
+// consume charges units to the limiter.
+func (l *capacityLimiter) consume(units float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	l.available -= units
+}
+
+// refill adds the capacity accrued since the last refill. Unused capacity
+// is capped at one second worth of capacity to avoid bursts. It must be
+// called with l.mu held.
+func (l *capacityLimiter) refill() {
+	now := l.now()
+	l.available += now.Sub(l.last).Seconds() * l.rate
+	if l.available > l.rate {
+		l.available = l.rate
+	}
+	l.last = now
+}
@@ -0,0 +1,262 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockSegmentedScanClient is a thread-safe mock of DynamoDB that returns
+// pages of results for each segment of a parallel scan. The first throttles
+// calls for each segment fail with ProvisionedThroughputExceededException.
+type mockSegmentedScanClient struct {
+	mu             sync.Mutex
+	segmentOutputs map[int64][]dynamodb.ScanOutput
+	calls          map[int64]int
+	throttles      int
+	inputs         []dynamodb.ScanInput
+	describeOutput *dynamodb.DescribeTableOutput
+	dynamodbiface.DynamoDBAPI
+}
+
+func (m *mockSegmentedScanClient) Scan(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var segment int64
+	if input.Segment != nil {
+		segment = *input.Segment
+	}
+	m.inputs = append(m.inputs, *input)
+	m.calls[segment]++
+	if m.calls[segment] <= m.throttles {
+		return nil, awserr.New(dynamodb.ErrCodeProvisionedThroughputExceededException, "throttled", nil)
+	}
+	outputs := m.segmentOutputs[segment]
+	page := m.calls[segment] - m.throttles - 1
+	if page >= len(outputs) {
+		return nil, fmt.Errorf("unexpected call to Scan: %v", input)
+	}
+	return &outputs[page], nil
+}
+
+func (m *mockSegmentedScanClient) DescribeTable(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	if m.describeOutput == nil {
+		return nil, fmt.Errorf("unexpected call to DescribeTable: %v", input)
+	}
+	return m.describeOutput, nil
+}
+
+func makeSegmentOutputs(segments, pages, itemsPerPage int) map[int64][]dynamodb.ScanOutput {
+	outputs := make(map[int64][]dynamodb.ScanOutput)
+	for s := 0; s < segments; s++ {
+		for p := 0; p < pages; p++ {
+			var items []map[string]*dynamodb.AttributeValue
+			for i := 0; i < itemsPerPage; i++ {
+				items = append(items, map[string]*dynamodb.AttributeValue{
+					"id": {S: aws.String(fmt.Sprintf("%d-%d-%d", s, p, i))},
+				})
+			}
+			out := dynamodb.ScanOutput{Items: items, ConsumedCapacity: &dynamodb.ConsumedCapacity{CapacityUnits: aws.Float64(1)}}
+			if p < pages-1 {
+				out.LastEvaluatedKey = items[len(items)-1]
+			}
+			outputs[int64(s)] = append(outputs[int64(s)], out)
+		}
+	}
+	return outputs
+}
+
+func scannedIds(items []map[string]*dynamodb.AttributeValue) []string {
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, *item["id"].S)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestTableScanner_Scan(t *testing.T) {
+	testCases := []struct {
+		name      string
+		segments  int64
+		throttles int
+	}{
+		{name: "sequential scan", segments: 1},
+		{name: "parallel scan", segments: 4},
+		{name: "parallel scan with throttling", segments: 3, throttles: 2},
+	}
+	for _, tc := range testCases {
+		client := &mockSegmentedScanClient{
+			segmentOutputs: makeSegmentOutputs(int(tc.segments), 2, 3),
+			calls:          make(map[int64]int),
+			throttles:      tc.throttles,
+		}
+		scanner, err := newTableScanner(client, "test", tc.segments, 0)
+		assert.Nil(t, err, tc.name)
+		var sleeps int
+		scanner.sleep = func(time.Duration) { sleeps++ }
+
+		var items []map[string]*dynamodb.AttributeValue
+		err = scanner.scan(context.Background(), func(page []map[string]*dynamodb.AttributeValue) {
+			items = append(items, page...)
+		})
+		assert.Nil(t, err, tc.name)
+		assert.Equal(t, int(tc.segments)*2*3, len(items), tc.name)
+		var want []map[string]*dynamodb.AttributeValue
+		for s := int64(0); s < tc.segments; s++ {
+			for _, out := range client.segmentOutputs[s] {
+				want = append(want, out.Items...)
+			}
+		}
+		assert.Equal(t, scannedIds(want), scannedIds(items), tc.name)
+		assert.Equal(t, int(tc.segments)*tc.throttles, sleeps, tc.name)
+		for _, input := range client.inputs {
+			if tc.segments == 1 {
+				assert.Nil(t, input.TotalSegments, tc.name)
+			} else {
+				assert.Equal(t, tc.segments, *input.TotalSegments, tc.name)
+			}
+			assert.Nil(t, input.ReturnConsumedCapacity, tc.name)
+		}
+	}
+}
+
+func TestTableScanner_ScanErrors(t *testing.T) {
+	// Throttled more times than we retry.
+	client := &mockSegmentedScanClient{
+		segmentOutputs: makeSegmentOutputs(2, 1, 1),
+		calls:          make(map[int64]int),
+		throttles:      maxScanRetries + 1,
+	}
+	scanner, err := newTableScanner(client, "test", 2, 0)
+	assert.Nil(t, err)
+	scanner.sleep = func(time.Duration) {}
+	err = scanner.scan(context.Background(), func([]map[string]*dynamodb.AttributeValue) {})
+	assert.NotNil(t, err)
+
+	// Errors other than throttling are not retried.
+	client = &mockSegmentedScanClient{
+		segmentOutputs: map[int64][]dynamodb.ScanOutput{},
+		calls:          make(map[int64]int),
+	}
+	scanner, err = newTableScanner(client, "test", 1, 0)
+	assert.Nil(t, err)
+	err = scanner.scan(context.Background(), func([]map[string]*dynamodb.AttributeValue) {})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, client.calls[0])
+
+	// Scanning stops once the context is done.
+	client = &mockSegmentedScanClient{
+		segmentOutputs: makeSegmentOutputs(2, 3, 1),
+		calls:          make(map[int64]int),
+	}
+	scanner, err = newTableScanner(client, "test", 2, 0)
+	assert.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	err = scanner.scan(ctx, func([]map[string]*dynamodb.AttributeValue) { cancel() })
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestTableScanner_ReadCapacityPercent(t *testing.T) {
+	client := &mockSegmentedScanClient{
+		segmentOutputs: makeSegmentOutputs(2, 3, 1),
+		calls:          make(map[int64]int),
+		describeOutput: &dynamodb.DescribeTableOutput{
+			Table: &dynamodb.TableDescription{
+				ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{ReadCapacityUnits: aws.Int64(40)},
+			},
+		},
+	}
+	scanner, err := newTableScanner(client, "test", 2, 25)
+	assert.Nil(t, err)
+	assert.NotNil(t, scanner.limiter)
+	assert.Equal(t, float64(10), scanner.limiter.rate)
+	scanner.sleep = func(time.Duration) {}
+	var count int
+	err = scanner.scan(context.Background(), func(page []map[string]*dynamodb.AttributeValue) { count += len(page) })
+	assert.Nil(t, err)
+	assert.Equal(t, 6, count)
+	for _, input := range client.inputs {
+		assert.Equal(t, dynamodb.ReturnConsumedCapacityTotal, *input.ReturnConsumedCapacity)
+	}
+
+	// On-demand tables have no provisioned capacity, so the budget is ignored.
+	client.describeOutput = &dynamodb.DescribeTableOutput{
+		Table: &dynamodb.TableDescription{
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{ReadCapacityUnits: aws.Int64(0)},
+		},
+	}
+	scanner, err = newTableScanner(client, "test", 2, 25)
+	assert.Nil(t, err)
+	assert.Nil(t, scanner.limiter)
+}
+
+func TestCapacityLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := &capacityLimiter{rate: 10, now: func() time.Time { return now }, last: now}
+	var slept []time.Duration
+	sleep := func(d time.Duration) {
+		slept = append(slept, d)
+		now = now.Add(d)
+	}
+
+	// No debt: no wait.
+	l.wait(sleep)
+	assert.Empty(t, slept)
+
+	// Consuming 25 units at 10 units/s puts the limiter 2.5s in debt.
+	l.consume(25)
+	l.wait(sleep)
+	assert.Equal(t, []time.Duration{2500 * time.Millisecond}, slept)
+
+	// Unused capacity accrues up to one second worth of units.
+	now = now.Add(time.Minute)
+	l.consume(10)
+	slept = nil
+	l.wait(sleep)
+	assert.Empty(t, slept)
+	l.consume(5)
+	l.wait(sleep)
+	assert.Equal(t, []time.Duration{500 * time.Millisecond}, slept)
+}
+
+func TestScanSampleData_Segments(t *testing.T) {
+	client := &mockSegmentedScanClient{
+		segmentOutputs: makeSegmentOutputs(4, 2, 2),
+		calls:          make(map[int64]int),
+	}
+	scanner, err := newTableScanner(client, "test", 4, 0)
+	assert.Nil(t, err)
+	stats, count, err := scanSampleData(scanner, 6)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(6), count)
+	assert.Equal(t, map[string]map[string]int64{"id": {typeString: 6}}, stats)
+	// The sample is split across segments: 2 items (a single page) from
+	// each segment.
+	for s := int64(0); s < 4; s++ {
+		assert.Equal(t, 1, client.calls[s])
+	}
+}
@@ -55,6 +55,12 @@ type InfoSchemaImpl struct {
 	DynamoClient        dynamodbiface.DynamoDBAPI
 	DynamoStreamsClient dynamodbstreamsiface.DynamoDBStreamsAPI
 	SampleSize          int64
+	// ScanSegments is the number of segments used for parallel scans of
+	// each table. Values less than 2 mean a sequential scan.
+	ScanSegments int64
+	// ReadCapacityPercent caps the read capacity used by scans to this
+	// percentage of each table's provisioned read capacity. 0 means no cap.
+	ReadCapacityPercent float64
 }
 
 func (isi InfoSchemaImpl) GetToDdl() common.ToDdl {
@@ -85,37 +91,34 @@ func (isi InfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
 }
 
 func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAndName, constraints map[string][]string, primaryKeys []string) (map[string]schema.Column, []string, error) {
-	stats, count, err := scanSampleData(isi.DynamoClient, isi.SampleSize, table.Name)
+	scanner, err := newTableScanner(isi.DynamoClient, table.Name, isi.ScanSegments, isi.ReadCapacityPercent)
+	if err != nil {
+		return nil, nil, err
+	}
+	stats, count, err := scanSampleData(scanner, isi.SampleSize)
 	if err != nil {
 		return nil, nil, err
 	}
 	return inferDataTypes(stats, count, primaryKeys)
 }
 
+// GetRowsFromTable returns all items of a table. Note that ProcessData
+// doesn't use it, since it streams items instead of loading whole tables in
+// memory.
 func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, srcTable string) (interface{}, error) {
 	srcTableName := conv.SrcSchema[srcTable].Name
-	var lastEvaluatedKey map[string]*dynamodb.AttributeValue
-	for {
-		// Build the query input parameters.
-		params := &dynamodb.ScanInput{
-			TableName: aws.String(srcTableName),
-		}
-		if lastEvaluatedKey != nil {
-			params.ExclusiveStartKey = lastEvaluatedKey
-		}
-
-		// Make the DynamoDB Query API call.
-		result, err := isi.DynamoClient.Scan(params)
-		if err != nil {
-			return nil, fmt.Errorf("failed to make Query API call for table %v: %v", srcTableName, err)
-		}
-
-		if result.LastEvaluatedKey == nil {
-			return result.Items, nil
-		}
-		// If there are more rows, then continue.
-		lastEvaluatedKey = result.LastEvaluatedKey
+	scanner, err := newTableScanner(isi.DynamoClient, srcTableName, isi.ScanSegments, isi.ReadCapacityPercent)
+	if err != nil {
+		return nil, err
 	}
+	var rows []map[string]*dynamodb.AttributeValue
+	err = scanner.scan(context.Background(), func(items []map[string]*dynamodb.AttributeValue) {
+		rows = append(rows, items...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
 }
 
 func (isi InfoSchemaImpl) GetRowCount(table common.SchemaAndName) (int64, error) {
@@ -177,20 +180,26 @@ func (isi InfoSchemaImpl) GetIndexes(conv *internal.Conv, table common.SchemaAnd
 }
 
 // ProcessData performs data conversion for DynamoDB database. For each table,
-// we extract data using Scan requests, convert the data to Spanner data (based
-// on the source and Spanner schemas), and write it to Spanner. If we can't
-// get/process data for a table, we skip that table and process the remaining
-// tables.
-func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, tableId string, srcSchema schema.Table, colIds []string, spSchema ddl.CreateTable) error {
-	rows, err := isi.GetRowsFromTable(conv, tableId)
+// we extract data using (parallel) Scan requests, convert the data to Spanner
+// data (based on the source and Spanner schemas), and write it to Spanner.
+// Items are converted as each page of scan results arrives, so tables are
+// never loaded in memory as a whole. If we can't get/process data for a
+// table, we skip that table and process the remaining tables. Scanning stops
+// once ctx is done.
+func (isi InfoSchemaImpl) ProcessData(ctx context.Context, conv *internal.Conv, tableId string, srcSchema schema.Table, colIds []string, spSchema ddl.CreateTable) error {
+	srcTableName := conv.SrcSchema[tableId].Name
+	scanner, err := newTableScanner(isi.DynamoClient, srcTableName, isi.ScanSegments, isi.ReadCapacityPercent)
+	if err == nil {
+		err = scanner.scan(ctx, func(items []map[string]*dynamodb.AttributeValue) {
+			for _, attrsMap := range items {
+				ProcessDataRow(attrsMap, conv, tableId, srcSchema, colIds, spSchema)
+			}
+		})
+	}
 	if err != nil {
-		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", conv.SrcSchema[tableId].Name, err))
+		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTableName, err))
 		return err
 	}
-	// Iterate the items returned.
-	for _, attrsMap := range rows.([]map[string]*dynamodb.AttributeValue) {
-		ProcessDataRow(attrsMap, conv, tableId, srcSchema, colIds, spSchema)
-	}
 	return nil
 }
 
@@ -256,45 +265,46 @@ func getSchemaIndexStruct(indexName string, keySchema []*dynamodb.KeySchemaEleme
 		Name: indexName, Keys: keys}
 }
 
-func scanSampleData(client dynamodbiface.DynamoDBAPI, sampleSize int64, table string) (map[string]map[string]int64, int64, error) {
+// scanSampleData reads up to sampleSize items of a table and returns a map
+// from column name to a count map of the data types seen for the column,
+// along with the number of items read. If the scanner uses several segments,
+// the sample is split evenly across segments so that it covers the whole
+// key space instead of only the first items returned by a sequential scan.
+func scanSampleData(scanner *tableScanner, sampleSize int64) (map[string]map[string]int64, int64, error) {
 	// A map from column name to a count map of possible data types.
 	stats := make(map[string]map[string]int64)
 	var count int64
-	// Build the query input parameters.
-	params := &dynamodb.ScanInput{
-		TableName: aws.String(table),
+	perSegment := sampleSize
+	if scanner.totalSegments > 1 {
+		perSegment = (sampleSize + scanner.totalSegments - 1) / scanner.totalSegments
 	}
-
-	for {
-		// Make the DynamoDB Query API call.
-		result, err := client.Scan(params)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to make Query API call for table %v: %v", table, err)
-		}
-
+	err := scanner.scanSegments(context.Background(), perSegment, func(items []map[string]*dynamodb.AttributeValue) {
 		// Iterate the items returned.
-		for _, attrsMap := range result.Items {
-			for attrName, attr := range attrsMap {
-				if _, ok := stats[attrName]; !ok {
-					stats[attrName] = make(map[string]int64)
-				}
-				incTypeCount(attrName, attr, stats[attrName])
-			}
-
-			count++
+		for _, attrsMap := range items {
 			if count >= sampleSize {
-				return stats, count, nil
+				return
 			}
+			addItemStats(attrsMap, stats)
+			count++
 		}
-		if result.LastEvaluatedKey == nil {
-			break
-		}
-		// If there are more rows, then continue.
-		params.ExclusiveStartKey = result.LastEvaluatedKey
+	})
+	if err != nil {
+		return nil, 0, err
 	}
 	return stats, count, nil
 }
 
+// addItemStats adds the data types of the attributes of an item to stats,
+// a map from column name to a count map of data types.
+func addItemStats(attrsMap map[string]*dynamodb.AttributeValue, stats map[string]map[string]int64) {
+	for attrName, attr := range attrsMap {
+		if _, ok := stats[attrName]; !ok {
+			stats[attrName] = make(map[string]int64)
+		}
+		incTypeCount(attrName, attr, stats[attrName])
+	}
+}
+
 func incTypeCount(attrName string, attr *dynamodb.AttributeValue, s map[string]int64) {
 	switch {
 	case attr.S != nil:
@@ -188,7 +188,7 @@ func TestProcessSchema(t *testing.T) {
 	sampleSize := int64(10000)
 
 	conv := internal.MakeConv()
-	err := common.ProcessSchema(conv, InfoSchemaImpl{client, nil, sampleSize}, 1)
+	err := common.ProcessSchema(conv, InfoSchemaImpl{DynamoClient: client, SampleSize: sampleSize}, 1)
 
 	assert.Nil(t, err)
 	expectedSchema := map[string]ddl.CreateTable{
@@ -288,7 +288,7 @@ func TestProcessSchema_FullDataTypes(t *testing.T) {
 	sampleSize := int64(10000)
 
 	conv := internal.MakeConv()
-	err := common.ProcessSchema(conv, InfoSchemaImpl{client, nil, sampleSize}, 1)
+	err := common.ProcessSchema(conv, InfoSchemaImpl{DynamoClient: client, SampleSize: sampleSize}, 1)
 
 	assert.Nil(t, err)
 	expectedSchema := map[string]ddl.CreateTable{
@@ -301,8 +301,8 @@ func TestProcessSchema_FullDataTypes(t *testing.T) {
 				"c": {Name: "c", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
 				"d": {Name: "d", T: ddl.Type{Name: ddl.Bool}},
 				"e": {Name: "e", T: ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}},
-				"f": {Name: "f", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
-				"g": {Name: "g", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+				"f": {Name: "f", T: ddl.Type{Name: ddl.JSON}},
+				"g": {Name: "g", T: ddl.Type{Name: ddl.JSON}},
 				"h": {Name: "h", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength, IsArray: true}},
 				"i": {Name: "i", T: ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength, IsArray: true}},
 				"j": {Name: "j", T: ddl.Type{Name: ddl.Numeric, IsArray: true}},
@@ -371,7 +371,7 @@ func TestProcessData(t *testing.T) {
 		func(table string, cols []string, vals []interface{}) {
 			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
 		})
-	common.ProcessData(conv, InfoSchemaImpl{client, nil, 10})
+	common.ProcessData(context.Background(), conv, InfoSchemaImpl{DynamoClient: client, SampleSize: 10})
 	assert.Equal(t,
 		[]spannerData{
 			{
@@ -502,7 +502,9 @@ func TestScanSampleData(t *testing.T) {
 		scanOutputs: scanOutputs,
 	}
 
-	stats, _, err := scanSampleData(client, 3, "test")
+	scanner, err := newTableScanner(client, "test", 1, 0)
+	assert.Nil(t, err)
+	stats, _, err := scanSampleData(scanner, 3)
 	assert.Nil(t, err)
 
 	expectedStats := map[string]map[string]int64{
@@ -569,7 +571,7 @@ func TestInfoSchemaImpl_GetIndexes(t *testing.T) {
 
 	dySchema := common.SchemaAndName{Name: "test"}
 	conv := internal.MakeConv()
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{DynamoClient: client, SampleSize: 10}
 	colNameToId := map[string]string{attrNameC: "c1", attrNameD: "c2"}
 	indexes, err := isi.GetIndexes(conv, dySchema, colNameToId)
 	assert.Nil(t, err)
@@ -629,7 +631,7 @@ func TestInfoSchemaImpl_GetConstraints(t *testing.T) {
 
 	dySchema := common.SchemaAndName{Name: "test"}
 	conv := internal.MakeConv()
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{DynamoClient: client, SampleSize: 10}
 	primaryKeys, constraints, err := isi.GetConstraints(conv, dySchema)
 	assert.Nil(t, err)
 
@@ -650,7 +652,7 @@ func TestInfoSchemaImpl_GetTables(t *testing.T) {
 	client := &mockDynamoClient{
 		listTableOutputs: listTableOutputs,
 	}
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{DynamoClient: client, SampleSize: 10}
 	tables, err := isi.GetTables()
 	assert.Nil(t, err)
 	assert.Equal(t, []common.SchemaAndName{{"", "table-a"}, {"", "table-b"}}, tables)
@@ -660,7 +662,7 @@ func TestInfoSchemaImpl_GetTableName(t *testing.T) {
 	tableNameA := "table-a"
 
 	client := &mockDynamoClient{}
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{DynamoClient: client, SampleSize: 10}
 	table := isi.GetTableName("", tableNameA)
 	assert.Equal(t, tableNameA, table)
 }
@@ -704,7 +706,7 @@ func TestInfoSchemaImpl_GetColumns(t *testing.T) {
 	}
 	dySchema := common.SchemaAndName{Name: "test"}
 
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{DynamoClient: client, SampleSize: 10}
 
 	colDefs, _, err := isi.GetColumns(conv, dySchema, nil, nil)
 	assert.Nil(t, err)
@@ -732,7 +734,7 @@ func TestInfoSchemaImpl_GetForeignKeys(t *testing.T) {
 	dySchema := common.SchemaAndName{Name: "test"}
 	conv := internal.MakeConv()
 	client := &mockDynamoClient{}
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{DynamoClient: client, SampleSize: 10}
 	fk, err := isi.GetForeignKeys(conv, dySchema)
 	assert.Nil(t, err)
 	assert.Nil(t, fk)
@@ -755,7 +757,7 @@ func TestInfoSchemaImpl_GetRowCount(t *testing.T) {
 		describeTableOutputs: describeTableOutputs,
 	}
 
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{DynamoClient: client, SampleSize: 10}
 	dySchema := common.SchemaAndName{Name: tableNameA}
 
 	rowCount, err := isi.GetRowCount(dySchema)
@@ -787,7 +789,7 @@ func TestInfoSchemaImpl_GetRowsFromTable(t *testing.T) {
 		scanOutputs: scanOutputs,
 	}
 	tableName := "testtable"
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{DynamoClient: client, SampleSize: 10}
 
 	rows, err := isi.GetRowsFromTable(conv, tableName)
 	assert.Nil(t, err)
@@ -823,7 +825,7 @@ func TestInfoSchemaImpl_ProcessData(t *testing.T) {
 	client := &mockDynamoClient{
 		scanOutputs: scanOutputs,
 	}
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{DynamoClient: client, SampleSize: 10}
 
 	tableName := "cart"
 	tableId := "t1"
@@ -862,7 +864,7 @@ func TestInfoSchemaImpl_ProcessData(t *testing.T) {
 		func(table string, cols []string, vals []interface{}) {
 			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
 		})
-	err := isi.ProcessData(conv, tableId, conv.SrcSchema[tableId],
+	err := isi.ProcessData(context.Background(), conv, tableId, conv.SrcSchema[tableId],
 		colIds, spSchema)
 	assert.Nil(t, err)
 	assert.Equal(t,
@@ -962,7 +964,7 @@ func TestSetRowStats(t *testing.T) {
 		describeTableOutputs: describeTableOutputs,
 	}
 
-	common.SetRowStats(conv, InfoSchemaImpl{client, nil, 10})
+	common.SetRowStats(conv, InfoSchemaImpl{DynamoClient: client, SampleSize: 10})
 
 	assert.Equal(t, tableItemCountA, conv.Stats.Rows[tableNameA])
 	assert.Equal(t, tableItemCountB, conv.Stats.Rows[tableNameB])
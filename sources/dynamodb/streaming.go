@@ -36,6 +36,7 @@ import (
 
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
 	"github.com/cloudspannerecosystem/harbourbridge/common/metrics"
+	"github.com/cloudspannerecosystem/harbourbridge/common/telemetry"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
@@ -344,6 +345,9 @@ func getRecords(streamClient dynamodbstreamsiface.DynamoDBStreamsAPI, shardItera
 func ProcessRecord(conv *internal.Conv, streamInfo *StreamingInfo, record *dynamodbstreams.Record, srcTable string) {
 	eventName := *record.EventName
 	streamInfo.StatsAddRecord(srcTable, eventName)
+	if record.Dynamodb != nil && record.Dynamodb.ApproximateCreationDateTime != nil {
+		telemetry.SetStreamingLag(srcTable, time.Since(*record.Dynamodb.ApproximateCreationDateTime))
+	}
 
 	// todo - write a function that will compute schemas and colums and return
 	tableId, err := internal.GetTableIdFromSrcName(conv.SrcSchema, srcTable)
@@ -337,11 +337,12 @@ func TestProcessRecord(t *testing.T) {
 	valA := "strA"
 	numStr := "10.1"
 	numVal := big.NewRat(101, 10)
+	mapVal := map[string]*dynamodb.AttributeValue{"n": {N: &numStr}}
 
 	tableName := "testtable"
 	tableId := "t1"
-	cols := []string{"a", "b"}
-	colIds := []string{"c1", "c2"}
+	cols := []string{"a", "b", "c"}
+	colIds := []string{"c1", "c2", "c3"}
 	spSchema := ddl.CreateTable{
 		Name:   tableName,
 		Id:     tableId,
@@ -349,6 +350,7 @@ func TestProcessRecord(t *testing.T) {
 		ColDefs: map[string]ddl.ColumnDef{
 			"c1": {Name: "a", Id: "c1", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
 			"c2": {Name: "b", Id: "c2", T: ddl.Type{Name: ddl.Numeric}},
+			"c3": {Name: "c", Id: "c3", T: ddl.Type{Name: ddl.JSON}},
 		},
 		PrimaryKeys: []ddl.IndexKey{{ColId: "c1"}},
 	}
@@ -361,6 +363,7 @@ func TestProcessRecord(t *testing.T) {
 			ColDefs: map[string]schema.Column{
 				"c1": {Name: "a", Id: "c1", Type: schema.Type{Name: typeString}},
 				"c2": {Name: "b", Id: "c2", Type: schema.Type{Name: typeNumber}},
+				"c3": {Name: "c", Id: "c3", Type: schema.Type{Name: typeMap}},
 			},
 			PrimaryKeys: []schema.Key{{ColId: "c1"}},
 		},
@@ -371,6 +374,7 @@ func TestProcessRecord(t *testing.T) {
 			NewImage: map[string]*dynamodb.AttributeValue{
 				"a": {S: &valA},
 				"b": {N: &numStr},
+				"c": {M: mapVal},
 			},
 		},
 		EventName: aws.String("INSERT"),
@@ -381,7 +385,7 @@ func TestProcessRecord(t *testing.T) {
 	writes := 0
 	streamInfo.write = func(m *sp.Mutation) error {
 		writes++
-		assert.Equal(t, m, sp.Insert(tableName, cols, []interface{}{valA, *numVal}))
+		assert.Equal(t, m, sp.Insert(tableName, cols, []interface{}{valA, *numVal, `{"n":10.1}`}))
 		return nil
 	}
 	ProcessRecord(conv, streamInfo, record, tableName)
@@ -33,19 +33,30 @@ type ToDdlImpl struct {
 // mapping.  toSpannerType returns the Spanner type and a list of type
 // conversion issues encountered.
 func (tdi ToDdlImpl) ToSpannerType(conv *internal.Conv, spType string, srcType schema.Type) (ddl.Type, []internal.SchemaIssue) {
-	ty, issues := toSpannerTypeInternal(conv, srcType)
+	ty, issues := toSpannerTypeInternal(conv, srcType, spType)
 	if conv.SpDialect == constants.DIALECT_POSTGRESQL {
 		ty = common.ToPGDialectType(ty)
 	}
 	return ty, issues
 }
 
-func toSpannerTypeInternal(conv *internal.Conv, srcType schema.Type) (ddl.Type, []internal.SchemaIssue) {
+// toSpannerTypeInternal defines the mapping of source types into Spanner
+// types. List and Map attributes map to JSON by default, but can be mapped
+// to STRING (holding the same json encoding) if spType is STRING. For all
+// other types spType is ignored.
+func toSpannerTypeInternal(conv *internal.Conv, srcType schema.Type, spType string) (ddl.Type, []internal.SchemaIssue) {
 	switch srcType.Name {
 	case typeNumber:
 		return ddl.Type{Name: ddl.Numeric}, nil
-	case typeNumberString, typeString, typeList, typeMap:
+	case typeNumberString, typeString:
 		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
+	case typeList, typeMap:
+		switch spType {
+		case ddl.String:
+			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
+		default:
+			return ddl.Type{Name: ddl.JSON}, nil
+		}
 	case typeBool:
 		return ddl.Type{Name: ddl.Bool}, nil
 	case typeBinary:
@@ -72,8 +72,8 @@ func TestToSpannerType(t *testing.T) {
 			"c3":  ddl.ColumnDef{Name: "c", T: ddl.Type{Name: "STRING", Len: 9223372036854775807, IsArray: false}, NotNull: false, Comment: "", Id: "c3"},
 			"c4":  ddl.ColumnDef{Name: "d", T: ddl.Type{Name: "BOOL", Len: 0, IsArray: false}, NotNull: false, Comment: "", Id: "c4"},
 			"c5":  ddl.ColumnDef{Name: "e", T: ddl.Type{Name: "BYTES", Len: 9223372036854775807, IsArray: false}, NotNull: false, Comment: "", Id: "c5"},
-			"c6":  ddl.ColumnDef{Name: "f", T: ddl.Type{Name: "STRING", Len: 9223372036854775807, IsArray: false}, NotNull: false, Comment: "", Id: "c6"},
-			"c7":  ddl.ColumnDef{Name: "g", T: ddl.Type{Name: "STRING", Len: 9223372036854775807, IsArray: false}, NotNull: false, Comment: "", Id: "c7"},
+			"c6":  ddl.ColumnDef{Name: "f", T: ddl.Type{Name: "JSON", Len: 0, IsArray: false}, NotNull: false, Comment: "", Id: "c6"},
+			"c7":  ddl.ColumnDef{Name: "g", T: ddl.Type{Name: "JSON", Len: 0, IsArray: false}, NotNull: false, Comment: "", Id: "c7"},
 			"c8":  ddl.ColumnDef{Name: "h", T: ddl.Type{Name: "STRING", Len: 9223372036854775807, IsArray: true}, NotNull: false, Comment: "", Id: "c8"},
 			"c9":  ddl.ColumnDef{Name: "i", T: ddl.Type{Name: "BYTES", Len: 9223372036854775807, IsArray: true}, NotNull: false, Comment: "", Id: "c9"}},
 		PrimaryKeys: []ddl.IndexKey{ddl.IndexKey{ColId: "c1", Desc: false, Order: 0}, ddl.IndexKey{ColId: "c2", Desc: false, Order: 0}},
@@ -132,8 +132,8 @@ func TestToSpannerPostgreSQLDialectType(t *testing.T) {
 			"c3":  ddl.ColumnDef{Name: "c", T: ddl.Type{Name: "STRING", Len: 9223372036854775807, IsArray: false}, NotNull: false, Comment: "", Id: "c3"},
 			"c4":  ddl.ColumnDef{Name: "d", T: ddl.Type{Name: "BOOL", Len: 0, IsArray: false}, NotNull: false, Comment: "", Id: "c4"},
 			"c5":  ddl.ColumnDef{Name: "e", T: ddl.Type{Name: "BYTES", Len: 9223372036854775807, IsArray: false}, NotNull: false, Comment: "", Id: "c5"},
-			"c6":  ddl.ColumnDef{Name: "f", T: ddl.Type{Name: "STRING", Len: 9223372036854775807, IsArray: false}, NotNull: false, Comment: "", Id: "c6"},
-			"c7":  ddl.ColumnDef{Name: "g", T: ddl.Type{Name: "STRING", Len: 9223372036854775807, IsArray: false}, NotNull: false, Comment: "", Id: "c7"},
+			"c6":  ddl.ColumnDef{Name: "f", T: ddl.Type{Name: "JSON", Len: 0, IsArray: false}, NotNull: false, Comment: "", Id: "c6"},
+			"c7":  ddl.ColumnDef{Name: "g", T: ddl.Type{Name: "JSON", Len: 0, IsArray: false}, NotNull: false, Comment: "", Id: "c7"},
 			"c8":  ddl.ColumnDef{Name: "h", T: ddl.Type{Name: "STRING", Len: 9223372036854775807, IsArray: false}, NotNull: false, Comment: "", Id: "c8"},
 			"c9":  ddl.ColumnDef{Name: "i", T: ddl.Type{Name: "STRING", Len: 9223372036854775807, IsArray: false}, NotNull: false, Comment: "", Id: "c9"}},
 		PrimaryKeys: []ddl.IndexKey{ddl.IndexKey{ColId: "c1", Desc: false, Order: 0}, ddl.IndexKey{ColId: "c2", Desc: false, Order: 0}},
@@ -145,6 +145,18 @@ func TestToSpannerPostgreSQLDialectType(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
+func TestToSpannerTypeListAndMapAsString(t *testing.T) {
+	conv := internal.MakeConv()
+	for _, srcType := range []string{typeList, typeMap} {
+		ty, issues := ToDdlImpl{}.ToSpannerType(conv, "", schema.Type{Name: srcType})
+		assert.Equal(t, ddl.Type{Name: ddl.JSON}, ty)
+		assert.Nil(t, issues)
+		ty, issues = ToDdlImpl{}.ToSpannerType(conv, ddl.String, schema.Type{Name: srcType})
+		assert.Equal(t, ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, ty)
+		assert.Nil(t, issues)
+	}
+}
+
 func dropComments(t *ddl.CreateTable) {
 	t.Comment = ""
 	for _, c := range t.ColIds {
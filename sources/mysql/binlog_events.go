@@ -0,0 +1,281 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/shopspring/decimal"
+)
+
+// This file converts the rows events of the MySQL binary log, as decoded by
+// go-mysql's replication package, to row images in the same text format as
+// values read with the MySQL driver, so that they can be converted with
+// ConvertData.
+
+// transactionPayloadEventType is the type of the events holding compressed
+// transactions, which the replication package doesn't decode.
+const transactionPayloadEventType replication.EventType = 40
+
+// binlogPosition is a position in the binary log. When the source database
+// has GTIDs enabled, streaming restarts from GTIDSet, and File and Pos are
+// informational; otherwise it restarts from File and Pos.
+type binlogPosition struct {
+	File string `json:"file"`
+	Pos  uint64 `json:"position"`
+	// GTIDSet is the set of GTIDs of the transactions applied.
+	GTIDSet string `json:"gtidSet,omitempty"`
+	// LastGTID is the GTID of the last transaction applied.
+	LastGTID string `json:"lastGtid,omitempty"`
+}
+
+func (p binlogPosition) String() string {
+	return fmt.Sprintf("%s:%d", p.File, p.Pos)
+}
+
+// binlogColumn describes a column of a source table, in ordinal order.
+// Table map events only contain column types, so the rest of the column
+// information comes from information_schema.
+type binlogColumn struct {
+	name     string
+	unsigned bool
+	// Values of ENUM and SET columns.
+	values []string
+	// geoJSON is set for spatial columns migrated as GeoJSON.
+	geoJSON bool
+}
+
+type rowsEventKind int
+
+const (
+	rowsInsert rowsEventKind = iota
+	rowsUpdate
+	rowsDelete
+)
+
+// rowsEventKinds maps the types of rows events to their kind.
+var rowsEventKinds = map[replication.EventType]rowsEventKind{
+	replication.WRITE_ROWS_EVENTv0:  rowsInsert,
+	replication.WRITE_ROWS_EVENTv1:  rowsInsert,
+	replication.WRITE_ROWS_EVENTv2:  rowsInsert,
+	replication.UPDATE_ROWS_EVENTv0: rowsUpdate,
+	replication.UPDATE_ROWS_EVENTv1: rowsUpdate,
+	replication.UPDATE_ROWS_EVENTv2: rowsUpdate,
+	replication.DELETE_ROWS_EVENTv0: rowsDelete,
+	replication.DELETE_ROWS_EVENTv1: rowsDelete,
+	replication.DELETE_ROWS_EVENTv2: rowsDelete,
+}
+
+// binlogRow is a row image. values[i] is nil if column i is NULL or
+// not present in the image.
+type binlogRow struct {
+	present []bool
+	values  []*string
+}
+
+// rowChange is a change to a row: before is nil for inserts, after is nil
+// for deletes.
+type rowChange struct {
+	before *binlogRow
+	after  *binlogRow
+}
+
+type rowsEvent struct {
+	kind    rowsEventKind
+	schema  string
+	table   string
+	columns []binlogColumn
+	changes []rowChange
+	// timestamp is when the statement that changed the rows was run on
+	// the source database.
+	timestamp time.Time
+}
+
+// decodeRowsEvent converts the rows of ev, a rows event of the given kind,
+// to row images. cols are the columns of the table.
+func decodeRowsEvent(kind rowsEventKind, ev *replication.RowsEvent, cols []binlogColumn) (*rowsEvent, error) {
+	re := &rowsEvent{kind: kind, schema: string(ev.Table.Schema), table: string(ev.Table.Table), columns: cols}
+	// Update events have a before and an after image for each row.
+	step := 1
+	if kind == rowsUpdate {
+		step = 2
+	}
+	if len(ev.Rows)%step != 0 {
+		return nil, fmt.Errorf("update event for table %s has an odd number of row images", re.table)
+	}
+	for i := 0; i < len(ev.Rows); i += step {
+		row, err := decodeRow(ev, i, cols)
+		if err != nil {
+			return nil, err
+		}
+		var change rowChange
+		switch kind {
+		case rowsInsert:
+			change.after = row
+		case rowsDelete:
+			change.before = row
+		case rowsUpdate:
+			change.before = row
+			if change.after, err = decodeRow(ev, i+1, cols); err != nil {
+				return nil, err
+			}
+		}
+		re.changes = append(re.changes, change)
+	}
+	return re, nil
+}
+
+func decodeRow(ev *replication.RowsEvent, i int, cols []binlogColumn) (*binlogRow, error) {
+	table := string(ev.Table.Table)
+	values := ev.Rows[i]
+	if len(values) != len(cols) || len(values) != len(ev.Table.ColumnType) {
+		return nil, fmt.Errorf("rows event for table %s has %d columns, table map has %d", table, len(values), len(ev.Table.ColumnType))
+	}
+	row := &binlogRow{present: make([]bool, len(values)), values: make([]*string, len(values))}
+	for j := range row.present {
+		row.present[j] = true
+	}
+	if i < len(ev.SkippedColumns) {
+		for _, j := range ev.SkippedColumns[i] {
+			row.present[j] = false
+		}
+	}
+	for j, v := range values {
+		if v == nil || !row.present[j] {
+			continue
+		}
+		s, err := binlogValue(v, ev.Table.ColumnType[j], ev.Table.ColumnMeta[j], cols[j])
+		if err != nil {
+			return nil, fmt.Errorf("can't decode column %s of table %s: %v", cols[j].name, table, err)
+		}
+		row.values[j] = &s
+	}
+	return row, nil
+}
+
+// binlogValue converts a value decoded by the replication package, for a
+// column of type t with metadata meta, to text.
+func binlogValue(v interface{}, t byte, meta uint16, col binlogColumn) (string, error) {
+	switch t = realType(t, meta); t {
+	case gomysql.MYSQL_TYPE_TINY, gomysql.MYSQL_TYPE_SHORT, gomysql.MYSQL_TYPE_INT24, gomysql.MYSQL_TYPE_LONG, gomysql.MYSQL_TYPE_LONGLONG:
+		if n, ok := binlogInt(v); ok {
+			if !col.unsigned {
+				return strconv.FormatInt(n, 10), nil
+			}
+			bits := map[byte]uint{gomysql.MYSQL_TYPE_TINY: 8, gomysql.MYSQL_TYPE_SHORT: 16, gomysql.MYSQL_TYPE_INT24: 24, gomysql.MYSQL_TYPE_LONG: 32, gomysql.MYSQL_TYPE_LONGLONG: 64}[t]
+			u := uint64(n)
+			if bits < 64 {
+				u &= 1<<bits - 1
+			}
+			return strconv.FormatUint(u, 10), nil
+		}
+	case gomysql.MYSQL_TYPE_FLOAT:
+		if f, ok := v.(float32); ok {
+			return strconv.FormatFloat(float64(f), 'g', -1, 32), nil
+		}
+	case gomysql.MYSQL_TYPE_DOUBLE:
+		if f, ok := v.(float64); ok {
+			return strconv.FormatFloat(f, 'g', -1, 64), nil
+		}
+	case gomysql.MYSQL_TYPE_NEWDECIMAL:
+		if d, ok := v.(decimal.Decimal); ok {
+			return d.StringFixed(int32(meta & 0xff)), nil
+		}
+	case gomysql.MYSQL_TYPE_YEAR:
+		if y, ok := v.(int); ok {
+			if y == 0 {
+				return "0000", nil
+			}
+			return strconv.Itoa(y), nil
+		}
+	case gomysql.MYSQL_TYPE_BIT:
+		// BIT values are read with the MySQL driver as big-endian bytes.
+		if n, ok := v.(int64); ok {
+			nbits := int(meta>>8)*8 + int(meta&0xff)
+			b := make([]byte, 8)
+			binary.BigEndian.PutUint64(b, uint64(n))
+			return string(b[8-(nbits+7)/8:]), nil
+		}
+	case gomysql.MYSQL_TYPE_ENUM:
+		if idx, ok := v.(int64); ok {
+			if idx == 0 || int(idx) > len(col.values) {
+				return "", nil
+			}
+			return col.values[idx-1], nil
+		}
+	case gomysql.MYSQL_TYPE_SET:
+		if bits, ok := v.(int64); ok {
+			var members []string
+			for i, m := range col.values {
+				if bits&(1<<uint(i)) != 0 {
+					members = append(members, m)
+				}
+			}
+			return strings.Join(members, ","), nil
+		}
+	case gomysql.MYSQL_TYPE_GEOMETRY:
+		if b, ok := v.([]byte); ok {
+			return decodeGeometry(b, col.geoJSON)
+		}
+	default:
+		// Strings, blobs, JSON and temporal types.
+		switch v := v.(type) {
+		case string:
+			return v, nil
+		case []byte:
+			return string(v), nil
+		}
+	}
+	return "", fmt.Errorf("unexpected value of type %T for column type %d", v, t)
+}
+
+func binlogInt(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	}
+	return 0, false
+}
+
+// realType returns the type of a column from its type in the table map
+// event: ENUM and SET columns are logged as STRING, with their real type
+// in the metadata.
+func realType(t byte, meta uint16) byte {
+	if t != gomysql.MYSQL_TYPE_STRING || meta < 256 {
+		return t
+	}
+	b0 := byte(meta >> 8)
+	if b0&0x30 != 0x30 {
+		// Lengths longer than 255 bytes store 2 extra bits in b0.
+		return b0 | 0x30
+	}
+	return b0
+}
+
+func formatUUID(b []byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,108 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"testing"
+
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinlogValue(t *testing.T) {
+	colors := binlogColumn{values: []string{"red", "green", "blue"}}
+	tests := []struct {
+		name string
+		v    interface{}
+		t    byte
+		meta uint16
+		col  binlogColumn
+		want string
+	}{
+		{"tinyint", int8(-1), gomysql.MYSQL_TYPE_TINY, 0, binlogColumn{}, "-1"},
+		{"tinyint unsigned", int8(-1), gomysql.MYSQL_TYPE_TINY, 0, binlogColumn{unsigned: true}, "255"},
+		{"mediumint unsigned", int32(-1), gomysql.MYSQL_TYPE_INT24, 0, binlogColumn{unsigned: true}, "16777215"},
+		{"int unsigned", int32(-1), gomysql.MYSQL_TYPE_LONG, 0, binlogColumn{unsigned: true}, "4294967295"},
+		{"bigint unsigned", int64(-1), gomysql.MYSQL_TYPE_LONGLONG, 0, binlogColumn{unsigned: true}, "18446744073709551615"},
+		{"float", float32(1.1), gomysql.MYSQL_TYPE_FLOAT, 4, binlogColumn{}, "1.1"},
+		{"double", 2.5, gomysql.MYSQL_TYPE_DOUBLE, 8, binlogColumn{}, "2.5"},
+		{"decimal", decimal.RequireFromString("-1234.50"), gomysql.MYSQL_TYPE_NEWDECIMAL, 10<<8 | 2, binlogColumn{}, "-1234.50"},
+		{"year", 2023, gomysql.MYSQL_TYPE_YEAR, 0, binlogColumn{}, "2023"},
+		{"zero year", 0, gomysql.MYSQL_TYPE_YEAR, 0, binlogColumn{}, "0000"},
+		{"bit(12)", int64(0x0abc), gomysql.MYSQL_TYPE_BIT, 1<<8 | 4, binlogColumn{}, "\x0a\xbc"},
+		{"enum", int64(2), gomysql.MYSQL_TYPE_STRING, uint16(gomysql.MYSQL_TYPE_ENUM)<<8 | 1, colors, "green"},
+		{"empty enum", int64(0), gomysql.MYSQL_TYPE_STRING, uint16(gomysql.MYSQL_TYPE_ENUM)<<8 | 1, colors, ""},
+		{"set", int64(5), gomysql.MYSQL_TYPE_STRING, uint16(gomysql.MYSQL_TYPE_SET)<<8 | 1, colors, "red,blue"},
+		{"char", "abc", gomysql.MYSQL_TYPE_STRING, uint16(gomysql.MYSQL_TYPE_STRING)<<8 | 40, binlogColumn{}, "abc"},
+		{"varchar", "abc", gomysql.MYSQL_TYPE_VARCHAR, 40, binlogColumn{}, "abc"},
+		{"blob", []byte{0, 1}, gomysql.MYSQL_TYPE_BLOB, 2, binlogColumn{}, "\x00\x01"},
+		{"json", `{"a":1}`, gomysql.MYSQL_TYPE_JSON, 4, binlogColumn{}, `{"a":1}`},
+		{"datetime", "2023-03-22 10:00:00.5", gomysql.MYSQL_TYPE_DATETIME2, 1, binlogColumn{}, "2023-03-22 10:00:00.5"},
+		{"geometry", concat([]byte{0, 0, 0, 0}, wkbHeader(1), wkbCoords(1, 2)), gomysql.MYSQL_TYPE_GEOMETRY, 4, binlogColumn{}, "POINT(1 2)"},
+	}
+	for _, tc := range tests {
+		got, err := binlogValue(tc.v, tc.t, tc.meta, tc.col)
+		assert.Nil(t, err, tc.name)
+		assert.Equal(t, tc.want, got, tc.name)
+	}
+	_, err := binlogValue("x", gomysql.MYSQL_TYPE_LONG, 0, binlogColumn{})
+	assert.NotNil(t, err)
+}
+
+func TestDecodeRowsEvent(t *testing.T) {
+	cols := []binlogColumn{{name: "id", unsigned: true}, {name: "name"}}
+	tm := &replication.TableMapEvent{
+		Schema:     []byte("test"),
+		Table:      []byte("product"),
+		ColumnType: []byte{gomysql.MYSQL_TYPE_LONG, gomysql.MYSQL_TYPE_VARCHAR},
+		ColumnMeta: []uint16{0, 40},
+	}
+	ev := &replication.RowsEvent{Table: tm, ColumnCount: 2, Rows: [][]interface{}{{int32(-1), "abc"}, {int32(2), nil}}}
+	got, err := decodeRowsEvent(rowsUpdate, ev, cols)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", got.schema)
+	assert.Equal(t, "product", got.table)
+	assert.Equal(t, 1, len(got.changes))
+	assert.Equal(t, []string{"4294967295", "abc"}, rowValues(got.changes[0].before))
+	assert.Equal(t, []string{"2", "NULL"}, rowValues(got.changes[0].after))
+
+	got, err = decodeRowsEvent(rowsInsert, ev, cols)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(got.changes))
+	assert.Nil(t, got.changes[0].before)
+
+	// Update events need pairs of row images.
+	ev.Rows = ev.Rows[:1]
+	_, err = decodeRowsEvent(rowsUpdate, ev, cols)
+	assert.NotNil(t, err)
+	// The number of columns must match the table.
+	_, err = decodeRowsEvent(rowsDelete, ev, cols[:1])
+	assert.NotNil(t, err)
+}
+
+// rowValues returns the values of a row, with "NULL" for NULL values.
+func rowValues(row *binlogRow) []string {
+	var vals []string
+	for _, v := range row.values {
+		if v == nil {
+			vals = append(vals, "NULL")
+		} else {
+			vals = append(vals, *v)
+		}
+	}
+	return vals
+}
@@ -0,0 +1,808 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sp "cloud.google.com/go/spanner"
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/siddontang/go-log/log"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+)
+
+// This file implements change data capture for MySQL by reading the
+// row-based binary log in-process as a replica would, with go-mysql's
+// replication package, and applying the changes to Spanner. It is the counterpart of the DynamoDB Streams
+// processing in sources/dynamodb/streaming.go.
+
+const (
+	// SkipSnapshotKey is set in the map returned by StartChangeDataCapture
+	// when streaming resumes from a persisted binlog position, in which case
+	// the snapshot migration has already been done.
+	SkipSnapshotKey = "skipSnapshot"
+
+	binlogPositionKey = "binlogPosition"
+	// Maximum number of mutations written to Spanner in one call.
+	binlogBatchSize = 500
+	// Number of consecutive failures to read the binlog before giving up.
+	binlogRetryLimit = 10
+	// How often the source database sends a heartbeat event when it has no
+	// other events to send, so that cutover requests are noticed even if
+	// the source database is idle.
+	binlogHeartbeatPeriod = time.Second
+)
+
+// Record types used for stats.
+const (
+	recordInsert = "INSERT"
+	recordUpdate = "UPDATE"
+	recordDelete = "DELETE"
+)
+
+// before reports whether p is strictly before q in the binary log.
+func (p binlogPosition) before(q binlogPosition) bool {
+	if p.File != q.File {
+		return p.File < q.File
+	}
+	return p.Pos < q.Pos
+}
+
+// loadBinlogState reads the binlog position persisted in stateFile. It
+// returns false if the file doesn't exist.
+func loadBinlogState(stateFile string) (binlogPosition, bool, error) {
+	var pos binlogPosition
+	data, err := os.ReadFile(stateFile)
+	if os.IsNotExist(err) {
+		return pos, false, nil
+	}
+	if err != nil {
+		return pos, false, fmt.Errorf("can't read binlog state file %s: %v", stateFile, err)
+	}
+	if err := json.Unmarshal(data, &pos); err != nil {
+		return pos, false, fmt.Errorf("can't parse binlog state file %s: %v", stateFile, err)
+	}
+	if pos.File == "" {
+		return pos, false, fmt.Errorf("binlog state file %s has no binlog file name", stateFile)
+	}
+	if _, err := gomysql.ParseMysqlGTIDSet(pos.GTIDSet); err != nil {
+		return pos, false, fmt.Errorf("binlog state file %s has an invalid GTID set: %v", stateFile, err)
+	}
+	return pos, true, nil
+}
+
+// saveBinlogState persists pos in stateFile. The position is written to a
+// temporary file first, so that a crash never leaves a truncated state file.
+func saveBinlogState(stateFile string, pos binlogPosition) error {
+	data, err := json.MarshalIndent(pos, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := stateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("can't write binlog state file: %v", err)
+	}
+	if err := os.Rename(tmp, stateFile); err != nil {
+		return fmt.Errorf("can't write binlog state file: %v", err)
+	}
+	return nil
+}
+
+// checkBinlogConfig verifies that the source database writes a binary log
+// that can be used for change data capture.
+func checkBinlogConfig(db *sql.DB) error {
+	var logBin, format, rowImage string
+	err := db.QueryRow("SELECT @@GLOBAL.log_bin, @@GLOBAL.binlog_format, @@GLOBAL.binlog_row_image").Scan(&logBin, &format, &rowImage)
+	if err != nil {
+		return fmt.Errorf("can't read binlog configuration: %v", err)
+	}
+	if logBin != "1" && !strings.EqualFold(logBin, "ON") {
+		return fmt.Errorf("binary logging is disabled, please enable it with log_bin")
+	}
+	if !strings.EqualFold(format, "ROW") {
+		return fmt.Errorf("binlog_format is %s, binlog streaming requires binlog_format=ROW", format)
+	}
+	if !strings.EqualFold(rowImage, "FULL") {
+		return fmt.Errorf("binlog_row_image is %s, binlog streaming requires binlog_row_image=FULL", rowImage)
+	}
+	return nil
+}
+
+// readMasterStatus returns the current position of the binary log.
+func readMasterStatus(db *sql.DB) (binlogPosition, error) {
+	var pos binlogPosition
+	rows, err := db.Query("SHOW MASTER STATUS")
+	if err != nil {
+		// MySQL 8.4 removed SHOW MASTER STATUS.
+		var err2 error
+		rows, err2 = db.Query("SHOW BINARY LOG STATUS")
+		if err2 != nil {
+			return pos, fmt.Errorf("can't read binlog position: %v", err)
+		}
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return pos, fmt.Errorf("can't read binlog position: %v", err)
+	}
+	if !rows.Next() {
+		return pos, fmt.Errorf("can't read binlog position: binary logging is disabled")
+	}
+	vals := make([]sql.NullString, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return pos, fmt.Errorf("can't read binlog position: %v", err)
+	}
+	for i, col := range cols {
+		switch col {
+		case "File":
+			pos.File = vals[i].String
+		case "Position":
+			fmt.Sscanf(vals[i].String, "%d", &pos.Pos)
+		case "Executed_Gtid_Set":
+			pos.GTIDSet = strings.ReplaceAll(vals[i].String, "\n", "")
+		}
+	}
+	if pos.File == "" {
+		return pos, fmt.Errorf("can't read binlog position: no binlog file")
+	}
+	return pos, nil
+}
+
+// queryTableColumns returns the columns of a table, in ordinal order.
+func queryTableColumns(db *sql.DB, dbName, table string) ([]binlogColumn, error) {
+	q := `SELECT column_name, column_type FROM information_schema.columns
+		WHERE table_schema = ? AND table_name = ? ORDER BY ordinal_position`
+	rows, err := db.Query(q, dbName, table)
+	if err != nil {
+		return nil, fmt.Errorf("can't read columns of table %s: %v", table, err)
+	}
+	defer rows.Close()
+	var cols []binlogColumn
+	for rows.Next() {
+		var name, colType string
+		if err := rows.Scan(&name, &colType); err != nil {
+			return nil, fmt.Errorf("can't read columns of table %s: %v", table, err)
+		}
+		cols = append(cols, binlogColumn{
+			name:     name,
+			unsigned: strings.Contains(strings.ToLower(colType), "unsigned"),
+			values:   parseEnumValues(colType),
+		})
+	}
+	return cols, rows.Err()
+}
+
+// parseEnumValues returns the values of an ENUM or SET column type, e.g.
+// enum('a','b”c') -> [a, b'c].
+func parseEnumValues(colType string) []string {
+	lower := strings.ToLower(colType)
+	if !strings.HasPrefix(lower, "enum(") && !strings.HasPrefix(lower, "set(") {
+		return nil
+	}
+	s := colType[strings.IndexByte(colType, '(')+1 : strings.LastIndexByte(colType, ')')]
+	var values []string
+	var cur strings.Builder
+	inQuote := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\'' && inQuote && i+1 < len(s) && s[i+1] == '\'':
+			cur.WriteByte('\'')
+			i++
+		case c == '\'':
+			inQuote = !inQuote
+			if !inQuote {
+				values = append(values, cur.String())
+				cur.Reset()
+			}
+		case inQuote:
+			cur.WriteByte(c)
+		}
+	}
+	return values
+}
+
+// binlogEventReader is a source of binlog events, usually a binlogStream.
+type binlogEventReader interface {
+	readEvent() (*replication.BinlogEvent, error)
+	Close() error
+}
+
+// binlogStream reads the binlog with a replication.BinlogSyncer. Close
+// interrupts a pending readEvent.
+type binlogStream struct {
+	syncer   *replication.BinlogSyncer
+	streamer *replication.BinlogStreamer
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+func (s *binlogStream) readEvent() (*replication.BinlogEvent, error) {
+	return s.streamer.GetEvent(s.ctx)
+}
+
+func (s *binlogStream) Close() error {
+	s.cancel()
+	s.syncer.Close()
+	return nil
+}
+
+// binlogTLSConfig returns the TLS configuration of the replication
+// connection for the tls source-profile param, nil for plain text.
+func binlogTLSConfig(cfg profiles.SourceProfileConnectionMySQL) *tls.Config {
+	switch cfg.Tls {
+	case profiles.MySQLTlsVerify:
+		return &tls.Config{ServerName: cfg.Host}
+	case profiles.MySQLTlsSkipVerify:
+		return &tls.Config{InsecureSkipVerify: true}
+	}
+	return nil
+}
+
+// dialBinlog connects to the source database as a replica with the given
+// server id, and starts reading the binlog after the transactions in
+// pos.GTIDSet if it's set, or at pos.File and pos.Pos otherwise.
+func dialBinlog(cfg profiles.SourceProfileConnectionMySQL, serverId uint32, pos binlogPosition) (binlogEventReader, error) {
+	port, err := strconv.ParseUint(cfg.Port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q: %v", cfg.Port, err)
+	}
+	syncer := replication.NewBinlogSyncer(replication.BinlogSyncerConfig{
+		ServerID:                serverId,
+		Flavor:                  gomysql.MySQLFlavor,
+		Host:                    cfg.Host,
+		Port:                    uint16(port),
+		User:                    cfg.User,
+		Password:                cfg.Pwd,
+		TLSConfig:               binlogTLSConfig(cfg),
+		UseDecimal:              true,
+		TimestampStringLocation: time.UTC,
+		// Reconnections are handled by binlogCDC.stream, which restarts
+		// after the last transaction applied to Spanner.
+		DisableRetrySync: true,
+		HeartbeatPeriod:  binlogHeartbeatPeriod,
+		Logger:           log.NewDefault(&log.NullHandler{}),
+	})
+	var streamer *replication.BinlogStreamer
+	if pos.GTIDSet != "" {
+		var set gomysql.GTIDSet
+		if set, err = gomysql.ParseMysqlGTIDSet(pos.GTIDSet); err == nil {
+			streamer, err = syncer.StartSyncGTID(set)
+		}
+	} else {
+		streamer, err = syncer.StartSync(gomysql.Position{Name: pos.File, Pos: uint32(pos.Pos)})
+	}
+	if err != nil {
+		syncer.Close()
+		return nil, fmt.Errorf("can't start reading the binlog: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &binlogStream{syncer: syncer, streamer: streamer, ctx: ctx, cancel: cancel}, nil
+}
+
+// binlogCDC applies the changes read from the binlog to Spanner.
+type binlogCDC struct {
+	conv      *internal.Conv
+	info      *common.StreamingInfo
+	dbName    string
+	stateFile string
+	// pos is the position after the last transaction applied to Spanner.
+	pos binlogPosition
+	// gtidSet is pos.GTIDSet, nil if the source database doesn't use GTIDs.
+	gtidSet  gomysql.GTIDSet
+	tableIds map[string]string // Maps source table name to table id.
+	// columns returns the columns of a source table.
+	columns     func(table string) ([]binlogColumn, error)
+	columnCache map[string][]binlogColumn
+	inTxn       bool
+	gtid        string
+	pending     []common.PendingMutation
+	// cutoverPos is set when a cutover has been requested, to the position
+	// of the binlog at that time.
+	cutoverPos  *binlogPosition
+	cutoverDone bool
+	// masterStatus returns the current position of the binlog.
+	masterStatus func() (binlogPosition, error)
+	lastSaved    binlogPosition
+	lastCheck    time.Time
+	lastProgress time.Time
+	connLock     sync.Mutex
+	conn         binlogEventReader
+}
+
+func newBinlogCDC(conv *internal.Conv, info *common.StreamingInfo, dbName, stateFile string, pos binlogPosition) *binlogCDC {
+	c := &binlogCDC{
+		conv:        conv,
+		info:        info,
+		dbName:      dbName,
+		stateFile:   stateFile,
+		pos:         pos,
+		lastSaved:   pos,
+		tableIds:    make(map[string]string),
+		columnCache: make(map[string][]binlogColumn),
+	}
+	for tableId, srcTable := range conv.SrcSchema {
+		if _, ok := conv.SpSchema[tableId]; ok {
+			c.tableIds[srcTable.Name] = tableId
+		}
+	}
+	if pos.GTIDSet != "" {
+		// The set was validated when it was read.
+		c.gtidSet, _ = gomysql.ParseMysqlGTIDSet(pos.GTIDSet)
+	}
+	return c
+}
+
+// tableColumns returns the columns of the tables in rows events, n being
+// the number of columns in the event. It returns nil for tables that aren't
+// being migrated, so that their rows aren't converted.
+func (c *binlogCDC) tableColumns(schema, table string, n int) ([]binlogColumn, error) {
+	if schema != c.dbName {
+		return nil, nil
+	}
+	if _, ok := c.tableIds[table]; !ok {
+		return nil, nil
+	}
+	cols, ok := c.columnCache[table]
+	if !ok || len(cols) != n {
+		var err error
+		if cols, err = c.columns(table); err != nil {
+			return nil, err
+		}
+		tableId := c.tableIds[table]
+		for i := range cols {
+			colId, _ := internal.GetColIdFromSrcName(c.conv.SrcSchema[tableId].ColDefs, cols[i].name)
+			cols[i].geoJSON = isGeoJSONColumn(c.conv, tableId, colId)
+		}
+		c.columnCache[table] = cols
+	}
+	if len(cols) != n {
+		return nil, fmt.Errorf("table %s has %d columns in the binlog but %d in the database, was it altered?", table, n, len(cols))
+	}
+	return cols, nil
+}
+
+// handleEvent processes a binlog event. Changes are buffered until the end
+// of their transaction, and then written to Spanner.
+func (c *binlogCDC) handleEvent(e *replication.BinlogEvent) error {
+	if e.Header.EventType == replication.HEARTBEAT_EVENT {
+		// Heartbeats only wake up consume, and don't change the position.
+		return nil
+	}
+	endTxn := false
+	switch ev := e.Event.(type) {
+	case *replication.RotateEvent:
+		c.pos.File, c.pos.Pos = string(ev.NextLogName), ev.Position
+		return nil
+	case *replication.GTIDEvent:
+		// Anonymous GTID events are also decoded as GTID events.
+		if e.Header.EventType == replication.GTID_EVENT {
+			c.inTxn = true
+			c.gtid = fmt.Sprintf("%s:%d", formatUUID(ev.SID), ev.GNO)
+		}
+	case *replication.TableMapEvent:
+		c.inTxn = true
+	case *replication.QueryEvent:
+		query := string(ev.Query)
+		switch strings.ToUpper(strings.TrimSpace(query)) {
+		case "BEGIN":
+			c.inTxn = true
+		case "COMMIT":
+			// Transactions on non-transactional engines end with a COMMIT
+			// query instead of an XID event.
+			endTxn = true
+		case "ROLLBACK":
+			c.pending = nil
+			endTxn = true
+		default:
+			// Statements other than transaction control are DDL, which
+			// we don't replicate. Column metadata is refreshed in case
+			// the statement changed a table.
+			c.info.Unexpected(fmt.Sprintf("Statement in binlog not applied to Spanner: %s", truncate(query, 200)))
+			c.columnCache = make(map[string][]binlogColumn)
+			endTxn = true
+		}
+	case *replication.XIDEvent:
+		endTxn = true
+	case *replication.RowsEvent:
+		c.inTxn = true
+		rows, err := c.decodeRows(e.Header.EventType, ev)
+		if err != nil {
+			return err
+		}
+		if rows != nil {
+			rows.timestamp = time.Unix(int64(e.Header.Timestamp), 0)
+			c.processRows(rows)
+		}
+	default:
+		if e.Header.EventType == transactionPayloadEventType {
+			return fmt.Errorf("compressed transaction payloads are not supported, please set binlog_transaction_compression=OFF")
+		}
+	}
+	if endTxn {
+		c.flush()
+		c.inTxn = false
+		if c.gtid != "" {
+			c.pos.LastGTID = c.gtid
+			if c.gtidSet != nil {
+				if err := c.gtidSet.Update(c.gtid); err != nil {
+					return fmt.Errorf("can't add GTID %s to the GTID set: %v", c.gtid, err)
+				}
+				c.pos.GTIDSet = c.gtidSet.String()
+			}
+			c.gtid = ""
+		}
+	}
+	if !c.inTxn && e.Header.LogPos > 0 {
+		c.pos.Pos = uint64(e.Header.LogPos)
+	}
+	if c.cutoverPos != nil && c.reached(*c.cutoverPos) {
+		c.cutoverDone = true
+	}
+	return nil
+}
+
+// reached reports whether all the transactions up to target have been
+// applied. GTID sets are compared when available, because file positions
+// aren't meaningful after a failover to another server.
+func (c *binlogCDC) reached(target binlogPosition) bool {
+	if c.inTxn {
+		return false
+	}
+	if c.gtidSet != nil && target.GTIDSet != "" {
+		if set, err := gomysql.ParseMysqlGTIDSet(target.GTIDSet); err == nil {
+			return c.gtidSet.Contain(set)
+		}
+	}
+	return !c.pos.before(target)
+}
+
+// decodeRows converts the rows of a rows event. It returns nil for events
+// of tables that aren't being migrated.
+func (c *binlogCDC) decodeRows(eventType replication.EventType, ev *replication.RowsEvent) (*rowsEvent, error) {
+	kind, ok := rowsEventKinds[eventType]
+	if !ok {
+		return nil, nil
+	}
+	cols, err := c.tableColumns(string(ev.Table.Schema), string(ev.Table.Table), int(ev.ColumnCount))
+	if err != nil || cols == nil {
+		return nil, err
+	}
+	return decodeRowsEvent(kind, ev, cols)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+func (c *binlogCDC) processRows(ev *rowsEvent) {
+	if ev.schema != c.dbName {
+		return
+	}
+	srcTable := ev.table
+	tableId, ok := c.tableIds[srcTable]
+	if !ok {
+		return
+	}
+	if _, ok := c.conv.SyntheticPKeys[tableId]; ok {
+		// Rows of tables without a primary key can't be matched to the rows
+		// written during the snapshot migration.
+		c.info.Unexpected(fmt.Sprintf("Changes to table %s not applied to Spanner: table has no primary key", srcTable))
+		return
+	}
+	for _, change := range ev.changes {
+		switch ev.kind {
+		case rowsInsert:
+			c.info.StatsAddRecord(srcTable, recordInsert)
+			c.upsert(tableId, ev, recordInsert, change.after)
+		case rowsDelete:
+			c.info.StatsAddRecord(srcTable, recordDelete)
+			c.delete(tableId, ev, recordDelete, change.before)
+		case rowsUpdate:
+			c.info.StatsAddRecord(srcTable, recordUpdate)
+			// If the primary key changed, the old row must be deleted.
+			_, _, oldKey, _, _, err1 := c.convertRow(tableId, ev, change.before, true)
+			_, _, newKey, _, _, err2 := c.convertRow(tableId, ev, change.after, true)
+			if err1 == nil && err2 == nil && !reflect.DeepEqual(oldKey, newKey) {
+				c.delete(tableId, ev, recordUpdate, change.before)
+			}
+			c.upsert(tableId, ev, recordUpdate, change.after)
+		}
+		c.info.StatsAddRecordProcessed()
+	}
+}
+
+func (c *binlogCDC) upsert(tableId string, ev *rowsEvent, recordType string, row *binlogRow) {
+	spTable, cols, vals, srcCols, srcVals, err := c.convertRow(tableId, ev, row, false)
+	if err != nil {
+		c.badRecord(ev.table, recordType, srcCols, srcVals, err)
+		return
+	}
+	c.pending = append(c.pending, common.PendingMutation{M: sp.InsertOrUpdate(spTable, cols, vals), SrcTable: ev.table, SpTable: spTable, RecordType: recordType, Cols: cols, Vals: vals, CommitTime: ev.timestamp})
+}
+
+func (c *binlogCDC) delete(tableId string, ev *rowsEvent, recordType string, row *binlogRow) {
+	spTable, cols, vals, srcCols, srcVals, err := c.convertRow(tableId, ev, row, true)
+	if err != nil {
+		c.badRecord(ev.table, recordType, srcCols, srcVals, err)
+		return
+	}
+	c.pending = append(c.pending, common.PendingMutation{M: sp.Delete(spTable, sp.Key(vals)), SrcTable: ev.table, SpTable: spTable, RecordType: recordType, Cols: cols, Vals: vals, CommitTime: ev.timestamp})
+}
+
+func (c *binlogCDC) badRecord(srcTable, recordType string, srcCols, srcVals []string, err error) {
+	c.info.Unexpected(fmt.Sprintf("Error while converting binlog data: %s", err))
+	c.info.StatsAddBadRecord(srcTable, recordType)
+	c.info.CollectBadRecord(recordType, srcTable, srcCols, srcVals)
+}
+
+// convertRow converts a row image to Spanner columns and values. If keyOnly
+// is set, only the primary key columns are converted, in key order. NULL
+// values are returned as nil, so that updates clear the column in Spanner.
+// The source columns and values are returned for error reporting.
+func (c *binlogCDC) convertRow(tableId string, ev *rowsEvent, row *binlogRow, keyOnly bool) (string, []string, []interface{}, []string, []string, error) {
+	srcSchema := c.conv.SrcSchema[tableId]
+	spSchema := c.conv.SpSchema[tableId]
+	index := make(map[string]int)
+	for i, col := range ev.columns {
+		index[col.name] = i
+	}
+	var colIds []string
+	if keyOnly {
+		for _, k := range spSchema.PrimaryKeys {
+			colIds = append(colIds, k.ColId)
+		}
+	} else {
+		colIds = common.IntersectionOfTwoStringSlices(spSchema.ColIds, srcSchema.ColIds)
+	}
+	var valColIds, srcCols, vals, nullCols []string
+	for _, colId := range colIds {
+		name := srcSchema.ColDefs[colId].Name
+		i, ok := index[name]
+		if !ok || !row.present[i] {
+			if keyOnly {
+				return "", nil, nil, srcCols, vals, fmt.Errorf("key column %s of table %s not found in binlog row", name, ev.table)
+			}
+			continue
+		}
+		if row.values[i] == nil {
+			if keyOnly {
+				return "", nil, nil, srcCols, vals, fmt.Errorf("key column %s of table %s is NULL", name, ev.table)
+			}
+			nullCols = append(nullCols, spSchema.ColDefs[colId].Name)
+			continue
+		}
+		valColIds = append(valColIds, colId)
+		srcCols = append(srcCols, name)
+		vals = append(vals, *row.values[i])
+	}
+	spTable, cols, spVals, err := ConvertData(c.conv, tableId, valColIds, srcSchema, spSchema, vals)
+	if err != nil {
+		return "", nil, nil, srcCols, vals, err
+	}
+	for _, col := range nullCols {
+		cols = append(cols, col)
+		spVals = append(spVals, nil)
+	}
+	return spTable, cols, spVals, srcCols, vals, nil
+}
+
+// flush writes the changes of the current transaction to Spanner. Small
+// transactions are written atomically; if a write fails, its mutations are
+// retried one at a time so that only the failing records are dropped.
+func (c *binlogCDC) flush() {
+	pending := c.pending
+	c.pending = nil
+	c.info.Flush(pending, binlogBatchSize)
+}
+
+// periodic persists the binlog position, checks for cutover requests and
+// reports progress. It's called after every event, including the heartbeats
+// sent while the source database is idle, and does work at most once a
+// second.
+func (c *binlogCDC) periodic() {
+	now := time.Now()
+	if now.Sub(c.lastCheck) < time.Second {
+		return
+	}
+	c.lastCheck = now
+	c.saveState()
+	if c.cutoverPos == nil {
+		if _, err := os.Stat(common.CutoverFile(c.stateFile)); err == nil {
+			c.startCutover()
+		}
+	}
+	if now.Sub(c.lastProgress) >= time.Minute {
+		c.lastProgress = now
+		fmt.Printf("Binlog position: %s, count of records processed: %d\n", c.pos, c.info.Processed())
+	}
+}
+
+func (c *binlogCDC) startCutover() {
+	target, err := c.masterStatus()
+	if err != nil {
+		c.info.Unexpected(fmt.Sprintf("Can't read binlog position for cutover: %v", err))
+		return
+	}
+	fmt.Printf("Cutover requested, applying changes up to binlog position %s...\n", target)
+	c.cutoverPos = &target
+	if c.reached(target) {
+		c.cutoverDone = true
+	}
+}
+
+func (c *binlogCDC) saveState() {
+	if c.pos == c.lastSaved {
+		return
+	}
+	if err := saveBinlogState(c.stateFile, c.pos); err != nil {
+		c.info.Unexpected(err.Error())
+		return
+	}
+	c.lastSaved = c.pos
+}
+
+// consume reads and processes events from r until an error occurs or
+// the cutover position is reached.
+func (c *binlogCDC) consume(r binlogEventReader) error {
+	// A new connection restarts from the start of a transaction.
+	c.pending, c.inTxn, c.gtid = nil, false, ""
+	for !c.cutoverDone {
+		e, err := r.readEvent()
+		if err != nil {
+			return err
+		}
+		if err := c.handleEvent(e); err != nil {
+			return err
+		}
+		c.periodic()
+	}
+	return nil
+}
+
+// stream reads the binlog from the current position until the user exits
+// or a cutover completes, reconnecting if the connection is lost. dial
+// opens a binlog stream starting at a position.
+func (c *binlogCDC) stream(dial func(pos binlogPosition) (binlogEventReader, error)) error {
+	var err error
+	failures := 0
+	for !c.info.Exited() && !c.cutoverDone {
+		start := c.pos
+		var r binlogEventReader
+		r, err = dial(c.pos)
+		if err == nil {
+			c.connLock.Lock()
+			c.conn = r
+			c.connLock.Unlock()
+			err = c.consume(r)
+			r.Close()
+		}
+		if c.info.Exited() || c.cutoverDone {
+			err = nil
+			break
+		}
+		if c.pos != start {
+			failures = 0
+		}
+		failures++
+		c.info.Unexpected(fmt.Sprintf("Binlog streaming interrupted: %v", err))
+		if failures >= binlogRetryLimit {
+			break
+		}
+		time.Sleep(5 * time.Second)
+	}
+	c.saveState()
+	if c.cutoverDone {
+		os.Remove(common.CutoverFile(c.stateFile))
+	}
+	return err
+}
+
+// catchCtrlC stops binlog streaming when the user presses Ctrl+C. The
+// connection is closed to interrupt the read of the next event.
+func (c *binlogCDC) catchCtrlC() {
+	c.info.CatchCtrlC(func() {
+		c.connLock.Lock()
+		if c.conn != nil {
+			c.conn.Close()
+		}
+		c.connLock.Unlock()
+	})
+}
+
+// startBinlogCapture captures the binlog position from which changes are
+// streamed to Spanner, or reads it from the state file if streaming is
+// being resumed.
+func (isi InfoSchemaImpl) startBinlogCapture() (map[string]interface{}, error) {
+	cfg := isi.SourceProfile.Conn.Mysql
+	if err := checkBinlogConfig(isi.Db); err != nil {
+		return nil, err
+	}
+	pos, ok, err := loadBinlogState(cfg.CdcStateFile)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		fmt.Printf("Resuming binlog streaming from position %s saved in %s, skipping snapshot migration.\n", pos, cfg.CdcStateFile)
+		return map[string]interface{}{binlogPositionKey: pos, SkipSnapshotKey: true}, nil
+	}
+	pos, err = readMasterStatus(isi.Db)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Captured binlog position %s, changes from this position will be streamed after the snapshot migration.\n", pos)
+	return map[string]interface{}{binlogPositionKey: pos}, nil
+}
+
+// streamBinlog applies the changes in the binlog to Spanner until the user
+// exits with Ctrl+C or a cutover is requested with the cutover command.
+func (isi InfoSchemaImpl) streamBinlog(client *sp.Client, conv *internal.Conv, streamInfo map[string]interface{}) error {
+	cfg := isi.SourceProfile.Conn.Mysql
+	pos, ok := streamInfo[binlogPositionKey].(binlogPosition)
+	if !ok {
+		return fmt.Errorf("binlog position not captured")
+	}
+	serverId := cfg.CdcServerId
+	if serverId == 0 {
+		// Use a random id, which must not clash with the id of the source
+		// database or of its other replicas.
+		serverId = uint32(rand.Int31n(1<<30) + 1<<20)
+	}
+	// Persist the starting position, so that a restart doesn't redo the
+	// snapshot migration.
+	if err := saveBinlogState(cfg.CdcStateFile, pos); err != nil {
+		return err
+	}
+	fmt.Println("Processing of the MySQL binlog started...")
+	fmt.Printf("Use Ctrl+C to stop the process, or run 'cutover -state-file=%s' to stop once all changes made so far are applied.\n", common.AbsPath(cfg.CdcStateFile))
+
+	info := common.MakeStreamingInfo()
+	info.SetWriter(client, conv)
+	c := newBinlogCDC(conv, info, isi.DbName, cfg.CdcStateFile, pos)
+	c.columns = func(table string) ([]binlogColumn, error) { return queryTableColumns(isi.Db, isi.DbName, table) }
+	c.masterStatus = func() (binlogPosition, error) { return readMasterStatus(isi.Db) }
+	c.catchCtrlC()
+	err := c.stream(func(pos binlogPosition) (binlogEventReader, error) {
+		return dialBinlog(cfg, serverId, pos)
+	})
+	info.FillConv(conv)
+	if err != nil {
+		return fmt.Errorf("binlog streaming stopped at position %s: %v", c.pos, err)
+	}
+	fmt.Printf("Binlog streaming stopped at position %s, saved in %s.\n", c.pos, cfg.CdcStateFile)
+	return nil
+}
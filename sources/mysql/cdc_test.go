@@ -0,0 +1,473 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"crypto/tls"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	sp "cloud.google.com/go/spanner"
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+var productColumns = []binlogColumn{{name: "id"}, {name: "name"}, {name: "price"}}
+
+func buildProductConv() *internal.Conv {
+	colIds := []string{"c1", "c2", "c3"}
+	return buildConv(
+		ddl.CreateTable{
+			Name:   "product",
+			Id:     "t1",
+			ColIds: colIds,
+			ColDefs: map[string]ddl.ColumnDef{
+				"c1": {Name: "id", T: ddl.Type{Name: ddl.Int64}},
+				"c2": {Name: "name", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+				"c3": {Name: "price", T: ddl.Type{Name: ddl.Float64}},
+			},
+			PrimaryKeys: []ddl.IndexKey{{ColId: "c1"}},
+		},
+		schema.Table{
+			Name:   "product",
+			Schema: "test",
+			Id:     "t1",
+			ColIds: colIds,
+			ColDefs: map[string]schema.Column{
+				"c1": {Name: "id", Type: schema.Type{Name: "int"}},
+				"c2": {Name: "name", Type: schema.Type{Name: "varchar"}},
+				"c3": {Name: "price", Type: schema.Type{Name: "double"}},
+			},
+			PrimaryKeys: []schema.Key{{ColId: "c1"}},
+		})
+}
+
+// productRow builds the values of a row of the product table as decoded by
+// the replication package.
+func productRow(id interface{}, name interface{}, price interface{}) []interface{} {
+	return []interface{}{id, name, price}
+}
+
+func productTableMap(schema string) *replication.TableMapEvent {
+	return &replication.TableMapEvent{
+		Schema:      []byte(schema),
+		Table:       []byte("product"),
+		ColumnCount: 3,
+		ColumnType:  []byte{gomysql.MYSQL_TYPE_LONG, gomysql.MYSQL_TYPE_VARCHAR, gomysql.MYSQL_TYPE_DOUBLE},
+		ColumnMeta:  []uint16{0, 40, 8},
+	}
+}
+
+func eventAt(eventType replication.EventType, logPos uint32, ev replication.Event) *replication.BinlogEvent {
+	return &replication.BinlogEvent{Header: &replication.EventHeader{EventType: eventType, LogPos: logPos}, Event: ev}
+}
+
+func rowsEventAt(logPos uint32, tm *replication.TableMapEvent, eventType replication.EventType, rows ...[]interface{}) *replication.BinlogEvent {
+	return eventAt(eventType, logPos, &replication.RowsEvent{Table: tm, ColumnCount: tm.ColumnCount, Rows: rows})
+}
+
+func commitAt(logPos uint32) *replication.BinlogEvent {
+	return eventAt(replication.XID_EVENT, logPos, &replication.XIDEvent{})
+}
+
+func queryAt(logPos uint32, query string) *replication.BinlogEvent {
+	return eventAt(replication.QUERY_EVENT, logPos, &replication.QueryEvent{Query: []byte(query)})
+}
+
+func gtidAt(logPos uint32, gno int64) *replication.BinlogEvent {
+	sid, _ := hex.DecodeString("3e11fa4771ca11e19e33c80aa9429562")
+	return eventAt(replication.GTID_EVENT, logPos, &replication.GTIDEvent{SID: sid, GNO: gno})
+}
+
+func rotateTo(file string) *replication.BinlogEvent {
+	return eventAt(replication.ROTATE_EVENT, 0, &replication.RotateEvent{Position: 4, NextLogName: []byte(file)})
+}
+
+func TestBinlogCDC_HandleEvent(t *testing.T) {
+	conv := buildProductConv()
+	info := common.MakeStreamingInfo()
+	cols := []string{"id", "name", "price"}
+	badWrite := sp.InsertOrUpdate("product", cols, []interface{}{int64(4), "bad", float64(4)})
+	var written [][]*sp.Mutation
+	info.Write = func(ms []*sp.Mutation) error {
+		for _, m := range ms {
+			if reflect.DeepEqual(m, badWrite) {
+				return fmt.Errorf("write failed")
+			}
+		}
+		written = append(written, ms)
+		return nil
+	}
+	c := newBinlogCDC(conv, info, "test", filepath.Join(t.TempDir(), "state.json"), binlogPosition{File: "binlog.000001", Pos: 100})
+	c.columns = func(table string) ([]binlogColumn, error) { return productColumns, nil }
+	tm, other := productTableMap("test"), productTableMap("other")
+	handle := func(events ...*replication.BinlogEvent) {
+		for _, e := range events {
+			assert.Nil(t, c.handleEvent(e))
+		}
+	}
+
+	handle(gtidAt(150, 1), eventAt(replication.TABLE_MAP_EVENT, 180, tm),
+		rowsEventAt(200, tm, replication.WRITE_ROWS_EVENTv2, productRow(int32(1), "a", 1.5), productRow(int32(2), nil, 2.0)))
+	// Changes are applied when their transaction commits, and the position
+	// only moves between transactions.
+	assert.Empty(t, written)
+	assert.Equal(t, uint64(100), c.pos.Pos)
+	handle(commitAt(250))
+	assert.Equal(t, [][]*sp.Mutation{{
+		sp.InsertOrUpdate("product", cols, []interface{}{int64(1), "a", float64(1.5)}),
+		sp.InsertOrUpdate("product", []string{"id", "price", "name"}, []interface{}{int64(2), float64(2), nil}),
+	}}, written)
+	// Without a GTID set to start from, GTIDs are informational.
+	assert.Equal(t, binlogPosition{File: "binlog.000001", Pos: 250, LastGTID: "3e11fa47-71ca-11e1-9e33-c80aa9429562:1"}, c.pos)
+
+	// Updates that change the primary key delete the old row. Update events
+	// have a before and an after image for each row.
+	written = nil
+	handle(queryAt(300, "BEGIN"),
+		rowsEventAt(320, tm, replication.UPDATE_ROWS_EVENTv2, productRow(int32(1), "a", 1.5), productRow(int32(3), "a", 1.5)),
+		rowsEventAt(340, tm, replication.DELETE_ROWS_EVENTv2, productRow(int32(2), nil, 2.0)),
+		rowsEventAt(360, tm, replication.DELETE_ROWS_EVENTv2, productRow(nil, "b", 1.0)),
+		rowsEventAt(370, other, replication.WRITE_ROWS_EVENTv2, productRow(int32(9), "c", 1.0)),
+		commitAt(400))
+	assert.Equal(t, [][]*sp.Mutation{{
+		sp.Delete("product", sp.Key{int64(1)}),
+		sp.InsertOrUpdate("product", cols, []interface{}{int64(3), "a", float64(1.5)}),
+		sp.Delete("product", sp.Key{int64(2)}),
+	}}, written)
+	assert.Equal(t, uint64(400), c.pos.Pos)
+
+	// When a write fails, the mutations are retried one at a time.
+	written = nil
+	handle(queryAt(420, "BEGIN"),
+		rowsEventAt(440, tm, replication.WRITE_ROWS_EVENTv2, productRow(int32(4), "bad", 4.0), productRow(int32(5), "ok", 5.0)),
+		commitAt(500))
+	assert.Equal(t, [][]*sp.Mutation{{sp.InsertOrUpdate("product", cols, []interface{}{int64(5), "ok", float64(5)})}}, written)
+
+	// DDL statements aren't applied.
+	handle(queryAt(550, "ALTER TABLE product ADD COLUMN x INT"))
+	assert.Equal(t, uint64(550), c.pos.Pos)
+
+	assert.Equal(t, map[string]map[string]int64{"product": {recordInsert: 4, recordUpdate: 1, recordDelete: 2}}, info.Records)
+	assert.Equal(t, map[string]map[string]int64{"product": {recordDelete: 1}}, info.BadRecords)
+	assert.Equal(t, map[string]map[string]int64{"product": {recordInsert: 1}}, info.DroppedRecords)
+	assert.Equal(t, 1, len(info.SampleBadRecords))
+	assert.Equal(t, 1, len(info.SampleBadWrites))
+	assert.Equal(t, int64(7), info.Processed())
+	assert.Equal(t, 2, len(info.Unexpecteds))
+
+	// Cutover completes once the position reaches the cutover position.
+	c.cutoverPos = &binlogPosition{File: "binlog.000001", Pos: 600}
+	handle(rotateTo("binlog.000002"))
+	assert.Equal(t, "binlog.000002:4", c.pos.String())
+	assert.False(t, c.cutoverDone)
+	handle(eventAt(replication.FORMAT_DESCRIPTION_EVENT, 120, &replication.FormatDescriptionEvent{}))
+	assert.True(t, c.cutoverDone)
+
+	// Compressed transactions can't be decoded.
+	assert.NotNil(t, c.handleEvent(eventAt(transactionPayloadEventType, 700, &replication.GenericEvent{})))
+
+	info.FillConv(conv)
+	assert.True(t, conv.Audit.StreamingStats.Streaming)
+	assert.Equal(t, info.Records, conv.Audit.StreamingStats.TotalRecords)
+}
+
+func TestBinlogCDC_GTIDSet(t *testing.T) {
+	info := common.MakeStreamingInfo()
+	info.Write = func(ms []*sp.Mutation) error { return nil }
+	start := binlogPosition{File: "binlog.000001", Pos: 100, GTIDSet: "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5"}
+	c := newBinlogCDC(buildProductConv(), info, "test", filepath.Join(t.TempDir(), "state.json"), start)
+	c.masterStatus = func() (binlogPosition, error) {
+		return binlogPosition{File: "binlog.000001", Pos: 50, GTIDSet: "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-7"}, nil
+	}
+	// With GTIDs, cutover waits for the transactions in the GTID set of
+	// the source database, whatever the file position.
+	c.startCutover()
+	assert.False(t, c.cutoverDone)
+	assert.Nil(t, c.handleEvent(gtidAt(150, 6)))
+	assert.Nil(t, c.handleEvent(queryAt(180, "BEGIN")))
+	assert.Nil(t, c.handleEvent(commitAt(200)))
+	assert.Equal(t, "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-6", c.pos.GTIDSet)
+	assert.False(t, c.cutoverDone)
+	assert.Nil(t, c.handleEvent(gtidAt(250, 7)))
+	assert.Nil(t, c.handleEvent(queryAt(300, "CREATE TABLE t (id INT)")))
+	assert.Equal(t, "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-7", c.pos.GTIDSet)
+	assert.True(t, c.cutoverDone)
+}
+
+func TestBinlogCDC_SyntheticPrimaryKey(t *testing.T) {
+	conv := buildProductConv()
+	conv.SyntheticPKeys["t1"] = internal.SyntheticPKey{ColId: "c4"}
+	info := common.MakeStreamingInfo()
+	info.Write = func(ms []*sp.Mutation) error {
+		t.Errorf("unexpected write %v", ms)
+		return nil
+	}
+	c := newBinlogCDC(conv, info, "test", filepath.Join(t.TempDir(), "state.json"), binlogPosition{File: "binlog.000001", Pos: 4})
+	c.columns = func(table string) ([]binlogColumn, error) { return productColumns, nil }
+	assert.Nil(t, c.handleEvent(rowsEventAt(200, productTableMap("test"), replication.WRITE_ROWS_EVENTv2, productRow(int32(1), "a", 1.5))))
+	assert.Nil(t, c.handleEvent(commitAt(250)))
+	assert.Equal(t, 1, len(info.Unexpecteds))
+}
+
+func TestBinlogCDC_TableColumns(t *testing.T) {
+	c := newBinlogCDC(buildProductConv(), common.MakeStreamingInfo(), "test", "", binlogPosition{})
+	queries := 0
+	c.columns = func(table string) ([]binlogColumn, error) {
+		queries++
+		return productColumns, nil
+	}
+	cols, err := c.tableColumns("test", "product", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, productColumns, cols)
+	_, err = c.tableColumns("test", "product", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, queries)
+	// Column count mismatches refresh the columns, and fail if the table
+	// still doesn't match.
+	_, err = c.tableColumns("test", "product", 4)
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, queries)
+	// Tables that aren't migrated have no columns.
+	cols, err = c.tableColumns("test", "other", 2)
+	assert.Nil(t, err)
+	assert.Nil(t, cols)
+	cols, err = c.tableColumns("other", "product", 3)
+	assert.Nil(t, err)
+	assert.Nil(t, cols)
+}
+
+// fakeEventReader returns a fixed list of events.
+type fakeEventReader struct {
+	events []*replication.BinlogEvent
+}
+
+func (r *fakeEventReader) readEvent() (*replication.BinlogEvent, error) {
+	if len(r.events) == 0 {
+		return nil, io.EOF
+	}
+	e := r.events[0]
+	r.events = r.events[1:]
+	return e, nil
+}
+
+func (r *fakeEventReader) Close() error {
+	return nil
+}
+
+func TestBinlogCDC_StreamUntilCutover(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	start := binlogPosition{File: "binlog.000001", Pos: 4}
+	assert.Nil(t, saveBinlogState(stateFile, start))
+	assert.Nil(t, common.RequestCutover(stateFile))
+
+	info := common.MakeStreamingInfo()
+	var written []*sp.Mutation
+	info.Write = func(ms []*sp.Mutation) error {
+		written = append(written, ms...)
+		return nil
+	}
+	c := newBinlogCDC(buildProductConv(), info, "test", stateFile, start)
+	c.columns = func(table string) ([]binlogColumn, error) { return productColumns, nil }
+	c.masterStatus = func() (binlogPosition, error) { return binlogPosition{File: "binlog.000001", Pos: 400}, nil }
+
+	tm := productTableMap("test")
+	var dialed []binlogPosition
+	err := c.stream(func(pos binlogPosition) (binlogEventReader, error) {
+		dialed = append(dialed, pos)
+		return &fakeEventReader{events: []*replication.BinlogEvent{
+			rotateTo("binlog.000001"),
+			eventAt(replication.FORMAT_DESCRIPTION_EVENT, 0, &replication.FormatDescriptionEvent{}),
+			queryAt(200, "BEGIN"),
+			eventAt(replication.TABLE_MAP_EVENT, 250, tm),
+			rowsEventAt(300, tm, replication.WRITE_ROWS_EVENTv2, productRow(int32(7), "abc", 2.5)),
+			commitAt(400),
+			// Not read: streaming stops at the cutover position.
+			queryAt(500, "BEGIN"),
+		}}, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []binlogPosition{start}, dialed)
+	assert.Equal(t, []*sp.Mutation{sp.InsertOrUpdate("product", []string{"id", "name", "price"}, []interface{}{int64(7), "abc", float64(2.5)})}, written)
+	pos, ok, err := loadBinlogState(stateFile)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, binlogPosition{File: "binlog.000001", Pos: 400}, pos)
+	_, err = os.Stat(common.CutoverFile(stateFile))
+	assert.True(t, os.IsNotExist(err))
+}
+
+// idleEventReader returns a fixed list of events, and then blocks like an
+// idle source database until the cutover file of stateFile exists, after
+// which it returns heartbeats.
+type idleEventReader struct {
+	fakeEventReader
+	stateFile string
+}
+
+func (r *idleEventReader) readEvent() (*replication.BinlogEvent, error) {
+	if len(r.events) > 0 {
+		return r.fakeEventReader.readEvent()
+	}
+	for {
+		if _, err := os.Stat(common.CutoverFile(r.stateFile)); err == nil {
+			return eventAt(replication.HEARTBEAT_EVENT, 9999, &replication.GenericEvent{}), nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBinlogCDC_CutoverWhileIdle(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	start := binlogPosition{File: "binlog.000001", Pos: 4}
+	assert.Nil(t, saveBinlogState(stateFile, start))
+
+	info := common.MakeStreamingInfo()
+	info.Write = func(ms []*sp.Mutation) error { return nil }
+	c := newBinlogCDC(buildProductConv(), info, "test", stateFile, start)
+	c.columns = func(table string) ([]binlogColumn, error) { return productColumns, nil }
+	c.masterStatus = func() (binlogPosition, error) { return binlogPosition{File: "binlog.000001", Pos: 400}, nil }
+
+	tm := productTableMap("test")
+	done := make(chan error, 1)
+	go func() {
+		done <- c.stream(func(pos binlogPosition) (binlogEventReader, error) {
+			return &idleEventReader{stateFile: stateFile, fakeEventReader: fakeEventReader{events: []*replication.BinlogEvent{
+				rotateTo("binlog.000001"),
+				queryAt(200, "BEGIN"),
+				eventAt(replication.TABLE_MAP_EVENT, 250, tm),
+				rowsEventAt(300, tm, replication.WRITE_ROWS_EVENTv2, productRow(int32(7), "abc", 2.5)),
+				commitAt(400),
+			}}}, nil
+		})
+	}()
+	// Writes to the source database have stopped when the cutover is
+	// requested, so only heartbeats are read after it.
+	time.Sleep(50 * time.Millisecond)
+	assert.Nil(t, common.RequestCutover(stateFile))
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("cutover of an idle source database didn't complete")
+	}
+	// The heartbeats didn't change the position.
+	pos, ok, err := loadBinlogState(stateFile)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, binlogPosition{File: "binlog.000001", Pos: 400}, pos)
+}
+
+func TestBinlogTLSConfig(t *testing.T) {
+	cfg := profiles.SourceProfileConnectionMySQL{Host: "db.example.com"}
+	assert.Nil(t, binlogTLSConfig(cfg))
+	cfg.Tls = profiles.MySQLTlsVerify
+	assert.Equal(t, &tls.Config{ServerName: "db.example.com"}, binlogTLSConfig(cfg))
+	cfg.Tls = profiles.MySQLTlsSkipVerify
+	assert.Equal(t, &tls.Config{InsecureSkipVerify: true}, binlogTLSConfig(cfg))
+}
+
+func TestBinlogState(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	_, ok, err := loadBinlogState(stateFile)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.NotNil(t, common.RequestCutover(stateFile))
+
+	pos := binlogPosition{File: "binlog.000003", Pos: 1234, GTIDSet: "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5"}
+	assert.Nil(t, saveBinlogState(stateFile, pos))
+	got, ok, err := loadBinlogState(stateFile)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, pos, got)
+
+	assert.Nil(t, os.WriteFile(stateFile, []byte("{"), 0644))
+	_, _, err = loadBinlogState(stateFile)
+	assert.NotNil(t, err)
+	assert.Nil(t, os.WriteFile(stateFile, []byte("{}"), 0644))
+	_, _, err = loadBinlogState(stateFile)
+	assert.NotNil(t, err)
+	assert.Nil(t, os.WriteFile(stateFile, []byte(`{"file": "binlog.000001", "position": 4, "gtidSet": "x:y"}`), 0644))
+	_, _, err = loadBinlogState(stateFile)
+	assert.NotNil(t, err)
+}
+
+func TestBinlogPositionBefore(t *testing.T) {
+	a := binlogPosition{File: "binlog.000001", Pos: 500}
+	assert.True(t, a.before(binlogPosition{File: "binlog.000001", Pos: 501}))
+	assert.True(t, a.before(binlogPosition{File: "binlog.000002", Pos: 4}))
+	assert.False(t, a.before(a))
+	assert.False(t, a.before(binlogPosition{File: "binlog.000001", Pos: 100}))
+}
+
+func TestParseEnumValues(t *testing.T) {
+	assert.Equal(t, []string{"a", "b'c", "d,e"}, parseEnumValues("enum('a','b''c','d,e')"))
+	assert.Equal(t, []string{"x", "y"}, parseEnumValues("SET('x','y')"))
+	assert.Nil(t, parseEnumValues("varchar(10)"))
+}
+
+func TestCheckBinlogConfig(t *testing.T) {
+	tests := []struct {
+		vals    []driver.Value
+		wantErr bool
+	}{
+		{[]driver.Value{"1", "ROW", "FULL"}, false},
+		{[]driver.Value{"0", "ROW", "FULL"}, true},
+		{[]driver.Value{"1", "MIXED", "FULL"}, true},
+		{[]driver.Value{"1", "ROW", "MINIMAL"}, true},
+	}
+	for _, tc := range tests {
+		db := mkMockDB(t, []mockSpec{{
+			query: "SELECT @@GLOBAL.log_bin, @@GLOBAL.binlog_format, @@GLOBAL.binlog_row_image",
+			cols:  []string{"log_bin", "binlog_format", "binlog_row_image"},
+			rows:  [][]driver.Value{tc.vals},
+		}})
+		err := checkBinlogConfig(db)
+		assert.Equal(t, tc.wantErr, err != nil, tc.vals)
+	}
+}
+
+func TestReadMasterStatus(t *testing.T) {
+	db := mkMockDB(t, []mockSpec{{
+		query: "SHOW MASTER STATUS",
+		cols:  []string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB", "Executed_Gtid_Set"},
+		rows:  [][]driver.Value{{"binlog.000003", "157", "", "", "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5"}},
+	}})
+	pos, err := readMasterStatus(db)
+	assert.Nil(t, err)
+	assert.Equal(t, binlogPosition{File: "binlog.000003", Pos: 157, GTIDSet: "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5"}, pos)
+
+	// No rows: binary logging is disabled.
+	db = mkMockDB(t, []mockSpec{{
+		query: "SHOW MASTER STATUS",
+		cols:  []string{"File", "Position"},
+	}})
+	_, err = readMasterStatus(db)
+	assert.NotNil(t, err)
+}
@@ -44,9 +44,9 @@ func ProcessDataRow(conv *internal.Conv, tableId string, colIds []string, srcSch
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
 		conv.StatsAddBadRow(srcTableName, conv.DataMode())
-		conv.CollectBadRow(srcTableName, srcCols, vals)
+		conv.CollectBadRow(srcTableName, srcCols, vals, err)
 	} else {
-		conv.WriteRow(srcTableName, spTableName, cvtCols, cvtVals)
+		conv.WriteSourceRow(internal.SourceRow{Table: srcTableName, Columns: srcCols, Values: vals}, spTableName, cvtCols, cvtVals)
 	}
 }
 
@@ -43,7 +43,7 @@ type InfoSchemaImpl struct {
 
 // GetToDdl implement the common.InfoSchema interface.
 func (isi InfoSchemaImpl) GetToDdl() common.ToDdl {
-	return ToDdlImpl{}
+	return ToDdlImpl{SpatialFormat: isi.SourceProfile.Conn.Mysql.SpatialFormat}
 }
 
 // GetTableName returns table name.
@@ -53,49 +53,54 @@ func (isi InfoSchemaImpl) GetTableName(dbName string, tableName string) string {
 
 // GetRowsFromTable returns a sql Rows object for a table.
 func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, tableId string) (interface{}, error) {
-	srcSchema := conv.SrcSchema[tableId]
-	srcCols := []string{}
+	return isi.getRowsFromTable(context.Background(), conv, tableId)
+}
 
-	for _, srcColId := range srcSchema.ColIds {
-		srcCols = append(srcCols, conv.SrcSchema[tableId].ColDefs[srcColId].Name)
-	}
-	if len(srcCols) == 0 {
+// getRowsFromTable is like GetRowsFromTable, but the query is cancelled
+// once ctx is done.
+func (isi InfoSchemaImpl) getRowsFromTable(ctx context.Context, conv *internal.Conv, tableId string) (interface{}, error) {
+	srcSchema := conv.SrcSchema[tableId]
+	if len(srcSchema.ColIds) == 0 {
 		conv.Unexpected(fmt.Sprintf("Couldn't get source columns for table %s ", srcSchema.Name))
 		return nil, nil
 	}
 	// MySQL schema and name can be arbitrary strings.
 	// Ideally we would pass schema/name as a query parameter,
 	// but MySQL doesn't support this. So we quote it instead.
-	colNameList := buildColNameList(srcSchema, srcCols)
+	colNameList := buildColNameList(conv, tableId, srcSchema.ColIds)
 	q := fmt.Sprintf("SELECT %s FROM `%s`.`%s`;", colNameList, srcSchema.Schema, srcSchema.Name)
-	rows, err := isi.Db.Query(q)
+	rows, err := isi.Db.QueryContext(ctx, q)
 	return rows, err
 }
 
+// GetSampleRows implements the common.SamplingInfoSchema interface.
+func (isi InfoSchemaImpl) GetSampleRows(conv *internal.Conv, tableId string, colIds []string, n int64) (*sql.Rows, error) {
+	srcSchema := conv.SrcSchema[tableId]
+	q := fmt.Sprintf("SELECT %s FROM `%s`.`%s` LIMIT %d;", buildColNameList(conv, tableId, colIds), srcSchema.Schema, srcSchema.Name, n)
+	return isi.Db.Query(q)
+}
+
 // Building list of column names to support mysql spatial datatypes instead of
-// using 'SELECT *' because spatial columns will be fetched using ST_AsText(colName).
-func buildColNameList(srcSchema schema.Table, srcColName []string) string {
-	var srcColTypes []string
-	var colList, colTmpName string
-	for _, colName := range srcColName {
+// using 'SELECT *' because spatial columns will be fetched using ST_AsText(colName),
+// or ST_AsGeoJSON(colName) if they are migrated to JSON columns.
+func buildColNameList(conv *internal.Conv, tableId string, colIds []string) string {
+	srcSchema := conv.SrcSchema[tableId]
+	var colList []string
+	for _, colId := range colIds {
 		// To handle cases where column name is reserved keyword or having space between words.
-		colTmpName = "`" + colName + "`"
-		srcColTypes = append(srcColTypes, srcSchema.ColDefs[colName].Type.Name)
-		for _, spatial := range MysqlSpatialDataTypes {
-			if strings.Contains(strings.ToLower(srcSchema.ColDefs[colName].Type.Name), spatial) {
-				colTmpName = "ST_AsText" + "(" + colTmpName + ")" + colTmpName
-				break
-			}
+		colName := "`" + srcSchema.ColDefs[colId].Name + "`"
+		if isSpatialType(srcSchema.ColDefs[colId].Type.Name) {
+			colName = spatialColumnExpr(colName, isGeoJSONColumn(conv, tableId, colId)) + colName
 		}
-		colList = colList + colTmpName + ","
+		colList = append(colList, colName)
 	}
-	return colList[:len(colList)-1]
+	return strings.Join(colList, ",")
 }
 
 // ProcessData performs data conversion for source database.
-func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, tableId string, srcSchema schema.Table, commonColIds []string, spSchema ddl.CreateTable) error {
+func (isi InfoSchemaImpl) ProcessData(ctx context.Context, conv *internal.Conv, tableId string, srcSchema schema.Table, commonColIds []string, spSchema ddl.CreateTable) error {
 	srcTableName := conv.SrcSchema[tableId].Name
-	rowsInterface, err := isi.GetRowsFromTable(conv, tableId)
+	rowsInterface, err := isi.getRowsFromTable(ctx, conv, tableId)
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTableName, err))
 		return err
@@ -120,13 +125,14 @@ func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, tableId string, srcSc
 		if err != nil {
 			conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
 			conv.StatsAddBadRow(srcTableName, conv.DataMode())
-			conv.CollectBadRow(srcTableName, srcCols, values)
+			conv.CollectBadRow(srcTableName, srcCols, values, err)
 			continue
 		}
 
 		ProcessDataRow(conv, tableId, commonColIds, srcSchema, spSchema, newValues)
 	}
-	return nil
+	// The query is cancelled once ctx is done, which ends the rows early.
+	return ctx.Err()
 }
 
 // GetRowCount with number of rows in each table.
@@ -359,8 +365,12 @@ func (isi InfoSchemaImpl) GetIndexes(conv *internal.Conv, table common.SchemaAnd
 }
 
 // StartChangeDataCapture is used for automatic triggering of Datastream job when
-// performing a streaming migration.
+// performing a streaming migration. With cdc=binlog, it instead captures the
+// binlog position from which changes are streamed in-process.
 func (isi InfoSchemaImpl) StartChangeDataCapture(ctx context.Context, conv *internal.Conv) (map[string]interface{}, error) {
+	if isi.SourceProfile.Conn.Mysql.Cdc == profiles.BinlogCdc {
+		return isi.startBinlogCapture()
+	}
 	mp := make(map[string]interface{})
 	streamingCfg, err := streaming.StartDatastream(ctx, isi.SourceProfile, isi.TargetProfile)
 	if err != nil {
@@ -372,8 +382,12 @@ func (isi InfoSchemaImpl) StartChangeDataCapture(ctx context.Context, conv *inte
 }
 
 // StartStreamingMigration is used for automatic triggering of Dataflow job when
-// performing a streaming migration.
+// performing a streaming migration. With cdc=binlog, it instead streams the
+// changes in the binlog to Spanner until cutover.
 func (isi InfoSchemaImpl) StartStreamingMigration(ctx context.Context, client *sp.Client, conv *internal.Conv, streamingInfo map[string]interface{}) error {
+	if isi.SourceProfile.Conn.Mysql.Cdc == profiles.BinlogCdc {
+		return isi.streamBinlog(client, conv, streamingInfo)
+	}
 	streamingCfg, _ := streamingInfo["streamingCfg"].(streaming.StreamingCfg)
 
 	err := streaming.StartDataflow(ctx, isi.SourceProfile, isi.TargetProfile, streamingCfg, conv)
@@ -387,7 +401,9 @@ func (isi InfoSchemaImpl) StartStreamingMigration(ctx context.Context, client *s
 func toType(dataType string, columnType string, charLen sql.NullInt64, numericPrecision, numericScale sql.NullInt64) schema.Type {
 	switch {
 	case dataType == "set":
-		return schema.Type{Name: dataType, ArrayBounds: []int64{-1}}
+		return schema.Type{Name: dataType, ArrayBounds: []int64{-1}, EnumValues: parseEnumValues(columnType)}
+	case dataType == "enum":
+		return schema.Type{Name: dataType, EnumValues: parseEnumValues(columnType)}
 	case charLen.Valid:
 		return schema.Type{Name: dataType, Mods: []int64{charLen.Int64}}
 	case dataType == "decimal" && numericPrecision.Valid && numericScale.Valid && numericScale.Int64 != 0:
@@ -15,8 +15,10 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
+	"regexp"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -154,7 +156,7 @@ func TestProcessSchemaMYSQL(t *testing.T) {
 			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra"},
 			rows: [][]driver.Value{
 				{"id", "bigint", "bigint", "NO", nil, nil, 64, 0, nil},
-				{"s", "set", "set", "YES", nil, nil, nil, nil, nil},
+				{"s", "set", "set('a','b')", "YES", nil, nil, nil, nil, nil},
 				{"txt", "text", "text", "NO", nil, nil, nil, nil, nil},
 				{"b", "boolean", "boolean", "YES", nil, nil, nil, nil, nil},
 				{"bs", "bigint", "bigint", "NO", "nextval('test11_bs_seq'::regclass)", nil, 64, 0, nil},
@@ -239,7 +241,7 @@ func TestProcessSchemaMYSQL(t *testing.T) {
 			"i4":  schema.Column{Name: "i4", Type: schema.Type{Name: "integer", Mods: []int64(nil), ArrayBounds: []int64(nil)}, NotNull: false, Ignored: schema.Ignored{Check: false, Identity: false, Default: false, Exclusion: false, ForeignKey: false, AutoIncrement: true}, Id: ""},
 			"i8":  schema.Column{Name: "i8", Type: schema.Type{Name: "bigint", Mods: []int64(nil), ArrayBounds: []int64(nil)}, NotNull: false, Ignored: schema.Ignored{Check: false, Identity: false, Default: false, Exclusion: false, ForeignKey: false, AutoIncrement: false}, Id: ""},
 			"id":  schema.Column{Name: "id", Type: schema.Type{Name: "bigint", Mods: []int64(nil), ArrayBounds: []int64(nil)}, NotNull: true, Ignored: schema.Ignored{Check: false, Identity: false, Default: false, Exclusion: false, ForeignKey: false, AutoIncrement: false}, Id: ""},
-			"s":   schema.Column{Name: "s", Type: schema.Type{Name: "set", Mods: []int64(nil), ArrayBounds: []int64{-1}}, NotNull: false, Ignored: schema.Ignored{Check: false, Identity: false, Default: false, Exclusion: false, ForeignKey: false, AutoIncrement: false}, Id: ""},
+			"s":   schema.Column{Name: "s", Type: schema.Type{Name: "set", Mods: []int64(nil), ArrayBounds: []int64{-1}, EnumValues: []string{"a", "b"}}, NotNull: false, Ignored: schema.Ignored{Check: false, Identity: false, Default: false, Exclusion: false, ForeignKey: false, AutoIncrement: false}, Id: ""},
 			"si":  schema.Column{Name: "si", Type: schema.Type{Name: "integer", Mods: []int64(nil), ArrayBounds: []int64(nil)}, NotNull: true, Ignored: schema.Ignored{Check: false, Identity: false, Default: true, Exclusion: false, ForeignKey: false, AutoIncrement: false}, Id: ""},
 			"ts":  schema.Column{Name: "ts", Type: schema.Type{Name: "datetime", Mods: []int64(nil), ArrayBounds: []int64(nil)}, NotNull: false, Ignored: schema.Ignored{Check: false, Identity: false, Default: false, Exclusion: false, ForeignKey: false, AutoIncrement: false}, Id: ""},
 			"txt": schema.Column{Name: "txt", Type: schema.Type{Name: "text", Mods: []int64(nil), ArrayBounds: []int64(nil)}, NotNull: true, Ignored: schema.Ignored{Check: false, Identity: false, Default: false, Exclusion: false, ForeignKey: false, AutoIncrement: false}, Id: ""},
@@ -308,7 +310,7 @@ func TestProcessData(t *testing.T) {
 			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
 		})
 	isi := InfoSchemaImpl{"test", db, profiles.SourceProfile{}, profiles.TargetProfile{}}
-	common.ProcessData(conv, isi)
+	common.ProcessData(context.Background(), conv, isi)
 	assert.Equal(t,
 		[]spannerData{
 			spannerData{table: "te_st", cols: []string{"a_a", "Ab", "Ac_"}, vals: []interface{}{float64(42.3), int64(3), "cat"}},
@@ -392,7 +394,7 @@ func TestProcessData_MultiCol(t *testing.T) {
 		func(table string, cols []string, vals []interface{}) {
 			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
 		})
-	common.ProcessData(conv, isi)
+	common.ProcessData(context.Background(), conv, isi)
 	assert.Equal(t, []spannerData{
 		{table: "test", cols: []string{"a", "b", "synth_id"}, vals: []interface{}{"cat", float64(42.3), "0"}},
 		{table: "test", cols: []string{"a", "c", "synth_id"}, vals: []interface{}{"dog", int64(22), "-9223372036854775808"}}},
@@ -400,6 +402,73 @@ func TestProcessData_MultiCol(t *testing.T) {
 	assert.Equal(t, int64(0), conv.Unexpecteds())
 }
 
+func TestBuildColNameList(t *testing.T) {
+	conv := internal.MakeConv()
+	conv.SrcSchema["t1"] = schema.Table{
+		Name:   "places",
+		Id:     "t1",
+		ColIds: []string{"c1", "c2", "c3"},
+		ColDefs: map[string]schema.Column{
+			"c1": {Name: "id", Id: "c1", Type: schema.Type{Name: "int"}},
+			"c2": {Name: "loc", Id: "c2", Type: schema.Type{Name: "point"}},
+			"c3": {Name: "area", Id: "c3", Type: schema.Type{Name: "polygon"}},
+		},
+	}
+	conv.SpSchema["t1"] = ddl.CreateTable{
+		Name:   "places",
+		Id:     "t1",
+		ColIds: []string{"c1", "c2", "c3"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"c1": {Name: "id", Id: "c1", T: ddl.Type{Name: ddl.Int64}},
+			"c2": {Name: "loc", Id: "c2", T: ddl.Type{Name: ddl.JSON}},
+			"c3": {Name: "area", Id: "c3", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+		},
+	}
+	assert.Equal(t, "`id`,ST_AsGeoJSON(`loc`, 4294967295, 2)`loc`,ST_AsText(`area`)`area`", buildColNameList(conv, "t1", []string{"c1", "c2", "c3"}))
+}
+
+func TestGetSampleRows(t *testing.T) {
+	ms := []mockSpec{
+		{
+			query: regexp.QuoteMeta("SELECT `code`,ST_AsText(`area`)`area` FROM `test`.`places` LIMIT 100;"),
+			cols:  []string{"code", "area"},
+			rows:  [][]driver.Value{{"AB", "POINT(1 2)"}},
+		},
+	}
+	db := mkMockDB(t, ms)
+	conv := internal.MakeConv()
+	conv.SrcSchema["t1"] = schema.Table{
+		Name:   "places",
+		Schema: "test",
+		Id:     "t1",
+		ColIds: []string{"c1", "c2", "c3"},
+		ColDefs: map[string]schema.Column{
+			"c1": {Name: "id", Id: "c1", Type: schema.Type{Name: "int"}},
+			"c2": {Name: "code", Id: "c2", Type: schema.Type{Name: "text"}},
+			"c3": {Name: "area", Id: "c3", Type: schema.Type{Name: "polygon"}},
+		},
+	}
+	conv.SpSchema["t1"] = ddl.CreateTable{
+		Name:   "places",
+		Id:     "t1",
+		ColIds: []string{"c1", "c2", "c3"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"c1": {Name: "id", Id: "c1", T: ddl.Type{Name: ddl.Int64}},
+			"c2": {Name: "code", Id: "c2", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			"c3": {Name: "area", Id: "c3", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+		},
+	}
+	isi := InfoSchemaImpl{Db: db}
+	rows, err := isi.GetSampleRows(conv, "t1", []string{"c2", "c3"}, 100)
+	assert.Nil(t, err)
+	defer rows.Close()
+	var code, area string
+	assert.True(t, rows.Next())
+	assert.Nil(t, rows.Scan(&code, &area))
+	assert.Equal(t, "AB", code)
+	assert.Equal(t, "POINT(1 2)", area)
+}
+
 func TestSetRowStats(t *testing.T) {
 	ms := []mockSpec{
 		{
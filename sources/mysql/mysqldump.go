@@ -15,6 +15,7 @@
 package mysql
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"regexp"
@@ -47,18 +48,30 @@ var spatialRegexps = func() []*regexp.Regexp {
 }()
 var spatialIndexRegex = regexp.MustCompile("(?i)\\sSPATIAL\\s")
 var spatialSridRegex = regexp.MustCompile("(?i)\\sSRID\\s\\d*")
+var spatialColumnRegex = regexp.MustCompile("(?i)`([^`]+)`\\s+(" + strings.Join(MysqlSpatialDataTypes, "|") + ")\\b")
+
+// spatialCreateTableStmt is a CREATE TABLE statement whose spatial column
+// types were replaced with text so that it can be parsed (see
+// handleSpatialDatatype). spatialCols maps the names of these columns to
+// their spatial types.
+type spatialCreateTableStmt struct {
+	*ast.CreateTableStmt
+	spatialCols map[string]string
+}
 
 // DbDumpImpl MySQL specific implementation for DdlDumpImpl.
-type DbDumpImpl struct{}
+type DbDumpImpl struct {
+	SpatialFormat string // See ToDdlImpl.
+}
 
 // GetToDdl function below implement the common.DbDump interface.
 func (ddi DbDumpImpl) GetToDdl() common.ToDdl {
-	return ToDdlImpl{}
+	return ToDdlImpl{SpatialFormat: ddi.SpatialFormat}
 }
 
 // ProcessDump processes the mysql dump.
-func (ddi DbDumpImpl) ProcessDump(conv *internal.Conv, r *internal.Reader) error {
-	return processMySQLDump(conv, r)
+func (ddi DbDumpImpl) ProcessDump(ctx context.Context, conv *internal.Conv, r *internal.Reader) error {
+	return processMySQLDump(ctx, conv, r)
 }
 
 // ProcessMySQLDump reads mysqldump data from r and does schema or data conversion,
@@ -66,8 +79,11 @@ func (ddi DbDumpImpl) ProcessDump(conv *internal.Conv, r *internal.Reader) error
 // In schema mode, ProcessMySQLDump incrementally builds a schema (updating conv).
 // In data mode, ProcessMySQLDump uses this schema to convert MySQL data
 // and writes it to Spanner, using the data sink specified in conv.
-func processMySQLDump(conv *internal.Conv, r *internal.Reader) error {
+func processMySQLDump(ctx context.Context, conv *internal.Conv, r *internal.Reader) error {
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		startLine := r.LineNumber
 		startOffset := r.Offset
 		b, stmts, err := readAndParseChunk(conv, r)
@@ -153,6 +169,11 @@ func processStatement(conv *internal.Conv, stmt ast.StmtNode) bool {
 		if conv.SchemaMode() {
 			processCreateTable(conv, s)
 		}
+	case *spatialCreateTableStmt:
+		if conv.SchemaMode() {
+			processCreateTable(conv, s.CreateTableStmt)
+			restoreSpatialTypes(conv, s)
+		}
 	case *ast.AlterTableStmt:
 		if conv.SchemaMode() {
 			processAlterTable(conv, s)
@@ -477,6 +498,9 @@ func processColumn(conv *internal.Conv, tableName string, col *ast.ColumnDef) (s
 		Name:        tid,
 		Mods:        mods,
 		ArrayBounds: getArrayBounds(col.Tp.String(), col.Tp.GetElems())}
+	if tid == "enum" || tid == "set" {
+		ty.EnumValues = col.Tp.GetElems()
+	}
 	column := schema.Column{Name: name, Type: ty}
 	return name, column, updateColsByOption(conv, tableName, col, &column), nil
 }
@@ -609,10 +633,8 @@ func handleParseError(conv *internal.Conv, chunk string, err error, l [][]byte)
 	for _, spatial := range MysqlSpatialDataTypes {
 		if strings.Contains(errMsg, `near "`+spatial) {
 			if conv.SchemaMode() {
-				conv.Unexpected(fmt.Sprintf("Unsupported datatype '%s' encountered while parsing following statement at line number %d : \n%s", spatial, len(l), chunk))
 				internal.VerbosePrintf("Converting datatype '%s' to 'Text' and retrying to parse the statement\n", spatial)
 				logger.Log.Debug(fmt.Sprintf("Converting datatype '%s' to 'Text' and retrying to parse the statement\n", spatial))
-
 			}
 			return handleSpatialDatatype(conv, chunk, l)
 		}
@@ -656,10 +678,16 @@ func handleInsertStatement(conv *internal.Conv, chunk, insertStmtPrefix string)
 // a) Replace spatial datatype with 'text'.
 // b) Remove 'SPATIAL' keyword from Index/Key.
 // c) Remove SRID(spatial reference identifier) attribute.
+// CREATE TABLE statements are returned as spatialCreateTableStmt, so that
+// the spatial types of their columns are kept in the source schema.
 func handleSpatialDatatype(conv *internal.Conv, chunk string, l [][]byte) ([]ast.StmtNode, bool) {
 	if !conv.SchemaMode() {
 		return nil, true
 	}
+	spatialCols := make(map[string]string)
+	for _, m := range spatialColumnRegex.FindAllStringSubmatch(chunk, -1) {
+		spatialCols[m[1]] = strings.ToLower(m[2])
+	}
 	for _, spatialRegexp := range spatialRegexps {
 		chunk = spatialRegexp.ReplaceAllString(chunk, " text")
 	}
@@ -669,9 +697,33 @@ func handleSpatialDatatype(conv *internal.Conv, chunk string, l [][]byte) ([]ast
 	if err != nil {
 		return nil, false
 	}
+	for i, stmt := range newTree {
+		if createTable, ok := stmt.(*ast.CreateTableStmt); ok {
+			newTree[i] = &spatialCreateTableStmt{CreateTableStmt: createTable, spatialCols: spatialCols}
+		}
+	}
 	return newTree, true
 }
 
+// restoreSpatialTypes sets the types of the spatial columns of a table
+// created by stmt back to their spatial types.
+func restoreSpatialTypes(conv *internal.Conv, stmt *spatialCreateTableStmt) {
+	tableName, err := getTableName(stmt.Table)
+	if err != nil {
+		return
+	}
+	tbl, ok := internal.GetSrcTableByName(conv.SrcSchema, tableName)
+	if !ok {
+		return
+	}
+	for colId, col := range tbl.ColDefs {
+		if spatial, ok := stmt.spatialCols[col.Name]; ok {
+			col.Type = schema.Type{Name: spatial}
+			tbl.ColDefs[colId] = col
+		}
+	}
+}
+
 // skipUnsupported skips the stored programs that are not supported
 // by pingcap parser.
 func skipUnsupported(conv *internal.Conv, chunk string) bool {
@@ -753,20 +805,54 @@ func processInsertStmt(conv *internal.Conv, stmt *ast.InsertStmt) {
 	commonColIds := common.IntersectionOfTwoStringSlices(conv.SpSchema[tableId].ColIds, srcColIds)
 	spSchema := conv.SpSchema[tableId]
 	colNameIdMap := internal.GetSrcColNameIdMap(conv.SrcSchema[tableId])
+	// Spatial values are in MySQL's internal binary format, and are
+	// converted to WKT or GeoJSON, like data read from the database.
+	spatialCols := make(map[int]bool)
+	for i, colId := range srcColIds {
+		if isSpatialType(srcSchema.ColDefs[colId].Type.Name) {
+			spatialCols[i] = isGeoJSONColumn(conv, tableId, colId)
+		}
+	}
 	for _, row := range stmt.Lists {
 		values, err = getVals(row)
+		if err == nil {
+			err = decodeSpatialVals(values, spatialCols)
+		}
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
+			conv.StatsAddBadRow(srcSchema.Name, conv.DataMode())
+			conv.CollectBadRow(srcSchema.Name, srcCols, values, err)
+			continue
+		}
 		//prepare values
 		newValues, err2 := common.PrepareValues(conv, tableId, colNameIdMap, commonColIds, srcCols, values)
 		if err2 != nil {
 			conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
 			conv.StatsAddBadRow(srcSchema.Name, conv.DataMode())
-			conv.CollectBadRow(srcSchema.Name, srcCols, values)
+			conv.CollectBadRow(srcSchema.Name, srcCols, values, err2)
 			continue
 		}
 		ProcessDataRow(conv, tableId, commonColIds, srcSchema, spSchema, newValues)
 	}
 }
 
+// decodeSpatialVals decodes the values of the spatial columns of a row,
+// given by spatialCols, which maps their indexes to whether they are
+// converted to GeoJSON.
+func decodeSpatialVals(values []string, spatialCols map[int]bool) error {
+	for i, geoJSON := range spatialCols {
+		if i >= len(values) || values[i] == "<nil>" {
+			continue
+		}
+		v, err := decodeDumpGeometry(values[i], geoJSON)
+		if err != nil {
+			return err
+		}
+		values[i] = v
+	}
+	return nil
+}
+
 func getCols(stmt *ast.InsertStmt) ([]string, error) {
 	if stmt.Columns == nil {
 		return nil, fmt.Errorf("No columns found in insert statement ")
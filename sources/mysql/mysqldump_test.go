@@ -16,6 +16,8 @@ package mysql
 
 import (
 	"bufio"
+	"context"
+	"encoding/hex"
 	"fmt"
 	"math/big"
 	"math/bits"
@@ -25,6 +27,7 @@ import (
 
 	"cloud.google.com/go/spanner"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
 	"github.com/stretchr/testify/assert"
@@ -56,7 +59,8 @@ func TestProcessMySQLDump_Scalar(t *testing.T) {
 		{"tinytext", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
 		{"mediumtext", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
 		{"longtext", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
-		{"enum('a','b')", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+		{"enum('a','b')", ddl.Type{Name: ddl.String, Len: 1}},
+		{"enum('small','medium')", ddl.Type{Name: ddl.String, Len: 6}},
 		{"timestamp", ddl.Type{Name: ddl.Timestamp}},
 		{"datetime", ddl.Type{Name: ddl.Timestamp}},
 		{"varchar(42)", ddl.Type{Name: ddl.String, Len: int64(42)}},
@@ -78,7 +82,8 @@ func TestProcessMySQLDump_SingleCol(t *testing.T) {
 		ty       string
 		expected ddl.ColumnDef
 	}{
-		{"set('a','b','c')", ddl.ColumnDef{Name: "a", T: ddl.Type{Name: "STRING", Len: 9223372036854775807, IsArray: true}, NotNull: false, Comment: ""}},
+		{"set('a','b','c')", ddl.ColumnDef{Name: "a", T: ddl.Type{Name: "STRING", Len: 1, IsArray: true}, NotNull: false, Comment: ""}},
+		{"enum('x','y''z') NOT NULL", ddl.ColumnDef{Name: "a", T: ddl.Type{Name: ddl.String, Len: 3}, NotNull: true, Checks: []string{`VALUE IN ('x', 'y\'z')`}}},
 		{"text NOT NULL", ddl.ColumnDef{Name: "a", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, NotNull: true}},
 	}
 
@@ -840,6 +845,60 @@ func TestProcessMySQLDump_DataError(t *testing.T) {
 	}
 }
 
+func TestProcessMySQLDump_Spatial(t *testing.T) {
+	// POINT(1 2) with SRID 4326, as dumped by mysqldump --hex-blob.
+	point := "0x" + hex.EncodeToString(concat(wkbUint32(4326), wkbHeader(1), wkbCoords(1, 2)))
+	// LINESTRING(0 0,1 1.5) without SRID, as a binary string.
+	line := string(concat(wkbUint32(0), wkbHeader(2), wkbUint32(2), wkbCoords(0, 0, 1, 1.5)))
+	dump := "CREATE TABLE `places` (\n" +
+		"  `id` int NOT NULL,\n" +
+		"  `loc` point NOT NULL /*!80003 SRID 4326 */,\n" +
+		"  `route` linestring DEFAULT NULL,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  SPATIAL KEY `loc` (`loc`)\n" +
+		") ENGINE=InnoDB;\n" +
+		"INSERT INTO `places` VALUES (1," + point + ",_binary '" + strings.ReplaceAll(line, "'", "\\'") + "'),(2," + point + ",NULL);\n"
+	tests := []struct {
+		spatialFormat string
+		wantType      ddl.Type
+		wantIssue     internal.SchemaIssue
+		wantPoint     string
+		wantLine      string
+	}{
+		{"", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, internal.NoGoodType, "POINT(1 2)", "LINESTRING(0 0,1 1.5)"},
+		{profiles.SpatialGeoJSON, ddl.Type{Name: ddl.JSON}, internal.SpatialGeoJSON,
+			`{"type":"Point","crs":{"type":"name","properties":{"name":"EPSG:4326"}},"coordinates":[1,2]}`,
+			`{"type":"LineString","coordinates":[[0,0],[1,1.5]]}`},
+	}
+	for _, tc := range tests {
+		conv := internal.MakeConv()
+		conv.SetSchemaMode()
+		dbDump := DbDumpImpl{SpatialFormat: tc.spatialFormat}
+		assert.Nil(t, common.ProcessDbDump(context.Background(), conv, internal.NewReader(bufio.NewReader(strings.NewReader(dump)), nil), dbDump))
+		noIssues(conv, t, "Spatial "+tc.spatialFormat)
+		tableId, err := internal.GetTableIdFromSrcName(conv.SrcSchema, "places")
+		assert.Nil(t, err)
+		locId, _ := internal.GetColIdFromSrcName(conv.SrcSchema[tableId].ColDefs, "loc")
+		routeId, _ := internal.GetColIdFromSrcName(conv.SrcSchema[tableId].ColDefs, "route")
+		assert.Equal(t, "point", conv.SrcSchema[tableId].ColDefs[locId].Type.Name)
+		assert.Equal(t, "linestring", conv.SrcSchema[tableId].ColDefs[routeId].Type.Name)
+		assert.Equal(t, tc.wantType, conv.SpSchema[tableId].ColDefs[locId].T)
+		assert.Equal(t, []internal.SchemaIssue{tc.wantIssue}, conv.SchemaIssues[tableId][locId])
+
+		conv.SetDataMode()
+		var rows []spannerData
+		conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
+		})
+		assert.Nil(t, common.ProcessDbDump(context.Background(), conv, internal.NewReader(bufio.NewReader(strings.NewReader(dump)), nil), dbDump))
+		assert.Equal(t, []spannerData{
+			{table: "places", cols: []string{"id", "loc", "route"}, vals: []interface{}{int64(1), tc.wantPoint, tc.wantLine}},
+			{table: "places", cols: []string{"id", "loc"}, vals: []interface{}{int64(2), tc.wantPoint}},
+		}, rows, tc.spatialFormat)
+		assert.Zero(t, conv.BadRows())
+	}
+}
+
 // The following test Conv API calls based on data generated by ProcessMySQLDump.
 func TestProcessMySQLDump_GetDDL(t *testing.T) {
 	conv, _ := runProcessMySQLDump("CREATE TABLE cart (productid text, userid text, quantity bigint);\n" +
@@ -925,13 +984,13 @@ func runProcessMySQLDump(s string) (*internal.Conv, []spannerData) {
 	conv.SetLocation(time.UTC)
 	conv.SetSchemaMode()
 	mysqlDbDump := DbDumpImpl{}
-	common.ProcessDbDump(conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), mysqlDbDump)
+	common.ProcessDbDump(context.Background(), conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), mysqlDbDump)
 	conv.SetDataMode()
 	var rows []spannerData
 	conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
 		rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
 	})
-	common.ProcessDbDump(conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), mysqlDbDump)
+	common.ProcessDbDump(context.Background(), conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), mysqlDbDump)
 	return conv, rows
 }
 
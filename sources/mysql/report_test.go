@@ -17,6 +17,7 @@ package mysql
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"path/filepath"
@@ -57,7 +58,7 @@ func TestReport(t *testing.T) {
       c text);`
 	conv := internal.MakeConv()
 	conv.SetSchemaMode()
-	common.ProcessDbDump(conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), DbDumpImpl{})
+	common.ProcessDbDump(context.Background(), conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), DbDumpImpl{})
 	conv.SetDataMode()
 
 	badSchemaTableId, err := internal.GetTableIdFromSpName(conv.SpSchema, "bad_schema")
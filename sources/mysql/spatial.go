@@ -0,0 +1,299 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// isSpatialType reports whether ty is one of MysqlSpatialDataTypes.
+func isSpatialType(ty string) bool {
+	ty = strings.ToLower(ty)
+	for _, spatial := range MysqlSpatialDataTypes {
+		if ty == spatial {
+			return true
+		}
+	}
+	return false
+}
+
+// isGeoJSONColumn reports whether the source column colId is a spatial
+// column migrated to a JSON column, whose data is converted to GeoJSON.
+func isGeoJSONColumn(conv *internal.Conv, tableId, colId string) bool {
+	srcCol, ok := conv.SrcSchema[tableId].ColDefs[colId]
+	if !ok || !isSpatialType(srcCol.Type.Name) {
+		return false
+	}
+	spCol, ok := conv.SpSchema[tableId].ColDefs[colId]
+	return ok && spCol.T.Name == ddl.JSON
+}
+
+// maxGeoJSONDigits is the maximum number of decimal digits of ST_AsGeoJSON,
+// which means coordinates aren't rounded.
+const maxGeoJSONDigits = 4294967295
+
+// spatialColumnExpr returns the expression used to read the spatial column
+// col: GeoJSON with the SRID as a short-form CRS if it's migrated to a JSON
+// column, and WKT otherwise.
+func spatialColumnExpr(col string, geoJSON bool) string {
+	if geoJSON {
+		return fmt.Sprintf("ST_AsGeoJSON(%s, %d, 2)", col, maxGeoJSONDigits)
+	}
+	return "ST_AsText(" + col + ")"
+}
+
+// decodeGeometry converts a geometry in MySQL's internal format, a 4 byte
+// SRID followed by the geometry in Well-Known Binary format, to WKT or to
+// GeoJSON. This is the format of spatial values in the binlog and in
+// mysqldump files.
+func decodeGeometry(data []byte, geoJSON bool) (string, error) {
+	if len(data) < 4 {
+		return "", fmt.Errorf("geometry value too short")
+	}
+	if !geoJSON {
+		return wkbToWKT(data[4:])
+	}
+	srid := uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16 | uint32(data[3])<<24
+	return wkbToGeoJSON(data[4:], srid)
+}
+
+// decodeDumpGeometry decodes a spatial value in a mysqldump file, which is
+// either a hex literal (with --hex-blob) or a binary string.
+func decodeDumpGeometry(val string, geoJSON bool) (string, error) {
+	data := []byte(val)
+	if strings.HasPrefix(val, "0x") {
+		var err error
+		if data, err = hex.DecodeString(val[2:]); err != nil {
+			return "", fmt.Errorf("invalid geometry hex literal: %v", err)
+		}
+	}
+	return decodeGeometry(data, geoJSON)
+}
+
+// geoJSONGeometry is a GeoJSON geometry object, as returned by MySQL's
+// ST_AsGeoJSON.
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	CRS         *geoJSONCRS `json:"crs,omitempty"`
+	Coordinates interface{} `json:"coordinates,omitempty"`
+	Geometries  interface{} `json:"geometries,omitempty"`
+}
+
+type geoJSONCRS struct {
+	Type       string            `json:"type"`
+	Properties map[string]string `json:"properties"`
+}
+
+// wkbToGeoJSON converts a geometry in Well-Known Binary format to GeoJSON.
+// Like ST_AsGeoJSON, a non-zero srid is included as a named CRS, e.g.
+// EPSG:4326.
+func wkbToGeoJSON(data []byte, srid uint32) (string, error) {
+	r := &wkbReader{data: data}
+	g := r.geoJSON()
+	if r.err != nil {
+		return "", fmt.Errorf("invalid WKB geometry: %v", r.err)
+	}
+	if srid != 0 {
+		g.CRS = &geoJSONCRS{Type: "name", Properties: map[string]string{"name": fmt.Sprintf("EPSG:%d", srid)}}
+	}
+	b, err := json.Marshal(g)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (r *wkbReader) coords() []float64 {
+	x := math.Float64frombits(r.order.Uint64(r.next(8)))
+	y := math.Float64frombits(r.order.Uint64(r.next(8)))
+	return []float64{x, y}
+}
+
+func (r *wkbReader) coordsList() [][]float64 {
+	n := r.uint32()
+	l := [][]float64{}
+	for i := uint32(0); i < n && r.err == nil; i++ {
+		l = append(l, r.coords())
+	}
+	return l
+}
+
+func (r *wkbReader) coordsRings() [][][]float64 {
+	n := r.uint32()
+	l := [][][]float64{}
+	for i := uint32(0); i < n && r.err == nil; i++ {
+		l = append(l, r.coordsList())
+	}
+	return l
+}
+
+func (r *wkbReader) geoJSON() *geoJSONGeometry {
+	switch r.next(1)[0] {
+	case 0:
+		r.order = binary.BigEndian
+	default:
+		r.order = binary.LittleEndian
+	}
+	geomType := r.uint32()
+	names := map[uint32]string{1: "Point", 2: "LineString", 3: "Polygon", 4: "MultiPoint", 5: "MultiLineString", 6: "MultiPolygon", 7: "GeometryCollection"}
+	g := &geoJSONGeometry{Type: names[geomType]}
+	switch geomType {
+	case 1:
+		g.Coordinates = r.coords()
+	case 2:
+		g.Coordinates = r.coordsList()
+	case 3:
+		g.Coordinates = r.coordsRings()
+	case 4, 5, 6, 7:
+		// Collections contain complete WKB geometries.
+		n := r.uint32()
+		coords := []interface{}{}
+		geoms := []*geoJSONGeometry{}
+		for i := uint32(0); i < n && r.err == nil; i++ {
+			elem := r.geoJSON()
+			coords = append(coords, elem.Coordinates)
+			geoms = append(geoms, elem)
+		}
+		if geomType == 7 {
+			g.Geometries = geoms
+		} else {
+			g.Coordinates = coords
+		}
+	default:
+		if r.err == nil {
+			r.err = fmt.Errorf("unsupported geometry type %d", geomType)
+		}
+	}
+	return g
+}
+
+// wkbToWKT converts a geometry in Well-Known Binary format to the
+// Well-Known Text format returned by MySQL's ST_AsText, which is what we
+// read for spatial columns when migrating data.
+func wkbToWKT(data []byte) (string, error) {
+	r := &wkbReader{data: data}
+	var sb strings.Builder
+	r.geometry(&sb)
+	if r.err != nil {
+		return "", fmt.Errorf("invalid WKB geometry: %v", r.err)
+	}
+	return sb.String(), nil
+}
+
+type wkbReader struct {
+	data  []byte
+	pos   int
+	order binary.ByteOrder
+	err   error
+}
+
+func (r *wkbReader) next(n int) []byte {
+	if r.err != nil || r.pos+n > len(r.data) {
+		if r.err == nil {
+			r.err = fmt.Errorf("unexpected end of data")
+		}
+		return make([]byte, n)
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *wkbReader) uint32() uint32 {
+	return r.order.Uint32(r.next(4))
+}
+
+func (r *wkbReader) point(sb *strings.Builder) {
+	x := math.Float64frombits(r.order.Uint64(r.next(8)))
+	y := math.Float64frombits(r.order.Uint64(r.next(8)))
+	sb.WriteString(strconv.FormatFloat(x, 'f', -1, 64) + " " + strconv.FormatFloat(y, 'f', -1, 64))
+}
+
+// points writes a list of points, e.g. "0 0,1 1".
+func (r *wkbReader) points(sb *strings.Builder) {
+	n := r.uint32()
+	for i := uint32(0); i < n && r.err == nil; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		r.point(sb)
+	}
+}
+
+// rings writes a list of lists of points, e.g. "(0 0,1 1),(2 2,3 3)".
+func (r *wkbReader) rings(sb *strings.Builder) {
+	n := r.uint32()
+	for i := uint32(0); i < n && r.err == nil; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteByte('(')
+		r.points(sb)
+		sb.WriteByte(')')
+	}
+}
+
+func (r *wkbReader) geometry(sb *strings.Builder) {
+	switch r.next(1)[0] {
+	case 0:
+		r.order = binary.BigEndian
+	default:
+		r.order = binary.LittleEndian
+	}
+	geomType := r.uint32()
+	names := map[uint32]string{1: "POINT", 2: "LINESTRING", 3: "POLYGON", 4: "MULTIPOINT", 5: "MULTILINESTRING", 6: "MULTIPOLYGON", 7: "GEOMETRYCOLLECTION"}
+	name, ok := names[geomType]
+	if !ok {
+		if r.err == nil {
+			r.err = fmt.Errorf("unsupported geometry type %d", geomType)
+		}
+		return
+	}
+	sb.WriteString(name + "(")
+	switch geomType {
+	case 1:
+		r.point(sb)
+	case 2:
+		r.points(sb)
+	case 3:
+		r.rings(sb)
+	default:
+		// Collections contain complete WKB geometries.
+		n := r.uint32()
+		for i := uint32(0); i < n && r.err == nil; i++ {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			var elem strings.Builder
+			r.geometry(&elem)
+			s := elem.String()
+			if geomType != 7 {
+				// Strip the element type name, e.g. POINT(0 0) -> (0 0).
+				s = s[strings.IndexByte(s, '('):]
+			}
+			sb.WriteString(s)
+		}
+	}
+	sb.WriteByte(')')
+}
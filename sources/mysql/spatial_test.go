@@ -0,0 +1,126 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWkbToGeoJSON(t *testing.T) {
+	point := concat(wkbHeader(1), wkbCoords(1, 2))
+	line := concat(wkbHeader(2), wkbUint32(2), wkbCoords(0, 0, 1, 1.5))
+	tests := []struct {
+		data []byte
+		srid uint32
+		want string
+	}{
+		{point, 0, `{"type":"Point","coordinates":[1,2]}`},
+		{point, 4326, `{"type":"Point","crs":{"type":"name","properties":{"name":"EPSG:4326"}},"coordinates":[1,2]}`},
+		{line, 0, `{"type":"LineString","coordinates":[[0,0],[1,1.5]]}`},
+		{concat(wkbHeader(3), wkbUint32(1), wkbUint32(4), wkbCoords(0, 0, 1, 0, 1, 1, 0, 0)), 0, `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`},
+		{concat(wkbHeader(4), wkbUint32(2), point, concat(wkbHeader(1), wkbCoords(3, 4))), 0, `{"type":"MultiPoint","coordinates":[[1,2],[3,4]]}`},
+		{concat(wkbHeader(5), wkbUint32(1), line), 0, `{"type":"MultiLineString","coordinates":[[[0,0],[1,1.5]]]}`},
+		{concat(wkbHeader(7), wkbUint32(2), point, line), 3857, `{"type":"GeometryCollection","crs":{"type":"name","properties":{"name":"EPSG:3857"}},"geometries":[{"type":"Point","coordinates":[1,2]},{"type":"LineString","coordinates":[[0,0],[1,1.5]]}]}`},
+		{concat(wkbHeader(7), wkbUint32(0)), 0, `{"type":"GeometryCollection","geometries":[]}`},
+	}
+	for _, tc := range tests {
+		got, err := wkbToGeoJSON(tc.data, tc.srid)
+		assert.Nil(t, err, tc.want)
+		assert.Equal(t, tc.want, got)
+	}
+	_, err := wkbToGeoJSON(wkbHeader(1), 0)
+	assert.NotNil(t, err)
+	_, err = wkbToGeoJSON(wkbHeader(42), 0)
+	assert.NotNil(t, err)
+}
+
+func TestDecodeDumpGeometry(t *testing.T) {
+	// POINT(1 2) with SRID 4326.
+	data := concat(wkbUint32(4326), wkbHeader(1), wkbCoords(1, 2))
+	tests := []struct {
+		val     string
+		geoJSON bool
+		want    string
+	}{
+		{"0x" + hex.EncodeToString(data), false, "POINT(1 2)"},
+		{"0x" + hex.EncodeToString(data), true, `{"type":"Point","crs":{"type":"name","properties":{"name":"EPSG:4326"}},"coordinates":[1,2]}`},
+		{string(data), true, `{"type":"Point","crs":{"type":"name","properties":{"name":"EPSG:4326"}},"coordinates":[1,2]}`},
+	}
+	for _, tc := range tests {
+		got, err := decodeDumpGeometry(tc.val, tc.geoJSON)
+		assert.Nil(t, err, tc.want)
+		assert.Equal(t, tc.want, got)
+	}
+	for _, val := range []string{"0xzz", "0x0000", ""} {
+		_, err := decodeDumpGeometry(val, true)
+		assert.NotNil(t, err, val)
+	}
+}
+
+func wkbHeader(geomType uint32) []byte {
+	b := []byte{1, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(b[1:], geomType)
+	return b
+}
+
+func wkbUint32(n uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, n)
+	return b
+}
+
+func wkbCoords(coords ...float64) []byte {
+	var b []byte
+	for _, c := range coords {
+		v := make([]byte, 8)
+		binary.LittleEndian.PutUint64(v, math.Float64bits(c))
+		b = append(b, v...)
+	}
+	return b
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestWkbToWKT(t *testing.T) {
+	point := concat(wkbHeader(1), wkbCoords(1, 2))
+	line := concat(wkbHeader(2), wkbUint32(2), wkbCoords(0, 0, 1, 1.5))
+	tests := []struct {
+		data []byte
+		want string
+	}{
+		{point, "POINT(1 2)"},
+		{line, "LINESTRING(0 0,1 1.5)"},
+		{concat(wkbHeader(3), wkbUint32(1), wkbUint32(4), wkbCoords(0, 0, 1, 0, 1, 1, 0, 0)), "POLYGON((0 0,1 0,1 1,0 0))"},
+		{concat(wkbHeader(4), wkbUint32(2), point, concat(wkbHeader(1), wkbCoords(3, 4))), "MULTIPOINT((1 2),(3 4))"},
+		{concat(wkbHeader(7), wkbUint32(2), point, line), "GEOMETRYCOLLECTION(POINT(1 2),LINESTRING(0 0,1 1.5))"},
+	}
+	for _, tc := range tests {
+		got, err := wkbToWKT(tc.data)
+		assert.Nil(t, err, tc.want)
+		assert.Equal(t, tc.want, got)
+	}
+	_, err := wkbToWKT(wkbHeader(1))
+	assert.NotNil(t, err)
+	_, err = wkbToWKT(wkbHeader(42))
+	assert.NotNil(t, err)
+}
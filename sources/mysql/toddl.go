@@ -16,8 +16,12 @@
 package mysql
 
 import (
+	"strings"
+	"unicode/utf8"
+
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
@@ -25,6 +29,9 @@ import (
 
 // ToDdlImpl MySQL specific implementation for ToDdl.
 type ToDdlImpl struct {
+	// SpatialFormat is profiles.SpatialGeoJSON if spatial columns are
+	// mapped to JSON by default, rather than STRING.
+	SpatialFormat string
 }
 
 // ToSpannerType maps a scalar source schema type (defined by id and
@@ -33,6 +40,9 @@ type ToDdlImpl struct {
 // conversion issues encountered.
 // Functions below implement the common.ToDdl interface
 func (tdi ToDdlImpl) ToSpannerType(conv *internal.Conv, spType string, srcType schema.Type) (ddl.Type, []internal.SchemaIssue) {
+	if spType == "" && tdi.SpatialFormat == profiles.SpatialGeoJSON && isSpatialType(srcType.Name) {
+		spType = ddl.JSON
+	}
 	ty, issues := toSpannerTypeInternal(srcType, spType)
 	if len(srcType.ArrayBounds) > 1 {
 		ty = ddl.Type{Name: ddl.String, Len: ddl.MaxLength}
@@ -139,6 +149,19 @@ func toSpannerTypeInternal(srcType schema.Type, spType string) (ddl.Type, []inte
 			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
 		}
 	case "set", "enum":
+		// Size the column to the longest allowed value when the values are
+		// known. For a set, this is the length of each array element.
+		if len(srcType.EnumValues) > 0 && (spType == "" || spType == ddl.String) {
+			var n int64
+			for _, v := range srcType.EnumValues {
+				if l := int64(utf8.RuneCountInString(v)); l > n {
+					n = l
+				}
+			}
+			if n > 0 {
+				return ddl.Type{Name: ddl.String, Len: n}, []internal.SchemaIssue{internal.AllowedValues}
+			}
+		}
 		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
 	case "json":
 		switch spType {
@@ -184,7 +207,34 @@ func toSpannerTypeInternal(srcType schema.Type, spType string) (ddl.Type, []inte
 		}
 	case "time", "year":
 		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.Time}
+	case "geometry", "point", "linestring", "polygon", "multipoint", "multilinestring", "multipolygon", "geometrycollection":
+		switch spType {
+		case ddl.JSON:
+			// Data is read as GeoJSON, see spatialColumnExpr and decodeGeometry.
+			return ddl.Type{Name: ddl.JSON}, []internal.SchemaIssue{internal.SpatialGeoJSON}
+		default:
+			// Data is read as WKT.
+			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.NoGoodType}
+		}
 
 	}
 	return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.NoGoodType}
 }
+
+// ToSpannerChecks maps the allowed values of an enum column into a Spanner
+// check constraint. The constraint is only kept when the column has its
+// default Spanner type. Sets are mapped to arrays, and are not checked.
+func (tdi ToDdlImpl) ToSpannerChecks(conv *internal.Conv, srcCol schema.Column, spType ddl.Type) ([]string, []internal.SchemaIssue) {
+	if srcCol.Type.Name != "enum" || len(srcCol.Type.EnumValues) == 0 {
+		return nil, nil
+	}
+	if ty, _ := tdi.ToSpannerType(conv, "", srcCol.Type); ty != spType || spType.IsArray {
+		return nil, []internal.SchemaIssue{internal.CheckConstraint}
+	}
+	pg := conv.SpDialect == constants.DIALECT_POSTGRESQL
+	var vals []string
+	for _, v := range srcCol.Type.EnumValues {
+		vals = append(vals, common.SpannerLiteral(v, pg))
+	}
+	return []string{"VALUE IN (" + strings.Join(vals, ", ") + ")"}, nil
+}
@@ -19,6 +19,7 @@ import (
 
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
@@ -192,6 +193,63 @@ func TestToSpannerPostgreSQLDialectType(t *testing.T) {
 	assert.Equal(t, expectedIssues, conv.SchemaIssues[tableId])
 }
 
+func TestToSpannerSpatialType(t *testing.T) {
+	conv := internal.MakeConv()
+	tests := []struct {
+		name          string
+		spatialFormat string
+		spType        string
+		want          ddl.Type
+		wantIssues    []internal.SchemaIssue
+	}{
+		{"default", "", "", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.NoGoodType}},
+		{"wkt", profiles.SpatialWKT, "", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.NoGoodType}},
+		{"geojson", profiles.SpatialGeoJSON, "", ddl.Type{Name: ddl.JSON}, []internal.SchemaIssue{internal.SpatialGeoJSON}},
+		{"geojson overridden", profiles.SpatialGeoJSON, ddl.String, ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.NoGoodType}},
+		{"json type", "", ddl.JSON, ddl.Type{Name: ddl.JSON}, []internal.SchemaIssue{internal.SpatialGeoJSON}},
+	}
+	for _, tc := range tests {
+		for _, srcType := range MysqlSpatialDataTypes {
+			ty, issues := ToDdlImpl{SpatialFormat: tc.spatialFormat}.ToSpannerType(conv, tc.spType, schema.Type{Name: srcType})
+			assert.Equal(t, tc.want, ty, tc.name+" "+srcType)
+			assert.Equal(t, tc.wantIssues, issues, tc.name+" "+srcType)
+		}
+	}
+	// Other types aren't affected by the spatial format.
+	ty, _ := ToDdlImpl{SpatialFormat: profiles.SpatialGeoJSON}.ToSpannerType(conv, "", schema.Type{Name: "text"})
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, ty)
+}
+
+func TestToSpannerChecks(t *testing.T) {
+	enum := schema.Column{Name: "e", Type: schema.Type{Name: "enum", EnumValues: []string{"a", "b'c"}}}
+	set := schema.Column{Name: "s", Type: schema.Type{Name: "set", ArrayBounds: []int64{-1}, EnumValues: []string{"a", "b'c"}}}
+	tests := []struct {
+		name       string
+		dialect    string
+		col        schema.Column
+		spType     ddl.Type
+		want       []string
+		wantIssues []internal.SchemaIssue
+	}{
+		{"enum", constants.DIALECT_GOOGLESQL, enum, ddl.Type{Name: ddl.String, Len: 3}, []string{`VALUE IN ('a', 'b\'c')`}, nil},
+		{"enum pg", constants.DIALECT_POSTGRESQL, enum, ddl.Type{Name: ddl.String, Len: 3}, []string{`VALUE IN ('a', 'b''c')`}, nil},
+		{"enum widened", constants.DIALECT_GOOGLESQL, enum, ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil, []internal.SchemaIssue{internal.CheckConstraint}},
+		{"enum without values", constants.DIALECT_GOOGLESQL, schema.Column{Name: "e", Type: schema.Type{Name: "enum"}}, ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil, nil},
+		{"set", constants.DIALECT_GOOGLESQL, set, ddl.Type{Name: ddl.String, Len: 3, IsArray: true}, nil, nil},
+	}
+	for _, tc := range tests {
+		conv := internal.MakeConv()
+		conv.SpDialect = tc.dialect
+		checks, issues := ToDdlImpl{}.ToSpannerChecks(conv, tc.col, tc.spType)
+		assert.Equal(t, tc.want, checks, tc.name)
+		assert.Equal(t, tc.wantIssues, issues, tc.name)
+	}
+	// Sets map to arrays sized to the longest allowed value.
+	ty, issues := ToDdlImpl{}.ToSpannerType(internal.MakeConv(), "", set.Type)
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: 3, IsArray: true}, ty)
+	assert.Equal(t, []internal.SchemaIssue{internal.AllowedValues}, issues)
+}
+
 func dropComments(t *ddl.CreateTable) {
 	t.Comment = ""
 	for _, c := range t.ColIds {
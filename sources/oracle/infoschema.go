@@ -50,6 +50,12 @@ func (isi InfoSchemaImpl) GetTableName(dbName string, tableName string) string {
 
 // GetRowsFromTable returns a sql Rows object for a table.
 func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, tableId string) (interface{}, error) {
+	return isi.getRowsFromTable(context.Background(), conv, tableId)
+}
+
+// getRowsFromTable is like GetRowsFromTable, but the query is cancelled
+// once ctx is done.
+func (isi InfoSchemaImpl) getRowsFromTable(ctx context.Context, conv *internal.Conv, tableId string) (interface{}, error) {
 	tbl := conv.SrcSchema[tableId]
 	srcCols := tbl.ColIds
 	if len(srcCols) == 0 {
@@ -57,10 +63,17 @@ func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, tableId string)
 		return nil, nil
 	}
 	q := getSelectQuery(isi.DbName, tbl.Schema, tbl.Name, tbl.ColIds, tbl.ColDefs)
-	rows, err := isi.Db.Query(q)
+	rows, err := isi.Db.QueryContext(ctx, q)
 	return rows, err
 }
 
+// GetSampleRows implements the common.SamplingInfoSchema interface.
+func (isi InfoSchemaImpl) GetSampleRows(conv *internal.Conv, tableId string, colIds []string, n int64) (*sql.Rows, error) {
+	tbl := conv.SrcSchema[tableId]
+	q := fmt.Sprintf("SELECT * FROM (%s) WHERE ROWNUM <= %d", getSelectQuery(isi.DbName, tbl.Schema, tbl.Name, colIds, tbl.ColDefs), n)
+	return isi.Db.Query(q)
+}
+
 func getSelectQuery(srcDb string, schemaName string, tableName string, colIds []string, colDefs map[string]schema.Column) string {
 	var selects = make([]string, len(colIds))
 
@@ -100,9 +113,9 @@ func getSelectQuery(srcDb string, schemaName string, tableName string, colIds []
 }
 
 // ProcessData performs data conversion for source database.
-func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, tableId string, srcSchema schema.Table, commonColIds []string, spSchema ddl.CreateTable) error {
+func (isi InfoSchemaImpl) ProcessData(ctx context.Context, conv *internal.Conv, tableId string, srcSchema schema.Table, commonColIds []string, spSchema ddl.CreateTable) error {
 	srcTableName := conv.SrcSchema[tableId].Name
-	rowsInterface, err := isi.GetRowsFromTable(conv, tableId)
+	rowsInterface, err := isi.getRowsFromTable(ctx, conv, tableId)
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTableName, err))
 		return err
@@ -126,12 +139,13 @@ func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, tableId string, srcSc
 		if err != nil {
 			conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
 			conv.StatsAddBadRow(srcTableName, conv.DataMode())
-			conv.CollectBadRow(srcTableName, srcCols, values)
+			conv.CollectBadRow(srcTableName, srcCols, values, err)
 			continue
 		}
 		ProcessDataRow(conv, tableId, commonColIds, srcSchema, spSchema, newValues)
 	}
-	return nil
+	// The query is cancelled once ctx is done, which ends the rows early.
+	return ctx.Err()
 }
 
 // GetRowCount with number of rows in each table.
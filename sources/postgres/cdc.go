@@ -0,0 +1,879 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	sp "cloud.google.com/go/spanner"
+	"github.com/jackc/pglogrepl"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+)
+
+// This file implements change data capture for PostgreSQL using logical
+// replication with the pgoutput plugin. Changes are read in-process from
+// a replication slot and applied to Spanner. The slot exports a snapshot,
+// which the snapshot migration reads the data in, so that the change
+// stream starts exactly where the snapshot ends. The replication protocol
+// and the pgoutput messages are handled by pglogrepl.
+
+const (
+	// SkipSnapshotKey is set in the map returned by StartChangeDataCapture
+	// when streaming resumes from an existing replication slot, in which
+	// case the snapshot migration has already been done.
+	SkipSnapshotKey = "skipSnapshot"
+	// SnapshotKey is the name of the snapshot exported by the replication
+	// slot, in the map returned by StartChangeDataCapture.
+	SnapshotKey = "snapshot"
+
+	replicationStateKey = "replicationState"
+	replicationConnKey  = "replicationConn"
+	// Maximum number of mutations written to Spanner in one call.
+	replicationBatchSize = 500
+	// Number of consecutive failures to read the replication stream before
+	// giving up.
+	replicationRetryLimit = 10
+)
+
+// Record types used for stats.
+const (
+	recordInsert   = "INSERT"
+	recordUpdate   = "UPDATE"
+	recordDelete   = "DELETE"
+	recordTruncate = "TRUNCATE"
+)
+
+// lsn is a position in the PostgreSQL write-ahead log. It's saved in the
+// state file in the format PostgreSQL uses, e.g. 0/16B3748.
+type lsn uint64
+
+func (l lsn) String() string {
+	return pglogrepl.LSN(l).String()
+}
+
+func parseLSN(s string) (lsn, error) {
+	l, err := pglogrepl.ParseLSN(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN %q", s)
+	}
+	return lsn(l), nil
+}
+
+func (l lsn) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+func (l *lsn) UnmarshalText(text []byte) error {
+	var err error
+	*l, err = parseLSN(string(text))
+	return err
+}
+
+// quoteIdent quotes an identifier for use in an SQL statement or a
+// replication command.
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// replicationState is persisted in the cdc state file.
+type replicationState struct {
+	Slot        string `json:"slot"`
+	Publication string `json:"publication"`
+	// ConfirmedLSN is the position up to which all changes have been
+	// applied to Spanner. It is also confirmed to the server as the flush
+	// position of the slot.
+	ConfirmedLSN lsn `json:"confirmedFlushLsn"`
+	// SnapshotDone is set once the snapshot migration has completed.
+	SnapshotDone bool `json:"snapshotDone"`
+}
+
+// loadReplicationState reads the state persisted in stateFile. It returns
+// false if the file doesn't exist.
+func loadReplicationState(stateFile string) (replicationState, bool, error) {
+	var state replicationState
+	data, err := os.ReadFile(stateFile)
+	if os.IsNotExist(err) {
+		return state, false, nil
+	}
+	if err != nil {
+		return state, false, fmt.Errorf("can't read replication state file %s: %v", stateFile, err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, false, fmt.Errorf("can't parse replication state file %s: %v", stateFile, err)
+	}
+	if state.Slot == "" {
+		return state, false, fmt.Errorf("replication state file %s has no slot name", stateFile)
+	}
+	return state, true, nil
+}
+
+// saveReplicationState persists state in stateFile. The state is written
+// to a temporary file first, so that a crash never leaves a truncated state
+// file.
+func saveReplicationState(stateFile string, state replicationState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := stateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("can't write replication state file: %v", err)
+	}
+	if err := os.Rename(tmp, stateFile); err != nil {
+		return fmt.Errorf("can't write replication state file: %v", err)
+	}
+	return nil
+}
+
+// srcTableIdent returns the quoted, schema qualified name of a source table.
+func srcTableIdent(t schema.Table) string {
+	// Table names outside the public schema are prefixed with their schema,
+	// see GetTableName.
+	name := t.Name
+	if t.Schema != "public" {
+		name = strings.TrimPrefix(name, t.Schema+".")
+	}
+	return quoteIdent(t.Schema) + "." + quoteIdent(name)
+}
+
+// srcPartitionIdent returns the quoted, schema qualified name of a partition
+// of a source table. Partition names have the same format as table names,
+// see GetPartitions.
+func srcPartitionIdent(name string) string {
+	if i := strings.Index(name, "."); i >= 0 {
+		return quoteIdent(name[:i]) + "." + quoteIdent(name[i+1:])
+	}
+	return quoteIdent("public") + "." + quoteIdent(name)
+}
+
+// createPublication creates publication for the tables being migrated,
+// unless it already exists. Tables without a primary key are left out:
+// PostgreSQL rejects updates and deletes on published tables without a
+// replica identity, and their rows can't be matched in Spanner anyway.
+// Partitioned tables are published through their leaf partitions, since
+// PostgreSQL versions before 13 can't publish partitioned tables.
+func createPublication(db *sql.DB, conv *internal.Conv, publication string) error {
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM pg_publication WHERE pubname = $1", publication).Scan(&n); err != nil {
+		return fmt.Errorf("can't read publications: %v", err)
+	}
+	if n > 0 {
+		fmt.Printf("Using existing publication %s.\n", publication)
+		return nil
+	}
+	var tables []string
+	for tableId, srcTable := range conv.SrcSchema {
+		if _, ok := conv.SpSchema[tableId]; !ok {
+			continue
+		}
+		if _, ok := conv.SyntheticPKeys[tableId]; ok {
+			conv.Unexpected(fmt.Sprintf("Changes to table %s not streamed to Spanner: table has no primary key", srcTable.Name))
+			continue
+		}
+		if srcTable.PartitionBy != "" {
+			for _, p := range srcTable.Partitions {
+				tables = append(tables, srcPartitionIdent(p))
+			}
+			continue
+		}
+		tables = append(tables, srcTableIdent(srcTable))
+	}
+	if len(tables) == 0 {
+		return fmt.Errorf("can't create publication %s: no tables with a primary key to publish", publication)
+	}
+	sort.Strings(tables)
+	if _, err := db.Exec(fmt.Sprintf("CREATE PUBLICATION %s FOR TABLE %s", quoteIdent(publication), strings.Join(tables, ", "))); err != nil {
+		return fmt.Errorf("can't create publication %s: %v", publication, err)
+	}
+	fmt.Printf("Created publication %s for %d tables.\n", publication, len(tables))
+	return nil
+}
+
+// readSlot returns the confirmed flush LSN of a replication slot of the
+// current database, and false if the slot doesn't exist.
+func readSlot(db *sql.DB, slot string) (lsn, bool, error) {
+	var plugin, confirmed sql.NullString
+	err := db.QueryRow("SELECT plugin, confirmed_flush_lsn FROM pg_replication_slots WHERE slot_name = $1 AND database = current_database()", slot).Scan(&plugin, &confirmed)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("can't read replication slot %s: %v", slot, err)
+	}
+	if plugin.String != "pgoutput" {
+		return 0, false, fmt.Errorf("replication slot %s uses plugin %q, pgoutput is required", slot, plugin.String)
+	}
+	pos, err := parseLSN(confirmed.String)
+	return pos, true, err
+}
+
+// replicationReader is a source of replication stream messages, usually a
+// replicationStream.
+type replicationReader interface {
+	readCopyData() ([]byte, error)
+	sendStandbyStatus(pos lsn) error
+	Close() error
+}
+
+// replicationStream is a logical replication connection streaming from a
+// replication slot.
+type replicationStream struct {
+	conn   *pgconn.PgConn
+	ctx    context.Context
+	cancel context.CancelFunc
+	// lock serializes reads with Close, which can be called from another
+	// goroutine to interrupt a read.
+	lock sync.Mutex
+}
+
+// dialReplication opens a logical replication connection to the source
+// database. The session settings make the server format values the way
+// the data conversion expects them.
+func dialReplication(cfg profiles.SourceProfileConnectionPostgreSQL) (*pgconn.PgConn, error) {
+	quote := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace
+	connCfg, err := pgconn.ParseConfig(fmt.Sprintf("host='%s' port='%s' user='%s' password='%s' dbname='%s' sslmode=disable",
+		quote(cfg.Host), quote(cfg.Port), quote(cfg.User), quote(cfg.Pwd), quote(cfg.Db)))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range map[string]string{
+		"replication":        "database",
+		"application_name":   "harbourbridge",
+		"client_encoding":    "UTF8",
+		"DateStyle":          "ISO",
+		"TimeZone":           "UTC",
+		"extra_float_digits": "3",
+	} {
+		connCfg.RuntimeParams[k] = v
+	}
+	return pgconn.ConnectConfig(context.Background(), connCfg)
+}
+
+// startReplication starts streaming the changes decoded by the pgoutput
+// plugin from slot, for the tables in publication. The server skips
+// transactions which committed before start.
+func startReplication(conn *pgconn.PgConn, slot, publication string, start lsn) (*replicationStream, error) {
+	err := pglogrepl.StartReplication(context.Background(), conn, quoteIdent(slot), pglogrepl.LSN(start), pglogrepl.StartReplicationOptions{
+		Mode:       pglogrepl.LogicalReplication,
+		PluginArgs: []string{"proto_version '1'", "publication_names " + quoteLiteral(quoteIdent(publication))},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &replicationStream{conn: conn, ctx: ctx, cancel: cancel}, nil
+}
+
+// readCopyData returns the next message of the replication stream, or
+// io.EOF when the server ends the stream.
+func (r *replicationStream) readCopyData() ([]byte, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for {
+		msg, err := r.conn.ReceiveMessage(r.ctx)
+		if err != nil {
+			return nil, err
+		}
+		switch msg := msg.(type) {
+		case *pgproto3.CopyData:
+			return msg.Data, nil
+		case *pgproto3.CopyDone:
+			return nil, io.EOF
+		case *pgproto3.ErrorResponse:
+			return nil, pgconn.ErrorResponseToPgError(msg)
+		case *pgproto3.NoticeResponse, *pgproto3.ParameterStatus:
+		default:
+			return nil, fmt.Errorf("unexpected message %T in replication stream", msg)
+		}
+	}
+}
+
+// sendStandbyStatus tells the server that all changes up to pos have been
+// applied, which lets it advance the confirmed flush LSN of the slot.
+func (r *replicationStream) sendStandbyStatus(pos lsn) error {
+	return pglogrepl.SendStandbyStatusUpdate(r.ctx, r.conn, pglogrepl.StandbyStatusUpdate{WALWritePosition: pglogrepl.LSN(pos)})
+}
+
+// Close closes the connection. Cancelling the context first interrupts a
+// read in progress.
+func (r *replicationStream) Close() error {
+	r.cancel()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.conn.Close(context.Background())
+}
+
+// replicationCDC applies the changes read from the replication stream to
+// Spanner.
+type replicationCDC struct {
+	conv      *internal.Conv
+	info      *common.StreamingInfo
+	stateFile string
+	// state.ConfirmedLSN is the end of the last transaction applied to
+	// Spanner.
+	state    replicationState
+	tableIds map[string]string // Maps source table name to table id.
+	inTxn    bool
+	pending  []common.PendingMutation
+	// commitTime is the commit time of the current transaction.
+	commitTime time.Time
+	// serverLSN is the latest WAL position reported by the server, which
+	// the lag is measured against.
+	serverLSN  lsn
+	lastCommit time.Time
+	// replyRequested is set when the server asks for a status update.
+	replyRequested bool
+	lastSaved      lsn
+	lastCheck      time.Time
+	lastProgress   time.Time
+	// relations maps relation ids to the last description of the relation
+	// sent in the stream.
+	relations map[uint32]*pglogrepl.RelationMessage
+	connLock  sync.Mutex
+	conn      replicationReader
+}
+
+func newReplicationCDC(conv *internal.Conv, info *common.StreamingInfo, stateFile string, state replicationState) *replicationCDC {
+	c := &replicationCDC{
+		conv:      conv,
+		info:      info,
+		stateFile: stateFile,
+		state:     state,
+		lastSaved: state.ConfirmedLSN,
+		tableIds:  make(map[string]string),
+		relations: make(map[uint32]*pglogrepl.RelationMessage),
+	}
+	for tableId, srcTable := range conv.SrcSchema {
+		if _, ok := conv.SpSchema[tableId]; ok {
+			c.tableIds[srcTable.Name] = tableId
+			// Changes are published for the partitions of partitioned
+			// tables, and applied to the table of their parent.
+			for _, p := range srcTable.Partitions {
+				c.tableIds[p] = tableId
+			}
+		}
+	}
+	return c
+}
+
+// handleCopyData processes a message of the replication stream.
+func (c *replicationCDC) handleCopyData(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("empty replication message")
+	}
+	switch data[0] {
+	case pglogrepl.XLogDataByteID:
+		xld, err := pglogrepl.ParseXLogData(data[1:])
+		if err != nil {
+			return err
+		}
+		c.advanceServerLSN(lsn(xld.ServerWALEnd))
+		m, err := pglogrepl.Parse(xld.WALData)
+		if err != nil {
+			return fmt.Errorf("can't decode pgoutput message: %v", err)
+		}
+		return c.handleMessage(m)
+	case pglogrepl.PrimaryKeepaliveMessageByteID:
+		pkm, err := pglogrepl.ParsePrimaryKeepaliveMessage(data[1:])
+		if err != nil {
+			return err
+		}
+		walEnd := lsn(pkm.ServerWALEnd)
+		c.advanceServerLSN(walEnd)
+		// All transactions committed before walEnd have been sent. When
+		// none is in progress, they have been applied, and confirming
+		// walEnd lets the server recycle WAL which has no changes to the
+		// published tables.
+		if !c.inTxn && walEnd > c.state.ConfirmedLSN {
+			c.state.ConfirmedLSN = walEnd
+		}
+		if pkm.ReplyRequested {
+			c.replyRequested = true
+		}
+	default:
+		return fmt.Errorf("unknown replication message type %q", data[0])
+	}
+	return nil
+}
+
+func (c *replicationCDC) advanceServerLSN(pos lsn) {
+	if pos > c.serverLSN {
+		c.serverLSN = pos
+	}
+}
+
+// handleMessage processes a pgoutput message. Changes are buffered until
+// the end of their transaction, and then written to Spanner.
+func (c *replicationCDC) handleMessage(m pglogrepl.Message) error {
+	switch m := m.(type) {
+	case *pglogrepl.RelationMessage:
+		c.relations[m.RelationID] = m
+	case *pglogrepl.BeginMessage:
+		c.inTxn = true
+		c.pending = nil
+		c.commitTime = m.CommitTime
+	case *pglogrepl.CommitMessage:
+		c.flush()
+		c.inTxn = false
+		c.lastCommit = m.CommitTime.UTC()
+		if end := lsn(m.TransactionEndLSN); end > c.state.ConfirmedLSN {
+			c.state.ConfirmedLSN = end
+		}
+	case *pglogrepl.InsertMessage:
+		return c.processChange(recordInsert, m.RelationID, nil, m.Tuple)
+	case *pglogrepl.UpdateMessage:
+		return c.processChange(recordUpdate, m.RelationID, m.OldTuple, m.NewTuple)
+	case *pglogrepl.DeleteMessage:
+		return c.processChange(recordDelete, m.RelationID, m.OldTuple, nil)
+	case *pglogrepl.TruncateMessage:
+		// Truncating a partitioned table truncates all its partitions, which
+		// are sent as separate relations.
+		truncated := make(map[string]int)
+		var tableIds []string
+		for _, id := range m.RelationIDs {
+			rel, err := c.relation(id)
+			if err != nil {
+				return err
+			}
+			tableId, ok := c.tableIds[InfoSchemaImpl{}.GetTableName(rel.Namespace, rel.RelationName)]
+			if !ok {
+				continue
+			}
+			if truncated[tableId] == 0 {
+				tableIds = append(tableIds, tableId)
+			}
+			truncated[tableId]++
+		}
+		for _, tableId := range tableIds {
+			srcSchema := c.conv.SrcSchema[tableId]
+			if srcSchema.PartitionBy != "" && truncated[tableId] < len(srcSchema.Partitions) {
+				// Truncating some partitions only removes some rows of the
+				// Spanner table, which can't be identified.
+				c.info.Unexpected(fmt.Sprintf("Truncate of partitions of table %s not applied to Spanner", srcSchema.Name))
+				continue
+			}
+			c.info.StatsAddRecord(srcSchema.Name, recordTruncate)
+			spTable := c.conv.SpSchema[tableId].Name
+			c.pending = append(c.pending, common.PendingMutation{M: sp.Delete(spTable, sp.AllKeys()), SrcTable: srcSchema.Name, SpTable: spTable, RecordType: recordTruncate, CommitTime: c.commitTime})
+			c.info.StatsAddRecordProcessed()
+		}
+	}
+	// Origin, type and logical decoding messages aren't needed.
+	return nil
+}
+
+// relation returns the description of a relation, which the server sends
+// before the first change to it in the stream.
+func (c *replicationCDC) relation(id uint32) (*pglogrepl.RelationMessage, error) {
+	rel, ok := c.relations[id]
+	if !ok {
+		return nil, fmt.Errorf("change to unknown relation %d", id)
+	}
+	return rel, nil
+}
+
+// processChange processes an insert, update or delete. oldRow is the old
+// key or row of updates and deletes, and newRow the new row of inserts and
+// updates.
+func (c *replicationCDC) processChange(recordType string, relId uint32, oldRow, newRow *pglogrepl.TupleData) error {
+	rel, err := c.relation(relId)
+	if err != nil {
+		return err
+	}
+	for _, row := range []*pglogrepl.TupleData{oldRow, newRow} {
+		if row != nil && len(row.Columns) != len(rel.Columns) {
+			return fmt.Errorf("tuple has %d columns, relation %s has %d", len(row.Columns), rel.RelationName, len(rel.Columns))
+		}
+	}
+	srcTable := InfoSchemaImpl{}.GetTableName(rel.Namespace, rel.RelationName)
+	tableId, ok := c.tableIds[srcTable]
+	if !ok {
+		return nil
+	}
+	// Changes to partitions are reported against their parent table.
+	srcTable = c.conv.SrcSchema[tableId].Name
+	if _, ok := c.conv.SyntheticPKeys[tableId]; ok {
+		// Rows of tables without a primary key can't be matched to the rows
+		// written during the snapshot migration.
+		c.info.Unexpected(fmt.Sprintf("Changes to table %s not applied to Spanner: table has no primary key", srcTable))
+		return nil
+	}
+	c.info.StatsAddRecord(srcTable, recordType)
+	switch recordType {
+	case recordInsert:
+		c.upsert(tableId, srcTable, rel, recordType, newRow)
+	case recordDelete:
+		c.delete(tableId, srcTable, rel, recordType, oldRow)
+	case recordUpdate:
+		// The old key is only sent if the key changed, in which case the
+		// old row must be deleted.
+		if oldRow != nil {
+			_, _, oldKey, _, _, err1 := c.convertRow(tableId, rel, oldRow, true)
+			_, _, newKey, _, _, err2 := c.convertRow(tableId, rel, newRow, true)
+			if err1 == nil && err2 == nil && !reflect.DeepEqual(oldKey, newKey) {
+				c.delete(tableId, srcTable, rel, recordType, oldRow)
+			}
+		}
+		c.upsert(tableId, srcTable, rel, recordType, newRow)
+	}
+	c.info.StatsAddRecordProcessed()
+	return nil
+}
+
+func (c *replicationCDC) upsert(tableId, srcTable string, rel *pglogrepl.RelationMessage, recordType string, row *pglogrepl.TupleData) {
+	spTable, cols, vals, srcCols, srcVals, err := c.convertRow(tableId, rel, row, false)
+	if err != nil {
+		c.badRecord(srcTable, recordType, srcCols, srcVals, err)
+		return
+	}
+	c.pending = append(c.pending, common.PendingMutation{M: sp.InsertOrUpdate(spTable, cols, vals), SrcTable: srcTable, SpTable: spTable, RecordType: recordType, Cols: cols, Vals: vals, CommitTime: c.commitTime})
+}
+
+func (c *replicationCDC) delete(tableId, srcTable string, rel *pglogrepl.RelationMessage, recordType string, row *pglogrepl.TupleData) {
+	spTable, cols, vals, srcCols, srcVals, err := c.convertRow(tableId, rel, row, true)
+	if err != nil {
+		c.badRecord(srcTable, recordType, srcCols, srcVals, err)
+		return
+	}
+	c.pending = append(c.pending, common.PendingMutation{M: sp.Delete(spTable, sp.Key(vals)), SrcTable: srcTable, SpTable: spTable, RecordType: recordType, Cols: cols, Vals: vals, CommitTime: c.commitTime})
+}
+
+func (c *replicationCDC) badRecord(srcTable, recordType string, srcCols, srcVals []string, err error) {
+	c.info.Unexpected(fmt.Sprintf("Error while converting replication data: %s", err))
+	c.info.StatsAddBadRecord(srcTable, recordType)
+	c.info.CollectBadRecord(recordType, srcTable, srcCols, srcVals)
+}
+
+// convertRow converts a row image to Spanner columns and values. If keyOnly
+// is set, only the primary key columns are converted, in key order. NULL
+// values are returned as nil, so that updates clear the column in Spanner,
+// while unchanged TOASTed values are left out. The source columns and
+// values are returned for error reporting.
+func (c *replicationCDC) convertRow(tableId string, rel *pglogrepl.RelationMessage, row *pglogrepl.TupleData, keyOnly bool) (string, []string, []interface{}, []string, []string, error) {
+	srcSchema := c.conv.SrcSchema[tableId]
+	spSchema := c.conv.SpSchema[tableId]
+	index := make(map[string]int)
+	for i, col := range rel.Columns {
+		index[col.Name] = i
+	}
+	var colIds []string
+	if keyOnly {
+		for _, k := range spSchema.PrimaryKeys {
+			colIds = append(colIds, k.ColId)
+		}
+	} else {
+		colIds = common.IntersectionOfTwoStringSlices(spSchema.ColIds, srcSchema.ColIds)
+	}
+	var cols, srcCols, srcVals []string
+	var vals []interface{}
+	for _, colId := range colIds {
+		srcCd := srcSchema.ColDefs[colId]
+		spCd := spSchema.ColDefs[colId]
+		i, ok := index[srcCd.Name]
+		if !ok || row.Columns[i].DataType == pglogrepl.TupleDataTypeToast {
+			if keyOnly {
+				return "", nil, nil, srcCols, srcVals, fmt.Errorf("key column %s of table %s not found in replication row", srcCd.Name, srcSchema.Name)
+			}
+			continue
+		}
+		if row.Columns[i].DataType == pglogrepl.TupleDataTypeNull {
+			if keyOnly {
+				return "", nil, nil, srcCols, srcVals, fmt.Errorf("key column %s of table %s is NULL", srcCd.Name, srcSchema.Name)
+			}
+			cols = append(cols, spCd.Name)
+			vals = append(vals, nil)
+			continue
+		}
+		if row.Columns[i].DataType != pglogrepl.TupleDataTypeText {
+			return "", nil, nil, srcCols, srcVals, fmt.Errorf("column %s of table %s isn't in text format", srcCd.Name, srcSchema.Name)
+		}
+		val := string(row.Columns[i].Data)
+		srcCols = append(srcCols, srcCd.Name)
+		srcVals = append(srcVals, val)
+		x, err := convValue(c.conv, srcCd, spCd, val)
+		if err != nil {
+			return "", nil, nil, srcCols, srcVals, err
+		}
+		cols = append(cols, spCd.Name)
+		vals = append(vals, x)
+	}
+	return spSchema.Name, cols, vals, srcCols, srcVals, nil
+}
+
+// flush writes the changes of the current transaction to Spanner. Small
+// transactions are written atomically; if a write fails, its mutations are
+// retried one at a time so that only the failing records are dropped.
+func (c *replicationCDC) flush() {
+	pending := c.pending
+	c.pending = nil
+	c.info.Flush(pending, replicationBatchSize)
+}
+
+// lag returns the number of bytes of WAL written by the server which
+// haven't been applied to Spanner yet.
+func (c *replicationCDC) lag() uint64 {
+	if c.serverLSN > c.state.ConfirmedLSN {
+		return uint64(c.serverLSN - c.state.ConfirmedLSN)
+	}
+	return 0
+}
+
+// periodic persists the confirmed LSN, confirms it to the server and
+// reports progress. It's called after every message, and does work at
+// most once a second unless the server requests a reply.
+func (c *replicationCDC) periodic(r replicationReader) error {
+	now := time.Now()
+	if !c.replyRequested && now.Sub(c.lastCheck) < time.Second {
+		return nil
+	}
+	c.lastCheck = now
+	c.replyRequested = false
+	// The state file is written before the position is confirmed, so
+	// that it's never behind the slot.
+	c.saveState()
+	if err := r.sendStandbyStatus(c.state.ConfirmedLSN); err != nil {
+		return err
+	}
+	if now.Sub(c.lastProgress) >= time.Minute {
+		c.lastProgress = now
+		fmt.Printf("Replication position: %s, lag: %d bytes, count of records processed: %d\n", c.state.ConfirmedLSN, c.lag(), c.info.Processed())
+	}
+	return nil
+}
+
+func (c *replicationCDC) saveState() {
+	if c.state.ConfirmedLSN == c.lastSaved {
+		return
+	}
+	if err := saveReplicationState(c.stateFile, c.state); err != nil {
+		c.info.Unexpected(err.Error())
+		return
+	}
+	c.lastSaved = c.state.ConfirmedLSN
+}
+
+// consume reads and processes messages from r until an error occurs.
+func (c *replicationCDC) consume(r replicationReader) error {
+	// A new connection restarts from the start of a transaction.
+	c.pending, c.inTxn = nil, false
+	c.relations = make(map[uint32]*pglogrepl.RelationMessage)
+	for {
+		data, err := r.readCopyData()
+		if err != nil {
+			return err
+		}
+		if err := c.handleCopyData(data); err != nil {
+			return err
+		}
+		if err := c.periodic(r); err != nil {
+			return err
+		}
+	}
+}
+
+// stream reads the replication stream from the confirmed LSN until the
+// user exits, reconnecting if the connection is lost. dial opens a
+// replication stream starting at a position.
+func (c *replicationCDC) stream(dial func(start lsn) (replicationReader, error)) error {
+	var err error
+	failures := 0
+	for !c.info.Exited() {
+		start := c.state.ConfirmedLSN
+		var r replicationReader
+		r, err = dial(start)
+		if err == nil {
+			c.connLock.Lock()
+			c.conn = r
+			c.connLock.Unlock()
+			err = c.consume(r)
+			r.Close()
+		}
+		if c.info.Exited() {
+			err = nil
+			break
+		}
+		if c.state.ConfirmedLSN != start {
+			failures = 0
+		}
+		failures++
+		c.info.Unexpected(fmt.Sprintf("Replication streaming interrupted: %v", err))
+		if failures >= replicationRetryLimit {
+			break
+		}
+		time.Sleep(5 * time.Second)
+	}
+	c.saveState()
+	return err
+}
+
+// catchCtrlC stops replication streaming when the user presses Ctrl+C.
+// The connection is closed to interrupt the read of the next message.
+func (c *replicationCDC) catchCtrlC() {
+	c.info.CatchCtrlC(func() {
+		c.connLock.Lock()
+		if c.conn != nil {
+			c.conn.Close()
+		}
+		c.connLock.Unlock()
+	})
+}
+
+// getRowsInSnapshot reads the rows of a table inside the snapshot exported
+// by the replication slot. The caller must end the returned transaction
+// once the rows have been read.
+func (isi InfoSchemaImpl) getRowsInSnapshot(ctx context.Context, conv *internal.Conv, tableId string) (*sql.Rows, *sql.Tx, error) {
+	tx, err := isi.Db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := tx.Exec("SET TRANSACTION SNAPSHOT " + quoteLiteral(isi.Snapshot)); err != nil {
+		tx.Rollback()
+		return nil, nil, fmt.Errorf("can't read in snapshot %s: %v", isi.Snapshot, err)
+	}
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s;", srcTableIdent(conv.SrcSchema[tableId])))
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+	return rows, tx, nil
+}
+
+// startLogicalReplication sets up the replication slot which changes are
+// streamed from. A new slot exports a snapshot for the snapshot migration.
+// Streaming resumes from the position in the state file, or from an
+// existing slot, in which case the snapshot migration is skipped.
+func (isi InfoSchemaImpl) startLogicalReplication(conv *internal.Conv) (map[string]interface{}, error) {
+	cfg := isi.SourceProfile.Conn.Pg
+	state, ok, err := loadReplicationState(cfg.CdcStateFile)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if !state.SnapshotDone {
+			return nil, fmt.Errorf("the snapshot migration using replication slot %s didn't complete: drop the slot with SELECT pg_drop_replication_slot('%s'), remove %s and restart the migration",
+				state.Slot, state.Slot, cfg.CdcStateFile)
+		}
+		fmt.Printf("Resuming replication from slot %s at %s saved in %s, skipping snapshot migration.\n", state.Slot, state.ConfirmedLSN, cfg.CdcStateFile)
+		return map[string]interface{}{replicationStateKey: state, SkipSnapshotKey: true}, nil
+	}
+	state = replicationState{Slot: cfg.CdcSlot, Publication: cfg.CdcPublication}
+	// The publication must exist before the slot, otherwise decoding
+	// changes made before its creation fails.
+	if err := createPublication(isi.Db, conv, state.Publication); err != nil {
+		return nil, err
+	}
+	pos, exists, err := readSlot(isi.Db, state.Slot)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		state.ConfirmedLSN, state.SnapshotDone = pos, true
+		fmt.Printf("Using existing replication slot %s at %s, skipping snapshot migration.\n", state.Slot, pos)
+		return map[string]interface{}{replicationStateKey: state, SkipSnapshotKey: true}, nil
+	}
+	conn, err := dialReplication(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	res, err := pglogrepl.CreateReplicationSlot(ctx, conn, quoteIdent(state.Slot), "pgoutput", pglogrepl.CreateReplicationSlotOptions{
+		Mode:           pglogrepl.LogicalReplication,
+		SnapshotAction: "EXPORT_SNAPSHOT",
+	})
+	if err == nil {
+		state.ConfirmedLSN, err = parseLSN(res.ConsistentPoint)
+	}
+	if err == nil {
+		if err = saveReplicationState(cfg.CdcStateFile, state); err != nil {
+			// Without the state file, a restart would take the slot for
+			// one whose snapshot migration is done.
+			pglogrepl.DropReplicationSlot(ctx, conn, quoteIdent(state.Slot), pglogrepl.DropReplicationSlotOptions{})
+		}
+	}
+	if err != nil {
+		conn.Close(ctx)
+		return nil, fmt.Errorf("can't create replication slot %s: %v", state.Slot, err)
+	}
+	snapshot := res.SnapshotName
+	fmt.Printf("Created replication slot %s at %s, the snapshot migration reads snapshot %s.\n", state.Slot, state.ConfirmedLSN, snapshot)
+	// The connection must stay open, without running other commands, for
+	// as long as the snapshot is used.
+	return map[string]interface{}{replicationStateKey: state, SnapshotKey: snapshot, replicationConnKey: conn}, nil
+}
+
+// streamReplication applies the changes from the replication slot to
+// Spanner until the user exits with Ctrl+C.
+func (isi InfoSchemaImpl) streamReplication(client *sp.Client, conv *internal.Conv, streamInfo map[string]interface{}) error {
+	cfg := isi.SourceProfile.Conn.Pg
+	state, ok := streamInfo[replicationStateKey].(replicationState)
+	if !ok {
+		return fmt.Errorf("replication slot not set up")
+	}
+	if conn, ok := streamInfo[replicationConnKey].(*pgconn.PgConn); ok {
+		// The snapshot migration is done, the exported snapshot isn't
+		// needed anymore.
+		conn.Close(context.Background())
+	}
+	state.SnapshotDone = true
+	if err := saveReplicationState(cfg.CdcStateFile, state); err != nil {
+		return err
+	}
+	fmt.Println("Processing of the PostgreSQL replication stream started...")
+	fmt.Println("Use Ctrl+C to stop the process.")
+
+	info := common.MakeStreamingInfo()
+	info.SetWriter(client, conv)
+	c := newReplicationCDC(conv, info, cfg.CdcStateFile, state)
+	c.catchCtrlC()
+	err := c.stream(func(start lsn) (replicationReader, error) {
+		conn, err := dialReplication(cfg)
+		if err != nil {
+			return nil, err
+		}
+		r, err := startReplication(conn, state.Slot, state.Publication, start)
+		if err != nil {
+			conn.Close(context.Background())
+			return nil, err
+		}
+		return r, nil
+	})
+	info.FillConv(conv)
+	if err != nil {
+		return fmt.Errorf("replication streaming stopped at %s: %v", c.state.ConfirmedLSN, err)
+	}
+	fmt.Printf("Replication streaming stopped at %s, saved in %s. Drop the replication slot with SELECT pg_drop_replication_slot('%s') once it's not needed anymore.\n",
+		c.state.ConfirmedLSN, cfg.CdcStateFile, state.Slot)
+	return nil
+}
@@ -0,0 +1,559 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	sp "cloud.google.com/go/spanner"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pglogrepl"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+func buildProductConv() *internal.Conv {
+	colIds := []string{"c1", "c2", "c3"}
+	return buildConv(
+		ddl.CreateTable{
+			Name:   "product",
+			Id:     "t1",
+			ColIds: colIds,
+			ColDefs: map[string]ddl.ColumnDef{
+				"c1": {Id: "c1", Name: "id", T: ddl.Type{Name: ddl.Int64}},
+				"c2": {Id: "c2", Name: "name", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+				"c3": {Id: "c3", Name: "tags", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength, IsArray: true}},
+			},
+			PrimaryKeys: []ddl.IndexKey{{ColId: "c1"}},
+		},
+		schema.Table{
+			Name:   "product",
+			Schema: "public",
+			Id:     "t1",
+			ColIds: colIds,
+			ColDefs: map[string]schema.Column{
+				"c1": {Id: "c1", Name: "id", Type: schema.Type{Name: "int8"}},
+				"c2": {Id: "c2", Name: "name", Type: schema.Type{Name: "text"}},
+				"c3": {Id: "c3", Name: "tags", Type: schema.Type{Name: "text", ArrayBounds: []int64{-1}}},
+			},
+			PrimaryKeys: []schema.Key{{ColId: "c1"}},
+		})
+}
+
+// unchangedToast marks an unchanged TOASTed value in tupleData.
+type unchangedToast struct{}
+
+func concat(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+func u16(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+func u32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+func u64(v uint64) []byte { return binary.BigEndian.AppendUint64(nil, v) }
+
+func cstring(s string) []byte {
+	return append([]byte(s), 0)
+}
+
+// tupleData encodes column values: nil is NULL, unchangedToast{} an
+// unchanged TOASTed value and strings are text values.
+func tupleData(vals ...interface{}) []byte {
+	b := u16(uint16(len(vals)))
+	for _, v := range vals {
+		switch v := v.(type) {
+		case nil:
+			b = append(b, pglogrepl.TupleDataTypeNull)
+		case unchangedToast:
+			b = append(b, pglogrepl.TupleDataTypeToast)
+		case string:
+			b = concat(b, []byte{pglogrepl.TupleDataTypeText}, u32(uint32(len(v))), []byte(v))
+		}
+	}
+	return b
+}
+
+// relationMessage describes a table whose first column is the key.
+func relationMessage(id uint32, schema, name string, cols ...string) []byte {
+	b := concat([]byte{byte(pglogrepl.MessageTypeRelation)}, u32(id), cstring(schema), cstring(name), []byte{'d'}, u16(uint16(len(cols))))
+	for i, col := range cols {
+		flags := byte(0)
+		if i == 0 {
+			flags = 1
+		}
+		b = concat(b, []byte{flags}, cstring(col), u32(23), u32(0xffffffff))
+	}
+	return b
+}
+
+// pgMicros returns t in microseconds since 2000-01-01, the epoch of
+// replication messages.
+func pgMicros(t time.Time) uint64 {
+	return uint64(t.Sub(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).Microseconds())
+}
+
+func beginMessage(xid uint32, commitTime time.Time) []byte {
+	return concat([]byte{byte(pglogrepl.MessageTypeBegin)}, u64(0x100), u64(pgMicros(commitTime)), u32(xid))
+}
+
+func commitMessage(end lsn, commitTime time.Time) []byte {
+	return concat([]byte{byte(pglogrepl.MessageTypeCommit), 0}, u64(uint64(end)-8), u64(uint64(end)), u64(pgMicros(commitTime)))
+}
+
+func insertMessage(id uint32, vals ...interface{}) []byte {
+	return concat([]byte{byte(pglogrepl.MessageTypeInsert)}, u32(id), []byte{'N'}, tupleData(vals...))
+}
+
+func updateMessage(id uint32, parts ...[]byte) []byte {
+	return concat(append([][]byte{{byte(pglogrepl.MessageTypeUpdate)}, u32(id)}, parts...)...)
+}
+
+func deleteMessage(id uint32, vals ...interface{}) []byte {
+	return concat([]byte{byte(pglogrepl.MessageTypeDelete)}, u32(id), []byte{'K'}, tupleData(vals...))
+}
+
+func truncateMessage(ids ...uint32) []byte {
+	b := concat([]byte{byte(pglogrepl.MessageTypeTruncate)}, u32(uint32(len(ids))), []byte{0})
+	for _, id := range ids {
+		b = append(b, u32(id)...)
+	}
+	return b
+}
+
+// xlogData wraps a pgoutput message in an XLogData message.
+func xlogData(walEnd lsn, msg []byte) []byte {
+	return concat([]byte{'w'}, u64(uint64(walEnd)), u64(uint64(walEnd)), u64(0), msg)
+}
+
+func keepalive(walEnd lsn, reply bool) []byte {
+	b := concat([]byte{'k'}, u64(uint64(walEnd)), u64(0))
+	if reply {
+		return append(b, 1)
+	}
+	return append(b, 0)
+}
+
+func TestReplicationCDC_HandleCopyData(t *testing.T) {
+	conv := buildProductConv()
+	info := common.MakeStreamingInfo()
+	cols := []string{"id", "name", "tags"}
+	badWrite := sp.InsertOrUpdate("product", cols, []interface{}{int64(4), "bad", []sp.NullString{}})
+	var written [][]*sp.Mutation
+	info.Write = func(ms []*sp.Mutation) error {
+		for _, m := range ms {
+			if reflect.DeepEqual(m, badWrite) {
+				return fmt.Errorf("write failed")
+			}
+		}
+		written = append(written, ms)
+		return nil
+	}
+	c := newReplicationCDC(conv, info, filepath.Join(t.TempDir(), "state.json"), replicationState{Slot: "hb", ConfirmedLSN: 0x100})
+	commitTime := time.Date(2023, 5, 6, 7, 8, 9, 0, time.UTC)
+	handle := func(msgs ...[]byte) {
+		for _, m := range msgs {
+			assert.Nil(t, c.handleCopyData(m))
+		}
+	}
+
+	handle(
+		xlogData(0x180, relationMessage(1, "public", "product", "id", "name", "tags")),
+		xlogData(0x180, relationMessage(2, "other", "product", "id", "name", "tags")),
+		xlogData(0x180, beginMessage(700, commitTime)),
+		xlogData(0x180, insertMessage(1, "1", "apple", `{red,green}`)),
+		xlogData(0x180, insertMessage(1, "2", nil, "{}")),
+		// Tables which aren't migrated are ignored.
+		xlogData(0x180, insertMessage(2, "9", "x", "{}")),
+	)
+	// Changes are applied when their transaction commits, and the
+	// confirmed position only moves between transactions.
+	assert.Empty(t, written)
+	assert.Equal(t, lsn(0x100), c.state.ConfirmedLSN)
+	assert.Equal(t, uint64(0x80), c.lag())
+	handle(xlogData(0x200, commitMessage(0x200, commitTime)))
+	assert.Equal(t, [][]*sp.Mutation{{
+		sp.InsertOrUpdate("product", cols, []interface{}{int64(1), "apple", []sp.NullString{{StringVal: "red", Valid: true}, {StringVal: "green", Valid: true}}}),
+		sp.InsertOrUpdate("product", cols, []interface{}{int64(2), nil, []sp.NullString{}}),
+	}}, written)
+	assert.Equal(t, lsn(0x200), c.state.ConfirmedLSN)
+	assert.Equal(t, commitTime, c.lastCommit)
+	assert.Equal(t, uint64(0), c.lag())
+
+	// An update of the key deletes the old row, and unchanged TOASTed
+	// values aren't written.
+	written = nil
+	handle(
+		xlogData(0x300, beginMessage(701, commitTime)),
+		xlogData(0x300, updateMessage(1, []byte{'K'}, tupleData("1", nil, nil), []byte{'N'}, tupleData("3", "apple", unchangedToast{}))),
+		xlogData(0x300, updateMessage(1, []byte{'N'}, tupleData("2", "pear", nil))),
+		xlogData(0x300, deleteMessage(1, "3", nil, nil)),
+		xlogData(0x300, commitMessage(0x300, commitTime)),
+	)
+	assert.Equal(t, [][]*sp.Mutation{{
+		sp.Delete("product", sp.Key{int64(1)}),
+		sp.InsertOrUpdate("product", []string{"id", "name"}, []interface{}{int64(3), "apple"}),
+		sp.InsertOrUpdate("product", cols, []interface{}{int64(2), "pear", nil}),
+		sp.Delete("product", sp.Key{int64(3)}),
+	}}, written)
+
+	// Bad data is reported, failed writes are retried one at a time and
+	// truncates delete all rows.
+	written = nil
+	handle(
+		xlogData(0x400, beginMessage(702, commitTime)),
+		xlogData(0x400, insertMessage(1, "x", "bad id", "{}")),
+		xlogData(0x400, insertMessage(1, "4", "bad", "{}")),
+		xlogData(0x400, insertMessage(1, "5", "good", "{}")),
+		xlogData(0x400, truncateMessage(1, 2)),
+		xlogData(0x400, commitMessage(0x400, commitTime)),
+	)
+	assert.Equal(t, [][]*sp.Mutation{
+		{sp.InsertOrUpdate("product", cols, []interface{}{int64(5), "good", []sp.NullString{}})},
+		{sp.Delete("product", sp.AllKeys())},
+	}, written)
+	assert.Equal(t, map[string]map[string]int64{"product": {recordInsert: 5, recordUpdate: 2, recordDelete: 1, recordTruncate: 1}}, info.Records)
+	assert.Equal(t, map[string]map[string]int64{"product": {recordInsert: 1}}, info.BadRecords)
+	assert.Equal(t, map[string]map[string]int64{"product": {recordInsert: 1}}, info.DroppedRecords)
+	assert.Equal(t, int64(9), info.Processed())
+	assert.Equal(t, []string{"type=INSERT table=product cols=[id] data=[x]"}, info.SampleBadRecords)
+
+	// Keepalives between transactions confirm the WAL they cover, but not
+	// while a transaction is being received.
+	handle(xlogData(0x500, beginMessage(703, commitTime)), keepalive(0x550, false))
+	assert.Equal(t, lsn(0x400), c.state.ConfirmedLSN)
+	assert.Equal(t, uint64(0x150), c.lag())
+	handle(xlogData(0x500, commitMessage(0x500, commitTime)), keepalive(0x600, true))
+	assert.Equal(t, lsn(0x600), c.state.ConfirmedLSN)
+	assert.True(t, c.replyRequested)
+
+	assert.NotNil(t, c.handleCopyData([]byte{'x'}))
+	assert.NotNil(t, c.handleCopyData([]byte{'w', 0}))
+}
+
+func TestReplicationCDC_MessageErrors(t *testing.T) {
+	c := newReplicationCDC(buildProductConv(), common.MakeStreamingInfo(), "", replicationState{})
+	assert.Nil(t, c.handleCopyData(xlogData(0x100, relationMessage(1, "public", "t", "id", "v"))))
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"empty", nil, "empty replication message"},
+		{"unknown message", []byte{'x'}, `unknown replication message type 'x'`},
+		{"bad keepalive", []byte{'k', 0}, "PrimaryKeepaliveMessage must be 17 bytes, got 1"},
+		{"unknown relation", xlogData(0x100, insertMessage(2, "1", "a")), "change to unknown relation 2"},
+		{"unknown truncated relation", xlogData(0x100, truncateMessage(1, 3)), "change to unknown relation 3"},
+		{"wrong column count", xlogData(0x100, insertMessage(1, "1")), "tuple has 1 columns, relation t has 2"},
+	}
+	for _, tc := range tests {
+		assert.EqualError(t, c.handleCopyData(tc.data), tc.want, tc.name)
+	}
+	// Values are only requested in text format.
+	assert.Nil(t, c.handleCopyData(xlogData(0x100, relationMessage(3, "public", "product", "id", "name", "tags"))))
+	_, _, _, _, _, err := c.convertRow("t1", c.relations[3], &pglogrepl.TupleData{Columns: []*pglogrepl.TupleDataColumn{
+		{DataType: pglogrepl.TupleDataTypeBinary, Data: []byte{1}}, {DataType: pglogrepl.TupleDataTypeNull}, {DataType: pglogrepl.TupleDataTypeNull},
+	}}, false)
+	assert.EqualError(t, err, "column id of table product isn't in text format")
+}
+
+func TestReplicationCDC_Partitions(t *testing.T) {
+	conv := buildProductConv()
+	srcTable := conv.SrcSchema["t1"]
+	srcTable.PartitionBy = "LIST (name)"
+	srcTable.Partitions = []string{"product_a", "sales.product_b"}
+	conv.SrcSchema["t1"] = srcTable
+	info := common.MakeStreamingInfo()
+	var written []*sp.Mutation
+	info.Write = func(ms []*sp.Mutation) error {
+		written = append(written, ms...)
+		return nil
+	}
+	c := newReplicationCDC(conv, info, filepath.Join(t.TempDir(), "state.json"), replicationState{})
+	for _, m := range [][]byte{
+		relationMessage(1, "public", "product_a", "id", "name", "tags"),
+		relationMessage(2, "sales", "product_b", "id", "name", "tags"),
+		beginMessage(1, time.Now()),
+		insertMessage(1, "1", "a", "{}"),
+		insertMessage(2, "2", "b", "{}"),
+		// Truncating a single partition can't be applied.
+		truncateMessage(1),
+		// Truncating all partitions truncates the table.
+		truncateMessage(1, 2),
+		commitMessage(0x100, time.Now()),
+	} {
+		assert.Nil(t, c.handleCopyData(xlogData(0x100, m)))
+	}
+	cols := []string{"id", "name", "tags"}
+	assert.Equal(t, []*sp.Mutation{
+		sp.InsertOrUpdate("product", cols, []interface{}{int64(1), "a", []sp.NullString{}}),
+		sp.InsertOrUpdate("product", cols, []interface{}{int64(2), "b", []sp.NullString{}}),
+		sp.Delete("product", sp.AllKeys()),
+	}, written)
+	assert.Equal(t, map[string]map[string]int64{"product": {recordInsert: 2, recordTruncate: 1}}, info.Records)
+	assert.Equal(t, map[string]int64{"Truncate of partitions of table product not applied to Spanner": 1}, info.Unexpecteds)
+}
+
+func TestReplicationCDC_SyntheticPrimaryKey(t *testing.T) {
+	conv := buildProductConv()
+	conv.SyntheticPKeys["t1"] = internal.SyntheticPKey{ColId: "c4"}
+	info := common.MakeStreamingInfo()
+	info.Write = func(ms []*sp.Mutation) error {
+		t.Errorf("unexpected write %v", ms)
+		return nil
+	}
+	c := newReplicationCDC(conv, info, filepath.Join(t.TempDir(), "state.json"), replicationState{})
+	for _, m := range [][]byte{
+		relationMessage(1, "public", "product", "id", "name", "tags"),
+		beginMessage(1, time.Now()),
+		insertMessage(1, "1", "a", "{}"),
+		commitMessage(0x100, time.Now()),
+	} {
+		assert.Nil(t, c.handleCopyData(xlogData(0x100, m)))
+	}
+	assert.Equal(t, map[string]int64{"Changes to table product not applied to Spanner: table has no primary key": 1}, info.Unexpecteds)
+}
+
+// fakeReplicationReader returns a fixed list of messages, and records the
+// standby status updates sent.
+type fakeReplicationReader struct {
+	msgs     [][]byte
+	statuses []lsn
+	// done is called when all messages have been read.
+	done func()
+}
+
+func (r *fakeReplicationReader) readCopyData() ([]byte, error) {
+	if len(r.msgs) == 0 {
+		r.done()
+		return nil, io.EOF
+	}
+	m := r.msgs[0]
+	r.msgs = r.msgs[1:]
+	return m, nil
+}
+
+func (r *fakeReplicationReader) sendStandbyStatus(pos lsn) error {
+	r.statuses = append(r.statuses, pos)
+	return nil
+}
+
+func (r *fakeReplicationReader) Close() error {
+	return nil
+}
+
+func TestReplicationCDC_Stream(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	start := replicationState{Slot: "hb", Publication: "pub", ConfirmedLSN: 0x100, SnapshotDone: true}
+	info := common.MakeStreamingInfo()
+	var written []*sp.Mutation
+	info.Write = func(ms []*sp.Mutation) error {
+		written = append(written, ms...)
+		return nil
+	}
+	c := newReplicationCDC(buildProductConv(), info, stateFile, start)
+	var dialed []lsn
+	var r *fakeReplicationReader
+	err := c.stream(func(pos lsn) (replicationReader, error) {
+		dialed = append(dialed, pos)
+		r = &fakeReplicationReader{msgs: [][]byte{
+			xlogData(0x200, relationMessage(1, "public", "product", "id", "name", "tags")),
+			xlogData(0x200, beginMessage(1, time.Now())),
+			xlogData(0x200, insertMessage(1, "7", "abc", "{x}")),
+			xlogData(0x200, commitMessage(0x200, time.Now())),
+			keepalive(0x250, true),
+		}, done: info.Exit}
+		return r, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []lsn{0x100}, dialed)
+	assert.Equal(t, []*sp.Mutation{sp.InsertOrUpdate("product", []string{"id", "name", "tags"}, []interface{}{int64(7), "abc", []sp.NullString{{StringVal: "x", Valid: true}}})}, written)
+	// The first message triggers a status update, and the keepalive
+	// requests one.
+	assert.Equal(t, []lsn{0x100, 0x250}, r.statuses)
+	state, ok, err := loadReplicationState(stateFile)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, replicationState{Slot: "hb", Publication: "pub", ConfirmedLSN: 0x250, SnapshotDone: true}, state)
+}
+
+func TestReplicationState(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	_, ok, err := loadReplicationState(stateFile)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	state := replicationState{Slot: "hb", Publication: "pub", ConfirmedLSN: 0x16B3748}
+	assert.Nil(t, saveReplicationState(stateFile, state))
+	got, ok, err := loadReplicationState(stateFile)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, state, got)
+
+	assert.Nil(t, saveReplicationState(stateFile, replicationState{}))
+	_, _, err = loadReplicationState(stateFile)
+	assert.NotNil(t, err)
+}
+
+func TestCreatePublication(t *testing.T) {
+	conv := buildProductConv()
+	conv.SrcSchema["t2"] = schema.Table{Name: "sales.orders", Schema: "sales", Id: "t2"}
+	conv.SpSchema["t2"] = ddl.CreateTable{Name: "orders", Id: "t2"}
+	conv.SrcSchema["t3"] = schema.Table{Name: "log", Schema: "public", Id: "t3"}
+	conv.SpSchema["t3"] = ddl.CreateTable{Name: "log", Id: "t3"}
+	conv.SyntheticPKeys["t3"] = internal.SyntheticPKey{ColId: "c9"}
+	conv.SrcSchema["t4"] = schema.Table{Name: "dropped", Schema: "public", Id: "t4"}
+	// Partitioned tables are published through their partitions.
+	conv.SrcSchema["t5"] = schema.Table{Name: "measurement", Schema: "public", Id: "t5", PartitionBy: "RANGE (logdate)", Partitions: []string{"measurement_y2022", "archive.measurement_y2021"}}
+	conv.SpSchema["t5"] = ddl.CreateTable{Name: "measurement", Id: "t5"}
+
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM pg_publication WHERE pubname = \\$1").WithArgs("pub").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(`CREATE PUBLICATION "pub" FOR TABLE "archive"."measurement_y2021", "public"."measurement_y2022", "public"."product", "sales"."orders"`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM pg_publication WHERE pubname = \\$1").WithArgs("pub").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	assert.Nil(t, createPublication(db, conv, "pub"))
+	// An existing publication is used as is.
+	assert.Nil(t, createPublication(db, conv, "pub"))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestReadSlot(t *testing.T) {
+	q := "SELECT plugin, confirmed_flush_lsn FROM pg_replication_slots"
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	mock.ExpectQuery(q).WithArgs("hb").WillReturnRows(sqlmock.NewRows([]string{"plugin", "confirmed_flush_lsn"}).AddRow("pgoutput", "0/16B3748"))
+	mock.ExpectQuery(q).WithArgs("missing").WillReturnRows(sqlmock.NewRows([]string{"plugin", "confirmed_flush_lsn"}))
+	mock.ExpectQuery(q).WithArgs("other").WillReturnRows(sqlmock.NewRows([]string{"plugin", "confirmed_flush_lsn"}).AddRow("wal2json", "0/1"))
+
+	pos, ok, err := readSlot(db, "hb")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, lsn(0x16B3748), pos)
+	_, ok, err = readSlot(db, "missing")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	_, _, err = readSlot(db, "other")
+	assert.EqualError(t, err, `replication slot other uses plugin "wal2json", pgoutput is required`)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestProcessDataInSnapshot(t *testing.T) {
+	conv := buildProductConv()
+	conv.SetDataMode()
+	var rows []spannerData
+	conv.SetDataSink(
+		func(table string, cols []string, vals []interface{}) {
+			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
+		})
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	mock.ExpectBegin()
+	mock.ExpectExec("SET TRANSACTION SNAPSHOT '00000003-00000002-1'").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT \* FROM "public"."product"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "tags"}).AddRow(int64(1), "apple", []byte("{red}")))
+	mock.ExpectRollback()
+
+	common.ProcessData(context.Background(), conv, InfoSchemaImpl{Db: db, Snapshot: "00000003-00000002-1"})
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, []spannerData{{table: "product", cols: []string{"id", "name", "tags"}, vals: []interface{}{int64(1), "apple", []sp.NullString{{StringVal: "red", Valid: true}}}}}, rows)
+}
+
+func TestReplicationConvertValues(t *testing.T) {
+	colIds := []string{"c1", "c2", "c3", "c4", "c5"}
+	conv := buildConv(
+		ddl.CreateTable{
+			Name:   "t",
+			Id:     "t1",
+			ColIds: colIds,
+			ColDefs: map[string]ddl.ColumnDef{
+				"c1": {Name: "id", T: ddl.Type{Name: ddl.Int64}},
+				"c2": {Name: "ts", T: ddl.Type{Name: ddl.Timestamp}},
+				"c3": {Name: "b", T: ddl.Type{Name: ddl.Bytes}},
+				"c4": {Name: "ok", T: ddl.Type{Name: ddl.Bool}},
+				"c5": {Name: "s", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			},
+			PrimaryKeys: []ddl.IndexKey{{ColId: "c1"}},
+		},
+		schema.Table{
+			Name:   "t",
+			Schema: "public",
+			Id:     "t1",
+			ColIds: colIds,
+			ColDefs: map[string]schema.Column{
+				"c1": {Name: "id", Type: schema.Type{Name: "int8"}},
+				"c2": {Name: "ts", Type: schema.Type{Name: "timestamptz"}},
+				"c3": {Name: "b", Type: schema.Type{Name: "bytea"}},
+				"c4": {Name: "ok", Type: schema.Type{Name: "bool"}},
+				"c5": {Name: "s", Type: schema.Type{Name: "text"}},
+			},
+			PrimaryKeys: []schema.Key{{ColId: "c1"}},
+		})
+	conv.SpDialect = constants.DIALECT_GOOGLESQL
+	c := newReplicationCDC(conv, common.MakeStreamingInfo(), "", replicationState{})
+	rel := &pglogrepl.RelationMessage{Namespace: "public", RelationName: "t"}
+	row := &pglogrepl.TupleData{}
+	for i, v := range []string{"1", "2023-05-06 07:08:09.5+00", `\x0102`, "t", "NULL"} {
+		rel.Columns = append(rel.Columns, &pglogrepl.RelationMessageColumn{Name: []string{"id", "ts", "b", "ok", "s"}[i]})
+		row.Columns = append(row.Columns, &pglogrepl.TupleDataColumn{DataType: pglogrepl.TupleDataTypeText, Data: []byte(v)})
+	}
+	spTable, cols, vals, _, _, err := c.convertRow("t1", rel, row, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "t", spTable)
+	assert.Equal(t, []string{"id", "ts", "b", "ok", "s"}, cols)
+	assert.True(t, time.Date(2023, 5, 6, 7, 8, 9, 500000000, time.UTC).Equal(vals[1].(time.Time)))
+	// The string "NULL" is a value, unlike in pg_dump output.
+	assert.Equal(t, []interface{}{int64(1), []byte{1, 2}, true, "NULL"}, append(vals[:1:1], vals[2:]...))
+	_, _, key, _, _, err := c.convertRow("t1", rel, row, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{int64(1)}, key)
+	row.Columns[0] = &pglogrepl.TupleDataColumn{DataType: pglogrepl.TupleDataTypeNull}
+	_, _, _, _, _, err = c.convertRow("t1", rel, row, true)
+	assert.EqualError(t, err, "key column id of table t is NULL")
+}
+
+func TestLSN(t *testing.T) {
+	l, err := parseLSN("16/B374D848")
+	assert.Nil(t, err)
+	assert.Equal(t, lsn(0x16B374D848), l)
+	assert.Equal(t, "16/B374D848", l.String())
+	_, err = parseLSN("16B374D848")
+	assert.NotNil(t, err)
+
+	data, err := json.Marshal(struct{ Pos lsn }{l})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"Pos":"16/B374D848"}`, string(data))
+	var v struct{ Pos lsn }
+	assert.Nil(t, json.Unmarshal(data, &v))
+	assert.Equal(t, l, v.Pos)
+}
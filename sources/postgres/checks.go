@@ -0,0 +1,291 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v2"
+
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+)
+
+// deparseExpr converts the expression e back into PostgreSQL syntax.
+func deparseExpr(e *pg_query.Node) (string, error) {
+	// Expressions are deparsed as the target of a SELECT statement.
+	sel := &pg_query.SelectStmt{TargetList: []*pg_query.Node{pg_query.MakeResTargetNodeWithVal(e, 0)}}
+	q, err := pg_query.Deparse(&pg_query.ParseResult{Stmts: []*pg_query.RawStmt{{Stmt: &pg_query.Node{Node: &pg_query.Node_SelectStmt{SelectStmt: sel}}}}})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(q, "SELECT "), nil
+}
+
+// parseExpr parses the PostgreSQL expression e.
+func parseExpr(e string) (*pg_query.Node, error) {
+	tree, err := pg_query.Parse("SELECT " + e)
+	if err != nil {
+		return nil, err
+	}
+	if len(tree.Stmts) != 1 {
+		return nil, fmt.Errorf("expected a single expression")
+	}
+	sel := tree.Stmts[0].Stmt.GetSelectStmt()
+	if sel == nil || len(sel.TargetList) != 1 || sel.TargetList[0].GetResTarget() == nil {
+		return nil, fmt.Errorf("expected a single expression")
+	}
+	return sel.TargetList[0].GetResTarget().Val, nil
+}
+
+// translateCheck translates the PostgreSQL CHECK constraint expression e of
+// a domain into a check expression for Spanner (see ddl.ColumnDef.Checks).
+// Both refer to the checked value as VALUE. Only comparisons, IN, LIKE,
+// BETWEEN, NULL tests, boolean operators and a few string functions over
+// constants are supported; an error is returned for anything else.
+func translateCheck(e string, pg bool) (string, error) {
+	n, err := parseExpr(e)
+	if err != nil {
+		return "", err
+	}
+	return checkTranslator{pg: pg}.expr(n)
+}
+
+type checkTranslator struct {
+	pg bool // Translate into the PostgreSQL dialect of Spanner.
+}
+
+var checkOps = map[string]string{
+	"=": "=", "<>": "<>", "!=": "<>", "<": "<", "<=": "<=", ">": ">", ">=": ">=",
+	"+": "+", "-": "-", "*": "*", "/": "/", "||": "||",
+	// pg_get_constraintdef prints LIKE as the ~~ operator.
+	"~~": "LIKE", "!~~": "NOT LIKE",
+}
+
+var checkFuncs = map[string]bool{"length": true, "char_length": true, "lower": true, "upper": true}
+
+func (t checkTranslator) expr(n *pg_query.Node) (string, error) {
+	switch x := n.GetNode().(type) {
+	case *pg_query.Node_AExpr:
+		return t.aExpr(x.AExpr)
+	case *pg_query.Node_BoolExpr:
+		var args []string
+		for _, a := range x.BoolExpr.Args {
+			s, err := t.operand(a)
+			if err != nil {
+				return "", err
+			}
+			args = append(args, s)
+		}
+		switch x.BoolExpr.Boolop {
+		case pg_query.BoolExprType_AND_EXPR:
+			return strings.Join(args, " AND "), nil
+		case pg_query.BoolExprType_OR_EXPR:
+			return strings.Join(args, " OR "), nil
+		case pg_query.BoolExprType_NOT_EXPR:
+			return "NOT " + args[0], nil
+		}
+	case *pg_query.Node_NullTest:
+		s, err := t.operand(x.NullTest.Arg)
+		if err != nil {
+			return "", err
+		}
+		if x.NullTest.Nulltesttype == pg_query.NullTestType_IS_NOT_NULL {
+			return s + " IS NOT NULL", nil
+		}
+		return s + " IS NULL", nil
+	case *pg_query.Node_AConst:
+		return t.constant(x.AConst.Val)
+	case *pg_query.Node_TypeCast:
+		return t.typeCast(x.TypeCast)
+	case *pg_query.Node_ColumnRef:
+		if len(x.ColumnRef.Fields) == 1 {
+			if s, err := getString(x.ColumnRef.Fields[0]); err == nil && s == "value" {
+				return "VALUE", nil
+			}
+		}
+	case *pg_query.Node_FuncCall:
+		name, err := getTypeID(x.FuncCall.Funcname)
+		if err != nil || !checkFuncs[name] || len(x.FuncCall.Args) != 1 {
+			break
+		}
+		arg, err := t.expr(x.FuncCall.Args[0])
+		if err != nil {
+			return "", err
+		}
+		if !t.pg {
+			name = strings.ToUpper(name)
+		}
+		return fmt.Sprintf("%s(%s)", name, arg), nil
+	}
+	return "", fmt.Errorf("unsupported expression %s", printNodeType(n.GetNode()))
+}
+
+// operand translates n for use as an operand of another expression, adding
+// parentheses when needed.
+func (t checkTranslator) operand(n *pg_query.Node) (string, error) {
+	s, err := t.expr(n)
+	if err != nil {
+		return "", err
+	}
+	switch n.GetNode().(type) {
+	case *pg_query.Node_AExpr, *pg_query.Node_BoolExpr, *pg_query.Node_NullTest:
+		return "(" + s + ")", nil
+	}
+	return s, nil
+}
+
+func (t checkTranslator) aExpr(a *pg_query.A_Expr) (string, error) {
+	op, err := getTypeID(a.Name)
+	if err != nil {
+		return "", err
+	}
+	var l string
+	if a.Lexpr != nil {
+		if l, err = t.operand(a.Lexpr); err != nil {
+			return "", err
+		}
+	}
+	switch a.Kind {
+	case pg_query.A_Expr_Kind_AEXPR_OP, pg_query.A_Expr_Kind_AEXPR_LIKE:
+		spOp, ok := checkOps[op]
+		if !ok {
+			break
+		}
+		r, err := t.operand(a.Rexpr)
+		if err != nil {
+			return "", err
+		}
+		if a.Lexpr == nil {
+			return spOp + r, nil
+		}
+		return fmt.Sprintf("%s %s %s", l, spOp, r), nil
+	case pg_query.A_Expr_Kind_AEXPR_OP_ANY, pg_query.A_Expr_Kind_AEXPR_OP_ALL, pg_query.A_Expr_Kind_AEXPR_IN:
+		// x = ANY (ARRAY[...]) and x <> ALL (ARRAY[...]) are how
+		// pg_get_constraintdef prints IN and NOT IN.
+		var in bool
+		switch {
+		case a.Kind != pg_query.A_Expr_Kind_AEXPR_OP_ALL && op == "=":
+			in = true
+		case a.Kind != pg_query.A_Expr_Kind_AEXPR_OP_ANY && op == "<>":
+			in = false
+		default:
+			return "", fmt.Errorf("unsupported operator %s", op)
+		}
+		elems, err := t.list(a.Rexpr)
+		if err != nil {
+			return "", err
+		}
+		if in {
+			return fmt.Sprintf("%s IN (%s)", l, strings.Join(elems, ", ")), nil
+		}
+		return fmt.Sprintf("%s NOT IN (%s)", l, strings.Join(elems, ", ")), nil
+	case pg_query.A_Expr_Kind_AEXPR_BETWEEN, pg_query.A_Expr_Kind_AEXPR_NOT_BETWEEN:
+		bounds, err := t.list(a.Rexpr)
+		if err != nil {
+			return "", err
+		}
+		if len(bounds) != 2 {
+			break
+		}
+		if a.Kind == pg_query.A_Expr_Kind_AEXPR_NOT_BETWEEN {
+			return fmt.Sprintf("%s NOT BETWEEN %s AND %s", l, bounds[0], bounds[1]), nil
+		}
+		return fmt.Sprintf("%s BETWEEN %s AND %s", l, bounds[0], bounds[1]), nil
+	}
+	return "", fmt.Errorf("unsupported operator %s", op)
+}
+
+// list translates the elements of a list or an ARRAY[...] expression.
+func (t checkTranslator) list(n *pg_query.Node) ([]string, error) {
+	var nodes []*pg_query.Node
+	switch x := n.GetNode().(type) {
+	case *pg_query.Node_List:
+		nodes = x.List.Items
+	case *pg_query.Node_AArrayExpr:
+		nodes = x.AArrayExpr.Elements
+	case *pg_query.Node_TypeCast:
+		// pg_get_constraintdef casts arrays of varchar to text[].
+		if x.TypeCast.TypeName != nil && len(x.TypeCast.TypeName.ArrayBounds) > 0 {
+			return t.list(x.TypeCast.Arg)
+		}
+		return nil, fmt.Errorf("unsupported type cast")
+	default:
+		return nil, fmt.Errorf("unsupported list %s", printNodeType(n.GetNode()))
+	}
+	var l []string
+	for _, e := range nodes {
+		s, err := t.operand(e)
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, s)
+	}
+	return l, nil
+}
+
+func (t checkTranslator) constant(n *pg_query.Node) (string, error) {
+	switch x := n.GetNode().(type) {
+	case *pg_query.Node_String_:
+		return common.SpannerLiteral(x.String_.Str, t.pg), nil
+	case *pg_query.Node_Integer:
+		return strconv.Itoa(int(x.Integer.Ival)), nil
+	case *pg_query.Node_Float:
+		return x.Float.Str, nil
+	case *pg_query.Node_Null:
+		return "NULL", nil
+	}
+	return "", fmt.Errorf("unsupported constant %s", printNodeType(n.GetNode()))
+}
+
+// typeCast translates a type cast. Casts between types that map to the same
+// Spanner type are dropped, and casts of string constants to other types
+// are translated into typed literals.
+func (t checkTranslator) typeCast(c *pg_query.TypeCast) (string, error) {
+	if c.TypeName == nil || len(c.TypeName.ArrayBounds) > 0 {
+		return "", fmt.Errorf("unsupported type cast")
+	}
+	ty, err := getTypeID(c.TypeName.Names)
+	if err != nil {
+		return "", err
+	}
+	switch ty {
+	case "text", "varchar", "bpchar", "int2", "int4", "int8", "numeric", "float8":
+		return t.expr(c.Arg)
+	}
+	con := c.Arg.GetAConst()
+	if con == nil || con.Val.GetString_() == nil {
+		return "", fmt.Errorf("unsupported type cast to %s", ty)
+	}
+	s := con.Val.GetString_().Str
+	switch ty {
+	case "bool":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return strings.ToUpper(strconv.FormatBool(b)), nil
+		}
+	case "date":
+		if t.pg {
+			return common.SpannerLiteral(s, t.pg) + "::date", nil
+		}
+		return "DATE " + common.SpannerLiteral(s, t.pg), nil
+	case "timestamp", "timestamptz":
+		if t.pg {
+			return common.SpannerLiteral(s, t.pg) + "::timestamptz", nil
+		}
+		return "TIMESTAMP " + common.SpannerLiteral(s, t.pg), nil
+	}
+	return "", fmt.Errorf("unsupported type cast to %s", ty)
+}
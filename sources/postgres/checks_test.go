@@ -0,0 +1,62 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslateCheck(t *testing.T) {
+	tests := []struct {
+		in string
+		e  string // Expected GoogleSQL expression, empty if not supported.
+		pg string // Expected PostgreSQL expression, empty if not supported.
+	}{
+		{"(VALUE > 0)", "VALUE > 0", "VALUE > 0"},
+		{"((VALUE >= 0) AND (VALUE <= 100))", "(VALUE >= 0) AND (VALUE <= 100)", "(VALUE >= 0) AND (VALUE <= 100)"},
+		{"VALUE != 5 OR VALUE IS NULL", "(VALUE <> 5) OR (VALUE IS NULL)", "(VALUE <> 5) OR (VALUE IS NULL)"},
+		{"NOT (VALUE = -1)", "NOT (VALUE = -1)", "NOT (VALUE = -1)"},
+		{"VALUE BETWEEN 1 AND 10", "VALUE BETWEEN 1 AND 10", "VALUE BETWEEN 1 AND 10"},
+		{"VALUE IN ('a', 'it''s')", `VALUE IN ('a', 'it\'s')`, "VALUE IN ('a', 'it''s')"},
+		{"VALUE NOT IN (1, 2)", "VALUE NOT IN (1, 2)", "VALUE NOT IN (1, 2)"},
+		// pg_get_constraintdef output.
+		{"((VALUE)::text = ANY ((ARRAY['a'::character varying, 'b'::character varying])::text[]))", "VALUE IN ('a', 'b')", "VALUE IN ('a', 'b')"},
+		{"(VALUE <> ALL (ARRAY[1, 2]))", "VALUE NOT IN (1, 2)", "VALUE NOT IN (1, 2)"},
+		{"((VALUE)::text ~~ 'A%'::text)", "VALUE LIKE 'A%'", "VALUE LIKE 'A%'"},
+		{"(char_length((VALUE)::text) <= 10)", "CHAR_LENGTH(VALUE) <= 10", "char_length(VALUE) <= 10"},
+		{"(VALUE > '2000-01-01'::date)", "VALUE > DATE '2000-01-01'", "VALUE > '2000-01-01'::date"},
+		{"(VALUE = 'true'::boolean)", "VALUE = TRUE", "VALUE = TRUE"},
+		{"(VALUE ~ '^[a-z]+$'::text)", "", ""},
+		{"(VALUE > now())", "", ""},
+		{"(other > 0)", "", ""},
+	}
+	for _, tc := range tests {
+		for _, pg := range []bool{false, true} {
+			e := tc.e
+			if pg {
+				e = tc.pg
+			}
+			s, err := translateCheck(tc.in, pg)
+			if e == "" {
+				assert.NotNil(t, err, tc.in)
+				continue
+			}
+			assert.Nil(t, err, tc.in)
+			assert.Equal(t, e, s, tc.in)
+		}
+	}
+}
@@ -16,6 +16,7 @@ package postgres
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"math/bits"
@@ -28,6 +29,7 @@ import (
 	"cloud.google.com/go/spanner"
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
 )
 
@@ -46,9 +48,9 @@ func ProcessDataRow(conv *internal.Conv, tableId string, colIds, vals []string)
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
 		conv.StatsAddBadRow(srcTableName, conv.DataMode())
-		conv.CollectBadRow(srcTableName, srcCols, vals)
+		conv.CollectBadRow(srcTableName, srcCols, vals, err)
 	} else {
-		conv.WriteRow(srcTableName, spTableName, spCols, spVals)
+		conv.WriteSourceRow(internal.SourceRow{Table: srcTableName, Columns: srcCols, Values: vals}, spTableName, spCols, spVals)
 	}
 }
 
@@ -84,13 +86,7 @@ func ConvertData(conv *internal.Conv, tableId string, colIds []string, vals []st
 		if !ok1 || !ok2 {
 			return "", []string{}, []interface{}{}, fmt.Errorf("can't find Spanner and source-db schema for colId %s", colId)
 		}
-		var x interface{}
-		var err error
-		if spColDef.T.IsArray {
-			x, err = convArray(spColDef.T, srcColDef.Type.Name, conv.Location, vals[i])
-		} else {
-			x, err = convScalar(conv, spColDef.T, srcColDef.Type.Name, conv.Location, vals[i])
-		}
+		x, err := convValue(conv, srcColDef, spColDef, vals[i])
 		if err != nil {
 			return "", []string{}, []interface{}{}, err
 		}
@@ -106,6 +102,19 @@ func ConvertData(conv *internal.Conv, tableId string, colIds []string, vals []st
 	return spSchema.Name, c, v, nil
 }
 
+// convValue converts a source database string value of column srcCd to
+// a value of Spanner column spCd.
+func convValue(conv *internal.Conv, srcCd schema.Column, spCd ddl.ColumnDef, val string) (interface{}, error) {
+	switch {
+	case len(srcCd.Type.Fields) > 0:
+		return convComposite(srcCd.Type, val)
+	case spCd.T.IsArray:
+		return convArray(spCd.T, srcCd.Type.Name, conv.Location, val)
+	default:
+		return convScalar(conv, spCd.T, srcCd.Type.Name, conv.Location, val)
+	}
+}
+
 // convScalar converts a source database string value to an
 // appropriate Spanner value. It is the caller's responsibility to
 // detect and handle NULL values: convScalar will return error if a
@@ -400,3 +409,148 @@ func processQuote(s string) (string, error) {
 	}
 	return s, nil
 }
+
+// convComposite converts a value of a composite type (or an array of
+// composite values) to JSON. Composite values are converted to JSON
+// objects whose keys are the field names of the type, and arrays to JSON
+// arrays. For example, '(1,"a b")' is converted to '{"id":1,"name":"a b"}'.
+func convComposite(ty schema.Type, v string) (string, error) {
+	if len(ty.ArrayBounds) > 0 {
+		elems, err := splitPgList(v, '{', '}')
+		if err != nil {
+			return "", err
+		}
+		elemTy := schema.Type{Name: ty.Name, Fields: ty.Fields, ArrayBounds: ty.ArrayBounds[1:]}
+		l := []string{}
+		for _, e := range elems {
+			if e == nil {
+				l = append(l, "null")
+				continue
+			}
+			j, err := convComposite(elemTy, *e)
+			if err != nil {
+				return "", err
+			}
+			l = append(l, j)
+		}
+		return "[" + strings.Join(l, ",") + "]", nil
+	}
+	vals, err := splitPgList(v, '(', ')')
+	if err != nil {
+		return "", err
+	}
+	if len(vals) != len(ty.Fields) {
+		return "", fmt.Errorf("expected %d fields for type %s, found %d", len(ty.Fields), ty.Name, len(vals))
+	}
+	var l []string
+	for i, f := range ty.Fields {
+		name, err := json.Marshal(f.Name)
+		if err != nil {
+			return "", err
+		}
+		j := "null"
+		if vals[i] != nil {
+			if j, err = convCompositeField(f.Type, *vals[i]); err != nil {
+				return "", err
+			}
+		}
+		l = append(l, string(name)+":"+j)
+	}
+	return "{" + strings.Join(l, ",") + "}", nil
+}
+
+// convCompositeField converts the value of a field of a composite type to
+// JSON. Numbers, booleans and JSON values keep their type, nested
+// composite values become objects and everything else becomes a string.
+func convCompositeField(ty schema.Type, v string) (string, error) {
+	if len(ty.Fields) > 0 {
+		return convComposite(ty, v)
+	}
+	if len(ty.ArrayBounds) == 0 {
+		switch ty.Name {
+		case "int2", "smallint", "int4", "integer", "int8", "bigint", "float4", "real", "float8", "double precision", "numeric":
+			// NaN and Infinity are not valid JSON numbers, and are kept as strings.
+			if json.Valid([]byte(v)) {
+				return v, nil
+			}
+		case "bool", "boolean":
+			if b, err := convBool(v); err == nil {
+				return strconv.FormatBool(b), nil
+			}
+		case "json", "jsonb":
+			if json.Valid([]byte(v)) {
+				return v, nil
+			}
+		}
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// splitPgList splits the text representation of a PostgreSQL array
+// ('{a,b}') or composite value ('(a,b)') into its elements, removing
+// quotes and escapes. NULL elements are returned as nil: these are
+// written as unquoted NULL in arrays and as empty fields in composite
+// values. Nested arrays are returned as is.
+func splitPgList(v string, open, close byte) ([]*string, error) {
+	v = strings.TrimSpace(v)
+	if len(v) < 2 || v[0] != open || v[len(v)-1] != close {
+		return nil, fmt.Errorf("unrecognized data format: expected %c...%c", open, close)
+	}
+	v = v[1 : len(v)-1]
+	if open == '{' && v == "" {
+		return nil, nil
+	}
+	var l []*string
+	var b strings.Builder
+	quoted, inQuotes, depth := false, false, 0
+	add := func() {
+		s := b.String()
+		if !quoted && (open == '{' && s == "NULL" || open == '(' && s == "") {
+			l = append(l, nil)
+		} else {
+			l = append(l, &s)
+		}
+		b.Reset()
+		quoted = false
+	}
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case depth > 0:
+			// Inside a nested array: copy it as is, for a later call
+			// of splitPgList.
+			b.WriteByte(c)
+			switch {
+			case c == '\\' && i+1 < len(v):
+				i++
+				b.WriteByte(v[i])
+			case c == '"':
+				inQuotes = !inQuotes
+			case c == '{' && !inQuotes:
+				depth++
+			case c == '}' && !inQuotes:
+				depth--
+			}
+		case c == '\\' && i+1 < len(v):
+			i++
+			b.WriteByte(v[i])
+		case c == '"' && inQuotes && open == '(' && i+1 < len(v) && v[i+1] == '"':
+			// Composite values escape quotes by doubling them.
+			i++
+			b.WriteByte(c)
+		case c == '"':
+			inQuotes = !inQuotes
+			quoted = true
+		case c == '{' && !inQuotes && open == '{':
+			depth++
+			b.WriteByte(c)
+		case c == ',' && !inQuotes:
+			add()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	add()
+	return l, nil
+}
@@ -300,6 +300,43 @@ func TestConvertData(t *testing.T) {
 	}
 }
 
+func TestConvComposite(t *testing.T) {
+	point := schema.Type{Name: "point2", Fields: []schema.Column{
+		{Name: "x", Type: schema.Type{Name: "integer"}},
+		{Name: "y", Type: schema.Type{Name: "double precision"}}}}
+	ty := schema.Type{Name: "item", Fields: []schema.Column{
+		{Name: "name", Type: schema.Type{Name: "text"}},
+		{Name: "ok", Type: schema.Type{Name: "boolean"}},
+		{Name: "n", Type: schema.Type{Name: "numeric"}},
+		{Name: "doc", Type: schema.Type{Name: "jsonb"}},
+		{Name: "tags", Type: schema.Type{Name: "text", ArrayBounds: []int64{-1}}},
+		{Name: "p", Type: point}}}
+	tests := []struct {
+		name string
+		ty   schema.Type
+		in   string
+		e    string // Expected JSON, empty if conversion fails.
+	}{
+		{"basic", ty, `(abc,t,1.5,"{""a"": 1}",{x},"(1,2.5)")`, `{"name":"abc","ok":true,"n":1.5,"doc":{"a": 1},"tags":"{x}","p":{"x":1,"y":2.5}}`},
+		{"quotes and escapes", ty, `("a ""b"" \\c",f,NaN,,"{""x y"",z}",)`, `{"name":"a \"b\" \\c","ok":false,"n":"NaN","doc":null,"tags":"{\"x y\",z}","p":null}`},
+		{"empty string", ty, `("",,,,,)`, `{"name":"","ok":null,"n":null,"doc":null,"tags":null,"p":null}`},
+		{"array", schema.Type{Name: "point2", Fields: point.Fields, ArrayBounds: []int64{-1}}, `{"(1,2)",NULL,"(3,)"}`, `[{"x":1,"y":2},null,{"x":3,"y":null}]`},
+		{"empty array", schema.Type{Name: "point2", Fields: point.Fields, ArrayBounds: []int64{-1}}, `{}`, `[]`},
+		{"nested array", schema.Type{Name: "point2", Fields: point.Fields, ArrayBounds: []int64{-1, -1}}, `{{"(1,2)"},{"(3,4)"}}`, `[[{"x":1,"y":2}],[{"x":3,"y":4}]]`},
+		{"wrong number of fields", point, `(1,2,3)`, ""},
+		{"bad format", point, `1,2`, ""},
+	}
+	for _, tc := range tests {
+		j, err := convComposite(tc.ty, tc.in)
+		if tc.e == "" {
+			assert.NotNil(t, err, tc.name)
+			continue
+		}
+		assert.Nil(t, err, tc.name)
+		assert.Equal(t, tc.e, j, tc.name)
+	}
+}
+
 func buildConv(spTable ddl.CreateTable, srcTable schema.Table) *internal.Conv {
 	conv := internal.MakeConv()
 	conv.SpSchema[spTable.Id] = spTable
@@ -22,6 +22,7 @@ import (
 	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/civil"
@@ -41,11 +42,17 @@ type InfoSchemaImpl struct {
 	Db            *sql.DB
 	SourceProfile profiles.SourceProfile
 	TargetProfile profiles.TargetProfile
+	// Snapshot is the name of an exported snapshot that ProcessData reads
+	// the data in, if set.
+	Snapshot string
 }
 
 // StartChangeDataCapture is used for automatic triggering of Datastream job when
 // performing a streaming migration.
 func (isi InfoSchemaImpl) StartChangeDataCapture(ctx context.Context, conv *internal.Conv) (map[string]interface{}, error) {
+	if isi.SourceProfile.Conn.Pg.Cdc == profiles.PgoutputCdc {
+		return isi.startLogicalReplication(conv)
+	}
 	mp := make(map[string]interface{})
 	streamingCfg, err := streaming.StartDatastream(ctx, isi.SourceProfile, isi.TargetProfile)
 	if err != nil {
@@ -59,6 +66,9 @@ func (isi InfoSchemaImpl) StartChangeDataCapture(ctx context.Context, conv *inte
 // StartStreamingMigration is used for automatic triggering of Dataflow job when
 // performing a streaming migration.
 func (isi InfoSchemaImpl) StartStreamingMigration(ctx context.Context, client *sp.Client, conv *internal.Conv, streamingInfo map[string]interface{}) error {
+	if isi.SourceProfile.Conn.Pg.Cdc == profiles.PgoutputCdc {
+		return isi.streamReplication(client, conv, streamingInfo)
+	}
 	streamingCfg, _ := streamingInfo["streamingCfg"].(streaming.StreamingCfg)
 
 	err := streaming.StartDataflow(ctx, isi.SourceProfile, isi.TargetProfile, streamingCfg, conv)
@@ -84,17 +94,34 @@ func (isi InfoSchemaImpl) GetTableName(schema string, tableName string) string {
 
 // GetRowsFromTable returns a sql Rows object for a table.
 func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, tableId string) (interface{}, error) {
+	return isi.getRowsFromTable(context.Background(), conv, tableId)
+}
+
+// getRowsFromTable is like GetRowsFromTable, but the query is cancelled
+// once ctx is done.
+func (isi InfoSchemaImpl) getRowsFromTable(ctx context.Context, conv *internal.Conv, tableId string) (interface{}, error) {
 	// PostgreSQL schema and name can be arbitrary strings.
 	// Ideally we would pass schema/name as a query parameter,
 	// but PostgreSQL doesn't support this. So we quote it instead.
 	q := fmt.Sprintf(`SELECT * FROM "%s"."%s";`, conv.SrcSchema[tableId].Schema, conv.SrcSchema[tableId].Name)
-	rows, err := isi.Db.Query(q)
+	rows, err := isi.Db.QueryContext(ctx, q)
 	if err != nil {
 		return nil, err
 	}
 	return rows, err
 }
 
+// GetSampleRows implements the common.SamplingInfoSchema interface.
+func (isi InfoSchemaImpl) GetSampleRows(conv *internal.Conv, tableId string, colIds []string, n int64) (*sql.Rows, error) {
+	tbl := conv.SrcSchema[tableId]
+	var cols []string
+	for _, colId := range colIds {
+		cols = append(cols, quoteIdent(tbl.ColDefs[colId].Name))
+	}
+	q := fmt.Sprintf(`SELECT %s FROM %s.%s LIMIT %d;`, strings.Join(cols, ", "), quoteIdent(tbl.Schema), quoteIdent(tbl.Name), n)
+	return isi.Db.Query(q)
+}
+
 // ProcessDataRows performs data conversion for source database
 // 'db'. For each table, we extract data using a "SELECT *" query,
 // convert the data to Spanner data (based on the source and Spanner
@@ -114,9 +141,21 @@ func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, tableId string)
 // We choose to do all type conversions explicitly ourselves so that
 // we can generate more targeted error messages: hence we pass
 // *interface{} parameters to row.Scan.
-func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, tableId string, srcSchema schema.Table, colIds []string, spSchema ddl.CreateTable) error {
+func (isi InfoSchemaImpl) ProcessData(ctx context.Context, conv *internal.Conv, tableId string, srcSchema schema.Table, colIds []string, spSchema ddl.CreateTable) error {
 	srcTableName := conv.SrcSchema[tableId].Name
-	rowsInterface, err := isi.GetRowsFromTable(conv, tableId)
+	var rowsInterface interface{}
+	var err error
+	if isi.Snapshot != "" {
+		// Read inside the snapshot exported by the replication slot, so
+		// that the change stream starts exactly where the data read ends.
+		var tx *sql.Tx
+		rowsInterface, tx, err = isi.getRowsInSnapshot(ctx, conv, tableId)
+		if tx != nil {
+			defer tx.Rollback()
+		}
+	} else {
+		rowsInterface, err = isi.getRowsFromTable(ctx, conv, tableId)
+	}
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTableName, err))
 		return err
@@ -137,14 +176,18 @@ func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, tableId string, srcSc
 		newValues, err1 := common.PrepareValues(conv, tableId, colNameIdMap, colIds, srcCols, v)
 		cvtCols, cvtVals, err2 := convertSQLRow(conv, tableId, colIds, srcSchema, spSchema, newValues)
 		if err1 != nil || err2 != nil {
+			if err = err1; err == nil {
+				err = err2
+			}
 			conv.Unexpected(fmt.Sprintf("Couldn't process sql data row: %s", err))
 			conv.StatsAddBadRow(srcTableName, conv.DataMode())
-			conv.CollectBadRow(srcTableName, srcCols, valsToStrings(v))
+			conv.CollectBadRow(srcTableName, srcCols, valsToStrings(v), err)
 			continue
 		}
 		conv.WriteRow(srcTableName, conv.SpSchema[tableId].Name, cvtCols, cvtVals)
 	}
-	return nil
+	// The query is cancelled once ctx is done, which ends the rows early.
+	return ctx.Err()
 }
 
 // ConvertSQLRow performs data conversion for a single row of data
@@ -167,9 +210,12 @@ func convertSQLRow(conv *internal.Conv, tableId string, colIds []string, srcSche
 		}
 		var spVal interface{}
 		var err error
-		if spCd.T.IsArray {
+		switch {
+		case len(srcCd.Type.Fields) > 0:
+			spVal, err = cvtSQLComposite(srcCd, srcVals[i])
+		case spCd.T.IsArray:
 			spVal, err = cvtSQLArray(conv, srcCd, spCd, srcVals[i])
-		} else {
+		default:
 			spVal, err = cvtSQLScalar(conv, srcCd, spCd, srcVals[i])
 		}
 		if err != nil { // Skip entire row if we hit error.
@@ -217,7 +263,12 @@ func (isi InfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
 	for _, s := range []string{"information_schema", "postgres", "pg_catalog", "pg_temp_1", "pg_toast", "pg_toast_temp_1"} {
 		ignored[s] = true
 	}
-	q := "SELECT table_schema, table_name FROM information_schema.tables where table_type = 'BASE TABLE'"
+	// Partitions of partitioned tables are also listed as base tables: skip
+	// them since their data is migrated through their parent table (see
+	// GetPartitions).
+	q := `SELECT table_schema, table_name FROM information_schema.tables where table_type = 'BASE TABLE'
+		AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_class c JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = table_schema AND c.relname = table_name AND c.relispartition)`
 	rows, err := isi.Db.Query(q)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't get tables: %w", err)
@@ -234,9 +285,49 @@ func (isi InfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
 	return tables, nil
 }
 
+// GetPartitions returns the partition key and the leaf partitions of a
+// partitioned table, descending into sub-partitioned partitions. The
+// partition key is empty if the table isn't partitioned.
+func (isi InfoSchemaImpl) GetPartitions(table common.SchemaAndName) (string, []string, error) {
+	q := `WITH RECURSIVE tree AS (
+			SELECT p.partrelid AS root, p.partrelid AS relid
+			FROM pg_catalog.pg_partitioned_table p
+			JOIN pg_catalog.pg_class c ON c.oid = p.partrelid
+			JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = $1 AND c.relname = $2
+			UNION ALL
+			SELECT t.root, i.inhrelid FROM tree t JOIN pg_catalog.pg_inherits i ON i.inhparent = t.relid
+		)
+		SELECT pg_catalog.pg_get_partkeydef(t.root), n.nspname, c.relname, c.relkind
+		FROM tree t
+		JOIN pg_catalog.pg_class c ON c.oid = t.relid
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		ORDER BY n.nspname, c.relname`
+	rows, err := isi.Db.Query(q, table.Schema, table.Name)
+	if err != nil {
+		return "", nil, err
+	}
+	defer rows.Close()
+	var partitionBy, partSchema, partName, relKind string
+	var partitions []string
+	for rows.Next() {
+		err := rows.Scan(&partitionBy, &partSchema, &partName, &relKind)
+		if err != nil {
+			return "", nil, fmt.Errorf("can't scan: %v", err)
+		}
+		// Only leaf partitions hold data: the partitioned table itself and
+		// sub-partitioned partitions have relkind 'p'.
+		if relKind == "r" {
+			partitions = append(partitions, isi.GetTableName(partSchema, partName))
+		}
+	}
+	return partitionBy, partitions, nil
+}
+
 // GetColumns returns a list of Column objects and names
 func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAndName, constraints map[string][]string, primaryKeys []string) (map[string]schema.Column, []string, error) {
-	q := `SELECT c.column_name, c.data_type, e.data_type, c.is_nullable, c.column_default, c.character_maximum_length, c.numeric_precision, c.numeric_scale
+	q := `SELECT c.column_name, c.data_type, e.data_type, c.is_nullable, c.column_default, c.character_maximum_length, c.numeric_precision, c.numeric_scale,
+                COALESCE(e.udt_schema, c.udt_schema), COALESCE(e.udt_name, c.udt_name), c.domain_schema, c.domain_name
               FROM information_schema.COLUMNS c LEFT JOIN information_schema.element_types e
                  ON ((c.table_catalog, c.table_schema, c.table_name, 'TABLE', c.dtd_identifier)
                      = (e.object_catalog, e.object_schema, e.object_name, e.object_type, e.collection_type_identifier))
@@ -249,10 +340,14 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 	colDefs := make(map[string]schema.Column)
 	var colIds []string
 	var colName, dataType, isNullable string
-	var colDefault, elementDataType sql.NullString
+	var colDefault, elementDataType, udtSchema, udtName, domainSchema, domainName sql.NullString
 	var charMaxLen, numericPrecision, numericScale sql.NullInt64
+	// User-defined types and domains are looked up once all columns have
+	// been read.
+	userTypes := make(map[string]common.SchemaAndName)
+	domains := make(map[string]common.SchemaAndName)
 	for cols.Next() {
-		err := cols.Scan(&colName, &dataType, &elementDataType, &isNullable, &colDefault, &charMaxLen, &numericPrecision, &numericScale)
+		err := cols.Scan(&colName, &dataType, &elementDataType, &isNullable, &colDefault, &charMaxLen, &numericPrecision, &numericScale, &udtSchema, &udtName, &domainSchema, &domainName)
 		if err != nil {
 			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
 			continue
@@ -280,10 +375,163 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 		}
 		colDefs[colId] = c
 		colIds = append(colIds, colId)
+		if c.Type.Name == "USER-DEFINED" && udtSchema.Valid && udtName.Valid {
+			userTypes[colId] = common.SchemaAndName{Schema: udtSchema.String, Name: udtName.String}
+		}
+		if domainSchema.Valid && domainName.Valid {
+			domains[colId] = common.SchemaAndName{Schema: domainSchema.String, Name: domainName.String}
+		}
+	}
+	cols.Close()
+	for _, colId := range colIds {
+		c := colDefs[colId]
+		if t, ok := userTypes[colId]; ok {
+			ty, err := isi.getUserType(t, 0)
+			if err != nil {
+				conv.Unexpected(fmt.Sprintf("Can't get user-defined type %s.%s of column %s: %s", t.Schema, t.Name, c.Name, err))
+			} else {
+				c.Type.Name, c.Type.EnumValues, c.Type.Fields = ty.Name, ty.EnumValues, ty.Fields
+			}
+		}
+		if d, ok := domains[colId]; ok {
+			notNull, checks, err := isi.getDomainConstraints(d)
+			if err != nil {
+				conv.Unexpected(fmt.Sprintf("Can't get constraints of domain %s.%s of column %s: %s", d.Schema, d.Name, c.Name, err))
+			} else {
+				c.NotNull = c.NotNull || notNull
+				c.Checks = checks
+			}
+		}
+		colDefs[colId] = c
 	}
 	return colDefs, colIds, nil
 }
 
+// maxUserTypeDepth bounds the nesting of composite types.
+const maxUserTypeDepth = 10
+
+// getUserType returns the type for the enum or composite type t. Other
+// user-defined types (such as range types) are returned by name.
+func (isi InfoSchemaImpl) getUserType(t common.SchemaAndName, depth int) (schema.Type, error) {
+	ty := schema.Type{Name: isi.GetTableName(t.Schema, t.Name)}
+	if depth > maxUserTypeDepth {
+		return ty, fmt.Errorf("composite types nested too deeply")
+	}
+	q := `SELECT t.typtype FROM pg_catalog.pg_type t JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace
+              WHERE n.nspname = $1 AND t.typname = $2;`
+	var typType string
+	if err := isi.Db.QueryRow(q, t.Schema, t.Name).Scan(&typType); err != nil {
+		return ty, err
+	}
+	switch typType {
+	case "e":
+		q := `SELECT e.enumlabel FROM pg_catalog.pg_enum e
+                JOIN pg_catalog.pg_type t ON t.oid = e.enumtypid
+                JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace
+              WHERE n.nspname = $1 AND t.typname = $2 ORDER BY e.enumsortorder;`
+		rows, err := isi.Db.Query(q, t.Schema, t.Name)
+		if err != nil {
+			return ty, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var label string
+			if err := rows.Scan(&label); err != nil {
+				return ty, err
+			}
+			ty.EnumValues = append(ty.EnumValues, label)
+		}
+		return ty, rows.Err()
+	case "c":
+		// Fields of domain types are read as their base type.
+		q := `SELECT a.attname, pg_catalog.format_type(CASE WHEN ft.typtype = 'd' THEN ft.typbasetype ELSE a.atttypid END, NULL),
+                fn.nspname, ft.typname, ft.typtype
+              FROM pg_catalog.pg_type t
+                JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace
+                JOIN pg_catalog.pg_attribute a ON a.attrelid = t.typrelid
+                JOIN pg_catalog.pg_type ft ON ft.oid = a.atttypid
+                JOIN pg_catalog.pg_namespace fn ON fn.oid = ft.typnamespace
+              WHERE n.nspname = $1 AND t.typname = $2 AND a.attnum > 0 AND NOT a.attisdropped
+              ORDER BY a.attnum;`
+		rows, err := isi.Db.Query(q, t.Schema, t.Name)
+		if err != nil {
+			return ty, err
+		}
+		var nested []common.SchemaAndName
+		for rows.Next() {
+			var name, fieldType, fieldSchema, fieldTypeName, fieldTypType string
+			if err := rows.Scan(&name, &fieldType, &fieldSchema, &fieldTypeName, &fieldTypType); err != nil {
+				rows.Close()
+				return ty, err
+			}
+			f := schema.Column{Name: name, Type: schema.Type{Name: fieldType}}
+			if strings.HasSuffix(fieldType, "[]") {
+				f.Type = schema.Type{Name: strings.TrimSuffix(fieldType, "[]"), ArrayBounds: []int64{-1}}
+			}
+			ty.Fields = append(ty.Fields, f)
+			if fieldTypType == "c" || fieldTypType == "e" {
+				nested = append(nested, common.SchemaAndName{Schema: fieldSchema, Name: fieldTypeName})
+			} else {
+				nested = append(nested, common.SchemaAndName{})
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return ty, err
+		}
+		for i, n := range nested {
+			if n.Name == "" {
+				continue
+			}
+			fty, err := isi.getUserType(n, depth+1)
+			if err != nil {
+				return ty, err
+			}
+			ty.Fields[i].Type = fty
+		}
+	}
+	return ty, nil
+}
+
+// getDomainConstraints returns the NOT NULL and CHECK constraints of domain
+// d and of the domains it is based on.
+func (isi InfoSchemaImpl) getDomainConstraints(d common.SchemaAndName) (bool, []string, error) {
+	q := `WITH RECURSIVE domains AS (
+                SELECT t.oid, t.typbasetype, t.typnotnull, 0 AS depth
+                FROM pg_catalog.pg_type t JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace
+                WHERE n.nspname = $1 AND t.typname = $2
+              UNION ALL
+                SELECT t.oid, t.typbasetype, t.typnotnull, d.depth + 1
+                FROM pg_catalog.pg_type t JOIN domains d ON t.oid = d.typbasetype
+                WHERE t.typtype = 'd'
+              )
+              SELECT d.typnotnull, pg_catalog.pg_get_constraintdef(c.oid)
+              FROM domains d LEFT JOIN pg_catalog.pg_constraint c ON c.contypid = d.oid AND c.contype = 'c'
+              ORDER BY d.depth, c.conname;`
+	rows, err := isi.Db.Query(q, d.Schema, d.Name)
+	if err != nil {
+		return false, nil, err
+	}
+	defer rows.Close()
+	var notNull bool
+	var checks []string
+	for rows.Next() {
+		var typNotNull bool
+		var def sql.NullString
+		if err := rows.Scan(&typNotNull, &def); err != nil {
+			return false, nil, err
+		}
+		notNull = notNull || typNotNull
+		if def.Valid {
+			// Definitions are printed as "CHECK (expr)", with a NOT VALID
+			// suffix for constraints that weren't validated.
+			e := strings.TrimSuffix(strings.TrimPrefix(def.String, "CHECK "), " NOT VALID")
+			checks = append(checks, e)
+		}
+	}
+	return notNull, checks, rows.Err()
+}
+
 // GetConstraints returns a list of primary keys and by-column map of
 // other constraints.  Note: we need to preserve ordinal order of
 // columns in primary key constraints.
@@ -480,6 +728,16 @@ func cvtSQLArray(conv *internal.Conv, srcCd schema.Column, spCd ddl.ColumnDef, v
 	return convArray(spCd.T, srcCd.Type.Name, conv.Location, string(a))
 }
 
+func cvtSQLComposite(srcCd schema.Column, val interface{}) (interface{}, error) {
+	switch v := val.(type) {
+	case []byte:
+		return convComposite(srcCd.Type, string(v))
+	case string:
+		return convComposite(srcCd.Type, v)
+	}
+	return nil, fmt.Errorf("can't convert composite values of type %s", reflect.TypeOf(val))
+}
+
 // cvtSQLScalar converts a values returned from a SQL query to a
 // Spanner value.  In principle, we could just hand the values we get
 // from the driver over to Spanner and have the Spanner client handle
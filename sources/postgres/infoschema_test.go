@@ -15,6 +15,7 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"math/big"
@@ -25,7 +26,6 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/logger"
-	"github.com/cloudspannerecosystem/harbourbridge/profiles"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
@@ -75,11 +75,11 @@ func TestProcessSchema(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"public", "user"},
-			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "udt_schema", "udt_name", "domain_schema", "domain_name"},
 			rows: [][]driver.Value{
-				{"user_id", "text", nil, "NO", nil, nil, nil, nil},
-				{"name", "text", nil, "NO", nil, nil, nil, nil},
-				{"ref", "bigint", nil, "YES", nil, nil, nil, nil}},
+				{"user_id", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil},
+				{"name", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil},
+				{"ref", "bigint", nil, "YES", nil, nil, nil, nil, nil, nil, nil, nil}},
 		},
 		// db call to fetch index happens after fetching of column
 		{
@@ -87,6 +87,11 @@ func TestProcessSchema(t *testing.T) {
 			args:  []driver.Value{"public", "user"},
 			cols:  []string{"index_name", "column_name", "column_position", "is_unique", "order"},
 		},
+		{
+			query: "WITH RECURSIVE (.+) pg_catalog.pg_partitioned_table (.+)",
+			args:  []driver.Value{"public", "user"},
+			cols:  []string{"partkey", "nspname", "relname", "relkind"},
+		},
 
 		{
 			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
@@ -107,11 +112,11 @@ func TestProcessSchema(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"public", "cart"},
-			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "udt_schema", "udt_name", "domain_schema", "domain_name"},
 			rows: [][]driver.Value{
-				{"productid", "text", nil, "NO", nil, nil, nil, nil},
-				{"userid", "text", nil, "NO", nil, nil, nil, nil},
-				{"quantity", "bigint", nil, "YES", nil, nil, 64, 0}},
+				{"productid", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil},
+				{"userid", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil},
+				{"quantity", "bigint", nil, "YES", nil, nil, 64, 0, nil, nil, nil, nil}},
 		},
 		// db call to fetch index happens after fetching of column
 		{
@@ -125,6 +130,11 @@ func TestProcessSchema(t *testing.T) {
 				{"index3", "userid", 2, "true", "ASC"},
 			},
 		},
+		{
+			query: "WITH RECURSIVE (.+) pg_catalog.pg_partitioned_table (.+)",
+			args:  []driver.Value{"public", "cart"},
+			cols:  []string{"partkey", "nspname", "relname", "relkind"},
+		},
 		{
 			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
 			args:  []driver.Value{"public", "product"},
@@ -140,10 +150,10 @@ func TestProcessSchema(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"public", "product"},
-			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "udt_schema", "udt_name", "domain_schema", "domain_name"},
 			rows: [][]driver.Value{
-				{"product_id", "text", nil, "NO", nil, nil, nil, nil},
-				{"product_name", "text", nil, "NO", nil, nil, nil, nil}},
+				{"product_id", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil},
+				{"product_name", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil}},
 		},
 		// db call to fetch index happens after fetching of column
 		{
@@ -151,6 +161,11 @@ func TestProcessSchema(t *testing.T) {
 			args:  []driver.Value{"public", "product"},
 			cols:  []string{"index_name", "column_name", "column_position", "is_unique", "order"},
 		},
+		{
+			query: "WITH RECURSIVE (.+) pg_catalog.pg_partitioned_table (.+)",
+			args:  []driver.Value{"public", "product"},
+			cols:  []string{"partkey", "nspname", "relname", "relkind"},
+		},
 
 		{
 			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
@@ -168,29 +183,29 @@ func TestProcessSchema(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"public", "test"},
-			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "udt_schema", "udt_name", "domain_schema", "domain_name"},
 			rows: [][]driver.Value{
-				{"id", "bigint", nil, "NO", nil, nil, 64, 0},
-				{"aint", "ARRAY", "integer", "YES", nil, nil, nil, nil},
-				{"atext", "ARRAY", "text", "YES", nil, nil, nil, nil},
-				{"b", "boolean", nil, "YES", nil, nil, nil, nil},
-				{"bs", "bigint", nil, "NO", "nextval('test11_bs_seq'::regclass)", nil, 64, 0},
-				{"by", "bytea", nil, "YES", nil, nil, nil, nil},
-				{"c", "character", nil, "YES", nil, 1, nil, nil},
-				{"c_8", "character", nil, "YES", nil, 8, nil, nil},
-				{"d", "date", nil, "YES", nil, nil, nil, nil},
-				{"f8", "double precision", nil, "YES", nil, nil, 53, nil},
-				{"f4", "real", nil, "YES", nil, nil, 24, nil},
-				{"i8", "bigint", nil, "YES", nil, nil, 64, 0},
-				{"i4", "integer", nil, "YES", nil, nil, 32, 0},
-				{"i2", "smallint", nil, "YES", nil, nil, 16, 0},
-				{"num", "numeric", nil, "YES", nil, nil, nil, nil},
-				{"s", "integer", nil, "NO", "nextval('test11_s_seq'::regclass)", nil, 32, 0},
-				{"ts", "timestamp without time zone", nil, "YES", nil, nil, nil, nil},
-				{"tz", "timestamp with time zone", nil, "YES", nil, nil, nil, nil},
-				{"txt", "text", nil, "NO", nil, nil, nil, nil},
-				{"vc", "character varying", nil, "YES", nil, nil, nil, nil},
-				{"vc6", "character varying", nil, "YES", nil, 6, nil, nil}},
+				{"id", "bigint", nil, "NO", nil, nil, 64, 0, nil, nil, nil, nil},
+				{"aint", "ARRAY", "integer", "YES", nil, nil, nil, nil, nil, nil, nil, nil},
+				{"atext", "ARRAY", "text", "YES", nil, nil, nil, nil, nil, nil, nil, nil},
+				{"b", "boolean", nil, "YES", nil, nil, nil, nil, nil, nil, nil, nil},
+				{"bs", "bigint", nil, "NO", "nextval('test11_bs_seq'::regclass)", nil, 64, 0, nil, nil, nil, nil},
+				{"by", "bytea", nil, "YES", nil, nil, nil, nil, nil, nil, nil, nil},
+				{"c", "character", nil, "YES", nil, 1, nil, nil, nil, nil, nil, nil},
+				{"c_8", "character", nil, "YES", nil, 8, nil, nil, nil, nil, nil, nil},
+				{"d", "date", nil, "YES", nil, nil, nil, nil, nil, nil, nil, nil},
+				{"f8", "double precision", nil, "YES", nil, nil, 53, nil, nil, nil, nil, nil},
+				{"f4", "real", nil, "YES", nil, nil, 24, nil, nil, nil, nil, nil},
+				{"i8", "bigint", nil, "YES", nil, nil, 64, 0, nil, nil, nil, nil},
+				{"i4", "integer", nil, "YES", nil, nil, 32, 0, nil, nil, nil, nil},
+				{"i2", "smallint", nil, "YES", nil, nil, 16, 0, nil, nil, nil, nil},
+				{"num", "numeric", nil, "YES", nil, nil, nil, nil, nil, nil, nil, nil},
+				{"s", "integer", nil, "NO", "nextval('test11_s_seq'::regclass)", nil, 32, 0, nil, nil, nil, nil},
+				{"ts", "timestamp without time zone", nil, "YES", nil, nil, nil, nil, nil, nil, nil, nil},
+				{"tz", "timestamp with time zone", nil, "YES", nil, nil, nil, nil, nil, nil, nil, nil},
+				{"txt", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil},
+				{"vc", "character varying", nil, "YES", nil, nil, nil, nil, nil, nil, nil, nil},
+				{"vc6", "character varying", nil, "YES", nil, 6, nil, nil, nil, nil, nil, nil}},
 		},
 		// db call to fetch index happens after fetching of column
 		{
@@ -198,6 +213,11 @@ func TestProcessSchema(t *testing.T) {
 			args:  []driver.Value{"public", "test"},
 			cols:  []string{"index_name", "column_name", "column_position", "is_unique", "order"},
 		},
+		{
+			query: "WITH RECURSIVE (.+) pg_catalog.pg_partitioned_table (.+)",
+			args:  []driver.Value{"public", "test"},
+			cols:  []string{"partkey", "nspname", "relname", "relkind"},
+		},
 
 		{
 			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
@@ -214,11 +234,11 @@ func TestProcessSchema(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"public", "test_ref"},
-			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "udt_schema", "udt_name", "domain_schema", "domain_name"},
 			rows: [][]driver.Value{
-				{"ref_id", "bigint", nil, "NO", nil, nil, 64, 0},
-				{"ref_txt", "text", nil, "NO", nil, nil, nil, nil},
-				{"abc", "text", nil, "NO", nil, nil, nil, nil}},
+				{"ref_id", "bigint", nil, "NO", nil, nil, 64, 0, nil, nil, nil, nil},
+				{"ref_txt", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil},
+				{"abc", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil}},
 		},
 		// db call to fetch index happens after fetching of column
 		{
@@ -226,10 +246,15 @@ func TestProcessSchema(t *testing.T) {
 			args:  []driver.Value{"public", "test_ref"},
 			cols:  []string{"index_name", "column_name", "column_position", "is_unique", "order"},
 		},
+		{
+			query: "WITH RECURSIVE (.+) pg_catalog.pg_partitioned_table (.+)",
+			args:  []driver.Value{"public", "test_ref"},
+			cols:  []string{"partkey", "nspname", "relname", "relkind"},
+		},
 	}
 	db := mkMockDB(t, ms)
 	conv := internal.MakeConv()
-	err := common.ProcessSchema(conv, InfoSchemaImpl{db, profiles.SourceProfile{}, profiles.TargetProfile{}}, 1)
+	err := common.ProcessSchema(conv, InfoSchemaImpl{Db: db}, 1)
 	assert.Nil(t, err)
 	expectedSchema := map[string]ddl.CreateTable{
 		"user": ddl.CreateTable{
@@ -321,6 +346,218 @@ func TestProcessSchema(t *testing.T) {
 	assert.Equal(t, int64(0), conv.Unexpecteds())
 }
 
+func TestProcessSchema_Partitioned(t *testing.T) {
+	ms := []mockSpec{
+		{
+			query: "SELECT table_schema, table_name FROM information_schema.tables where table_type = 'BASE TABLE' AND NOT EXISTS (.+) c.relispartition",
+			cols:  []string{"table_schema", "table_name"},
+			rows:  [][]driver.Value{{"public", "measurement"}},
+		},
+		{
+			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
+			args:  []driver.Value{"public", "measurement"},
+			cols:  []string{"column_name", "constraint_type"},
+			rows: [][]driver.Value{
+				{"city_id", "PRIMARY KEY"},
+				{"logdate", "PRIMARY KEY"}},
+		},
+		{
+			query: "SELECT (.+) FROM PG_CLASS (.+) JOIN PG_NAMESPACE (.+) JOIN PG_CONSTRAINT (.+)",
+			args:  []driver.Value{"public", "measurement"},
+			cols:  []string{"TABLE_SCHEMA", "TABLE_NAME", "COLUMN_NAME", "REF_COLUMN_NAME", "CONSTRAINT_NAME"},
+		},
+		{
+			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
+			args:  []driver.Value{"public", "measurement"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "udt_schema", "udt_name", "domain_schema", "domain_name"},
+			rows: [][]driver.Value{
+				{"city_id", "bigint", nil, "NO", nil, nil, 64, 0, nil, nil, nil, nil},
+				{"logdate", "date", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil},
+				{"peaktemp", "bigint", nil, "YES", nil, nil, 64, 0, nil, nil, nil, nil}},
+		},
+		{
+			query: "SELECT (.+) FROM pg_index (.+)",
+			args:  []driver.Value{"public", "measurement"},
+			cols:  []string{"index_name", "column_name", "column_position", "is_unique", "order"},
+		},
+		{
+			query: "WITH RECURSIVE (.+) pg_catalog.pg_partitioned_table (.+)",
+			args:  []driver.Value{"public", "measurement"},
+			cols:  []string{"partkey", "nspname", "relname", "relkind"},
+			rows: [][]driver.Value{
+				{"RANGE (logdate)", "public", "measurement", "p"},
+				{"RANGE (logdate)", "public", "measurement_y2022", "r"},
+				{"RANGE (logdate)", "public", "measurement_y2023", "p"},
+				{"RANGE (logdate)", "public", "measurement_y2023h1", "r"},
+				{"RANGE (logdate)", "sales", "measurement_y2024", "r"}},
+		},
+	}
+	db := mkMockDB(t, ms)
+	conv := internal.MakeConv()
+	err := common.ProcessSchema(conv, InfoSchemaImpl{Db: db}, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(conv.SrcSchema))
+	tableId, err := internal.GetTableIdFromSrcName(conv.SrcSchema, "measurement")
+	assert.Nil(t, err)
+	srcTable := conv.SrcSchema[tableId]
+	assert.Equal(t, "RANGE (logdate)", srcTable.PartitionBy)
+	assert.Equal(t, []string{"measurement_y2022", "measurement_y2023h1", "sales.measurement_y2024"}, srcTable.Partitions)
+	expectedSchema := map[string]ddl.CreateTable{
+		"measurement": ddl.CreateTable{
+			Name:   "measurement",
+			ColIds: []string{"city_id", "logdate", "peaktemp"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"city_id":  ddl.ColumnDef{Name: "city_id", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+				"logdate":  ddl.ColumnDef{Name: "logdate", T: ddl.Type{Name: ddl.Date}, NotNull: true},
+				"peaktemp": ddl.ColumnDef{Name: "peaktemp", T: ddl.Type{Name: ddl.Int64}},
+			},
+			PrimaryKeys: []ddl.IndexKey{ddl.IndexKey{ColId: "city_id", Order: 1}, ddl.IndexKey{ColId: "logdate", Order: 2}}},
+	}
+	internal.AssertSpSchema(conv, t, expectedSchema, stripSchemaComments(conv.SpSchema))
+	assert.Equal(t, int64(0), conv.Unexpecteds())
+}
+
+func TestProcessSchema_UserDefinedTypes(t *testing.T) {
+	ms := []mockSpec{
+		{
+			query: "SELECT table_schema, table_name FROM information_schema.tables where table_type = 'BASE TABLE' AND NOT EXISTS (.+) c.relispartition",
+			cols:  []string{"table_schema", "table_name"},
+			rows:  [][]driver.Value{{"public", "t"}},
+		},
+		{
+			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
+			args:  []driver.Value{"public", "t"},
+			cols:  []string{"column_name", "constraint_type"},
+			rows:  [][]driver.Value{{"id", "PRIMARY KEY"}},
+		},
+		{
+			query: "SELECT (.+) FROM PG_CLASS (.+) JOIN PG_NAMESPACE (.+) JOIN PG_CONSTRAINT (.+)",
+			args:  []driver.Value{"public", "t"},
+			cols:  []string{"TABLE_SCHEMA", "TABLE_NAME", "COLUMN_NAME", "REF_COLUMN_NAME", "CONSTRAINT_NAME"},
+		},
+		{
+			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
+			args:  []driver.Value{"public", "t"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "udt_schema", "udt_name", "domain_schema", "domain_name"},
+			rows: [][]driver.Value{
+				{"id", "integer", nil, "NO", nil, nil, 32, 0, "pg_catalog", "int4", "public", "posint"},
+				{"m", "USER-DEFINED", nil, "YES", nil, nil, nil, nil, "public", "mood", nil, nil},
+				{"ms", "ARRAY", "USER-DEFINED", "YES", nil, nil, nil, nil, "public", "mood", nil, nil},
+				{"a", "USER-DEFINED", nil, "YES", nil, nil, nil, nil, "public", "address", nil, nil},
+				{"w", "text", nil, "YES", nil, nil, nil, nil, "pg_catalog", "text", "public", "word"}},
+		},
+		{
+			query: "SELECT (.+) FROM pg_catalog.pg_type (.+)",
+			args:  []driver.Value{"public", "posint"},
+			cols:  []string{"typnotnull", "pg_get_constraintdef"},
+			rows: [][]driver.Value{
+				{true, "CHECK ((VALUE > 0))"},
+				{false, "CHECK ((VALUE < 100)) NOT VALID"}},
+		},
+		{
+			query: "SELECT t.typtype FROM pg_catalog.pg_type (.+)",
+			args:  []driver.Value{"public", "mood"},
+			cols:  []string{"typtype"},
+			rows:  [][]driver.Value{{"e"}},
+		},
+		{
+			query: "SELECT e.enumlabel FROM pg_catalog.pg_enum (.+)",
+			args:  []driver.Value{"public", "mood"},
+			cols:  []string{"enumlabel"},
+			rows:  [][]driver.Value{{"sad"}, {"happy"}},
+		},
+		{
+			query: "SELECT t.typtype FROM pg_catalog.pg_type (.+)",
+			args:  []driver.Value{"public", "mood"},
+			cols:  []string{"typtype"},
+			rows:  [][]driver.Value{{"e"}},
+		},
+		{
+			query: "SELECT e.enumlabel FROM pg_catalog.pg_enum (.+)",
+			args:  []driver.Value{"public", "mood"},
+			cols:  []string{"enumlabel"},
+			rows:  [][]driver.Value{{"sad"}, {"happy"}},
+		},
+		{
+			query: "SELECT t.typtype FROM pg_catalog.pg_type (.+)",
+			args:  []driver.Value{"public", "address"},
+			cols:  []string{"typtype"},
+			rows:  [][]driver.Value{{"c"}},
+		},
+		{
+			query: "SELECT a.attname, (.+) FROM pg_catalog.pg_type t (.+) pg_catalog.pg_attribute (.+)",
+			args:  []driver.Value{"public", "address"},
+			cols:  []string{"attname", "format_type", "nspname", "typname", "typtype"},
+			rows: [][]driver.Value{
+				{"street", "text", "pg_catalog", "text", "b"},
+				{"num", "integer", "public", "posint", "d"},
+				{"tags", "text[]", "pg_catalog", "_text", "b"},
+				{"m", "mood", "public", "mood", "e"}},
+		},
+		{
+			query: "SELECT t.typtype FROM pg_catalog.pg_type (.+)",
+			args:  []driver.Value{"public", "mood"},
+			cols:  []string{"typtype"},
+			rows:  [][]driver.Value{{"e"}},
+		},
+		{
+			query: "SELECT e.enumlabel FROM pg_catalog.pg_enum (.+)",
+			args:  []driver.Value{"public", "mood"},
+			cols:  []string{"enumlabel"},
+			rows:  [][]driver.Value{{"sad"}, {"happy"}},
+		},
+		{
+			query: "SELECT (.+) FROM pg_catalog.pg_type (.+)",
+			args:  []driver.Value{"public", "word"},
+			cols:  []string{"typnotnull", "pg_get_constraintdef"},
+			rows:  [][]driver.Value{{false, "CHECK ((VALUE ~ '^[a-z]+$'::text))"}},
+		},
+		{
+			query: "SELECT (.+) FROM pg_index (.+)",
+			args:  []driver.Value{"public", "t"},
+			cols:  []string{"index_name", "column_name", "column_position", "is_unique", "order"},
+		},
+		{
+			query: "WITH RECURSIVE (.+) pg_catalog.pg_partitioned_table (.+)",
+			args:  []driver.Value{"public", "t"},
+			cols:  []string{"partkey", "nspname", "relname", "relkind"},
+		},
+	}
+	db := mkMockDB(t, ms)
+	conv := internal.MakeConv()
+	err := common.ProcessSchema(conv, InfoSchemaImpl{Db: db}, 1)
+	assert.Nil(t, err)
+	tableId, err := internal.GetTableIdFromSrcName(conv.SrcSchema, "t")
+	assert.Nil(t, err)
+	aColId, err := internal.GetColIdFromSrcName(conv.SrcSchema[tableId].ColDefs, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, schema.Type{Name: "address", Fields: []schema.Column{
+		{Name: "street", Type: schema.Type{Name: "text"}},
+		{Name: "num", Type: schema.Type{Name: "integer"}},
+		{Name: "tags", Type: schema.Type{Name: "text", ArrayBounds: []int64{-1}}},
+		{Name: "m", Type: schema.Type{Name: "mood", EnumValues: []string{"sad", "happy"}}}}}, conv.SrcSchema[tableId].ColDefs[aColId].Type)
+	expectedSchema := map[string]ddl.CreateTable{
+		"t": ddl.CreateTable{
+			Name:   "t",
+			ColIds: []string{"id", "m", "ms", "a", "w"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"id": ddl.ColumnDef{Name: "id", T: ddl.Type{Name: ddl.Int64}, NotNull: true, Checks: []string{"VALUE > 0", "VALUE < 100"}},
+				"m":  ddl.ColumnDef{Name: "m", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, Checks: []string{"VALUE IN ('sad', 'happy')"}},
+				"ms": ddl.ColumnDef{Name: "ms", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength, IsArray: true}},
+				"a":  ddl.ColumnDef{Name: "a", T: ddl.Type{Name: ddl.JSON}},
+				"w":  ddl.ColumnDef{Name: "w", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			},
+			PrimaryKeys: []ddl.IndexKey{ddl.IndexKey{ColId: "id", Order: 1}}},
+	}
+	internal.AssertSpSchema(conv, t, expectedSchema, stripSchemaComments(conv.SpSchema))
+	for _, col := range []string{"ms", "w"} {
+		colId, err := internal.GetColIdFromSrcName(conv.SrcSchema[tableId].ColDefs, col)
+		assert.Nil(t, err)
+		assert.Equal(t, []internal.SchemaIssue{internal.CheckConstraint}, conv.SchemaIssues[tableId][colId], col)
+	}
+	assert.Equal(t, int64(0), conv.Unexpecteds())
+}
+
 // TestProcessSqlData is a basic test of ProcessSqlData that checks
 // handling of bad rows and table and column renaming. The core data
 // conversion work of ProcessSqlData is done by ConvertData, which is
@@ -363,7 +600,7 @@ func TestProcessData(t *testing.T) {
 		func(table string, cols []string, vals []interface{}) {
 			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
 		})
-	common.ProcessData(conv, InfoSchemaImpl{db, profiles.SourceProfile{}, profiles.TargetProfile{}})
+	common.ProcessData(context.Background(), conv, InfoSchemaImpl{Db: db})
 
 	assert.Equal(t,
 		[]spannerData{
@@ -482,11 +719,11 @@ func TestConvertSqlRow_MultiCol(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"public", "test"},
-			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "udt_schema", "udt_name", "domain_schema", "domain_name"},
 			rows: [][]driver.Value{
-				{"a", "text", nil, "NO", nil, nil, nil, nil},
-				{"b", "double precision", nil, "YES", nil, nil, 53, nil},
-				{"c", "bigint", nil, "YES", nil, nil, 64, 0}},
+				{"a", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil},
+				{"b", "double precision", nil, "YES", nil, nil, 53, nil, nil, nil, nil, nil},
+				{"c", "bigint", nil, "YES", nil, nil, 64, 0, nil, nil, nil, nil}},
 		},
 		// db call to fetch index happens after fetching of column
 		{
@@ -494,6 +731,11 @@ func TestConvertSqlRow_MultiCol(t *testing.T) {
 			args:  []driver.Value{"public", "test"},
 			cols:  []string{"index_name", "column_name", "column_position", "is_unique", "order"},
 		},
+		{
+			query: "WITH RECURSIVE (.+) pg_catalog.pg_partitioned_table (.+)",
+			args:  []driver.Value{"public", "test"},
+			cols:  []string{"partkey", "nspname", "relname", "relkind"},
+		},
 		{
 			query: `SELECT [*] FROM "public"."test"`, // query is a regexp!
 			cols:  []string{"a", "b", "c"},
@@ -504,7 +746,7 @@ func TestConvertSqlRow_MultiCol(t *testing.T) {
 	}
 	db := mkMockDB(t, ms)
 	conv := internal.MakeConv()
-	err := common.ProcessSchema(conv, InfoSchemaImpl{db, profiles.SourceProfile{}, profiles.TargetProfile{}}, 1)
+	err := common.ProcessSchema(conv, InfoSchemaImpl{Db: db}, 1)
 	assert.Nil(t, err)
 	conv.SetDataMode()
 	var rows []spannerData
@@ -512,7 +754,7 @@ func TestConvertSqlRow_MultiCol(t *testing.T) {
 		func(table string, cols []string, vals []interface{}) {
 			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
 		})
-	common.ProcessData(conv, InfoSchemaImpl{db, profiles.SourceProfile{}, profiles.TargetProfile{}})
+	common.ProcessData(context.Background(), conv, InfoSchemaImpl{Db: db})
 	assert.Equal(t, []spannerData{
 		{table: "test", cols: []string{"a", "b", "synth_id"}, vals: []interface{}{"cat", float64(42.3), "0"}},
 		{table: "test", cols: []string{"a", "c", "synth_id"}, vals: []interface{}{"dog", int64(22), "-9223372036854775808"}}},
@@ -539,7 +781,7 @@ func TestSetRowStats(t *testing.T) {
 	db := mkMockDB(t, ms)
 	conv := internal.MakeConv()
 	conv.SetDataMode()
-	common.SetRowStats(conv, InfoSchemaImpl{db, profiles.SourceProfile{}, profiles.TargetProfile{}})
+	common.SetRowStats(conv, InfoSchemaImpl{Db: db})
 	assert.Equal(t, int64(5), conv.Stats.Rows["test1"])
 	assert.Equal(t, int64(142), conv.Stats.Rows["test2"])
 	assert.Equal(t, int64(0), conv.Unexpecteds())
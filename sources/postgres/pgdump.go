@@ -15,6 +15,7 @@
 package postgres
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -52,8 +53,8 @@ func (ddi DbDumpImpl) GetToDdl() common.ToDdl {
 }
 
 // ProcessDump calls processPgDump to read a Postgres dump file
-func (ddi DbDumpImpl) ProcessDump(conv *internal.Conv, r *internal.Reader) error {
-	return processPgDump(conv, r)
+func (ddi DbDumpImpl) ProcessDump(ctx context.Context, conv *internal.Conv, r *internal.Reader) error {
+	return processPgDump(ctx, conv, r)
 }
 
 // processPgDump reads pg_dump data from r and does schema or data conversion,
@@ -61,15 +62,19 @@ func (ddi DbDumpImpl) ProcessDump(conv *internal.Conv, r *internal.Reader) error
 // In schema mode, ProcessPgDump incrementally builds a schema (updating conv).
 // In data mode, ProcessPgDump uses this schema to convert PostgreSQL data
 // and writes it to Spanner, using the data sink specified in conv.
-func processPgDump(conv *internal.Conv, r *internal.Reader) error {
+func processPgDump(ctx context.Context, conv *internal.Conv, r *internal.Reader) error {
+	types := userTypes{}
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		startLine := r.LineNumber
 		startOffset := r.Offset
 		b, stmts, err := readAndParseChunk(conv, r)
 		if err != nil {
 			return err
 		}
-		ci := processStatements(conv, stmts)
+		ci := processStatements(conv, stmts, types)
 		internal.VerbosePrintf("Parsed SQL command at line=%d/fpos=%d: %d stmts (%d lines, %d bytes) ci=%v\n", startLine, startOffset, len(stmts), r.LineNumber-startLine, len(b), ci != nil)
 		logger.Log.Debug(fmt.Sprintf("Parsed SQL command at line=%d/fpos=%d: %d stmts (%d lines, %d bytes) ci=%v\n", startLine, startOffset, len(stmts), r.LineNumber-startLine, len(b), ci != nil))
 		if ci != nil {
@@ -79,7 +84,9 @@ func processPgDump(conv *internal.Conv, r *internal.Reader) error {
 				if err != nil && !conv.SchemaMode() {
 					return err
 				}
-				processCopyBlock(conv, ci.table, commonColIds, ci.cols, r)
+				if err := processCopyBlock(ctx, conv, ci.table, commonColIds, ci.cols, r); err != nil {
+					return err
+				}
 			case insert:
 				if conv.SchemaMode() {
 					continue
@@ -105,7 +112,7 @@ func processPgDump(conv *internal.Conv, r *internal.Reader) error {
 						srcTableName := conv.SrcSchema[ci.table].Name
 						conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
 						conv.StatsAddBadRow(srcTableName, conv.DataMode())
-						conv.CollectBadRow(srcTableName, colNames, vals)
+						conv.CollectBadRow(srcTableName, colNames, vals, err)
 						continue
 					}
 					ProcessDataRow(conv, ci.table, commonColIds, newVals)
@@ -156,7 +163,7 @@ func readAndParseChunk(conv *internal.Conv, r *internal.Reader) ([]byte, []*pg_q
 	}
 }
 
-func processCopyBlock(conv *internal.Conv, tableId string, commonColIds, srcCols []string, r *internal.Reader) {
+func processCopyBlock(ctx context.Context, conv *internal.Conv, tableId string, commonColIds, srcCols []string, r *internal.Reader) error {
 	srcTableName := conv.SrcSchema[tableId].Name
 	internal.VerbosePrintf("Parsing COPY-FROM stdin block starting at line=%d/fpos=%d\n", r.LineNumber, r.Offset)
 	logger.Log.Debug(fmt.Sprintf("Parsing COPY-FROM stdin block starting at line=%d/fpos=%d\n", r.LineNumber, r.Offset))
@@ -165,11 +172,14 @@ func processCopyBlock(conv *internal.Conv, tableId string, commonColIds, srcCols
 		if string(b) == "\\.\n" || string(b) == "\\.\r\n" {
 			internal.VerbosePrintf("Parsed COPY-FROM stdin block ending at line=%d/fpos=%d\n", r.LineNumber, r.Offset)
 			logger.Log.Debug(fmt.Sprintf("Parsed COPY-FROM stdin block ending at line=%d/fpos=%d\n", r.LineNumber, r.Offset))
-			return
+			return nil
 		}
 		if r.EOF {
 			conv.Unexpected("Reached eof while parsing copy-block")
-			return
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
 		}
 		conv.StatsAddRow(srcTableName, conv.SchemaMode())
 		// We have to read the copy-block data so that we can process the remaining
@@ -193,7 +203,7 @@ func processCopyBlock(conv *internal.Conv, tableId string, commonColIds, srcCols
 		if err != nil {
 			conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
 			conv.StatsAddBadRow(srcTableName, conv.DataMode())
-			conv.CollectBadRow(srcTableName, srcCols, values)
+			conv.CollectBadRow(srcTableName, srcCols, values, err)
 			continue
 		}
 		ProcessDataRow(conv, tableId, commonColIds, newValues)
@@ -205,7 +215,7 @@ func processCopyBlock(conv *internal.Conv, tableId string, commonColIds, srcCols
 // copyOrInsert if a COPY-FROM or INSERT statement is encountered.
 // Note that the actual parsing/processing of COPY-FROM data blocks is
 // handled elsewhere (see process.go).
-func processStatements(conv *internal.Conv, rawStmts []*pg_query.RawStmt) *copyOrInsert {
+func processStatements(conv *internal.Conv, rawStmts []*pg_query.RawStmt, types userTypes) *copyOrInsert {
 	// Typically we'll have only one statement, but we handle the general case.
 	for i, rawStmt := range rawStmts {
 		node := rawStmt.Stmt
@@ -222,7 +232,19 @@ func processStatements(conv *internal.Conv, rawStmts []*pg_query.RawStmt) *copyO
 			return processCopyStmt(conv, n.CopyStmt)
 		case *pg_query.Node_CreateStmt:
 			if conv.SchemaMode() {
-				processCreateStmt(conv, n.CreateStmt)
+				processCreateStmt(conv, n.CreateStmt, types)
+			}
+		case *pg_query.Node_CreateEnumStmt:
+			if conv.SchemaMode() {
+				processCreateEnumStmt(conv, n.CreateEnumStmt, types)
+			}
+		case *pg_query.Node_CreateDomainStmt:
+			if conv.SchemaMode() {
+				processCreateDomainStmt(conv, n.CreateDomainStmt, types)
+			}
+		case *pg_query.Node_CompositeTypeStmt:
+			if conv.SchemaMode() {
+				processCompositeTypeStmt(conv, n.CompositeTypeStmt, types)
 			}
 		case *pg_query.Node_InsertStmt:
 			return processInsertStmt(conv, n.InsertStmt)
@@ -260,6 +282,10 @@ func processIndexStmt(conv *internal.Conv, n *pg_query.IndexStmt) {
 			Keys:   toIndexKeys(conv, n.Idxname, n.IndexParams, ctable.ColNameIdMap),
 		})
 		conv.SrcSchema[tbl.Id] = ctable
+	} else if _, err := getTableIdFromSrcName(conv, tableName); err == nil {
+		// Indexes of partitions are created for the indexes of their
+		// parent table.
+		conv.SkipStatement(printNodeType(n))
 	} else {
 		conv.Unexpected(fmt.Sprintf("Table %s not found while processing index statement", tableName))
 		conv.SkipStatement(printNodeType(n))
@@ -296,6 +322,14 @@ func processAlterTableStmt(conv *internal.Conv, n *pg_query.AlterTableStmt) {
 					default:
 						conv.SkipStatement(strings.Join([]string{printNodeType(n), printNodeType(t), printNodeType(at)}, "."))
 					}
+				case a.Subtype == pg_query.AlterTableType_AT_AttachPartition && a.Def.GetPartitionCmd() != nil:
+					partition, err := getTableName(conv, a.Def.GetPartitionCmd().Name)
+					if err != nil {
+						logStmtError(conv, n, fmt.Errorf("can't get partition name: %w", err))
+						continue
+					}
+					attachPartition(conv, tableName, partition)
+					conv.SchemaStatement(strings.Join([]string{printNodeType(n), printNodeType(t)}, "."))
 				default:
 					conv.SkipStatement(strings.Join([]string{printNodeType(n), printNodeType(t)}, "."))
 				}
@@ -315,7 +349,7 @@ func processAlterTableStmt(conv *internal.Conv, n *pg_query.AlterTableStmt) {
 	}
 }
 
-func processCreateStmt(conv *internal.Conv, n *pg_query.CreateStmt) {
+func processCreateStmt(conv *internal.Conv, n *pg_query.CreateStmt, types userTypes) {
 	colDef := make(map[string]schema.Column)
 	if n.Relation == nil {
 		logStmtError(conv, n, fmt.Errorf("relation is nil"))
@@ -326,6 +360,23 @@ func processCreateStmt(conv *internal.Conv, n *pg_query.CreateStmt) {
 		logStmtError(conv, n, fmt.Errorf("can't get table name: %w", err))
 		return
 	}
+	if n.Partbound != nil && len(n.InhRelations) == 1 {
+		// Partitions are created as "CREATE TABLE ... PARTITION OF parent":
+		// their data is migrated into the parent table.
+		parent, err := getTableName(conv, n.InhRelations[0].GetRangeVar())
+		if err != nil {
+			logStmtError(conv, n, fmt.Errorf("can't get parent table name: %w", err))
+			return
+		}
+		if _, err := getTableIdFromSrcName(conv, parent); err != nil {
+			conv.Unexpected(fmt.Sprintf("Table %s not found while processing partition %s", parent, table))
+			conv.SkipStatement(printNodeType(n))
+			return
+		}
+		conv.SchemaStatement(printNodeType(n))
+		attachPartition(conv, parent, table)
+		return
+	}
 	if len(n.InhRelations) > 0 {
 		// Skip inherited tables.
 		conv.SkipStatement(printNodeType(n))
@@ -341,7 +392,7 @@ func processCreateStmt(conv *internal.Conv, n *pg_query.CreateStmt) {
 	for _, te := range n.TableElts {
 		switch te.GetNode().(type) {
 		case *pg_query.Node_ColumnDef:
-			_, col, cdConstraints, err := processColumn(conv, te.GetColumnDef(), table)
+			_, col, cdConstraints, err := processColumn(conv, te.GetColumnDef(), table, types)
 			if err != nil {
 				logStmtError(conv, n, err)
 				return
@@ -369,12 +420,79 @@ func processCreateStmt(conv *internal.Conv, n *pg_query.CreateStmt) {
 		ColNameIdMap: colNameIdMap,
 		ColDefs:      colDef,
 	}
+	if n.Partspec != nil {
+		srcTable := conv.SrcSchema[tableId]
+		srcTable.PartitionBy = getPartitionBy(conv, n.Partspec)
+		conv.SrcSchema[tableId] = srcTable
+	}
 	// Note: constraints contains all info about primary keys, not-null keys
 	// and foreign keys.
 	updateSchema(conv, tableId, constraints, "CREATE TABLE")
 }
 
-func processColumn(conv *internal.Conv, n *pg_query.ColumnDef, table string) (string, schema.Column, []constraint, error) {
+// attachPartition collapses a partition into its parent table. Partitions
+// are either leaf tables, whose rows are migrated into the parent table, or
+// partitioned tables, whose own partitions move to the parent table. pg_dump
+// creates partitions as regular tables before attaching them, so the table of
+// the partition is dropped.
+func attachPartition(conv *internal.Conv, parent, partition string) {
+	parentId, err := getTableIdFromSrcName(conv, parent)
+	if err != nil {
+		conv.Unexpected(fmt.Sprintf("Table %s not found while attaching partition %s", parent, partition))
+		return
+	}
+	partitions := []string{partition}
+	if partitionId, err := internal.GetTableIdFromSrcName(conv.SrcSchema, partition); err == nil {
+		partitions = append(partitions, conv.SrcSchema[partitionId].Partitions...)
+		delete(conv.SrcSchema, partitionId)
+	}
+	parentTable := conv.SrcSchema[parentId]
+	parentTable.Partitions = append(parentTable.Partitions, partitions...)
+	conv.SrcSchema[parentId] = parentTable
+}
+
+// getPartitionBy returns the partition key of a partitioned table in the
+// format of pg_get_partkeydef e.g. "RANGE (logdate)".
+func getPartitionBy(conv *internal.Conv, n *pg_query.PartitionSpec) string {
+	var keys []string
+	for _, p := range n.PartParams {
+		e := p.GetPartitionElem()
+		if e == nil {
+			continue
+		}
+		if e.Name != "" {
+			keys = append(keys, e.Name)
+			continue
+		}
+		q, err := deparseExpr(e.Expr)
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't deparse partition key: %s", err))
+			continue
+		}
+		keys = append(keys, "("+q+")")
+	}
+	return fmt.Sprintf("%s (%s)", strings.ToUpper(n.Strategy), strings.Join(keys, ", "))
+}
+
+// getTableIdFromSrcName returns the id of a source table. Partitions are
+// collapsed into their parent table, so the id of the parent table is
+// returned for partitions.
+func getTableIdFromSrcName(conv *internal.Conv, table string) (string, error) {
+	tableId, err := internal.GetTableIdFromSrcName(conv.SrcSchema, table)
+	if err == nil {
+		return tableId, nil
+	}
+	for id, t := range conv.SrcSchema {
+		for _, p := range t.Partitions {
+			if p == table {
+				return id, nil
+			}
+		}
+	}
+	return "", err
+}
+
+func processColumn(conv *internal.Conv, n *pg_query.ColumnDef, table string, types userTypes) (string, schema.Column, []constraint, error) {
 	mods := getTypeMods(conv, n.TypeName.Typmods)
 	if n.Colname == "" {
 		return "", schema.Column{}, nil, fmt.Errorf("colname is empty string")
@@ -388,7 +506,137 @@ func processColumn(conv *internal.Conv, n *pg_query.ColumnDef, table string) (st
 		Name:        tid,
 		Mods:        mods,
 		ArrayBounds: getArrayBounds(conv, n.TypeName.ArrayBounds)}
-	return name, schema.Column{Name: name, Type: ty}, analyzeColDefConstraints(conv, printNodeType(n), table, n.Constraints, name), nil
+	ty, notNull, checks := types.resolve(ty)
+	col := schema.Column{Name: name, Type: ty, NotNull: notNull, Checks: checks}
+	return name, col, analyzeColDefConstraints(conv, printNodeType(n), table, n.Constraints, name), nil
+}
+
+// userType is a type defined by CREATE TYPE or CREATE DOMAIN.
+type userType struct {
+	ty      schema.Type // For domains, the base type of the domain.
+	domain  bool
+	notNull bool     // Domain NOT NULL constraint.
+	checks  []string // Domain CHECK constraints.
+}
+
+// userTypes maps the names of user-defined types to their definition.
+type userTypes map[string]userType
+
+// userTypeName normalizes type name, dropping the default "public" schema.
+func userTypeName(name string) string {
+	return strings.TrimPrefix(name, "public.")
+}
+
+// resolve resolves domains to their base type, collecting their NOT NULL
+// and CHECK constraints, and fills in the values of enums and the fields of
+// composite types. Other types are returned as is.
+func (ut userTypes) resolve(ty schema.Type) (schema.Type, bool, []string) {
+	var notNull bool
+	var checks []string
+	for {
+		t, ok := ut[userTypeName(ty.Name)]
+		if !ok {
+			return ty, notNull, checks
+		}
+		if !t.domain {
+			ty.Name, ty.EnumValues, ty.Fields = t.ty.Name, t.ty.EnumValues, t.ty.Fields
+			return ty, notNull, checks
+		}
+		// Constraints of a domain apply to the elements of arrays of the
+		// domain. These have no equivalent in Spanner for NOT NULL, and
+		// checks are dropped by ToSpannerChecks.
+		notNull = notNull || (t.notNull && len(ty.ArrayBounds) == 0)
+		checks = append(checks, t.checks...)
+		ty = schema.Type{Name: t.ty.Name, Mods: t.ty.Mods, ArrayBounds: append(ty.ArrayBounds, t.ty.ArrayBounds...)}
+	}
+}
+
+func processCreateEnumStmt(conv *internal.Conv, n *pg_query.CreateEnumStmt, types userTypes) {
+	name, err := getTypeID(n.TypeName)
+	if err != nil {
+		logStmtError(conv, n, fmt.Errorf("can't get type name: %w", err))
+		return
+	}
+	name = userTypeName(name)
+	var vals []string
+	for _, v := range n.Vals {
+		if v.GetString_() == nil {
+			logStmtError(conv, n, fmt.Errorf("found %s node while processing enum values", printNodeType(v.GetNode())))
+			return
+		}
+		vals = append(vals, v.GetString_().Str)
+	}
+	conv.SchemaStatement(printNodeType(n))
+	types[name] = userType{ty: schema.Type{Name: name, EnumValues: vals}}
+}
+
+func processCreateDomainStmt(conv *internal.Conv, n *pg_query.CreateDomainStmt, types userTypes) {
+	name, err := getTypeID(n.Domainname)
+	if err != nil {
+		logStmtError(conv, n, fmt.Errorf("can't get domain name: %w", err))
+		return
+	}
+	base, err := getTypeID(n.TypeName.Names)
+	if err != nil {
+		logStmtError(conv, n, fmt.Errorf("can't get type id for domain %s: %w", name, err))
+		return
+	}
+	t := userType{
+		ty: schema.Type{
+			Name:        base,
+			Mods:        getTypeMods(conv, n.TypeName.Typmods),
+			ArrayBounds: getArrayBounds(conv, n.TypeName.ArrayBounds)},
+		domain: true,
+	}
+	for _, c := range n.Constraints {
+		con := c.GetConstraint()
+		if con == nil {
+			conv.Unexpected(fmt.Sprintf("Found %s node while processing constraints of domain %s", printNodeType(c.GetNode()), name))
+			continue
+		}
+		switch con.Contype {
+		case pg_query.ConstrType_CONSTR_NOTNULL:
+			t.notNull = true
+		case pg_query.ConstrType_CONSTR_CHECK:
+			e, err := deparseExpr(con.RawExpr)
+			if err != nil {
+				conv.Unexpected(fmt.Sprintf("Can't deparse check constraint of domain %s: %s", name, err))
+				continue
+			}
+			t.checks = append(t.checks, e)
+		}
+	}
+	conv.SchemaStatement(printNodeType(n))
+	types[userTypeName(name)] = t
+}
+
+func processCompositeTypeStmt(conv *internal.Conv, n *pg_query.CompositeTypeStmt, types userTypes) {
+	if n.Typevar == nil {
+		logStmtError(conv, n, fmt.Errorf("typevar is nil"))
+		return
+	}
+	name, err := getTableName(conv, n.Typevar)
+	if err != nil {
+		logStmtError(conv, n, fmt.Errorf("can't get type name: %w", err))
+		return
+	}
+	name = userTypeName(name)
+	var fields []schema.Column
+	for _, f := range n.Coldeflist {
+		cd := f.GetColumnDef()
+		if cd == nil {
+			logStmtError(conv, n, fmt.Errorf("found %s node while processing fields of type %s", printNodeType(f.GetNode()), name))
+			return
+		}
+		_, field, _, err := processColumn(conv, cd, name, types)
+		if err != nil {
+			logStmtError(conv, n, err)
+			return
+		}
+		fields = append(fields, field)
+	}
+	conv.SchemaStatement(printNodeType(n))
+	types[name] = userType{ty: schema.Type{Name: name, Fields: fields}}
 }
 
 func processInsertStmt(conv *internal.Conv, n *pg_query.InsertStmt) *copyOrInsert {
@@ -401,7 +649,7 @@ func processInsertStmt(conv *internal.Conv, n *pg_query.InsertStmt) *copyOrInser
 		logStmtError(conv, n, fmt.Errorf("can't get table name: %w", err))
 		return nil
 	}
-	tableId, _ := internal.GetTableIdFromSrcName(conv.SrcSchema, table)
+	tableId, _ := getTableIdFromSrcName(conv, table)
 	if _, ok := conv.SrcSchema[tableId]; !ok {
 		// If we don't have schema information for a table, we drop all insert
 		// statements for it. The most likely reason we don't have schema information
@@ -449,7 +697,7 @@ func processCopyStmt(conv *internal.Conv, n *pg_query.CopyStmt) *copyOrInsert {
 		logStmtError(conv, n, fmt.Errorf("relation is nil"))
 	}
 	if !conv.SchemaMode() {
-		table, _ = internal.GetTableIdFromSrcName(conv.SrcSchema, table)
+		table, _ = getTableIdFromSrcName(conv, table)
 	}
 
 	if _, ok := conv.SrcSchema[table]; !ok {
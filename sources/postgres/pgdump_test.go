@@ -16,6 +16,7 @@ package postgres
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"math/big"
 	"math/bits"
@@ -1485,12 +1486,116 @@ func TestProcessPgDump_AddPrimaryKeys(t *testing.T) {
 	}
 }
 
+func TestProcessPgDump_Partitions(t *testing.T) {
+	cases := []struct {
+		name                string
+		input               string
+		expectedPartitionBy string
+		expectedPartitions  []string
+	}{
+		{
+			// pg_dump creates partitions as tables, and then attaches them.
+			name: "Attached partitions",
+			input: "CREATE TABLE measurement (city_id integer NOT NULL, logdate date NOT NULL, peaktemp integer) PARTITION BY RANGE (logdate);\n" +
+				"CREATE TABLE measurement_y2022 (city_id integer NOT NULL, logdate date NOT NULL, peaktemp integer);\n" +
+				"CREATE TABLE sales.measurement_y2023 (peaktemp integer, city_id integer NOT NULL, logdate date NOT NULL);\n" +
+				"ALTER TABLE ONLY measurement ATTACH PARTITION measurement_y2022 FOR VALUES FROM ('2022-01-01') TO ('2023-01-01');\n" +
+				"ALTER TABLE ONLY measurement ATTACH PARTITION sales.measurement_y2023 FOR VALUES FROM ('2023-01-01') TO ('2024-01-01');\n" +
+				"ALTER TABLE ONLY measurement ADD CONSTRAINT measurement_pkey PRIMARY KEY (city_id, logdate);\n" +
+				"ALTER TABLE ONLY measurement_y2022 ADD CONSTRAINT measurement_y2022_pkey PRIMARY KEY (city_id, logdate);\n" +
+				"CREATE INDEX measurement_y2022_peaktemp_idx ON measurement_y2022 (peaktemp);\n" +
+				"COPY measurement_y2022 (city_id, logdate, peaktemp) FROM stdin;\n" +
+				"1	2022-06-01	30\n" +
+				"\\.\n" +
+				"INSERT INTO sales.measurement_y2023 (peaktemp, city_id, logdate) VALUES (25, 2, '2023-06-01');\n",
+			expectedPartitionBy: "RANGE (logdate)",
+			expectedPartitions:  []string{"measurement_y2022", "sales.measurement_y2023"},
+		},
+		{
+			name: "Partitions of partitioned table",
+			input: "CREATE TABLE measurement (city_id integer NOT NULL, logdate date NOT NULL, peaktemp integer, PRIMARY KEY (city_id, logdate)) PARTITION BY LIST (city_id, (logdate + 1));\n" +
+				"CREATE TABLE measurement_y2022 PARTITION OF measurement FOR VALUES IN (1) PARTITION BY HASH (city_id);\n" +
+				"CREATE TABLE measurement_y2022_h0 PARTITION OF measurement_y2022 FOR VALUES WITH (MODULUS 2, REMAINDER 0);\n" +
+				"CREATE TABLE sales.measurement_y2023 PARTITION OF measurement FOR VALUES IN (2);\n" +
+				"COPY measurement_y2022_h0 (city_id, logdate, peaktemp) FROM stdin;\n" +
+				"1	2022-06-01	30\n" +
+				"\\.\n" +
+				"INSERT INTO sales.measurement_y2023 (peaktemp, city_id, logdate) VALUES (25, 2, '2023-06-01');\n",
+			expectedPartitionBy: "LIST (city_id, (logdate + 1))",
+			expectedPartitions:  []string{"measurement_y2022", "measurement_y2022_h0", "sales.measurement_y2023"},
+		},
+	}
+	for _, tc := range cases {
+		conv, rows := runProcessPgDump(tc.input)
+		noIssues(conv, t, tc.name)
+		assert.Equal(t, 1, len(conv.SrcSchema), tc.name)
+		tableId, err := internal.GetTableIdFromSrcName(conv.SrcSchema, "measurement")
+		assert.Nil(t, err, tc.name)
+		assert.Equal(t, tc.expectedPartitionBy, conv.SrcSchema[tableId].PartitionBy, tc.name)
+		assert.Equal(t, tc.expectedPartitions, conv.SrcSchema[tableId].Partitions, tc.name)
+		expectedSchema := map[string]ddl.CreateTable{
+			"measurement": {
+				Name:   "measurement",
+				ColIds: []string{"city_id", "logdate", "peaktemp"},
+				ColDefs: map[string]ddl.ColumnDef{
+					"city_id":  {Name: "city_id", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+					"logdate":  {Name: "logdate", T: ddl.Type{Name: ddl.Date}, NotNull: true},
+					"peaktemp": {Name: "peaktemp", T: ddl.Type{Name: ddl.Int64}},
+				},
+				PrimaryKeys: []ddl.IndexKey{{ColId: "city_id", Order: 1}, {ColId: "logdate", Order: 2}}}}
+		internal.AssertSpSchema(conv, t, expectedSchema, stripSchemaComments(conv.SpSchema))
+		assert.Equal(t, []spannerData{
+			{table: "measurement", cols: []string{"city_id", "logdate", "peaktemp"}, vals: []interface{}{int64(1), getDate("2022-06-01"), int64(30)}},
+			{table: "measurement", cols: []string{"peaktemp", "city_id", "logdate"}, vals: []interface{}{int64(25), int64(2), getDate("2023-06-01")}},
+		}, rows, tc.name)
+	}
+}
+
+func TestProcessPgDump_UserDefinedTypes(t *testing.T) {
+	input := "CREATE TYPE public.mood AS ENUM ('sad', 'ok', 'it''s ok');\n" +
+		"CREATE DOMAIN public.posint AS integer NOT NULL CONSTRAINT posint_check CHECK ((VALUE > 0));\n" +
+		"CREATE DOMAIN public.smallposint AS public.posint CONSTRAINT smallposint_check CHECK ((VALUE < 100));\n" +
+		"CREATE DOMAIN public.code AS character varying(5) CONSTRAINT code_check CHECK (((VALUE)::text ~~ 'A%'::text));\n" +
+		"CREATE DOMAIN public.word AS text CONSTRAINT word_check CHECK ((VALUE ~ '^[a-z]+$'::text));\n" +
+		"CREATE TYPE public.address AS (street text, num integer, ok boolean, m public.mood);\n" +
+		"CREATE TABLE public.t (id public.smallposint, m public.mood, c public.code, w public.word, a public.address, al public.address[], PRIMARY KEY (id));\n" +
+		"COPY public.t (id, m, c, w, a, al) FROM stdin;\n" +
+		"1	it's ok	AB	abc	(\"1 Main St\",10,t,sad)	{\"(x,1,f,)\",NULL}\n" +
+		"\\.\n"
+	conv, rows := runProcessPgDump(input)
+	noIssues(conv, t, "User-defined types")
+	expectedSchema := map[string]ddl.CreateTable{
+		"t": {
+			Name:   "t",
+			ColIds: []string{"id", "m", "c", "w", "a", "al"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"id": {Name: "id", T: ddl.Type{Name: ddl.Int64}, NotNull: true, Checks: []string{"VALUE < 100", "VALUE > 0"}},
+				"m":  {Name: "m", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, Checks: []string{`VALUE IN ('sad', 'ok', 'it\'s ok')`}},
+				"c":  {Name: "c", T: ddl.Type{Name: ddl.String, Len: 5}, Checks: []string{"VALUE LIKE 'A%'"}},
+				"w":  {Name: "w", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+				"a":  {Name: "a", T: ddl.Type{Name: ddl.JSON}},
+				"al": {Name: "al", T: ddl.Type{Name: ddl.JSON}},
+			},
+			PrimaryKeys: []ddl.IndexKey{{ColId: "id", Order: 1}}}}
+	internal.AssertSpSchema(conv, t, expectedSchema, stripSchemaComments(conv.SpSchema))
+	tableId, err := internal.GetTableIdFromSrcName(conv.SrcSchema, "t")
+	assert.Nil(t, err)
+	colId, err := internal.GetColIdFromSrcName(conv.SrcSchema[tableId].ColDefs, "w")
+	assert.Nil(t, err)
+	assert.Equal(t, []internal.SchemaIssue{internal.CheckConstraint}, conv.SchemaIssues[tableId][colId])
+	assert.Equal(t, []spannerData{
+		{table: "t", cols: []string{"id", "m", "c", "w", "a", "al"}, vals: []interface{}{int64(1), "it's ok", "AB", "abc",
+			`{"street":"1 Main St","num":10,"ok":true,"m":"sad"}`,
+			`[{"street":"x","num":1,"ok":false,"m":null},null]`}},
+	}, rows)
+}
+
 func TestProcessPgDump_WithUnparsableContent(t *testing.T) {
 	s := "This is unparsable content"
 	conv := internal.MakeConv()
 	conv.SetLocation(time.UTC)
 	conv.SetSchemaMode()
-	err := common.ProcessDbDump(conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), DbDumpImpl{})
+	err := common.ProcessDbDump(context.Background(), conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), DbDumpImpl{})
 	if err == nil {
 		t.Fatalf("Expect an error, but got nil")
 	}
@@ -1499,19 +1604,46 @@ func TestProcessPgDump_WithUnparsableContent(t *testing.T) {
 	}
 }
 
+func TestProcessPgDump_Cancelled(t *testing.T) {
+	s := `
+CREATE TABLE test (id integer PRIMARY KEY, a text);
+COPY test (id, a) FROM stdin;
+1	x
+2	y
+3	z
+\.
+`
+	conv := internal.MakeConv()
+	conv.SetLocation(time.UTC)
+	conv.SetSchemaMode()
+	assert.Nil(t, common.ProcessDbDump(context.Background(), conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), DbDumpImpl{}))
+	conv.SetDataMode()
+	// Reading stops in the middle of the COPY block once ctx is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	var rows []spannerData
+	conv.SetDataSink(
+		func(table string, cols []string, vals []interface{}) {
+			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
+			cancel()
+		})
+	err := common.ProcessDbDump(ctx, conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), DbDumpImpl{})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, []spannerData{{table: "test", cols: []string{"id", "a"}, vals: []interface{}{int64(1), "x"}}}, rows)
+}
+
 func runProcessPgDump(s string) (*internal.Conv, []spannerData) {
 	conv := internal.MakeConv()
 	conv.SetLocation(time.UTC)
 	conv.SetSchemaMode()
 	pgDump := DbDumpImpl{}
-	common.ProcessDbDump(conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), pgDump)
+	common.ProcessDbDump(context.Background(), conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), pgDump)
 	conv.SetDataMode()
 	var rows []spannerData
 	conv.SetDataSink(
 		func(table string, cols []string, vals []interface{}) {
 			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
 		})
-	common.ProcessDbDump(conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), pgDump)
+	common.ProcessDbDump(context.Background(), conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), pgDump)
 	return conv, rows
 }
 
@@ -1521,14 +1653,14 @@ func runProcessPgDumpPGTarget(s string) (*internal.Conv, []spannerData) {
 	conv.SetLocation(time.UTC)
 	conv.SetSchemaMode()
 	pgDump := DbDumpImpl{}
-	common.ProcessDbDump(conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), pgDump)
+	common.ProcessDbDump(context.Background(), conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), pgDump)
 	conv.SetDataMode()
 	var rows []spannerData
 	conv.SetDataSink(
 		func(table string, cols []string, vals []interface{}) {
 			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
 		})
-	common.ProcessDbDump(conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), pgDump)
+	common.ProcessDbDump(context.Background(), conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), pgDump)
 	return conv, rows
 }
 
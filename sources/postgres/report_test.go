@@ -17,6 +17,7 @@ package postgres
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"path/filepath"
@@ -53,7 +54,7 @@ func TestReport(t *testing.T) {
             c text);`
 	conv := internal.MakeConv()
 	conv.SetSchemaMode()
-	common.ProcessDbDump(conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), DbDumpImpl{})
+	common.ProcessDbDump(context.Background(), conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), DbDumpImpl{})
 	conv.SetDataMode()
 
 	badSchemaTableId, err := internal.GetTableIdFromSpName(conv.SpSchema, "bad_schema")
@@ -83,3 +84,25 @@ func TestReport(t *testing.T) {
 	actual := buf.String()
 	assert.Equal(t, expected, actual)
 }
+
+func TestReport_Partitions(t *testing.T) {
+	s := `
+        CREATE TABLE measurement (
+            city_id integer,
+            logdate date,
+            PRIMARY KEY (city_id, logdate)) PARTITION BY RANGE (logdate);
+        CREATE TABLE measurement_y2022 PARTITION OF measurement FOR VALUES FROM ('2022-01-01') TO ('2023-01-01');
+        CREATE TABLE measurement_y2023 PARTITION OF measurement FOR VALUES FROM ('2023-01-01') TO ('2024-01-01');`
+	conv := internal.MakeConv()
+	conv.SetSchemaMode()
+	common.ProcessDbDump(context.Background(), conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), DbDumpImpl{})
+	conv.Audit = internal.Audit{
+		MigrationType: migration.MigrationData_SCHEMA_ONLY.Enum(),
+	}
+	report := reports.GenerateStructuredReport(constants.PGDUMP, "sampleDB", conv, nil, true, true)
+	assert.Equal(t, 1, len(report.TableReports))
+	assert.Contains(t, report.TableReports[0].Warnings, reports.Warnings{
+		WarningType: "Note",
+		WarningList: []string{"Table 'measurement' is partitioned by RANGE (logdate) and the data of its 2 partitions is migrated into this table. Consider whether the partition key should lead the primary key"},
+	})
+}
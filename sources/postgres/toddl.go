@@ -16,6 +16,8 @@
 package postgres
 
 import (
+	"strings"
+
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
@@ -33,11 +35,12 @@ type ToDdlImpl struct {
 // conversion issues encountered.
 func (tdi ToDdlImpl) ToSpannerType(conv *internal.Conv, spType string, srcType schema.Type) (ddl.Type, []internal.SchemaIssue) {
 	ty, issues := toSpannerTypeInternal(srcType, spType)
-	if len(srcType.ArrayBounds) > 1 {
+	if len(srcType.ArrayBounds) > 1 && len(srcType.Fields) == 0 {
 		ty = ddl.Type{Name: ddl.String, Len: ddl.MaxLength}
 		issues = append(issues, internal.MultiDimensionalArray)
 	}
-	ty.IsArray = len(srcType.ArrayBounds) == 1
+	// Arrays of composite types map to a single JSON array.
+	ty.IsArray = len(srcType.ArrayBounds) == 1 && len(srcType.Fields) == 0
 	if conv.SpDialect == constants.DIALECT_POSTGRESQL {
 		ty = common.ToPGDialectType(ty)
 	}
@@ -53,6 +56,19 @@ func (tdi ToDdlImpl) ToSpannerType(conv *internal.Conv, spType string, srcType s
 // then it will be used to build the returned ddl.Type. If not, the default
 // Spanner type for this source type will be used.
 func toSpannerTypeInternal(srcType schema.Type, spType string) (ddl.Type, []internal.SchemaIssue) {
+	// User-defined enum and composite types. Domains are resolved to
+	// their base type when the source schema is read.
+	switch {
+	case len(srcType.EnumValues) > 0:
+		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
+	case len(srcType.Fields) > 0:
+		switch spType {
+		case ddl.String:
+			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
+		default:
+			return ddl.Type{Name: ddl.JSON}, nil
+		}
+	}
 	switch srcType.Name {
 	case "bool", "boolean":
 		switch spType {
@@ -194,3 +210,35 @@ func toSpannerTypeInternal(srcType schema.Type, spType string) (ddl.Type, []inte
 	}
 	return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.NoGoodType}
 }
+
+// ToSpannerChecks maps the allowed values of an enum and the CHECK
+// constraints of a domain into Spanner check constraints. Constraints are
+// only kept when the column has its default Spanner type, and when they can
+// be translated.
+func (tdi ToDdlImpl) ToSpannerChecks(conv *internal.Conv, srcCol schema.Column, spType ddl.Type) ([]string, []internal.SchemaIssue) {
+	if len(srcCol.Type.EnumValues) == 0 && len(srcCol.Checks) == 0 {
+		return nil, nil
+	}
+	if ty, _ := tdi.ToSpannerType(conv, "", srcCol.Type); ty != spType || spType.IsArray {
+		return nil, []internal.SchemaIssue{internal.CheckConstraint}
+	}
+	pg := conv.SpDialect == constants.DIALECT_POSTGRESQL
+	var checks []string
+	if len(srcCol.Type.EnumValues) > 0 {
+		var vals []string
+		for _, v := range srcCol.Type.EnumValues {
+			vals = append(vals, common.SpannerLiteral(v, pg))
+		}
+		checks = append(checks, "VALUE IN ("+strings.Join(vals, ", ")+")")
+	}
+	var issues []internal.SchemaIssue
+	for _, c := range srcCol.Checks {
+		e, err := translateCheck(c, pg)
+		if err != nil {
+			issues = []internal.SchemaIssue{internal.CheckConstraint}
+			continue
+		}
+		checks = append(checks, e)
+	}
+	return checks, issues
+}
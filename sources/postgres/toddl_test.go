@@ -192,3 +192,34 @@ func dropComments(t *ddl.CreateTable) {
 		t.ColDefs[c] = cd
 	}
 }
+
+func TestToSpannerChecks(t *testing.T) {
+	enum := schema.Type{Name: "mood", EnumValues: []string{"sad", "it's ok"}}
+	tests := []struct {
+		name    string
+		dialect string
+		col     schema.Column
+		spType  ddl.Type
+		checks  []string
+		issues  []internal.SchemaIssue
+	}{
+		{"enum", constants.DIALECT_GOOGLESQL, schema.Column{Type: enum}, ddl.Type{Name: ddl.String, Len: ddl.MaxLength},
+			[]string{`VALUE IN ('sad', 'it\'s ok')`}, nil},
+		{"enum pg", constants.DIALECT_POSTGRESQL, schema.Column{Type: enum}, ddl.Type{Name: ddl.String, Len: ddl.MaxLength},
+			[]string{`VALUE IN ('sad', 'it''s ok')`}, nil},
+		{"enum array", constants.DIALECT_GOOGLESQL, schema.Column{Type: schema.Type{Name: "mood", EnumValues: enum.EnumValues, ArrayBounds: []int64{-1}}},
+			ddl.Type{Name: ddl.String, Len: ddl.MaxLength, IsArray: true}, nil, []internal.SchemaIssue{internal.CheckConstraint}},
+		{"enum as bytes", constants.DIALECT_GOOGLESQL, schema.Column{Type: enum}, ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength},
+			nil, []internal.SchemaIssue{internal.CheckConstraint}},
+		{"domain", constants.DIALECT_GOOGLESQL, schema.Column{Type: schema.Type{Name: "int4"}, Checks: []string{"(VALUE > 0)", "(VALUE ~ '1')"}},
+			ddl.Type{Name: ddl.Int64}, []string{"VALUE > 0"}, []internal.SchemaIssue{internal.CheckConstraint}},
+		{"no checks", constants.DIALECT_GOOGLESQL, schema.Column{Type: schema.Type{Name: "int4"}}, ddl.Type{Name: ddl.Int64}, nil, nil},
+	}
+	for _, tc := range tests {
+		conv := internal.MakeConv()
+		conv.SpDialect = tc.dialect
+		checks, issues := ToDdlImpl{}.ToSpannerChecks(conv, tc.col, tc.spType)
+		assert.Equal(t, tc.checks, checks, tc.name)
+		assert.Equal(t, tc.issues, issues, tc.name)
+	}
+}
@@ -0,0 +1,423 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanner
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// ProcessDataRow converts a row read from the source Spanner database and
+// writes it to the target Spanner database.
+func ProcessDataRow(conv *internal.Conv, tableId string, colIds []string, srcSchema schema.Table, spSchema ddl.CreateTable, row *spanner.Row) {
+	spTableName, cvtCols, cvtVals, err := ConvertData(conv, tableId, colIds, srcSchema, spSchema, row)
+	srcTableName := conv.SrcSchema[tableId].Name
+	if err != nil {
+		conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
+		conv.StatsAddBadRow(srcTableName, conv.DataMode())
+		conv.CollectBadRow(srcTableName, row.ColumnNames(), rowToStrings(row), err)
+	} else {
+		conv.WriteRow(srcTableName, spTableName, cvtCols, cvtVals)
+	}
+}
+
+// ConvertData maps the source Spanner row into target Spanner types and
+// values. Values are decoded from the wire format of the source database and
+// re-encoded for the dialect of the target database.
+func ConvertData(conv *internal.Conv, tableId string, colIds []string, srcSchema schema.Table, spSchema ddl.CreateTable, row *spanner.Row) (string, []string, []interface{}, error) {
+	var c []string
+	var v []interface{}
+	for _, colId := range colIds {
+		spColDef, ok1 := spSchema.ColDefs[colId]
+		srcColDef, ok2 := srcSchema.ColDefs[colId]
+		if !ok1 || !ok2 {
+			return "", []string{}, []interface{}{}, fmt.Errorf("can't find Spanner and source-db schema for colId %s", colId)
+		}
+		i, err := row.ColumnIndex(srcColDef.Name)
+		if err != nil {
+			return "", []string{}, []interface{}{}, err
+		}
+		var gcv spanner.GenericColumnValue
+		if err := row.Column(i, &gcv); err != nil {
+			return "", []string{}, []interface{}{}, err
+		}
+		val, err := decodeValue(gcv.Type, gcv.Value)
+		if err != nil {
+			return "", []string{}, []interface{}{}, fmt.Errorf("can't decode column %s: %w", srcColDef.Name, err)
+		}
+		// Skip columns with 'NULL' values.
+		if val == nil {
+			continue
+		}
+		var x interface{}
+		if a, ok := val.([]interface{}); ok {
+			x, err = convArray(conv, spColDef.T, a)
+		} else {
+			x, err = convScalar(conv, spColDef.T, val)
+		}
+		if err != nil {
+			return "", []string{}, []interface{}{}, fmt.Errorf("can't convert column %s: %w", srcColDef.Name, err)
+		}
+		v = append(v, x)
+		c = append(c, spColDef.Name)
+	}
+	if aux, ok := conv.SyntheticPKeys[tableId]; ok {
+		c = append(c, conv.SpSchema[tableId].ColDefs[aux.ColId].Name)
+		v = append(v, fmt.Sprintf("%d", int64(bits.Reverse64(uint64(aux.Sequence)))))
+		aux.Sequence++
+		conv.SyntheticPKeys[tableId] = aux
+	}
+	return conv.SpSchema[tableId].Name, c, v, nil
+}
+
+// decodeValue decodes a value in the Spanner wire format into a
+// dialect-independent Go value: nil for NULL, bool, int64, float64, []byte,
+// civil.Date, time.Time, string (for STRING, NUMERIC and JSON values) or
+// []interface{} for arrays.
+func decodeValue(t *sppb.Type, v *structpb.Value) (interface{}, error) {
+	if _, ok := v.GetKind().(*structpb.Value_NullValue); ok {
+		return nil, nil
+	}
+	switch t.GetCode() {
+	case sppb.TypeCode_ARRAY:
+		l := v.GetListValue()
+		if l == nil {
+			return nil, fmt.Errorf("expected list value for %v", t)
+		}
+		a := []interface{}{}
+		for _, e := range l.GetValues() {
+			x, err := decodeValue(t.GetArrayElementType(), e)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, x)
+		}
+		return a, nil
+	case sppb.TypeCode_BOOL:
+		b, ok := v.GetKind().(*structpb.Value_BoolValue)
+		if !ok {
+			return nil, fmt.Errorf("expected bool value for %v", t)
+		}
+		return b.BoolValue, nil
+	case sppb.TypeCode_FLOAT64:
+		switch x := v.GetKind().(type) {
+		case *structpb.Value_NumberValue:
+			return x.NumberValue, nil
+		case *structpb.Value_StringValue:
+			// NaN and infinities are encoded as strings.
+			switch x.StringValue {
+			case "NaN":
+				return math.NaN(), nil
+			case "Infinity":
+				return math.Inf(1), nil
+			case "-Infinity":
+				return math.Inf(-1), nil
+			}
+		}
+		return nil, fmt.Errorf("expected float64 value for %v", t)
+	}
+	// All remaining types are encoded as strings.
+	s, ok := v.GetKind().(*structpb.Value_StringValue)
+	if !ok {
+		return nil, fmt.Errorf("expected string value for %v", t)
+	}
+	switch t.GetCode() {
+	case sppb.TypeCode_INT64:
+		return strconv.ParseInt(s.StringValue, 10, 64)
+	case sppb.TypeCode_BYTES:
+		return base64.StdEncoding.DecodeString(s.StringValue)
+	case sppb.TypeCode_DATE:
+		return civil.ParseDate(s.StringValue)
+	case sppb.TypeCode_TIMESTAMP:
+		return time.Parse(time.RFC3339Nano, s.StringValue)
+	case sppb.TypeCode_STRING, sppb.TypeCode_NUMERIC, sppb.TypeCode_JSON:
+		return s.StringValue, nil
+	}
+	return nil, fmt.Errorf("data conversion not implemented for type %v", t.GetCode())
+}
+
+// convScalar converts a decoded source value to an appropriate Spanner
+// value for the target database. It is the caller's responsibility to
+// detect and handle NULL values.
+func convScalar(conv *internal.Conv, spannerType ddl.Type, val interface{}) (interface{}, error) {
+	switch spannerType.Name {
+	case ddl.String:
+		return formatScalar(val), nil
+	case ddl.Numeric:
+		if s, ok := val.(string); ok {
+			return convNumeric(conv, s)
+		}
+	case ddl.JSON:
+		if s, ok := val.(string); ok {
+			return s, nil
+		}
+	case ddl.Bool:
+		if b, ok := val.(bool); ok {
+			return b, nil
+		}
+	case ddl.Bytes:
+		if b, ok := val.([]byte); ok {
+			return b, nil
+		}
+	case ddl.Date:
+		if d, ok := val.(civil.Date); ok {
+			return d, nil
+		}
+	case ddl.Float64:
+		if f, ok := val.(float64); ok {
+			return f, nil
+		}
+	case ddl.Int64:
+		if i, ok := val.(int64); ok {
+			return i, nil
+		}
+	case ddl.Timestamp:
+		if t, ok := val.(time.Time); ok {
+			return t, nil
+		}
+	default:
+		return val, fmt.Errorf("data conversion not implemented for type %v", spannerType.Name)
+	}
+	return val, fmt.Errorf("can't convert value of type %T to %v", val, spannerType.Name)
+}
+
+// convArray converts a decoded source array to a typed slice for the target
+// database. Arrays written to PostgreSQL-dialect databases, which map array
+// columns to strings, are written as JSON arrays.
+func convArray(conv *internal.Conv, spannerType ddl.Type, vals []interface{}) (interface{}, error) {
+	if !spannerType.IsArray {
+		if spannerType.Name != ddl.String {
+			return nil, fmt.Errorf("can't convert array to %v", spannerType.Name)
+		}
+		b, err := json.Marshal(vals)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	}
+	// The Spanner client for go does not accept []interface{} for arrays.
+	// Instead it only accepts slices of a specific type e.g. []int64, []string.
+	// Hence we have to do the following case analysis.
+	switch spannerType.Name {
+	case ddl.Bool:
+		r := []spanner.NullBool{}
+		for _, v := range vals {
+			if v == nil {
+				r = append(r, spanner.NullBool{Valid: false})
+				continue
+			}
+			x, err := convScalar(conv, spannerType, v)
+			if err != nil {
+				return []spanner.NullBool{}, err
+			}
+			r = append(r, spanner.NullBool{Bool: x.(bool), Valid: true})
+		}
+		return r, nil
+	case ddl.Bytes:
+		r := [][]byte{}
+		for _, v := range vals {
+			if v == nil {
+				r = append(r, nil)
+				continue
+			}
+			x, err := convScalar(conv, spannerType, v)
+			if err != nil {
+				return [][]byte{}, err
+			}
+			r = append(r, x.([]byte))
+		}
+		return r, nil
+	case ddl.Date:
+		r := []spanner.NullDate{}
+		for _, v := range vals {
+			if v == nil {
+				r = append(r, spanner.NullDate{Valid: false})
+				continue
+			}
+			x, err := convScalar(conv, spannerType, v)
+			if err != nil {
+				return []spanner.NullDate{}, err
+			}
+			r = append(r, spanner.NullDate{Date: x.(civil.Date), Valid: true})
+		}
+		return r, nil
+	case ddl.Float64:
+		r := []spanner.NullFloat64{}
+		for _, v := range vals {
+			if v == nil {
+				r = append(r, spanner.NullFloat64{Valid: false})
+				continue
+			}
+			x, err := convScalar(conv, spannerType, v)
+			if err != nil {
+				return []spanner.NullFloat64{}, err
+			}
+			r = append(r, spanner.NullFloat64{Float64: x.(float64), Valid: true})
+		}
+		return r, nil
+	case ddl.Int64:
+		r := []spanner.NullInt64{}
+		for _, v := range vals {
+			if v == nil {
+				r = append(r, spanner.NullInt64{Valid: false})
+				continue
+			}
+			x, err := convScalar(conv, spannerType, v)
+			if err != nil {
+				return []spanner.NullInt64{}, err
+			}
+			r = append(r, spanner.NullInt64{Int64: x.(int64), Valid: true})
+		}
+		return r, nil
+	case ddl.JSON:
+		r := []spanner.NullJSON{}
+		for _, v := range vals {
+			if v == nil {
+				r = append(r, spanner.NullJSON{Valid: false})
+				continue
+			}
+			x, err := convScalar(conv, spannerType, v)
+			if err != nil {
+				return []spanner.NullJSON{}, err
+			}
+			r = append(r, spanner.NullJSON{Value: json.RawMessage(x.(string)), Valid: true})
+		}
+		return r, nil
+	case ddl.Numeric:
+		r := []spanner.NullNumeric{}
+		for _, v := range vals {
+			if v == nil {
+				r = append(r, spanner.NullNumeric{Valid: false})
+				continue
+			}
+			s, ok := v.(string)
+			if !ok {
+				return []spanner.NullNumeric{}, fmt.Errorf("can't convert value of type %T to %v", v, spannerType.Name)
+			}
+			n := new(big.Rat)
+			if _, ok := n.SetString(s); !ok {
+				return []spanner.NullNumeric{}, fmt.Errorf("can't convert %q to big.Rat", s)
+			}
+			r = append(r, spanner.NullNumeric{Numeric: *n, Valid: true})
+		}
+		return r, nil
+	case ddl.String:
+		r := []spanner.NullString{}
+		for _, v := range vals {
+			if v == nil {
+				r = append(r, spanner.NullString{Valid: false})
+				continue
+			}
+			r = append(r, spanner.NullString{StringVal: formatScalar(v), Valid: true})
+		}
+		return r, nil
+	case ddl.Timestamp:
+		r := []spanner.NullTime{}
+		for _, v := range vals {
+			if v == nil {
+				r = append(r, spanner.NullTime{Valid: false})
+				continue
+			}
+			x, err := convScalar(conv, spannerType, v)
+			if err != nil {
+				return []spanner.NullTime{}, err
+			}
+			r = append(r, spanner.NullTime{Time: x.(time.Time), Valid: true})
+		}
+		return r, nil
+	}
+	return []interface{}{}, fmt.Errorf("array type conversion not implemented for type %v", spannerType.Name)
+}
+
+// convNumeric converts a numeric string to a value for the dialect of the
+// target database.
+func convNumeric(conv *internal.Conv, val string) (interface{}, error) {
+	if conv.SpDialect == constants.DIALECT_POSTGRESQL {
+		return spanner.PGNumeric{Numeric: val, Valid: true}, nil
+	}
+	r := new(big.Rat)
+	if _, ok := r.SetString(val); !ok {
+		return "", fmt.Errorf("can't convert %q to big.Rat", val)
+	}
+	return r, nil
+}
+
+// formatScalar returns the string representation of a decoded value, as
+// used when a column is mapped to STRING.
+func formatScalar(val interface{}) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case civil.Date:
+		return v.String()
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprintf("%v", val)
+}
+
+// rowToStrings returns the values of row as strings, for bad row reporting.
+func rowToStrings(row *spanner.Row) []string {
+	var vals []string
+	for i := 0; i < row.Size(); i++ {
+		var gcv spanner.GenericColumnValue
+		if err := row.Column(i, &gcv); err != nil {
+			vals = append(vals, "")
+			continue
+		}
+		v, err := decodeValue(gcv.Type, gcv.Value)
+		switch {
+		case err != nil:
+			vals = append(vals, gcv.Value.String())
+		case v == nil:
+			vals = append(vals, "NULL")
+		case isArray(v):
+			b, _ := json.Marshal(v)
+			vals = append(vals, string(b))
+		default:
+			vals = append(vals, formatScalar(v))
+		}
+	}
+	return vals
+}
+
+func isArray(v interface{}) bool {
+	_, ok := v.([]interface{})
+	return ok
+}
@@ -0,0 +1,175 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanner
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+type spannerData struct {
+	table string
+	cols  []string
+	vals  []interface{}
+}
+
+// Basic smoke test of ProcessDataRow. The core part of this code path
+// (ConvertData) is tested in TestConvertData.
+func TestProcessDataRow(t *testing.T) {
+	tableName := "testtable"
+	tableId := "t1"
+	colIds := []string{"c1", "c2", "c3"}
+	conv := buildConv(
+		ddl.CreateTable{
+			Name:   tableName,
+			Id:     tableId,
+			ColIds: colIds,
+			ColDefs: map[string]ddl.ColumnDef{
+				"c1": {Name: "a", Id: "c1", T: ddl.Type{Name: ddl.Float64}},
+				"c2": {Name: "b", Id: "c2", T: ddl.Type{Name: ddl.Int64}},
+				"c3": {Name: "c", Id: "c3", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			}},
+		schema.Table{
+			Name:   tableName,
+			Id:     tableId,
+			ColIds: colIds,
+			ColDefs: map[string]schema.Column{
+				"c1": {Name: "a", Id: "c1", Type: schema.Type{Name: "FLOAT64"}},
+				"c2": {Name: "b", Id: "c2", Type: schema.Type{Name: "INT64"}},
+				"c3": {Name: "c", Id: "c3", Type: schema.Type{Name: "STRING", Mods: []int64{ddl.MaxLength}}},
+			}})
+	conv.SetDataMode()
+	var rows []spannerData
+	conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+		rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
+	})
+	row, err := spanner.NewRow([]string{"a", "b", "c"}, []interface{}{4.2, int64(6), spanner.NullString{}})
+	assert.Nil(t, err)
+	ProcessDataRow(conv, tableId, colIds, conv.SrcSchema[tableId], conv.SpSchema[tableId], row)
+	// NULL values are skipped.
+	assert.Equal(t, []spannerData{{table: tableName, cols: []string{"a", "b"}, vals: []interface{}{float64(4.2), int64(6)}}}, rows)
+}
+
+func TestConvertData(t *testing.T) {
+	ts := time.Date(2019, 10, 29, 5, 30, 0, 123456000, time.UTC)
+	date := civil.Date{Year: 2019, Month: 10, Day: 29}
+	singleColTests := []struct {
+		name     string
+		ty       ddl.Type
+		pgTarget bool
+		in       interface{} // Source value, as written with the Spanner client.
+		e        interface{} // Expected result.
+	}{
+		{"bool", ddl.Type{Name: ddl.Bool}, false, true, true},
+		{"bytes", ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, false, []byte{0x89, 0x50}, []byte{0x89, 0x50}},
+		{"date", ddl.Type{Name: ddl.Date}, false, date, date},
+		{"float64", ddl.Type{Name: ddl.Float64}, false, 42.6, float64(42.6)},
+		{"int64", ddl.Type{Name: ddl.Int64}, false, int64(42), int64(42)},
+		{"string", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, false, "eh", "eh"},
+		{"timestamp", ddl.Type{Name: ddl.Timestamp}, false, ts, ts},
+		{"json", ddl.Type{Name: ddl.JSON}, false, spanner.NullJSON{Value: map[string]int{"a": 1}, Valid: true}, `{"a":1}`},
+		{"numeric", ddl.Type{Name: ddl.Numeric}, false, big.NewRat(2345, 10), big.NewRat(2345, 10)},
+		// GoogleSQL to PostgreSQL dialect.
+		{"numeric to pg", ddl.Type{Name: ddl.Numeric}, true, big.NewRat(2345, 10), spanner.PGNumeric{Numeric: "234.500000000", Valid: true}},
+		{"json to pg", ddl.Type{Name: ddl.JSON}, true, spanner.NullJSON{Value: []int{1, 2}, Valid: true}, "[1,2]"},
+		{"array to pg", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, true, []spanner.NullInt64{{Int64: 1, Valid: true}, {}}, "[1,null]"},
+		// PostgreSQL to GoogleSQL dialect.
+		{"pg numeric", ddl.Type{Name: ddl.Numeric}, false, spanner.PGNumeric{Numeric: "12.5", Valid: true}, big.NewRat(25, 2)},
+		{"pg jsonb", ddl.Type{Name: ddl.JSON}, false, spanner.PGJsonB{Value: map[string]bool{"b": true}, Valid: true}, `{"b":true}`},
+		// Widened to STRING.
+		{"int64 to string", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, false, int64(42), "42"},
+		{"timestamp to string", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, false, ts, "2019-10-29T05:30:00.123456Z"},
+		// Arrays.
+		{"int64 array", ddl.Type{Name: ddl.Int64, IsArray: true}, false, []int64{1, 2}, []spanner.NullInt64{{Int64: 1, Valid: true}, {Int64: 2, Valid: true}}},
+		{"string array", ddl.Type{Name: ddl.String, Len: ddl.MaxLength, IsArray: true}, false, []spanner.NullString{{StringVal: "x", Valid: true}, {}}, []spanner.NullString{{StringVal: "x", Valid: true}, {}}},
+		{"empty array", ddl.Type{Name: ddl.Date, IsArray: true}, false, []civil.Date{}, []spanner.NullDate{}},
+		{"json array", ddl.Type{Name: ddl.JSON, IsArray: true}, false, []spanner.NullJSON{{Value: 1, Valid: true}}, []spanner.NullJSON{{Value: json.RawMessage("1"), Valid: true}}},
+	}
+	tableName := "testtable"
+	tableId := "t1"
+	for _, tc := range singleColTests {
+		col := "a"
+		colId := "c1"
+		conv := buildConv(
+			ddl.CreateTable{
+				Name:        tableName,
+				Id:          tableId,
+				ColIds:      []string{colId},
+				ColDefs:     map[string]ddl.ColumnDef{colId: {Name: col, Id: colId, T: tc.ty}},
+				PrimaryKeys: []ddl.IndexKey{}},
+			schema.Table{
+				Name:    tableName,
+				Id:      tableId,
+				ColIds:  []string{colId},
+				ColDefs: map[string]schema.Column{colId: {Name: col, Id: colId}}})
+		if tc.pgTarget {
+			conv.SpDialect = constants.DIALECT_POSTGRESQL
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			row, err := spanner.NewRow([]string{col}, []interface{}{tc.in})
+			assert.Nil(t, err)
+			at, ac, av, err := ConvertData(conv, tableId, []string{colId}, conv.SrcSchema[tableId], conv.SpSchema[tableId], row)
+			checkResults(t, at, ac, av, err, tableName, []string{col}, []interface{}{tc.e}, tc.name)
+		})
+	}
+}
+
+func TestConvertError(t *testing.T) {
+	errorTests := []struct {
+		name string
+		ty   ddl.Type
+		in   interface{}
+	}{
+		{"int64 to bool", ddl.Type{Name: ddl.Bool}, int64(1)},
+		{"bytes to int64", ddl.Type{Name: ddl.Int64}, []byte{1}},
+		{"pg NaN to numeric", ddl.Type{Name: ddl.Numeric}, spanner.PGNumeric{Numeric: "NaN", Valid: true}},
+		{"array to int64", ddl.Type{Name: ddl.Int64}, []int64{1}},
+	}
+	conv := internal.MakeConv()
+	conv.SpSchema["t1"] = ddl.CreateTable{Name: "t", Id: "t1"}
+	for _, tc := range errorTests {
+		row, err := spanner.NewRow([]string{"a"}, []interface{}{tc.in})
+		assert.Nil(t, err, tc.name)
+		spTable := ddl.CreateTable{ColDefs: map[string]ddl.ColumnDef{"c1": {Name: "a", T: tc.ty}}}
+		srcTable := schema.Table{ColDefs: map[string]schema.Column{"c1": {Name: "a"}}}
+		_, _, _, err = ConvertData(conv, "t1", []string{"c1"}, srcTable, spTable, row)
+		assert.NotNil(t, err, tc.name)
+	}
+}
+
+func buildConv(spTable ddl.CreateTable, srcTable schema.Table) *internal.Conv {
+	conv := internal.MakeConv()
+	conv.SpSchema[spTable.Id] = spTable
+	conv.SrcSchema[srcTable.Id] = srcTable
+	return conv
+}
+
+func checkResults(t *testing.T, atable string, acols []string, avals []interface{}, err error, etable string, ecols []string, evals []interface{}, name string) {
+	assert.Nil(t, err, name)
+	assert.Equal(t, etable, atable, name+": table mismatch")
+	assert.Equal(t, ecols, acols, name+": column mismatch")
+	assert.Equal(t, evals, avals, name+": value mismatch")
+}
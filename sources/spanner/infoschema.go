@@ -21,6 +21,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"cloud.google.com/go/spanner"
 	_ "github.com/lib/pq" // we will use database/sql package instead of using this package directly
@@ -33,11 +34,15 @@ import (
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
 )
 
-// InfoSchemaImpl postgres specific implementation for InfoSchema.
+// InfoSchemaImpl Spanner specific implementation for InfoSchema.
 type InfoSchemaImpl struct {
 	Client    *spanner.Client
 	Ctx       context.Context
 	SpDialect string
+	// ReadTimestamp is the timestamp at which row counts and data are read,
+	// so that all tables are migrated from the same consistent snapshot. If
+	// it is zero, strong reads are used instead.
+	ReadTimestamp time.Time
 }
 
 // GetToDdl function below implement the common.InfoSchema interface.
@@ -45,18 +50,36 @@ func (isi InfoSchemaImpl) GetToDdl() common.ToDdl {
 	return ToDdlImpl{}
 }
 
-// We leave the 5 functions below empty to be able to pass this as an infoSchema interface. We don't need these for now.
-func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, tableId string, srcSchema schema.Table, spCols []string, spSchema ddl.CreateTable) error {
+// ProcessData performs data conversion for a Spanner database. The table is
+// read with partitioned queries at isi.ReadTimestamp, and each row is
+// converted to the dialect of the target database and written with
+// conv.WriteRow.
+func (isi InfoSchemaImpl) ProcessData(ctx context.Context, conv *internal.Conv, tableId string, srcSchema schema.Table, commonColIds []string, spSchema ddl.CreateTable) error {
+	srcTableName := conv.SrcSchema[tableId].Name
+	rowsInterface, err := isi.GetRowsFromTable(conv, tableId)
+	if err != nil {
+		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTableName, err))
+		return err
+	}
+	rows := rowsInterface.(*partitionedRows)
+	defer rows.close()
+	err = rows.do(ctx, func(row *spanner.Row) {
+		ProcessDataRow(conv, tableId, commonColIds, srcSchema, spSchema, row)
+	})
+	if err != nil {
+		conv.Unexpected(fmt.Sprintf("Couldn't read data for table %s : err = %s", srcTableName, err))
+		return err
+	}
 	return nil
 }
 
 // GetRowCount returns the row count of the table.
 func (isi InfoSchemaImpl) GetRowCount(table common.SchemaAndName) (int64, error) {
-	q := "SELECT count(*) FROM " + table.Name + ";"
+	q := "SELECT count(*) FROM " + isi.quoteIdent(table.Name) + ";"
 	stmt := spanner.Statement{
 		SQL: q,
 	}
-	iter := isi.Client.Single().Query(isi.Ctx, stmt)
+	iter := isi.single().Query(isi.Ctx, stmt)
 	defer iter.Stop()
 	var count int64
 	row, err := iter.Next()
@@ -71,10 +94,58 @@ func (isi InfoSchemaImpl) GetRowCount(table common.SchemaAndName) (int64, error)
 
 }
 
-func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, srcTable string) (interface{}, error) {
-	return nil, nil
+// GetRowsFromTable partitions a query that selects all columns of the table
+// and returns the partitions together with the batch transaction they
+// belong to. Partitions can be executed independently of each other.
+func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, tableId string) (interface{}, error) {
+	srcSchema := conv.SrcSchema[tableId]
+	var cols []string
+	for _, colId := range srcSchema.ColIds {
+		cols = append(cols, isi.quoteIdent(srcSchema.ColDefs[colId].Name))
+	}
+	stmt := spanner.Statement{SQL: fmt.Sprintf("SELECT %s FROM %s", strings.Join(cols, ", "), isi.quoteIdent(srcSchema.Name))}
+	tb := spanner.StrongRead()
+	if !isi.ReadTimestamp.IsZero() {
+		tb = spanner.ReadTimestamp(isi.ReadTimestamp)
+	}
+	txn, err := isi.Client.BatchReadOnlyTransaction(isi.Ctx, tb)
+	if err != nil {
+		return nil, fmt.Errorf("can't start read-only transaction: %w", err)
+	}
+	partitions, err := txn.PartitionQuery(isi.Ctx, stmt, spanner.PartitionOptions{})
+	if err != nil {
+		txn.Cleanup(isi.Ctx)
+		return nil, fmt.Errorf("can't partition query %q: %w", stmt.SQL, err)
+	}
+	return &partitionedRows{txn: txn, partitions: partitions}, nil
 }
 
+// partitionedRows holds the partitions of a table query.
+type partitionedRows struct {
+	txn        *spanner.BatchReadOnlyTransaction
+	partitions []*spanner.Partition
+}
+
+// do executes the partitions one after the other and calls f for each row.
+func (r *partitionedRows) do(ctx context.Context, f func(row *spanner.Row)) error {
+	for _, p := range r.partitions {
+		err := r.txn.Execute(ctx, p).Do(func(row *spanner.Row) error {
+			f(row)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *partitionedRows) close() {
+	r.txn.Cleanup(context.Background())
+}
+
+// We leave the 2 functions below empty to be able to pass this as an infoSchema interface. Streaming
+// is not supported for Spanner sources.
 func (isi InfoSchemaImpl) StartChangeDataCapture(ctx context.Context, conv *internal.Conv) (map[string]interface{}, error) {
 	return nil, nil
 }
@@ -83,6 +154,27 @@ func (isi InfoSchemaImpl) StartStreamingMigration(ctx context.Context, client *s
 	return nil
 }
 
+// FetchDialect returns the dialect of the database client is connected to,
+// as one of constants.DIALECT_GOOGLESQL and constants.DIALECT_POSTGRESQL.
+func FetchDialect(ctx context.Context, client *spanner.Client) (string, error) {
+	stmt := spanner.Statement{SQL: "SELECT option_value FROM information_schema.database_options WHERE option_name = 'database_dialect'"}
+	iter := client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+	row, err := iter.Next()
+	if err == iterator.Done {
+		// Databases created before dialects were introduced don't report one.
+		return constants.DIALECT_GOOGLESQL, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("couldn't get database dialect: %w", err)
+	}
+	var dialect string
+	if err := row.Columns(&dialect); err != nil {
+		return "", err
+	}
+	return strings.ToLower(dialect), nil
+}
+
 // GetTableName returns table name.
 func (isi InfoSchemaImpl) GetTableName(schema string, tableName string) string {
 	if isi.SpDialect == constants.DIALECT_POSTGRESQL {
@@ -404,6 +496,43 @@ func (isi InfoSchemaImpl) GetInterleaveTables() (map[string]string, error) {
 	return parentTables, nil
 }
 
+// SetParentTables reads the interleaving of the tables and sets the parent
+// of each interleaved table in conv.SpSchema accordingly.
+func (isi InfoSchemaImpl) SetParentTables(conv *internal.Conv) error {
+	parentTables, err := isi.GetInterleaveTables()
+	if err != nil {
+		return err
+	}
+	for tableName, parentName := range parentTables {
+		tableId, _ := internal.GetTableIdFromSpName(conv.SpSchema, tableName)
+		parentTableId, _ := internal.GetTableIdFromSpName(conv.SpSchema, parentName)
+		spTable := conv.SpSchema[tableId]
+		spTable.ParentId = parentTableId
+		conv.SpSchema[tableId] = spTable
+	}
+	return nil
+}
+
+// single returns a single-use read-only transaction at isi.ReadTimestamp.
+func (isi InfoSchemaImpl) single() *spanner.ReadOnlyTransaction {
+	if isi.ReadTimestamp.IsZero() {
+		return isi.Client.Single()
+	}
+	return isi.Client.Single().WithTimestampBound(spanner.ReadTimestamp(isi.ReadTimestamp))
+}
+
+// quoteIdent quotes a table or column name for use in a query in the dialect
+// of the source database.
+func (isi InfoSchemaImpl) quoteIdent(name string) string {
+	if isi.SpDialect == constants.DIALECT_POSTGRESQL {
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+	return "`" + name + "`"
+}
+
+// toType converts the spanner_type of a column into a schema.Type. Types
+// of GoogleSQL databases look like STRING(MAX) or ARRAY<INT64>, and types of
+// PostgreSQL-dialect databases look like character varying(100) or bigint[].
 func toType(dataType string) schema.Type {
 	switch {
 	case strings.Contains(dataType, "ARRAY"):
@@ -411,6 +540,10 @@ func toType(dataType string) schema.Type {
 		schemaType := toType(typeLenStr)
 		schemaType.ArrayBounds = []int64{-1}
 		return schemaType
+	case strings.HasSuffix(dataType, "[]"):
+		schemaType := toType(strings.TrimSuffix(dataType, "[]"))
+		schemaType.ArrayBounds = []int64{-1}
+		return schemaType
 	case strings.Contains(dataType, "("):
 		idx := strings.Index(dataType, "(")
 		typeLenStr := dataType[(idx + 1):(len(dataType) - 1)]
@@ -45,6 +45,13 @@ func TestToType(t *testing.T) {
 		{"string_arr", "ARRAY<STRING(100)>", schema.Type{Name: "STRING", Mods: []int64{100}, ArrayBounds: []int64{-1}}},
 		{"float_arr", "ARRAY<FLOAT64>", schema.Type{Name: "FLOAT64", ArrayBounds: []int64{-1}}},
 		{"numeric_arr", "ARRAY<NUMERIC>", schema.Type{Name: "NUMERIC", ArrayBounds: []int64{-1}}},
+		// PostgreSQL-dialect types.
+		{"pg_bigint", "bigint", schema.Type{Name: "bigint"}},
+		{"pg_timestamptz", "timestamp with time zone", schema.Type{Name: "timestamp with time zone"}},
+		{"pg_varchar", "character varying(100)", schema.Type{Name: "character varying", Mods: []int64{100}}},
+		{"pg_varchar_no_len", "character varying", schema.Type{Name: "character varying"}},
+		{"pg_bigint_arr", "bigint[]", schema.Type{Name: "bigint", ArrayBounds: []int64{-1}}},
+		{"pg_varchar_arr", "character varying(100)[]", schema.Type{Name: "character varying", Mods: []int64{100}, ArrayBounds: []int64{-1}}},
 	}
 	for _, tc := range testCases {
 		ty := toType(tc.dataType)
@@ -15,8 +15,10 @@
 package spanner
 
 import (
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
 )
 
@@ -27,37 +29,58 @@ type ToDdlImpl struct {
 // mods) into a Spanner type. This is the core source-to-Spanner type
 // mapping.  toSpannerType returns the Spanner type and a list of type
 // conversion issues encountered.
+// The source types can be GoogleSQL types (e.g. INT64) or PostgreSQL-dialect
+// types (e.g. bigint), and the returned type is printed in the dialect of the
+// target database, so this also converts between the two dialects.
 // Functions below implement the common.ToDdl interface
 func (tdi ToDdlImpl) ToSpannerType(conv *internal.Conv, spType string, srcType schema.Type) (ddl.Type, []internal.SchemaIssue) {
-	ty, issues := toSpannerTypeInternal(conv, srcType)
+	ty, issues := toSpannerTypeInternal(srcType, spType)
 	ty.IsArray = len(srcType.ArrayBounds) == 1
+	if conv.SpDialect == constants.DIALECT_POSTGRESQL {
+		ty = common.ToPGDialectType(ty)
+	}
 	return ty, issues
 }
 
-// toSpannerType maps a scalar source schema type (defined by id and
-// mods) into a Spanner type. This is the core source-to-Spanner type
-// mapping.  toSpannerType returns the Spanner type and a list of type
-// conversion issues encountered.
-func toSpannerTypeInternal(conv *internal.Conv, srcType schema.Type) (ddl.Type, []internal.SchemaIssue) {
+// toSpannerTypeInternal defines the mapping of source types into Spanner
+// types. Each source type maps to the equivalent Spanner type by default,
+// and all types except BYTES can also be mapped to STRING if spType asks
+// for it.
+func toSpannerTypeInternal(srcType schema.Type, spType string) (ddl.Type, []internal.SchemaIssue) {
+	var ty ddl.Type
 	switch srcType.Name {
-	case "BOOL":
-		return ddl.Type{Name: ddl.Bool}, nil
-	case "BYTES":
-		return ddl.Type{Name: ddl.Bytes, Len: srcType.Mods[0]}, nil
-	case "DATE":
-		return ddl.Type{Name: ddl.Date}, nil
-	case "FLOAT64":
-		return ddl.Type{Name: ddl.Float64}, nil
-	case "INT64":
-		return ddl.Type{Name: ddl.Int64}, nil
-	case "JSON", "JSONB":
-		return ddl.Type{Name: ddl.JSON}, nil
-	case "NUMERIC":
-		return ddl.Type{Name: ddl.Numeric}, nil
-	case "STRING":
-		return ddl.Type{Name: ddl.String, Len: srcType.Mods[0]}, nil
-	case "TIMESTAMP":
-		return ddl.Type{Name: ddl.Timestamp}, nil
+	case "BOOL", "boolean":
+		ty = ddl.Type{Name: ddl.Bool}
+	case "BYTES", "bytea":
+		return ddl.Type{Name: ddl.Bytes, Len: typeLen(srcType)}, nil
+	case "DATE", "date":
+		ty = ddl.Type{Name: ddl.Date}
+	case "FLOAT64", "double precision":
+		ty = ddl.Type{Name: ddl.Float64}
+	case "INT64", "bigint":
+		ty = ddl.Type{Name: ddl.Int64}
+	case "JSON", "JSONB", "jsonb":
+		ty = ddl.Type{Name: ddl.JSON}
+	case "NUMERIC", "numeric":
+		ty = ddl.Type{Name: ddl.Numeric}
+	case "STRING", "character varying":
+		return ddl.Type{Name: ddl.String, Len: typeLen(srcType)}, nil
+	case "TIMESTAMP", "timestamp with time zone":
+		ty = ddl.Type{Name: ddl.Timestamp}
+	default:
+		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.NoGoodType}
+	}
+	if spType == ddl.String {
+		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.Widened}
+	}
+	return ty, nil
+}
+
+// typeLen returns the length of a STRING or BYTES type. PostgreSQL-dialect
+// types without a length (e.g. character varying) have the maximum length.
+func typeLen(srcType schema.Type) int64 {
+	if len(srcType.Mods) > 0 {
+		return srcType.Mods[0]
 	}
-	return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.NoGoodType}
+	return ddl.MaxLength
 }
@@ -46,6 +46,19 @@ func TestToSpannerType(t *testing.T) {
 		// PG target.
 		{"pg_numeric", true, schema.Type{Name: "NUMERIC"}, ddl.Type{Name: ddl.Numeric}},
 		{"pg_json", true, schema.Type{Name: "JSONB"}, ddl.Type{Name: ddl.JSON}},
+		{"pg_string_arr", true, schema.Type{Name: "STRING", Mods: []int64{100}, ArrayBounds: []int64{-1}}, ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+		// PostgreSQL-dialect source.
+		{"pg_source_bool", false, schema.Type{Name: "boolean"}, ddl.Type{Name: ddl.Bool}},
+		{"pg_source_bytea", false, schema.Type{Name: "bytea"}, ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}},
+		{"pg_source_date", false, schema.Type{Name: "date"}, ddl.Type{Name: ddl.Date}},
+		{"pg_source_float", false, schema.Type{Name: "double precision"}, ddl.Type{Name: ddl.Float64}},
+		{"pg_source_int", false, schema.Type{Name: "bigint"}, ddl.Type{Name: ddl.Int64}},
+		{"pg_source_jsonb", false, schema.Type{Name: "jsonb"}, ddl.Type{Name: ddl.JSON}},
+		{"pg_source_numeric", false, schema.Type{Name: "numeric"}, ddl.Type{Name: ddl.Numeric}},
+		{"pg_source_varchar", false, schema.Type{Name: "character varying", Mods: []int64{50}}, ddl.Type{Name: ddl.String, Len: 50}},
+		{"pg_source_varchar_no_len", false, schema.Type{Name: "character varying"}, ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+		{"pg_source_timestamptz", false, schema.Type{Name: "timestamp with time zone"}, ddl.Type{Name: ddl.Timestamp}},
+		{"pg_source_int_arr", false, schema.Type{Name: "bigint", ArrayBounds: []int64{-1}}, ddl.Type{Name: ddl.Int64, IsArray: true}},
 	}
 	for _, tc := range toDDLTests {
 		conv.SpDialect = constants.DIALECT_GOOGLESQL
@@ -57,3 +70,20 @@ func TestToSpannerType(t *testing.T) {
 		assert.Equal(t, tc.expDDLType, ty, tc.name)
 	}
 }
+
+func TestToSpannerTypeWithSpType(t *testing.T) {
+	conv := internal.MakeConv()
+	toDDLImpl := ToDdlImpl{}
+	ty, issues := toDDLImpl.ToSpannerType(conv, ddl.String, schema.Type{Name: "INT64"})
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, ty)
+	assert.Equal(t, []internal.SchemaIssue{internal.Widened}, issues)
+
+	// BYTES can't be mapped to STRING.
+	ty, issues = toDDLImpl.ToSpannerType(conv, ddl.String, schema.Type{Name: "BYTES", Mods: []int64{10}})
+	assert.Equal(t, ddl.Type{Name: ddl.Bytes, Len: 10}, ty)
+	assert.Nil(t, issues)
+
+	ty, issues = toDDLImpl.ToSpannerType(conv, "", schema.Type{Name: "PROTO"})
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, ty)
+	assert.Equal(t, []internal.SchemaIssue{internal.NoGoodType}, issues)
+}
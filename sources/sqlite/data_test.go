@@ -0,0 +1,190 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlite
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+type spannerData struct {
+	table string
+	cols  []string
+	vals  []interface{}
+}
+
+// Basic smoke test of ProcessDataRow. The core part of this code path
+// (ConvertData) is tested in TestConvertData.
+func TestProcessDataRow(t *testing.T) {
+	tableName := "testtable"
+	tableId := "t1"
+	cols := []string{"a", "b", "c"}
+	colIds := []string{"c1", "c2", "c3"}
+	conv := buildConv(
+		ddl.CreateTable{
+			Name:   tableName,
+			Id:     tableId,
+			ColIds: colIds,
+			ColDefs: map[string]ddl.ColumnDef{
+				"c1": {Name: "a", Id: "c1", T: ddl.Type{Name: ddl.Float64}},
+				"c2": {Name: "b", Id: "c2", T: ddl.Type{Name: ddl.Int64}},
+				"c3": {Name: "c", Id: "c3", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			}},
+		schema.Table{
+			Name:   tableName,
+			Id:     tableId,
+			ColIds: colIds,
+			ColDefs: map[string]schema.Column{
+				"c1": {Name: "a", Id: "c1", Type: schema.Type{Name: "real"}},
+				"c2": {Name: "b", Id: "c2", Type: schema.Type{Name: "integer"}},
+				"c3": {Name: "c", Id: "c3", Type: schema.Type{Name: "text"}},
+			}})
+	conv.SetDataMode()
+	var rows []spannerData
+	conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+		rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
+	})
+	ProcessDataRow(conv, tableId, colIds, conv.SrcSchema[tableId], conv.SpSchema[tableId], []string{"4.2", "6", "prisoner zero"})
+	assert.Equal(t, []spannerData{{table: tableName, cols: cols, vals: []interface{}{float64(4.2), int64(6), "prisoner zero"}}}, rows)
+}
+
+func TestConvertData(t *testing.T) {
+	singleColTests := []struct {
+		name  string
+		ty    ddl.Type
+		srcTy string      // Source DB type
+		in    string      // Input value for conversion.
+		e     interface{} // Expected result.
+	}{
+		{"bool 0", ddl.Type{Name: ddl.Bool}, "boolean", "0", false},
+		{"bool 1", ddl.Type{Name: ddl.Bool}, "boolean", "1", true},
+		{"bool keyword", ddl.Type{Name: ddl.Bool}, "boolean", "true", true},
+		{"bytes", ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, "blob", string([]byte{137, 80}), []byte{0x89, 0x50}},
+		{"date", ddl.Type{Name: ddl.Date}, "date", "2019-10-29", civil.Date{Year: 2019, Month: 10, Day: 29}},
+		{"date with time", ddl.Type{Name: ddl.Date}, "date", "2019-10-29 05:30:00", civil.Date{Year: 2019, Month: 10, Day: 29}},
+		{"float64", ddl.Type{Name: ddl.Float64}, "real", "42.6", float64(42.6)},
+		{"int64", ddl.Type{Name: ddl.Int64}, "integer", "42", int64(42)},
+		{"int64 stored as real", ddl.Type{Name: ddl.Int64}, "integer", "42.0", int64(42)},
+		{"numeric", ddl.Type{Name: ddl.Numeric}, "decimal", "234.5", big.NewRat(2345, 10)},
+		{"string", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, "text", "eh", "eh"},
+		{"json", ddl.Type{Name: ddl.JSON}, "json", `{"a": [1, 2]}`, `{"a": [1, 2]}`},
+		{"timestamp text", ddl.Type{Name: ddl.Timestamp}, "datetime", "2019-10-29 05:30:00", time.Date(2019, 10, 29, 5, 30, 0, 0, time.UTC)},
+		{"timestamp iso", ddl.Type{Name: ddl.Timestamp}, "datetime", "2019-10-29T05:30:00.5", time.Date(2019, 10, 29, 5, 30, 0, 500000000, time.UTC)},
+		{"timestamp with offset", ddl.Type{Name: ddl.Timestamp}, "datetime", "2019-10-29T05:30:00+01:00", time.Date(2019, 10, 29, 4, 30, 0, 0, time.UTC)},
+		{"timestamp unix", ddl.Type{Name: ddl.Timestamp}, "datetime", "1572327000", time.Date(2019, 10, 29, 5, 30, 0, 0, time.UTC)},
+		{"timestamp julian day", ddl.Type{Name: ddl.Timestamp}, "datetime", "2440588.0", time.Date(1970, 1, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	tableName := "testtable"
+	tableId := "t1"
+	for _, tc := range singleColTests {
+		col := "a"
+		colId := "c1"
+		conv := buildConv(
+			ddl.CreateTable{
+				Name:        tableName,
+				Id:          tableId,
+				ColIds:      []string{colId},
+				ColDefs:     map[string]ddl.ColumnDef{colId: {Name: col, Id: colId, T: tc.ty}},
+				PrimaryKeys: []ddl.IndexKey{}},
+			schema.Table{
+				Name:    tableName,
+				Id:      tableId,
+				ColIds:  []string{colId},
+				ColDefs: map[string]schema.Column{colId: {Name: col, Id: colId, Type: schema.Type{Name: tc.srcTy}}}})
+		t.Run(tc.name, func(t *testing.T) {
+			at, ac, av, err := ConvertData(conv, tableId, []string{colId}, conv.SrcSchema[tableId], conv.SpSchema[tableId], []string{tc.in})
+			checkResults(t, at, ac, av, err, tableName, []string{col}, []interface{}{tc.e}, tc.name)
+		})
+	}
+}
+
+func TestConvertDataPGDialectNumeric(t *testing.T) {
+	conv := internal.MakeConv()
+	conv.SpDialect = constants.DIALECT_POSTGRESQL
+	v, err := convScalar(conv, ddl.Type{Name: ddl.Numeric}, "numeric", "12.5")
+	assert.Nil(t, err)
+	assert.Equal(t, spanner.PGNumeric{Numeric: "12.5", Valid: true}, v)
+}
+
+func TestConvertError(t *testing.T) {
+	errorTests := []struct {
+		name string
+		ty   ddl.Type
+		in   string
+	}{
+		{"bad bool", ddl.Type{Name: ddl.Bool}, "maybe"},
+		{"bad date", ddl.Type{Name: ddl.Date}, "tomorrow"},
+		{"bad float", ddl.Type{Name: ddl.Float64}, "pi"},
+		{"bad int", ddl.Type{Name: ddl.Int64}, "1.5"},
+		{"bad numeric", ddl.Type{Name: ddl.Numeric}, "abc"},
+		{"bad timestamp", ddl.Type{Name: ddl.Timestamp}, "yesterday"},
+	}
+	conv := internal.MakeConv()
+	for _, tc := range errorTests {
+		_, err := convScalar(conv, tc.ty, "", tc.in)
+		assert.NotNil(t, err, tc.name)
+	}
+}
+
+func TestConvertsyntheticPKey(t *testing.T) {
+	tableName := "testtable"
+	tableId := "t1"
+	conv := buildConv(
+		ddl.CreateTable{
+			Name:   tableName,
+			Id:     tableId,
+			ColIds: []string{"c1", "c2"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"c1": {Name: "a", Id: "c1", T: ddl.Type{Name: ddl.Int64}},
+				"c2": {Name: "synth_id", Id: "c2", T: ddl.Type{Name: ddl.String, Len: 50}},
+			}},
+		schema.Table{
+			Name:    tableName,
+			Id:      tableId,
+			ColIds:  []string{"c1"},
+			ColDefs: map[string]schema.Column{"c1": {Name: "a", Id: "c1", Type: schema.Type{Name: "integer"}}}})
+	conv.SyntheticPKeys[tableId] = internal.SyntheticPKey{ColId: "c2", Sequence: 0}
+	for i := int64(0); i < 3; i++ {
+		at, ac, av, err := ConvertData(conv, tableId, []string{"c1"}, conv.SrcSchema[tableId], conv.SpSchema[tableId], []string{"7"})
+		checkResults(t, at, ac, av, err, tableName, []string{"a", "synth_id"},
+			[]interface{}{int64(7), fmt.Sprintf("%d", int64(bits.Reverse64(uint64(i))))}, "synthetic pkey")
+	}
+}
+
+func buildConv(spTable ddl.CreateTable, srcTable schema.Table) *internal.Conv {
+	conv := internal.MakeConv()
+	conv.SpSchema[spTable.Id] = spTable
+	conv.SrcSchema[srcTable.Id] = srcTable
+	return conv
+}
+
+func checkResults(t *testing.T, atable string, acols []string, avals []interface{}, err error, etable string, ecols []string, evals []interface{}, name string) {
+	assert.Nil(t, err, name)
+	assert.Equal(t, atable, etable, name+": table mismatch")
+	assert.Equal(t, ecols, acols, name+": column mismatch")
+	assert.Equal(t, evals, avals, name+": value mismatch")
+}
@@ -0,0 +1,437 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	sp "cloud.google.com/go/spanner"
+	_ "github.com/mattn/go-sqlite3" // The driver should be used via the database/sql package.
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// schemaName is the name SQLite uses for the main database of a connection.
+const schemaName string = "main"
+
+// InfoSchemaImpl is SQLite specific implementation for InfoSchema.
+// SQLite doesn't provide information_schema tables, so schema information is
+// read from sqlite_master and the table_info, index_list, index_xinfo and
+// foreign_key_list pragmas.
+type InfoSchemaImpl struct {
+	DbName string
+	Db     *sql.DB
+}
+
+// GetToDdl implement the common.InfoSchema interface.
+func (isi InfoSchemaImpl) GetToDdl() common.ToDdl {
+	return ToDdlImpl{}
+}
+
+// We leave the 2 functions below empty to be able to pass this as an infoSchema interface.
+// SQLite has no change stream, so streaming migrations are not supported.
+func (isi InfoSchemaImpl) StartChangeDataCapture(ctx context.Context, conv *internal.Conv) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+func (isi InfoSchemaImpl) StartStreamingMigration(ctx context.Context, client *sp.Client, conv *internal.Conv, streamingInfo map[string]interface{}) error {
+	return nil
+}
+
+// GetTableName returns table name. SQLite tables always belong to the
+// 'main' schema, so we drop it.
+func (isi InfoSchemaImpl) GetTableName(schema string, tableName string) string {
+	return tableName
+}
+
+// GetRowsFromTable returns a sql Rows object for a table.
+func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, tableId string) (interface{}, error) {
+	return isi.getRowsFromTable(context.Background(), conv, tableId)
+}
+
+// getRowsFromTable is like GetRowsFromTable, but the query is cancelled
+// once ctx is done.
+func (isi InfoSchemaImpl) getRowsFromTable(ctx context.Context, conv *internal.Conv, tableId string) (interface{}, error) {
+	srcSchema := conv.SrcSchema[tableId]
+	var cols []string
+	for _, colId := range srcSchema.ColIds {
+		cols = append(cols, quoteIdent(srcSchema.ColDefs[colId].Name))
+	}
+	if len(cols) == 0 {
+		conv.Unexpected(fmt.Sprintf("Couldn't get source columns for table %s ", srcSchema.Name))
+		return nil, fmt.Errorf("no columns found for table %s", srcSchema.Name)
+	}
+	q := fmt.Sprintf("SELECT %s FROM %s;", strings.Join(cols, ", "), quoteIdent(srcSchema.Name))
+	return isi.Db.QueryContext(ctx, q)
+}
+
+// GetSampleRows implements the common.SamplingInfoSchema interface.
+func (isi InfoSchemaImpl) GetSampleRows(conv *internal.Conv, tableId string, colIds []string, n int64) (*sql.Rows, error) {
+	srcSchema := conv.SrcSchema[tableId]
+	var cols []string
+	for _, colId := range colIds {
+		cols = append(cols, quoteIdent(srcSchema.ColDefs[colId].Name))
+	}
+	q := fmt.Sprintf("SELECT %s FROM %s LIMIT %d;", strings.Join(cols, ", "), quoteIdent(srcSchema.Name), n)
+	return isi.Db.Query(q)
+}
+
+// ProcessData performs data conversion for source database.
+func (isi InfoSchemaImpl) ProcessData(ctx context.Context, conv *internal.Conv, tableId string, srcSchema schema.Table, commonColIds []string, spSchema ddl.CreateTable) error {
+	srcTableName := conv.SrcSchema[tableId].Name
+	rowsInterface, err := isi.getRowsFromTable(ctx, conv, tableId)
+	if err != nil {
+		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTableName, err))
+		return err
+	}
+	rows := rowsInterface.(*sql.Rows)
+	defer rows.Close()
+	srcCols, _ := rows.Columns()
+	v, scanArgs := buildVals(len(srcCols))
+	colNameIdMap := internal.GetSrcColNameIdMap(conv.SrcSchema[tableId])
+	for rows.Next() {
+		err := rows.Scan(scanArgs...)
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Couldn't process sql data row: %s", err))
+			// Scan failed, so we don't have any data to add to bad rows.
+			conv.StatsAddBadRow(srcTableName, conv.DataMode())
+			continue
+		}
+		values := valsToStrings(v)
+		newValues, err := common.PrepareValues(conv, tableId, colNameIdMap, commonColIds, srcCols, values)
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
+			conv.StatsAddBadRow(srcTableName, conv.DataMode())
+			conv.CollectBadRow(srcTableName, srcCols, values, err)
+			continue
+		}
+		ProcessDataRow(conv, tableId, commonColIds, srcSchema, spSchema, newValues)
+	}
+	// The query is cancelled once ctx is done, which ends the rows early.
+	return ctx.Err()
+}
+
+// GetRowCount with number of rows in each table.
+func (isi InfoSchemaImpl) GetRowCount(table common.SchemaAndName) (int64, error) {
+	q := fmt.Sprintf("SELECT COUNT(*) FROM %s;", quoteIdent(table.Name))
+	rows, err := isi.Db.Query(q)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var count int64
+	if rows.Next() {
+		err := rows.Scan(&count)
+		return count, err
+	}
+	return 0, nil
+}
+
+// GetTables return list of tables in the selected database. Internal tables
+// (sqlite_sequence, sqlite_stat1 etc.) are skipped.
+func (isi InfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
+	// '_' is a LIKE wildcard, so it is escaped to only skip the names that
+	// start with "sqlite_".
+	q := `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite\_%' ESCAPE '\' ORDER BY name;`
+	rows, err := isi.Db.Query(q)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get tables: %w", err)
+	}
+	defer rows.Close()
+	var tableName string
+	var tables []common.SchemaAndName
+	for rows.Next() {
+		rows.Scan(&tableName)
+		tables = append(tables, common.SchemaAndName{Schema: schemaName, Name: tableName})
+	}
+	return tables, nil
+}
+
+// GetColumns returns a list of Column objects and names.
+func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAndName, constraints map[string][]string, primaryKeys []string) (map[string]schema.Column, []string, error) {
+	cols, err := isi.Db.Query(fmt.Sprintf("PRAGMA table_info(%s);", quoteIdent(table.Name)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't get schema for table %s.%s: %s", table.Schema, table.Name, err)
+	}
+	defer cols.Close()
+	colDefs := make(map[string]schema.Column)
+	var colIds []string
+	var cid, notNull, pk int64
+	var colName, colType string
+	var colDefault sql.NullString
+	for cols.Next() {
+		err := cols.Scan(&cid, &colName, &colType, &notNull, &colDefault, &pk)
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
+			continue
+		}
+		ignored := schema.Ignored{}
+		for _, c := range constraints[colName] {
+			switch c {
+			case "CHECK":
+				ignored.Check = true
+			case "FOREIGN KEY", "PRIMARY KEY", "UNIQUE":
+				// Nothing to do here -- these are all handled elsewhere.
+			}
+		}
+		ignored.Default = colDefault.Valid
+		colId := internal.GenerateColumnId()
+		colDefs[colId] = schema.Column{
+			Id:      colId,
+			Name:    colName,
+			Type:    toType(colType),
+			NotNull: notNull != 0,
+			Ignored: ignored,
+		}
+		colIds = append(colIds, colId)
+	}
+	return colDefs, colIds, nil
+}
+
+// GetConstraints returns a list of primary keys and by-column map of
+// other constraints. Primary key columns are returned in key order
+// (the pk column of table_info is the 1-based position in the key).
+// Note that foreign key constraints are handled in GetForeignKeys and
+// unique constraints are reported by index_list, so they are handled
+// in GetIndexes.
+func (isi InfoSchemaImpl) GetConstraints(conv *internal.Conv, table common.SchemaAndName) ([]string, map[string][]string, error) {
+	rows, err := isi.Db.Query(fmt.Sprintf("PRAGMA table_info(%s);", quoteIdent(table.Name)))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	type pkCol struct {
+		name  string
+		order int64
+	}
+	var pkCols []pkCol
+	var cid, notNull, pk int64
+	var colName, colType string
+	var colDefault sql.NullString
+	for rows.Next() {
+		err := rows.Scan(&cid, &colName, &colType, &notNull, &colDefault, &pk)
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
+			continue
+		}
+		if pk > 0 {
+			pkCols = append(pkCols, pkCol{name: colName, order: pk})
+		}
+	}
+	sort.Slice(pkCols, func(i, j int) bool { return pkCols[i].order < pkCols[j].order })
+	var primaryKeys []string
+	for _, c := range pkCols {
+		primaryKeys = append(primaryKeys, c.name)
+	}
+	return primaryKeys, map[string][]string{}, nil
+}
+
+// GetForeignKeys returns a list of all the foreign key constraints.
+// SQLite doesn't name foreign keys in foreign_key_list, so we generate
+// names using the table name and the id of the constraint. When the
+// referenced columns are omitted in the constraint (REFERENCES parent),
+// the foreign key references the primary key of the parent table.
+func (isi InfoSchemaImpl) GetForeignKeys(conv *internal.Conv, table common.SchemaAndName) (foreignKeys []schema.ForeignKey, err error) {
+	rows, err := isi.Db.Query(fmt.Sprintf("PRAGMA foreign_key_list(%s);", quoteIdent(table.Name)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var id, seq int64
+	var refTable, col, onUpdate, onDelete, match string
+	var refCol sql.NullString
+	fKeys := make(map[int64]common.FkConstraint)
+	var keyIds []int64
+	for rows.Next() {
+		err := rows.Scan(&id, &seq, &refTable, &col, &refCol, &onUpdate, &onDelete, &match)
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
+			continue
+		}
+		fk, found := fKeys[id]
+		if !found {
+			fk = common.FkConstraint{Name: fmt.Sprintf("fk_%s_%d", table.Name, id), Table: refTable}
+			keyIds = append(keyIds, id)
+		}
+		fk.Cols = append(fk.Cols, col)
+		fk.Refcols = append(fk.Refcols, refCol.String)
+		fKeys[id] = fk
+	}
+	rows.Close()
+	sort.Slice(keyIds, func(i, j int) bool { return keyIds[i] < keyIds[j] })
+	for _, k := range keyIds {
+		fk := fKeys[k]
+		if len(fk.Refcols) > 0 && fk.Refcols[0] == "" {
+			pks, _, err := isi.GetConstraints(conv, common.SchemaAndName{Schema: table.Schema, Name: fk.Table})
+			if err != nil || len(pks) != len(fk.Cols) {
+				conv.Unexpected(fmt.Sprintf("Couldn't resolve referenced columns of foreign key %s on table %s", fk.Name, table.Name))
+				continue
+			}
+			fk.Refcols = pks
+		}
+		foreignKeys = append(foreignKeys,
+			schema.ForeignKey{
+				Id:               internal.GenerateForeignkeyId(),
+				Name:             fk.Name,
+				ColumnNames:      fk.Cols,
+				ReferTableName:   fk.Table,
+				ReferColumnNames: fk.Refcols,
+			})
+	}
+	return foreignKeys, nil
+}
+
+// GetIndexes return a list of all indexes for the specified table.
+// Indexes backing the primary key are skipped. Unique constraints are
+// returned as unique indexes.
+func (isi InfoSchemaImpl) GetIndexes(conv *internal.Conv, table common.SchemaAndName, colNameIdMap map[string]string) ([]schema.Index, error) {
+	rows, err := isi.Db.Query(fmt.Sprintf("PRAGMA index_list(%s);", quoteIdent(table.Name)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var seq, unique, partial int64
+	var name, origin string
+	var indexes []schema.Index
+	for rows.Next() {
+		if err := rows.Scan(&seq, &name, &unique, &origin, &partial); err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
+			continue
+		}
+		if origin == "pk" {
+			continue
+		}
+		indexes = append(indexes, schema.Index{
+			Id:     internal.GenerateIndexesId(),
+			Name:   name,
+			Unique: unique == 1,
+		})
+	}
+	rows.Close()
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i].Name < indexes[j].Name })
+	for i := range indexes {
+		keys, err := isi.getIndexKeys(conv, indexes[i].Name, colNameIdMap)
+		if err != nil {
+			return nil, err
+		}
+		indexes[i].Keys = keys
+	}
+	return indexes, nil
+}
+
+// getIndexKeys returns the key columns of an index, in key order.
+// Index entries on expressions (cid = -2) are skipped.
+func (isi InfoSchemaImpl) getIndexKeys(conv *internal.Conv, indexName string, colNameIdMap map[string]string) ([]schema.Key, error) {
+	rows, err := isi.Db.Query(fmt.Sprintf("PRAGMA index_xinfo(%s);", quoteIdent(indexName)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var seqno, cid, desc, key int64
+	var colName, coll sql.NullString
+	var keys []schema.Key
+	for rows.Next() {
+		if err := rows.Scan(&seqno, &cid, &colName, &desc, &coll, &key); err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
+			continue
+		}
+		// Auxiliary columns (such as the rowid) have key = 0.
+		if key == 0 {
+			continue
+		}
+		if !colName.Valid {
+			conv.Unexpected(fmt.Sprintf("Skipping expression in index %s", indexName))
+			continue
+		}
+		keys = append(keys, schema.Key{ColId: colNameIdMap[colName.String], Desc: desc == 1})
+	}
+	return keys, nil
+}
+
+// toType converts a SQLite declared type such as 'VARCHAR(255)' or
+// 'DECIMAL(10, 5)' into a schema.Type. Type names are lower-cased.
+func toType(declaredType string) schema.Type {
+	t := strings.ToLower(strings.TrimSpace(declaredType))
+	i := strings.Index(t, "(")
+	if i < 0 || !strings.HasSuffix(t, ")") {
+		return schema.Type{Name: t}
+	}
+	ty := schema.Type{Name: strings.TrimSpace(t[:i])}
+	for _, m := range strings.Split(t[i+1:len(t)-1], ",") {
+		n, err := strconv.ParseInt(strings.TrimSpace(m), 10, 64)
+		if err != nil {
+			// Unparseable modifiers are dropped; SQLite ignores them anyway.
+			return schema.Type{Name: ty.Name}
+		}
+		ty.Mods = append(ty.Mods, n)
+	}
+	return ty
+}
+
+// quoteIdent quotes a SQLite identifier. SQLite doesn't support query
+// parameters for table names in pragmas, so we quote instead.
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// buildVals contructs interface{} value containers to scan row
+// results into.  Returns both the underlying containers (as a slice)
+// as well as an interface{} of pointers to containers to pass to
+// rows.Scan. SQLite values are dynamically typed, so we can't scan
+// into sql.RawBytes (e.g. date columns are returned as time.Time).
+func buildVals(n int) (v []interface{}, iv []interface{}) {
+	v = make([]interface{}, n)
+	for i := range v {
+		iv = append(iv, &v[i])
+	}
+	return v, iv
+}
+
+func valsToStrings(vals []interface{}) []string {
+	toString := func(val interface{}) string {
+		switch v := val.(type) {
+		case nil:
+			return "NULL"
+		case []byte:
+			return string(v)
+		case string:
+			return v
+		case int64:
+			return strconv.FormatInt(v, 10)
+		case float64:
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		case bool:
+			return strconv.FormatBool(v)
+		case time.Time:
+			return v.Format(time.RFC3339Nano)
+		default:
+			return fmt.Sprintf("%v", v)
+		}
+	}
+	var s []string
+	for _, v := range vals {
+		s = append(s, toString(v))
+	}
+	return s
+}
@@ -0,0 +1,225 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+func init() {
+	logger.Log = zap.NewNop()
+}
+
+// SQLite runs in-process, so rather than mocking queries we build a real
+// database in a temporary directory and run the conversion against it.
+const testSchema = `
+CREATE TABLE user (
+	user_id INTEGER PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	ref INT,
+	UNIQUE (name)
+);
+CREATE TABLE cart (
+	productid TEXT,
+	userid INTEGER REFERENCES user,
+	quantity BIGINT DEFAULT 1,
+	PRIMARY KEY (userid, productid)
+);
+CREATE TABLE test (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	b BOOLEAN,
+	d DATE,
+	dt DATETIME,
+	f DOUBLE PRECISION,
+	j JSON,
+	n DECIMAL(10, 2),
+	bl BLOB,
+	untyped,
+	FOREIGN KEY (id) REFERENCES user (user_id)
+);
+CREATE INDEX idx_test ON test (d DESC, b);
+CREATE TABLE no_pk (
+	a INTEGER,
+	b TEXT
+);
+INSERT INTO user VALUES (1, 'Alice', 7);
+INSERT INTO test (b, d, dt, f, j, n, bl, untyped) VALUES
+	(1, '2021-03-04', '2021-03-04 05:06:07', 1.5, '{"a": 1}', 12.34, x'0102', 'x'),
+	(0, '2022-01-01', NULL, 2, NULL, '5', NULL, NULL);
+INSERT INTO no_pk VALUES (42, 'hello');
+`
+
+func openTestDb(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("couldn't open sqlite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(testSchema); err != nil {
+		t.Fatalf("couldn't create test schema: %v", err)
+	}
+	return db
+}
+
+func TestProcessSchema(t *testing.T) {
+	db := openTestDb(t)
+	conv := internal.MakeConv()
+	err := common.ProcessSchema(conv, InfoSchemaImpl{"test", db}, 1)
+	assert.Nil(t, err)
+	expectedSchema := map[string]ddl.CreateTable{
+		"user": {
+			Name:   "user",
+			ColIds: []string{"user_id", "name", "ref"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"user_id": {Name: "user_id", T: ddl.Type{Name: ddl.Int64}},
+				"name":    {Name: "name", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, NotNull: true},
+				"ref":     {Name: "ref", T: ddl.Type{Name: ddl.Int64}},
+			},
+			PrimaryKeys: []ddl.IndexKey{{ColId: "user_id", Order: 1}},
+			Indexes:     []ddl.CreateIndex{{Name: "sqlite_autoindex_user_1", TableId: "user", Unique: true, Keys: []ddl.IndexKey{{ColId: "name", Order: 1}}}},
+		},
+		"cart": {
+			Name:   "cart",
+			ColIds: []string{"productid", "userid", "quantity"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"productid": {Name: "productid", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+				"userid":    {Name: "userid", T: ddl.Type{Name: ddl.Int64}},
+				"quantity":  {Name: "quantity", T: ddl.Type{Name: ddl.Int64}},
+			},
+			PrimaryKeys: []ddl.IndexKey{{ColId: "userid", Order: 1}, {ColId: "productid", Order: 2}},
+			ForeignKeys: []ddl.Foreignkey{{Name: "fk_cart_0", ColIds: []string{"userid"}, ReferTableId: "user", ReferColumnIds: []string{"user_id"}}},
+		},
+		"test": {
+			Name:   "test",
+			ColIds: []string{"id", "b", "d", "dt", "f", "j", "n", "bl", "untyped"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"id":      {Name: "id", T: ddl.Type{Name: ddl.Int64}},
+				"b":       {Name: "b", T: ddl.Type{Name: ddl.Bool}},
+				"d":       {Name: "d", T: ddl.Type{Name: ddl.Date}},
+				"dt":      {Name: "dt", T: ddl.Type{Name: ddl.Timestamp}},
+				"f":       {Name: "f", T: ddl.Type{Name: ddl.Float64}},
+				"j":       {Name: "j", T: ddl.Type{Name: ddl.JSON}},
+				"n":       {Name: "n", T: ddl.Type{Name: ddl.Numeric}},
+				"bl":      {Name: "bl", T: ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}},
+				"untyped": {Name: "untyped", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			},
+			PrimaryKeys: []ddl.IndexKey{{ColId: "id", Order: 1}},
+			ForeignKeys: []ddl.Foreignkey{{Name: "fk_test_0", ColIds: []string{"id"}, ReferTableId: "user", ReferColumnIds: []string{"user_id"}}},
+			Indexes:     []ddl.CreateIndex{{Name: "idx_test", TableId: "test", Keys: []ddl.IndexKey{{ColId: "d", Desc: true, Order: 1}, {ColId: "b", Order: 2}}}},
+		},
+		"no_pk": {
+			Name:   "no_pk",
+			ColIds: []string{"a", "b", "synth_id"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"a":        {Name: "a", T: ddl.Type{Name: ddl.Int64}},
+				"b":        {Name: "b", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+				"synth_id": {Name: "synth_id", T: ddl.Type{Name: ddl.String, Len: 50}},
+			},
+			PrimaryKeys: []ddl.IndexKey{{ColId: "synth_id", Order: 1}},
+		},
+	}
+	internal.AssertSpSchema(conv, t, expectedSchema, stripSchemaComments(conv.SpSchema))
+	assert.Equal(t, len(conv.SchemaIssues), 4)
+	assert.Equal(t, int64(0), conv.Unexpecteds())
+}
+
+func TestProcessData(t *testing.T) {
+	db := openTestDb(t)
+	conv := internal.MakeConv()
+	assert.Nil(t, common.ProcessSchema(conv, InfoSchemaImpl{"test", db}, 1))
+	conv.SetDataMode()
+	var rows []spannerData
+	conv.SetDataSink(
+		func(table string, cols []string, vals []interface{}) {
+			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
+		})
+	common.ProcessData(context.Background(), conv, InfoSchemaImpl{"test", db})
+	assert.Equal(t,
+		[]spannerData{
+			{table: "no_pk", cols: []string{"a", "b", "synth_id"}, vals: []interface{}{int64(42), "hello", "0"}},
+			{
+				table: "test",
+				cols:  []string{"id", "b", "d", "dt", "f", "j", "n", "bl", "untyped"},
+				vals: []interface{}{int64(1), true, civil.Date{Year: 2021, Month: 3, Day: 4},
+					time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC), float64(1.5), `{"a": 1}`, big.NewRat(1234, 100), []byte{1, 2}, "x"},
+			},
+			{
+				table: "test",
+				cols:  []string{"id", "b", "d", "f", "n"},
+				vals:  []interface{}{int64(2), false, civil.Date{Year: 2022, Month: 1, Day: 1}, float64(2), big.NewRat(5, 1)},
+			},
+			{table: "user", cols: []string{"user_id", "name", "ref"}, vals: []interface{}{int64(1), "Alice", int64(7)}},
+		},
+		rows)
+	assert.Equal(t, int64(0), conv.Unexpecteds())
+}
+
+func TestGetTables(t *testing.T) {
+	db := openTestDb(t)
+	// AUTOINCREMENT creates the internal sqlite_sequence table, while the
+	// names below only look like internal ones.
+	if _, err := db.Exec("CREATE TABLE sqlitex (a INTEGER); CREATE TABLE sqlite1_data (a INTEGER);"); err != nil {
+		t.Fatalf("couldn't create tables: %v", err)
+	}
+	tables, err := InfoSchemaImpl{"test", db}.GetTables()
+	assert.Nil(t, err)
+	var names []string
+	for _, table := range tables {
+		names = append(names, table.Name)
+	}
+	assert.Equal(t, []string{"cart", "no_pk", "sqlite1_data", "sqlitex", "test", "user"}, names)
+}
+
+func TestGetRowCount(t *testing.T) {
+	db := openTestDb(t)
+	count, err := InfoSchemaImpl{"test", db}.GetRowCount(common.SchemaAndName{Schema: schemaName, Name: "test"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestToType(t *testing.T) {
+	assert.Equal(t, "varchar", toType("VARCHAR(255)").Name)
+	assert.Equal(t, []int64{255}, toType("VARCHAR(255)").Mods)
+	assert.Equal(t, []int64{10, 5}, toType("decimal(10, 5)").Mods)
+	assert.Equal(t, "double precision", toType("DOUBLE PRECISION").Name)
+	assert.Nil(t, toType("CHAR(x)").Mods)
+	assert.Equal(t, "", toType("").Name)
+}
+
+func stripSchemaComments(spSchema map[string]ddl.CreateTable) map[string]ddl.CreateTable {
+	for t, ct := range spSchema {
+		for c, cd := range ct.ColDefs {
+			cd.Comment = ""
+			ct.ColDefs[c] = cd
+		}
+		ct.Comment = ""
+		spSchema[t] = ct
+	}
+	return spSchema
+}
@@ -0,0 +1,182 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sqlite handles schema and data migrations from SQLite.
+package sqlite
+
+import (
+	"strings"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// Type affinities of SQLite columns.
+// See https://www.sqlite.org/datatype3.html#type_affinity.
+const (
+	integerAffinity string = "INTEGER"
+	textAffinity    string = "TEXT"
+	blobAffinity    string = "BLOB"
+	realAffinity    string = "REAL"
+	numericAffinity string = "NUMERIC"
+)
+
+// ToDdlImpl SQLite specific implementation for ToDdl.
+type ToDdlImpl struct {
+}
+
+// ToSpannerType maps a scalar source schema type (defined by id and
+// mods) into a Spanner type. This is the core source-to-Spanner type
+// mapping.  toSpannerType returns the Spanner type and a list of type
+// conversion issues encountered.
+// Functions below implement the common.ToDdl interface
+func (tdi ToDdlImpl) ToSpannerType(conv *internal.Conv, spType string, srcType schema.Type) (ddl.Type, []internal.SchemaIssue) {
+	ty, issues := toSpannerTypeInternal(srcType, spType)
+	if conv.SpDialect == constants.DIALECT_POSTGRESQL {
+		ty = common.ToPGDialectType(ty)
+	}
+	return ty, issues
+}
+
+// GetAffinity returns the type affinity SQLite assigns to a column with the
+// declared type name typeName. SQLite applies the rules below in order, so
+// e.g. "CHARINT" has INTEGER affinity and "FLOATING POINT" (which contains
+// "INT") has INTEGER affinity as well.
+func GetAffinity(typeName string) string {
+	t := strings.ToUpper(typeName)
+	switch {
+	case strings.Contains(t, "INT"):
+		return integerAffinity
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"):
+		return textAffinity
+	case strings.Contains(t, "BLOB"), t == "":
+		return blobAffinity
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		return realAffinity
+	default:
+		return numericAffinity
+	}
+}
+
+// toSpannerTypeInternal defines the mapping of source types into Spanner
+// types. SQLite only enforces type affinity (not declared types), so we first
+// compute the affinity of the declared type and then refine the NUMERIC
+// affinity using well-known declared type names (BOOLEAN, DATE, DATETIME etc.)
+// since applications commonly rely on those to store booleans and dates.
+// If the target Spanner type name is specified and is a potential mapping
+// for this source type, then it will be used to build the returned ddl.Type.
+// If not, the default Spanner type for this source type will be used.
+func toSpannerTypeInternal(srcType schema.Type, spType string) (ddl.Type, []internal.SchemaIssue) {
+	switch GetAffinity(srcType.Name) {
+	case integerAffinity:
+		switch spType {
+		case ddl.String:
+			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.Widened}
+		case ddl.Numeric:
+			return ddl.Type{Name: ddl.Numeric}, []internal.SchemaIssue{internal.Widened}
+		default:
+			return ddl.Type{Name: ddl.Int64}, nil
+		}
+	case textAffinity:
+		switch spType {
+		case ddl.Bytes:
+			return ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, nil
+		case ddl.JSON:
+			return ddl.Type{Name: ddl.JSON}, nil
+		default:
+			// SQLite ignores length modifiers of character types (e.g.
+			// VARCHAR(10) can hold arbitrarily long strings), so we always
+			// use STRING(MAX).
+			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
+		}
+	case realAffinity:
+		switch spType {
+		case ddl.String:
+			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.Widened}
+		case ddl.Numeric:
+			return ddl.Type{Name: ddl.Numeric}, nil
+		default:
+			return ddl.Type{Name: ddl.Float64}, nil
+		}
+	case blobAffinity:
+		if srcType.Name == "" {
+			// Columns without a declared type can hold values of any storage
+			// class, so there is no good Spanner type for them.
+			switch spType {
+			case ddl.Bytes:
+				return ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, nil
+			default:
+				return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.NoGoodType}
+			}
+		}
+		switch spType {
+		case ddl.String:
+			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
+		default:
+			return ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, nil
+		}
+	}
+	return toSpannerTypeNumericAffinity(srcType, spType)
+}
+
+// toSpannerTypeNumericAffinity maps types with NUMERIC affinity. This affinity
+// covers a variety of declared types that have well understood semantics.
+func toSpannerTypeNumericAffinity(srcType schema.Type, spType string) (ddl.Type, []internal.SchemaIssue) {
+	switch strings.ToLower(srcType.Name) {
+	case "bool", "boolean":
+		switch spType {
+		case ddl.String:
+			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.Widened}
+		case ddl.Int64:
+			return ddl.Type{Name: ddl.Int64}, []internal.SchemaIssue{internal.Widened}
+		default:
+			return ddl.Type{Name: ddl.Bool}, nil
+		}
+	case "date":
+		switch spType {
+		case ddl.String:
+			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.Widened}
+		default:
+			return ddl.Type{Name: ddl.Date}, nil
+		}
+	case "datetime", "timestamp":
+		switch spType {
+		case ddl.String:
+			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.Widened}
+		default:
+			return ddl.Type{Name: ddl.Timestamp}, []internal.SchemaIssue{internal.Timestamp}
+		}
+	case "json":
+		switch spType {
+		case ddl.String:
+			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
+		default:
+			return ddl.Type{Name: ddl.JSON}, nil
+		}
+	default:
+		// NUMERIC, DECIMAL(10,5) and any other declared type that doesn't
+		// match the affinity rules.
+		switch spType {
+		case ddl.String:
+			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.Widened}
+		case ddl.Float64:
+			return ddl.Type{Name: ddl.Float64}, []internal.SchemaIssue{internal.Widened}
+		default:
+			return ddl.Type{Name: ddl.Numeric}, nil
+		}
+	}
+}
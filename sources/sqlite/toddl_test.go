@@ -0,0 +1,96 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAffinity(t *testing.T) {
+	tests := []struct {
+		declared string
+		affinity string
+	}{
+		{"INT", integerAffinity},
+		{"TINYINT", integerAffinity},
+		{"UNSIGNED BIG INT", integerAffinity},
+		{"CHARINT", integerAffinity},
+		{"FLOATING POINT", integerAffinity},
+		{"VARCHAR(255)", textAffinity},
+		{"NCHAR(55)", textAffinity},
+		{"CLOB", textAffinity},
+		{"text", textAffinity},
+		{"BLOB", blobAffinity},
+		{"", blobAffinity},
+		{"REAL", realAffinity},
+		{"DOUBLE PRECISION", realAffinity},
+		{"FLOAT", realAffinity},
+		{"NUMERIC", numericAffinity},
+		{"DECIMAL(10,5)", numericAffinity},
+		{"BOOLEAN", numericAffinity},
+		{"DATETIME", numericAffinity},
+		{"STRING", numericAffinity},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.affinity, GetAffinity(tc.declared), tc.declared)
+	}
+}
+
+func TestToSpannerType(t *testing.T) {
+	tests := []struct {
+		srcType string
+		spType  string
+		want    ddl.Type
+		issues  []internal.SchemaIssue
+	}{
+		{"integer", "", ddl.Type{Name: ddl.Int64}, nil},
+		{"bigint", ddl.String, ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.Widened}},
+		{"varchar", "", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil},
+		{"text", ddl.JSON, ddl.Type{Name: ddl.JSON}, nil},
+		{"real", "", ddl.Type{Name: ddl.Float64}, nil},
+		{"double", ddl.Numeric, ddl.Type{Name: ddl.Numeric}, nil},
+		{"blob", "", ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, nil},
+		{"", "", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.NoGoodType}},
+		{"", ddl.Bytes, ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, nil},
+		{"boolean", "", ddl.Type{Name: ddl.Bool}, nil},
+		{"date", "", ddl.Type{Name: ddl.Date}, nil},
+		{"datetime", "", ddl.Type{Name: ddl.Timestamp}, []internal.SchemaIssue{internal.Timestamp}},
+		{"json", "", ddl.Type{Name: ddl.JSON}, nil},
+		{"decimal", "", ddl.Type{Name: ddl.Numeric}, nil},
+		{"decimal", ddl.Float64, ddl.Type{Name: ddl.Float64}, []internal.SchemaIssue{internal.Widened}},
+	}
+	conv := internal.MakeConv()
+	for _, tc := range tests {
+		ty, issues := ToDdlImpl{}.ToSpannerType(conv, tc.spType, schema.Type{Name: tc.srcType})
+		assert.Equal(t, tc.want, ty, tc.srcType)
+		assert.Equal(t, tc.issues, issues, tc.srcType)
+	}
+}
+
+func TestToSpannerPostgreSQLDialectType(t *testing.T) {
+	conv := internal.MakeConv()
+	conv.SpDialect = constants.DIALECT_POSTGRESQL
+	ty, issues := ToDdlImpl{}.ToSpannerType(conv, "", schema.Type{Name: "json"})
+	assert.Equal(t, ddl.Type{Name: ddl.JSON}, ty)
+	assert.Nil(t, issues)
+	ty, _ = ToDdlImpl{}.ToSpannerType(conv, "", schema.Type{Name: "varchar", Mods: []int64{10}})
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, ty)
+}
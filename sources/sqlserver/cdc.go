@@ -0,0 +1,757 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlserver
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	sp "cloud.google.com/go/spanner"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+)
+
+// This file implements change data capture for SQL Server by polling the
+// change tables of SQL Server's own change data capture with the
+// cdc.fn_cdc_get_all_changes_<capture_instance> functions, and applying the
+// changes to Spanner. It is the counterpart of the DynamoDB Streams
+// processing in sources/dynamodb/streaming.go.
+
+const (
+	// SkipSnapshotKey is set in the map returned by StartChangeDataCapture
+	// when streaming resumes from a persisted LSN, in which case the
+	// snapshot migration has already been done.
+	SkipSnapshotKey = "skipSnapshot"
+
+	cdcLSNKey           = "cdcLsn"
+	captureInstancesKey = "captureInstances"
+	// Maximum number of mutations written to Spanner in one call.
+	cdcBatchSize = 500
+	// Maximum number of source transactions read from the change tables in
+	// one query, which bounds the memory used to order the changes.
+	cdcPollTransactions = 1000
+	// Number of consecutive failures to poll the change tables before giving up.
+	cdcRetryLimit = 10
+)
+
+// Record types used for stats.
+const (
+	recordInsert = "INSERT"
+	recordUpdate = "UPDATE"
+	recordDelete = "DELETE"
+)
+
+// Values of the __$operation column of change tables.
+const (
+	opDelete       = 1
+	opInsert       = 2
+	opUpdateBefore = 3
+	opUpdateAfter  = 4
+)
+
+// lsn is a SQL Server log sequence number, a binary(10) value. LSNs are
+// compared as big-endian numbers.
+type lsn [10]byte
+
+func lsnFromBytes(b []byte) (lsn, error) {
+	var l lsn
+	if len(b) != len(l) {
+		return l, fmt.Errorf("invalid LSN 0x%X: want %d bytes, got %d", b, len(l), len(b))
+	}
+	copy(l[:], b)
+	return l, nil
+}
+
+// parseLSN parses an LSN in the 0x0000002A000001F00003 format used by SQL
+// Server tools.
+func parseLSN(s string) (lsn, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+	if err != nil {
+		return lsn{}, fmt.Errorf("invalid LSN %q: %v", s, err)
+	}
+	return lsnFromBytes(b)
+}
+
+func (l lsn) String() string {
+	return fmt.Sprintf("0x%X", l[:])
+}
+
+func (l lsn) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+func (l *lsn) UnmarshalText(text []byte) error {
+	v, err := parseLSN(string(text))
+	if err != nil {
+		return err
+	}
+	*l = v
+	return nil
+}
+
+func (l lsn) before(m lsn) bool {
+	return bytes.Compare(l[:], m[:]) < 0
+}
+
+func (l lsn) isZero() bool {
+	return l == lsn{}
+}
+
+// next returns the next LSN, as sys.fn_cdc_increment_lsn does.
+func (l lsn) next() lsn {
+	for i := len(l) - 1; i >= 0; i-- {
+		l[i]++
+		if l[i] != 0 {
+			break
+		}
+	}
+	return l
+}
+
+// cdcState is persisted in the state file.
+type cdcState struct {
+	// LSN is the last LSN whose changes have been applied to Spanner.
+	LSN lsn `json:"lsn"`
+}
+
+// loadCdcState reads the LSN persisted in stateFile. It returns false if
+// the file doesn't exist.
+func loadCdcState(stateFile string) (lsn, bool, error) {
+	var state cdcState
+	data, err := os.ReadFile(stateFile)
+	if os.IsNotExist(err) {
+		return state.LSN, false, nil
+	}
+	if err != nil {
+		return state.LSN, false, fmt.Errorf("can't read cdc state file %s: %v", stateFile, err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state.LSN, false, fmt.Errorf("can't parse cdc state file %s: %v", stateFile, err)
+	}
+	if state.LSN.isZero() {
+		return state.LSN, false, fmt.Errorf("cdc state file %s has no LSN", stateFile)
+	}
+	return state.LSN, true, nil
+}
+
+// saveCdcState persists pos in stateFile. The state is written to a
+// temporary file first, so that a crash never leaves a truncated state file.
+func saveCdcState(stateFile string, pos lsn) error {
+	data, err := json.MarshalIndent(cdcState{LSN: pos}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := stateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("can't write cdc state file: %v", err)
+	}
+	if err := os.Rename(tmp, stateFile); err != nil {
+		return fmt.Errorf("can't write cdc state file: %v", err)
+	}
+	return nil
+}
+
+// checkCdcEnabled verifies that change data capture is enabled for the
+// source database.
+func checkCdcEnabled(db *sql.DB, dbName string) error {
+	var enabled bool
+	if err := db.QueryRow("SELECT is_cdc_enabled FROM sys.databases WHERE name = DB_NAME()").Scan(&enabled); err != nil {
+		return fmt.Errorf("can't read change data capture configuration: %v", err)
+	}
+	if !enabled {
+		return fmt.Errorf("change data capture is disabled for database %s, please enable it with sys.sp_cdc_enable_db", dbName)
+	}
+	return nil
+}
+
+// queryMaxLSN returns the LSN of the last change recorded in the change tables.
+func queryMaxLSN(db *sql.DB) (lsn, error) {
+	var b []byte
+	if err := db.QueryRow("SELECT sys.fn_cdc_get_max_lsn()").Scan(&b); err != nil {
+		return lsn{}, fmt.Errorf("can't read max LSN: %v", err)
+	}
+	if b == nil {
+		return lsn{}, fmt.Errorf("can't read max LSN: no changes captured yet, is the SQL Server Agent running?")
+	}
+	return lsnFromBytes(b)
+}
+
+// captureInstance is the change data capture instance of a migrated table.
+type captureInstance struct {
+	name    string
+	tableId string
+	// colIds are the migrated columns captured by the instance.
+	colIds []string
+	// minLSN is the oldest LSN available in the change table. It moves
+	// forward when the cleanup job removes old changes.
+	minLSN lsn
+}
+
+// queryCaptureInstances finds the capture instances of the tables being
+// migrated. If a table has two capture instances, the newest one is used.
+// Tables without a primary key are skipped.
+func queryCaptureInstances(db *sql.DB, conv *internal.Conv) ([]*captureInstance, error) {
+	q := `SELECT s.name, t.name, ct.capture_instance, ct.start_lsn, cc.column_name
+		FROM cdc.change_tables ct
+		JOIN sys.tables t ON t.object_id = ct.source_object_id
+		JOIN sys.schemas s ON s.schema_id = t.schema_id
+		JOIN cdc.captured_columns cc ON cc.object_id = ct.object_id
+		ORDER BY ct.create_date, ct.capture_instance, cc.column_ordinal`
+	rows, err := db.Query(q)
+	if err != nil {
+		return nil, fmt.Errorf("can't read capture instances: %v", err)
+	}
+	defer rows.Close()
+	tableInstances := make(map[string]string)
+	minLSNs := make(map[string]lsn)
+	capturedCols := make(map[string]map[string]bool)
+	for rows.Next() {
+		var schemaName, tableName, instance, column string
+		var startLSN []byte
+		if err := rows.Scan(&schemaName, &tableName, &instance, &startLSN, &column); err != nil {
+			return nil, fmt.Errorf("can't read capture instances: %v", err)
+		}
+		l, err := lsnFromBytes(startLSN)
+		if err != nil {
+			return nil, fmt.Errorf("can't read capture instance %s: %v", instance, err)
+		}
+		tableInstances[schemaName+"."+tableName] = instance
+		minLSNs[instance] = l
+		if capturedCols[instance] == nil {
+			capturedCols[instance] = make(map[string]bool)
+		}
+		capturedCols[instance][column] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("can't read capture instances: %v", err)
+	}
+
+	var tableIds []string
+	for tableId := range conv.SrcSchema {
+		if _, ok := conv.SpSchema[tableId]; ok {
+			tableIds = append(tableIds, tableId)
+		}
+	}
+	sort.Strings(tableIds)
+	var instances []*captureInstance
+	var missing []string
+	for _, tableId := range tableIds {
+		srcSchema, spSchema := conv.SrcSchema[tableId], conv.SpSchema[tableId]
+		if _, ok := conv.SyntheticPKeys[tableId]; ok {
+			// Rows of tables without a primary key can't be matched to the
+			// rows written during the snapshot migration.
+			conv.Unexpected(fmt.Sprintf("Changes to table %s not streamed to Spanner: table has no primary key", srcSchema.Name))
+			continue
+		}
+		tableName := strings.Replace(srcSchema.Name, srcSchema.Schema+".", "", 1)
+		name, ok := tableInstances[srcSchema.Schema+"."+tableName]
+		if !ok {
+			missing = append(missing, srcSchema.Schema+"."+tableName)
+			continue
+		}
+		ci := &captureInstance{name: name, tableId: tableId, minLSN: minLSNs[name]}
+		for _, colId := range common.IntersectionOfTwoStringSlices(spSchema.ColIds, srcSchema.ColIds) {
+			if capturedCols[name][srcSchema.ColDefs[colId].Name] {
+				ci.colIds = append(ci.colIds, colId)
+			}
+		}
+		for _, k := range spSchema.PrimaryKeys {
+			if col := srcSchema.ColDefs[k.ColId].Name; !capturedCols[name][col] {
+				return nil, fmt.Errorf("capture instance %s of table %s doesn't capture key column %s", name, srcSchema.Name, col)
+			}
+		}
+		instances = append(instances, ci)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("change data capture is disabled for tables %s, please enable it with sys.sp_cdc_enable_table", strings.Join(missing, ", "))
+	}
+	return instances, nil
+}
+
+// changeRow is a row of a change table.
+type changeRow struct {
+	instance  *captureInstance
+	startLSN  lsn // Commit LSN of the source transaction.
+	seqval    lsn // Orders the changes within the transaction.
+	operation int64
+	// commitTime is the commit time of the source transaction, zero if
+	// unknown.
+	commitTime time.Time
+	// vals are the values of instance.colIds, as returned by the driver.
+	vals []interface{}
+}
+
+// changeReader reads the change tables, usually through the source database.
+type changeReader interface {
+	// maxLSN returns the LSN of the last change recorded in the change tables.
+	maxLSN() (lsn, error)
+	// minLSN returns the oldest LSN available for a capture instance.
+	minLSN(instance string) (lsn, error)
+	// windowEnd returns the commit LSN of the n-th transaction after from,
+	// or of the last one up to to. It returns false if no transactions
+	// committed in between.
+	windowEnd(from, to lsn, n int) (lsn, bool, error)
+	// changes returns the changes of a capture instance committed between
+	// from and to, inclusive.
+	changes(ci *captureInstance, from, to lsn) ([]*changeRow, error)
+}
+
+type dbChangeReader struct {
+	db   *sql.DB
+	conv *internal.Conv
+}
+
+func (r dbChangeReader) maxLSN() (lsn, error) {
+	return queryMaxLSN(r.db)
+}
+
+func (r dbChangeReader) minLSN(instance string) (lsn, error) {
+	var b []byte
+	if err := r.db.QueryRow("SELECT sys.fn_cdc_get_min_lsn(@p1)", instance).Scan(&b); err != nil {
+		return lsn{}, fmt.Errorf("can't read min LSN of capture instance %s: %v", instance, err)
+	}
+	l, err := lsnFromBytes(b)
+	if err == nil && l.isZero() {
+		err = fmt.Errorf("capture instance %s doesn't exist anymore", instance)
+	}
+	return l, err
+}
+
+func (r dbChangeReader) windowEnd(from, to lsn, n int) (lsn, bool, error) {
+	q := `SELECT MAX(start_lsn) FROM (
+		SELECT TOP (@p1) start_lsn FROM cdc.lsn_time_mapping
+		WHERE start_lsn > @p2 AND start_lsn <= @p3 ORDER BY start_lsn) AS w`
+	var b []byte
+	if err := r.db.QueryRow(q, n, from[:], to[:]).Scan(&b); err != nil {
+		return lsn{}, false, fmt.Errorf("can't read transactions after LSN %s: %v", from, err)
+	}
+	if b == nil {
+		return lsn{}, false, nil
+	}
+	l, err := lsnFromBytes(b)
+	return l, err == nil, err
+}
+
+// getChangesQuery returns the query for the changes of a capture instance.
+// The before image of updates is requested, to detect changes to the
+// primary key, and the commit time of the changes is read to report the
+// streaming lag.
+func getChangesQuery(ci *captureInstance, conv *internal.Conv) string {
+	selects := getSelectColumns(ci.colIds, conv.SrcSchema[ci.tableId].ColDefs)
+	fn := "fn_cdc_get_all_changes_" + ci.name
+	return fmt.Sprintf("SELECT __$start_lsn, __$seqval, __$operation, sys.fn_cdc_map_lsn_to_time(__$start_lsn), %s FROM [cdc].[%s](@p1, @p2, N'all update old')",
+		strings.Join(selects, ", "), strings.ReplaceAll(fn, "]", "]]"))
+}
+
+func (r dbChangeReader) changes(ci *captureInstance, from, to lsn) ([]*changeRow, error) {
+	rows, err := r.db.Query(getChangesQuery(ci, r.conv), from[:], to[:])
+	if err != nil {
+		return nil, fmt.Errorf("can't read changes of capture instance %s: %v", ci.name, err)
+	}
+	defer rows.Close()
+	var changes []*changeRow
+	for rows.Next() {
+		var startLSN, seqval []byte
+		var operation int64
+		var commitTime sql.NullTime
+		v, scanArgs := buildVals(len(ci.colIds))
+		if err := rows.Scan(append([]interface{}{&startLSN, &seqval, &operation, &commitTime}, scanArgs...)...); err != nil {
+			return nil, fmt.Errorf("can't read changes of capture instance %s: %v", ci.name, err)
+		}
+		c := &changeRow{instance: ci, operation: operation, commitTime: commitTime.Time, vals: v}
+		if c.startLSN, err = lsnFromBytes(startLSN); err == nil {
+			c.seqval, err = lsnFromBytes(seqval)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't read changes of capture instance %s: %v", ci.name, err)
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// changeTableCDC applies the changes read from the change tables to Spanner.
+type changeTableCDC struct {
+	conv         *internal.Conv
+	info         *common.StreamingInfo
+	reader       changeReader
+	instances    []*captureInstance
+	stateFile    string
+	pollInterval time.Duration
+	// pos is the last LSN whose changes have been applied to Spanner.
+	pos       lsn
+	lastSaved lsn
+	// before is the before image of the update being processed.
+	before  *changeRow
+	pending []common.PendingMutation
+	// cutoverLSN is set when a cutover has been requested, to the max LSN
+	// at that time.
+	cutoverLSN   *lsn
+	cutoverDone  bool
+	lastProgress time.Time
+	stop         chan struct{}
+	stopOnce     sync.Once
+}
+
+func newChangeTableCDC(conv *internal.Conv, info *common.StreamingInfo, reader changeReader, instances []*captureInstance, stateFile string, pollInterval time.Duration, pos lsn) *changeTableCDC {
+	if pollInterval <= 0 {
+		pollInterval = profiles.DefaultSqlServerCdcPollInterval
+	}
+	return &changeTableCDC{
+		conv:         conv,
+		info:         info,
+		reader:       reader,
+		instances:    instances,
+		stateFile:    stateFile,
+		pollInterval: pollInterval,
+		pos:          pos,
+		lastSaved:    pos,
+		stop:         make(chan struct{}),
+	}
+}
+
+// poll applies the changes committed since the last poll, a window of
+// cdcPollTransactions transactions at a time.
+func (c *changeTableCDC) poll() error {
+	max, err := c.reader.maxLSN()
+	if err != nil {
+		return err
+	}
+	if c.cutoverLSN == nil {
+		if _, err := os.Stat(common.CutoverFile(c.stateFile)); err == nil {
+			fmt.Printf("Cutover requested, applying changes up to LSN %s...\n", max)
+			c.cutoverLSN = &max
+		}
+	}
+	for c.pos.before(max) && !c.info.Exited() {
+		end, ok, err := c.reader.windowEnd(c.pos, max, cdcPollTransactions)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// No transactions with changes, only an advance of the max LSN.
+			end = max
+		} else if err := c.apply(c.pos.next(), end); err != nil {
+			return err
+		}
+		c.pos = end
+		c.saveState()
+		c.progress(max)
+	}
+	if c.cutoverLSN != nil && !c.pos.before(*c.cutoverLSN) {
+		c.cutoverDone = true
+	}
+	return nil
+}
+
+// apply reads the changes committed between from and to from all change
+// tables, and writes them to Spanner in commit order.
+func (c *changeTableCDC) apply(from, to lsn) error {
+	var changes []*changeRow
+	for _, ci := range c.instances {
+		min, err := c.reader.minLSN(ci.name)
+		if err != nil {
+			return err
+		}
+		start := from
+		if start.before(min) {
+			// The instance may have been enabled after from, but if its
+			// oldest LSN moved, the cleanup job removed changes we need.
+			if min != ci.minLSN {
+				return fmt.Errorf("changes of capture instance %s before LSN %s were removed by the change data capture cleanup job before being applied", ci.name, min)
+			}
+			start = min
+		}
+		ci.minLSN = min
+		if to.before(start) {
+			continue
+		}
+		rows, err := c.reader.changes(ci, start, to)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, rows...)
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.startLSN != b.startLSN {
+			return a.startLSN.before(b.startLSN)
+		}
+		if a.seqval != b.seqval {
+			return a.seqval.before(b.seqval)
+		}
+		return a.operation < b.operation
+	})
+	for i, r := range changes {
+		if i > 0 && r.startLSN != changes[i-1].startLSN {
+			c.flush()
+		}
+		c.processChange(r)
+	}
+	c.flush()
+	return nil
+}
+
+func (c *changeTableCDC) processChange(r *changeRow) {
+	srcTable := c.conv.SrcSchema[r.instance.tableId].Name
+	switch r.operation {
+	case opUpdateBefore:
+		c.before = r
+		return
+	case opInsert:
+		c.info.StatsAddRecord(srcTable, recordInsert)
+		c.upsert(r, recordInsert)
+	case opDelete:
+		c.info.StatsAddRecord(srcTable, recordDelete)
+		c.delete(r, recordDelete)
+	case opUpdateAfter:
+		c.info.StatsAddRecord(srcTable, recordUpdate)
+		// If the primary key changed, the old row must be deleted.
+		if b := c.before; b != nil && b.instance == r.instance && b.seqval == r.seqval {
+			_, _, oldKey, _, _, err1 := c.convertRow(b, true)
+			_, _, newKey, _, _, err2 := c.convertRow(r, true)
+			if err1 == nil && err2 == nil && !reflect.DeepEqual(oldKey, newKey) {
+				c.delete(b, recordUpdate)
+			}
+		}
+		c.upsert(r, recordUpdate)
+	default:
+		c.info.Unexpected(fmt.Sprintf("Unknown change table operation %d for table %s", r.operation, srcTable))
+	}
+	c.before = nil
+	c.info.StatsAddRecordProcessed()
+}
+
+func (c *changeTableCDC) upsert(r *changeRow, recordType string) {
+	srcTable := c.conv.SrcSchema[r.instance.tableId].Name
+	spTable, cols, vals, srcCols, srcVals, err := c.convertRow(r, false)
+	if err != nil {
+		c.badRecord(srcTable, recordType, srcCols, srcVals, err)
+		return
+	}
+	c.pending = append(c.pending, common.PendingMutation{M: sp.InsertOrUpdate(spTable, cols, vals), SrcTable: srcTable, SpTable: spTable, RecordType: recordType, Cols: cols, Vals: vals, CommitTime: r.commitTime})
+}
+
+func (c *changeTableCDC) delete(r *changeRow, recordType string) {
+	srcTable := c.conv.SrcSchema[r.instance.tableId].Name
+	spTable, cols, vals, srcCols, srcVals, err := c.convertRow(r, true)
+	if err != nil {
+		c.badRecord(srcTable, recordType, srcCols, srcVals, err)
+		return
+	}
+	c.pending = append(c.pending, common.PendingMutation{M: sp.Delete(spTable, sp.Key(vals)), SrcTable: srcTable, SpTable: spTable, RecordType: recordType, Cols: cols, Vals: vals, CommitTime: r.commitTime})
+}
+
+func (c *changeTableCDC) badRecord(srcTable, recordType string, srcCols, srcVals []string, err error) {
+	c.info.Unexpected(fmt.Sprintf("Error while converting change table data: %s", err))
+	c.info.StatsAddBadRecord(srcTable, recordType)
+	c.info.CollectBadRecord(recordType, srcTable, srcCols, srcVals)
+}
+
+// convertRow converts a change table row to Spanner columns and values. If
+// keyOnly is set, only the primary key columns are converted, in key order.
+// NULL values are returned as nil, so that updates clear the column in
+// Spanner. The source columns and values are returned for error reporting.
+func (c *changeTableCDC) convertRow(r *changeRow, keyOnly bool) (string, []string, []interface{}, []string, []string, error) {
+	tableId := r.instance.tableId
+	srcSchema := c.conv.SrcSchema[tableId]
+	spSchema := c.conv.SpSchema[tableId]
+	index := make(map[string]int)
+	for i, colId := range r.instance.colIds {
+		index[colId] = i
+	}
+	colIds := r.instance.colIds
+	if keyOnly {
+		colIds = nil
+		for _, k := range spSchema.PrimaryKeys {
+			colIds = append(colIds, k.ColId)
+		}
+	}
+	var valColIds, srcCols, vals, nullCols []string
+	for _, colId := range colIds {
+		name := srcSchema.ColDefs[colId].Name
+		i, ok := index[colId]
+		if !ok || r.vals[i] == nil {
+			if keyOnly {
+				return "", nil, nil, srcCols, vals, fmt.Errorf("key column %s of table %s is NULL", name, srcSchema.Name)
+			}
+			nullCols = append(nullCols, spSchema.ColDefs[colId].Name)
+			continue
+		}
+		valColIds = append(valColIds, colId)
+		srcCols = append(srcCols, name)
+		vals = append(vals, valsToStrings(r.vals[i : i+1])[0])
+	}
+	spTable, cols, spVals, err := ConvertData(c.conv, tableId, valColIds, srcSchema, spSchema, vals)
+	if err != nil {
+		return "", nil, nil, srcCols, vals, err
+	}
+	for _, col := range nullCols {
+		cols = append(cols, col)
+		spVals = append(spVals, nil)
+	}
+	return spTable, cols, spVals, srcCols, vals, nil
+}
+
+// flush writes the changes of the current transaction to Spanner. Small
+// transactions are written atomically; if a write fails, its mutations are
+// retried one at a time so that only the failing records are dropped.
+func (c *changeTableCDC) flush() {
+	pending := c.pending
+	c.pending = nil
+	c.info.Flush(pending, cdcBatchSize)
+}
+
+func (c *changeTableCDC) saveState() {
+	if c.pos == c.lastSaved {
+		return
+	}
+	if err := saveCdcState(c.stateFile, c.pos); err != nil {
+		c.info.Unexpected(err.Error())
+		return
+	}
+	c.lastSaved = c.pos
+}
+
+// progress reports progress at most once a minute.
+func (c *changeTableCDC) progress(max lsn) {
+	now := time.Now()
+	if now.Sub(c.lastProgress) < time.Minute {
+		return
+	}
+	c.lastProgress = now
+	fmt.Printf("Change data capture LSN: %s, max LSN: %s, count of records processed: %d\n", c.pos, max, c.info.Processed())
+}
+
+// sleep waits for d, or until the user exits.
+func (c *changeTableCDC) sleep(d time.Duration) {
+	select {
+	case <-c.stop:
+	case <-time.After(d):
+	}
+}
+
+// stream polls the change tables until the user exits or a cutover
+// completes, retrying if polling fails.
+func (c *changeTableCDC) stream() error {
+	var err error
+	failures := 0
+	for !c.info.Exited() && !c.cutoverDone {
+		start := c.pos
+		err = c.poll()
+		if c.info.Exited() || c.cutoverDone {
+			err = nil
+			break
+		}
+		if err == nil {
+			failures = 0
+			c.sleep(c.pollInterval)
+			continue
+		}
+		if c.pos != start {
+			failures = 0
+		}
+		failures++
+		c.info.Unexpected(fmt.Sprintf("Polling of change tables failed: %v", err))
+		if failures >= cdcRetryLimit {
+			break
+		}
+		c.sleep(5 * time.Second)
+	}
+	c.saveState()
+	if c.cutoverDone {
+		os.Remove(common.CutoverFile(c.stateFile))
+	}
+	return err
+}
+
+// catchCtrlC stops streaming when the user presses Ctrl+C.
+func (c *changeTableCDC) catchCtrlC() {
+	c.info.CatchCtrlC(func() { c.stopOnce.Do(func() { close(c.stop) }) })
+}
+
+// startChangeTableCapture captures the LSN from which changes are streamed
+// to Spanner, or reads it from the state file if streaming is being resumed.
+func (isi InfoSchemaImpl) startChangeTableCapture(conv *internal.Conv) (map[string]interface{}, error) {
+	cfg := isi.SourceProfile.Conn.SqlServer
+	if err := checkCdcEnabled(isi.Db, isi.DbName); err != nil {
+		return nil, err
+	}
+	instances, err := queryCaptureInstances(isi.Db, conv)
+	if err != nil {
+		return nil, err
+	}
+	pos, ok, err := loadCdcState(cfg.CdcStateFile)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		for _, ci := range instances {
+			if pos.next().before(ci.minLSN) {
+				return nil, fmt.Errorf("can't resume from LSN %s saved in %s: the oldest change of capture instance %s is at LSN %s, newer changes were removed by the cleanup job", pos, cfg.CdcStateFile, ci.name, ci.minLSN)
+			}
+		}
+		fmt.Printf("Resuming change data capture streaming from LSN %s saved in %s, skipping snapshot migration.\n", pos, cfg.CdcStateFile)
+		return map[string]interface{}{cdcLSNKey: pos, captureInstancesKey: instances, SkipSnapshotKey: true}, nil
+	}
+	pos, err = queryMaxLSN(isi.Db)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Captured LSN %s, changes after this LSN will be streamed after the snapshot migration.\n", pos)
+	return map[string]interface{}{cdcLSNKey: pos, captureInstancesKey: instances}, nil
+}
+
+// streamChangeTables applies the changes in the change tables to Spanner
+// until the user exits with Ctrl+C or a cutover is requested with the
+// cutover command.
+func (isi InfoSchemaImpl) streamChangeTables(client *sp.Client, conv *internal.Conv, streamInfo map[string]interface{}) error {
+	cfg := isi.SourceProfile.Conn.SqlServer
+	pos, ok := streamInfo[cdcLSNKey].(lsn)
+	if !ok {
+		return fmt.Errorf("change data capture LSN not captured")
+	}
+	instances, _ := streamInfo[captureInstancesKey].([]*captureInstance)
+	// Persist the starting LSN, so that a restart doesn't redo the
+	// snapshot migration.
+	if err := saveCdcState(cfg.CdcStateFile, pos); err != nil {
+		return err
+	}
+	fmt.Println("Processing of the SQL Server change tables started...")
+	fmt.Printf("Use Ctrl+C to stop the process, or run 'cutover -state-file=%s' to stop once all changes made so far are applied.\n", common.AbsPath(cfg.CdcStateFile))
+
+	info := common.MakeStreamingInfo()
+	info.SetWriter(client, conv)
+	c := newChangeTableCDC(conv, info, dbChangeReader{db: isi.Db, conv: conv}, instances, cfg.CdcStateFile, cfg.CdcPollInterval, pos)
+	c.catchCtrlC()
+	err := c.stream()
+	info.FillConv(conv)
+	if err != nil {
+		return fmt.Errorf("change data capture streaming stopped at LSN %s: %v", c.pos, err)
+	}
+	fmt.Printf("Change data capture streaming stopped at LSN %s, saved in %s.\n", c.pos, cfg.CdcStateFile)
+	return nil
+}
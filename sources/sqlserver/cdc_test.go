@@ -0,0 +1,374 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlserver
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	sp "cloud.google.com/go/spanner"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// buildCdcConv returns a conv with the tables dbo.product and sales.orders.
+func buildCdcConv() *internal.Conv {
+	conv := internal.MakeConv()
+	conv.SpSchema["t1"] = ddl.CreateTable{
+		Name:   "product",
+		Id:     "t1",
+		ColIds: []string{"c1", "c2", "c3"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"c1": {Name: "id", Id: "c1", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+			"c2": {Name: "name", Id: "c2", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			"c3": {Name: "price", Id: "c3", T: ddl.Type{Name: ddl.Float64}},
+		},
+		PrimaryKeys: []ddl.IndexKey{{ColId: "c1", Order: 1}},
+	}
+	conv.SrcSchema["t1"] = schema.Table{
+		Name:   "product",
+		Schema: "dbo",
+		Id:     "t1",
+		ColIds: []string{"c1", "c2", "c3"},
+		ColDefs: map[string]schema.Column{
+			"c1": {Name: "id", Id: "c1", Type: schema.Type{Name: "int"}},
+			"c2": {Name: "name", Id: "c2", Type: schema.Type{Name: "nvarchar"}},
+			"c3": {Name: "price", Id: "c3", Type: schema.Type{Name: "float"}},
+		},
+		PrimaryKeys: []schema.Key{{ColId: "c1"}},
+	}
+	conv.SpSchema["t2"] = ddl.CreateTable{
+		Name:   "sales_orders",
+		Id:     "t2",
+		ColIds: []string{"c4", "c5", "c6"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"c4": {Name: "order_id", Id: "c4", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+			"c5": {Name: "product_id", Id: "c5", T: ddl.Type{Name: ddl.Int64}},
+			"c6": {Name: "placed", Id: "c6", T: ddl.Type{Name: ddl.Timestamp}},
+		},
+		PrimaryKeys: []ddl.IndexKey{{ColId: "c4", Order: 1}},
+	}
+	conv.SrcSchema["t2"] = schema.Table{
+		Name:   "sales.orders",
+		Schema: "sales",
+		Id:     "t2",
+		ColIds: []string{"c4", "c5", "c6"},
+		ColDefs: map[string]schema.Column{
+			"c4": {Name: "order_id", Id: "c4", Type: schema.Type{Name: "int"}},
+			"c5": {Name: "product_id", Id: "c5", Type: schema.Type{Name: "int"}},
+			"c6": {Name: "placed", Id: "c6", Type: schema.Type{Name: "datetime2"}},
+		},
+		PrimaryKeys: []schema.Key{{ColId: "c4"}},
+	}
+	return conv
+}
+
+func mkLSN(b byte) lsn {
+	return lsn{0, 0, 0, 0x2a, 0, 0, 0, 0, 0, b}
+}
+
+func lsnBytes(b byte) []byte {
+	l := mkLSN(b)
+	return l[:]
+}
+
+func TestLSN(t *testing.T) {
+	l, err := parseLSN("0x0000002A000001F00003")
+	assert.Nil(t, err)
+	assert.Equal(t, lsn{0, 0, 0, 0x2a, 0, 0, 1, 0xf0, 0, 3}, l)
+	assert.Equal(t, "0x0000002A000001F00003", l.String())
+	assert.True(t, mkLSN(3).before(l))
+	assert.False(t, l.before(l))
+	assert.Equal(t, lsn{0, 0, 0, 0x2a, 0, 0, 1, 0xf1, 0, 0}, lsn{0, 0, 0, 0x2a, 0, 0, 1, 0xf0, 0xff, 0xff}.next())
+	_, err = parseLSN("0x2A")
+	assert.NotNil(t, err)
+	_, err = parseLSN("0xZZ00002A000001F00003")
+	assert.NotNil(t, err)
+
+	data, err := json.Marshal(cdcState{LSN: l})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"lsn":"0x0000002A000001F00003"}`, string(data))
+	var state cdcState
+	assert.Nil(t, json.Unmarshal(data, &state))
+	assert.Equal(t, l, state.LSN)
+}
+
+func TestCdcState(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	_, ok, err := loadCdcState(stateFile)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.NotNil(t, common.RequestCutover(stateFile))
+
+	assert.Nil(t, saveCdcState(stateFile, mkLSN(7)))
+	pos, ok, err := loadCdcState(stateFile)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, mkLSN(7), pos)
+
+	assert.Nil(t, os.WriteFile(stateFile, []byte(`{"lsn": "0x00"}`), 0644))
+	_, _, err = loadCdcState(stateFile)
+	assert.NotNil(t, err)
+}
+
+var captureInstanceCols = []string{"schema", "table", "capture_instance", "start_lsn", "column_name"}
+
+func TestQueryCaptureInstances(t *testing.T) {
+	old, current, orders := mkLSN(1), mkLSN(5), mkLSN(2)
+	db := mkMockDB(t, []mockSpec{{
+		query: "SELECT (.+) FROM cdc.change_tables ct (.+)",
+		cols:  captureInstanceCols,
+		rows: [][]driver.Value{
+			{"dbo", "product", "dbo_product_old", old[:], "id"},
+			{"dbo", "product", "dbo_product_old", old[:], "name"},
+			{"sales", "orders", "sales_orders", orders[:], "order_id"},
+			{"sales", "orders", "sales_orders", orders[:], "placed"},
+			{"dbo", "product", "dbo_product", current[:], "id"},
+			{"dbo", "product", "dbo_product", current[:], "name"},
+			{"dbo", "product", "dbo_product", current[:], "price"},
+		},
+	}})
+	instances, err := queryCaptureInstances(db, buildCdcConv())
+	assert.Nil(t, err)
+	assert.Equal(t, []*captureInstance{
+		{name: "dbo_product", tableId: "t1", colIds: []string{"c1", "c2", "c3"}, minLSN: current},
+		{name: "sales_orders", tableId: "t2", colIds: []string{"c4", "c6"}, minLSN: orders},
+	}, instances)
+}
+
+func TestQueryCaptureInstancesErrors(t *testing.T) {
+	l := mkLSN(1)
+	tests := []struct {
+		name string
+		rows [][]driver.Value
+		want string
+	}{
+		{
+			name: "table without capture instance",
+			rows: [][]driver.Value{{"dbo", "product", "dbo_product", l[:], "id"}},
+			want: "change data capture is disabled for tables sales.orders, please enable it with sys.sp_cdc_enable_table",
+		},
+		{
+			name: "key column not captured",
+			rows: [][]driver.Value{
+				{"dbo", "product", "dbo_product", l[:], "name"},
+				{"sales", "orders", "sales_orders", l[:], "order_id"},
+			},
+			want: "capture instance dbo_product of table product doesn't capture key column id",
+		},
+	}
+	for _, tc := range tests {
+		db := mkMockDB(t, []mockSpec{{query: "SELECT (.+) FROM cdc.change_tables ct (.+)", cols: captureInstanceCols, rows: tc.rows}})
+		_, err := queryCaptureInstances(db, buildCdcConv())
+		assert.EqualError(t, err, tc.want, tc.name)
+	}
+
+	// Tables without a primary key are skipped.
+	conv := buildCdcConv()
+	conv.SyntheticPKeys["t2"] = internal.SyntheticPKey{ColId: "c7"}
+	db := mkMockDB(t, []mockSpec{{query: "SELECT (.+) FROM cdc.change_tables ct (.+)", cols: captureInstanceCols, rows: [][]driver.Value{{"dbo", "product", "dbo_product", l[:], "id"}}}})
+	instances, err := queryCaptureInstances(db, conv)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(instances))
+	assert.Equal(t, int64(1), conv.Unexpecteds())
+}
+
+func TestDbChangeReader(t *testing.T) {
+	conv := buildCdcConv()
+	ci := &captureInstance{name: "sales_orders", tableId: "t2", colIds: []string{"c4", "c6"}}
+	assert.Equal(t, "SELECT __$start_lsn, __$seqval, __$operation, sys.fn_cdc_map_lsn_to_time(__$start_lsn), [order_id], CONVERT(VARCHAR(33), [placed], 126) AS placed FROM [cdc].[fn_cdc_get_all_changes_sales_orders](@p1, @p2, N'all update old')", getChangesQuery(ci, conv))
+
+	from, to, min := mkLSN(3), mkLSN(9), mkLSN(1)
+	commitTime := time.Date(2023, 5, 6, 7, 8, 10, 0, time.UTC)
+	db := mkMockDB(t, []mockSpec{
+		{
+			query: `SELECT sys.fn_cdc_get_min_lsn\(@p1\)`,
+			args:  []driver.Value{"sales_orders"},
+			cols:  []string{"lsn"},
+			rows:  [][]driver.Value{{min[:]}},
+		},
+		{
+			query: "SELECT MAX(.+) FROM cdc.lsn_time_mapping (.+)",
+			args:  []driver.Value{int64(2), from[:], to[:]},
+			cols:  []string{"lsn"},
+			rows:  [][]driver.Value{{lsnBytes(5)}},
+		},
+		{
+			query: "SELECT MAX(.+) FROM cdc.lsn_time_mapping (.+)",
+			cols:  []string{"lsn"},
+			rows:  [][]driver.Value{{nil}},
+		},
+		{
+			query: `SELECT __\$start_lsn, __\$seqval, __\$operation, (.+) FROM \[cdc\].\[fn_cdc_get_all_changes_sales_orders\]`,
+			args:  []driver.Value{from[:], to[:]},
+			cols:  []string{"__$start_lsn", "__$seqval", "__$operation", "commit_time", "order_id", "placed"},
+			rows: [][]driver.Value{
+				{lsnBytes(4), lsnBytes(4), int64(2), commitTime, int64(11), "2023-05-06T07:08:09"},
+				{lsnBytes(6), lsnBytes(5), int64(1), nil, int64(11), nil},
+			},
+		},
+	})
+	r := dbChangeReader{db: db, conv: conv}
+	l, err := r.minLSN("sales_orders")
+	assert.Nil(t, err)
+	assert.Equal(t, min, l)
+	end, ok, err := r.windowEnd(from, to, 2)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, mkLSN(5), end)
+	_, ok, err = r.windowEnd(from, to, 2)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	changes, err := r.changes(ci, from, to)
+	assert.Nil(t, err)
+	assert.Equal(t, []*changeRow{
+		{instance: ci, startLSN: mkLSN(4), seqval: mkLSN(4), operation: opInsert, commitTime: commitTime, vals: []interface{}{int64(11), "2023-05-06T07:08:09"}},
+		{instance: ci, startLSN: mkLSN(6), seqval: mkLSN(5), operation: opDelete, vals: []interface{}{int64(11), nil}},
+	}, changes)
+}
+
+// fakeChangeReader serves changes from memory.
+type fakeChangeReader struct {
+	max     lsn
+	min     map[string]lsn
+	rows    []*changeRow
+	queried [][2]lsn
+}
+
+func (r *fakeChangeReader) maxLSN() (lsn, error) {
+	return r.max, nil
+}
+
+func (r *fakeChangeReader) minLSN(instance string) (lsn, error) {
+	return r.min[instance], nil
+}
+
+func (r *fakeChangeReader) windowEnd(from, to lsn, n int) (lsn, bool, error) {
+	var commits []lsn
+	for _, row := range r.rows {
+		if from.before(row.startLSN) && !to.before(row.startLSN) {
+			commits = append(commits, row.startLSN)
+		}
+	}
+	if len(commits) == 0 {
+		return lsn{}, false, nil
+	}
+	sort.Slice(commits, func(i, j int) bool { return commits[i].before(commits[j]) })
+	return commits[len(commits)-1], true, nil
+}
+
+func (r *fakeChangeReader) changes(ci *captureInstance, from, to lsn) ([]*changeRow, error) {
+	r.queried = append(r.queried, [2]lsn{from, to})
+	var changes []*changeRow
+	for _, row := range r.rows {
+		if row.instance == ci && !row.startLSN.before(from) && !to.before(row.startLSN) {
+			changes = append(changes, row)
+		}
+	}
+	return changes, nil
+}
+
+func TestChangeTableCDC_StreamUntilCutover(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	start := mkLSN(10)
+	assert.Nil(t, saveCdcState(stateFile, start))
+	assert.Nil(t, common.RequestCutover(stateFile))
+
+	product := &captureInstance{name: "dbo_product", tableId: "t1", colIds: []string{"c1", "c2", "c3"}, minLSN: mkLSN(1)}
+	orders := &captureInstance{name: "sales_orders", tableId: "t2", colIds: []string{"c4", "c5"}, minLSN: mkLSN(15)}
+	reader := &fakeChangeReader{
+		max: mkLSN(30),
+		min: map[string]lsn{"dbo_product": mkLSN(1), "sales_orders": mkLSN(15)},
+		rows: []*changeRow{
+			// Applied during the snapshot, not read again.
+			{instance: product, startLSN: mkLSN(10), seqval: mkLSN(9), operation: opInsert, vals: []interface{}{int64(1), "old", 1.0}},
+			// The order row is returned first, but committed after the product.
+			{instance: orders, startLSN: mkLSN(20), seqval: mkLSN(19), operation: opInsert, vals: []interface{}{int64(100), int64(7)}},
+			{instance: product, startLSN: mkLSN(20), seqval: mkLSN(18), operation: opInsert, vals: []interface{}{int64(7), "apple", 2.5}},
+			// Update of the key and of name to NULL.
+			{instance: product, startLSN: mkLSN(25), seqval: mkLSN(21), operation: opUpdateAfter, vals: []interface{}{int64(8), nil, 2.5}},
+			{instance: product, startLSN: mkLSN(25), seqval: mkLSN(21), operation: opUpdateBefore, vals: []interface{}{int64(7), "apple", 2.5}},
+			{instance: orders, startLSN: mkLSN(25), seqval: mkLSN(22), operation: opDelete, vals: []interface{}{int64(100), int64(7)}},
+			// Bad value.
+			{instance: product, startLSN: mkLSN(30), seqval: mkLSN(29), operation: opInsert, vals: []interface{}{int64(9), "pear", "cheap"}},
+		},
+	}
+	info := common.MakeStreamingInfo()
+	var written [][]*sp.Mutation
+	info.Write = func(ms []*sp.Mutation) error {
+		written = append(written, ms)
+		return nil
+	}
+	conv := buildCdcConv()
+	c := newChangeTableCDC(conv, info, reader, []*captureInstance{product, orders}, stateFile, 0, start)
+	assert.Nil(t, c.stream())
+
+	assert.Equal(t, [][]*sp.Mutation{
+		{
+			sp.InsertOrUpdate("product", []string{"id", "name", "price"}, []interface{}{int64(7), "apple", 2.5}),
+			sp.InsertOrUpdate("sales_orders", []string{"order_id", "product_id"}, []interface{}{int64(100), int64(7)}),
+		},
+		{
+			sp.Delete("product", sp.Key{int64(7)}),
+			sp.InsertOrUpdate("product", []string{"id", "price", "name"}, []interface{}{int64(8), 2.5, nil}),
+			sp.Delete("sales_orders", sp.Key{int64(100)}),
+		},
+	}, written)
+	// The orders instance was enabled after the start LSN.
+	assert.Equal(t, [][2]lsn{{mkLSN(11), mkLSN(30)}, {mkLSN(15), mkLSN(30)}}, reader.queried)
+	assert.Equal(t, map[string]map[string]int64{
+		"product":      {recordInsert: 2, recordUpdate: 1},
+		"sales.orders": {recordInsert: 1, recordDelete: 1},
+	}, info.Records)
+	assert.Equal(t, map[string]map[string]int64{"product": {recordInsert: 1}}, info.BadRecords)
+	assert.Equal(t, int64(5), info.Processed())
+
+	pos, ok, err := loadCdcState(stateFile)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, mkLSN(30), pos)
+	_, err = os.Stat(common.CutoverFile(stateFile))
+	assert.True(t, os.IsNotExist(err))
+
+	info.FillConv(conv)
+	assert.True(t, conv.Audit.StreamingStats.Streaming)
+	assert.Equal(t, info.Records, conv.Audit.StreamingStats.TotalRecords)
+}
+
+func TestChangeTableCDC_CleanedUpChanges(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	product := &captureInstance{name: "dbo_product", tableId: "t1", colIds: []string{"c1", "c2", "c3"}, minLSN: mkLSN(1)}
+	reader := &fakeChangeReader{
+		max: mkLSN(30),
+		// The cleanup job removed changes that weren't applied.
+		min:  map[string]lsn{"dbo_product": mkLSN(20)},
+		rows: []*changeRow{{instance: product, startLSN: mkLSN(25), seqval: mkLSN(25), operation: opInsert, vals: []interface{}{int64(7), "apple", 2.5}}},
+	}
+	info := common.MakeStreamingInfo()
+	info.Write = func(ms []*sp.Mutation) error { return nil }
+	c := newChangeTableCDC(buildCdcConv(), info, reader, []*captureInstance{product}, stateFile, 0, mkLSN(10))
+	err := c.poll()
+	assert.EqualError(t, err, "changes of capture instance dbo_product before LSN 0x0000002A000000000014 were removed by the change data capture cleanup job before being applied")
+	assert.Equal(t, mkLSN(10), c.pos)
+}
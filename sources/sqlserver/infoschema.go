@@ -24,6 +24,7 @@ import (
 	sp "cloud.google.com/go/spanner"
 
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
@@ -44,8 +45,9 @@ const (
 )
 
 type InfoSchemaImpl struct {
-	DbName string
-	Db     *sql.DB
+	DbName        string
+	Db            *sql.DB
+	SourceProfile profiles.SourceProfile
 }
 
 // GetToDdl function below implement the common.InfoSchema interface.
@@ -53,12 +55,23 @@ func (isi InfoSchemaImpl) GetToDdl() common.ToDdl {
 	return ToDdlImpl{}
 }
 
-// We leave the 2 functions below empty to be able to pass this as an infoSchema interface. We don't need these for now.
+// StartChangeDataCapture is used when performing a streaming migration. With
+// cdc=changetables, it captures the LSN from which the changes recorded by
+// SQL Server change data capture are streamed in-process.
 func (isi InfoSchemaImpl) StartChangeDataCapture(ctx context.Context, conv *internal.Conv) (map[string]interface{}, error) {
+	if isi.SourceProfile.Conn.SqlServer.Cdc == profiles.ChangeTablesCdc {
+		return isi.startChangeTableCapture(conv)
+	}
 	return nil, nil
 }
 
+// StartStreamingMigration is used when performing a streaming migration. With
+// cdc=changetables, it polls the change tables and applies the changes to
+// Spanner until cutover.
 func (isi InfoSchemaImpl) StartStreamingMigration(ctx context.Context, client *sp.Client, conv *internal.Conv, streamingInfo map[string]interface{}) error {
+	if isi.SourceProfile.Conn.SqlServer.Cdc == profiles.ChangeTablesCdc {
+		return isi.streamChangeTables(client, conv, streamingInfo)
+	}
 	return nil
 }
 
@@ -84,9 +97,9 @@ func (isi InfoSchemaImpl) GetTableName(schema string, tableName string) string {
 // We choose to do all type conversions explicitly ourselves so that
 // we can generate more targeted error messages: hence we pass
 // *interface{} parameters to row.Scan.
-func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, tableId string, srcSchema schema.Table, commonColIds []string, spSchema ddl.CreateTable) error {
+func (isi InfoSchemaImpl) ProcessData(ctx context.Context, conv *internal.Conv, tableId string, srcSchema schema.Table, commonColIds []string, spSchema ddl.CreateTable) error {
 	srcTableName := conv.SrcSchema[tableId].Name
-	rowsInterface, err := isi.GetRowsFromTable(conv, tableId)
+	rowsInterface, err := isi.getRowsFromTable(ctx, conv, tableId)
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTableName, err))
 		return err
@@ -110,29 +123,50 @@ func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, tableId string, srcSc
 		if err != nil {
 			conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
 			conv.StatsAddBadRow(srcTableName, conv.DataMode())
-			conv.CollectBadRow(srcTableName, srcCols, values)
+			conv.CollectBadRow(srcTableName, srcCols, values, err)
 			continue
 		}
 		ProcessDataRow(conv, tableId, commonColIds, srcSchema, spSchema, newValues)
 	}
-	return nil
+	// The query is cancelled once ctx is done, which ends the rows early.
+	return ctx.Err()
 }
 
 // GetRowsFromTable returns a sql Rows object for a table.
 func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, tableId string) (interface{}, error) {
+	return isi.getRowsFromTable(context.Background(), conv, tableId)
+}
+
+// getRowsFromTable is like GetRowsFromTable, but the query is cancelled
+// once ctx is done.
+func (isi InfoSchemaImpl) getRowsFromTable(ctx context.Context, conv *internal.Conv, tableId string) (interface{}, error) {
 	tbl := conv.SrcSchema[tableId]
 	//To get only the table name by removing the schema name prefix
 	tblName := strings.Replace(tbl.Name, tbl.Schema+".", "", 1)
 
 	q := getSelectQuery(isi.DbName, tbl.Schema, tblName, tbl.ColIds, tbl.ColDefs)
-	rows, err := isi.Db.Query(q)
+	rows, err := isi.Db.QueryContext(ctx, q)
 	if err != nil {
 		return nil, err
 	}
 	return rows, err
 }
 
+// GetSampleRows implements the common.SamplingInfoSchema interface.
+func (isi InfoSchemaImpl) GetSampleRows(conv *internal.Conv, tableId string, colIds []string, n int64) (*sql.Rows, error) {
+	tbl := conv.SrcSchema[tableId]
+	tblName := strings.Replace(tbl.Name, tbl.Schema+".", "", 1)
+	q := fmt.Sprintf("SELECT TOP (%d) %s FROM [%s].[%s].[%s]", n, strings.Join(getSelectColumns(colIds, tbl.ColDefs), ", "), isi.DbName, tbl.Schema, tblName)
+	return isi.Db.Query(q)
+}
+
 func getSelectQuery(srcDb string, schemaName string, tableName string, colIds []string, colDefs map[string]schema.Column) string {
+	return fmt.Sprintf("SELECT %s FROM [%s].[%s].[%s]", strings.Join(getSelectColumns(colIds, colDefs), ", "), srcDb, schemaName, tableName)
+}
+
+// getSelectColumns returns the select list expressions for the columns,
+// which convert values to formats that we can parse.
+func getSelectColumns(colIds []string, colDefs map[string]schema.Column) []string {
 	var selects = make([]string, len(colIds))
 
 	for i, colId := range colIds {
@@ -158,8 +192,7 @@ func getSelectQuery(srcDb string, schemaName string, tableName string, colIds []
 		}
 		selects[i] = s
 	}
-
-	return fmt.Sprintf("SELECT %s FROM [%s].[%s].[%s]", strings.Join(selects, ", "), srcDb, schemaName, tableName)
+	return selects
 }
 
 // buildVals contructs interface{} value containers to scan row
@@ -199,7 +232,7 @@ func (isi InfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
 	FROM sys.tables AS TBL
 	INNER JOIN sys.schemas AS SCH 
 	ON SCH.schema_id = TBL.schema_id
-	WHERE TBL.type = 'U' AND TBL.is_tracked_by_cdc = 0 AND TBL.is_ms_shipped = 0 AND TBL.name <> 'sysdiagrams'
+	WHERE TBL.type = 'U' AND TBL.is_ms_shipped = 0 AND SCH.name <> 'cdc' AND TBL.name <> 'sysdiagrams'
 	`
 	rows, err := isi.Db.Query(q)
 	if err != nil {
@@ -0,0 +1,1223 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlserver
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+)
+
+// Regexps applied to lines of the script that start outside of any
+// string, comment or parentheses.
+var (
+	// goRegexp matches the GO batch separator, with an optional count.
+	goRegexp = regexp.MustCompile(`(?i)^\s*GO(\s+\d+)?\s*(--.*)?$`)
+	// stmtStartRegexp matches lines that start a new statement.
+	stmtStartRegexp = regexp.MustCompile(`(?i)^\s*(CREATE|ALTER|INSERT|SET|USE|DROP|EXEC|EXECUTE|PRINT|GRANT|DENY|REVOKE|DECLARE|IF|BEGIN|END)\b`)
+	// moduleRegexp matches lines that start a module, whose body extends
+	// to the end of the batch.
+	moduleRegexp = regexp.MustCompile(`(?i)^\s*(CREATE|ALTER)\s+(OR\s+ALTER\s+)?(PROC|PROCEDURE|FUNCTION|TRIGGER|VIEW)\b`)
+)
+
+// DbDumpImpl SQL Server specific implementation for DdlDumpImpl. It
+// processes T-SQL scripts such as those produced by the "Generate Scripts"
+// task of SQL Server Management Studio.
+type DbDumpImpl struct{}
+
+// GetToDdl function below implement the common.DbDump interface.
+func (ddi DbDumpImpl) GetToDdl() common.ToDdl {
+	return ToDdlImpl{}
+}
+
+// ProcessDump processes the T-SQL script.
+func (ddi DbDumpImpl) ProcessDump(ctx context.Context, conv *internal.Conv, r *internal.Reader) error {
+	return processTSQLDump(ctx, conv, r)
+}
+
+// processTSQLDump reads a T-SQL script from r and does schema or data
+// conversion, depending on whether conv is configured for schema mode or
+// data mode. The script is read batch by batch, and batches are further
+// split into statements at lines that start a new statement, so that the
+// large number of INSERT statements of a script with data are processed
+// one at a time.
+func processTSQLDump(ctx context.Context, conv *internal.Conv, r *internal.Reader) error {
+	sr := &scriptReader{r: r}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		startLine := r.LineNumber
+		chunk, err := sr.readChunk()
+		if err != nil {
+			return err
+		}
+		for _, stmt := range splitStatements(tokenize(chunk)) {
+			isInsert := processStatement(conv, stmt)
+			internal.VerbosePrintf("Parsed SQL command at line=%d: %d lines, %d bytes, Insert Statement=%v\n", startLine, r.LineNumber-startLine, len(chunk), isInsert)
+			logger.Log.Debug(fmt.Sprintf("Parsed SQL command at line=%d: %d lines, %d bytes, Insert Statement=%v\n", startLine, r.LineNumber-startLine, len(chunk), isInsert))
+		}
+		if sr.eof() {
+			break
+		}
+	}
+	if conv.SchemaMode() {
+		internal.ResolveForeignKeyIds(conv.SrcSchema)
+	}
+	return nil
+}
+
+// scriptReader reads the lines of a script, which is UTF-8 or, as is
+// the default for scripts generated by SQL Server Management Studio,
+// UTF-16 with a byte order mark.
+type scriptReader struct {
+	r       *internal.Reader
+	started bool
+	utf16   binary.ByteOrder // Nil for UTF-8.
+	buf     []byte           // UTF-16 input not yet returned.
+	next    *string          // Line that starts the next chunk.
+}
+
+func (sr *scriptReader) eof() bool {
+	return sr.r.EOF && len(sr.buf) == 0 && sr.next == nil
+}
+
+// readLine returns the next line of the script, including its newline.
+func (sr *scriptReader) readLine() string {
+	if !sr.started {
+		sr.started = true
+		b := sr.r.ReadLine()
+		switch {
+		case len(b) >= 3 && b[0] == 0xEF && b[1] == 0xBB && b[2] == 0xBF:
+			return string(b[3:])
+		case len(b) >= 2 && b[0] == 0xFF && b[1] == 0xFE:
+			sr.utf16, sr.buf = binary.LittleEndian, b[2:]
+		case len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF:
+			sr.utf16, sr.buf = binary.BigEndian, b[2:]
+		case len(b) >= 2 && b[0] != 0 && b[1] == 0:
+			sr.utf16, sr.buf = binary.LittleEndian, b
+		default:
+			return string(b)
+		}
+	}
+	if sr.utf16 == nil {
+		return string(sr.r.ReadLine())
+	}
+	// Newlines are found in UTF-16 code units, since a '\n' byte can
+	// also be half of a code unit of another character.
+	i := 0
+	for {
+		for ; i+1 < len(sr.buf); i += 2 {
+			if sr.utf16.Uint16(sr.buf[i:]) == '\n' {
+				line := decodeUTF16(sr.buf[:i+2], sr.utf16)
+				sr.buf = sr.buf[i+2:]
+				return line
+			}
+		}
+		if sr.r.EOF {
+			line := decodeUTF16(sr.buf, sr.utf16)
+			sr.buf = nil
+			return line
+		}
+		sr.buf = append(sr.buf, sr.r.ReadLine()...)
+	}
+}
+
+func decodeUTF16(b []byte, order binary.ByteOrder) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = order.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// readChunk returns the next chunk of the script: the lines up to the end
+// of the batch, or up to the next line that starts a statement outside of
+// any string, comment or parentheses. The body of a module (e.g. a stored
+// procedure) always extends to the end of the batch.
+func (sr *scriptReader) readChunk() (string, error) {
+	var lines []string
+	var st lexState
+	blank, module := true, false
+	for !sr.eof() {
+		var line string
+		if sr.next != nil {
+			line, sr.next = *sr.next, nil
+		} else {
+			line = sr.readLine()
+		}
+		if st.idle() {
+			if goRegexp.MatchString(line) {
+				if !blank {
+					break
+				}
+				continue
+			}
+			if !blank && !module && stmtStartRegexp.MatchString(line) {
+				sr.next = &line
+				break
+			}
+			if blank && moduleRegexp.MatchString(line) {
+				module = true
+			}
+		}
+		lines = append(lines, line)
+		st.scan(line)
+		blank = blank && strings.TrimSpace(line) == ""
+	}
+	if !st.idle() && sr.eof() {
+		return "", fmt.Errorf("error parsing last %d line(s) of input: unterminated string, identifier or comment", len(lines))
+	}
+	return strings.Join(lines, ""), nil
+}
+
+// lexState tracks whether the end of the text scanned so far is inside a
+// string, quoted identifier, comment or parentheses.
+type lexState struct {
+	quote   byte // Closing quote of the string or identifier, or 0.
+	comment int  // Nesting depth of block comments.
+	parens  int
+}
+
+func (st *lexState) idle() bool {
+	return st.quote == 0 && st.comment == 0 && st.parens <= 0
+}
+
+func (st *lexState) scan(s string) {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case st.comment > 0:
+			if c == '*' && i+1 < len(s) && s[i+1] == '/' {
+				st.comment--
+				i++
+			} else if c == '/' && i+1 < len(s) && s[i+1] == '*' {
+				st.comment++
+				i++
+			}
+		case st.quote != 0:
+			if c == st.quote {
+				if i+1 < len(s) && s[i+1] == st.quote {
+					i++ // Escaped quote.
+				} else {
+					st.quote = 0
+				}
+			}
+		case c == '-' && i+1 < len(s) && s[i+1] == '-':
+			return
+		case c == '/' && i+1 < len(s) && s[i+1] == '*':
+			st.comment++
+			i++
+		case c == '\'' || c == '"':
+			st.quote = c
+		case c == '[':
+			st.quote = ']'
+		case c == '(':
+			st.parens++
+		case c == ')':
+			st.parens--
+		}
+	}
+}
+
+type tokenKind int
+
+const (
+	tokEOF    tokenKind = iota
+	tokWord             // Keyword or unquoted identifier.
+	tokIdent            // Quoted identifier: [name] or "name".
+	tokString           // String literal: 'text' or N'text'.
+	tokNumber           // Numeric literal.
+	tokBinary           // Binary literal: 0x0A1B.
+	tokPunct            // Any other character.
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+}
+
+// tokenize splits T-SQL text into tokens, dropping whitespace and comments.
+// Strings and quoted identifiers are unescaped.
+func tokenize(s string) []token {
+	var toks []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '-' && strings.HasPrefix(s[i:], "--"):
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case c == '/' && strings.HasPrefix(s[i:], "/*"):
+			depth := 0
+			for i < len(s) {
+				if strings.HasPrefix(s[i:], "/*") {
+					depth++
+					i += 2
+				} else if strings.HasPrefix(s[i:], "*/") {
+					depth--
+					i += 2
+					if depth == 0 {
+						break
+					}
+				} else {
+					i++
+				}
+			}
+		case c == '\'' || ((c == 'N' || c == 'n') && i+1 < len(s) && s[i+1] == '\''):
+			if c != '\'' {
+				i++
+			}
+			v, n := unquote(s[i:], '\'')
+			toks = append(toks, token{tokString, v})
+			i += n
+		case c == '[':
+			v, n := unquote(s[i:], ']')
+			toks = append(toks, token{tokIdent, v})
+			i += n
+		case c == '"':
+			v, n := unquote(s[i:], '"')
+			toks = append(toks, token{tokIdent, v})
+			i += n
+		case c == '0' && i+1 < len(s) && (s[i+1] == 'x' || s[i+1] == 'X'):
+			j := i + 2
+			for j < len(s) && isHexDigit(s[j]) {
+				j++
+			}
+			toks = append(toks, token{tokBinary, s[i+2 : j]})
+			i = j
+		case isDigit(c) || (c == '.' && i+1 < len(s) && isDigit(s[i+1])):
+			j := i
+			for j < len(s) && (isDigit(s[j]) || s[j] == '.') {
+				j++
+			}
+			if j < len(s) && (s[j] == 'e' || s[j] == 'E') {
+				j++
+				if j < len(s) && (s[j] == '+' || s[j] == '-') {
+					j++
+				}
+				for j < len(s) && isDigit(s[j]) {
+					j++
+				}
+			}
+			toks = append(toks, token{tokNumber, s[i:j]})
+			i = j
+		case isWordChar(c) && !isDigit(c):
+			j := i
+			for j < len(s) && isWordChar(s[j]) {
+				j++
+			}
+			toks = append(toks, token{tokWord, s[i:j]})
+			i = j
+		default:
+			toks = append(toks, token{tokPunct, string(c)})
+			i++
+		}
+	}
+	return toks
+}
+
+// unquote returns the unescaped content of the string or identifier at
+// the start of s, which is closed by quote, and the number of bytes it
+// takes up in s.
+func unquote(s string, quote byte) (string, int) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] == quote {
+			if i+1 < len(s) && s[i+1] == quote {
+				b.WriteByte(quote)
+				i++
+				continue
+			}
+			return b.String(), i + 1
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String(), len(s)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '@' || c == '#' || c == '$' || c >= 0x80 || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// splitStatements splits tokens into statements at semicolons.
+func splitStatements(toks []token) [][]token {
+	var stmts [][]token
+	for _, stmt := range splitTopLevel(toks, ";") {
+		if len(stmt) > 0 {
+			stmts = append(stmts, stmt)
+		}
+	}
+	return stmts
+}
+
+// splitTopLevel splits tokens at the punctuation sep outside of
+// parentheses.
+func splitTopLevel(toks []token, sep string) [][]token {
+	var l [][]token
+	depth, start := 0, 0
+	for i, t := range toks {
+		if t.kind != tokPunct {
+			continue
+		}
+		switch t.val {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case sep:
+			if depth == 0 {
+				l = append(l, toks[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(l, toks[start:])
+}
+
+// parser provides lookahead and matching over the tokens of a statement.
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return token{}
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	if p.pos < len(p.toks) {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) atEnd() bool {
+	return p.pos >= len(p.toks)
+}
+
+// isWord returns whether the next token is one of the keywords kws.
+func (p *parser) isWord(kws ...string) bool {
+	t := p.peek()
+	if t.kind != tokWord {
+		return false
+	}
+	for _, kw := range kws {
+		if strings.EqualFold(t.val, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptWord consumes the next token if it is one of the keywords kws.
+func (p *parser) acceptWord(kws ...string) bool {
+	if p.isWord(kws...) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) isPunct(c string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.val == c
+}
+
+func (p *parser) acceptPunct(c string) bool {
+	if p.isPunct(c) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// name parses a (possibly qualified) object name, and returns its parts.
+func (p *parser) name() ([]string, error) {
+	var parts []string
+	for {
+		t := p.peek()
+		if t.kind != tokWord && t.kind != tokIdent {
+			return nil, fmt.Errorf("expected name, found %q", t.val)
+		}
+		parts = append(parts, p.next().val)
+		if !p.acceptPunct(".") {
+			return parts, nil
+		}
+	}
+}
+
+// tableName parses a table name, and returns it in the form used for
+// tables read from the database (see InfoSchemaImpl.GetTableName).
+func (p *parser) tableName() (string, error) {
+	parts, err := p.name()
+	if err != nil {
+		return "", err
+	}
+	schemaName := "dbo"
+	if len(parts) > 1 && parts[len(parts)-2] != "" {
+		schemaName = parts[len(parts)-2]
+	}
+	return InfoSchemaImpl{}.GetTableName(schemaName, parts[len(parts)-1]), nil
+}
+
+// group parses a parenthesized list of tokens, and returns the tokens
+// inside the parentheses.
+func (p *parser) group() ([]token, error) {
+	if !p.acceptPunct("(") {
+		return nil, fmt.Errorf("expected '(', found %q", p.peek().val)
+	}
+	start, depth := p.pos, 1
+	for !p.atEnd() {
+		t := p.next()
+		if t.kind != tokPunct {
+			continue
+		}
+		switch t.val {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return p.toks[start : p.pos-1], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("missing ')'")
+}
+
+// skipGroup skips a parenthesized list of tokens, if there is one.
+func (p *parser) skipGroup() {
+	if p.isPunct("(") {
+		p.group()
+	}
+}
+
+// stmtKind returns the kind of a statement used in statement stats,
+// e.g. "CREATE TABLE" or "INSERT".
+func stmtKind(stmt []token) string {
+	p := &parser{toks: stmt}
+	kw := strings.ToUpper(p.next().val)
+	switch kw {
+	case "CREATE", "ALTER", "DROP":
+		p.acceptWord("OR")
+		p.acceptWord("ALTER")
+		for p.acceptWord("UNIQUE", "CLUSTERED", "NONCLUSTERED", "COLUMNSTORE", "PRIMARY", "XML", "SPATIAL") {
+		}
+		obj := strings.ToUpper(p.next().val)
+		if obj == "PROC" {
+			obj = "PROCEDURE"
+		}
+		return kw + " " + obj
+	case "EXECUTE":
+		return "EXEC"
+	}
+	return kw
+}
+
+// processStatement extracts schema information from T-SQL statements,
+// updating conv with new schema information, and returning true if an
+// INSERT statement is encountered.
+func processStatement(conv *internal.Conv, stmt []token) bool {
+	kind := stmtKind(stmt)
+	p := &parser{toks: stmt}
+	switch kind {
+	case "CREATE TABLE":
+		if conv.SchemaMode() {
+			processCreateTable(conv, p, kind)
+		}
+	case "ALTER TABLE":
+		if conv.SchemaMode() {
+			processAlterTable(conv, p, kind)
+		}
+	case "CREATE INDEX":
+		if conv.SchemaMode() {
+			processCreateIndex(conv, p, kind)
+		}
+	case "INSERT":
+		processInsert(conv, p, kind)
+		return true
+	default:
+		conv.SkipStatement(kind)
+	}
+	return false
+}
+
+func logStmtError(conv *internal.Conv, kind string, err error) {
+	conv.Unexpected(fmt.Sprintf("Processing %s statement: %s", kind, err))
+	conv.ErrorInStatement(kind)
+}
+
+func processCreateTable(conv *internal.Conv, p *parser, kind string) {
+	p.next() // CREATE
+	p.next() // TABLE
+	tableName, err := p.tableName()
+	if err != nil {
+		logStmtError(conv, kind, fmt.Errorf("can't get table name: %w", err))
+		return
+	}
+	if _, ok := internal.GetSrcTableByName(conv.SrcSchema, tableName); ok {
+		logStmtError(conv, kind, fmt.Errorf("table %s is defined more than once", tableName))
+		return
+	}
+	body, err := p.group()
+	if err != nil {
+		logStmtError(conv, kind, fmt.Errorf("can't get columns of table %s: %w", tableName, err))
+		return
+	}
+	internal.VerbosePrintf("processing create table elem=%s\n", tableName)
+	logger.Log.Debug(fmt.Sprintf("processing create table elem=%s\n", tableName))
+	tableId := internal.GenerateTableId()
+	st := schema.Table{
+		Id:           tableId,
+		Name:         tableName,
+		ColNameIdMap: make(map[string]string),
+		ColDefs:      make(map[string]schema.Column),
+	}
+	// Columns are processed first, since table constraints can appear
+	// anywhere in the list of columns.
+	var constraints [][]token
+	for _, elem := range splitTopLevel(body, ",") {
+		if isConstraint(elem) {
+			constraints = append(constraints, elem)
+			continue
+		}
+		if err := processColumn(conv, &st, elem, kind); err != nil {
+			logStmtError(conv, kind, err)
+			return
+		}
+	}
+	for _, elem := range constraints {
+		if err := processConstraint(conv, &st, &parser{toks: elem}, "", kind); err != nil {
+			conv.Unexpected(fmt.Sprintf("Processing %s statement for table %s: %s", kind, tableName, err))
+		}
+	}
+	conv.SchemaStatement(kind)
+	conv.SrcSchema[tableId] = st
+}
+
+// isConstraint returns whether an element of a CREATE TABLE or ALTER
+// TABLE ADD statement is a table constraint or index.
+func isConstraint(elem []token) bool {
+	p := &parser{toks: elem}
+	return p.isWord("CONSTRAINT", "PRIMARY", "UNIQUE", "FOREIGN", "CHECK", "DEFAULT", "INDEX")
+}
+
+// processColumn adds the column defined by elem to st.
+func processColumn(conv *internal.Conv, st *schema.Table, elem []token, kind string) error {
+	p := &parser{toks: elem}
+	t := p.next()
+	if t.kind != tokWord && t.kind != tokIdent {
+		return fmt.Errorf("expected column name, found %q", t.val)
+	}
+	colName := t.val
+	if p.isWord("AS") {
+		conv.Unexpected(fmt.Sprintf("Skipping computed column %s of table %s", colName, st.Name))
+		return nil
+	}
+	ty, err := p.columnType()
+	if err != nil {
+		return fmt.Errorf("can't get type of column %s: %w", colName, err)
+	}
+	col := schema.Column{Id: internal.GenerateColumnId(), Name: colName, Type: ty}
+	st.ColIds = append(st.ColIds, col.Id)
+	st.ColNameIdMap[colName] = col.Id
+	st.ColDefs[col.Id] = col
+	for !p.atEnd() {
+		switch {
+		case p.acceptWord("NOT"):
+			if p.acceptWord("NULL") {
+				col.NotNull = true
+			} else {
+				p.acceptWord("FOR") // NOT FOR REPLICATION.
+				p.acceptWord("REPLICATION")
+			}
+		case p.acceptWord("NULL"):
+			col.NotNull = false
+		case p.acceptWord("IDENTITY"):
+			col.Ignored.AutoIncrement = true
+			p.skipGroup()
+		case p.acceptWord("DEFAULT"):
+			col.Ignored.Default = true
+			p.skipExpr()
+		case p.acceptWord("COLLATE"):
+			p.next()
+		case p.isWord("CONSTRAINT", "PRIMARY", "UNIQUE", "FOREIGN", "REFERENCES", "CHECK"):
+			// Column constraints refer to the column being defined.
+			st.ColDefs[col.Id] = col
+			if err := processConstraint(conv, st, p, col.Id, kind); err != nil {
+				return fmt.Errorf("column %s: %w", colName, err)
+			}
+			col = st.ColDefs[col.Id]
+		default:
+			// ROWGUIDCOL, SPARSE, etc.
+			p.next()
+			p.skipGroup()
+		}
+	}
+	st.ColDefs[col.Id] = col
+	return nil
+}
+
+// skipExpr skips a simple expression, such as the value of a DEFAULT
+// constraint: a literal, a parenthesized expression or a function call.
+func (p *parser) skipExpr() {
+	if p.isPunct("(") {
+		p.skipGroup()
+		return
+	}
+	p.acceptPunct("-")
+	p.next()
+	p.skipGroup()
+}
+
+// columnType parses the type of a column. Type modifiers are stored as
+// they are when the schema is read from the database (see toType).
+func (p *parser) columnType() (schema.Type, error) {
+	parts, err := p.name()
+	if err != nil {
+		return schema.Type{}, err
+	}
+	name := strings.ToLower(parts[len(parts)-1])
+	if name == "double" && p.acceptWord("PRECISION") {
+		name = "float"
+	}
+	var args []int64
+	if p.isPunct("(") {
+		toks, err := p.group()
+		if err != nil {
+			return schema.Type{}, err
+		}
+		for _, arg := range splitTopLevel(toks, ",") {
+			if len(arg) == 1 && arg[0].kind == tokWord && strings.EqualFold(arg[0].val, "max") {
+				args = append(args, -1)
+				continue
+			}
+			if len(arg) != 1 || arg[0].kind != tokNumber {
+				return schema.Type{}, fmt.Errorf("unexpected modifier of type %s", name)
+			}
+			n, err := strconv.ParseInt(arg[0].val, 10, 64)
+			if err != nil {
+				return schema.Type{}, err
+			}
+			args = append(args, n)
+		}
+	}
+	switch name {
+	case "sysname":
+		return schema.Type{Name: "nvarchar", Mods: []int64{128}}, nil
+	case "char", "varchar", "nchar", "nvarchar", "binary", "varbinary":
+		if len(args) == 0 {
+			args = []int64{1}
+		}
+		return schema.Type{Name: name, Mods: args[:1]}, nil
+	case "decimal", "numeric":
+		switch {
+		case len(args) == 0:
+			args = []int64{18}
+		case len(args) > 1 && args[1] == 0:
+			args = args[:1]
+		}
+		return schema.Type{Name: name, Mods: args}, nil
+	}
+	return schema.Type{Name: name}, nil
+}
+
+// processConstraint adds the table constraint or index at the current
+// position of p to st. For column constraints, colId is the column
+// being defined, which the constraint applies to if it doesn't specify
+// columns.
+func processConstraint(conv *internal.Conv, st *schema.Table, p *parser, colId, kind string) error {
+	var name string
+	if p.acceptWord("CONSTRAINT") {
+		name = p.next().val
+	}
+	switch {
+	case p.acceptWord("PRIMARY"):
+		p.acceptWord("KEY")
+		p.acceptWord("CLUSTERED", "NONCLUSTERED")
+		keys, err := p.keys(st, colId)
+		if err != nil {
+			return err
+		}
+		if len(st.PrimaryKeys) != 0 {
+			conv.Unexpected(fmt.Sprintf("Multiple primary keys found. `%s` statement is overwriting primary key", kind))
+		}
+		st.PrimaryKeys = keys
+		// Primary key columns are NOT NULL in SQL Server.
+		for _, k := range keys {
+			cd := st.ColDefs[k.ColId]
+			cd.NotNull = true
+			st.ColDefs[k.ColId] = cd
+		}
+	case p.acceptWord("UNIQUE"):
+		p.acceptWord("CLUSTERED", "NONCLUSTERED")
+		keys, err := p.keys(st, colId)
+		if err != nil {
+			return err
+		}
+		// Unique constraints are represented as unique indexes in schema.
+		st.Indexes = append(st.Indexes, schema.Index{Id: internal.GenerateIndexesId(), Name: name, Unique: true, Keys: keys})
+	case p.acceptWord("INDEX"):
+		name = p.next().val
+		unique := p.acceptWord("UNIQUE")
+		p.acceptWord("CLUSTERED", "NONCLUSTERED")
+		if p.isWord("COLUMNSTORE") {
+			conv.Unexpected(fmt.Sprintf("Skipping columnstore index %s of table %s", name, st.Name))
+			return nil
+		}
+		keys, err := p.keys(st, colId)
+		if err != nil {
+			return err
+		}
+		st.Indexes = append(st.Indexes, schema.Index{Id: internal.GenerateIndexesId(), Name: name, Unique: unique, Keys: keys})
+	case p.isWord("FOREIGN", "REFERENCES"):
+		fk, err := p.foreignKey(conv, st, colId)
+		if err != nil {
+			return err
+		}
+		fk.Name = name
+		st.ForeignKeys = append(st.ForeignKeys, fk)
+	case p.acceptWord("CHECK"):
+		p.acceptWord("NOT")
+		p.acceptWord("FOR")
+		p.acceptWord("REPLICATION")
+		expr, err := p.group()
+		if err != nil {
+			return err
+		}
+		// Check constraints aren't migrated: flag the columns they use.
+		ids := map[string]bool{}
+		if colId != "" {
+			ids[colId] = true
+		}
+		for _, t := range expr {
+			if id, ok := st.ColNameIdMap[t.val]; ok && (t.kind == tokWord || t.kind == tokIdent) {
+				ids[id] = true
+			}
+		}
+		for id := range ids {
+			cd := st.ColDefs[id]
+			cd.Ignored.Check = true
+			st.ColDefs[id] = cd
+		}
+	case p.acceptWord("DEFAULT"):
+		p.skipExpr()
+		if p.acceptWord("FOR") {
+			colId = st.ColNameIdMap[p.next().val]
+		}
+		if cd, ok := st.ColDefs[colId]; ok {
+			cd.Ignored.Default = true
+			st.ColDefs[colId] = cd
+		}
+	default:
+		return fmt.Errorf("unexpected constraint %q", p.peek().val)
+	}
+	return nil
+}
+
+// keys parses the list of key columns of a constraint or index. If there
+// is no list, the key is the column colId.
+func (p *parser) keys(st *schema.Table, colId string) ([]schema.Key, error) {
+	if !p.isPunct("(") {
+		if colId == "" {
+			return nil, fmt.Errorf("missing key columns")
+		}
+		return []schema.Key{{ColId: colId}}, nil
+	}
+	toks, err := p.group()
+	if err != nil {
+		return nil, err
+	}
+	var keys []schema.Key
+	for _, elem := range splitTopLevel(toks, ",") {
+		kp := &parser{toks: elem}
+		t := kp.next()
+		id, ok := st.ColNameIdMap[t.val]
+		if !ok {
+			return nil, fmt.Errorf("can't find key column %s of table %s", t.val, st.Name)
+		}
+		desc := kp.acceptWord("DESC")
+		keys = append(keys, schema.Key{ColId: id, Desc: desc})
+	}
+	return keys, nil
+}
+
+// names parses a parenthesized list of column names.
+func (p *parser) names() ([]string, error) {
+	toks, err := p.group()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, elem := range splitTopLevel(toks, ",") {
+		if len(elem) == 0 {
+			return nil, fmt.Errorf("missing column name")
+		}
+		names = append(names, elem[0].val)
+	}
+	return names, nil
+}
+
+// foreignKey parses a FOREIGN KEY or REFERENCES constraint.
+func (p *parser) foreignKey(conv *internal.Conv, st *schema.Table, colId string) (schema.ForeignKey, error) {
+	var cols []string
+	if p.acceptWord("FOREIGN") {
+		p.acceptWord("KEY")
+		if p.isPunct("(") {
+			var err error
+			if cols, err = p.names(); err != nil {
+				return schema.ForeignKey{}, err
+			}
+		}
+	}
+	if len(cols) == 0 && colId != "" {
+		cols = []string{st.ColDefs[colId].Name}
+	}
+	if !p.acceptWord("REFERENCES") {
+		return schema.ForeignKey{}, fmt.Errorf("expected REFERENCES, found %q", p.peek().val)
+	}
+	referTable, err := p.tableName()
+	if err != nil {
+		return schema.ForeignKey{}, err
+	}
+	var referCols []string
+	if p.isPunct("(") {
+		if referCols, err = p.names(); err != nil {
+			return schema.ForeignKey{}, err
+		}
+	} else if rt, ok := internal.GetSrcTableByName(conv.SrcSchema, referTable); ok {
+		// Without a list of columns, the foreign key refers to the
+		// primary key of the table.
+		for _, k := range rt.PrimaryKeys {
+			referCols = append(referCols, rt.ColDefs[k.ColId].Name)
+		}
+	}
+	fk := schema.ForeignKey{
+		Id:               internal.GenerateForeignkeyId(),
+		ColumnNames:      cols,
+		ReferTableName:   referTable,
+		ReferColumnNames: referCols,
+	}
+	for p.acceptWord("ON") {
+		action := &fk.OnDelete
+		if p.acceptWord("UPDATE") {
+			action = &fk.OnUpdate
+		} else {
+			p.acceptWord("DELETE")
+		}
+		switch {
+		case p.acceptWord("CASCADE"):
+			*action = "CASCADE"
+		case p.acceptWord("NO"):
+			p.acceptWord("ACTION")
+			*action = "NO ACTION"
+		case p.acceptWord("SET"):
+			*action = "SET " + strings.ToUpper(p.next().val)
+		}
+	}
+	return fk, nil
+}
+
+func processAlterTable(conv *internal.Conv, p *parser, kind string) {
+	p.next() // ALTER
+	p.next() // TABLE
+	tableName, err := p.tableName()
+	if err != nil {
+		logStmtError(conv, kind, fmt.Errorf("can't get table name: %w", err))
+		return
+	}
+	tbl, ok := internal.GetSrcTableByName(conv.SrcSchema, tableName)
+	if !ok {
+		conv.Unexpected(fmt.Sprintf("Table %s not found while processing %s statement", tableName, kind))
+		conv.SkipStatement(kind)
+		return
+	}
+	if p.acceptWord("WITH") {
+		p.acceptWord("CHECK", "NOCHECK")
+	}
+	if !p.acceptWord("ADD") {
+		// CHECK CONSTRAINT, SET (LOCK_ESCALATION = ...), etc.
+		conv.SkipStatement(kind)
+		return
+	}
+	st := conv.SrcSchema[tbl.Id]
+	for _, elem := range splitTopLevel(p.toks[p.pos:], ",") {
+		if isConstraint(elem) {
+			err = processConstraint(conv, &st, &parser{toks: elem}, "", kind)
+		} else {
+			err = processColumn(conv, &st, elem, kind)
+		}
+		if err != nil {
+			logStmtError(conv, kind, err)
+			return
+		}
+	}
+	conv.SrcSchema[tbl.Id] = st
+	conv.SchemaStatement(kind)
+}
+
+func processCreateIndex(conv *internal.Conv, p *parser, kind string) {
+	p.next() // CREATE
+	unique := p.acceptWord("UNIQUE")
+	p.acceptWord("CLUSTERED", "NONCLUSTERED")
+	if !p.acceptWord("INDEX") {
+		// Columnstore, XML and spatial indexes aren't supported in Spanner.
+		conv.Unexpected(fmt.Sprintf("Skipping %s index", strings.ToLower(p.peek().val)))
+		conv.SkipStatement(kind)
+		return
+	}
+	name := p.next().val
+	if !p.acceptWord("ON") {
+		logStmtError(conv, kind, fmt.Errorf("expected ON, found %q", p.peek().val))
+		return
+	}
+	tableName, err := p.tableName()
+	if err != nil {
+		logStmtError(conv, kind, fmt.Errorf("can't get table name: %w", err))
+		return
+	}
+	tbl, ok := internal.GetSrcTableByName(conv.SrcSchema, tableName)
+	if !ok {
+		conv.Unexpected(fmt.Sprintf("Table %s not found while processing index statement", tableName))
+		conv.SkipStatement(kind)
+		return
+	}
+	keys, err := p.keys(tbl, "")
+	if err != nil {
+		logStmtError(conv, kind, err)
+		return
+	}
+	index := schema.Index{Id: internal.GenerateIndexesId(), Name: name, Unique: unique, Keys: keys}
+	if p.acceptWord("INCLUDE") {
+		cols, err := p.names()
+		if err != nil {
+			logStmtError(conv, kind, err)
+			return
+		}
+		for _, col := range cols {
+			if id, ok := tbl.ColNameIdMap[col]; ok {
+				index.StoredColumnIds = append(index.StoredColumnIds, id)
+			}
+		}
+	}
+	st := conv.SrcSchema[tbl.Id]
+	st.Indexes = append(st.Indexes, index)
+	conv.SrcSchema[tbl.Id] = st
+	conv.SchemaStatement(kind)
+}
+
+func processInsert(conv *internal.Conv, p *parser, kind string) {
+	p.next() // INSERT
+	p.acceptWord("INTO")
+	srcTable, err := p.tableName()
+	if err != nil {
+		logStmtError(conv, kind, fmt.Errorf("can't get source table name: %w", err))
+		return
+	}
+	var srcCols []string
+	if p.isPunct("(") {
+		if srcCols, err = p.names(); err != nil {
+			logStmtError(conv, kind, fmt.Errorf("can't get columns: %w", err))
+			return
+		}
+	}
+	if !p.acceptWord("VALUES") {
+		logStmtError(conv, kind, fmt.Errorf("only INSERT ... VALUES statements are supported"))
+		return
+	}
+	rows := splitTopLevel(p.toks[p.pos:], ",")
+	if conv.SchemaMode() {
+		conv.Stats.Rows[srcTable] += int64(len(rows))
+		conv.DataStatement(kind)
+		return
+	}
+	tableId, _ := internal.GetTableIdFromSrcName(conv.SrcSchema, srcTable)
+	srcSchema, ok := conv.SrcSchema[tableId]
+	if !ok {
+		conv.Unexpected(fmt.Sprintf("Can't get schemas for table %s", srcTable))
+		conv.Stats.BadRows[srcTable] += int64(len(rows))
+		return
+	}
+	var srcColIds []string
+	if srcCols == nil {
+		// Column names are optional in INSERT statements.
+		for _, colId := range srcSchema.ColIds {
+			srcCols = append(srcCols, srcSchema.ColDefs[colId].Name)
+		}
+	}
+	for _, col := range srcCols {
+		colId, _ := internal.GetColIdFromSrcName(srcSchema.ColDefs, col)
+		srcColIds = append(srcColIds, colId)
+	}
+	spSchema := conv.SpSchema[tableId]
+	commonColIds := common.IntersectionOfTwoStringSlices(spSchema.ColIds, srcColIds)
+	colNameIdMap := internal.GetSrcColNameIdMap(srcSchema)
+	for _, row := range rows {
+		values, err := rowValues(row)
+		if err == nil && len(values) != len(srcCols) {
+			err = fmt.Errorf("found %d values for %d columns", len(values), len(srcCols))
+		}
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
+			conv.StatsAddBadRow(srcTable, conv.DataMode())
+			conv.CollectBadRow(srcTable, srcCols, values, err)
+			continue
+		}
+		for i, colId := range srcColIds {
+			values[i] = normalizeTimestamp(srcSchema.ColDefs[colId].Type.Name, values[i])
+		}
+		newValues, err := common.PrepareValues(conv, tableId, colNameIdMap, commonColIds, srcCols, values)
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
+			conv.StatsAddBadRow(srcTable, conv.DataMode())
+			conv.CollectBadRow(srcTable, srcCols, values, err)
+			continue
+		}
+		ProcessDataRow(conv, tableId, commonColIds, srcSchema, spSchema, newValues)
+	}
+}
+
+// rowValues returns the values of a row of an INSERT statement as strings,
+// in the form used for data read from the database: NULL is "NULL" and
+// binary values are raw bytes.
+func rowValues(row []token) ([]string, error) {
+	p := &parser{toks: row}
+	toks, err := p.group()
+	if err != nil {
+		return nil, err
+	}
+	if !p.atEnd() {
+		return nil, fmt.Errorf("unexpected %q after values", p.peek().val)
+	}
+	var values []string
+	for _, elem := range splitTopLevel(toks, ",") {
+		vp := &parser{toks: elem}
+		v, err := vp.value()
+		if err != nil {
+			return nil, err
+		}
+		if !vp.atEnd() {
+			return nil, fmt.Errorf("unsupported value expression %q", vp.peek().val)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// value parses a literal, which can be cast to another type with CAST or
+// CONVERT, as scripts generated by SQL Server Management Studio do for
+// e.g. dates.
+func (p *parser) value() (string, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString, tokNumber:
+		return t.val, nil
+	case tokBinary:
+		if len(t.val)%2 == 1 {
+			t.val = "0" + t.val
+		}
+		b, err := hex.DecodeString(t.val)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	case tokPunct:
+		if t.val == "-" || t.val == "+" {
+			n := p.next()
+			if n.kind != tokNumber {
+				return "", fmt.Errorf("unexpected %q after sign", n.val)
+			}
+			return strings.TrimPrefix(t.val, "+") + n.val, nil
+		}
+	case tokWord:
+		switch strings.ToUpper(t.val) {
+		case "NULL":
+			return "NULL", nil
+		case "CAST":
+			toks, err := p.group()
+			if err != nil {
+				return "", err
+			}
+			// The type follows the value: CAST(value AS type).
+			vp := &parser{toks: toks}
+			v, err := vp.value()
+			if err != nil || !vp.acceptWord("AS") {
+				return "", fmt.Errorf("unsupported CAST expression")
+			}
+			return v, nil
+		case "CONVERT":
+			toks, err := p.group()
+			if err != nil {
+				return "", err
+			}
+			// The type precedes the value: CONVERT(type, value[, style]).
+			args := splitTopLevel(toks, ",")
+			if len(args) < 2 {
+				return "", fmt.Errorf("unsupported CONVERT expression")
+			}
+			vp := &parser{toks: args[1]}
+			v, err := vp.value()
+			if err != nil || !vp.atEnd() {
+				return "", fmt.Errorf("unsupported CONVERT expression")
+			}
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported value %q", t.val)
+}
+
+// normalizeTimestamp converts a datetime literal to the ISO 8601 format
+// used for data read from the database, e.g. "2021-12-15 07:39:52.943"
+// to "2021-12-15T07:39:52.943" (see convTimestamp).
+func normalizeTimestamp(srcType, val string) string {
+	switch srcType {
+	case dateTimeType, dateTime2Type, smallDateTimeType, dateTimeOffsetType:
+	default:
+		return val
+	}
+	if len(val) > 10 && val[10] == ' ' {
+		val = val[:10] + "T" + val[11:]
+	}
+	if srcType == dateTimeOffsetType {
+		// The offset may be separated from the time by a space.
+		val = strings.Replace(val, " ", "", 1)
+	}
+	return val
+}
@@ -0,0 +1,297 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/stretchr/testify/assert"
+)
+
+// generatedScript is a script in the form produced by the "Generate
+// Scripts" task of SQL Server Management Studio, with schema and data.
+const generatedScript = `USE [Shop]
+GO
+/****** Object:  Table [dbo].[Customers]    Script Date: 1/3/2023 10:00:00 AM ******/
+SET ANSI_NULLS ON
+GO
+SET QUOTED_IDENTIFIER ON
+GO
+CREATE TABLE [dbo].[Customers](
+	[CustomerID] [int] IDENTITY(1,1) NOT NULL,
+	[Name] [nvarchar](100) NOT NULL,
+	[Email] [varchar](max) NULL,
+	[Active] [bit] NOT NULL,
+	[Created] [datetime] NULL,
+ CONSTRAINT [PK_Customers] PRIMARY KEY CLUSTERED
+(
+	[CustomerID] ASC
+)WITH (PAD_INDEX = OFF, STATISTICS_NORECOMPUTE = OFF, IGNORE_DUP_KEY = OFF, ALLOW_ROW_LOCKS = ON, ALLOW_PAGE_LOCKS = ON) ON [PRIMARY],
+ CONSTRAINT [UQ_Customers_Name] UNIQUE NONCLUSTERED
+(
+	[Name] ASC
+)WITH (PAD_INDEX = OFF) ON [PRIMARY]
+) ON [PRIMARY] TEXTIMAGE_ON [PRIMARY]
+GO
+/****** Object:  Table [Sales].[Orders]    Script Date: 1/3/2023 10:00:00 AM ******/
+CREATE TABLE [Sales].[Orders](
+	[OrderID] [bigint] NOT NULL,
+	[CustomerID] [int] NOT NULL,
+	[Total] [decimal](18, 2) NULL,
+	[Status] [nchar](1) NULL,
+	[Receipt] [varbinary](max) NULL,
+	[Placed] [datetime2](7) NULL,
+	[Note] [nvarchar](20) NULL,
+ CONSTRAINT [PK_Orders] PRIMARY KEY CLUSTERED
+(
+	[OrderID] DESC
+)WITH (PAD_INDEX = OFF) ON [PRIMARY]
+) ON [PRIMARY]
+GO
+/****** Object:  Index [IX_Orders_Customer]    Script Date: 1/3/2023 10:00:00 AM ******/
+CREATE NONCLUSTERED INDEX [IX_Orders_Customer] ON [Sales].[Orders]
+(
+	[CustomerID] ASC,
+	[Placed] DESC
+)
+INCLUDE([Total]) WITH (PAD_INDEX = OFF, SORT_IN_TEMPDB = OFF, DROP_EXISTING = OFF, ONLINE = OFF) ON [PRIMARY]
+GO
+ALTER TABLE [dbo].[Customers] ADD  CONSTRAINT [DF_Customers_Active]  DEFAULT ((1)) FOR [Active]
+GO
+ALTER TABLE [Sales].[Orders]  WITH CHECK ADD  CONSTRAINT [FK_Orders_Customers] FOREIGN KEY([CustomerID])
+REFERENCES [dbo].[Customers] ([CustomerID])
+ON DELETE CASCADE
+GO
+ALTER TABLE [Sales].[Orders] CHECK CONSTRAINT [FK_Orders_Customers]
+GO
+ALTER TABLE [Sales].[Orders]  WITH CHECK ADD  CONSTRAINT [CK_Orders_Total] CHECK  (([Total]>=(0)))
+GO
+/****** Object:  StoredProcedure [dbo].[AddCustomer]    Script Date: 1/3/2023 10:00:00 AM ******/
+CREATE PROCEDURE [dbo].[AddCustomer]
+	@Name nvarchar(100)
+AS
+BEGIN
+INSERT INTO [dbo].[Customers] ([Name], [Active]) VALUES (@Name, 1)
+END
+GO
+SET IDENTITY_INSERT [dbo].[Customers] ON
+
+INSERT [dbo].[Customers] ([CustomerID], [Name], [Email], [Active], [Created]) VALUES (1, N'O''Brien; Pat', N'pat@example.com', 1, CAST(N'2021-12-15T07:39:52.943' AS DateTime))
+INSERT [dbo].[Customers] ([CustomerID], [Name], [Email], [Active], [Created]) VALUES (2, N'Ana
+Smith', NULL, 0, NULL)
+SET IDENTITY_INSERT [dbo].[Customers] OFF
+GO
+INSERT [Sales].[Orders] ([OrderID], [CustomerID], [Total], [Status], [Receipt], [Placed], [Note]) VALUES (10, 1, CAST(-12.50 AS Decimal(18, 2)), N'N', 0x89504E, CONVERT(datetime2, '2021-12-15 07:40:00', 120), N'GO')
+GO
+`
+
+func TestProcessTSQLDump_Scalar(t *testing.T) {
+	scalarTests := []struct {
+		ty       string
+		expected ddl.Type
+	}{
+		{"bigint", ddl.Type{Name: ddl.Int64}},
+		{"[int]", ddl.Type{Name: ddl.Int64}},
+		{"bit", ddl.Type{Name: ddl.Bool}},
+		{"[nvarchar](42)", ddl.Type{Name: ddl.String, Len: 42}},
+		{"nvarchar(max)", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+		{"varchar", ddl.Type{Name: ddl.String, Len: 1}},
+		{"sysname", ddl.Type{Name: ddl.String, Len: 128}},
+		{"varbinary(max)", ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}},
+		{"[decimal](18, 2)", ddl.Type{Name: ddl.Numeric}},
+		{"float", ddl.Type{Name: ddl.Float64}},
+		{"date", ddl.Type{Name: ddl.Date}},
+		{"datetime2(7)", ddl.Type{Name: ddl.Timestamp}},
+		{"datetimeoffset(7)", ddl.Type{Name: ddl.Timestamp}},
+		{"time(7)", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+		{"uniqueidentifier", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+		{"xml", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+	}
+	for _, tc := range scalarTests {
+		t.Run(tc.ty, func(t *testing.T) {
+			conv, _ := runProcessTSQLDump(fmt.Sprintf("CREATE TABLE t (a %s)\nGO\n", tc.ty))
+			tableId, _ := internal.GetTableIdFromSrcName(conv.SrcSchema, "t")
+			columnId, _ := internal.GetColIdFromSrcName(conv.SrcSchema[tableId].ColDefs, "a")
+			assert.Zero(t, conv.Unexpecteds())
+			assert.Equal(t, tc.expected, conv.SpSchema[tableId].ColDefs[columnId].T)
+		})
+	}
+}
+
+func TestProcessTSQLDump_GeneratedScript(t *testing.T) {
+	conv, rows := runProcessTSQLDump(generatedScript)
+	assert.Zero(t, conv.Unexpecteds(), fmt.Sprintf("unexpected conditions: %v", conv.Stats.Unexpected))
+	assert.Zero(t, conv.BadRows())
+
+	customersId, err := internal.GetTableIdFromSrcName(conv.SrcSchema, "Customers")
+	assert.Nil(t, err)
+	customers := conv.SrcSchema[customersId]
+	col := func(table schema.Table, name string) schema.Column {
+		return table.ColDefs[table.ColNameIdMap[name]]
+	}
+	assert.Equal(t, schema.Column{Id: customers.ColNameIdMap["CustomerID"], Name: "CustomerID", Type: schema.Type{Name: "int"}, NotNull: true, Ignored: schema.Ignored{AutoIncrement: true}}, col(customers, "CustomerID"))
+	assert.Equal(t, schema.Type{Name: "varchar", Mods: []int64{-1}}, col(customers, "Email").Type)
+	assert.True(t, col(customers, "Active").Ignored.Default)
+	ordersId, err := internal.GetTableIdFromSrcName(conv.SrcSchema, "Sales.Orders")
+	assert.Nil(t, err)
+	orders := conv.SrcSchema[ordersId]
+	assert.Equal(t, schema.Type{Name: "decimal", Mods: []int64{18, 2}}, col(orders, "Total").Type)
+	assert.True(t, col(orders, "Total").Ignored.Check)
+	assert.Equal(t, []schema.ForeignKey{{
+		Id:             orders.ForeignKeys[0].Id,
+		Name:           "FK_Orders_Customers",
+		ColIds:         []string{orders.ColNameIdMap["CustomerID"]},
+		ReferTableId:   customersId,
+		ReferColumnIds: []string{customers.ColNameIdMap["CustomerID"]},
+		OnDelete:       "CASCADE",
+	}}, orders.ForeignKeys)
+
+	expectedSchema := map[string]ddl.CreateTable{
+		"Customers": {
+			Name:   "Customers",
+			ColIds: []string{"CustomerID", "Name", "Email", "Active", "Created"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"CustomerID": {Name: "CustomerID", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+				"Name":       {Name: "Name", T: ddl.Type{Name: ddl.String, Len: 100}, NotNull: true},
+				"Email":      {Name: "Email", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+				"Active":     {Name: "Active", T: ddl.Type{Name: ddl.Bool}, NotNull: true},
+				"Created":    {Name: "Created", T: ddl.Type{Name: ddl.Timestamp}},
+			},
+			PrimaryKeys: []ddl.IndexKey{{ColId: "CustomerID", Order: 1}},
+			Indexes:     []ddl.CreateIndex{{Name: "UQ_Customers_Name", TableId: "Customers", Unique: true, Keys: []ddl.IndexKey{{ColId: "Name", Order: 1}}}},
+		},
+		"Sales_Orders": {
+			Name:   "Sales_Orders",
+			ColIds: []string{"OrderID", "CustomerID", "Total", "Status", "Receipt", "Placed", "Note"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"OrderID":    {Name: "OrderID", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+				"CustomerID": {Name: "CustomerID", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+				"Total":      {Name: "Total", T: ddl.Type{Name: ddl.Numeric}},
+				"Status":     {Name: "Status", T: ddl.Type{Name: ddl.String, Len: 1}},
+				"Receipt":    {Name: "Receipt", T: ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}},
+				"Placed":     {Name: "Placed", T: ddl.Type{Name: ddl.Timestamp}},
+				"Note":       {Name: "Note", T: ddl.Type{Name: ddl.String, Len: 20}},
+			},
+			PrimaryKeys: []ddl.IndexKey{{ColId: "OrderID", Desc: true, Order: 1}},
+			ForeignKeys: []ddl.Foreignkey{{Name: "FK_Orders_Customers", ColIds: []string{"CustomerID"}, ReferTableId: "Customers", ReferColumnIds: []string{"CustomerID"}}},
+			Indexes: []ddl.CreateIndex{{Name: "IX_Orders_Customer", TableId: "Sales_Orders", Keys: []ddl.IndexKey{{ColId: "CustomerID", Order: 1}, {ColId: "Placed", Desc: true, Order: 2}},
+				StoredColumnIds: []string{"Total"}}},
+		},
+	}
+	internal.AssertSpSchema(conv, t, expectedSchema, stripSchemaComments(conv.SpSchema))
+
+	assert.Equal(t, []spannerData{
+		{table: "Customers", cols: []string{"CustomerID", "Name", "Email", "Active", "Created"},
+			vals: []interface{}{int64(1), "O'Brien; Pat", "pat@example.com", true, getTimeWithoutTimezone(t, "2021-12-15T07:39:52.943")}},
+		{table: "Customers", cols: []string{"CustomerID", "Name", "Active"},
+			vals: []interface{}{int64(2), "Ana\nSmith", false}},
+		{table: "Sales_Orders", cols: []string{"OrderID", "CustomerID", "Total", "Status", "Receipt", "Placed", "Note"},
+			vals: []interface{}{int64(10), int64(1), big.NewRat(-25, 2), "N", []byte{0x89, 0x50, 0x4E}, getTimeWithoutTimezone(t, "2021-12-15T07:40:00"), "GO"}},
+	}, rows)
+
+	// The INSERT statement of the stored procedure isn't processed.
+	assert.Equal(t, int64(2), conv.Stats.Rows["Customers"])
+	assert.Equal(t, int64(1), conv.Stats.Rows["Sales.Orders"])
+	for kind, stats := range map[string][3]int64{ // Schema, data and skipped statements.
+		"CREATE TABLE":     {2, 0, 0},
+		"CREATE INDEX":     {1, 0, 0},
+		"ALTER TABLE":      {3, 0, 1},
+		"INSERT":           {0, 3, 0},
+		"SET":              {0, 0, 4},
+		"USE":              {0, 0, 1},
+		"CREATE PROCEDURE": {0, 0, 1},
+	} {
+		s := conv.Stats.Statement[kind]
+		assert.Equal(t, stats, [3]int64{s.Schema, s.Data, s.Skip}, kind)
+		assert.Zero(t, s.Error, kind)
+	}
+}
+
+func TestProcessTSQLDump_UTF16(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			var b bytes.Buffer
+			for _, u := range utf16.Encode([]rune("\uFEFF" + generatedScript)) {
+				binary.Write(&b, order, u)
+			}
+			conv, rows := runProcessTSQLDump(b.String())
+			assert.Zero(t, conv.Unexpecteds())
+			assert.Equal(t, 2, len(conv.SpSchema))
+			assert.Equal(t, 3, len(rows))
+			assert.Equal(t, "O'Brien; Pat", rows[0].vals[1])
+		})
+	}
+}
+
+func TestProcessTSQLDump_DataError(t *testing.T) {
+	script := "CREATE TABLE [dbo].[test] ([a] [int] NOT NULL PRIMARY KEY, [b] [date] NULL, [c] [datetimeoffset](7) NULL)\n" +
+		"GO\n" +
+		"INSERT INTO [test] ([a], [b], [c]) VALUES (1, '2019-10-29', '2021-12-08 03:00:52.95 +01:00'), (2, NULL, NULL)\n" + // Good
+		"INSERT INTO [test] ([a], [b]) VALUES (7.1, NULL)\n" + // Error
+		"INSERT INTO [test] ([a], [b]) VALUES (3, '2019-10-42')\n" + // Error
+		"INSERT INTO [test] ([a], [b]) VALUES (4, GETDATE())\n" + // Error
+		"INSERT INTO [test] ([a], [b]) VALUES (5)\n" // Error
+	conv, rows := runProcessTSQLDump(script)
+	assert.Equal(t, []spannerData{
+		{table: "test", cols: []string{"a", "b", "c"}, vals: []interface{}{int64(1), getDate("2019-10-29"), getTimeWithTimezone(t, "2021-12-08T03:00:52.95+01:00")}},
+		{table: "test", cols: []string{"a"}, vals: []interface{}{int64(2)}},
+	}, rows)
+	assert.Equal(t, int64(4), conv.BadRows())
+}
+
+func TestProcessTSQLDump_Errors(t *testing.T) {
+	_, err := processTSQLDumpString("CREATE TABLE t (a nvarchar(10))\nINSERT INTO t VALUES (N'unterminated)\n")
+	assert.NotNil(t, err)
+
+	conv, err := processTSQLDumpString("CREATE TABLE t (a int)\nGO\nCREATE INDEX i ON missing (a)\nGO\nALTER TABLE t ADD CONSTRAINT pk PRIMARY KEY (b)\nGO\n")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), conv.Unexpecteds())
+	assert.Equal(t, int64(1), conv.Stats.Statement["CREATE INDEX"].Skip)
+	assert.Equal(t, int64(1), conv.Stats.Statement["ALTER TABLE"].Error)
+}
+
+func processTSQLDumpString(s string) (*internal.Conv, error) {
+	conv := internal.MakeConv()
+	conv.SetSchemaMode()
+	err := common.ProcessDbDump(context.Background(), conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), DbDumpImpl{})
+	return conv, err
+}
+
+func runProcessTSQLDump(s string) (*internal.Conv, []spannerData) {
+	conv := internal.MakeConv()
+	conv.SetLocation(time.UTC)
+	conv.SetSchemaMode()
+	common.ProcessDbDump(context.Background(), conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), DbDumpImpl{})
+	conv.SetDataMode()
+	var rows []spannerData
+	conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+		rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
+	})
+	common.ProcessDbDump(context.Background(), conv, internal.NewReader(bufio.NewReader(strings.NewReader(s)), nil), DbDumpImpl{})
+	return conv, rows
+}
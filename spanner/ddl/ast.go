@@ -164,6 +164,7 @@ type ColumnDef struct {
 	NotNull bool
 	Comment string
 	Id      string
+	Checks  []string `json:",omitempty"` // CHECK constraint expressions, with the column written as VALUE.
 }
 
 // Config controls how AST nodes are printed (aka unparsed).
@@ -172,6 +173,7 @@ type Config struct {
 	ProtectIds  bool // If true, table and col names are quoted using backticks (avoids reserved-word issue).
 	Tables      bool // If true, print tables
 	ForeignKeys bool // If true, print foreign key constraints.
+	SkipIndexes bool // If true, don't print secondary indexes with tables.
 	SpDialect   string
 }
 
@@ -202,6 +204,63 @@ func (cd ColumnDef) PrintColumnDef(c Config) (string, string) {
 	return s, cd.Comment
 }
 
+// PrintChecks unparses the CHECK constraints of a column. Check expressions
+// refer to the column as VALUE (as PostgreSQL domain constraints do), so that
+// they don't have to be rewritten when the column is renamed.
+func (cd ColumnDef) PrintChecks(c Config) []string {
+	var l []string
+	for _, e := range cd.Checks {
+		l = append(l, fmt.Sprintf("CHECK (%s)", replaceValue(e, c.quote(cd.Name), c.SpDialect == constants.DIALECT_POSTGRESQL)))
+	}
+	return l
+}
+
+// replaceValue replaces the VALUE keyword of a check expression with name.
+// String literals and quoted identifiers are left as is. GoogleSQL string
+// literals use backslash escapes, while PostgreSQL string literals double
+// their quotes.
+func replaceValue(e, name string, pg bool) string {
+	var b strings.Builder
+	for i := 0; i < len(e); {
+		switch c := e[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			j := i + 1
+			for j < len(e) && e[j] != c {
+				if e[j] == '\\' && !pg {
+					j++
+				}
+				j++
+			}
+			if j < len(e) {
+				j++
+			} else {
+				j = len(e)
+			}
+			b.WriteString(e[i:j])
+			i = j
+		case isIdentChar(c):
+			j := i
+			for j < len(e) && isIdentChar(e[j]) {
+				j++
+			}
+			if e[i:j] == "VALUE" {
+				b.WriteString(name)
+			} else {
+				b.WriteString(e[i:j])
+			}
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || c >= 0x80
+}
+
 // IndexKey encodes the following DDL definition:
 //
 //	primary_key:
@@ -276,6 +335,12 @@ func (ct CreateTable) PrintCreateTable(spSchema Schema, config Config) string {
 		col = append(col, s)
 		colComment = append(colComment, c)
 	}
+	for _, colId := range ct.ColIds {
+		for _, s := range ct.ColDefs[colId].PrintChecks(config) {
+			col = append(col, "\t"+s+",")
+			colComment = append(colComment, "")
+		}
+	}
 
 	n := maxStringLength(col)
 	var cols string
@@ -442,6 +507,9 @@ func (s Schema) GetDDL(c Config) []string {
 	if c.Tables {
 		for _, tableId := range tableIds {
 			ddl = append(ddl, s[tableId].PrintCreateTable(s, c))
+			if c.SkipIndexes {
+				continue
+			}
 			for _, index := range s[tableId].Indexes {
 				ddl = append(ddl, index.PrintCreateIndex(s[tableId], c))
 			}
@@ -463,6 +531,32 @@ func (s Schema) GetDDL(c Config) []string {
 	return ddl
 }
 
+// GetPostDataDDL returns the statements that create the secondary indexes
+// and, if c.ForeignKeys is set, the foreign key constraints of s once its
+// tables exist and are loaded. The indexes must be applied before the foreign
+// keys, so that every foreign key is created after the indexes of the tables
+// it links. Tables are visited in the same order as GetDDL (parents before
+// their interleaved children). Indexes and foreign keys whose name is in
+// exists (lower-cased, since Spanner names are case-insensitive) are left out.
+func (s Schema) GetPostDataDDL(c Config, exists map[string]bool) (indexes, foreignKeys []string) {
+	for _, t := range GetSortedTableIdsBySpName(s) {
+		for _, index := range s[t].Indexes {
+			if !exists[strings.ToLower(index.Name)] {
+				indexes = append(indexes, index.PrintCreateIndex(s[t], c))
+			}
+		}
+		if !c.ForeignKeys {
+			continue
+		}
+		for _, fk := range s[t].ForeignKeys {
+			if !exists[strings.ToLower(fk.Name)] {
+				foreignKeys = append(foreignKeys, fk.PrintForeignKeyAlterTable(s, c, t))
+			}
+		}
+	}
+	return indexes, foreignKeys
+}
+
 // CheckInterleaved checks if schema contains interleaved tables.
 func (s Schema) CheckInterleaved() bool {
 	for _, table := range s {
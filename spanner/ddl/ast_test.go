@@ -96,6 +96,43 @@ func TestPrintColumnDefPG(t *testing.T) {
 	}
 }
 
+func TestPrintChecks(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []string
+		protectIds bool
+		dialect    string
+		expected   []string
+	}{
+		{"in list", []string{"VALUE IN ('a', 'b')"}, false, "", []string{"CHECK (Col IN ('a', 'b'))"}},
+		{"several", []string{"VALUE > 0", "VALUE < 10"}, true, "", []string{"CHECK (`Col` > 0)", "CHECK (`Col` < 10)"}},
+		{"literals", []string{`VALUE != 'VALUE\'s' AND VALUES_1 = "VALUE"`}, false, "", []string{`CHECK (Col != 'VALUE\'s' AND VALUES_1 = "VALUE")`}},
+		{"pg literals", []string{`VALUE <> 'it''s VALUE\'`}, false, constants.DIALECT_POSTGRESQL, []string{`CHECK (Col <> 'it''s VALUE\')`}},
+		{"unterminated", []string{`VALUE = 'a\`}, false, "", []string{`CHECK (Col = 'a\)`}},
+	}
+	for _, tc := range tests {
+		cd := ColumnDef{Name: "Col", T: Type{Name: String, Len: MaxLength}, Checks: tc.checks}
+		assert.Equal(t, tc.expected, cd.PrintChecks(Config{ProtectIds: tc.protectIds, SpDialect: tc.dialect}), tc.name)
+	}
+	ct := CreateTable{
+		Name:        "mytable",
+		ColIds:      []string{"col1", "col2"},
+		ColDefs:     map[string]ColumnDef{"col1": {Name: "col1", T: Type{Name: Int64}, NotNull: true}, "col2": {Name: "col2", T: Type{Name: String, Len: MaxLength}, Checks: []string{"VALUE IN ('x')"}}},
+		PrimaryKeys: []IndexKey{{ColId: "col1"}},
+	}
+	assert.Equal(t, "CREATE TABLE mytable (\n"+
+		"	col1 INT64 NOT NULL,\n"+
+		"	col2 STRING(MAX),\n"+
+		"	CHECK (col2 IN ('x')),\n"+
+		") PRIMARY KEY (col1)", ct.PrintCreateTable(Schema{}, Config{}))
+	assert.Equal(t, "CREATE TABLE mytable (\n"+
+		"	col1 INT8 NOT NULL,\n"+
+		"	col2 VARCHAR(2621440),\n"+
+		"	CHECK (col2 IN ('x')),\n"+
+		"	PRIMARY KEY (col1)\n"+
+		")", ct.PrintCreateTable(Schema{}, Config{SpDialect: constants.DIALECT_POSTGRESQL}))
+}
+
 func TestPrintPkOrIndexKey(t *testing.T) {
 	ct := CreateTable{
 		Name:   "table1",
@@ -589,3 +626,93 @@ func TestGetPGDDL(t *testing.T) {
 	}
 	assert.ElementsMatch(t, e3, tablesAndFks)
 }
+
+func TestGetDDLSkipIndexes(t *testing.T) {
+	s := postDataSchema()
+	e := []string{
+		"CREATE TABLE table1 (\n" +
+			"	a INT64,\n" +
+			"	b INT64,\n" +
+			") PRIMARY KEY (a)",
+		"CREATE TABLE table2 (\n" +
+			"	a INT64,\n" +
+			"	b INT64,\n" +
+			") PRIMARY KEY (a),\n" +
+			"INTERLEAVE IN PARENT table1",
+	}
+	assert.Equal(t, e, s.GetDDL(Config{Tables: true, SkipIndexes: true}))
+}
+
+func TestGetPostDataDDL(t *testing.T) {
+	s := postDataSchema()
+	allIndexes := []string{
+		"CREATE INDEX index1 ON table1 (b)",
+		"CREATE UNIQUE INDEX index2 ON table2 (b DESC)",
+	}
+	tests := []struct {
+		name            string
+		c               Config
+		exists          map[string]bool
+		wantIndexes     []string
+		wantForeignKeys []string
+	}{
+		{
+			name:            "indexes and foreign keys",
+			c:               Config{ForeignKeys: true},
+			wantIndexes:     allIndexes,
+			wantForeignKeys: []string{"ALTER TABLE table2 ADD CONSTRAINT FK1 FOREIGN KEY (b) REFERENCES table1 (b)"},
+		},
+		{
+			name:        "indexes only",
+			c:           Config{ForeignKeys: false},
+			wantIndexes: allIndexes,
+		},
+		{
+			name:        "existing indexes and foreign keys are skipped",
+			c:           Config{ForeignKeys: true},
+			exists:      map[string]bool{"index1": true, "fk1": true},
+			wantIndexes: []string{"CREATE UNIQUE INDEX index2 ON table2 (b DESC)"},
+		},
+		{
+			name:   "everything exists",
+			c:      Config{ForeignKeys: true},
+			exists: map[string]bool{"index1": true, "index2": true, "fk1": true},
+		},
+	}
+	for _, tc := range tests {
+		indexes, foreignKeys := s.GetPostDataDDL(tc.c, tc.exists)
+		assert.Equal(t, tc.wantIndexes, indexes, tc.name)
+		assert.Equal(t, tc.wantForeignKeys, foreignKeys, tc.name)
+	}
+}
+
+// postDataSchema returns a schema with an interleaved table, secondary
+// indexes and a foreign key.
+func postDataSchema() Schema {
+	return Schema{
+		"t2": CreateTable{
+			Name:   "table2",
+			Id:     "t2",
+			ColIds: []string{"c3", "c4"},
+			ColDefs: map[string]ColumnDef{
+				"c3": {Name: "a", Id: "c3", T: Type{Name: Int64}},
+				"c4": {Name: "b", Id: "c4", T: Type{Name: Int64}},
+			},
+			PrimaryKeys: []IndexKey{{ColId: "c3"}},
+			ForeignKeys: []Foreignkey{{Name: "FK1", ColIds: []string{"c4"}, ReferTableId: "t1", ReferColumnIds: []string{"c2"}}},
+			Indexes:     []CreateIndex{{Name: "index2", TableId: "t2", Unique: true, Keys: []IndexKey{{ColId: "c4", Desc: true}}}},
+			ParentId:    "t1",
+		},
+		"t1": CreateTable{
+			Name:   "table1",
+			Id:     "t1",
+			ColIds: []string{"c1", "c2"},
+			ColDefs: map[string]ColumnDef{
+				"c1": {Name: "a", Id: "c1", T: Type{Name: Int64}},
+				"c2": {Name: "b", Id: "c2", T: Type{Name: Int64}},
+			},
+			PrimaryKeys: []IndexKey{{ColId: "c1"}},
+			Indexes:     []CreateIndex{{Name: "index1", TableId: "t1", Keys: []IndexKey{{ColId: "c2"}}}},
+		},
+	}
+}
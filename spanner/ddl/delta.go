@@ -0,0 +1,210 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+)
+
+// GetDDLDelta returns the DDL statements that change a database with
+// schema old into one with schema new. Tables and columns are matched by
+// id. Spanner can't rename tables or columns, or change the primary key
+// or parent of a table, so tables with such changes are dropped and
+// recreated (along with their interleaved tables), and renamed columns are
+// dropped and added back. Statements are ordered so that each one can be
+// applied after the previous ones: foreign keys and indexes are dropped
+// before the tables and columns they use, and created after them.
+func GetDDLDelta(old, new Schema, c Config) []string {
+	recreated := recreatedTables(old, new)
+	dropped := func(id string) bool {
+		_, ok := new[id]
+		return !ok || recreated[id]
+	}
+	created := func(id string) bool {
+		_, ok := old[id]
+		return !ok || recreated[id]
+	}
+	oldIds, newIds := GetSortedTableIdsBySpName(old), GetSortedTableIdsBySpName(new)
+	var stmts []string
+
+	// Drop foreign keys, of and to dropped tables, and that changed.
+	for _, id := range oldIds {
+		for _, fk := range old[id].ForeignKeys {
+			if dropped(id) || dropped(fk.ReferTableId) || fkChanged(old, new, id, fk) {
+				stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", c.quote(old[id].Name), c.quote(fk.Name)))
+			}
+		}
+	}
+	// Drop indexes of dropped tables, and that changed.
+	for _, id := range oldIds {
+		for _, idx := range old[id].Indexes {
+			if dropped(id) || indexChanged(old, new, id, idx, c) {
+				stmts = append(stmts, fmt.Sprintf("DROP INDEX %s", c.quote(idx.Name)))
+			}
+		}
+	}
+	// Drop columns that were removed or renamed.
+	for _, id := range oldIds {
+		if dropped(id) {
+			continue
+		}
+		for _, colId := range old[id].ColIds {
+			if cd, ok := new[id].ColDefs[colId]; !ok || cd.Name != old[id].ColDefs[colId].Name {
+				stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", c.quote(old[id].Name), c.quote(old[id].ColDefs[colId].Name)))
+			}
+		}
+	}
+	// Drop tables, interleaved tables before their parents.
+	for i := len(oldIds) - 1; i >= 0; i-- {
+		if id := oldIds[i]; dropped(id) {
+			stmts = append(stmts, fmt.Sprintf("DROP TABLE %s", c.quote(old[id].Name)))
+		}
+	}
+	// Create tables, parents before their interleaved tables.
+	for _, id := range newIds {
+		if created(id) {
+			stmts = append(stmts, new[id].PrintCreateTable(new, c))
+		}
+	}
+	// Add columns that are new or were renamed, and alter columns whose
+	// type or nullability changed.
+	for _, id := range newIds {
+		if created(id) {
+			continue
+		}
+		for _, colId := range new[id].ColIds {
+			cd := new[id].ColDefs[colId]
+			oldCd, ok := old[id].ColDefs[colId]
+			if !ok || oldCd.Name != cd.Name {
+				s, _ := cd.PrintColumnDef(c)
+				stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", c.quote(new[id].Name), s))
+				for _, check := range cd.PrintChecks(c) {
+					stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD %s", c.quote(new[id].Name), check))
+				}
+				continue
+			}
+			stmts = append(stmts, alterColumn(new[id], oldCd, cd, c)...)
+		}
+	}
+	// Create indexes of created tables, and that changed.
+	for _, id := range newIds {
+		for _, idx := range new[id].Indexes {
+			if created(id) || indexChanged(new, old, id, idx, c) {
+				stmts = append(stmts, idx.PrintCreateIndex(new[id], c))
+			}
+		}
+	}
+	// Add foreign keys, of and to created tables, and that changed.
+	for _, id := range newIds {
+		for _, fk := range new[id].ForeignKeys {
+			if created(id) || created(fk.ReferTableId) || fkChanged(new, old, id, fk) {
+				stmts = append(stmts, fk.PrintForeignKeyAlterTable(new, c, id))
+			}
+		}
+	}
+	return stmts
+}
+
+// recreatedTables returns the ids of the tables of both old and new that
+// have to be dropped and created again to get from old to new.
+func recreatedTables(old, new Schema) map[string]bool {
+	recreated := make(map[string]bool)
+	for id, ot := range old {
+		nt, ok := new[id]
+		if ok && (ot.Name != nt.Name || ot.ParentId != nt.ParentId || primaryKey(ot) != primaryKey(nt)) {
+			recreated[id] = true
+		}
+	}
+	// Interleaved tables must be dropped before their parent, and so are
+	// recreated with it.
+	for changed := true; changed; {
+		changed = false
+		for id, ot := range old {
+			_, ok := new[id]
+			_, parentKept := new[ot.ParentId]
+			if ok && !recreated[id] && ot.ParentId != "" && (recreated[ot.ParentId] || !parentKept) {
+				recreated[id] = true
+				changed = true
+			}
+		}
+	}
+	return recreated
+}
+
+func primaryKey(ct CreateTable) string {
+	pks := append([]IndexKey{}, ct.PrimaryKeys...)
+	sort.Slice(pks, func(i, j int) bool { return pks[i].Order < pks[j].Order })
+	var keys []string
+	for _, pk := range pks {
+		keys = append(keys, pk.PrintPkOrIndexKey(ct, Config{ProtectIds: true}))
+	}
+	return strings.Join(keys, ", ")
+}
+
+// indexChanged returns whether index idx of table id of schema s is not in
+// schema other, or is defined differently there.
+func indexChanged(s, other Schema, id string, idx CreateIndex, c Config) bool {
+	for _, otherIdx := range other[id].Indexes {
+		if otherIdx.Id == idx.Id {
+			return otherIdx.PrintCreateIndex(other[id], c) != idx.PrintCreateIndex(s[id], c)
+		}
+	}
+	return true
+}
+
+// fkChanged returns whether foreign key fk of table id of schema s is not
+// in schema other, or is defined differently there.
+func fkChanged(s, other Schema, id string, fk Foreignkey) bool {
+	if _, ok := other[fk.ReferTableId]; !ok {
+		return true
+	}
+	c := Config{ProtectIds: true}
+	for _, otherFk := range other[id].ForeignKeys {
+		if otherFk.Id == fk.Id {
+			return otherFk.PrintForeignKeyAlterTable(other, c, id) != fk.PrintForeignKeyAlterTable(s, c, id)
+		}
+	}
+	return true
+}
+
+// alterColumn returns the statements that change column old of table ct
+// into column new.
+func alterColumn(ct CreateTable, old, new ColumnDef, c Config) []string {
+	table := c.quote(ct.Name)
+	if c.SpDialect != constants.DIALECT_POSTGRESQL {
+		o, _ := old.PrintColumnDef(c)
+		n, _ := new.PrintColumnDef(c)
+		if o == n {
+			return nil
+		}
+		return []string{fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", table, n)}
+	}
+	var stmts []string
+	if t := new.T.PGPrintColumnDefType(); t != old.T.PGPrintColumnDefType() {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s", table, c.quote(new.Name), t))
+	}
+	if new.NotNull != old.NotNull {
+		op := "DROP"
+		if new.NotNull {
+			op = "SET"
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s NOT NULL", table, c.quote(new.Name), op))
+	}
+	return stmts
+}
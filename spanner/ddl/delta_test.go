@@ -0,0 +1,164 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"testing"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeDeltaSchema() Schema {
+	return Schema{
+		"t1": CreateTable{
+			Name:   "singers",
+			Id:     "t1",
+			ColIds: []string{"c1", "c2"},
+			ColDefs: map[string]ColumnDef{
+				"c1": {Name: "singer_id", Id: "c1", T: Type{Name: Int64}, NotNull: true},
+				"c2": {Name: "name", Id: "c2", T: Type{Name: String, Len: MaxLength}},
+			},
+			PrimaryKeys: []IndexKey{{ColId: "c1", Order: 1}},
+			Indexes:     []CreateIndex{{Name: "singers_name", TableId: "t1", Id: "i1", Keys: []IndexKey{{ColId: "c2", Order: 1}}}},
+		},
+		"t2": CreateTable{
+			Name:   "albums",
+			Id:     "t2",
+			ColIds: []string{"c3", "c4"},
+			ColDefs: map[string]ColumnDef{
+				"c3": {Name: "singer_id", Id: "c3", T: Type{Name: Int64}, NotNull: true},
+				"c4": {Name: "album_id", Id: "c4", T: Type{Name: Int64}, NotNull: true},
+			},
+			PrimaryKeys: []IndexKey{{ColId: "c3", Order: 1}, {ColId: "c4", Order: 2}},
+			ParentId:    "t1",
+		},
+		"t3": CreateTable{
+			Name:   "reviews",
+			Id:     "t3",
+			ColIds: []string{"c5", "c6", "c7"},
+			ColDefs: map[string]ColumnDef{
+				"c5": {Name: "review_id", Id: "c5", T: Type{Name: Int64}, NotNull: true},
+				"c6": {Name: "singer_id", Id: "c6", T: Type{Name: Int64}},
+				"c7": {Name: "album_id", Id: "c7", T: Type{Name: Int64}},
+			},
+			PrimaryKeys: []IndexKey{{ColId: "c5", Order: 1}},
+			ForeignKeys: []Foreignkey{{Name: "fk_albums", Id: "f1", ColIds: []string{"c6", "c7"}, ReferTableId: "t2", ReferColumnIds: []string{"c3", "c4"}}},
+		},
+	}
+}
+
+func TestGetDDLDelta(t *testing.T) {
+	tests := []struct {
+		name     string
+		edit     func(s Schema)
+		dialect  string
+		expected []string
+	}{
+		{
+			name:     "no change",
+			edit:     func(s Schema) {},
+			expected: nil,
+		},
+		{
+			name: "columns",
+			edit: func(s Schema) {
+				t3 := s["t3"]
+				t3.ColIds = append(t3.ColIds, "c8", "c9")
+				t3.ColDefs["c5"] = ColumnDef{Name: "review_id", Id: "c5", T: Type{Name: String, Len: 36}, NotNull: true}
+				t3.ColDefs["c8"] = ColumnDef{Name: "rating", Id: "c8", T: Type{Name: Int64}, Checks: []string{"VALUE > 0"}}
+				t3.ColDefs["c9"] = ColumnDef{Name: "text", Id: "c9", T: Type{Name: String, Len: MaxLength}}
+				s["t3"] = t3
+				t1 := s["t1"]
+				t1.ColDefs["c2"] = ColumnDef{Name: "full_name", Id: "c2", T: Type{Name: String, Len: MaxLength}}
+			},
+			expected: []string{
+				"DROP INDEX singers_name",
+				"ALTER TABLE singers DROP COLUMN name",
+				"ALTER TABLE reviews ALTER COLUMN review_id STRING(36) NOT NULL",
+				"ALTER TABLE reviews ADD COLUMN rating INT64",
+				"ALTER TABLE reviews ADD CHECK (rating > 0)",
+				"ALTER TABLE reviews ADD COLUMN text STRING(MAX)",
+				"ALTER TABLE singers ADD COLUMN full_name STRING(MAX)",
+				"CREATE INDEX singers_name ON singers (full_name)",
+			},
+		},
+		{
+			name: "columns pg",
+			edit: func(s Schema) {
+				t3 := s["t3"]
+				t3.ColDefs["c5"] = ColumnDef{Name: "review_id", Id: "c5", T: Type{Name: String, Len: 36}, NotNull: true}
+				t3.ColDefs["c6"] = ColumnDef{Name: "singer_id", Id: "c6", T: Type{Name: Int64}, NotNull: true}
+			},
+			dialect: constants.DIALECT_POSTGRESQL,
+			expected: []string{
+				"ALTER TABLE reviews ALTER COLUMN review_id TYPE VARCHAR(36)",
+				"ALTER TABLE reviews ALTER COLUMN singer_id SET NOT NULL",
+			},
+		},
+		{
+			name: "drop table",
+			edit: func(s Schema) {
+				delete(s, "t2")
+				t3 := s["t3"]
+				t3.ForeignKeys = nil
+				s["t3"] = t3
+			},
+			expected: []string{
+				"ALTER TABLE reviews DROP CONSTRAINT fk_albums",
+				"DROP TABLE albums",
+			},
+		},
+		{
+			name: "primary key",
+			edit: func(s Schema) {
+				t1 := s["t1"]
+				t1.PrimaryKeys = []IndexKey{{ColId: "c1", Order: 1, Desc: true}}
+				s["t1"] = t1
+			},
+			expected: []string{
+				"ALTER TABLE reviews DROP CONSTRAINT fk_albums",
+				"DROP INDEX singers_name",
+				"DROP TABLE albums",
+				"DROP TABLE singers",
+				"CREATE TABLE singers (\n\tsinger_id INT64 NOT NULL,\n\tname STRING(MAX),\n) PRIMARY KEY (singer_id DESC)",
+				"CREATE TABLE albums (\n\tsinger_id INT64 NOT NULL,\n\talbum_id INT64 NOT NULL,\n) PRIMARY KEY (singer_id, album_id),\nINTERLEAVE IN PARENT singers",
+				"CREATE INDEX singers_name ON singers (name)",
+				"ALTER TABLE reviews ADD CONSTRAINT fk_albums FOREIGN KEY (singer_id, album_id) REFERENCES albums (singer_id, album_id)",
+			},
+		},
+		{
+			name: "add table",
+			edit: func(s Schema) {
+				s["t4"] = CreateTable{
+					Name:        "labels",
+					Id:          "t4",
+					ColIds:      []string{"c10"},
+					ColDefs:     map[string]ColumnDef{"c10": {Name: "label_id", Id: "c10", T: Type{Name: Int64}, NotNull: true}},
+					PrimaryKeys: []IndexKey{{ColId: "c10", Order: 1}},
+				}
+			},
+			expected: []string{
+				"CREATE TABLE labels (\n\tlabel_id INT64 NOT NULL,\n) PRIMARY KEY (label_id)",
+			},
+		},
+	}
+	for _, tc := range tests {
+		new := makeDeltaSchema()
+		tc.edit(new)
+		got := GetDDLDelta(makeDeltaSchema(), new, Config{SpDialect: tc.dialect})
+		assert.Equal(t, tc.expected, got, tc.name)
+	}
+}
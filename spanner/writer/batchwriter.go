@@ -18,13 +18,17 @@
 package writer
 
 import (
+	"context"
 	"fmt"
+	"math/big"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"time"
 	"unsafe"
 
 	sp "cloud.google.com/go/spanner"
+	"github.com/cloudspannerecosystem/harbourbridge/common/telemetry"
 	"github.com/cloudspannerecosystem/harbourbridge/logger"
 )
 
@@ -61,12 +65,34 @@ type BatchWriter struct {
 	retryLimit int64                      // Limit on retries.
 	verbose    bool                       // If true, print out messages about each write batch.
 	async      asyncState
+
+	oversized        OversizedValuePolicy                                 // What to do with values that exceed Spanner's limits.
+	valueStore       ValueStore                                           // Where values are offloaded to.
+	onOversizedValue func(table, col string, policy OversizedValuePolicy) // Called for each truncated or offloaded value.
+	onWritten        func(table string, n int64)                          // Called after rows of a table are written.
+	onDropped        func(table string, n int64, err error)               // Called after rows of a table are dropped.
+	ctx              context.Context                                      // Once done, rows are no longer written.
+
+	// Called with each dropped row, and the writes of it attempted.
+	onBadRow func(table string, cols []string, vals []interface{}, source interface{}, attempts int64, err error)
+
+	commit     func([]*sp.Mutation) (int64, error) // If set, used instead of write, and returns the mutation count of the commit.
+	batchWrite func([][]*sp.Mutation) []error      // Writes mutation groups with StrategyMutationGroups.
+	strategy   WriteStrategy
+	groupRows  int                 // Maximum rows of a mutation group with StrategyMutationGroups.
+	keyOrder   *keyOrder           // If set, buffered rows are sorted by key before being batched.
+	sorted     bool                // Whether the buffered rows are sorted by key.
+	window     int64               // Batches worth of rows buffered before writing.
+	limiter    *concurrencyLimiter // If set, adapts the limit on in-progress writes.
+	stats      writeStats
 }
 
 type row struct {
-	table string
-	cols  []string
-	vals  []interface{}
+	table    string
+	cols     []string
+	vals     []interface{}
+	attempts int64       // Writes of the row attempted so far.
+	source   interface{} // Source of the row, passed to OnBadRow.
 }
 
 // Fields in this struct are modified asynchronously e.g. by go routines writing
@@ -93,30 +119,135 @@ type BatchWriterConfig struct {
 	RetryLimit int64                      // Limit on retries.
 	Write      func([]*sp.Mutation) error // Function to call to write to Spanner (typically a closure that calls client.Apply).
 	Verbose    bool                       // If true, print out messages about each write batch.
+
+	// OversizedValues specifies what to do with STRING and BYTES values that
+	// exceed Spanner's limits. Defaults to OversizedFail.
+	OversizedValues OversizedValuePolicy
+	// ValueStore is where values are offloaded to with OversizedOffload.
+	ValueStore ValueStore
+	// OnOversizedValue, if set, is called for each value that was truncated
+	// or offloaded.
+	OnOversizedValue func(table, col string, policy OversizedValuePolicy)
+	// OnWritten, if set, is called with the number of rows of table n each
+	// time rows were written. It is called from the go routines that write
+	// data, so must be thread-safe.
+	OnWritten func(table string, n int64)
+	// OnDropped, if set, is called with the number of rows of table n each
+	// time rows were dropped because writing them failed with err. Like
+	// OnWritten, it must be thread-safe.
+	OnDropped func(table string, n int64, err error)
+	// OnBadRow, if set, is called with each row that is dropped because
+	// writing it failed with err, the source it was added with (see
+	// AddSourceRow), and the number of writes attempted. It must be
+	// thread-safe too.
+	OnBadRow func(table string, cols []string, vals []interface{}, source interface{}, attempts int64, err error)
+	// Context, if set, cancels the writes: once it is done, rows that are
+	// added or buffered are discarded, and failed writes are not retried.
+	Context context.Context
+
+	// Commit, if set, is used instead of Write to commit a batch, and
+	// returns the number of mutations in the commit stats of Spanner.
+	Commit func([]*sp.Mutation) (int64, error)
+	// WriteStrategy is how batches are written. With
+	// StrategyMutationGroups, each batch is split into mutation groups of
+	// at most GroupRows rows of a table (DefaultGroupRows if 0), that are
+	// written with BatchWrite and committed independently of each other.
+	WriteStrategy WriteStrategy
+	GroupRows     int
+	// BatchWrite writes mutation groups in a single request (typically a
+	// closure that calls client.BatchWrite), and returns the error of
+	// each group, nil if it was committed. Without it, batches are
+	// written as with StrategyApply.
+	BatchWrite func(groups [][]*sp.Mutation) []error
+	// KeyColumns, if set, returns the primary key columns of a Spanner
+	// table. Rows are then buffered and sorted by table and key before
+	// being batched, and batches hold rows of a single table, so that
+	// commits span fewer key ranges. It is called once per table and
+	// columns of its rows.
+	KeyColumns func(table string) []string
+	// AdaptiveConcurrency adapts the number of in-progress writes, up to
+	// WriteLimit, to the latency of writes: it grows while writes take
+	// less than TargetLatency (DefaultTargetLatency if 0), and shrinks
+	// when they take longer or fail because Spanner is overloaded.
+	AdaptiveConcurrency bool
+	TargetLatency       time.Duration
 }
 
 // NewBatchWriter returns a new BatchWriter with parameters defined by config.
 func NewBatchWriter(config BatchWriterConfig) *BatchWriter {
-	return &BatchWriter{
-		write:      config.Write,
-		writeLimit: config.WriteLimit,
-		bytesLimit: config.BytesLimit,
-		retryLimit: config.RetryLimit,
-		verbose:    config.Verbose,
+	ctx := config.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	bw := &BatchWriter{
+		write:            config.Write,
+		writeLimit:       config.WriteLimit,
+		bytesLimit:       config.BytesLimit,
+		retryLimit:       config.RetryLimit,
+		verbose:          config.Verbose,
+		oversized:        config.OversizedValues,
+		valueStore:       config.ValueStore,
+		onOversizedValue: config.OnOversizedValue,
+		onWritten:        config.OnWritten,
+		onDropped:        config.OnDropped,
+		onBadRow:         config.OnBadRow,
+		ctx:              ctx,
 		async: asyncState{
 			errors:      make(map[string]int64),
 			droppedRows: make(map[string]int64),
 		},
+		commit:     config.Commit,
+		batchWrite: config.BatchWrite,
+		strategy:   config.WriteStrategy,
+		groupRows:  config.GroupRows,
+		window:     1,
+	}
+	if bw.groupRows <= 0 {
+		bw.groupRows = DefaultGroupRows
+	}
+	if config.KeyColumns != nil {
+		bw.keyOrder = newKeyOrder(config.KeyColumns)
+		bw.window = keyOrderWindow
+	}
+	if config.AdaptiveConcurrency {
+		target := config.TargetLatency
+		if target <= 0 {
+			target = DefaultTargetLatency
+		}
+		bw.limiter = newConcurrencyLimiter(config.WriteLimit, target)
 	}
+	return bw
 }
 
 // AddRow appends a new row of data to bw's buffer of rows. Depending on the
 // state of BatchWriter, AddRow may immediately return, or it may initiate writes,
 // or it may block (waiting for some of the writes already in progress to
 // complete) and then initiate writes.
+// Rows with values that exceed Spanner's limits are handled as specified
+// by BatchWriterConfig.OversizedValues.
 func (bw *BatchWriter) AddRow(table string, cols []string, vals []interface{}) {
-	r := &row{table, cols, vals}
+	bw.RetryRow(table, cols, vals, 0)
+}
+
+// RetryRow is like AddRow, for a row that was already written attempts
+// times without success e.g. a row replayed from a dead-letter file.
+func (bw *BatchWriter) RetryRow(table string, cols []string, vals []interface{}, attempts int64) {
+	bw.AddSourceRow(table, cols, vals, nil, attempts)
+}
+
+// AddSourceRow is like RetryRow, for a row converted from source, which
+// is passed to OnBadRow if the row is dropped.
+func (bw *BatchWriter) AddSourceRow(table string, cols []string, vals []interface{}, source interface{}, attempts int64) {
+	if bw.cancelled() {
+		return
+	}
+	r := &row{table, cols, vals, attempts, source}
+	if err := bw.handleOversizedValues(r); err != nil {
+		bw.errorStats([]*row{r}, err, false)
+		return
+	}
 	bw.rows = append(bw.rows, r)
+	bw.sorted = false
 	bw.rBytes += byteSize(r)
 	bw.rCount += int64(len(r.cols))
 	bw.writeData()
@@ -126,7 +257,11 @@ func (bw *BatchWriter) AddRow(table string, cols []string, vals []interface{}) {
 // for them to complete.
 func (bw *BatchWriter) Flush() {
 	for len(bw.rows) > 0 {
-		if atomic.LoadInt64(&bw.async.writes) < bw.writeLimit {
+		if bw.cancelled() {
+			bw.rows, bw.rCount, bw.rBytes = nil, 0, 0
+			break
+		}
+		if atomic.LoadInt64(&bw.async.writes) < bw.concurrency() {
 			m, count, bytes := bw.getBatch()
 			if bw.verbose {
 				fmt.Printf("Starting write of %d rows to Spanner (%d bytes, %d mutations) [%d in progress]\n",
@@ -135,7 +270,7 @@ func (bw *BatchWriter) Flush() {
 			logger.Log.Debug(fmt.Sprintf("Starting write of %d rows to Spanner (%d bytes, %d mutations) [%d in progress]\n",
 				len(m), bytes, count, atomic.LoadInt64(&bw.async.writes)))
 
-			bw.startWrite(m)
+			bw.startWrite(m, bytes)
 		} else {
 			time.Sleep(10 * time.Millisecond)
 		}
@@ -194,7 +329,13 @@ func (bw *BatchWriter) getBadRowsForTest() []*row {
 
 // getBatch returns a slice of data from the front of bw.rows.  The slice
 // returned is the largest one not exceeding countThreshold and byteThreshold.
+// With key-ordered batching, bw.rows are sorted first, and the slice only
+// has rows of one table.
 func (bw *BatchWriter) getBatch() (rows []*row, count int64, bytes int64) {
+	if bw.keyOrder != nil && !bw.sorted {
+		bw.keyOrder.sort(bw.rows)
+		bw.sorted = true
+	}
 	for i := range bw.rows {
 		c := count + int64(len(bw.rows[i].cols))
 		b := bytes + byteSize(bw.rows[i])
@@ -202,7 +343,8 @@ func (bw *BatchWriter) getBatch() (rows []*row, count int64, bytes int64) {
 		// we have at least one row. If a single row puts us over the
 		// thresholds, there's not much we can do: we just try sending it to Spanner
 		// (it might succeed, since our thresholds are conservative).
-		if (c >= countThreshold || b >= byteThreshold) && len(rows) >= 1 {
+		tableChange := bw.keyOrder != nil && len(rows) >= 1 && bw.rows[i].table != rows[0].table
+		if ((c >= countThreshold || b >= byteThreshold) && len(rows) >= 1) || tableChange {
 			bw.rCount -= count
 			bw.rBytes -= bytes
 			bw.rows = bw.rows[i:]
@@ -241,8 +383,19 @@ func (bw *BatchWriter) errorStats(rows []*row, err error, retry bool) {
 			bw.async.sampleBadRowsBytes += n
 		}
 	}
+	dropped := make(map[string]int64)
 	for _, x := range rows {
 		bw.async.droppedRows[x.table]++
+		dropped[x.table]++
+		if bw.onBadRow != nil {
+			bw.onBadRow(x.table, x.cols, x.vals, x.source, x.attempts, err)
+		}
+	}
+	for table, n := range dropped {
+		telemetry.AddRowsDropped(table, n)
+		if bw.onDropped != nil {
+			bw.onDropped(table, n, err)
+		}
 	}
 	return
 }
@@ -250,11 +403,65 @@ func (bw *BatchWriter) errorStats(rows []*row, err error, retry bool) {
 // Note: doWriteAndHandleErrors must be thread-safe because it is run
 // inside a go routine.
 func (bw *BatchWriter) doWriteAndHandleErrors(rows []*row) {
-	var m []*sp.Mutation
+	if bw.strategy == StrategyMutationGroups && bw.batchWrite != nil {
+		bw.doBatchWriteAndHandleErrors(bw.getGroups(rows))
+		return
+	}
 	for _, x := range rows {
-		m = append(m, sp.Insert(x.table, x.cols, x.vals))
+		x.attempts++
+	}
+	m := mutations(rows)
+	start := time.Now()
+	var (
+		count int64
+		err   error
+	)
+	if bw.commit != nil {
+		count, err = bw.commit(m)
+	} else {
+		err = bw.write(m)
 	}
-	if err := bw.write(m); err != nil {
+	bw.recordWrite(start, time.Since(start), err)
+	bw.handleWriteResult(rows, err, count)
+}
+
+// doBatchWriteAndHandleErrors writes groups of rows as mutation groups in
+// a single request, and handles the result of each group independently:
+// the rows of a failed group are retried like a failed batch.
+// Note: doBatchWriteAndHandleErrors must be thread-safe because it is run
+// inside a go routine.
+func (bw *BatchWriter) doBatchWriteAndHandleErrors(groups [][]*row) {
+	m := make([][]*sp.Mutation, len(groups))
+	for i, g := range groups {
+		for _, x := range g {
+			x.attempts++
+		}
+		m[i] = mutations(g)
+	}
+	start := time.Now()
+	errs := bw.batchWrite(m)
+	bw.recordWrite(start, time.Since(start), groupsError(errs))
+	for i, g := range groups {
+		var err error
+		if i < len(errs) {
+			err = errs[i]
+		} else {
+			err = fmt.Errorf("no result for mutation group %d of %d", i+1, len(groups))
+		}
+		// Spanner doesn't report commit stats for mutation groups.
+		bw.handleWriteResult(g, err, 0)
+	}
+}
+
+// handleWriteResult handles the result of the write of rows, which
+// failed with err if it isn't nil, or else had mutationCount mutations.
+func (bw *BatchWriter) handleWriteResult(rows []*row, err error, mutationCount int64) {
+	if err != nil {
+		if bw.cancelled() {
+			// The write failed since it was cancelled: don't count the rows
+			// as bad, and don't retry.
+			return
+		}
 		hitRetryLimit := atomic.LoadInt64(&bw.async.retries) >= bw.retryLimit
 		retry := len(rows) > 1 && !hitRetryLimit
 		bw.errorStats(rows, err, retry)
@@ -281,9 +488,76 @@ func (bw *BatchWriter) doWriteAndHandleErrors(rows []*row) {
 		}
 		for i := 0; i < len(rows); i += k {
 			atomic.AddInt64(&bw.async.retries, 1)
+			telemetry.AddWriteRetry()
 			bw.doWriteAndHandleErrors(rows[i:min(i+k, len(rows))])
 		}
+		return
+	}
+	bw.stats.recordCommit(int64(len(rows)), mutationCount)
+	telemetry.AddMutationsWritten(mutationCount)
+	written := make(map[string]int64)
+	for _, x := range rows {
+		written[x.table]++
+	}
+	for table, n := range written {
+		telemetry.AddRowsWritten(table, n)
+		if bw.onWritten != nil {
+			bw.onWritten(table, n)
+		}
+	}
+}
+
+// recordWrite records the stats of a write that started at start, took d
+// and failed with err if it isn't nil.
+func (bw *BatchWriter) recordWrite(start time.Time, d time.Duration, err error) {
+	telemetry.ObserveApplyLatency(d)
+	bw.stats.recordWrite(start, d)
+	if bw.limiter != nil {
+		bw.limiter.record(d, err)
+		telemetry.SetWriteConcurrency(bw.limiter.get())
+	}
+}
+
+// getGroups splits rows into mutation groups of at most bw.groupRows
+// consecutive rows of a table.
+func (bw *BatchWriter) getGroups(rows []*row) [][]*row {
+	var groups [][]*row
+	start := 0
+	for i := 1; i <= len(rows); i++ {
+		if i == len(rows) || i-start == bw.groupRows || rows[i].table != rows[start].table {
+			groups = append(groups, rows[start:i])
+			start = i
+		}
+	}
+	return groups
+}
+
+func mutations(rows []*row) []*sp.Mutation {
+	var m []*sp.Mutation
+	for _, x := range rows {
+		m = append(m, sp.Insert(x.table, x.cols, x.vals))
+	}
+	return m
+}
+
+// concurrency returns the current limit on in-progress writes.
+func (bw *BatchWriter) concurrency() int64 {
+	if bw.limiter != nil {
+		return bw.limiter.get()
 	}
+	return bw.writeLimit
+}
+
+// WriteStats returns the statistics of the writes so far.
+func (bw *BatchWriter) WriteStats() WriteStats {
+	s := bw.stats.get()
+	s.Concurrency = bw.concurrency()
+	return s
+}
+
+// cancelled returns whether the context of bw is done.
+func (bw *BatchWriter) cancelled() bool {
+	return bw.ctx != nil && bw.ctx.Err() != nil
 }
 
 // Note: backgroundWrite must be thread-safe because it is run as
@@ -291,13 +565,16 @@ func (bw *BatchWriter) doWriteAndHandleErrors(rows []*row) {
 func (bw *BatchWriter) backgroundWrite(rows []*row) {
 	defer bw.wg.Done()
 	defer atomic.AddInt64(&bw.async.writes, -1)
+	defer telemetry.WriteFinished()
 	bw.doWriteAndHandleErrors(rows)
 }
 
-// startWrite initiates an asynchronous write of rows to Spanner.
-func (bw *BatchWriter) startWrite(rows []*row) {
+// startWrite initiates an asynchronous write of rows to Spanner, whose
+// estimated size is bytes.
+func (bw *BatchWriter) startWrite(rows []*row, bytes int64) {
 	bw.wg.Add(1)
 	atomic.AddInt64(&bw.async.writes, 1)
+	telemetry.WriteStarted(len(rows), bytes)
 	go bw.backgroundWrite(rows)
 }
 
@@ -306,8 +583,12 @@ func (bw *BatchWriter) startWrite(rows []*row) {
 // b) we've hit writeLimit and we're under bytesLimit.
 // It will block and re-try till either (a) or (b) holds.
 func (bw *BatchWriter) writeData() {
-	for bw.rCount > countThreshold || bw.rBytes > byteThreshold {
-		if atomic.LoadInt64(&bw.async.writes) < bw.writeLimit {
+	for bw.rCount > bw.window*countThreshold || bw.rBytes > bw.window*byteThreshold {
+		if bw.cancelled() {
+			bw.rows, bw.rCount, bw.rBytes = nil, 0, 0
+			return
+		}
+		if atomic.LoadInt64(&bw.async.writes) < bw.concurrency() {
 			m, count, bytes := bw.getBatch()
 			if bw.verbose {
 				fmt.Printf("Starting write of %d rows to Spanner (%d bytes, %d mutations) [%d in progress]\n",
@@ -315,7 +596,7 @@ func (bw *BatchWriter) writeData() {
 			}
 			logger.Log.Debug(fmt.Sprintf("Starting write of %d rows to Spanner (%d bytes, %d mutations) [%d in progress]\n",
 				len(m), bytes, count, atomic.LoadInt64(&bw.async.writes)))
-			bw.startWrite(m)
+			bw.startWrite(m, bytes)
 		} else {
 			if bw.rBytes < bw.bytesLimit {
 				return
@@ -325,18 +606,42 @@ func (bw *BatchWriter) writeData() {
 	}
 }
 
+// byteSize returns an estimate of the size of the mutation for r.
 func byteSize(r *row) int64 {
 	n := int64(len(r.table))
 	for _, c := range r.cols {
 		n += int64(len(c))
 	}
 	for _, v := range r.vals {
-		switch x := v.(type) {
-		case string:
-			n += int64(len(x))
-		default:
-			n += int64(unsafe.Sizeof(v))
-		}
+		n += valueSize(v)
 	}
 	return n
 }
+
+// valueSize returns an estimate of the size of v in a mutation.
+func valueSize(v interface{}) int64 {
+	switch x := v.(type) {
+	case string:
+		return int64(len(x))
+	case []byte:
+		return int64(len(x))
+	case sp.NullString:
+		return int64(len(x.StringVal))
+	case sp.NullJSON:
+		return int64(len(x.String()))
+	case *big.Rat:
+		if x != nil {
+			return int64(len(x.Num().Bytes()) + len(x.Denom().Bytes()))
+		}
+	case big.Rat:
+		return int64(len(x.Num().Bytes()) + len(x.Denom().Bytes()))
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+		n := int64(0)
+		for i := 0; i < rv.Len(); i++ {
+			n += valueSize(rv.Index(i).Interface())
+		}
+		return n
+	}
+	return int64(unsafe.Sizeof(v))
+}
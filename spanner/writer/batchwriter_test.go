@@ -15,6 +15,7 @@
 package writer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
@@ -99,6 +100,145 @@ func TestFlush(t *testing.T) {
 	}
 }
 
+func TestOnWritten(t *testing.T) {
+	var mutex sync.Mutex
+	written := make(map[string]int64)
+	bw := NewBatchWriter(BatchWriterConfig{
+		BytesLimit: 100 << 20,
+		WriteLimit: 4,
+		RetryLimit: 1000,
+		Write: func(m []*sp.Mutation) error {
+			if len(m) > 1 {
+				return errors.New("too many rows")
+			}
+			return nil
+		},
+		OnWritten: func(table string, n int64) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			written[table] += n
+		},
+	})
+	bw.AddRow("t1", []string{"a"}, []interface{}{int64(1)})
+	bw.AddRow("t2", []string{"a"}, []interface{}{int64(2)})
+	bw.AddRow("t2", []string{"a"}, []interface{}{int64(3)})
+	bw.Flush()
+	// Rows written by retries of failed writes are counted.
+	assert.Equal(t, map[string]int64{"t1": 1, "t2": 2}, written)
+}
+
+func TestOnDropped(t *testing.T) {
+	var mutex sync.Mutex
+	dropped := make(map[string]int64)
+	errs := make(map[string]int64)
+	bw := NewBatchWriter(BatchWriterConfig{
+		BytesLimit: 100 << 20,
+		WriteLimit: 4,
+		RetryLimit: 1000,
+		Write: func(m []*sp.Mutation) error {
+			if len(m) > 1 {
+				return errors.New("too many rows")
+			}
+			if reflect.DeepEqual(m[0], sp.Insert("t2", []string{"a"}, []interface{}{int64(3)})) {
+				return errors.New("bad row")
+			}
+			return nil
+		},
+		OnDropped: func(table string, n int64, err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			dropped[table] += n
+			errs[err.Error()] += n
+		},
+	})
+	bw.AddRow("t1", []string{"a"}, []interface{}{int64(1)})
+	bw.AddRow("t2", []string{"a"}, []interface{}{int64(2)})
+	bw.AddRow("t2", []string{"a"}, []interface{}{int64(3)})
+	bw.Flush()
+	// Failed writes that are retried don't drop rows.
+	assert.Equal(t, map[string]int64{"t2": 1}, dropped)
+	assert.Equal(t, map[string]int64{"bad row": 1}, errs)
+	assert.Equal(t, map[string]int64{"t2": 1}, bw.DroppedRowsByTable())
+}
+
+func TestOnBadRow(t *testing.T) {
+	type badRow struct {
+		table    string
+		vals     []interface{}
+		source   interface{}
+		attempts int64
+		err      string
+	}
+	var mutex sync.Mutex
+	var bad []badRow
+	bw := NewBatchWriter(BatchWriterConfig{
+		BytesLimit: 100 << 20,
+		WriteLimit: 4,
+		RetryLimit: 1000,
+		Write: func(m []*sp.Mutation) error {
+			for _, x := range m {
+				if reflect.DeepEqual(x, sp.Insert("t", []string{"a"}, []interface{}{int64(3)})) {
+					return errors.New("bad row")
+				}
+			}
+			return nil
+		},
+		OnBadRow: func(table string, cols []string, vals []interface{}, source interface{}, attempts int64, err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			bad = append(bad, badRow{table, vals, source, attempts, err.Error()})
+		},
+	})
+	bw.AddRow("t", []string{"a"}, []interface{}{int64(1)})
+	bw.AddRow("t", []string{"a"}, []interface{}{int64(3)})
+	bw.Flush()
+	// The row failed in the batch of both rows, and then on its own.
+	assert.Equal(t, []badRow{{"t", []interface{}{int64(3)}, nil, 2, "bad row"}}, bad)
+
+	bad = nil
+	bw.RetryRow("t", []string{"a"}, []interface{}{int64(3)}, 5)
+	bw.Flush()
+	assert.Equal(t, []badRow{{"t", []interface{}{int64(3)}, nil, 6, "bad row"}}, bad)
+
+	bad = nil
+	bw.AddSourceRow("t", []string{"a"}, []interface{}{int64(3)}, "3", 0)
+	bw.Flush()
+	assert.Equal(t, []badRow{{"t", []interface{}{int64(3)}, "3", 1, "bad row"}}, bad)
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var writes int64
+	var mutex sync.Mutex
+	bw := NewBatchWriter(BatchWriterConfig{
+		BytesLimit: 100 << 20,
+		WriteLimit: 40,
+		RetryLimit: 1000,
+		Context:    ctx,
+		Write: func(m []*sp.Mutation) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			writes++
+			cancel()
+			return ctx.Err()
+		},
+	})
+	data, _ := generateRows(50000, 5)
+	for _, x := range data {
+		bw.AddRow(x.table, x.cols, x.vals)
+	}
+	bw.Flush()
+	// The first write cancels the context: no more writes are started,
+	// and the failed writes are neither retried nor counted as errors.
+	assert.LessOrEqual(t, writes, int64(40))
+	assert.Empty(t, bw.Errors())
+	assert.Empty(t, bw.DroppedRowsByTable())
+
+	bw.AddRow("test", []string{"col1"}, []interface{}{"a"})
+	bw.Flush()
+	assert.LessOrEqual(t, writes, int64(40))
+}
+
 func TestDroppedRowsByTable(t *testing.T) {
 	bw := NewBatchWriter(BatchWriterConfig{})
 	bw.async.lock.Lock()
@@ -115,8 +255,8 @@ func TestSampleBadRows(t *testing.T) {
 	bw := NewBatchWriter(BatchWriterConfig{})
 	bw.async.lock.Lock()
 	bw.async.sampleBadRows = []*row{
-		&row{"test", []string{"col1", "col2"}, []interface{}{"a", int64(42)}},
-		&row{"test", []string{"col1", "col2"}, []interface{}{"b", int64(6)}},
+		&row{"test", []string{"col1", "col2"}, []interface{}{"a", int64(42)}, 0, nil},
+		&row{"test", []string{"col1", "col2"}, []interface{}{"b", int64(6)}, 0, nil},
 	}
 	bw.async.lock.Unlock()
 	l := bw.SampleBadRows(1)
@@ -170,7 +310,7 @@ func generateRows(count int, size int) ([]*row, int) {
 	for i := 0; i < count; i++ {
 		// vals[0] serves as a unique id for each row.
 		vals := []interface{}{i, val}
-		r = append(r, &row{"table", cols, vals})
+		r = append(r, &row{"table", cols, vals, 0, nil})
 	}
 	// Find the max number of rows in a write for the (fixed sized)
 	// rows generated in this test data.
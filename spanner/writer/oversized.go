@@ -0,0 +1,251 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package writer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"cloud.google.com/go/storage"
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// Spanner's limits on the size of the values of a row.
+// See https://cloud.google.com/spanner/quotas#tables.
+const (
+	MaxCellBytes   = 10 << 20            // Limit on the size of a STRING or BYTES value.
+	maxStringChars = ddl.StringMaxLength // Limit on the number of characters of a STRING value.
+	maxCommitBytes = 100 * 1000 * 1000   // Limit on the size of a commit, and so of a row.
+)
+
+// OversizedValuePolicy specifies what BatchWriter does with STRING and BYTES
+// values that exceed Spanner's limits.
+type OversizedValuePolicy string
+
+const (
+	// OversizedFail drops rows with oversized values, which are reported
+	// as bad rows. This is the default.
+	OversizedFail OversizedValuePolicy = "fail"
+	// OversizedTruncate truncates oversized values to Spanner's limits.
+	OversizedTruncate OversizedValuePolicy = "truncate"
+	// OversizedOffload writes oversized values to a ValueStore, and stores
+	// their URI in the column instead.
+	OversizedOffload OversizedValuePolicy = "offload"
+)
+
+// ParseOversizedValuePolicy returns the policy named s, which is
+// case-insensitive. The empty string is OversizedFail.
+func ParseOversizedValuePolicy(s string) (OversizedValuePolicy, error) {
+	switch p := OversizedValuePolicy(strings.ToLower(s)); p {
+	case "":
+		return OversizedFail, nil
+	case OversizedFail, OversizedTruncate, OversizedOffload:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid oversized value policy %q, must be one of %s, %s or %s", s, OversizedFail, OversizedTruncate, OversizedOffload)
+	}
+}
+
+// ValueStore stores values offloaded from rows.
+type ValueStore interface {
+	// Put stores data under name, a slash-separated path, and returns
+	// the URI of the stored value.
+	Put(name string, data []byte) (string, error)
+}
+
+// NewValueStore returns a ValueStore that stores values under location,
+// which is either a local directory or a GCS path (gs://bucket/path).
+func NewValueStore(ctx context.Context, location string) (ValueStore, error) {
+	if strings.HasPrefix(location, constants.GCS_SCHEME+"://") {
+		u, err := utils.ParseGCSFilePath(location)
+		if err != nil {
+			return nil, err
+		}
+		client, err := storage.NewClient(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GCS client: %v", err)
+		}
+		return &gcsValueStore{ctx: ctx, bucketName: u.Host, bucket: client.Bucket(u.Host), prefix: strings.TrimPrefix(u.Path, "/")}, nil
+	}
+	dir, err := filepath.Abs(location)
+	if err != nil {
+		return nil, err
+	}
+	return localValueStore{dir: dir}, nil
+}
+
+type localValueStore struct {
+	dir string
+}
+
+func (l localValueStore) Put(name string, data []byte) (string, error) {
+	file := filepath.Join(l.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return "", err
+	}
+	return "file://" + filepath.ToSlash(file), nil
+}
+
+type gcsValueStore struct {
+	ctx        context.Context
+	bucketName string
+	bucket     *storage.BucketHandle
+	prefix     string
+}
+
+func (g *gcsValueStore) Put(name string, data []byte) (string, error) {
+	w := g.bucket.Object(g.prefix + name).NewWriter(g.ctx)
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return "", fmt.Errorf("can't write to GCS: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("can't write to GCS: %v", err)
+	}
+	return fmt.Sprintf("%s://%s/%s%s", constants.GCS_SCHEME, g.bucketName, g.prefix, name), nil
+}
+
+// handleOversizedValues applies bw's oversized value policy to the values
+// of r that exceed Spanner's limits on the size of a value, and then to the
+// largest values of the row until it fits in a commit. Values are changed
+// in place. It returns an error if the row must be dropped.
+func (bw *BatchWriter) handleOversizedValues(r *row) error {
+	var oversized []int
+	size := byteSize(r)
+	for i, v := range r.vals {
+		if isOversized(v) {
+			oversized = append(oversized, i)
+		}
+	}
+	if len(oversized) == 0 && size <= maxCommitBytes {
+		return nil
+	}
+	if size > maxCommitBytes {
+		oversized = oversized[:0]
+		for i := range r.vals {
+			oversized = append(oversized, i)
+		}
+		sort.SliceStable(oversized, func(i, j int) bool {
+			return valueSize(r.vals[oversized[i]]) > valueSize(r.vals[oversized[j]])
+		})
+	}
+	for _, i := range oversized {
+		n := valueSize(r.vals[i])
+		if !isOversized(r.vals[i]) && size <= maxCommitBytes {
+			break
+		}
+		if bw.oversized == OversizedTruncate || bw.oversized == OversizedOffload {
+			v, err := bw.shrinkValue(r, i)
+			if err != nil {
+				return err
+			}
+			if v != nil && valueSize(v) < n {
+				r.vals[i] = v
+				size += valueSize(v) - n
+				if bw.onOversizedValue != nil {
+					bw.onOversizedValue(r.table, r.cols[i], bw.oversized)
+				}
+				continue
+			}
+		}
+		return fmt.Errorf("value of column %s has %d bytes, which exceeds Spanner's limit", r.cols[i], n)
+	}
+	return nil
+}
+
+// shrinkValue returns the value that replaces the i-th value of r under
+// bw's policy, or nil if the value can't be replaced, e.g. because it's
+// neither a STRING nor a BYTES value.
+func (bw *BatchWriter) shrinkValue(r *row, i int) (interface{}, error) {
+	var data []byte
+	switch v := r.vals[i].(type) {
+	case string:
+		if bw.oversized == OversizedTruncate {
+			return truncateString(v, MaxCellBytes, maxStringChars), nil
+		}
+		data = []byte(v)
+	case []byte:
+		if bw.oversized == OversizedTruncate {
+			if len(v) > MaxCellBytes {
+				v = v[:MaxCellBytes]
+			}
+			return v, nil
+		}
+		data = v
+	default:
+		return nil, nil
+	}
+	if bw.valueStore == nil {
+		return nil, fmt.Errorf("can't offload value of column %s: no location configured for offloaded values", r.cols[i])
+	}
+	// Values are content-addressed, so retries and re-runs don't create
+	// duplicates.
+	sum := sha256.Sum256(data)
+	uri, err := bw.valueStore.Put(path.Join(r.table, r.cols[i], hex.EncodeToString(sum[:])), data)
+	if err != nil {
+		return nil, fmt.Errorf("can't offload value of column %s: %v", r.cols[i], err)
+	}
+	if _, ok := r.vals[i].([]byte); ok {
+		return []byte(uri), nil
+	}
+	return uri, nil
+}
+
+// isOversized returns whether v is a STRING or BYTES value that exceeds
+// Spanner's limits on the size of a value.
+func isOversized(v interface{}) bool {
+	switch x := v.(type) {
+	case string:
+		return len(x) > MaxCellBytes || (len(x) > maxStringChars && utf8.RuneCountInString(x) > maxStringChars)
+	case []byte:
+		return len(x) > MaxCellBytes
+	}
+	return valueSize(v) > MaxCellBytes
+}
+
+// truncateString truncates s to at most n bytes and c characters, without
+// splitting a UTF-8 encoded character.
+func truncateString(s string, n, c int) string {
+	if len(s) > c {
+		i := 0
+		for j := range s {
+			if i == c {
+				s = s[:j]
+				break
+			}
+			i++
+		}
+	}
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,169 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package writer
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	sp "cloud.google.com/go/spanner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOversizedValuePolicy(t *testing.T) {
+	for s, want := range map[string]OversizedValuePolicy{"": OversizedFail, "fail": OversizedFail, "Truncate": OversizedTruncate, "offload": OversizedOffload} {
+		p, err := ParseOversizedValuePolicy(s)
+		assert.Nil(t, err)
+		assert.Equal(t, want, p)
+	}
+	_, err := ParseOversizedValuePolicy("skip")
+	assert.NotNil(t, err)
+}
+
+func TestOversizedValues(t *testing.T) {
+	big := strings.Repeat("x", MaxCellBytes+1)
+	wide := strings.Repeat("é", maxStringChars+1) // Fewer bytes than MaxCellBytes, but too many characters.
+	tests := []struct {
+		name      string
+		policy    OversizedValuePolicy
+		vals      []interface{}
+		want      []interface{} // Nil if the row is dropped.
+		truncated int
+	}{
+		{name: "small values", policy: OversizedFail, vals: []interface{}{int64(1), "abc"}, want: []interface{}{int64(1), "abc"}},
+		{name: "fail", policy: OversizedFail, vals: []interface{}{int64(1), big}},
+		{name: "default fails", vals: []interface{}{int64(1), []byte(big)}},
+		{name: "truncate string", policy: OversizedTruncate, vals: []interface{}{int64(1), big}, want: []interface{}{int64(1), big[:maxStringChars]}, truncated: 1},
+		{name: "truncate characters", policy: OversizedTruncate, vals: []interface{}{int64(1), wide}, want: []interface{}{int64(1), wide[:2*maxStringChars]}, truncated: 1},
+		{name: "truncate bytes", policy: OversizedTruncate, vals: []interface{}{int64(1), []byte(big)}, want: []interface{}{int64(1), []byte(big[:MaxCellBytes])}, truncated: 1},
+		{name: "truncate arrays", policy: OversizedTruncate, vals: []interface{}{int64(1), []string{big}}},
+	}
+	for _, tc := range tests {
+		var written []*sp.Mutation
+		var truncated []string
+		bw := NewBatchWriter(BatchWriterConfig{
+			WriteLimit:      1,
+			BytesLimit:      100 << 20,
+			OversizedValues: tc.policy,
+			Write: func(m []*sp.Mutation) error {
+				written = append(written, m...)
+				return nil
+			},
+			OnOversizedValue: func(table, col string, policy OversizedValuePolicy) {
+				assert.Equal(t, OversizedTruncate, policy, tc.name)
+				truncated = append(truncated, table+"."+col)
+			},
+		})
+		bw.AddRow("t", []string{"id", "data"}, tc.vals)
+		bw.Flush()
+		if tc.want == nil {
+			assert.Empty(t, written, tc.name)
+			assert.Equal(t, map[string]int64{"t": 1}, bw.DroppedRowsByTable(), tc.name)
+			continue
+		}
+		assert.Equal(t, []*sp.Mutation{sp.Insert("t", []string{"id", "data"}, tc.want)}, written, tc.name)
+		assert.Empty(t, bw.DroppedRowsByTable(), tc.name)
+		assert.Equal(t, tc.truncated, len(truncated), tc.name)
+	}
+}
+
+func TestOffloadValues(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewValueStore(context.Background(), dir)
+	assert.Nil(t, err)
+	var written []*sp.Mutation
+	var offloaded []string
+	bw := NewBatchWriter(BatchWriterConfig{
+		WriteLimit:      1,
+		BytesLimit:      100 << 20,
+		OversizedValues: OversizedOffload,
+		ValueStore:      store,
+		Write: func(m []*sp.Mutation) error {
+			written = append(written, m...)
+			return nil
+		},
+		OnOversizedValue: func(table, col string, policy OversizedValuePolicy) {
+			offloaded = append(offloaded, table+"."+col)
+		},
+	})
+	big := strings.Repeat("x", MaxCellBytes+1)
+	bw.AddRow("t", []string{"id", "s", "b"}, []interface{}{int64(1), big, []byte(big)})
+	bw.Flush()
+	assert.Equal(t, []string{"t.s", "t.b"}, offloaded)
+	assert.Equal(t, 1, len(written))
+
+	// Values are stored in files named after their hash.
+	entries, err := os.ReadDir(dir + "/t/s")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	name := "t/s/" + entries[0].Name()
+	data, err := os.ReadFile(dir + "/" + name)
+	assert.Nil(t, err)
+	assert.True(t, big == string(data))
+	assert.Equal(t, []*sp.Mutation{sp.Insert("t", []string{"id", "s", "b"}, []interface{}{
+		int64(1),
+		"file://" + dir + "/" + name,
+		[]byte("file://" + dir + "/t/b/" + entries[0].Name()),
+	})}, written)
+}
+
+func TestOffloadValuesForLargeRows(t *testing.T) {
+	// Each value fits in a cell, but the row doesn't fit in a commit: the
+	// largest values are offloaded until it does.
+	store, err := NewValueStore(context.Background(), t.TempDir())
+	assert.Nil(t, err)
+	bw := NewBatchWriter(BatchWriterConfig{OversizedValues: OversizedOffload, ValueStore: store})
+	val := make([]byte, MaxCellBytes)
+	var cols []string
+	var vals []interface{}
+	for i := 0; i < 12; i++ {
+		cols = append(cols, string(rune('a'+i)))
+		vals = append(vals, val)
+	}
+	vals[3] = append(val, 'y')
+	r := &row{"t", cols, append([]interface{}{}, vals...), 0, nil}
+	assert.Nil(t, bw.handleOversizedValues(r))
+	assert.LessOrEqual(t, byteSize(r), int64(maxCommitBytes))
+	assert.True(t, strings.HasPrefix(string(r.vals[3].([]byte)), "file://"))
+	assert.True(t, strings.HasPrefix(string(r.vals[0].([]byte)), "file://"))
+	assert.Equal(t, MaxCellBytes, len(r.vals[11].([]byte)))
+
+	// Truncation can't make such rows fit.
+	bw = NewBatchWriter(BatchWriterConfig{OversizedValues: OversizedTruncate})
+	r = &row{"t", cols, append([]interface{}{}, vals...), 0, nil}
+	r.vals[3] = val
+	assert.NotNil(t, bw.handleOversizedValues(r))
+}
+
+func TestValueSize(t *testing.T) {
+	assert.Equal(t, int64(3), valueSize("abc"))
+	assert.Equal(t, int64(1000), valueSize(make([]byte, 1000)))
+	assert.Equal(t, int64(5), valueSize([]string{"ab", "cde"}))
+	assert.Equal(t, int64(7), valueSize([][]byte{make([]byte, 3), make([]byte, 4)}))
+	assert.Equal(t, int64(2), valueSize(sp.NullString{StringVal: "ab", Valid: true}))
+}
+
+func TestGetBatchWithBytes(t *testing.T) {
+	// Batches are split based on the size of BYTES values too.
+	bw := NewBatchWriter(BatchWriterConfig{})
+	for i := 0; i < 10; i++ {
+		bw.rows = append(bw.rows, &row{"t", []string{"b"}, []interface{}{make([]byte, 5<<20)}, 0, nil})
+	}
+	rows, _, bytes := bw.getBatch()
+	assert.Equal(t, 3, len(rows))
+	assert.Less(t, bytes, int64(byteThreshold))
+}
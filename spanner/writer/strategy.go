@@ -0,0 +1,359 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package writer
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/civil"
+	sp "cloud.google.com/go/spanner"
+	"google.golang.org/grpc/codes"
+)
+
+// WriteStrategy is how a BatchWriter writes batches of rows to Spanner.
+type WriteStrategy string
+
+const (
+	// StrategyApply commits each batch atomically. This is the default.
+	StrategyApply WriteStrategy = "apply"
+	// StrategyMutationGroups splits each batch into mutation groups that
+	// are written with the BatchWrite API of Spanner in a single request,
+	// and committed atomically but independently of each other. A bad row
+	// then only fails its group, which suits high-throughput loads that
+	// don't need batches to be atomic.
+	StrategyMutationGroups WriteStrategy = "mutationgroups"
+)
+
+// ParseWriteStrategy returns the strategy named s, which is
+// case-insensitive. The empty string is StrategyApply.
+func ParseWriteStrategy(s string) (WriteStrategy, error) {
+	switch ws := WriteStrategy(strings.ToLower(s)); ws {
+	case "":
+		return StrategyApply, nil
+	case StrategyApply, StrategyMutationGroups:
+		return ws, nil
+	default:
+		return "", fmt.Errorf("invalid write strategy %q, must be one of %s or %s", s, StrategyApply, StrategyMutationGroups)
+	}
+}
+
+// Defaults of the BatchWriterConfig parameters of the write strategies.
+const (
+	DefaultGroupRows     = 100
+	DefaultTargetLatency = time.Second
+)
+
+// keyOrderWindow is how many batches worth of rows are buffered, and
+// sorted by key, before writing with key-ordered batching.
+const keyOrderWindow = 4
+
+// WriteStats are statistics of the writes of a BatchWriter.
+type WriteStats struct {
+	Commits int64 // Successful commits, or mutation groups committed with StrategyMutationGroups.
+	Rows    int64 // Rows written.
+	// Mutations counted by Spanner in the commit stats, if reported.
+	// Spanner counts a mutation for each column of each row written,
+	// including the columns of indexes.
+	Mutations   int64
+	WriteTime   time.Duration // Total time of the writes.
+	Elapsed     time.Duration // From the start of the first write to the end of the last one.
+	Concurrency int64         // Limit on in-progress writes at the end.
+}
+
+// writeStats accumulates WriteStats. It is safe for concurrent use.
+type writeStats struct {
+	mu          sync.Mutex
+	stats       WriteStats
+	first, last time.Time
+}
+
+// recordWrite records a write that started at start and took d.
+func (ws *writeStats) recordWrite(start time.Time, d time.Duration) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.stats.WriteTime += d
+	if ws.first.IsZero() || start.Before(ws.first) {
+		ws.first = start
+	}
+	if end := start.Add(d); end.After(ws.last) {
+		ws.last = end
+	}
+}
+
+// recordCommit records that a commit wrote rows with mutations.
+func (ws *writeStats) recordCommit(rows, mutations int64) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.stats.Commits++
+	ws.stats.Rows += rows
+	ws.stats.Mutations += mutations
+}
+
+func (ws *writeStats) get() WriteStats {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	s := ws.stats
+	s.Elapsed = ws.last.Sub(ws.first)
+	return s
+}
+
+// concurrencyLimiter adapts the number of in-progress writes: additively
+// increasing it after a round of writes that took less than target, and
+// halving it when a write takes longer or fails because Spanner is
+// overloaded, at most once per target. It is safe for concurrent use.
+type concurrencyLimiter struct {
+	mu           sync.Mutex
+	limit, max   int64
+	target       time.Duration
+	successes    int64
+	lastDecrease time.Time
+	now          func() time.Time
+}
+
+func newConcurrencyLimiter(max int64, target time.Duration) *concurrencyLimiter {
+	limit := max / 4
+	if limit < 1 {
+		limit = 1
+	}
+	return &concurrencyLimiter{limit: limit, max: max, target: target, now: time.Now}
+}
+
+// get returns the current limit.
+func (cl *concurrencyLimiter) get() int64 {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.limit
+}
+
+// record adapts the limit to a write that took d and failed with err.
+func (cl *concurrencyLimiter) record(d time.Duration, err error) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if d > cl.target || overloaded(err) {
+		cl.successes = 0
+		if now := cl.now(); now.Sub(cl.lastDecrease) >= cl.target {
+			cl.lastDecrease = now
+			if cl.limit = cl.limit / 2; cl.limit < 1 {
+				cl.limit = 1
+			}
+		}
+		return
+	}
+	if err != nil {
+		return
+	}
+	if cl.successes++; cl.successes >= cl.limit && cl.limit < cl.max {
+		cl.successes = 0
+		cl.limit++
+	}
+}
+
+// groupsError returns the error of the write of mutation groups that
+// failed with errs: the first error showing that Spanner is overloaded,
+// or else the first error.
+func groupsError(errs []error) error {
+	var first error
+	for _, err := range errs {
+		if overloaded(err) {
+			return err
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// overloaded returns whether err shows that Spanner is overloaded or
+// that transactions contend.
+func overloaded(err error) bool {
+	switch sp.ErrCode(err) {
+	case codes.Aborted, codes.ResourceExhausted, codes.DeadlineExceeded, codes.Unavailable:
+		return true
+	}
+	return false
+}
+
+// keyOrder sorts rows by table, and then by the values of the primary key
+// columns of the table.
+type keyOrder struct {
+	keyColumns func(table string) []string
+	indexes    map[string][]int // Cached indexes of the key columns of a table in row.cols.
+	cols       map[string][]string
+}
+
+func newKeyOrder(keyColumns func(table string) []string) *keyOrder {
+	return &keyOrder{keyColumns: keyColumns, indexes: make(map[string][]int), cols: make(map[string][]string)}
+}
+
+// keyIndexes returns the indexes of the key columns of r in r.cols.
+func (ko *keyOrder) keyIndexes(r *row) []int {
+	if idx, ok := ko.indexes[r.table]; ok && sameStrings(ko.cols[r.table], r.cols) {
+		return idx
+	}
+	var idx []int
+	for _, k := range ko.keyColumns(r.table) {
+		for i, c := range r.cols {
+			if c == k {
+				idx = append(idx, i)
+				break
+			}
+		}
+	}
+	ko.indexes[r.table], ko.cols[r.table] = idx, r.cols
+	return idx
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sort sorts rows, keeping the order of rows with equal keys.
+func (ko *keyOrder) sort(rows []*row) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.table != b.table {
+			return a.table < b.table
+		}
+		ia, ib := ko.keyIndexes(a), ko.keyIndexes(b)
+		for k := 0; k < len(ia) && k < len(ib); k++ {
+			if c := compareValues(a.vals[ia[k]], b.vals[ib[k]]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+// compareValues compares two values of a key column in Spanner's key
+// order, where NULL sorts first. Values of unknown types are compared by
+// their string representation.
+func compareValues(a, b interface{}) int {
+	a, b = nullValue(a), nullValue(b)
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	switch x := a.(type) {
+	case int64:
+		if y, ok := b.(int64); ok {
+			return compareOrdered(x < y, x > y)
+		}
+	case float64:
+		if y, ok := b.(float64); ok {
+			return compareOrdered(x < y, x > y)
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y)
+		}
+	case []byte:
+		if y, ok := b.([]byte); ok {
+			return bytes.Compare(x, y)
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			return compareOrdered(!x && y, x && !y)
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return compareOrdered(x.Before(y), x.After(y))
+		}
+	case civil.Date:
+		if y, ok := b.(civil.Date); ok {
+			return compareOrdered(x.Before(y), y.Before(x))
+		}
+	case big.Rat:
+		if y, ok := b.(big.Rat); ok {
+			return x.Cmp(&y)
+		}
+	case *big.Rat:
+		if y, ok := b.(*big.Rat); ok {
+			return x.Cmp(y)
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func compareOrdered(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+// nullValue returns the value of v if it is a Spanner null type, and nil
+// if it is NULL.
+func nullValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case sp.NullInt64:
+		if x.Valid {
+			return x.Int64
+		}
+		return nil
+	case sp.NullFloat64:
+		if x.Valid {
+			return x.Float64
+		}
+		return nil
+	case sp.NullString:
+		if x.Valid {
+			return x.StringVal
+		}
+		return nil
+	case sp.NullBool:
+		if x.Valid {
+			return x.Bool
+		}
+		return nil
+	case sp.NullTime:
+		if x.Valid {
+			return x.Time
+		}
+		return nil
+	case sp.NullDate:
+		if x.Valid {
+			return x.Date
+		}
+		return nil
+	case sp.NullNumeric:
+		if x.Valid {
+			return x.Numeric
+		}
+		return nil
+	}
+	return v
+}
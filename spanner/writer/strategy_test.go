@@ -0,0 +1,249 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package writer
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	sp "cloud.google.com/go/spanner"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseWriteStrategy(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    WriteStrategy
+		wantErr bool
+	}{
+		{"", StrategyApply, false},
+		{"apply", StrategyApply, false},
+		{"mutationGroups", StrategyMutationGroups, false},
+		{"batchwrite", "", true},
+	}
+	for _, tc := range tests {
+		got, err := ParseWriteStrategy(tc.s)
+		assert.Equal(t, tc.wantErr, err != nil, tc.s)
+		assert.Equal(t, tc.want, got, tc.s)
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b interface{}
+		want int
+	}{
+		{"int64", int64(1), int64(2), -1},
+		{"float64", float64(2), float64(1), 1},
+		{"string", "a", "a", 0},
+		{"bytes", []byte{1}, []byte{1, 0}, -1},
+		{"bool", true, false, 1},
+		{"time", time.Unix(1, 0), time.Unix(2, 0), -1},
+		{"date", civil.Date{Year: 2022, Month: 2, Day: 1}, civil.Date{Year: 2022, Month: 1, Day: 1}, 1},
+		{"numeric", big.NewRat(1, 3), big.NewRat(1, 2), -1},
+		{"null first", sp.NullInt64{}, int64(-5), -1},
+		{"null values", sp.NullInt64{Int64: 3, Valid: true}, int64(2), 1},
+		{"nulls", nil, sp.NullString{}, 0},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, compareValues(tc.a, tc.b), tc.name)
+	}
+}
+
+func TestKeyOrderedBatches(t *testing.T) {
+	var mutex sync.Mutex
+	var batches [][]*sp.Mutation
+	bw := NewBatchWriter(BatchWriterConfig{
+		BytesLimit: 100 << 20,
+		WriteLimit: 1,
+		RetryLimit: 1000,
+		Write: func(m []*sp.Mutation) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			batches = append(batches, m)
+			return nil
+		},
+		KeyColumns: func(table string) []string { return []string{"k"} },
+	})
+	bw.AddRow("t2", []string{"v", "k"}, []interface{}{"c", int64(3)})
+	bw.AddRow("t1", []string{"k", "v"}, []interface{}{int64(2), "b"})
+	bw.AddRow("t2", []string{"v", "k"}, []interface{}{"a", int64(1)})
+	bw.AddRow("t1", []string{"k", "v"}, []interface{}{int64(1), "a"})
+	bw.Flush()
+	assert.Equal(t, [][]*sp.Mutation{
+		{
+			sp.Insert("t1", []string{"k", "v"}, []interface{}{int64(1), "a"}),
+			sp.Insert("t1", []string{"k", "v"}, []interface{}{int64(2), "b"}),
+		},
+		{
+			sp.Insert("t2", []string{"v", "k"}, []interface{}{"a", int64(1)}),
+			sp.Insert("t2", []string{"v", "k"}, []interface{}{"c", int64(3)}),
+		},
+	}, batches)
+}
+
+func TestMutationGroups(t *testing.T) {
+	var mutex sync.Mutex
+	var groups []int
+	var requests, inProgress, maxInProgress int
+	bw := NewBatchWriter(BatchWriterConfig{
+		BytesLimit:    100 << 20,
+		WriteLimit:    4,
+		RetryLimit:    1000,
+		WriteStrategy: StrategyMutationGroups,
+		GroupRows:     2,
+		Commit: func(m []*sp.Mutation) (int64, error) {
+			t.Error("batches of mutation groups must be written with BatchWrite")
+			return 0, nil
+		},
+		BatchWrite: func(mgs [][]*sp.Mutation) []error {
+			mutex.Lock()
+			requests++
+			if inProgress++; inProgress > maxInProgress {
+				maxInProgress = inProgress
+			}
+			mutex.Unlock()
+			time.Sleep(time.Millisecond)
+			mutex.Lock()
+			defer mutex.Unlock()
+			inProgress--
+			errs := make([]error, len(mgs))
+			for i, m := range mgs {
+				if containsRow(m, sp.Insert("t", []string{"a"}, []interface{}{int64(3)})) {
+					errs[i] = errors.New("bad row")
+					continue
+				}
+				groups = append(groups, len(m))
+			}
+			return errs
+		},
+	})
+	for i := 1; i <= 5; i++ {
+		bw.AddRow("t", []string{"a"}, []interface{}{int64(i)})
+	}
+	bw.Flush()
+	sort.Ints(groups)
+	// Rows 1 and 2 are written as a group, and row 5 as a group of its own.
+	// Row 3 fails the group of rows 3 and 4, which is retried row by row.
+	assert.Equal(t, []int{1, 1, 2}, groups)
+	assert.Equal(t, map[string]int64{"t": 1}, bw.DroppedRowsByTable())
+	// The batch is written in one request, and each row of the failed
+	// group in a request of its own.
+	assert.Equal(t, 3, requests)
+	assert.LessOrEqual(t, maxInProgress, 4)
+	stats := bw.WriteStats()
+	assert.Equal(t, int64(3), stats.Commits)
+	assert.Equal(t, int64(4), stats.Rows)
+	assert.Equal(t, int64(0), stats.Mutations)
+	assert.Equal(t, int64(4), stats.Concurrency)
+}
+
+func TestMutationGroupsWriteLimit(t *testing.T) {
+	var mutex sync.Mutex
+	var inProgress, maxInProgress int
+	bw := NewBatchWriter(BatchWriterConfig{
+		BytesLimit:    100 << 20,
+		WriteLimit:    2,
+		RetryLimit:    1000,
+		WriteStrategy: StrategyMutationGroups,
+		GroupRows:     1,
+		BatchWrite: func(mgs [][]*sp.Mutation) []error {
+			mutex.Lock()
+			if inProgress++; inProgress > maxInProgress {
+				maxInProgress = inProgress
+			}
+			mutex.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mutex.Lock()
+			inProgress--
+			mutex.Unlock()
+			return make([]error, len(mgs))
+		},
+	})
+	// Enough rows for several batches, each of which has a mutation group
+	// per row.
+	for i := 0; i < 4*countThreshold; i++ {
+		bw.AddRow("t", []string{"a"}, []interface{}{int64(i)})
+	}
+	bw.Flush()
+	assert.Equal(t, int64(4*countThreshold), bw.WriteStats().Rows)
+	assert.LessOrEqual(t, maxInProgress, 2)
+}
+
+func containsRow(m []*sp.Mutation, want *sp.Mutation) bool {
+	for _, x := range m {
+		if assert.ObjectsAreEqual(want, x) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	cl := newConcurrencyLimiter(8, time.Second)
+	cl.now = func() time.Time { return now }
+	assert.Equal(t, int64(2), cl.get())
+	// A round of fast writes increases the limit by one.
+	cl.record(100*time.Millisecond, nil)
+	cl.record(100*time.Millisecond, nil)
+	assert.Equal(t, int64(3), cl.get())
+	// Failed writes that aren't caused by overload don't change it.
+	cl.record(100*time.Millisecond, errors.New("bad row"))
+	assert.Equal(t, int64(3), cl.get())
+	// A slow write halves it, but only once per target latency.
+	now = now.Add(time.Minute)
+	cl.record(2*time.Second, nil)
+	assert.Equal(t, int64(1), cl.get())
+	cl.record(2*time.Second, nil)
+	assert.Equal(t, int64(1), cl.get())
+	for i := 0; i < 20; i++ {
+		cl.record(100*time.Millisecond, nil)
+	}
+	assert.Equal(t, int64(6), cl.get())
+	// Aborted transactions halve it too.
+	now = now.Add(time.Minute)
+	cl.record(100*time.Millisecond, status.Error(codes.Aborted, "aborted"))
+	assert.Equal(t, int64(3), cl.get())
+	// It doesn't exceed the maximum.
+	for i := 0; i < 100; i++ {
+		cl.record(100*time.Millisecond, nil)
+	}
+	assert.Equal(t, int64(8), cl.get())
+}
+
+func TestWriteStats(t *testing.T) {
+	var ws writeStats
+	start := time.Unix(100, 0)
+	ws.recordWrite(start, time.Second)
+	ws.recordWrite(start.Add(500*time.Millisecond), 2*time.Second)
+	ws.recordCommit(10, 30)
+	ws.recordCommit(5, 15)
+	assert.Equal(t, WriteStats{
+		Commits:   2,
+		Rows:      15,
+		Mutations: 45,
+		WriteTime: 3 * time.Second,
+		Elapsed:   2500 * time.Millisecond,
+	}, ws.get())
+}
@@ -0,0 +1,80 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package writer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	sp "cloud.google.com/go/spanner"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// encodedValue is a value in the wire format of Spanner.
+type encodedValue struct {
+	Type  json.RawMessage `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// EncodeValues encodes vals, the values of the columns cols of a row, as
+// JSON objects with the type and value of the wire format of Spanner, from
+// which DecodeValues restores them without loss.
+func EncodeValues(cols []string, vals []interface{}) ([]json.RawMessage, error) {
+	r, err := sp.NewRow(cols, vals)
+	if err != nil {
+		return nil, err
+	}
+	encoded := make([]json.RawMessage, len(vals))
+	for i := range vals {
+		var g sp.GenericColumnValue
+		if err := r.Column(i, &g); err != nil {
+			return nil, err
+		}
+		var ev encodedValue
+		if ev.Type, err = protojson.Marshal(g.Type); err != nil {
+			return nil, err
+		}
+		if ev.Value, err = protojson.Marshal(g.Value); err != nil {
+			return nil, err
+		}
+		if encoded[i], err = json.Marshal(ev); err != nil {
+			return nil, err
+		}
+	}
+	return encoded, nil
+}
+
+// DecodeValues decodes values encoded by EncodeValues, as values that can
+// be written to Spanner.
+func DecodeValues(encoded []json.RawMessage) ([]interface{}, error) {
+	vals := make([]interface{}, len(encoded))
+	for i, e := range encoded {
+		var ev encodedValue
+		if err := json.Unmarshal(e, &ev); err != nil {
+			return nil, fmt.Errorf("can't decode value %d: %v", i, err)
+		}
+		g := sp.GenericColumnValue{Type: &sppb.Type{}, Value: &structpb.Value{}}
+		if err := protojson.Unmarshal(ev.Type, g.Type); err != nil {
+			return nil, fmt.Errorf("can't decode type of value %d: %v", i, err)
+		}
+		if err := protojson.Unmarshal(ev.Value, g.Value); err != nil {
+			return nil, fmt.Errorf("can't decode value %d: %v", i, err)
+		}
+		vals[i] = g
+	}
+	return vals, nil
+}
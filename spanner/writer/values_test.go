@@ -0,0 +1,74 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package writer
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	sp "cloud.google.com/go/spanner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeValues(t *testing.T) {
+	cols := []string{"i", "f", "s", "b", "t", "d", "n", "a", "null"}
+	vals := []interface{}{
+		int64(1 << 60),
+		1.5,
+		"héllo",
+		[]byte{0, 1, 2},
+		time.Date(2022, 10, 1, 12, 30, 0, 123456789, time.UTC),
+		civil.Date{Year: 2022, Month: 10, Day: 1},
+		big.NewRat(1, 4),
+		[]sp.NullInt64{{Int64: 1, Valid: true}, {}},
+		sp.NullString{},
+	}
+	encoded, err := EncodeValues(cols, vals)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"type":{"code":"INT64"},"value":"1152921504606846976"}`, string(encoded[0]))
+	decoded, err := DecodeValues(encoded)
+	assert.Nil(t, err)
+	// Decoding the values restores them without loss.
+	var (
+		i  int64
+		f  float64
+		s  string
+		b  []byte
+		ts time.Time
+		d  civil.Date
+		n  big.Rat
+		a  []sp.NullInt64
+		ns sp.NullString
+	)
+	for k, ptr := range []interface{}{&i, &f, &s, &b, &ts, &d, &n, &a, &ns} {
+		g := decoded[k].(sp.GenericColumnValue)
+		assert.Nil(t, g.Decode(ptr), cols[k])
+	}
+	assert.Equal(t, vals[0], i)
+	assert.Equal(t, vals[1], f)
+	assert.Equal(t, vals[2], s)
+	assert.Equal(t, vals[3], b)
+	assert.True(t, vals[4].(time.Time).Equal(ts))
+	assert.Equal(t, vals[5], d)
+	assert.Equal(t, 0, n.Cmp(big.NewRat(1, 4)))
+	assert.Equal(t, vals[7], a)
+	assert.Equal(t, vals[8], ns)
+
+	_, err = DecodeValues([]json.RawMessage{json.RawMessage(`{"type":{"code":"NOPE"},"value":"1"}`)})
+	assert.NotNil(t, err)
+}
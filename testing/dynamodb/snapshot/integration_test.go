@@ -248,11 +248,11 @@ func checkRow(ctx context.Context, t *testing.T, client *spanner.Client) {
 		AttrNumberSet: []float64{1.5, 2.5, 3.5},
 		AttrByteSet:   [][]byte{[]byte{48, 49}, []byte{50, 51}},
 		AttrStringSet: []string{"abc", "xyz"},
-		AttrList:      "[\"str-1\",\"12.34\",true]",
-		AttrMap:       "{\"key\":\"100\"}",
+		AttrList:      "[\"str-1\",12.34,true]",
+		AttrMap:       "{\"key\":100}",
 	}
 	gotRecord := SpannerRecord{}
-	stmt := spanner.Statement{SQL: `SELECT AttrString, AttrInt, AttrFloat, AttrBool, AttrBytes, AttrNumberSet, AttrByteSet, AttrStringSet, AttrList, AttrMap FROM table_test`}
+	stmt := spanner.Statement{SQL: `SELECT AttrString, AttrInt, AttrFloat, AttrBool, AttrBytes, AttrNumberSet, AttrByteSet, AttrStringSet, TO_JSON_STRING(AttrList), TO_JSON_STRING(AttrMap) FROM table_test`}
 	iter := client.Single().Query(ctx, stmt)
 	defer iter.Stop()
 	for {
@@ -356,8 +356,8 @@ func checkResults(t *testing.T, client *spanner.Client) {
 			AttrNumberSet: []float64{1.5, 2.5, 3.5},
 			AttrByteSet:   [][]byte{[]byte{48, 49}, []byte{50, 51}},
 			AttrStringSet: []string{"abc", "xyz"},
-			AttrList:      "[\"str-1\",\"12.34\",true]",
-			AttrMap:       "{\"key\":\"100\"}",
+			AttrList:      "[\"str-1\",12.34,true]",
+			AttrMap:       "{\"key\":100}",
 		},
 		{
 			AttrString:    "efgh",
@@ -368,13 +368,13 @@ func checkResults(t *testing.T, client *spanner.Client) {
 			AttrNumberSet: []float64{1.5, 2.5, 3.5},
 			AttrByteSet:   [][]byte{[]byte{48, 49}, []byte{50, 51}},
 			AttrStringSet: []string{"abc", "xyz"},
-			AttrList:      "[\"str-1\",\"12.34\",true]",
-			AttrMap:       "{\"key\":\"102\"}",
+			AttrList:      "[\"str-1\",12.34,true]",
+			AttrMap:       "{\"key\":102}",
 		},
 	}
 
 	gotRecords := []SpannerRecord{}
-	stmt := spanner.Statement{SQL: `SELECT AttrString, AttrInt, AttrFloat, AttrBool, AttrBytes, AttrNumberSet, AttrByteSet, AttrStringSet, AttrList, AttrMap FROM testtable`}
+	stmt := spanner.Statement{SQL: `SELECT AttrString, AttrInt, AttrFloat, AttrBool, AttrBytes, AttrNumberSet, AttrByteSet, AttrStringSet, TO_JSON_STRING(AttrList), TO_JSON_STRING(AttrMap) FROM testtable`}
 	iter := client.ReadOnlyTransaction().Query(ctx, stmt)
 	defer iter.Stop()
 	for {
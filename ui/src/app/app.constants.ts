@@ -9,6 +9,7 @@ export enum StorageKeys {
   Type = 'inputType',
   Config = 'config',
   SourceDbName = 'sourceDbName',
+  AuthToken = 'authToken',
 }
 
 export enum SourceDbNames {
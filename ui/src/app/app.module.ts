@@ -27,6 +27,7 @@ import { LoaderComponent } from './components/loader/loader.component'
 import { InfodialogComponent } from './components/infodialog/infodialog.component'
 import { RuleComponent } from './components/rule/rule.component'
 import { InterceptorService } from './services/interceptor/interceptor.service'
+import { AuthInterceptorService } from './services/auth-interceptor/auth-interceptor.service'
 import { UpdateSpannerConfigFormComponent } from './components/update-spanner-config-form/update-spanner-config-form.component'
 import { SidenavRuleComponent } from './components/sidenav-rule/sidenav-rule.component'
 import { AddIndexFormComponent } from './components/add-index-form/add-index-form.component'
@@ -97,6 +98,11 @@ import { DataflowFormComponent } from './components/dataflow-form/dataflow-form.
       useClass: InterceptorService,
       multi: true,
     },
+    {
+      provide: HTTP_INTERCEPTORS,
+      useClass: AuthInterceptorService,
+      multi: true,
+    },
   ],
   bootstrap: [AppComponent],
 })
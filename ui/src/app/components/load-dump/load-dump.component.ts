@@ -27,6 +27,7 @@ export class LoadDumpComponent implements OnInit {
   dbEngineList = [
     { value: 'mysqldump', displayName: 'MySQL' },
     { value: 'pg_dump', displayName: 'PostgreSQL' },
+    { value: 'sqlserverdump', displayName: 'SQL Server' },
   ]
   dialect = DialectList
   fileToUpload: File | null = null
@@ -22,6 +22,7 @@ import { ConversionService } from 'src/app/services/conversion/conversion.servic
 import { DropIndexOrTableDialogComponent } from '../drop-index-or-table-dialog/drop-index-or-table-dialog.component'
 import { SidenavService } from 'src/app/services/sidenav/sidenav.service'
 import { TableUpdatePubSubService } from 'src/app/services/table-update-pub-sub/table-update-pub-sub.service'
+import ITypeSuggestion from 'src/app/model/type-suggestion'
 
 @Component({
   selector: 'app-object-detail',
@@ -41,6 +42,7 @@ export class ObjectDetailComponent implements OnInit {
 
   @Input() currentObject: FlatNode | null = null
   @Input() typeMap: any = {}
+  @Input() typeSuggestions: Record<string, Record<string, ITypeSuggestion>> = {}
   @Input() ddlStmts: any = {}
   @Input() fkData: IFkTabData[] = []
   @Input() tableData: IColumnTabData[] = []
@@ -222,6 +224,7 @@ export class ObjectDetailComponent implements OnInit {
             srcOrder: new FormControl(col.srcOrder),
             srcColName: new FormControl(col.srcColName),
             srcDataType: new FormControl(col.srcDataType),
+            srcAllowedValues: new FormControl(col.srcAllowedValues),
             srcIsPk: new FormControl(col.srcIsPk),
             srcIsNotNull: new FormControl(col.srcIsNotNull),
             spOrder: new FormControl(col.spOrder),
@@ -239,6 +242,7 @@ export class ObjectDetailComponent implements OnInit {
             srcOrder: new FormControl(col.srcOrder),
             srcColName: new FormControl(col.srcColName),
             srcDataType: new FormControl(col.srcDataType),
+            srcAllowedValues: new FormControl(col.srcAllowedValues),
             srcIsPk: new FormControl(col.srcIsPk),
             srcIsNotNull: new FormControl(col.srcIsNotNull),
             spOrder: new FormControl(col.srcOrder),
@@ -448,6 +452,15 @@ export class ObjectDetailComponent implements OnInit {
       this.spTableSuggestion.push(brief)
     })
   }
+  getTypeSuggestions() {
+    this.data.getTypeSuggestions()
+  }
+
+  typeSuggestion(spColId: string): ITypeSuggestion | undefined {
+    if (!this.currentObject) return undefined
+    return this.typeSuggestions[this.currentObject.id]?.[spColId]
+  }
+
   spTableEditSuggestionHandler(index: number, spDataType: string) {
     const srDataType = this.localTableData[index].srcDataType
     let brief: string = ''
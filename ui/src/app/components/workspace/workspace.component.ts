@@ -16,6 +16,7 @@ import { extractSourceDbName } from 'src/app/utils/utils'
 import { ClickEventService } from 'src/app/services/click-event/click-event.service'
 import IViewAssesmentData from 'src/app/model/view-assesment'
 import IDbConfig from 'src/app/model/db-config'
+import ITypeSuggestion from 'src/app/model/type-suggestion'
 
 @Component({
   selector: 'app-workspace',
@@ -29,8 +30,10 @@ export class WorkspaceComponent implements OnInit, OnDestroy {
   tableData: IColumnTabData[] = []
   indexData: IIndexData[] = []
   typeMap: Record<string, Record<string, string>> | boolean = false
+  typeSuggestions: Record<string, Record<string, ITypeSuggestion>> = {}
   conversionRates: Record<string, string> = {}
   typemapObj!: Subscription
+  typeSuggestionsObj!: Subscription
   convObj!: Subscription
   converObj!: Subscription
   ddlsumconvObj!: Subscription
@@ -69,6 +72,10 @@ export class WorkspaceComponent implements OnInit, OnDestroy {
       this.typeMap = types
     })
 
+    this.typeSuggestionsObj = this.data.typeSuggestions.subscribe((suggestions) => {
+      this.typeSuggestions = suggestions
+    })
+
     this.ddlObj = this.data.ddl.subscribe((res) => {
       this.ddlStmts = res
     })
@@ -147,6 +154,7 @@ export class WorkspaceComponent implements OnInit, OnDestroy {
 
   ngOnDestroy(): void {
     this.typemapObj.unsubscribe()
+    this.typeSuggestionsObj.unsubscribe()
     this.convObj.unsubscribe()
     this.ddlObj.unsubscribe()
     this.ddlsumconvObj.unsubscribe()
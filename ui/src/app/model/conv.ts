@@ -71,6 +71,7 @@ export interface ISpannerType {
   Name: string
   Mods: number[]
   ArrayBounds: number[]
+  EnumValues?: string[]
 }
 
 export interface IIndex {
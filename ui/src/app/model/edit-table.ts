@@ -3,6 +3,7 @@ export default interface IColumnTabData {
   srcOrder: number | string
   srcColName: string
   srcDataType: string
+  srcAllowedValues?: string
   spColName: string
   spDataType: string | String
   spIsPk: boolean
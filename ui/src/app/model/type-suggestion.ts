@@ -0,0 +1,6 @@
+export default interface ITypeSuggestion {
+  T: string
+  Len: number
+  DisplayT: string
+  Reason: string
+}
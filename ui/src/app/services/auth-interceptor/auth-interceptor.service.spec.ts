@@ -0,0 +1,54 @@
+import { HttpClient, HTTP_INTERCEPTORS } from '@angular/common/http'
+import { HttpClientTestingModule, HttpTestingController } from '@angular/common/http/testing'
+import { TestBed } from '@angular/core/testing'
+import { StorageKeys } from 'src/app/app.constants'
+
+import { AuthInterceptorService } from './auth-interceptor.service'
+
+describe('AuthInterceptorService', () => {
+  let http: HttpClient
+  let httpMock: HttpTestingController
+
+  beforeEach(() => {
+    sessionStorage.removeItem(StorageKeys.AuthToken)
+    TestBed.configureTestingModule({
+      imports: [HttpClientTestingModule],
+      providers: [{ provide: HTTP_INTERCEPTORS, useClass: AuthInterceptorService, multi: true }],
+    })
+    http = TestBed.inject(HttpClient)
+    httpMock = TestBed.inject(HttpTestingController)
+  })
+
+  afterEach(() => {
+    httpMock.verify()
+    sessionStorage.removeItem(StorageKeys.AuthToken)
+  })
+
+  it('should send the stored token', () => {
+    sessionStorage.setItem(StorageKeys.AuthToken, 'secret')
+    http.get('/ddl').subscribe()
+    const req = httpMock.expectOne('/ddl')
+    expect(req.request.headers.get('Authorization')).toEqual('Bearer secret')
+    req.flush({})
+  })
+
+  it('should ask for a token on a bearer challenge and retry', () => {
+    spyOn(window, 'prompt').and.returnValue('secret')
+    http.get('/ddl').subscribe()
+    httpMock.expectOne('/ddl').flush('Authentication required', {
+      status: 401,
+      statusText: 'Unauthorized',
+      headers: { 'WWW-Authenticate': 'Bearer realm="HarbourBridge"' },
+    })
+    const req = httpMock.expectOne('/ddl')
+    expect(req.request.headers.get('Authorization')).toEqual('Bearer secret')
+    req.flush({})
+  })
+
+  it('should not ask for a token on other errors', () => {
+    spyOn(window, 'prompt')
+    http.get('/ddl').subscribe({ error: () => {} })
+    httpMock.expectOne('/ddl').flush('Forbidden', { status: 403, statusText: 'Forbidden' })
+    expect(window.prompt).not.toHaveBeenCalled()
+  })
+})
@@ -0,0 +1,64 @@
+import {
+  HttpErrorResponse,
+  HttpEvent,
+  HttpHandler,
+  HttpInterceptor,
+  HttpRequest,
+} from '@angular/common/http'
+import { Injectable } from '@angular/core'
+import { catchError, Observable, throwError } from 'rxjs'
+import { StorageKeys } from 'src/app/app.constants'
+
+// AuthInterceptorService sends the bearer token of the user with each API
+// request, for web servers started with -auth=token or -auth=oidc. When a
+// request is rejected with a bearer challenge, the user is asked for a token
+// (the editor or viewer token, or an OIDC ID token), and the request is sent
+// again with it. The token is kept in the session storage of the tab.
+// Servers started with -auth=basic use the login dialog of the browser.
+@Injectable({
+  providedIn: 'root',
+})
+export class AuthInterceptorService implements HttpInterceptor {
+  constructor() {}
+
+  intercept(req: HttpRequest<any>, next: HttpHandler): Observable<HttpEvent<any>> {
+    return next.handle(this.withToken(req)).pipe(
+      catchError((err) => {
+        if (!this.isBearerChallenge(err)) {
+          return throwError(() => err)
+        }
+        const token = this.askToken()
+        if (!token) {
+          return throwError(() => err)
+        }
+        return next.handle(this.withToken(req))
+      })
+    )
+  }
+
+  withToken(req: HttpRequest<any>): HttpRequest<any> {
+    const token = sessionStorage.getItem(StorageKeys.AuthToken)
+    if (!token) {
+      return req
+    }
+    return req.clone({ setHeaders: { Authorization: `Bearer ${token}` } })
+  }
+
+  isBearerChallenge(err: any): boolean {
+    if (!(err instanceof HttpErrorResponse) || err.status != 401) {
+      return false
+    }
+    const challenge = err.headers.get('WWW-Authenticate') || ''
+    return challenge.toLowerCase().startsWith('bearer')
+  }
+
+  askToken(): string | null {
+    const token = window.prompt('HarbourBridge requires authentication. Enter your access token:')
+    if (token) {
+      sessionStorage.setItem(StorageKeys.AuthToken, token.trim())
+    } else {
+      sessionStorage.removeItem(StorageKeys.AuthToken)
+    }
+    return token
+  }
+}
@@ -266,6 +266,7 @@ export class ConversionService {
         spDataType: spannerColDef ? (data.SpDialect === Dialect.PostgreSQLDialect ? (pgSQLDatatype === undefined ? spannerColDef.T.Name : pgSQLDatatype) : spannerColDef.T.Name) : '',
         srcColName: data.SrcSchema[tableId].ColDefs[colId].Name,
         srcDataType: data.SrcSchema[tableId].ColDefs[colId].Type.Name,
+        srcAllowedValues: data.SrcSchema[tableId].ColDefs[colId].Type.EnumValues?.join(', '),
         spIsPk:
           spannerColDef && spTableName
             ? data.SpSchema[tableId].PrimaryKeys?.map((pk) => pk.ColId).indexOf(colId) !== -1
@@ -11,6 +11,7 @@ import ISession from 'src/app/model/session'
 import ISpannerConfig from '../../model/spanner-config'
 import { SnackbarService } from '../snackbar/snackbar.service'
 import ISummary from 'src/app/model/summary'
+import ITypeSuggestion from 'src/app/model/type-suggestion'
 import { ClickEventService } from '../click-event/click-event.service'
 import { TableUpdatePubSubService } from '../table-update-pub-sub/table-update-pub-sub.service'
 import { ConversionService } from '../conversion/conversion.service'
@@ -23,6 +24,9 @@ export class DataService {
   private convSubject = new BehaviorSubject<IConv>({} as IConv)
   private conversionRateSub = new BehaviorSubject({})
   private typeMapSub = new BehaviorSubject({})
+  private typeSuggestionsSub = new BehaviorSubject<
+    Record<string, Record<string, ITypeSuggestion>>
+  >({})
   private summarySub = new BehaviorSubject(new Map<string, ISummary>())
   private ddlSub = new BehaviorSubject({})
   private tableInterleaveStatusSub = new BehaviorSubject({} as IInterleaveStatus)
@@ -39,6 +43,7 @@ export class DataService {
     .asObservable()
     .pipe(filter((res) => Object.keys(res).length !== 0))
   typeMap = this.typeMapSub.asObservable().pipe(filter((res) => Object.keys(res).length !== 0))
+  typeSuggestions = this.typeSuggestionsSub.asObservable()
   summary = this.summarySub.asObservable()
   ddl = this.ddlSub.asObservable().pipe(filter((res) => Object.keys(res).length !== 0))
   tableInterleaveStatus = this.tableInterleaveStatusSub.asObservable()
@@ -64,6 +69,7 @@ export class DataService {
     this.convSubject.next({} as IConv)
     this.conversionRateSub.next({})
     this.typeMapSub.next({})
+    this.typeSuggestionsSub.next({})
     this.summarySub.next(new Map<string, ISummary>())
     this.ddlSub.next({})
     this.tableInterleaveStatusSub.next({} as IInterleaveStatus)
@@ -175,6 +181,18 @@ export class DataService {
         this.ddlSub.next(ddl)
       })
   }
+  getTypeSuggestions(sampleRows?: number) {
+    this.fetch.getTypeSuggestions(sampleRows).subscribe({
+      next: (res: any) => {
+        this.typeSuggestionsSub.next(res)
+        this.getSummary()
+      },
+      error: (err: any) => {
+        this.snackbar.openSnackBar(err.error, 'Close')
+      },
+    })
+  }
+
   getSummary() {
     return this.fetch.getSummary().subscribe({
       next: (summary: any) => {
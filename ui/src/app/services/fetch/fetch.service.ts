@@ -106,6 +106,11 @@ export class FetchService {
     return this.http.get(`${this.url}/typemap`)
   }
 
+  getTypeSuggestions(sampleRows?: number) {
+    const query = sampleRows ? `?sampleRows=${sampleRows}` : ''
+    return this.http.get(`${this.url}/typemap/suggestions${query}`)
+  }
+
   reviewTableUpdate(tableName: string, data: IUpdateTable): any {
     return this.http.post<HttpResponse<IReviewUpdateTable>>(
       `${this.url}/typemap/reviewTableSchema?table=${tableName}`,
@@ -10,7 +10,7 @@ export function extractSourceDbName(srcDbName: string) {
   if (srcDbName === 'oracle') {
     return SourceDbNames.Oracle
   }
-  if (srcDbName === 'sqlserver') {
+  if (srcDbName === 'sqlserver' || srcDbName === 'sqlserverdump') {
     return SourceDbNames.SQLServer
   }
   return srcDbName
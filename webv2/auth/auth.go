@@ -0,0 +1,155 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package auth authenticates the requests to the web server, and restricts
+// what users with the viewer role can do.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Role of an authenticated user.
+type Role string
+
+const (
+	// Editors can use every endpoint.
+	RoleEditor Role = "editor"
+	// Viewers can only use the endpoints that view the DDL and reports.
+	RoleViewer Role = "viewer"
+)
+
+// Authentication modes of Config.
+const (
+	ModeNone  = "none"
+	ModeToken = "token"
+	ModeBasic = "basic"
+	ModeOIDC  = "oidc"
+)
+
+// Identity is an authenticated user.
+type Identity struct {
+	User string
+	Role Role
+}
+
+// ErrNoCredentials is returned by authenticators for requests without
+// credentials.
+var ErrNoCredentials = errors.New("no credentials")
+
+// Authenticator authenticates requests.
+type Authenticator interface {
+	// Authenticate returns the user that sent r, or an error if r has no
+	// valid credentials.
+	Authenticate(r *http.Request) (Identity, error)
+	// Challenge is the value of the WWW-Authenticate header of responses
+	// to requests that failed authentication.
+	Challenge() string
+}
+
+// Config configures how requests are authenticated.
+type Config struct {
+	Mode string // One of ModeNone, ModeToken, ModeBasic or ModeOIDC.
+	// Bearer tokens of editors and viewers, for ModeToken.
+	Token       string
+	ViewerToken string
+	// htpasswd file of the users, for ModeBasic.
+	HtpasswdFile string
+	// Issuer and audience of the ID tokens, and the file with the JSON web
+	// key set that signs them, for ModeOIDC.
+	OIDCIssuer   string
+	OIDCAudience string
+	OIDCJWKSFile string
+	// Users of ModeBasic with the viewer role; the others are editors.
+	Viewers []string
+	// Users of ModeOIDC with the editor role; the others are viewers, since
+	// any account of the issuer can get a token. OIDC users are identified
+	// by the email claim of their token, or the sub claim if there is none.
+	Editors []string
+}
+
+// NewAuthenticator returns the authenticator configured by c, or nil if
+// requests aren't authenticated.
+func NewAuthenticator(c Config) (Authenticator, error) {
+	switch c.Mode {
+	case "", ModeNone:
+		return nil, nil
+	case ModeToken:
+		return NewTokenAuthenticator(c.Token, c.ViewerToken)
+	case ModeBasic:
+		return NewBasicAuthenticator(c.HtpasswdFile, c.Viewers)
+	case ModeOIDC:
+		return NewOIDCAuthenticator(c.OIDCIssuer, c.OIDCAudience, c.OIDCJWKSFile, c.Editors)
+	default:
+		return nil, fmt.Errorf("unknown authentication mode %q, expected one of %s, %s, %s or %s", c.Mode, ModeNone, ModeToken, ModeBasic, ModeOIDC)
+	}
+}
+
+type identityKey struct{}
+
+// GetIdentity returns the user that sent the request with context ctx, and
+// whether the request was authenticated.
+func GetIdentity(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
+
+// Handler returns middleware that rejects requests that a does not
+// authenticate, and requests of viewers for which viewerAllowed returns
+// false. The identity of the user is added to the request context.
+func Handler(a Authenticator, viewerAllowed func(r *http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, err := a.Authenticate(r)
+			if err != nil {
+				if err != ErrNoCredentials {
+					log.Printf("Authentication failed for %s %s: %v", r.Method, r.URL.Path, err)
+				}
+				w.Header().Set("WWW-Authenticate", a.Challenge())
+				http.Error(w, "Authentication required", http.StatusUnauthorized)
+				return
+			}
+			if id.Role != RoleEditor && !(id.Role == RoleViewer && viewerAllowed(r)) {
+				http.Error(w, fmt.Sprintf("User %s with role %s can't use %s %s", id.User, id.Role, r.Method, r.URL.Path), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, id)))
+		})
+	}
+}
+
+// bearerToken returns the token of the Authorization header of r.
+func bearerToken(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+	const prefix = "bearer "
+	if len(h) <= len(prefix) || !strings.EqualFold(h[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(h[len(prefix):]), true
+}
+
+// listed returns whether user is one of users.
+func listed(user string, users []string) bool {
+	for _, u := range users {
+		if u == user {
+			return true
+		}
+	}
+	return false
+}
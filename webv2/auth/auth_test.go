@@ -0,0 +1,224 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func serve(a Authenticator, r *http.Request) (*httptest.ResponseRecorder, Identity) {
+	var id Identity
+	h := Handler(a, func(r *http.Request) bool { return r.URL.Path == "/ddl" })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ = GetIdentity(r.Context())
+	}))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, r)
+	return rr, id
+}
+
+func TestTokenAuthenticator(t *testing.T) {
+	a, err := NewTokenAuthenticator("secret", "viewer-secret")
+	assert.Nil(t, err)
+	tests := []struct {
+		name       string
+		path       string
+		header     string
+		statusCode int
+		identity   Identity
+	}{
+		{name: "editor", path: "/connect", header: "Bearer secret", statusCode: http.StatusOK, identity: Identity{User: "token", Role: RoleEditor}},
+		{name: "viewer allowed", path: "/ddl", header: "bearer viewer-secret", statusCode: http.StatusOK, identity: Identity{User: "viewer-token", Role: RoleViewer}},
+		{name: "viewer forbidden", path: "/connect", header: "Bearer viewer-secret", statusCode: http.StatusForbidden},
+		{name: "wrong token", path: "/ddl", header: "Bearer other", statusCode: http.StatusUnauthorized},
+		{name: "no token", path: "/ddl", statusCode: http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		r := httptest.NewRequest("GET", tc.path, nil)
+		if tc.header != "" {
+			r.Header.Set("Authorization", tc.header)
+		}
+		rr, id := serve(a, r)
+		assert.Equal(t, tc.statusCode, rr.Code, tc.name)
+		assert.Equal(t, tc.identity, id, tc.name)
+		if tc.statusCode == http.StatusUnauthorized {
+			assert.Equal(t, `Bearer realm="HarbourBridge"`, rr.Header().Get("WWW-Authenticate"), tc.name)
+		}
+	}
+
+	_, err = NewTokenAuthenticator("", "")
+	assert.NotNil(t, err)
+	_, err = NewTokenAuthenticator("secret", "secret")
+	assert.NotNil(t, err)
+}
+
+func TestBasicAuthenticator(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("alice-password"), bcrypt.MinCost)
+	assert.Nil(t, err)
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	// The SHA-1 hash of "bob-password".
+	htpasswd := "# Users\nalice:" + string(hash) + "\nbob:{SHA}oHryCTyM4ObJvET53dSBiRe/fXQ=\n"
+	assert.Nil(t, os.WriteFile(path, []byte(htpasswd), 0600))
+	a, err := NewBasicAuthenticator(path, []string{"bob"})
+	assert.Nil(t, err)
+	tests := []struct {
+		name       string
+		path       string
+		user       string
+		password   string
+		statusCode int
+		identity   Identity
+	}{
+		{name: "bcrypt", path: "/connect", user: "alice", password: "alice-password", statusCode: http.StatusOK, identity: Identity{User: "alice", Role: RoleEditor}},
+		{name: "sha viewer", path: "/ddl", user: "bob", password: "bob-password", statusCode: http.StatusOK, identity: Identity{User: "bob", Role: RoleViewer}},
+		{name: "viewer forbidden", path: "/connect", user: "bob", password: "bob-password", statusCode: http.StatusForbidden},
+		{name: "wrong password", path: "/ddl", user: "alice", password: "bob-password", statusCode: http.StatusUnauthorized},
+		{name: "unknown user", path: "/ddl", user: "carol", password: "alice-password", statusCode: http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		r := httptest.NewRequest("GET", tc.path, nil)
+		r.SetBasicAuth(tc.user, tc.password)
+		rr, id := serve(a, r)
+		assert.Equal(t, tc.statusCode, rr.Code, tc.name)
+		assert.Equal(t, tc.identity, id, tc.name)
+	}
+	rr, _ := serve(a, httptest.NewRequest("GET", "/ddl", nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, `Basic realm="HarbourBridge", charset="UTF-8"`, rr.Header().Get("WWW-Authenticate"))
+
+	// Passwords hashed with MD5 aren't supported.
+	assert.Nil(t, os.WriteFile(path, []byte("carol:$apr1$salt$hash\n"), 0600))
+	_, err = NewBasicAuthenticator(path, nil)
+	assert.NotNil(t, err)
+}
+
+func encodeSegment(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func TestOIDCAuthenticator(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	jwks := map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa", "use": "sig", "n": encodeBigInt(rsaKey.N), "e": encodeBigInt(big.NewInt(int64(rsaKey.E)))},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": encodeBigInt(ecKey.X), "y": encodeBigInt(ecKey.Y)},
+	}}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	b, _ := json.Marshal(jwks)
+	assert.Nil(t, os.WriteFile(path, b, 0600))
+	a, err := NewOIDCAuthenticator("https://issuer.example.com", "harbourbridge", path, []string{"editor@example.com", "5678"})
+	assert.Nil(t, err)
+	now := time.Unix(1700000000, 0)
+	a.(*oidcAuthenticator).now = func() time.Time { return now }
+
+	sign := func(alg, kid string, claims map[string]interface{}) string {
+		signed := encodeSegment(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + encodeSegment(claims)
+		digest := sha256.Sum256([]byte(signed))
+		var sig []byte
+		if alg == "RS256" {
+			sig, err = rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+			assert.Nil(t, err)
+		} else {
+			r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+			assert.Nil(t, err)
+			sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+		return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+	}
+	claims := func(edit func(c map[string]interface{})) map[string]interface{} {
+		c := map[string]interface{}{"iss": "https://issuer.example.com", "aud": "harbourbridge", "sub": "1234", "exp": now.Add(time.Hour).Unix()}
+		edit(c)
+		return c
+	}
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		statusCode int
+		identity   Identity
+	}{
+		{name: "rs256 editor", path: "/connect", token: sign("RS256", "rsa", claims(func(c map[string]interface{}) { c["email"] = "editor@example.com" })), statusCode: http.StatusOK, identity: Identity{User: "editor@example.com", Role: RoleEditor}},
+		{name: "editor by subject", path: "/connect", token: sign("RS256", "rsa", claims(func(c map[string]interface{}) { c["sub"] = "5678" })), statusCode: http.StatusOK, identity: Identity{User: "5678", Role: RoleEditor}},
+		{name: "unlisted viewer", path: "/ddl", token: sign("RS256", "rsa", claims(func(c map[string]interface{}) {})), statusCode: http.StatusOK, identity: Identity{User: "1234", Role: RoleViewer}},
+		{name: "unlisted forbidden", path: "/connect", token: sign("RS256", "rsa", claims(func(c map[string]interface{}) {})), statusCode: http.StatusForbidden},
+		{name: "es256 viewer", path: "/ddl", token: sign("ES256", "ec", claims(func(c map[string]interface{}) {
+			c["email"] = "viewer@example.com"
+			c["aud"] = []string{"other", "harbourbridge"}
+		})), statusCode: http.StatusOK, identity: Identity{User: "viewer@example.com", Role: RoleViewer}},
+		{name: "viewer forbidden", path: "/connect", token: sign("ES256", "ec", claims(func(c map[string]interface{}) { c["email"] = "viewer@example.com" })), statusCode: http.StatusForbidden},
+		{name: "expired", path: "/ddl", token: sign("RS256", "rsa", claims(func(c map[string]interface{}) { c["exp"] = now.Add(-time.Hour).Unix() })), statusCode: http.StatusUnauthorized},
+		{name: "not valid yet", path: "/ddl", token: sign("RS256", "rsa", claims(func(c map[string]interface{}) { c["nbf"] = now.Add(time.Hour).Unix() })), statusCode: http.StatusUnauthorized},
+		{name: "wrong issuer", path: "/ddl", token: sign("RS256", "rsa", claims(func(c map[string]interface{}) { c["iss"] = "https://other.example.com" })), statusCode: http.StatusUnauthorized},
+		{name: "wrong audience", path: "/ddl", token: sign("RS256", "rsa", claims(func(c map[string]interface{}) { c["aud"] = "other" })), statusCode: http.StatusUnauthorized},
+		{name: "unknown key", path: "/ddl", token: sign("RS256", "other", claims(func(c map[string]interface{}) {})), statusCode: http.StatusUnauthorized},
+		{name: "algorithm of other key", path: "/ddl", token: sign("RS256", "ec", claims(func(c map[string]interface{}) {})), statusCode: http.StatusUnauthorized},
+		{name: "hmac", path: "/ddl", token: encodeSegment(map[string]string{"alg": "HS256", "kid": "rsa"}) + "." + encodeSegment(claims(func(c map[string]interface{}) {})) + "." + base64.RawURLEncoding.EncodeToString(make([]byte, 32)), statusCode: http.StatusUnauthorized},
+		{name: "no expiry", path: "/ddl", token: sign("RS256", "rsa", claims(func(c map[string]interface{}) { delete(c, "exp") })), statusCode: http.StatusUnauthorized},
+		{name: "unsigned", path: "/ddl", token: encodeSegment(map[string]string{"alg": "none", "kid": "rsa"}) + "." + encodeSegment(claims(func(c map[string]interface{}) {})) + ".", statusCode: http.StatusUnauthorized},
+		{name: "malformed", path: "/ddl", token: "not-a-jwt", statusCode: http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		r := httptest.NewRequest("GET", tc.path, nil)
+		r.Header.Set("Authorization", "Bearer "+tc.token)
+		rr, id := serve(a, r)
+		assert.Equal(t, tc.statusCode, rr.Code, tc.name)
+		assert.Equal(t, tc.identity, id, tc.name)
+	}
+
+	// A token whose claims were changed after signing is rejected.
+	parts := strings.Split(sign("RS256", "rsa", claims(func(c map[string]interface{}) { c["email"] = "viewer@example.com" })), ".")
+	parts[1] = encodeSegment(claims(func(c map[string]interface{}) { c["email"] = "admin@example.com" }))
+	r := httptest.NewRequest("GET", "/ddl", nil)
+	r.Header.Set("Authorization", "Bearer "+strings.Join(parts, "."))
+	rr, _ := serve(a, r)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	a, err := NewAuthenticator(Config{Mode: ModeNone})
+	assert.Nil(t, err)
+	assert.Nil(t, a)
+	a, err = NewAuthenticator(Config{Mode: ModeToken, Token: "secret"})
+	assert.Nil(t, err)
+	assert.NotNil(t, a)
+	_, err = NewAuthenticator(Config{Mode: ModeOIDC})
+	assert.NotNil(t, err)
+	_, err = NewAuthenticator(Config{Mode: "ldap"})
+	assert.NotNil(t, err)
+}
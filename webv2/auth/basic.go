@@ -0,0 +1,106 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type basicAuthenticator struct {
+	hashes  map[string]string // Password hashes by user.
+	viewers []string
+}
+
+// NewBasicAuthenticator returns an authenticator of requests with HTTP
+// basic auth credentials of the users of htpasswd file path. Passwords
+// must be hashed with bcrypt (htpasswd -B) or SHA-1 (htpasswd -s). Users
+// in viewers get the viewer role, others the editor role.
+func NewBasicAuthenticator(path string, viewers []string) (Authenticator, error) {
+	if path == "" {
+		return nil, fmt.Errorf("basic authentication needs a htpasswd file")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read htpasswd file: %v", err)
+	}
+	defer f.Close()
+	hashes := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		user, hash, ok := strings.Cut(line, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("%s:%d: expected user:hash", path, n)
+		}
+		if !isBcrypt(hash) && !strings.HasPrefix(hash, "{SHA}") {
+			return nil, fmt.Errorf("%s:%d: password of user %s isn't hashed with bcrypt or SHA-1", path, n, user)
+		}
+		hashes[user] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can't read htpasswd file: %v", err)
+	}
+	return &basicAuthenticator{hashes: hashes, viewers: viewers}, nil
+}
+
+func (a *basicAuthenticator) Authenticate(r *http.Request) (Identity, error) {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return Identity{}, ErrNoCredentials
+	}
+	hash, ok := a.hashes[user]
+	if !ok || !checkPassword(hash, password) {
+		return Identity{}, fmt.Errorf("invalid password for user %s", user)
+	}
+	role := RoleEditor
+	if listed(user, a.viewers) {
+		role = RoleViewer
+	}
+	return Identity{User: user, Role: role}, nil
+}
+
+func (a *basicAuthenticator) Challenge() string {
+	return `Basic realm="HarbourBridge", charset="UTF-8"`
+}
+
+func isBcrypt(hash string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkPassword(hash, password string) bool {
+	if isBcrypt(hash) {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+	sum := sha1.Sum([]byte(password))
+	expected := "{SHA}" + base64.StdEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(expected)) == 1
+}
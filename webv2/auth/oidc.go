@@ -0,0 +1,149 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+// clockSkew is how far the clocks of the token issuer and the server may
+// differ when checking the expiry of tokens.
+const clockSkew = time.Minute
+
+// signingAlgorithms are the algorithms that ID tokens can be signed with.
+var signingAlgorithms = map[jose.SignatureAlgorithm]bool{
+	jose.RS256: true,
+	jose.RS384: true,
+	jose.RS512: true,
+	jose.ES256: true,
+	jose.ES384: true,
+}
+
+type oidcAuthenticator struct {
+	issuer, audience string
+	keys             map[string]jose.JSONWebKey // Signing keys by key id.
+	editors          []string
+	now              func() time.Time
+}
+
+// NewOIDCAuthenticator returns an authenticator of requests with an OIDC
+// ID token as bearer token, issued by issuer for audience and signed with
+// one of the keys of JSON web key set file jwksFile. Tokens must be signed
+// with RS256, RS384, RS512, ES256 or ES384. Users in editors, by the email
+// claim of their token or else its sub claim, get the editor role, others
+// the viewer role.
+func NewOIDCAuthenticator(issuer, audience, jwksFile string, editors []string) (Authenticator, error) {
+	if issuer == "" || audience == "" || jwksFile == "" {
+		return nil, fmt.Errorf("OIDC authentication needs an issuer, an audience and a JWKS file")
+	}
+	b, err := os.ReadFile(jwksFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't read JWKS file: %v", err)
+	}
+	keys, err := parseJWKS(b)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse JWKS file %s: %v", jwksFile, err)
+	}
+	return &oidcAuthenticator{issuer: issuer, audience: audience, keys: keys, editors: editors, now: time.Now}, nil
+}
+
+// parseJWKS returns the public signing keys of JSON web key set b.
+func parseJWKS(b []byte) (map[string]jose.JSONWebKey, error) {
+	var jwks jose.JSONWebKeySet
+	if err := json.Unmarshal(b, &jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]jose.JSONWebKey)
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if !k.IsPublic() || !k.Valid() {
+			return nil, fmt.Errorf("key %q isn't a valid public key", k.KeyID)
+		}
+		keys[k.KeyID] = k
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys")
+	}
+	return keys, nil
+}
+
+// emailClaim is the claim of ID tokens that isn't a registered JWT claim.
+type emailClaim struct {
+	Email string `json:"email"`
+}
+
+func (a *oidcAuthenticator) Authenticate(r *http.Request) (Identity, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return Identity{}, ErrNoCredentials
+	}
+	claims, email, err := a.verify(token)
+	if err != nil {
+		return Identity{}, err
+	}
+	user := email.Email
+	if user == "" {
+		user = claims.Subject
+	}
+	role := RoleViewer
+	if listed(user, a.editors) {
+		role = RoleEditor
+	}
+	return Identity{User: user, Role: role}, nil
+}
+
+func (a *oidcAuthenticator) Challenge() string {
+	return `Bearer realm="HarbourBridge"`
+}
+
+// verify checks the signature and claims of JWT token, and returns its
+// claims.
+func (a *oidcAuthenticator) verify(token string) (jwt.Claims, emailClaim, error) {
+	var claims jwt.Claims
+	var email emailClaim
+	tok, err := jwt.ParseSigned(token)
+	if err != nil {
+		return claims, email, fmt.Errorf("malformed token: %v", err)
+	}
+	header := tok.Headers[0]
+	if !signingAlgorithms[jose.SignatureAlgorithm(header.Algorithm)] {
+		return claims, email, fmt.Errorf("unsupported signing algorithm %q", header.Algorithm)
+	}
+	key, ok := a.keys[header.KeyID]
+	if !ok {
+		return claims, email, fmt.Errorf("unknown signing key %q", header.KeyID)
+	}
+	if err := tok.Claims(key.Key, &claims, &email); err != nil {
+		return claims, email, fmt.Errorf("invalid token: %v", err)
+	}
+	if claims.Expiry == nil {
+		return claims, email, errors.New("token has no expiry")
+	}
+	expected := jwt.Expected{Issuer: a.issuer, Audience: jwt.Audience{a.audience}, Time: a.now()}
+	if err := claims.ValidateWithLeeway(expected, clockSkew); err != nil {
+		return claims, email, fmt.Errorf("invalid token claims: %v", err)
+	}
+	return claims, email, nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type tokenAuthenticator struct {
+	token, viewerToken string
+}
+
+// NewTokenAuthenticator returns an authenticator of requests with bearer
+// token token, as editor, or viewerToken, as viewer. viewerToken may be
+// empty.
+func NewTokenAuthenticator(token, viewerToken string) (Authenticator, error) {
+	if token == "" {
+		return nil, fmt.Errorf("token authentication needs a token")
+	}
+	if token == viewerToken {
+		return nil, fmt.Errorf("the tokens of editors and viewers must differ")
+	}
+	return &tokenAuthenticator{token: token, viewerToken: viewerToken}, nil
+}
+
+func (a *tokenAuthenticator) Authenticate(r *http.Request) (Identity, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return Identity{}, ErrNoCredentials
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) == 1 {
+		return Identity{User: "token", Role: RoleEditor}, nil
+	}
+	if a.viewerToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.viewerToken)) == 1 {
+		return Identity{User: "viewer-token", Role: RoleViewer}, nil
+	}
+	return Identity{}, errors.New("invalid token")
+}
+
+func (a *tokenAuthenticator) Challenge() string {
+	return `Bearer realm="HarbourBridge"`
+}
@@ -133,7 +133,9 @@ func GetSourceDatabaseFromDriver(driver string) (string, error) {
 		return constants.MYSQL, nil
 	case constants.PGDUMP, constants.POSTGRES:
 		return constants.POSTGRES, nil
-	case constants.ORACLE, constants.SQLSERVER:
+	case constants.SQLSERVERDUMP, constants.SQLSERVER:
+		return constants.SQLSERVER, nil
+	case constants.ORACLE:
 		return driver, nil
 	default:
 		return "", fmt.Errorf("unsupported driver type: %v", driver)
@@ -22,17 +22,14 @@ import (
 )
 
 // IndexSuggestion adds redundant index issue and interleved index suggestion in issues and suggestions tab.
-func IndexSuggestion() {
-
-	sessionState := session.GetSessionState()
+func IndexSuggestion(sessionState *session.SessionState) {
 
 	for _, spannerTable := range sessionState.Conv.SpSchema {
-		CheckIndexSuggestion(spannerTable.Indexes, spannerTable)
+		CheckIndexSuggestion(sessionState, spannerTable.Indexes, spannerTable)
 	}
 }
 
-func AssignInitialOrders() {
-	sessionState := session.GetSessionState()
+func AssignInitialOrders(sessionState *session.SessionState) {
 	conv := sessionState.Conv
 
 	for _, spannerTable := range conv.SpSchema {
@@ -50,15 +47,15 @@ func AssignInitialOrders() {
 }
 
 // Helper method for checking Index Suggestion.
-func CheckIndexSuggestion(index []ddl.CreateIndex, spannerTable ddl.CreateTable) {
+func CheckIndexSuggestion(sessionState *session.SessionState, index []ddl.CreateIndex, spannerTable ddl.CreateTable) {
 
-	checkRedundantIndex(index, spannerTable)
-	checkInterleaveIndex(index, spannerTable)
+	checkRedundantIndex(sessionState, index, spannerTable)
+	checkInterleaveIndex(sessionState, index, spannerTable)
 }
 
 // redundantIndex check for redundant Index.
 // If present adds Redundant as an issue in Issues.
-func checkRedundantIndex(index []ddl.CreateIndex, spannerTable ddl.CreateTable) {
+func checkRedundantIndex(sessionState *session.SessionState, index []ddl.CreateIndex, spannerTable ddl.CreateTable) {
 
 	var primaryKeyFirstColumnId string
 	pks := spannerTable.PrimaryKeys
@@ -76,7 +73,6 @@ func checkRedundantIndex(index []ddl.CreateIndex, spannerTable ddl.CreateTable)
 
 			if primaryKeyFirstColumnId == indexFirstColumnId {
 				columnId := indexFirstColumnId
-				sessionState := session.GetSessionState()
 				schemaissue := sessionState.Conv.SchemaIssues[spannerTable.Id][columnId]
 				schemaissue = append(schemaissue, internal.RedundantIndex)
 				sessionState.Conv.SchemaIssues[spannerTable.Id][columnId] = schemaissue
@@ -87,7 +83,7 @@ func checkRedundantIndex(index []ddl.CreateIndex, spannerTable ddl.CreateTable)
 
 // interleaveIndex suggests if an index can be converted to interleave.
 // If possible it gets added as a suggestion.
-func checkInterleaveIndex(index []ddl.CreateIndex, spannerTable ddl.CreateTable) {
+func checkInterleaveIndex(sessionState *session.SessionState, index []ddl.CreateIndex, spannerTable ddl.CreateTable) {
 
 	// Suggestion gets added only if the table can be interleaved.
 	isInterleavable := spannerTable.ParentId != ""
@@ -108,8 +104,6 @@ func checkInterleaveIndex(index []ddl.CreateIndex, spannerTable ddl.CreateTable)
 			if len(index[i].Keys) > 0 {
 				indexFirstColumnId := index[i].Keys[0].ColId
 
-				sessionState := session.GetSessionState()
-
 				// Ensuring it is not a redundant index.
 				if primaryKeyFirstColumnId != indexFirstColumnId {
 
@@ -137,7 +131,6 @@ func checkInterleaveIndex(index []ddl.CreateIndex, spannerTable ddl.CreateTable)
 							if c.T.Name == ddl.Timestamp {
 
 								columnId := c.Id
-								sessionState := session.GetSessionState()
 								schemaissue := sessionState.Conv.SchemaIssues[spannerTable.Id][columnId]
 
 								schemaissue = append(schemaissue, internal.AutoIncrementIndex)
@@ -156,7 +149,7 @@ func checkInterleaveIndex(index []ddl.CreateIndex, spannerTable ddl.CreateTable)
 // RemoveIndexIssues removes the issues in a column which is part of the passed Index.
 // This is called when we drop an index or make changes in the primarykey of the current table.
 // Editing the primary key can affect the issues in an index (eg. Changing pk order affects Redundant index issue).
-func RemoveIndexIssues(tableId string, Index ddl.CreateIndex) {
+func RemoveIndexIssues(sessionState *session.SessionState, tableId string, Index ddl.CreateIndex) {
 
 	for i := 0; i < len(Index.Keys); i++ {
 
@@ -164,7 +157,6 @@ func RemoveIndexIssues(tableId string, Index ddl.CreateIndex) {
 
 		{
 			schemaissue := []internal.SchemaIssue{}
-			sessionState := session.GetSessionState()
 			if sessionState.Conv.SchemaIssues != nil {
 				schemaissue = sessionState.Conv.SchemaIssues[tableId][columnId]
 			}
@@ -0,0 +1,123 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/session"
+	"github.com/gorilla/mux"
+)
+
+// eventInterval is how often JobEvents sends the events of a job.
+var eventInterval = time.Second
+
+// The handlers only see the jobs of the session of the request, identified
+// by session.RequestSessionId. They don't go through session.Handler, so
+// that the events of a job can be streamed without blocking the other
+// requests of its session.
+
+// ListJobs lists the status of the jobs of the session of the request.
+func (m *Manager) ListJobs(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(m.List(session.RequestSessionId(r)))
+}
+
+// GetJob returns the status and log of the job with id jobId.
+func (m *Manager) GetJob(w http.ResponseWriter, r *http.Request) {
+	j, err := m.Get(session.RequestSessionId(r), mux.Vars(r)["jobId"])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Can't get job: %v", err), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(j)
+}
+
+// CancelJob cancels the job with id jobId, and returns its status.
+func (m *Manager) CancelJob(w http.ResponseWriter, r *http.Request) {
+	sessionId, id := session.RequestSessionId(r), mux.Vars(r)["jobId"]
+	if err := m.Cancel(sessionId, id); err == ErrNotFound {
+		http.Error(w, fmt.Sprintf("Can't cancel job: %v", err), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf("Can't cancel job: %v", err), http.StatusConflict)
+		return
+	}
+	j, _ := m.Get(sessionId, id)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(j)
+}
+
+// JobEvents streams the events of the job with id jobId as server-sent
+// events until the job finishes or the client disconnects: the
+// internal.ProgressEvent of its migration, and a "state" event with its
+// status each time its state changes.
+func (m *Manager) JobEvents(w http.ResponseWriter, r *http.Request) {
+	sessionId, id := session.RequestSessionId(r), mux.Vars(r)["jobId"]
+	mp, err := m.Migration(sessionId, id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Can't get job: %v", err), http.StatusNotFound)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming isn't supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	events := internal.NewProgressEvents(mp)
+	ticker := time.NewTicker(eventInterval)
+	defer ticker.Stop()
+	var state State
+	for {
+		// Get the status first, so that the events of a finished job are
+		// complete.
+		j, err := m.Get(sessionId, id)
+		if err != nil {
+			return
+		}
+		for _, e := range events.Next() {
+			writeEvent(w, string(e.Type), e)
+		}
+		if j.State != state {
+			state = j.State
+			j.Log = nil
+			writeEvent(w, "state", j)
+		}
+		flusher.Flush()
+		if state.Finished() {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeEvent writes a server-sent event of type typ whose data is v as
+// JSON.
+func writeEvent(w http.ResponseWriter, typ string, v interface{}) {
+	data, _ := json.Marshal(v)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typ, data)
+}
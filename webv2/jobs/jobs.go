@@ -0,0 +1,307 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jobs runs migrations of the web server as asynchronous jobs that
+// can be monitored and cancelled.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/google/uuid"
+)
+
+// State of a job.
+type State string
+
+const (
+	Queued    State = "queued"
+	Running   State = "running"
+	Failed    State = "failed"
+	Succeeded State = "succeeded"
+	Cancelled State = "cancelled"
+)
+
+// Finished returns whether a job in state s has stopped running.
+func (s State) Finished() bool {
+	return s == Failed || s == Succeeded || s == Cancelled
+}
+
+// maxFinishedJobs is how many finished jobs are kept by a Manager.
+const maxFinishedJobs = 100
+
+// Job is the status of a job.
+type Job struct {
+	Id          string
+	Description string
+	SessionId   string `json:"-"`
+	State       State
+	Error       string `json:",omitempty"`
+	CreatedAt   time.Time
+	StartedAt   *time.Time `json:",omitempty"`
+	FinishedAt  *time.Time `json:",omitempty"`
+	// Percentage of the job done, and the internal.ProgressStatus of the
+	// stage it is in.
+	Progress       int
+	ProgressStatus int
+	// Phase of the migration, and the progress of the data migration of
+	// each Spanner table.
+	Phase  internal.Phase                `json:",omitempty"`
+	Tables map[string]internal.TableRows `json:",omitempty"`
+	Log    []LogEntry
+}
+
+// LogEntry is a message logged by a job.
+type LogEntry struct {
+	Time    time.Time
+	Message string
+}
+
+// Spec describes a job to submit.
+type Spec struct {
+	Description string
+	SessionId   string
+	// Run does the work of the job. It must return soon after ctx is
+	// cancelled, and can log messages to the job log with logf.
+	Run func(ctx context.Context, logf func(format string, a ...interface{})) error
+	// Progress, if set, returns the percentage done and the stage of the
+	// running job.
+	Progress func() (int, int)
+	// Migration, if set, tracks the phase of the migration and the
+	// progress of each table.
+	Migration *internal.MigrationProgress
+}
+
+type job struct {
+	Job
+	spec   Spec
+	cancel context.CancelFunc
+}
+
+// ErrNotFound is returned for jobs that don't exist, or that belong to
+// another session.
+var ErrNotFound = errors.New("job not found")
+
+// Manager runs jobs, at most a given number at a time; the others are
+// queued. It is safe for concurrent use.
+type Manager struct {
+	mu    sync.Mutex
+	jobs  map[string]*job
+	slots chan struct{} // Holds a value for each running job.
+}
+
+// NewManager returns a Manager that runs at most maxRunning jobs at a time.
+func NewManager(maxRunning int) *Manager {
+	return &Manager{jobs: make(map[string]*job), slots: make(chan struct{}, maxRunning)}
+}
+
+// Submit queues a job, and returns its status.
+func (m *Manager) Submit(spec Spec) Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		Job:    Job{Id: uuid.New().String(), Description: spec.Description, SessionId: spec.SessionId, State: Queued, CreatedAt: time.Now()},
+		spec:   spec,
+		cancel: cancel,
+	}
+	m.mu.Lock()
+	m.jobs[j.Id] = j
+	m.logLocked(j, "Job queued: %s", spec.Description)
+	status := m.statusLocked(j)
+	m.mu.Unlock()
+	go m.run(ctx, j)
+	return status
+}
+
+func (m *Manager) run(ctx context.Context, j *job) {
+	defer j.cancel()
+	select {
+	case m.slots <- struct{}{}:
+		defer func() { <-m.slots }()
+	case <-ctx.Done():
+		m.finish(ctx, j, nil)
+		return
+	}
+	m.mu.Lock()
+	if ctx.Err() != nil {
+		m.mu.Unlock()
+		m.finish(ctx, j, nil)
+		return
+	}
+	now := time.Now()
+	j.State, j.StartedAt = Running, &now
+	m.logLocked(j, "Job started")
+	m.mu.Unlock()
+	err := j.spec.Run(ctx, func(format string, a ...interface{}) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.logLocked(j, format, a...)
+	})
+	m.finish(ctx, j, err)
+}
+
+// finish records the final state of j, which failed with err if it isn't
+// nil.
+func (m *Manager) finish(ctx context.Context, j *job, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	j.FinishedAt = &now
+	if j.spec.Progress != nil && j.StartedAt != nil {
+		j.Progress, j.ProgressStatus = j.spec.Progress()
+	}
+	m.snapshotLocked(j, &j.Job)
+	switch {
+	case ctx.Err() != nil:
+		j.State = Cancelled
+		m.logLocked(j, "Job cancelled")
+	case err != nil:
+		j.State, j.Error = Failed, err.Error()
+		m.logLocked(j, "Job failed: %v", err)
+	default:
+		j.State = Succeeded
+		j.Progress = 100
+		m.logLocked(j, "Job succeeded")
+	}
+	m.pruneLocked()
+}
+
+// Get returns the status of job id of session sessionId.
+func (m *Manager) Get(sessionId, id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, err := m.lookupLocked(sessionId, id)
+	if err != nil {
+		return Job{}, err
+	}
+	return m.statusLocked(j), nil
+}
+
+// List returns the status of the jobs of session sessionId, oldest first,
+// without their logs.
+func (m *Manager) List(sessionId string) []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l := []Job{}
+	for _, j := range m.jobs {
+		if j.SessionId != sessionId {
+			continue
+		}
+		status := m.statusLocked(j)
+		status.Log = nil
+		l = append(l, status)
+	}
+	sort.Slice(l, func(i, k int) bool { return l[i].CreatedAt.Before(l[k].CreatedAt) })
+	return l
+}
+
+// Active returns the status of the queued or running job of session
+// sessionId, if there is one.
+func (m *Manager) Active(sessionId string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, j := range m.jobs {
+		if j.SessionId == sessionId && !j.State.Finished() {
+			return m.statusLocked(j), true
+		}
+	}
+	return Job{}, false
+}
+
+// Cancel cancels job id of session sessionId. A queued job is cancelled
+// right away; a running job is cancelled once it stops.
+func (m *Manager) Cancel(sessionId, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, err := m.lookupLocked(sessionId, id)
+	if err != nil {
+		return err
+	}
+	if j.State.Finished() {
+		return fmt.Errorf("job %s has already %s", id, j.State)
+	}
+	m.logLocked(j, "Cancellation requested")
+	j.cancel()
+	return nil
+}
+
+// statusLocked returns a copy of the status of j. m.mu must be held.
+func (m *Manager) statusLocked(j *job) Job {
+	status := j.Job
+	if j.State == Running {
+		if j.spec.Progress != nil {
+			status.Progress, status.ProgressStatus = j.spec.Progress()
+		}
+		m.snapshotLocked(j, &status)
+	}
+	status.Log = append([]LogEntry{}, j.Log...)
+	return status
+}
+
+// snapshotLocked sets the phase and table progress of status from the
+// migration of j. m.mu must be held.
+func (m *Manager) snapshotLocked(j *job, status *Job) {
+	s := j.spec.Migration.Snapshot()
+	status.Phase, status.Tables = s.Phase, s.Tables
+}
+
+// Migration returns the progress tracker of the migration of job id of
+// session sessionId, which is nil if the job doesn't track one.
+func (m *Manager) Migration(sessionId, id string) (*internal.MigrationProgress, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, err := m.lookupLocked(sessionId, id)
+	if err != nil {
+		return nil, err
+	}
+	return j.spec.Migration, nil
+}
+
+// lookupLocked returns job id if it belongs to session sessionId. Jobs of
+// other sessions aren't found. m.mu must be held.
+func (m *Manager) lookupLocked(sessionId, id string) (*job, error) {
+	j, ok := m.jobs[id]
+	if !ok || j.SessionId != sessionId {
+		return nil, ErrNotFound
+	}
+	return j, nil
+}
+
+// logLocked appends a message to the log of j. m.mu must be held.
+func (m *Manager) logLocked(j *job, format string, a ...interface{}) {
+	j.Log = append(j.Log, LogEntry{Time: time.Now(), Message: fmt.Sprintf(format, a...)})
+}
+
+// pruneLocked drops the oldest finished jobs beyond maxFinishedJobs. m.mu
+// must be held.
+func (m *Manager) pruneLocked() {
+	var finished []*job
+	for _, j := range m.jobs {
+		if j.State.Finished() {
+			finished = append(finished, j)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(i, k int) bool { return finished[i].FinishedAt.Before(*finished[k].FinishedAt) })
+	for _, j := range finished[:len(finished)-maxFinishedJobs] {
+		delete(m.jobs, j.Id)
+	}
+}
@@ -0,0 +1,256 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/session"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// waitForState waits for job submitted of m to reach state s, and returns
+// its status.
+func waitForState(t *testing.T, m *Manager, submitted Job, s State) Job {
+	id := submitted.Id
+	for i := 0; i < 500; i++ {
+		j, err := m.Get(submitted.SessionId, id)
+		assert.Nil(t, err)
+		if j.State == s {
+			return j
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s didn't reach state %s", id, s)
+	return Job{}
+}
+
+func messages(j Job) []string {
+	var l []string
+	for _, e := range j.Log {
+		l = append(l, e.Message)
+	}
+	return l
+}
+
+// blockingSpec returns a spec of a job that runs until it is cancelled or
+// release is closed, and that closes started once it runs.
+func blockingSpec(sessionId string, started, release chan struct{}) Spec {
+	return Spec{
+		Description: "blocking",
+		SessionId:   sessionId,
+		Run: func(ctx context.Context, logf func(format string, a ...interface{})) error {
+			close(started)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-release:
+				return nil
+			}
+		},
+	}
+}
+
+func TestJobStates(t *testing.T) {
+	m := NewManager(2)
+	migration := internal.NewMigrationProgress()
+	migration.SetTable("singers", "singers", 10)
+	succeeded := m.Submit(Spec{
+		Description: "succeeds",
+		SessionId:   "s1",
+		Run: func(ctx context.Context, logf func(format string, a ...interface{})) error {
+			migration.SetPhase(internal.PhaseData)
+			migration.AddWritten("singers", 10)
+			logf("Wrote %d rows", 10)
+			return nil
+		},
+		Progress:  func() (int, int) { return 40, int(internal.DataWriteInProgress) },
+		Migration: migration,
+	})
+	assert.Equal(t, "succeeds", succeeded.Description)
+	j := waitForState(t, m, succeeded, Succeeded)
+	assert.Equal(t, []string{"Job queued: succeeds", "Job started", "Wrote 10 rows", "Job succeeded"}, messages(j))
+	assert.Equal(t, 100, j.Progress)
+	assert.Equal(t, int(internal.DataWriteInProgress), j.ProgressStatus)
+	assert.Equal(t, internal.PhaseData, j.Phase)
+	assert.Equal(t, map[string]internal.TableRows{"singers": {Total: 10, Written: 10}}, j.Tables)
+	assert.NotNil(t, j.StartedAt)
+	assert.NotNil(t, j.FinishedAt)
+
+	failed := m.Submit(Spec{
+		Description: "fails",
+		SessionId:   "s2",
+		Run: func(ctx context.Context, logf func(format string, a ...interface{})) error {
+			return errors.New("can't connect")
+		},
+	})
+	j = waitForState(t, m, failed, Failed)
+	assert.Equal(t, "can't connect", j.Error)
+	assert.Equal(t, "Job failed: can't connect", j.Log[len(j.Log)-1].Message)
+
+	l := m.List("s1")
+	assert.Equal(t, 1, len(l))
+	assert.Equal(t, succeeded.Id, l[0].Id)
+	assert.Nil(t, l[0].Log)
+	assert.Equal(t, []Job{}, m.List("s3"))
+
+	_, err := m.Get("s1", "unknown")
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, ErrNotFound, m.Cancel("s1", "unknown"))
+	assert.NotNil(t, m.Cancel("s2", failed.Id))
+
+	// Jobs of other sessions aren't found.
+	_, err = m.Get("s2", succeeded.Id)
+	assert.Equal(t, ErrNotFound, err)
+	_, err = m.Migration("s2", succeeded.Id)
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, ErrNotFound, m.Cancel("s1", failed.Id))
+}
+
+func TestCancel(t *testing.T) {
+	m := NewManager(1)
+	started, release := make(chan struct{}), make(chan struct{})
+	running := m.Submit(blockingSpec("s1", started, release))
+	<-started
+	// The second job is queued until the first one finishes.
+	queued := m.Submit(blockingSpec("s2", make(chan struct{}), release))
+	assert.Equal(t, Queued, waitForState(t, m, queued, Queued).State)
+	j, ok := m.Active("s2")
+	assert.True(t, ok)
+	assert.Equal(t, queued.Id, j.Id)
+	_, ok = m.Active("s3")
+	assert.False(t, ok)
+
+	assert.Nil(t, m.Cancel("s2", queued.Id))
+	j = waitForState(t, m, queued, Cancelled)
+	assert.Nil(t, j.StartedAt)
+	assert.Equal(t, []string{"Job queued: blocking", "Cancellation requested", "Job cancelled"}, messages(j))
+
+	assert.Nil(t, m.Cancel("s1", running.Id))
+	j = waitForState(t, m, running, Cancelled)
+	assert.Equal(t, "", j.Error)
+	_, ok = m.Active("s1")
+	assert.False(t, ok)
+}
+
+func TestHandlers(t *testing.T) {
+	m := NewManager(1)
+	started, release := make(chan struct{}), make(chan struct{})
+	running := m.Submit(blockingSpec("s1", started, release))
+	<-started
+	router := mux.NewRouter()
+	router.HandleFunc("/jobs", m.ListJobs).Methods("GET")
+	router.HandleFunc("/jobs/{jobId}", m.GetJob).Methods("GET")
+	router.HandleFunc("/jobs/{jobId}/cancel", m.CancelJob).Methods("POST")
+	serve := func(sessionId, method, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(session.SessionIdHeader, sessionId)
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve("s1", "GET", "/jobs")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "SessionId")
+	var l []Job
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &l))
+	assert.Equal(t, 1, len(l))
+	assert.Equal(t, Running, l[0].State)
+
+	rr = serve("s1", "GET", "/jobs/"+running.Id)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var j Job
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &j))
+	assert.Equal(t, running.Id, j.Id)
+	assert.Equal(t, "Job started", j.Log[len(j.Log)-1].Message)
+
+	assert.Equal(t, http.StatusNotFound, serve("s1", "GET", "/jobs/unknown").Code)
+	assert.Equal(t, http.StatusNotFound, serve("s1", "POST", "/jobs/unknown/cancel").Code)
+
+	// Other sessions don't see the job, and can't cancel it.
+	rr = serve("s2", "GET", "/jobs")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &l))
+	assert.Equal(t, 0, len(l))
+	assert.Equal(t, http.StatusNotFound, serve("s2", "GET", "/jobs/"+running.Id).Code)
+	assert.Equal(t, http.StatusNotFound, serve("s2", "POST", "/jobs/"+running.Id+"/cancel").Code)
+
+	rr = serve("s1", "POST", "/jobs/"+running.Id+"/cancel")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	waitForState(t, m, running, Cancelled)
+	assert.Equal(t, http.StatusConflict, serve("s1", "POST", "/jobs/"+running.Id+"/cancel").Code)
+}
+
+func TestJobEvents(t *testing.T) {
+	eventInterval = 10 * time.Millisecond
+	defer func() { eventInterval = time.Second }()
+	m := NewManager(1)
+	migration := internal.NewMigrationProgress()
+	j := m.Submit(Spec{
+		Description: "migrates",
+		Run: func(ctx context.Context, logf func(format string, a ...interface{})) error {
+			migration.SetPhase(internal.PhaseData)
+			migration.SetTable("singers", "singers", 2)
+			migration.AddWritten("singers", 2)
+			migration.SetPhase(internal.PhaseDone)
+			return nil
+		},
+		Migration: migration,
+	})
+	router := mux.NewRouter()
+	router.HandleFunc("/jobs/{jobId}/events", m.JobEvents).Methods("GET")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/jobs/unknown/events", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// The stream ends once the job finishes.
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/jobs/"+j.Id+"/events", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/event-stream", rr.Header().Get("Content-Type"))
+	var types []string
+	var rows internal.TableRows
+	var last Job
+	for _, e := range strings.Split(strings.TrimSpace(rr.Body.String()), "\n\n") {
+		lines := strings.SplitN(e, "\n", 2)
+		assert.Equal(t, 2, len(lines))
+		typ, data := strings.TrimPrefix(lines[0], "event: "), strings.TrimPrefix(lines[1], "data: ")
+		types = append(types, typ)
+		switch typ {
+		case "table":
+			var pe internal.ProgressEvent
+			assert.Nil(t, json.Unmarshal([]byte(data), &pe))
+			rows = *pe.Rows
+		case "state":
+			assert.Nil(t, json.Unmarshal([]byte(data), &last))
+		}
+	}
+	assert.Contains(t, types, "phase")
+	assert.Equal(t, internal.TableRows{Total: 2, Written: 2}, rows)
+	assert.Equal(t, "state", types[len(types)-1])
+	assert.Equal(t, Succeeded, last.State)
+	assert.Equal(t, internal.PhaseDone, last.Phase)
+}
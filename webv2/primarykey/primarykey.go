@@ -23,9 +23,9 @@ import (
 
 // updateprimaryKey insert or delete primary key column.
 // updateprimaryKey also update desc and order for primaryKey column.
-func updatePrimaryKey(pkRequest PrimaryKeyRequest, spannerTable ddl.CreateTable, synthColId string) (ddl.CreateTable, bool) {
+func updatePrimaryKey(sessionState *session.SessionState, pkRequest PrimaryKeyRequest, spannerTable ddl.CreateTable, synthColId string) (ddl.CreateTable, bool) {
 
-	spannerTable, isSynthPkRemoved := insertOrRemovePrimarykey(pkRequest, spannerTable, synthColId)
+	spannerTable, isSynthPkRemoved := insertOrRemovePrimarykey(sessionState, pkRequest, spannerTable, synthColId)
 
 	for i := 0; i < len(pkRequest.Columns); i++ {
 
@@ -45,7 +45,7 @@ func updatePrimaryKey(pkRequest PrimaryKeyRequest, spannerTable ddl.CreateTable,
 
 // insertOrRemovePrimarykey performs insert or remove primary key operation based on
 // difference of two pkRequest and spannerTable.PrimaryKeys.
-func insertOrRemovePrimarykey(pkRequest PrimaryKeyRequest, spannerTable ddl.CreateTable, synthColId string) (ddl.CreateTable, bool) {
+func insertOrRemovePrimarykey(sessionState *session.SessionState, pkRequest PrimaryKeyRequest, spannerTable ddl.CreateTable, synthColId string) (ddl.CreateTable, bool) {
 
 	cidRequestList := getColumnIdListFromPrimaryKeyRequest(pkRequest)
 	cidSpannerTableList := getColumnIdListOfSpannerTablePrimaryKey(spannerTable)
@@ -53,9 +53,9 @@ func insertOrRemovePrimarykey(pkRequest PrimaryKeyRequest, spannerTable ddl.Crea
 	// primary key Id only presnt in pkeyrequest.
 	// hence new primary key add primary key into  spannerTable.Pk list
 	leftjoin := utilities.Difference(cidRequestList, cidSpannerTableList)
-	insert := addPrimaryKey(leftjoin, pkRequest, spannerTable)
+	insert := addPrimaryKey(sessionState, leftjoin, pkRequest, spannerTable)
 
-	isHotSpot(insert, spannerTable)
+	isHotSpot(sessionState, insert, spannerTable)
 
 	spannerTable.PrimaryKeys = append(spannerTable.PrimaryKeys, insert...)
 
@@ -71,7 +71,7 @@ func insertOrRemovePrimarykey(pkRequest PrimaryKeyRequest, spannerTable ddl.Crea
 	}
 
 	if len(rightjoin) > 0 {
-		nlist := removePrimaryKey(rightjoin, spannerTable)
+		nlist := removePrimaryKey(sessionState, rightjoin, spannerTable)
 		spannerTable.PrimaryKeys = nlist
 
 	}
@@ -82,7 +82,7 @@ func insertOrRemovePrimarykey(pkRequest PrimaryKeyRequest, spannerTable ddl.Crea
 }
 
 // addPrimaryKey insert primary key into list of IndexKey.
-func addPrimaryKey(add []string, pkRequest PrimaryKeyRequest, spannerTable ddl.CreateTable) []ddl.IndexKey {
+func addPrimaryKey(sessionState *session.SessionState, add []string, pkRequest PrimaryKeyRequest, spannerTable ddl.CreateTable) []ddl.IndexKey {
 
 	list := []ddl.IndexKey{}
 
@@ -100,7 +100,6 @@ func addPrimaryKey(add []string, pkRequest PrimaryKeyRequest, spannerTable ddl.C
 				{
 					schemaissue := []internal.SchemaIssue{}
 
-					sessionState := session.GetSessionState()
 					schemaissue = sessionState.Conv.SchemaIssues[spannerTable.Name][pkey.ColId]
 
 					if len(schemaissue) > 0 {
@@ -132,7 +131,7 @@ func addPrimaryKey(add []string, pkRequest PrimaryKeyRequest, spannerTable ddl.C
 }
 
 // removePrimaryKey removes primary key from list of IndexKey.
-func removePrimaryKey(remove []string, spannerTable ddl.CreateTable) []ddl.IndexKey {
+func removePrimaryKey(sessionState *session.SessionState, remove []string, spannerTable ddl.CreateTable) []ddl.IndexKey {
 
 	list := spannerTable.PrimaryKeys
 
@@ -144,7 +143,6 @@ func removePrimaryKey(remove []string, spannerTable ddl.CreateTable) []ddl.Index
 
 				{
 					schemaissue := []internal.SchemaIssue{}
-					sessionState := session.GetSessionState()
 					schemaissue = sessionState.Conv.SchemaIssues[spannerTable.Name][spannerTable.PrimaryKeys[i].ColId]
 
 					if len(schemaissue) > 0 {
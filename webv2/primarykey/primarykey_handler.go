@@ -73,7 +73,7 @@ func PrimaryKey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	spannerTable, found := getSpannerTable(sessionState, pkRequest)
 
 	if !found {
@@ -110,7 +110,7 @@ func PrimaryKey(w http.ResponseWriter, r *http.Request) {
 		synthColId = synthCol.ColId
 	}
 
-	spannerTable, isSynthPkRemoved := updatePrimaryKey(pkRequest, spannerTable, synthColId)
+	spannerTable, isSynthPkRemoved := updatePrimaryKey(sessionState, pkRequest, spannerTable, synthColId)
 
 	if isSynthPkRemoved {
 		synthPks := sessionState.Conv.SyntheticPKeys
@@ -131,13 +131,13 @@ func PrimaryKey(w http.ResponseWriter, r *http.Request) {
 		if pkRequest.TableId == table.Id {
 			sessionState.Conv.SpSchema[table.Id] = spannerTable
 			for _, ind := range spannerTable.Indexes {
-				index.RemoveIndexIssues(spannerTable.Id, ind)
+				index.RemoveIndexIssues(sessionState, spannerTable.Id, ind)
 			}
 		}
 	}
 
 	RemoveInterleave(sessionState.Conv, spannerTable)
-	session.UpdateSessionFile()
+	session.UpdateSessionFile(sessionState)
 
 	convm := session.ConvWithMetadata{
 		SessionMetadata: sessionState.SessionMetadata,
@@ -16,6 +16,7 @@ package primarykey
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -30,7 +31,7 @@ import (
 
 func TestUpdatePrimaryKey(t *testing.T) {
 
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(context.Background())
 
 	c := &internal.Conv{
 
@@ -100,7 +101,7 @@ func TestUpdatePrimaryKey(t *testing.T) {
 
 func TestAddPrimaryKey(t *testing.T) {
 
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(context.Background())
 
 	c := &internal.Conv{
 
@@ -176,7 +177,7 @@ func TestAddPrimaryKey(t *testing.T) {
 
 func TestRemovePrimaryKey(t *testing.T) {
 
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(context.Background())
 
 	c := &internal.Conv{
 
@@ -251,7 +252,7 @@ func TestRemovePrimaryKey(t *testing.T) {
 
 func TestPrimarykey(t *testing.T) {
 
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(context.Background())
 
 	c := &internal.Conv{
 
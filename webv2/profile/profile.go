@@ -45,7 +45,7 @@ func ListConnectionProfiles(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("datastream client can not be created: %v", err), http.StatusBadRequest)
 	}
 	defer dsClient.Close()
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	source := r.FormValue("source") == "true"
 	if !source {
 		sessionState.Conv.Audit.MigrationRequestId = "HB-" + uuid.New().String()
@@ -91,7 +91,7 @@ func GetStaticIps(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("datastream client can not be created: %v", err), http.StatusBadRequest)
 	}
 	defer dsClient.Close()
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	req := &datastreampb.FetchStaticIpsRequest{
 		Name: fmt.Sprintf("projects/%s/locations/%s", sessionState.GCPProjectID, sessionState.Region),
 	}
@@ -133,7 +133,7 @@ func CreateConnectionProfile(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("datastream client can not be created: %v", err), http.StatusBadRequest)
 	}
 	defer dsClient.Close()
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	databaseType, err := helpers.GetSourceDatabaseFromDriver(sessionState.Driver)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error while getting source database: %v", err), http.StatusBadRequest)
@@ -155,7 +155,7 @@ func CreateConnectionProfile(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	setConnectionProfile(details.IsSource, *sessionState, req, databaseType)
+	setConnectionProfile(details.IsSource, sessionState, req, databaseType)
 	op, err := dsClient.CreateConnectionProfile(ctx, req)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error while creating connection profile: %v", err), http.StatusBadRequest)
@@ -169,7 +169,7 @@ func CreateConnectionProfile(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func setConnectionProfile(isSource bool, sessionState session.SessionState, req *datastreampb.CreateConnectionProfileRequest, databaseType string) {
+func setConnectionProfile(isSource bool, sessionState *session.SessionState, req *datastreampb.CreateConnectionProfileRequest, databaseType string) {
 	if isSource {
 		port, _ := strconv.ParseInt((sessionState.SourceDBConnDetails.Port), 10, 32)
 		if databaseType == constants.MYSQL {
@@ -215,7 +215,7 @@ func setConnectionProfile(isSource bool, sessionState session.SessionState, req
 
 func CleanUpStreamingJobs(w http.ResponseWriter, r *http.Request) {
 	ctx := context.Background()
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	err := streaming.CleanUpStreamingJobs(ctx, sessionState.Conv, sessionState.GCPProjectID, sessionState.Region)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error while cleaning up streaming jobs: %v", err), http.StatusBadRequest)
@@ -15,9 +15,12 @@
 package webv2
 
 import (
+	"fmt"
 	"io/fs"
 	"net/http"
 
+	"github.com/cloudspannerecosystem/harbourbridge/common/telemetry"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/auth"
 	"github.com/cloudspannerecosystem/harbourbridge/webv2/config"
 	"github.com/cloudspannerecosystem/harbourbridge/webv2/primarykey"
 	"github.com/cloudspannerecosystem/harbourbridge/webv2/profile"
@@ -28,48 +31,112 @@ import (
 	"github.com/gorilla/mux"
 )
 
-func getRoutes() *mux.Router {
-	router := mux.NewRouter().StrictSlash(true)
+// viewerRoutes are the routes that users with the viewer role can use:
+// those that show the DDL, reports and saved sessions. Viewers can load a
+// saved session into their own session to view it, but not edit it.
+var viewerRoutes = map[string]bool{
+	"/ddl":                    true,
+	"/conversion":             true,
+	"/typemap":                true,
+	"/report":                 true,
+	"/schema":                 true,
+	"/summary":                true,
+	"/IsOffline":              true,
+	"/GetSessions":            true,
+	"/GetSession/{versionId}": true,
+	"/GetSessionDiff/{fromVersionId}/{toVersionId}": true,
+	"/ResumeSession/{versionId}":                    true,
+	"/history":                                      true,
+	"/GetLatestSessionDetails":                      true,
+	"/metrics":                                      true,
+}
+
+// viewerAllowed returns whether users with the viewer role can send r.
+func viewerAllowed(r *http.Request) bool {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return false
+	}
+	tmpl, err := route.GetPathTemplate()
+	return err == nil && viewerRoutes[tmpl]
+}
+
+// unlessMigrating serves r with next, unless the session of r has a queued
+// or running migration job, which reads the Conv of the session: requests
+// that change the schema of the session are then rejected with a 409
+// status.
+func unlessMigrating(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionState := session.GetSessionState(r.Context())
+		if j, ok := migrationJobs.Active(sessionState.Id); ok {
+			http.Error(w, fmt.Sprintf("Can't change the schema while migration job %s of this session is %s", j.Id, j.State), http.StatusConflict)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// getRoutes returns the routes of the web server. If authenticator isn't
+// nil, API requests must be authenticated by it. The frontend itself is
+// served without authentication.
+func getRoutes(authenticator auth.Authenticator) *mux.Router {
+	root := mux.NewRouter().StrictSlash(true)
+	api := root.NewRoute().Subrouter()
+	if authenticator != nil {
+		api.Use(auth.Handler(authenticator, viewerAllowed))
+	}
+	// Most API routes are served with the state of the session of the
+	// request, which is locked while they are served. Routes that change
+	// the schema of the session are wrapped in unlessMigrating.
+	router := api.NewRoute().Subrouter()
+	router.Use(session.Handler)
 	frontendRoot, _ := fs.Sub(FrontendDir, "ui/dist/ui")
 	frontendStatic := http.FileServer(http.FS(frontendRoot))
 	router.HandleFunc("/connect", databaseConnection).Methods("POST")
-	router.HandleFunc("/convert/infoschema", convertSchemaSQL).Methods("GET")
-	router.HandleFunc("/convert/dump", convertSchemaDump).Methods("POST")
-	router.HandleFunc("/convert/session", loadSession).Methods("POST")
+	router.HandleFunc("/convert/infoschema", unlessMigrating(convertSchemaSQL)).Methods("GET")
+	router.HandleFunc("/convert/dump", unlessMigrating(convertSchemaDump)).Methods("POST")
+	router.HandleFunc("/convert/session", unlessMigrating(loadSession)).Methods("POST")
 	router.HandleFunc("/ddl", getDDL).Methods("GET")
 	router.HandleFunc("/conversion", getConversionRate).Methods("GET")
 	router.HandleFunc("/typemap", getTypeMap).Methods("GET")
 	router.HandleFunc("/report", getReportFile).Methods("GET")
 	router.HandleFunc("/schema", getSchemaFile).Methods("GET")
-	router.HandleFunc("/applyrule", applyRule).Methods("POST")
-	router.HandleFunc("/dropRule", dropRule).Methods("POST")
-	router.HandleFunc("/typemap/table", table.UpdateTableSchema).Methods("POST")
+	router.HandleFunc("/applyrule", unlessMigrating(session.RecordEdit("Apply rule", applyRule))).Methods("POST")
+	router.HandleFunc("/dropRule", unlessMigrating(session.RecordEdit("Drop rule", dropRule))).Methods("POST")
+	router.HandleFunc("/typemap/suggestions", getTypeSuggestions).Methods("GET")
+	router.HandleFunc("/typemap/table", unlessMigrating(session.RecordEdit("Update table", table.UpdateTableSchema))).Methods("POST")
 	router.HandleFunc("/typemap/reviewTableSchema", table.ReviewTableSchema).Methods("POST")
 	router.HandleFunc("/typemap/GetStandardTypeToPGSQLTypemap", getStandardTypeToPGSQLTypemap).Methods("GET")
 	router.HandleFunc("/typemap/GetPGSQLToStandardTypeTypemap", getPGSQLToStandardTypeTypemap).Methods("GET")
 
-	router.HandleFunc("/setparent", setParentTable).Methods("GET")
-	router.HandleFunc("/removeParent", removeParentTable).Methods("POST")
+	router.HandleFunc("/setparent", unlessMigrating(session.RecordEdit("Set parent of table", setParentTable))).Methods("GET")
+	router.HandleFunc("/removeParent", unlessMigrating(session.RecordEdit("Remove parent of table", removeParentTable))).Methods("POST")
 
 	// TODO:(searce) take constraint names themselves which are guaranteed to be unique for Spanner.
-	router.HandleFunc("/drop/secondaryindex", dropSecondaryIndex).Methods("POST")
-	router.HandleFunc("/restore/secondaryIndex", restoreSecondaryIndex).Methods("POST")
+	router.HandleFunc("/drop/secondaryindex", unlessMigrating(session.RecordEdit("Drop secondary index of table", dropSecondaryIndex))).Methods("POST")
+	router.HandleFunc("/restore/secondaryIndex", unlessMigrating(session.RecordEdit("Restore secondary index of table", restoreSecondaryIndex))).Methods("POST")
 
-	router.HandleFunc("/restore/table", restoreTable).Methods("POST")
-	router.HandleFunc("/drop/table", dropTable).Methods("POST")
+	router.HandleFunc("/restore/table", unlessMigrating(session.RecordEdit("Restore table", restoreTable))).Methods("POST")
+	router.HandleFunc("/drop/table", unlessMigrating(session.RecordEdit("Drop table", dropTable))).Methods("POST")
 
-	router.HandleFunc("/update/fks", updateForeignKeys).Methods("POST")
-	router.HandleFunc("/update/indexes", updateIndexes).Methods("POST")
+	router.HandleFunc("/update/fks", unlessMigrating(session.RecordEdit("Update foreign keys of table", updateForeignKeys))).Methods("POST")
+	router.HandleFunc("/update/indexes", unlessMigrating(session.RecordEdit("Update indexes of table", updateIndexes))).Methods("POST")
 
 	// Session Management
 	router.HandleFunc("/IsOffline", session.IsOfflineSession).Methods("GET")
 	router.HandleFunc("/GetSessions", session.GetSessions).Methods("GET")
 	router.HandleFunc("/GetSession/{versionId}", session.GetConv).Methods("GET")
+	router.HandleFunc("/GetSessionDiff/{fromVersionId}/{toVersionId}", session.GetSessionDiff).Methods("GET")
 	router.HandleFunc("/SaveRemoteSession", session.SaveRemoteSession).Methods("POST")
-	router.HandleFunc("/ResumeSession/{versionId}", session.ResumeSession).Methods("POST")
+	router.HandleFunc("/ResumeSession/{versionId}", unlessMigrating(session.ResumeSession)).Methods("POST")
+
+	// Edit history
+	router.HandleFunc("/undo", unlessMigrating(session.Undo)).Methods("POST")
+	router.HandleFunc("/redo", unlessMigrating(session.Redo)).Methods("POST")
+	router.HandleFunc("/history", session.GetEditHistory).Methods("GET")
 
 	// primarykey
-	router.HandleFunc("/primaryKey", primarykey.PrimaryKey).Methods("POST")
+	router.HandleFunc("/primaryKey", unlessMigrating(session.RecordEdit("Update primary key", primarykey.PrimaryKey))).Methods("POST")
 
 	// Summary
 	router.HandleFunc("/summary", summary.GetSummary).Methods("GET")
@@ -99,6 +166,19 @@ func getRoutes() *mux.Router {
 
 	router.HandleFunc("/uploadFile", uploadFile).Methods("POST")
 
-	router.PathPrefix("/").Handler(frontendStatic)
-	return router
+	// Migration jobs don't use the session state, so that their events can
+	// be streamed without blocking the other requests of the session. The
+	// handlers only see the jobs of the session id of the request.
+	api.HandleFunc("/jobs", migrationJobs.ListJobs).Methods("GET")
+	api.HandleFunc("/jobs/{jobId}", migrationJobs.GetJob).Methods("GET")
+	api.HandleFunc("/jobs/{jobId}/events", migrationJobs.JobEvents).Methods("GET")
+	api.HandleFunc("/jobs/{jobId}/cancel", migrationJobs.CancelJob).Methods("POST")
+
+	// Migration metrics, for Prometheus.
+	if telemetry.Enabled() {
+		api.Handle("/metrics", telemetry.Handler()).Methods("GET")
+	}
+
+	root.PathPrefix("/").Handler(frontendStatic)
+	return root
 }
@@ -0,0 +1,151 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+)
+
+// EditHistoryEntry describes an edit in the edit history of a session.
+type EditHistoryEntry struct {
+	Id          int
+	Description string
+	Time        time.Time
+	TableIds    []string // Tables changed by the edit.
+	Applied     bool     // False if the edit has been undone.
+}
+
+// statusRecorder records the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// RecordEdit wraps next, a handler that edits the schema of the session,
+// so that the edit is recorded in the edit history of the session if
+// next succeeds and changes the schema. The edit is described by
+// description, followed by the name of the table of the request, if any.
+func RecordEdit(description string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionState := GetSessionState(r.Context())
+		if sessionState.Conv == nil {
+			next(w, r)
+			return
+		}
+		conv := sessionState.Conv
+		before, err := conv.TakeSchemaSnapshot()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Can't record edit: %v", err), http.StatusInternalServerError)
+			return
+		}
+		tableId := r.URL.Query().Get("table")
+		if tableId == "" {
+			tableId = r.URL.Query().Get("tableId")
+		}
+		if tableId != "" {
+			description = fmt.Sprintf("%s %s", description, tableName(conv, tableId))
+		}
+		sr := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(sr, r)
+		// Edits that fail or load a new Conv aren't recorded.
+		if sr.status != http.StatusOK || sessionState.Conv != conv {
+			return
+		}
+		edit, err := conv.RecordEdit(description, before)
+		if err != nil {
+			log.Printf("can't record edit %q: %v", description, err)
+			return
+		}
+		if edit != nil {
+			UpdateSessionFile(sessionState)
+		}
+	}
+}
+
+// Undo reverts the last edit of the session, and returns the updated
+// session.
+func Undo(w http.ResponseWriter, r *http.Request) {
+	applyEdit(w, r, (*internal.Conv).UndoEdit)
+}
+
+// Redo reapplies the last undone edit of the session, and returns the
+// updated session.
+func Redo(w http.ResponseWriter, r *http.Request) {
+	applyEdit(w, r, (*internal.Conv).RedoEdit)
+}
+
+func applyEdit(w http.ResponseWriter, r *http.Request, apply func(*internal.Conv) (*internal.Edit, error)) {
+	sessionState := GetSessionState(r.Context())
+	if sessionState.Conv == nil {
+		http.Error(w, "Schema is not converted", http.StatusNotFound)
+		return
+	}
+	if _, err := apply(sessionState.Conv); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	UpdateSessionFile(sessionState)
+	convm := ConvWithMetadata{
+		SessionMetadata: sessionState.SessionMetadata,
+		Conv:            *sessionState.Conv,
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convm)
+}
+
+// GetEditHistory returns the edits of the session, oldest first.
+func GetEditHistory(w http.ResponseWriter, r *http.Request) {
+	sessionState := GetSessionState(r.Context())
+	entries := []EditHistoryEntry{}
+	if sessionState.Conv != nil {
+		h := sessionState.Conv.EditHistory
+		for i, edit := range h.Edits {
+			entry := EditHistoryEntry{
+				Id:          edit.Id,
+				Description: edit.Description,
+				Time:        edit.Time,
+				TableIds:    []string{},
+				Applied:     i < h.Applied,
+			}
+			for _, t := range edit.Tables {
+				entry.TableIds = append(entry.TableIds, t.TableId)
+			}
+			entries = append(entries, entry)
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
+
+func tableName(conv *internal.Conv, tableId string) string {
+	if spTable, ok := conv.SpSchema[tableId]; ok {
+		return spTable.Name
+	}
+	if srcTable, ok := conv.SrcSchema[tableId]; ok {
+		return srcTable.Name
+	}
+	return tableId
+}
@@ -18,11 +18,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 )
 
 const hbOutputDirPath string = "harbour_bridge_output"
 
 type localStore struct {
+	mu       sync.Mutex
 	sessions []SchemaConversionSession
 }
 
@@ -38,10 +40,14 @@ func NewLocalSessionStore() *localStore {
 }
 
 func (st *localStore) GetSessionsMetadata(ctx context.Context) ([]SchemaConversionSession, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
 	return st.sessions, nil
 }
 
 func (st *localStore) GetConvWithMetadata(ctx context.Context, versionId string) (ConvWithMetadata, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
 	var convm ConvWithMetadata
 	var match *SchemaConversionSession
 	for _, s := range st.sessions {
@@ -73,11 +79,15 @@ func (st *localStore) GetConvWithMetadata(ctx context.Context, versionId string)
 }
 
 func (st *localStore) SaveSession(ctx context.Context, scs SchemaConversionSession) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
 	st.sessions = append(st.sessions, scs)
 	return nil
 }
 
 func (st *localStore) IsSessionNameUnique(ctx context.Context, scs SchemaConversionSession) (bool, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
 	for _, s := range st.sessions {
 		if s.SessionName == scs.SessionName && s.DatabaseType == scs.DatabaseType && s.DatabaseName == scs.DatabaseName {
 			return false, nil
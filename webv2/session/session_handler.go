@@ -40,18 +40,23 @@ type SessionParams struct {
 	CreatedAt string `json:"createdAt"`
 }
 
+// CurrentVersionId stands for the current state of a session, where a
+// saved session version is expected.
+const CurrentVersionId = "current"
+
 func IsOfflineSession(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(GetSessionState().IsOffline)
+	json.NewEncoder(w).Encode(GetSessionState(r.Context()).IsOffline)
 }
 
 func GetSessions(w http.ResponseWriter, r *http.Request) {
 	var sessions []SchemaConversionSession
 	var err error
-	if GetSessionState().IsOffline {
+	sessionState := GetSessionState(r.Context())
+	if sessionState.IsOffline {
 		sessions, err = getLocalSessions()
 	} else {
-		sessions, err = getRemoteSessions()
+		sessions, err = getRemoteSessions(sessionState)
 	}
 	if err != nil {
 		http.Error(w, fmt.Sprintf("%v", err), http.StatusInternalServerError)
@@ -69,13 +74,7 @@ func GetConv(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var convm ConvWithMetadata
-	var err error
-	if GetSessionState().IsOffline {
-		convm, err = getLocalConv(vid)
-	} else {
-		convm, err = getRemoteConv(vid)
-	}
+	convm, err := getConv(GetSessionState(r.Context()), vid)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("%v", err), http.StatusInternalServerError)
 		return
@@ -84,6 +83,39 @@ func GetConv(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(convm)
 }
 
+// GetSessionDiff returns how the saved session version toVersionId differs
+// from fromVersionId: the changes to tables, columns, indexes, foreign keys,
+// type mappings and rules, and the Spanner DDL that applies them. The
+// version "current" stands for the current state of the session.
+func GetSessionDiff(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	sessionState := GetSessionState(r.Context())
+	var convs [2]*internal.Conv
+	for i, key := range []string{"fromVersionId", "toVersionId"} {
+		vid := vars[key]
+		if vid == "" {
+			http.Error(w, fmt.Sprintf("%s not supplied", key), http.StatusBadRequest)
+			return
+		}
+		if vid == CurrentVersionId {
+			if sessionState.Conv == nil {
+				http.Error(w, "Schema is not converted", http.StatusNotFound)
+				return
+			}
+			convs[i] = sessionState.Conv
+			continue
+		}
+		convm, err := getConv(sessionState, vid)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Can't load session version %s: %v", vid, err), http.StatusNotFound)
+			return
+		}
+		convs[i] = &convm.Conv
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(internal.DiffConv(convs[0], convs[1]))
+}
+
 func ResumeSession(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	vid, ok := vars["versionId"]
@@ -94,24 +126,18 @@ func ResumeSession(w http.ResponseWriter, r *http.Request) {
 
 	var convm ConvWithMetadata
 	var err error
-	if GetSessionState().IsOffline {
+	sessionState := GetSessionState(r.Context())
+	if sessionState.IsOffline {
 		convm, err = getLocalConv(vid)
 	} else {
-		convm, err = getRemoteConv(vid)
+		convm, err = getRemoteConv(sessionState, vid)
 	}
 	if err != nil {
 		http.Error(w, fmt.Sprintf("%v", err), http.StatusInternalServerError)
 		return
 	}
 
-	sessionState := GetSessionState()
-	sessionState.Conv = &convm.Conv
-	sessionState.Driver = convm.DatabaseType
-	sessionState.DbName = convm.DatabaseName
-	sessionState.SourceDBConnDetails = SourceDBConnDetails{
-		ConnectionType: helpers.SESSION_FILE_MODE,
-	}
-	sessionState.Conv.UsedNames = internal.ComputeUsedNames(sessionState.Conv)
+	resumeConv(sessionState, convm)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(convm)
@@ -133,21 +159,61 @@ func SaveRemoteSession(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := context.Background()
-	spannerClient, err := spanner.NewClient(ctx, getMetadataDbUri())
+	sessionState := GetSessionState(r.Context())
+	spannerClient, err := newMetadataDbClient(ctx, sessionState)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Spanner Client error : %v", err), http.StatusInternalServerError)
 		return
 	}
 	defer spannerClient.Close()
 
-	sessionState := GetSessionState()
 	ssvc := NewSessionService(ctx, NewRemoteSessionStore(spannerClient))
-	conv, err := json.Marshal(sessionState.Conv)
+	scs, err := newSchemaConversionSession(sessionState, sm)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Conv object error : %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	err = ssvc.SaveSession(scs)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Spanner Transaction error : %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	sessionMetaData := sessionState.SessionMetadata
+
+	sessionMetaData.DatabaseName = scs.DatabaseName
+	sessionMetaData.DatabaseType = scs.DatabaseType
+	sessionMetaData.SessionName = scs.SessionName
+	sessionMetaData.Dialect = scs.Dialect
+
+	sessionState.SessionMetadata = sessionMetaData
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Save successful, VersionId : " + scs.VersionId)
+}
+
+//Helpers
+
+// resumeConv makes convm the conversion of sessionState.
+func resumeConv(sessionState *SessionState, convm ConvWithMetadata) {
+	sessionState.Conv = &convm.Conv
+	sessionState.Driver = convm.DatabaseType
+	sessionState.DbName = convm.DatabaseName
+	sessionState.SourceDBConnDetails = SourceDBConnDetails{
+		ConnectionType: helpers.SESSION_FILE_MODE,
+	}
+	sessionState.Conv.UsedNames = internal.ComputeUsedNames(sessionState.Conv)
+}
+
+// newSchemaConversionSession returns a new version of the conversion of
+// sessionState, with metadata sm.
+func newSchemaConversionSession(sessionState *SessionState, sm SessionMetadata) (SchemaConversionSession, error) {
+	conv, err := json.Marshal(sessionState.Conv)
+	if err != nil {
+		return SchemaConversionSession{}, err
+	}
+
 	// TODO: To compute few metadata fields if empty
 	t := time.Now()
 
@@ -156,44 +222,30 @@ func SaveRemoteSession(w http.ResponseWriter, r *http.Request) {
 		sm.DatabaseType = constants.MYSQL
 	case constants.PGDUMP:
 		sm.DatabaseType = constants.POSTGRES
+	case constants.SQLSERVERDUMP:
+		sm.DatabaseType = constants.SQLSERVER
 	default:
 		sm.DatabaseType = sessionState.Driver
 	}
 
 	sm.Dialect = helpers.GetDialectDisplayStringFromDialect(sessionState.Dialect)
 
-	scs := SchemaConversionSession{
+	return SchemaConversionSession{
 		VersionId:              uuid.New().String(),
 		PreviousVersionId:      []string{},
 		SchemaConversionObject: string(conv),
 		CreateTimestamp:        t,
 		SessionMetadata:        sm,
-	}
-
-	err = ssvc.SaveSession(scs)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Spanner Transaction error : %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	sessionMetaData := GetSessionState().SessionMetadata
-
-	sessionMetaData.DatabaseName = sm.DatabaseName
-	sessionMetaData.DatabaseType = sm.DatabaseType
-	sessionMetaData.SessionName = sm.SessionName
-	sessionMetaData.Dialect = sm.Dialect
-
-	GetSessionState().SessionMetadata = sessionMetaData
-
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode("Save successful, VersionId : " + scs.VersionId)
+	}, nil
 }
 
-//Helpers
+func newMetadataDbClient(ctx context.Context, sessionState *SessionState) (*spanner.Client, error) {
+	return spanner.NewClient(ctx, getMetadataDbUri(sessionState))
+}
 
-func getRemoteSessions() ([]SchemaConversionSession, error) {
+func getRemoteSessions(sessionState *SessionState) ([]SchemaConversionSession, error) {
 	ctx := context.Background()
-	spannerClient, err := spanner.NewClient(ctx, getMetadataDbUri())
+	spannerClient, err := newMetadataDbClient(ctx, sessionState)
 	if err != nil {
 		return nil, fmt.Errorf("Spanner Client error : %v", err)
 	}
@@ -216,10 +268,10 @@ func getLocalSessions() ([]SchemaConversionSession, error) {
 	return result, nil
 }
 
-func getRemoteConv(versionId string) (ConvWithMetadata, error) {
+func getRemoteConv(sessionState *SessionState, versionId string) (ConvWithMetadata, error) {
 	var convm ConvWithMetadata
 	ctx := context.Background()
-	spannerClient, err := spanner.NewClient(ctx, getMetadataDbUri())
+	spannerClient, err := newMetadataDbClient(ctx, sessionState)
 	if err != nil {
 		return convm, err
 	}
@@ -233,6 +285,15 @@ func getRemoteConv(versionId string) (ConvWithMetadata, error) {
 	return convm, nil
 }
 
+// getConv returns session version versionId from the session store of
+// sessionState.
+func getConv(sessionState *SessionState, versionId string) (ConvWithMetadata, error) {
+	if sessionState.IsOffline {
+		return getLocalConv(versionId)
+	}
+	return getRemoteConv(sessionState, versionId)
+}
+
 func getLocalConv(versionId string) (ConvWithMetadata, error) {
 	svc := NewSessionService(context.Background(), NewLocalSessionStore())
 	result, err := svc.GetConvWithMetadata(versionId)
@@ -242,8 +303,7 @@ func getLocalConv(versionId string) (ConvWithMetadata, error) {
 	return result, nil
 }
 
-func getMetadataDbUri() string {
-	sessionState := GetSessionState()
+func getMetadataDbUri(sessionState *SessionState) string {
 	if sessionState.GCPProjectID == "" || sessionState.SpannerInstanceID == "" {
 		return ""
 	}
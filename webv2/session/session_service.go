@@ -50,21 +50,19 @@ func (ss *SessionService) GetConvWithMetadata(versionId string) (ConvWithMetadat
 	return ss.store.GetConvWithMetadata(ss.context, versionId)
 }
 
+// SetSessionStorageConnectionState sets the metadata database of all
+// sessions, and whether they are offline.
 func SetSessionStorageConnectionState(projectId string, spInstanceId string) (bool, bool) {
-	sessionState := GetSessionState()
-	sessionState.GCPProjectID = projectId
-	sessionState.SpannerInstanceID = spInstanceId
-	if projectId == "" || spInstanceId == "" {
-		sessionState.IsOffline = true
-		return false, false
-	} else {
-		if isExist, isDbCreated := helpers.CheckOrCreateMetadataDb(projectId, spInstanceId); isExist {
-			sessionState.IsOffline = false
-			isConfigValid := isExist || isDbCreated
-			return isDbCreated, isConfigValid
-		} else {
-			sessionState.IsOffline = true
-			return false, false
+	isOffline, isDbCreated, isConfigValid := true, false, false
+	if projectId != "" && spInstanceId != "" {
+		if isExist, created := helpers.CheckOrCreateMetadataDb(projectId, spInstanceId); isExist {
+			isOffline, isDbCreated, isConfigValid = false, created, true
 		}
 	}
+	forEachSessionState(func(sessionState *SessionState) {
+		sessionState.GCPProjectID = projectId
+		sessionState.SpannerInstanceID = spInstanceId
+		sessionState.IsOffline = isOffline
+	})
+	return isDbCreated, isConfigValid
 }
@@ -1,22 +1,231 @@
 package session
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
 	"sync"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/google/uuid"
+)
+
+const (
+	// SessionIdHeader is the request header that carries the session id.
+	// Requests without it use the SessionIdCookie cookie, which is set on
+	// the first response to a new client.
+	SessionIdHeader = "X-Session-Id"
+	SessionIdCookie = "hb_session_id"
 )
 
-var once sync.Once
+type sessionKey struct{}
+
+// evictedSession records where the state of an evicted session was saved.
+type evictedSession struct {
+	versionId string
+	remote    bool
+}
+
+// sessions maintains the state of each migration session, keyed by session
+// id, and is used to track state from one request to the next. The default
+// session (with an empty id) holds the server configuration that new
+// sessions start from, and is used by requests that don't carry a session,
+// e.g. in tests.
+var sessions = struct {
+	sync.Mutex
+	states  map[string]*SessionState
+	evicted map[string]evictedSession
+}{
+	states:  map[string]*SessionState{"": {Counter: Counter{ObjectId: "0"}}},
+	evicted: map[string]evictedSession{},
+}
 
-// sessionState maintains the current state of the session, and is used to
-// track state from one request to the next. Session state is global:
-// all requests see the same session state.
-var sessionState *SessionState
+// GetSessionState returns the state of the session of the request context
+// ctx, or the state of the default session if ctx has none.
+func GetSessionState(ctx context.Context) *SessionState {
+	if sessionState, ok := ctx.Value(sessionKey{}).(*SessionState); ok {
+		return sessionState
+	}
+	sessions.Lock()
+	defer sessions.Unlock()
+	return sessions.states[""]
+}
 
-func GetSessionState() *SessionState {
-	if sessionState == nil {
-		once.Do(
-			func() {
-				sessionState = &SessionState{}
-			})
+// WithSessionState returns a copy of ctx carrying sessionState.
+func WithSessionState(ctx context.Context, sessionState *SessionState) context.Context {
+	return context.WithValue(ctx, sessionKey{}, sessionState)
+}
+
+// Handler routes each request to the state of its session, creating the
+// session on first use. Requests of a session are serialized, so that
+// handlers can update the Conv of the session without further locking.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := RequestSessionId(r)
+		if id == "" {
+			id = uuid.New().String()
+			http.SetCookie(w, &http.Cookie{Name: SessionIdCookie, Value: id, Path: "/", HttpOnly: true, SameSite: http.SameSiteStrictMode})
+		}
+		w.Header().Set(SessionIdHeader, id)
+		sessionState := getOrCreateSessionState(id)
+		sessionState.mu.Lock()
+		defer sessionState.mu.Unlock()
+		sessionState.lastUsed = time.Now()
+		next.ServeHTTP(w, r.WithContext(WithSessionState(r.Context(), sessionState)))
+	})
+}
+
+// RequestSessionId returns the id of the session of request r, from its
+// SessionIdHeader header or SessionIdCookie cookie, or "" if it has none.
+// It doesn't create the session, nor wait for its other requests.
+func RequestSessionId(r *http.Request) string {
+	id := r.Header.Get(SessionIdHeader)
+	if c, err := r.Cookie(SessionIdCookie); id == "" && err == nil {
+		id = c.Value
+	}
+	return id
+}
+
+// getOrCreateSessionState returns the state of session id. A new session
+// starts from the configuration of the default session, and an evicted
+// session is restored from the session store it was saved to.
+func getOrCreateSessionState(id string) *SessionState {
+	sessions.Lock()
+	sessionState, ok := sessions.states[id]
+	evicted, wasEvicted := sessions.evicted[id]
+	defaults := sessions.states[""]
+	sessions.Unlock()
+	if ok {
+		return sessionState
+	}
+	sessionState = &SessionState{
+		Id:                id,
+		Conv:              internal.MakeConv(),
+		GCPProjectID:      defaults.GCPProjectID,
+		SpannerInstanceID: defaults.SpannerInstanceID,
+		IsOffline:         defaults.IsOffline,
+		Counter:           Counter{ObjectId: "0"},
+	}
+	if wasEvicted {
+		if err := restoreSessionState(sessionState, evicted); err != nil {
+			log.Printf("can't restore evicted session %s: %v", id, err)
+		}
+	}
+	sessions.Lock()
+	defer sessions.Unlock()
+	// Another request of the same session may have got here first.
+	if s, ok := sessions.states[id]; ok {
+		return s
 	}
+	sessions.states[id] = sessionState
+	delete(sessions.evicted, id)
 	return sessionState
 }
+
+func restoreSessionState(sessionState *SessionState, evicted evictedSession) error {
+	var convm ConvWithMetadata
+	var err error
+	if evicted.remote {
+		convm, err = getRemoteConv(sessionState, evicted.versionId)
+	} else {
+		convm, err = getLocalConv(evicted.versionId)
+	}
+	if err != nil {
+		return err
+	}
+	resumeConv(sessionState, convm)
+	sessionState.SessionMetadata = convm.SessionMetadata
+	return nil
+}
+
+// EvictIdleSessions saves the sessions that haven't been used for longer
+// than idle to the session store, and drops their state. Sessions without
+// a schema are dropped without being saved. An evicted session is restored
+// from the store on its next request. Sessions for which busy returns true,
+// such as sessions with a running migration job, are kept.
+func EvictIdleSessions(idle time.Duration, busy func(id string) bool) {
+	sessions.Lock()
+	var idleStates []*SessionState
+	for id, sessionState := range sessions.states {
+		if id != "" && time.Since(sessionState.lastUsed) > idle && !busy(id) {
+			idleStates = append(idleStates, sessionState)
+		}
+	}
+	sessions.Unlock()
+	for _, sessionState := range idleStates {
+		// Skip sessions with a request in flight.
+		if !sessionState.mu.TryLock() {
+			continue
+		}
+		evicted, err := evictSessionState(sessionState)
+		if err != nil {
+			log.Printf("can't save idle session %s, keeping it in memory: %v", sessionState.Id, err)
+			sessionState.mu.Unlock()
+			continue
+		}
+		sessions.Lock()
+		delete(sessions.states, sessionState.Id)
+		if evicted != nil {
+			sessions.evicted[sessionState.Id] = *evicted
+		}
+		sessions.Unlock()
+		sessionState.mu.Unlock()
+		log.Println("evicted idle session", sessionState.Id)
+	}
+}
+
+// StartSessionEviction evicts idle sessions in the background, checking
+// every minute, until ctx is done. See EvictIdleSessions for busy.
+func StartSessionEviction(ctx context.Context, idle time.Duration, busy func(id string) bool) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				EvictIdleSessions(idle, busy)
+			}
+		}
+	}()
+}
+
+// evictSessionState saves sessionState to the remote session store, or to
+// the local one if the session is offline.
+func evictSessionState(sessionState *SessionState) (*evictedSession, error) {
+	if sessionState.Conv == nil || len(sessionState.Conv.SrcSchema) == 0 {
+		return nil, nil
+	}
+	sm := sessionState.SessionMetadata
+	sm.SessionName = fmt.Sprintf("idle-%s-%s", sessionState.Id, time.Now().Format("20060102150405"))
+	sm.DatabaseName = sessionState.DbName
+	scs, err := newSchemaConversionSession(sessionState, sm)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	if sessionState.IsOffline {
+		err = NewSessionService(ctx, NewLocalSessionStore()).SaveSession(scs)
+		return &evictedSession{versionId: scs.VersionId}, err
+	}
+	spannerClient, err := newMetadataDbClient(ctx, sessionState)
+	if err != nil {
+		return nil, err
+	}
+	defer spannerClient.Close()
+	err = NewSessionService(ctx, NewRemoteSessionStore(spannerClient)).SaveSession(scs)
+	return &evictedSession{versionId: scs.VersionId, remote: true}, err
+}
+
+// forEachSessionState calls f on the state of every session, including the
+// default one.
+func forEachSessionState(f func(*SessionState)) {
+	sessions.Lock()
+	defer sessions.Unlock()
+	for _, sessionState := range sessions.states {
+		f(sessionState)
+	}
+}
@@ -1,15 +1,22 @@
 package session_test
 
 import (
+	"context"
 	"encoding/json"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
 	"github.com/cloudspannerecosystem/harbourbridge/webv2/session"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
 )
 
 func getTestData() []session.SchemaConversionSession {
@@ -169,3 +176,162 @@ func TestSaveSession(t *testing.T) {
 		t.Errorf("Expected: %d, got: %d", expect, got)
 	}
 }
+
+// serveSession sends a request of session id to a handler that sets the
+// database name of the session to dbName, if not empty, and returns the
+// recorded response and the state the handler saw.
+func serveSession(id, dbName string) (*httptest.ResponseRecorder, *session.SessionState) {
+	var sessionState *session.SessionState
+	handler := session.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sessionState = session.GetSessionState(r.Context())
+		if dbName != "" {
+			sessionState.DbName = dbName
+		}
+	}))
+	req := httptest.NewRequest("GET", "/ddl", nil)
+	if id != "" {
+		req.Header.Set(session.SessionIdHeader, id)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr, sessionState
+}
+
+func TestHandler(t *testing.T) {
+	_, a := serveSession("a", "dbA")
+	_, b := serveSession("b", "dbB")
+	assert.NotSame(t, a, b)
+	assert.NotSame(t, session.GetSessionState(context.Background()), a)
+
+	_, got := serveSession("a", "")
+	assert.Same(t, a, got)
+	assert.Equal(t, "a", got.Id)
+	assert.Equal(t, "dbA", got.DbName)
+	assert.NotNil(t, got.Conv)
+
+	// A request without a session id starts a new session, whose id is
+	// returned in a cookie.
+	rr, got := serveSession("", "")
+	cookies := rr.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, session.SessionIdCookie, cookies[0].Name)
+	assert.Equal(t, got.Id, cookies[0].Value)
+	assert.Equal(t, got.Id, rr.Header().Get(session.SessionIdHeader))
+}
+
+func TestEvictIdleSessions(t *testing.T) {
+	defaults := session.GetSessionState(context.Background())
+	defaults.IsOffline = true
+	defer func() { defaults.IsOffline = false }()
+
+	_, sessionState := serveSession("idle", "dbIdle")
+	sessionState.Driver = constants.MYSQL
+	sessionState.Conv.SrcSchema["t1"] = schema.Table{Name: "table1", Id: "t1"}
+
+	notBusy := func(string) bool { return false }
+	session.EvictIdleSessions(time.Hour, notBusy)
+	_, got := serveSession("idle", "")
+	assert.Same(t, sessionState, got)
+
+	// Busy sessions, e.g. with a running migration job, are kept.
+	session.EvictIdleSessions(0, func(id string) bool { return id == "idle" })
+	_, got = serveSession("idle", "")
+	assert.Same(t, sessionState, got)
+
+	session.EvictIdleSessions(0, notBusy)
+	_, got = serveSession("idle", "")
+	assert.NotSame(t, sessionState, got)
+	assert.Equal(t, "idle", got.Id)
+	assert.Equal(t, "dbIdle", got.DbName)
+	assert.Equal(t, constants.MYSQL, got.Driver)
+	assert.Equal(t, "table1", got.Conv.SrcSchema["t1"].Name)
+}
+
+func TestEditHistoryHandlers(t *testing.T) {
+	sessionState := session.GetSessionState(context.Background())
+	sessionState.Conv = internal.MakeConv()
+	sessionState.Conv.SpSchema["t1"] = ddl.CreateTable{Name: "table1", Id: "t1"}
+	sessionState.Driver = constants.MYSQL
+	defer os.RemoveAll("harbour_bridge_output")
+
+	dropTable := session.RecordEdit("Drop table", func(w http.ResponseWriter, r *http.Request) {
+		delete(session.GetSessionState(r.Context()).Conv.SpSchema, r.URL.Query().Get("table"))
+	})
+	failedEdit := session.RecordEdit("Failed edit", func(w http.ResponseWriter, r *http.Request) {
+		session.GetSessionState(r.Context()).Conv.SpSchema["t2"] = ddl.CreateTable{Name: "table2", Id: "t2"}
+		http.Error(w, "failed", http.StatusBadRequest)
+	})
+	serve := func(handler http.HandlerFunc, method, url string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest(method, url, nil))
+		return rr
+	}
+	history := func() []session.EditHistoryEntry {
+		var entries []session.EditHistoryEntry
+		rr := serve(session.GetEditHistory, "GET", "/history")
+		assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &entries))
+		return entries
+	}
+
+	assert.Equal(t, http.StatusBadRequest, serve(session.Undo, "POST", "/undo").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(failedEdit, "POST", "/edit").Code)
+	assert.Empty(t, history())
+
+	assert.Equal(t, http.StatusOK, serve(dropTable, "POST", "/drop/table?table=t1").Code)
+	_, ok := sessionState.Conv.SpSchema["t1"]
+	assert.False(t, ok)
+	entries := history()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "Drop table table1", entries[0].Description)
+	assert.Equal(t, []string{"t1"}, entries[0].TableIds)
+	assert.True(t, entries[0].Applied)
+
+	rr := serve(session.Undo, "POST", "/undo")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var convm session.ConvWithMetadata
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &convm))
+	assert.Equal(t, "table1", convm.SpSchema["t1"].Name)
+	assert.Equal(t, "table1", sessionState.Conv.SpSchema["t1"].Name)
+	assert.False(t, history()[0].Applied)
+
+	assert.Equal(t, http.StatusOK, serve(session.Redo, "POST", "/redo").Code)
+	_, ok = sessionState.Conv.SpSchema["t1"]
+	assert.False(t, ok)
+	assert.True(t, history()[0].Applied)
+	assert.Equal(t, http.StatusBadRequest, serve(session.Redo, "POST", "/redo").Code)
+}
+
+func TestGetSessionDiff(t *testing.T) {
+	sessionState := session.GetSessionState(context.Background())
+	sessionState.IsOffline = true
+	defer func() { sessionState.IsOffline = false }()
+
+	conv := internal.MakeConv()
+	conv.SpSchema["t1"] = ddl.CreateTable{Name: "table1", Id: "t1", ColDefs: map[string]ddl.ColumnDef{}}
+	convStr, _ := json.Marshal(conv)
+	store := session.NewLocalSessionStore()
+	store.SaveSession(nil, session.SchemaConversionSession{
+		VersionId:              "diff-v1",
+		CreateTimestamp:        time.Now(),
+		SchemaConversionObject: string(convStr),
+		SessionMetadata:        session.SessionMetadata{SessionName: "diff-1", DatabaseName: "db", DatabaseType: "mysql"},
+	})
+	sessionState.Conv = internal.MakeConv()
+
+	diff := func(from, to string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/GetSessionDiff/"+from+"/"+to, nil)
+		req = mux.SetURLVars(req, map[string]string{"fromVersionId": from, "toVersionId": to})
+		rr := httptest.NewRecorder()
+		session.GetSessionDiff(rr, req)
+		return rr
+	}
+
+	rr := diff("diff-v1", session.CurrentVersionId)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var d internal.ConvDiff
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &d))
+	assert.Equal(t, []internal.TableDiff{{TableId: "t1", Name: "table1", Change: internal.DiffDropped}}, d.Tables)
+	assert.Equal(t, []string{"DROP TABLE table1"}, d.DDL)
+
+	assert.Equal(t, http.StatusNotFound, diff("diff-v1", "no-such-version").Code)
+}
@@ -2,6 +2,7 @@ package session
 
 import (
 	"database/sql"
+	"sync"
 	"time"
 
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
@@ -40,8 +41,9 @@ type SourceDBConnDetails struct {
 	ConnectionType string
 }
 
-// SessionState stores information for the current migration session.
+// SessionState stores information for a migration session.
 type SessionState struct {
+	Id                  string              // Session id, empty for the default session
 	SourceDB            *sql.DB             // Connection to source database in case of direct connection
 	SourceDBConnDetails SourceDBConnDetails // Connection details for source database
 	DbName              string              // Name of source database
@@ -59,6 +61,8 @@ type SessionState struct {
 	SessionMetadata     SessionMetadata
 	Error               error
 	Counter
+	mu       sync.Mutex // Serializes the requests of the session
+	lastUsed time.Time  // Time of the last request of the session
 }
 
 // Counter used to generate id for table, column, Foreignkey and indexes.
@@ -3,10 +3,12 @@ package summary
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/session"
 )
 
 // getSummary returns table wise summary of conversion.
 func GetSummary(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(getSummary())
+	json.NewEncoder(w).Encode(getSummary(session.GetSessionState(r.Context())))
 }
@@ -6,8 +6,7 @@ import (
 )
 
 // getSummary returns table wise summary of conversion.
-func getSummary() map[string]ConversionSummary {
-	sessionState := session.GetSessionState()
+func getSummary(sessionState *session.SessionState) map[string]ConversionSummary {
 	reports := reports.AnalyzeTables(sessionState.Conv, nil)
 
 	summary := make(map[string]ConversionSummary)
@@ -24,7 +24,7 @@ func renameColumn(newName, tableId, colId string, conv *internal.Conv) {
 	sp := conv.SpSchema[tableId]
 
 	// update interleave table relation.
-	isParent, childTableId := IsParent(tableId)
+	isParent, childTableId := IsParent(conv, tableId)
 
 	if isParent {
 		childColId, err := getColIdFromSpannerName(conv, childTableId, sp.ColDefs[colId].Name)
@@ -22,7 +22,7 @@ import (
 )
 
 // ReviewColumnNameType review update of colum type to given newType.
-func ReviewColumnType(newType, tableId, colId string, conv *internal.Conv, interleaveTableSchema []InterleaveTableSchema, w http.ResponseWriter) (_ []InterleaveTableSchema, err error) {
+func ReviewColumnType(driver, newType, tableId, colId string, conv *internal.Conv, interleaveTableSchema []InterleaveTableSchema, w http.ResponseWriter) (_ []InterleaveTableSchema, err error) {
 	sp := conv.SpSchema[tableId]
 
 	// review update of column type for refer table.
@@ -31,7 +31,7 @@ func ReviewColumnType(newType, tableId, colId string, conv *internal.Conv, inter
 		if fkReferColPosition == -1 {
 			continue
 		}
-		err = reviewColumnTypeChangeTableSchema(conv, fk.ReferTableId, fk.ReferColumnIds[fkReferColPosition], newType)
+		err = reviewColumnTypeChangeTableSchema(conv, driver, fk.ReferTableId, fk.ReferColumnIds[fkReferColPosition], newType)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return interleaveTableSchema, err
@@ -46,7 +46,7 @@ func ReviewColumnType(newType, tableId, colId string, conv *internal.Conv, inter
 				if fkColPosition == -1 {
 					continue
 				}
-				err = reviewColumnTypeChangeTableSchema(conv, sp.Id, sp.ForeignKeys[j].ColIds[fkColPosition], newType)
+				err = reviewColumnTypeChangeTableSchema(conv, driver, sp.Id, sp.ForeignKeys[j].ColIds[fkColPosition], newType)
 				if err != nil {
 					http.Error(w, err.Error(), http.StatusBadRequest)
 					return interleaveTableSchema, err
@@ -56,12 +56,12 @@ func ReviewColumnType(newType, tableId, colId string, conv *internal.Conv, inter
 	}
 
 	// review update of column type for child talbe.
-	isParent, childTableId := IsParent(tableId)
+	isParent, childTableId := IsParent(conv, tableId)
 	if isParent {
 		childColId, err := getColIdFromSpannerName(conv, childTableId, sp.ColDefs[colId].Name)
 		if err == nil {
 			previousType := conv.SpSchema[childTableId].ColDefs[childColId].T.Name
-			err = reviewColumnTypeChangeTableSchema(conv, childTableId, childColId, newType)
+			err = reviewColumnTypeChangeTableSchema(conv, driver, childTableId, childColId, newType)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusBadRequest)
 				return interleaveTableSchema, err
@@ -79,7 +79,7 @@ func ReviewColumnType(newType, tableId, colId string, conv *internal.Conv, inter
 		parentColId, err := getColIdFromSpannerName(conv, parentTableId, sp.ColDefs[colId].Name)
 		if err == nil {
 			previousType := conv.SpSchema[parentTableId].ColDefs[parentColId].T.Name
-			err = reviewColumnTypeChangeTableSchema(conv, parentTableId, parentColId, newType)
+			err = reviewColumnTypeChangeTableSchema(conv, driver, parentTableId, parentColId, newType)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusBadRequest)
 				return interleaveTableSchema, err
@@ -93,7 +93,7 @@ func ReviewColumnType(newType, tableId, colId string, conv *internal.Conv, inter
 
 	// review update of column type for curren table.
 	previousType := conv.SpSchema[tableId].ColDefs[colId].T.Name
-	err = reviewColumnTypeChangeTableSchema(conv, tableId, colId, newType)
+	err = reviewColumnTypeChangeTableSchema(conv, driver, tableId, colId, newType)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return interleaveTableSchema, err
@@ -109,8 +109,8 @@ func ReviewColumnType(newType, tableId, colId string, conv *internal.Conv, inter
 }
 
 // reviewColumnTypeChangeTableSchema review update of column type to given newType.
-func reviewColumnTypeChangeTableSchema(conv *internal.Conv, tableId string, colId string, newType string) error {
-	sp, ty, err := utilities.GetType(conv, newType, tableId, colId)
+func reviewColumnTypeChangeTableSchema(conv *internal.Conv, driver, tableId string, colId string, newType string) error {
+	sp, ty, err := utilities.GetType(conv, driver, newType, tableId, colId)
 
 	if err != nil {
 		return err
@@ -118,6 +118,7 @@ func reviewColumnTypeChangeTableSchema(conv *internal.Conv, tableId string, colI
 
 	colDef := sp.ColDefs[colId]
 	colDef.T = ty
+	colDef.Checks = utilities.GetChecks(conv, driver, tableId, colId, ty)
 	sp.ColDefs[colId] = colDef
 	conv.SpSchema[tableId] = sp
 
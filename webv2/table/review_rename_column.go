@@ -24,7 +24,7 @@ func reviewRenameColumn(newName, tableId, colId string, conv *internal.Conv, int
 	sp := conv.SpSchema[tableId]
 
 	// review column name update for interleaved child.
-	isParent, childTableId := IsParent(tableId)
+	isParent, childTableId := IsParent(conv, tableId)
 
 	if isParent {
 		childColId, err := getColIdFromSpannerName(conv, childTableId, sp.ColDefs[colId].Name)
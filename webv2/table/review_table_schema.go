@@ -61,7 +61,7 @@ func ReviewTableSchema(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 
 	var conv *internal.Conv
 
@@ -108,7 +108,7 @@ func ReviewTableSchema(w http.ResponseWriter, r *http.Request) {
 
 		if v.ToType != "" {
 
-			typeChange, err := utilities.IsTypeChanged(v.ToType, tableId, colId, conv)
+			typeChange, err := utilities.IsTypeChanged(sessionState.Driver, v.ToType, tableId, colId, conv)
 
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusBadRequest)
@@ -117,7 +117,7 @@ func ReviewTableSchema(w http.ResponseWriter, r *http.Request) {
 
 			if typeChange {
 
-				interleaveTableSchema, err = ReviewColumnType(v.ToType, tableId, colId, conv, interleaveTableSchema, w)
+				interleaveTableSchema, err = ReviewColumnType(sessionState.Driver, v.ToType, tableId, colId, conv, interleaveTableSchema, w)
 				if err != nil {
 					return
 				}
@@ -129,7 +129,7 @@ func ReviewTableSchema(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	ddl := GetSpannerTableDDL(conv.SpSchema[tableId], conv.SpDialect)
+	ddl := GetSpannerTableDDL(conv.SpSchema[tableId], conv.SpDialect, sessionState.Conv.SpSchema)
 
 	interleaveTableSchema = trimRedundantInterleaveTableSchema(interleaveTableSchema)
 	// update interleaveTableSchema by filling the missing fields.
@@ -140,13 +140,13 @@ func ReviewTableSchema(w http.ResponseWriter, r *http.Request) {
 		Changes: interleaveTableSchema,
 	}
 
-	sessionMetaData := session.GetSessionState().SessionMetadata
+	sessionMetaData := sessionState.SessionMetadata
 	if sessionMetaData.DatabaseName == "" || sessionMetaData.DatabaseType == "" || sessionMetaData.SessionName == "" {
 		sessionMetaData.DatabaseName = sessionState.DbName
 		sessionMetaData.DatabaseType = sessionState.Driver
 		sessionMetaData.SessionName = "NewSession"
 	}
-	session.GetSessionState().SessionMetadata = sessionMetaData
+	sessionState.SessionMetadata = sessionMetaData
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(resp)
 }
@@ -15,6 +15,7 @@
 package table
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -340,7 +341,7 @@ func TestReviewTableSchema(t *testing.T) {
 
 	for _, tc := range tc {
 
-		sessionState := session.GetSessionState()
+		sessionState := session.GetSessionState(context.Background())
 		sessionState.Conv = tc.conv
 		sessionState.Driver = constants.MYSQL
 
@@ -368,7 +369,7 @@ func TestReviewTableSchema(t *testing.T) {
 				status, tc.statusCode)
 		}
 
-		expectedddl := GetSpannerTableDDL(tc.expectedConv.SpSchema[tc.tableId], tc.expectedConv.SpDialect)
+		expectedddl := GetSpannerTableDDL(tc.expectedConv.SpSchema[tc.tableId], tc.expectedConv.SpDialect, tc.expectedConv.SpSchema)
 
 		if tc.statusCode == http.StatusOK {
 			assert.Equal(t, expectedddl, res.DDL)
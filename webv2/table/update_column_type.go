@@ -22,11 +22,11 @@ import (
 )
 
 // UpdateColumnType updates type of given column to newType.
-func UpdateColumnType(newType, tableId, colId string, conv *internal.Conv, w http.ResponseWriter) {
+func UpdateColumnType(driver, newType, tableId, colId string, conv *internal.Conv, w http.ResponseWriter) {
 	sp := conv.SpSchema[tableId]
 
 	// update column type for current table.
-	err := UpdateColumnTypeChangeTableSchema(conv, tableId, colId, newType, w)
+	err := UpdateColumnTypeChangeTableSchema(conv, driver, tableId, colId, newType, w)
 	if err != nil {
 		return
 	}
@@ -37,7 +37,7 @@ func UpdateColumnType(newType, tableId, colId string, conv *internal.Conv, w htt
 		if fkReferColPosition == -1 {
 			continue
 		}
-		err = UpdateColumnTypeChangeTableSchema(conv, fk.ReferTableId, fk.ReferColumnIds[fkReferColPosition], newType, w)
+		err = UpdateColumnTypeChangeTableSchema(conv, driver, fk.ReferTableId, fk.ReferColumnIds[fkReferColPosition], newType, w)
 		if err != nil {
 			return
 		}
@@ -51,17 +51,17 @@ func UpdateColumnType(newType, tableId, colId string, conv *internal.Conv, w htt
 				if fkColPosition == -1 {
 					continue
 				}
-				UpdateColumnTypeChangeTableSchema(conv, sp.Name, sp.ForeignKeys[j].ColIds[fkColPosition], newType, w)
+				UpdateColumnTypeChangeTableSchema(conv, driver, sp.Name, sp.ForeignKeys[j].ColIds[fkColPosition], newType, w)
 			}
 		}
 	}
 
 	// update column type of child table.
-	isParent, childTableId := IsParent(tableId)
+	isParent, childTableId := IsParent(conv, tableId)
 	if isParent {
 		childColId, err := getColIdFromSpannerName(conv, childTableId, sp.ColDefs[colId].Name)
 		if err == nil {
-			err = UpdateColumnTypeChangeTableSchema(conv, childTableId, childColId, newType, w)
+			err = UpdateColumnTypeChangeTableSchema(conv, driver, childTableId, childColId, newType, w)
 			if err != nil {
 				return
 			}
@@ -73,7 +73,7 @@ func UpdateColumnType(newType, tableId, colId string, conv *internal.Conv, w htt
 	if parentTableId != "" {
 		parentColId, err := getColIdFromSpannerName(conv, parentTableId, sp.ColDefs[colId].Name)
 		if err == nil {
-			err = UpdateColumnTypeChangeTableSchema(conv, parentTableId, parentColId, newType, w)
+			err = UpdateColumnTypeChangeTableSchema(conv, driver, parentTableId, parentColId, newType, w)
 			if err != nil {
 				return
 			}
@@ -82,9 +82,9 @@ func UpdateColumnType(newType, tableId, colId string, conv *internal.Conv, w htt
 }
 
 // UpdateColumnTypeTableSchema updates column type to newtype for a column of a table.
-func UpdateColumnTypeChangeTableSchema(conv *internal.Conv, tableId string, colId string, newType string, w http.ResponseWriter) error {
+func UpdateColumnTypeChangeTableSchema(conv *internal.Conv, driver, tableId string, colId string, newType string, w http.ResponseWriter) error {
 
-	sp, ty, err := utilities.GetType(conv, newType, tableId, colId)
+	sp, ty, err := utilities.GetType(conv, driver, newType, tableId, colId)
 
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -93,6 +93,7 @@ func UpdateColumnTypeChangeTableSchema(conv *internal.Conv, tableId string, colI
 
 	colDef := sp.ColDefs[colId]
 	colDef.T = ty
+	colDef.Checks = utilities.GetChecks(conv, driver, tableId, colId, ty)
 	sp.ColDefs[colId] = colDef
 	conv.SpSchema[tableId] = sp
 
@@ -68,7 +68,7 @@ func UpdateTableSchema(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 
 	var conv *internal.Conv
 	conv = nil
@@ -95,7 +95,7 @@ func UpdateTableSchema(w http.ResponseWriter, r *http.Request) {
 
 		if v.ToType != "" {
 
-			typeChange, err := utilities.IsTypeChanged(v.ToType, tableId, colId, conv)
+			typeChange, err := utilities.IsTypeChanged(sessionState.Driver, v.ToType, tableId, colId, conv)
 
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusBadRequest)
@@ -104,7 +104,7 @@ func UpdateTableSchema(w http.ResponseWriter, r *http.Request) {
 
 			if typeChange {
 
-				UpdateColumnType(v.ToType, tableId, colId, conv, w)
+				UpdateColumnType(sessionState.Driver, v.ToType, tableId, colId, conv, w)
 
 			}
 		}
@@ -117,7 +117,7 @@ func UpdateTableSchema(w http.ResponseWriter, r *http.Request) {
 	delete(conv.SpSchema[tableId].ColDefs, "")
 	sessionState.Conv = conv
 
-	session.UpdateSessionFile()
+	session.UpdateSessionFile(sessionState)
 
 	convm := session.ConvWithMetadata{
 		SessionMetadata: sessionState.SessionMetadata,
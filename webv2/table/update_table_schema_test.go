@@ -15,6 +15,7 @@
 package table
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -387,7 +388,7 @@ func TestUpdateTableSchema(t *testing.T) {
 
 	for _, tc := range tc {
 
-		sessionState := session.GetSessionState()
+		sessionState := session.GetSessionState(context.Background())
 		sessionState.Conv = tc.conv
 		sessionState.Driver = constants.MYSQL
 
@@ -19,7 +19,6 @@ import (
 
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
-	"github.com/cloudspannerecosystem/harbourbridge/webv2/session"
 )
 
 const (
@@ -40,11 +39,10 @@ func IsColumnPresentInColNames(colIds []string, colId string) bool {
 }
 
 // GetSpannerTableDDL return Spanner Table DDL as string.
-func GetSpannerTableDDL(spannerTable ddl.CreateTable, spDialect string) string {
-	sessionState := session.GetSessionState()
+func GetSpannerTableDDL(spannerTable ddl.CreateTable, spDialect string, spSchema ddl.Schema) string {
 	c := ddl.Config{Comments: true, ProtectIds: false, SpDialect: spDialect}
 
-	ddl := spannerTable.PrintCreateTable(sessionState.Conv.SpSchema, c)
+	ddl := spannerTable.PrintCreateTable(spSchema, c)
 
 	return ddl
 }
@@ -217,10 +215,8 @@ func UpdateNotNull(notNullChange, tableId, colId string, conv *internal.Conv) {
 	}
 }
 
-func IsParent(tableId string) (bool, string) {
-	sessionState := session.GetSessionState()
-
-	for _, spSchema := range sessionState.Conv.SpSchema {
+func IsParent(conv *internal.Conv, tableId string) (bool, string) {
+	for _, spSchema := range conv.SpSchema {
 		if spSchema.ParentId == tableId {
 			return true, spSchema.Id
 		}
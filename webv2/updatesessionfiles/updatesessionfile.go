@@ -25,8 +25,7 @@ import (
 
 // UpdateSessionFile updates the content of session file with
 // latest sessionState.Conv while also dumping schemas and report.
-func UpdateSessionFile() error {
-	sessionState := session.GetSessionState()
+func UpdateSessionFile(sessionState *session.SessionState) error {
 
 	ioHelper := &utils.IOStreams{In: os.Stdin, Out: os.Stdout}
 	_, err := conversion.WriteConvGeneratedFiles(sessionState.Conv, sessionState.DbName, sessionState.Driver, ioHelper.BytesRead, ioHelper.Out)
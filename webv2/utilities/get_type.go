@@ -20,41 +20,33 @@ import (
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/dynamodb"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/mysql"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/oracle"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/postgres"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/spanner"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/sqlite"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/sqlserver"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
-	"github.com/cloudspannerecosystem/harbourbridge/webv2/session"
 )
 
-func GetType(conv *internal.Conv, newType, tableId, colId string) (ddl.CreateTable, ddl.Type, error) {
-	sessionState := session.GetSessionState()
-
+// GetType returns the Spanner table of column colId of table tableId, and
+// the type it gets when it is mapped to newType from source driver.
+func GetType(conv *internal.Conv, driver, newType, tableId, colId string) (ddl.CreateTable, ddl.Type, error) {
 	sp := conv.SpSchema[tableId]
 	srcCol := conv.SrcSchema[tableId].ColDefs[colId]
 	var ty ddl.Type
-	var issues []internal.SchemaIssue
-	var toddl common.ToDdl
-	switch sessionState.Driver {
-	case constants.MYSQL, constants.MYSQLDUMP:
-		toddl = mysql.InfoSchemaImpl{}.GetToDdl()
-		ty, issues = toddl.ToSpannerType(conv, newType, srcCol.Type)
-	case constants.PGDUMP, constants.POSTGRES:
-		toddl = postgres.InfoSchemaImpl{}.GetToDdl()
-		ty, issues = toddl.ToSpannerType(conv, newType, srcCol.Type)
-	case constants.SQLSERVER:
-		toddl = sqlserver.InfoSchemaImpl{}.GetToDdl()
-		ty, issues = toddl.ToSpannerType(conv, newType, srcCol.Type)
-	case constants.ORACLE:
-		toddl = oracle.InfoSchemaImpl{}.GetToDdl()
-		ty, issues = toddl.ToSpannerType(conv, newType, srcCol.Type)
-	default:
-		return sp, ty, fmt.Errorf("driver : '%s' is not supported", sessionState.Driver)
+	toddl, err := getToDdl(driver)
+	if err != nil {
+		return sp, ty, err
 	}
-	if len(srcCol.Type.ArrayBounds) > 0 && conv.SpDialect == constants.DIALECT_POSTGRESQL {
+	ty, issues := toddl.ToSpannerType(conv, newType, srcCol.Type)
+	// Arrays of composite types are mapped to a single JSON value by
+	// ToSpannerType.
+	isComposite := len(srcCol.Type.Fields) > 0
+	if len(srcCol.Type.ArrayBounds) > 0 && conv.SpDialect == constants.DIALECT_POSTGRESQL && !isComposite {
 		ty = ddl.Type{Name: ddl.String, Len: ddl.MaxLength}
-	} else if len(srcCol.Type.ArrayBounds) > 1 {
+	} else if len(srcCol.Type.ArrayBounds) > 1 && !isComposite {
 		ty = ddl.Type{Name: ddl.String, Len: ddl.MaxLength}
 		issues = append(issues, internal.MultiDimensionalArray)
 	}
@@ -64,9 +56,51 @@ func GetType(conv *internal.Conv, newType, tableId, colId string) (ddl.CreateTab
 	if srcCol.Ignored.AutoIncrement {
 		issues = append(issues, internal.AutoIncrement)
 	}
+	// DynamoDB set types are mapped to arrays by ToSpannerType itself
+	// since they don't have array bounds.
+	ty.IsArray = ty.IsArray || (len(srcCol.Type.ArrayBounds) == 1 && !isComposite)
+	if tc, ok := toddl.(common.ToDdlChecks); ok {
+		_, checkIssues := tc.ToSpannerChecks(conv, srcCol, ty)
+		issues = append(issues, checkIssues...)
+	}
 	if conv.SchemaIssues != nil && len(issues) > 0 {
 		conv.SchemaIssues[tableId][colId] = issues
 	}
-	ty.IsArray = len(srcCol.Type.ArrayBounds) == 1
 	return sp, ty, nil
 }
+
+// GetChecks returns the check constraints of column colId of table tableId
+// when it is mapped to Spanner type ty from source driver.
+func GetChecks(conv *internal.Conv, driver, tableId, colId string, ty ddl.Type) []string {
+	toddl, err := getToDdl(driver)
+	if err != nil {
+		return nil
+	}
+	tc, ok := toddl.(common.ToDdlChecks)
+	if !ok {
+		return nil
+	}
+	checks, _ := tc.ToSpannerChecks(conv, conv.SrcSchema[tableId].ColDefs[colId], ty)
+	return checks
+}
+
+func getToDdl(driver string) (common.ToDdl, error) {
+	switch driver {
+	case constants.MYSQL, constants.MYSQLDUMP:
+		return mysql.InfoSchemaImpl{}.GetToDdl(), nil
+	case constants.PGDUMP, constants.POSTGRES:
+		return postgres.InfoSchemaImpl{}.GetToDdl(), nil
+	case constants.SQLSERVER, constants.SQLSERVERDUMP:
+		return sqlserver.InfoSchemaImpl{}.GetToDdl(), nil
+	case constants.ORACLE:
+		return oracle.InfoSchemaImpl{}.GetToDdl(), nil
+	case constants.SQLITE:
+		return sqlite.InfoSchemaImpl{}.GetToDdl(), nil
+	case constants.DYNAMODB:
+		return dynamodb.InfoSchemaImpl{}.GetToDdl(), nil
+	case constants.SPANNER:
+		return spanner.InfoSchemaImpl{}.GetToDdl(), nil
+	default:
+		return nil, fmt.Errorf("driver : '%s' is not supported", driver)
+	}
+}
@@ -29,9 +29,7 @@ import (
 
 const metadataDbName string = "harbourbridge_metadata"
 
-func InitObjectId() {
-
-	sessionState := session.GetSessionState()
+func InitObjectId(sessionState *session.SessionState) {
 	sessionState.Counter.ObjectId = "0"
 }
 
@@ -158,9 +156,9 @@ func RemoveFkReferColumns(slice []string, s int) []string {
 	return append(slice[:s], slice[s+1:]...)
 }
 
-func IsTypeChanged(newType, tableId, colId string, conv *internal.Conv) (bool, error) {
+func IsTypeChanged(driver, newType, tableId, colId string, conv *internal.Conv) (bool, error) {
 
-	sp, ty, err := GetType(conv, newType, tableId, colId)
+	sp, ty, err := GetType(conv, driver, newType, tableId, colId)
 	if err != nil {
 		return false, err
 	}
@@ -168,9 +166,7 @@ func IsTypeChanged(newType, tableId, colId string, conv *internal.Conv) (bool, e
 	return !reflect.DeepEqual(colDef.T, ty), nil
 }
 
-func IsPartOfPK(col, table string) bool {
-	sessionState := session.GetSessionState()
-
+func IsPartOfPK(sessionState *session.SessionState, col, table string) bool {
 	for _, pk := range sessionState.Conv.SpSchema[table].PrimaryKeys {
 		if pk.ColId == col {
 			return true
@@ -179,9 +175,7 @@ func IsPartOfPK(col, table string) bool {
 	return false
 }
 
-func IsPartOfSecondaryIndex(col, table string) (bool, string) {
-	sessionState := session.GetSessionState()
-
+func IsPartOfSecondaryIndex(sessionState *session.SessionState, col, table string) (bool, string) {
 	for _, index := range sessionState.Conv.SpSchema[table].Indexes {
 		for _, key := range index.Keys {
 			if key.ColId == col {
@@ -192,9 +186,7 @@ func IsPartOfSecondaryIndex(col, table string) (bool, string) {
 	return false, ""
 }
 
-func IsPartOfFK(col, table string) bool {
-	sessionState := session.GetSessionState()
-
+func IsPartOfFK(sessionState *session.SessionState, col, table string) bool {
 	for _, fk := range sessionState.Conv.SpSchema[table].ForeignKeys {
 		for _, column := range fk.ColIds {
 			if column == col {
@@ -205,9 +197,7 @@ func IsPartOfFK(col, table string) bool {
 	return false
 }
 
-func IsReferencedByFK(col, table string) (bool, string) {
-	sessionState := session.GetSessionState()
-
+func IsReferencedByFK(sessionState *session.SessionState, col, table string) (bool, string) {
 	for _, spSchema := range sessionState.Conv.SpSchema {
 		if table != spSchema.Name {
 			for _, fk := range spSchema.ForeignKeys {
@@ -269,8 +259,7 @@ func CheckSpannerNamesValidity(input []string) (bool, []string) {
 	return status, invalidNewNames
 }
 
-func CanRename(names []string, table string) (bool, error) {
-	sessionState := session.GetSessionState()
+func CanRename(sessionState *session.SessionState, names []string, table string) (bool, error) {
 	for _, name := range names {
 		if _, ok := sessionState.Conv.UsedNames[name]; ok {
 			return false, fmt.Errorf("new name : '%s' is used by another entity", name)
@@ -298,9 +287,7 @@ func GetRefColIndexFromFk(fk ddl.Foreignkey, colId string) int {
 	return -1
 }
 
-func GetFilePrefix(now time.Time) (string, error) {
-	sessionState := session.GetSessionState()
-
+func GetFilePrefix(sessionState *session.SessionState, now time.Time) (string, error) {
 	dbName := sessionState.DbName
 	var err error
 	if dbName == "" {
@@ -312,13 +299,14 @@ func GetFilePrefix(now time.Time) (string, error) {
 	return dbName, nil
 }
 
-func UpdateDataType(conv *internal.Conv, newType, tableId, colId string) error {
-	sp, ty, err := GetType(conv, newType, tableId, colId)
+func UpdateDataType(conv *internal.Conv, driver, newType, tableId, colId string) error {
+	sp, ty, err := GetType(conv, driver, newType, tableId, colId)
 	if err != nil {
 		return err
 	}
 	colDef := sp.ColDefs[colId]
 	colDef.T = ty
+	colDef.Checks = GetChecks(conv, driver, tableId, colId, ty)
 	sp.ColDefs[colId] = colDef
 	return nil
 }
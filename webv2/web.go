@@ -31,11 +31,13 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	instance "cloud.google.com/go/spanner/admin/instance/apiv1"
 	"github.com/cloudspannerecosystem/harbourbridge/cmd"
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/common/telemetry"
 	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
 	"github.com/cloudspannerecosystem/harbourbridge/conversion"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
@@ -45,13 +47,18 @@ import (
 	"github.com/cloudspannerecosystem/harbourbridge/proto/migration"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/dynamodb"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/mysql"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/oracle"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/postgres"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/spanner"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/sqlite"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/sqlserver"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/auth"
 	"github.com/cloudspannerecosystem/harbourbridge/webv2/config"
 	helpers "github.com/cloudspannerecosystem/harbourbridge/webv2/helpers"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/jobs"
 	"github.com/cloudspannerecosystem/harbourbridge/webv2/profile"
 	utilities "github.com/cloudspannerecosystem/harbourbridge/webv2/utilities"
 	"github.com/google/uuid"
@@ -82,6 +89,14 @@ var mysqlTypeMap = make(map[string][]typeIssue)
 var postgresTypeMap = make(map[string][]typeIssue)
 var sqlserverTypeMap = make(map[string][]typeIssue)
 var oracleTypeMap = make(map[string][]typeIssue)
+var sqliteTypeMap = make(map[string][]typeIssue)
+var dynamodbTypeMap = make(map[string][]typeIssue)
+var spannerTypeMap = make(map[string][]typeIssue)
+var typeMapMu sync.Mutex
+
+// defaultSampleRows is the number of rows of each table profiled to
+// suggest narrower types, unless set in the request.
+const defaultSampleRows = 1000
 
 // TODO:(searce) organize this file according to go style guidelines: generally
 // have public constants and public type definitions first, then public
@@ -206,7 +221,7 @@ func databaseConnection(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	sessionState.SourceDB = sourceDB
 	sessionState.DbName = config.Database
 	// schema and user is same in oracle.
@@ -229,7 +244,7 @@ func databaseConnection(w http.ResponseWriter, r *http.Request) {
 // convertSchemaSQL converts source database to Spanner when using
 // with postgres and mysql driver.
 func convertSchemaSQL(w http.ResponseWriter, r *http.Request) {
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	if sessionState.SourceDB == nil || sessionState.DbName == "" || sessionState.Driver == "" {
 		http.Error(w, fmt.Sprintf("Database is not configured or Database connection is lost. Please set configuration and connect to database."), http.StatusNotFound)
 		return
@@ -258,8 +273,8 @@ func convertSchemaSQL(w http.ResponseWriter, r *http.Request) {
 
 	sessionState.Conv = conv
 
-	primarykey.DetectHotspot()
-	index.IndexSuggestion()
+	primarykey.DetectHotspot(sessionState)
+	index.IndexSuggestion(sessionState)
 
 	sessionMetadata := session.SessionMetadata{
 		SessionName:  "NewSession",
@@ -295,7 +310,7 @@ type convertFromDumpRequest struct {
 }
 
 func setSourceDBDetailsForDump(w http.ResponseWriter, r *http.Request) {
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	reqBody, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
@@ -321,7 +336,7 @@ func setSourceDBDetailsForDump(w http.ResponseWriter, r *http.Request) {
 }
 
 func setSourceDBDetailsForDirectConnect(w http.ResponseWriter, r *http.Request) {
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	reqBody, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
@@ -413,11 +428,11 @@ func convertSchemaDump(w http.ResponseWriter, r *http.Request) {
 		Dialect:      dc.SpannerDetails.Dialect,
 	}
 
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	sessionState.Conv = conv
 
-	primarykey.DetectHotspot()
-	index.IndexSuggestion()
+	primarykey.DetectHotspot(sessionState)
+	index.IndexSuggestion(sessionState)
 
 	sessionState.SessionMetadata = sessionMetadata
 	sessionState.Driver = dc.Config.Driver
@@ -440,9 +455,9 @@ func convertSchemaDump(w http.ResponseWriter, r *http.Request) {
 
 // loadSession load seesion file to Harbourbridge.
 func loadSession(w http.ResponseWriter, r *http.Request) {
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 
-	utilities.InitObjectId()
+	utilities.InitObjectId(sessionState)
 
 	reqBody, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -475,6 +490,8 @@ func loadSession(w http.ResponseWriter, r *http.Request) {
 		dbType = constants.POSTGRES
 	case constants.MYSQLDUMP:
 		dbType = constants.MYSQL
+	case constants.SQLSERVERDUMP:
+		dbType = constants.SQLSERVER
 	}
 	if dbType != s.Driver {
 		http.Error(w, fmt.Sprintf("Not a valid %v session file", dbType), http.StatusBadRequest)
@@ -505,8 +522,8 @@ func loadSession(w http.ResponseWriter, r *http.Request) {
 
 	sessionState.Conv = conv
 
-	primarykey.DetectHotspot()
-	index.IndexSuggestion()
+	primarykey.DetectHotspot(sessionState)
+	index.IndexSuggestion(sessionState)
 
 	sessionState.Conv.UsedNames = internal.ComputeUsedNames(sessionState.Conv)
 
@@ -528,7 +545,7 @@ func loadSession(w http.ResponseWriter, r *http.Request) {
 }
 
 func fetchLastLoadedSessionDetails(w http.ResponseWriter, r *http.Request) {
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	convm := session.ConvWithMetadata{
 		SessionMetadata: sessionState.SessionMetadata,
 		Conv:            *sessionState.Conv,
@@ -543,7 +560,7 @@ func fetchLastLoadedSessionDetails(w http.ResponseWriter, r *http.Request) {
 // Though foreign keys and secondary indexes are displayed, getDDL cannot be used to
 // build DDL to send to Spanner.
 func getDDL(w http.ResponseWriter, r *http.Request) {
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	c := ddl.Config{Comments: true, ProtectIds: false, SpDialect: sessionState.Conv.SpDialect}
 	var tables []string
 	for t := range sessionState.Conv.SpSchema {
@@ -586,23 +603,32 @@ func getPGSQLToStandardTypeTypemap(w http.ResponseWriter, r *http.Request) {
 // getTypeMap returns the source to Spanner typemap only for the
 // source types used in current conversion.
 func getTypeMap(w http.ResponseWriter, r *http.Request) {
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 
 	if sessionState.Conv == nil || sessionState.Driver == "" {
 		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
 		return
 	}
 	var typeMap map[string][]typeIssue
-	initializeTypeMap()
+	// The typemaps are shared by all sessions.
+	typeMapMu.Lock()
+	defer typeMapMu.Unlock()
+	initializeTypeMap(sessionState.Conv)
 	switch sessionState.Driver {
 	case constants.MYSQL, constants.MYSQLDUMP:
 		typeMap = mysqlTypeMap
 	case constants.POSTGRES, constants.PGDUMP:
 		typeMap = postgresTypeMap
-	case constants.SQLSERVER:
+	case constants.SQLSERVER, constants.SQLSERVERDUMP:
 		typeMap = sqlserverTypeMap
 	case constants.ORACLE:
 		typeMap = oracleTypeMap
+	case constants.SQLITE:
+		typeMap = sqliteTypeMap
+	case constants.DYNAMODB:
+		typeMap = dynamodbTypeMap
+	case constants.SPANNER:
+		typeMap = spannerTypeMap
 	default:
 		http.Error(w, fmt.Sprintf("Driver : '%s' is not supported", sessionState.Driver), http.StatusBadRequest)
 		return
@@ -646,6 +672,60 @@ func getTypeMap(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(filteredTypeMap)
 }
 
+// getTypeSuggestions profiles a sample of the rows of each table of the
+// source database and returns the narrower Spanner types suggested for
+// its columns, by table and column id. The number of rows sampled per
+// table can be set with the sampleRows query parameter.
+func getTypeSuggestions(w http.ResponseWriter, r *http.Request) {
+	sessionState := session.GetSessionState(r.Context())
+	if sessionState.Conv == nil || sessionState.SourceDB == nil || sessionState.Driver == "" {
+		http.Error(w, fmt.Sprintf("Database is not configured or Database connection is lost. Please set configuration and connect to database."), http.StatusNotFound)
+		return
+	}
+	sampleRows := int64(defaultSampleRows)
+	if v := r.FormValue("sampleRows"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			http.Error(w, fmt.Sprintf("sampleRows must be a positive integer, got %q", v), http.StatusBadRequest)
+			return
+		}
+		sampleRows = n
+	}
+	var infoSchema common.InfoSchema
+	switch sessionState.Driver {
+	case constants.MYSQL:
+		infoSchema = mysql.InfoSchemaImpl{DbName: sessionState.DbName, Db: sessionState.SourceDB}
+	case constants.POSTGRES:
+		infoSchema = postgres.InfoSchemaImpl{Db: sessionState.SourceDB}
+	case constants.SQLSERVER:
+		infoSchema = sqlserver.InfoSchemaImpl{DbName: sessionState.DbName, Db: sessionState.SourceDB}
+	case constants.ORACLE:
+		infoSchema = oracle.InfoSchemaImpl{DbName: strings.ToUpper(sessionState.DbName), Db: sessionState.SourceDB}
+	case constants.SQLITE:
+		infoSchema = sqlite.InfoSchemaImpl{DbName: sessionState.DbName, Db: sessionState.SourceDB}
+	default:
+		http.Error(w, fmt.Sprintf("Driver : '%s' is not supported", sessionState.Driver), http.StatusBadRequest)
+		return
+	}
+	if err := common.ProfileData(sessionState.Conv, infoSchema, sampleRows); err != nil {
+		http.Error(w, fmt.Sprintf("Data profiling error : %v", err), http.StatusInternalServerError)
+		return
+	}
+	suggestions := make(map[string]map[string]typeSuggestion)
+	for tableId, cols := range sessionState.Conv.TypeSuggestions {
+		suggestions[tableId] = make(map[string]typeSuggestion)
+		for colId, s := range cols {
+			displayT := s.T.PrintColumnDefType()
+			if sessionState.Dialect == constants.DIALECT_POSTGRESQL {
+				displayT = s.T.PGPrintColumnDefType()
+			}
+			suggestions[tableId][colId] = typeSuggestion{T: s.T.Name, Len: s.T.Len, DisplayT: displayT, Reason: s.Reason}
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(suggestions)
+}
+
 // applyRule allows to add rules that changes the schema
 // currently it supports two types of operations viz. SetGlobalDataType and AddIndex
 func applyRule(w http.ResponseWriter, r *http.Request) {
@@ -660,6 +740,7 @@ func applyRule(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Request Body parse error : %v", err), http.StatusBadRequest)
 		return
 	}
+	sessionState := session.GetSessionState(r.Context())
 
 	if rule.Type == constants.GlobalDataTypeChange {
 		d, err := json.Marshal(rule.Data)
@@ -673,7 +754,7 @@ func applyRule(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Invalid rule data", http.StatusInternalServerError)
 			return
 		}
-		setGlobalDataType(typeMap)
+		setGlobalDataType(sessionState, typeMap)
 	} else if rule.Type == constants.AddIndex {
 		d, err := json.Marshal(rule.Data)
 		if err != nil {
@@ -686,7 +767,7 @@ func applyRule(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Invalid rule data", http.StatusInternalServerError)
 			return
 		}
-		addedIndex, err := addIndex(newIdx)
+		addedIndex, err := addIndex(sessionState, newIdx)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -700,9 +781,8 @@ func applyRule(w http.ResponseWriter, r *http.Request) {
 	ruleId := internal.GenerateRuleId()
 	rule.Id = ruleId
 
-	sessionState := session.GetSessionState()
 	sessionState.Conv.Rules = append(sessionState.Conv.Rules, rule)
-	session.UpdateSessionFile()
+	session.UpdateSessionFile(sessionState)
 	convm := session.ConvWithMetadata{
 		SessionMetadata: sessionState.SessionMetadata,
 		Conv:            *sessionState.Conv,
@@ -717,7 +797,7 @@ func dropRule(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprint("Rule id is empty"), http.StatusBadRequest)
 		return
 	}
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	conv := sessionState.Conv
 	var rule internal.Rule
 	position := -1
@@ -749,7 +829,7 @@ func dropRule(w http.ResponseWriter, r *http.Request) {
 			}
 			tableId := index.TableId
 			indexId := index.Id
-			err = dropSecondaryIndexHelper(tableId, indexId)
+			err = dropSecondaryIndexHelper(sessionState, tableId, indexId)
 			if err != nil {
 				http.Error(w, fmt.Sprintf("%v", err), http.StatusBadRequest)
 				return
@@ -767,7 +847,7 @@ func dropRule(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Invalid rule data", http.StatusInternalServerError)
 			return
 		}
-		revertGlobalDataType(typeMap)
+		revertGlobalDataType(sessionState, typeMap)
 	} else {
 		http.Error(w, "Invalid rule type", http.StatusInternalServerError)
 		return
@@ -777,7 +857,7 @@ func dropRule(w http.ResponseWriter, r *http.Request) {
 	if len(sessionState.Conv.Rules) == 0 {
 		sessionState.Conv.Rules = nil
 	}
-	session.UpdateSessionFile()
+	session.UpdateSessionFile(sessionState)
 	convm := session.ConvWithMetadata{
 		SessionMetadata: sessionState.SessionMetadata,
 		Conv:            *sessionState.Conv,
@@ -790,8 +870,7 @@ func dropRule(w http.ResponseWriter, r *http.Request) {
 // setGlobalDataType allows to change Spanner type globally.
 // It takes a map from source type to Spanner type and updates
 // the Spanner schema accordingly.
-func setGlobalDataType(typeMap map[string]string) {
-	sessionState := session.GetSessionState()
+func setGlobalDataType(sessionState *session.SessionState, typeMap map[string]string) {
 
 	// Redo source-to-Spanner typeMap using t (the mapping specified in the http request).
 	// We drive this process by iterating over the Spanner schema because we want to preserve all
@@ -806,7 +885,7 @@ func setGlobalDataType(typeMap map[string]string) {
 			// column as is. Note that per-column type overrides could be lost in
 			// this process -- the mapping in typeMap always takes precendence.
 			if _, found := typeMap[srcColDef.Type.Name]; found {
-				utilities.UpdateDataType(sessionState.Conv, typeMap[srcColDef.Type.Name], tableId, colId)
+				utilities.UpdateDataType(sessionState.Conv, sessionState.Driver, typeMap[srcColDef.Type.Name], tableId, colId)
 			}
 		}
 	}
@@ -816,8 +895,7 @@ func setGlobalDataType(typeMap map[string]string) {
 // when the rule that is used to apply the data-type change is deleted.
 // It takes a map from source type to Spanner type and updates
 // the Spanner schema accordingly.
-func revertGlobalDataType(typeMap map[string]string) {
-	sessionState := session.GetSessionState()
+func revertGlobalDataType(sessionState *session.SessionState, typeMap map[string]string) {
 
 	for tableId, spSchema := range sessionState.Conv.SpSchema {
 		for colId, colDef := range spSchema.ColDefs {
@@ -832,7 +910,7 @@ func revertGlobalDataType(typeMap map[string]string) {
 			}
 
 			if colDef.T.Name == spType {
-				utilities.UpdateDataType(sessionState.Conv, "", tableId, colId)
+				utilities.UpdateDataType(sessionState.Conv, sessionState.Driver, "", tableId, colId)
 			}
 		}
 	}
@@ -841,7 +919,7 @@ func revertGlobalDataType(typeMap map[string]string) {
 // addIndex checks the new name for spanner name validity, ensures the new name is already not used by existing tables
 // secondary indexes or foreign key constraints. If above checks passed then new indexes are added to the schema else appropriate
 // error thrown.
-func addIndex(newIndex ddl.CreateIndex) (ddl.CreateIndex, error) {
+func addIndex(sessionState *session.SessionState, newIndex ddl.CreateIndex) (ddl.CreateIndex, error) {
 	// Check new name for spanner name validity.
 	newNames := []string{}
 	newNames = append(newNames, newIndex.Name)
@@ -850,15 +928,14 @@ func addIndex(newIndex ddl.CreateIndex) (ddl.CreateIndex, error) {
 		return ddl.CreateIndex{}, fmt.Errorf("following names are not valid Spanner identifiers: %s", strings.Join(invalidNames, ","))
 	}
 	// Check that the new names are not already used by existing tables, secondary indexes or foreign key constraints.
-	if ok, err := utilities.CanRename(newNames, newIndex.TableId); !ok {
+	if ok, err := utilities.CanRename(sessionState, newNames, newIndex.TableId); !ok {
 		return ddl.CreateIndex{}, err
 	}
 
-	sessionState := session.GetSessionState()
 	sp := sessionState.Conv.SpSchema[newIndex.TableId]
 
 	newIndexes := []ddl.CreateIndex{newIndex}
-	index.CheckIndexSuggestion(newIndexes, sp)
+	index.CheckIndexSuggestion(sessionState, newIndexes, sp)
 	for i := 0; i < len(newIndexes); i++ {
 		newIndexes[i].Id = internal.GenerateIndexesId()
 	}
@@ -871,7 +948,7 @@ func addIndex(newIndex ddl.CreateIndex) (ddl.CreateIndex, error) {
 
 // getConversionRate returns table wise color coded conversion rate.
 func getConversionRate(w http.ResponseWriter, r *http.Request) {
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	hb_reports := reports.AnalyzeTables(sessionState.Conv, nil)
 	rate := make(map[string]string)
 	for _, t := range hb_reports {
@@ -886,13 +963,13 @@ func getSchemaFile(w http.ResponseWriter, r *http.Request) {
 	ioHelper := &utils.IOStreams{In: os.Stdin, Out: os.Stdout}
 	var err error
 	now := time.Now()
-	filePrefix, err := utilities.GetFilePrefix(now)
+	sessionState := session.GetSessionState(r.Context())
+	filePrefix, err := utilities.GetFilePrefix(sessionState, now)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Can not get file prefix : %v", err), http.StatusInternalServerError)
 	}
 	schemaFileName := "frontend/" + filePrefix + "schema.txt"
 
-	sessionState := session.GetSessionState()
 	conversion.WriteSchemaFile(sessionState.Conv, now, schemaFileName, ioHelper.Out)
 	schemaAbsPath, err := filepath.Abs(schemaFileName)
 	if err != nil {
@@ -907,12 +984,12 @@ func getReportFile(w http.ResponseWriter, r *http.Request) {
 	ioHelper := &utils.IOStreams{In: os.Stdin, Out: os.Stdout}
 	var err error
 	now := time.Now()
-	filePrefix, err := utilities.GetFilePrefix(now)
+	sessionState := session.GetSessionState(r.Context())
+	filePrefix, err := utilities.GetFilePrefix(sessionState, now)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Can not get file prefix : %v", err), http.StatusInternalServerError)
 	}
 	reportFileName := "frontend/" + filePrefix
-	sessionState := session.GetSessionState()
 	conversion.Report(sessionState.Driver, nil, ioHelper.BytesRead, "", sessionState.Conv, reportFileName, sessionState.DbName, ioHelper.Out)
 	reportAbsPath, err := filepath.Abs(reportFileName)
 	if err != nil {
@@ -935,7 +1012,7 @@ type TableInterleaveStatus struct {
 func setParentTable(w http.ResponseWriter, r *http.Request) {
 	tableId := r.FormValue("table")
 	update := r.FormValue("update") == "true"
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 
 	if sessionState.Conv == nil || sessionState.Driver == "" {
 		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
@@ -944,13 +1021,13 @@ func setParentTable(w http.ResponseWriter, r *http.Request) {
 	if tableId == "" {
 		http.Error(w, fmt.Sprintf("Table Id is empty"), http.StatusBadRequest)
 	}
-	tableInterleaveStatus := parentTableHelper(tableId, update)
+	tableInterleaveStatus := parentTableHelper(sessionState, tableId, update)
 
 	if tableInterleaveStatus.Possible {
 
 		childPks := sessionState.Conv.SpSchema[tableId].PrimaryKeys
 		childindex := utilities.GetPrimaryKeyIndexFromOrder(childPks, 1)
-		sessionState := session.GetSessionState()
+		sessionState := session.GetSessionState(r.Context())
 		schemaissue := []internal.SchemaIssue{}
 
 		colId := childPks[childindex].ColId
@@ -976,8 +1053,8 @@ func setParentTable(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	index.IndexSuggestion()
-	session.UpdateSessionFile()
+	index.IndexSuggestion(sessionState)
+	session.UpdateSessionFile(sessionState)
 	w.WriteHeader(http.StatusOK)
 
 	if update {
@@ -995,12 +1072,11 @@ func setParentTable(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func parentTableHelper(tableId string, update bool) *TableInterleaveStatus {
+func parentTableHelper(sessionState *session.SessionState, tableId string, update bool) *TableInterleaveStatus {
 	tableInterleaveStatus := &TableInterleaveStatus{
 		Possible: false,
 		Comment:  "No valid prefix",
 	}
-	sessionState := session.GetSessionState()
 
 	if _, found := sessionState.Conv.SyntheticPKeys[tableId]; found {
 		tableInterleaveStatus.Possible = false
@@ -1019,7 +1095,7 @@ func parentTableHelper(tableId string, update bool) *TableInterleaveStatus {
 			continue
 		}
 
-		if checkPrimaryKeyPrefix(tableId, refTableId, fk, tableInterleaveStatus) {
+		if checkPrimaryKeyPrefix(sessionState, tableId, refTableId, fk, tableInterleaveStatus) {
 			sp := sessionState.Conv.SpSchema[tableId]
 			setInterleave := false
 
@@ -1052,7 +1128,6 @@ func parentTableHelper(tableId string, update bool) *TableInterleaveStatus {
 
 					if (parentpks[parentindex].Order == childPks[childindex].Order) && (parentTable.ColDefs[parentpks[parentindex].ColId].Name == childTable.ColDefs[childPks[childindex].ColId].Name) {
 
-						sessionState := session.GetSessionState()
 						schemaissue := []internal.SchemaIssue{}
 
 						colId := childPks[childindex].ColId
@@ -1074,8 +1149,6 @@ func parentTableHelper(tableId string, update bool) *TableInterleaveStatus {
 					referColIndex := utilities.GetRefColIndexFromFk(fk, parentpks[parentindex].ColId)
 					if !setInterleave && referColIndex != -1 && fk.ColIds[referColIndex] != childPks[childindex].ColId {
 
-						sessionState := session.GetSessionState()
-
 						colId := fk.ColIds[referColIndex]
 
 						schemaissue := []internal.SchemaIssue{}
@@ -1101,7 +1174,7 @@ func parentTableHelper(tableId string, update bool) *TableInterleaveStatus {
 
 func removeParentTable(w http.ResponseWriter, r *http.Request) {
 	tableId := r.FormValue("tableId")
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	if sessionState.Conv == nil || sessionState.Driver == "" {
 		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
 		return
@@ -1165,7 +1238,7 @@ type DropDetail struct {
 
 func restoreTable(w http.ResponseWriter, r *http.Request) {
 	tableId := r.FormValue("table")
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	if sessionState.Conv == nil || sessionState.Driver == "" {
 		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
 		return
@@ -1185,10 +1258,18 @@ func restoreTable(w http.ResponseWriter, r *http.Request) {
 		toddl = sqlserver.InfoSchemaImpl{}.GetToDdl()
 	case constants.ORACLE:
 		toddl = oracle.InfoSchemaImpl{}.GetToDdl()
+	case constants.SQLITE:
+		toddl = sqlite.InfoSchemaImpl{}.GetToDdl()
+	case constants.DYNAMODB:
+		toddl = dynamodb.InfoSchemaImpl{}.GetToDdl()
+	case constants.SPANNER:
+		toddl = spanner.InfoSchemaImpl{}.GetToDdl()
 	case constants.MYSQLDUMP:
 		toddl = mysql.DbDumpImpl{}.GetToDdl()
 	case constants.PGDUMP:
 		toddl = postgres.DbDumpImpl{}.GetToDdl()
+	case constants.SQLSERVERDUMP:
+		toddl = sqlserver.DbDumpImpl{}.GetToDdl()
 	default:
 		http.Error(w, fmt.Sprintf("Driver : '%s' is not supported", sessionState.Driver), http.StatusBadRequest)
 		return
@@ -1201,7 +1282,7 @@ func restoreTable(w http.ResponseWriter, r *http.Request) {
 	}
 	conv.AddPrimaryKeys()
 	sessionState.Conv = conv
-	primarykey.DetectHotspot()
+	primarykey.DetectHotspot(sessionState)
 
 	convm := session.ConvWithMetadata{
 		SessionMetadata: sessionState.SessionMetadata,
@@ -1213,7 +1294,7 @@ func restoreTable(w http.ResponseWriter, r *http.Request) {
 
 func dropTable(w http.ResponseWriter, r *http.Request) {
 	tableId := r.FormValue("table")
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	if sessionState.Conv == nil || sessionState.Driver == "" {
 		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
 		return
@@ -1294,7 +1375,7 @@ func dropTable(w http.ResponseWriter, r *http.Request) {
 func restoreSecondaryIndex(w http.ResponseWriter, r *http.Request) {
 	tableId := r.FormValue("tableId")
 	indexId := r.FormValue("indexId")
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	if sessionState.Conv == nil || sessionState.Driver == "" {
 		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
 		return
@@ -1332,8 +1413,8 @@ func restoreSecondaryIndex(w http.ResponseWriter, r *http.Request) {
 	conv.SpSchema[tableId] = spTable
 
 	sessionState.Conv = conv
-	index.AssignInitialOrders()
-	index.IndexSuggestion()
+	index.AssignInitialOrders(sessionState)
+	index.IndexSuggestion(sessionState)
 
 	convm := session.ConvWithMetadata{
 		SessionMetadata: sessionState.SessionMetadata,
@@ -1354,7 +1435,7 @@ func updateForeignKeys(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
 	}
 
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	if sessionState.Conv == nil || sessionState.Driver == "" {
 		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
 		return
@@ -1391,7 +1472,7 @@ func updateForeignKeys(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check that the new names are not already used by existing tables, secondary indexes or foreign key constraints.
-	if ok, err := utilities.CanRename(newNames, tableId); !ok {
+	if ok, err := utilities.CanRename(sessionState, newNames, tableId); !ok {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -1437,7 +1518,7 @@ func updateForeignKeys(w http.ResponseWriter, r *http.Request) {
 	}
 	sp.ForeignKeys = updatedFKs
 	sessionState.Conv.SpSchema[tableId] = sp
-	session.UpdateSessionFile()
+	session.UpdateSessionFile(sessionState)
 
 	convm := session.ConvWithMetadata{
 		SessionMetadata: sessionState.SessionMetadata,
@@ -1481,11 +1562,11 @@ func renameIndexes(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check that the new names are not already used by existing tables, secondary indexes or foreign key constraints.
-	if ok, err := utilities.CanRename(newNames, table); !ok {
+	sessionState := session.GetSessionState(r.Context())
+	if ok, err := utilities.CanRename(sessionState, newNames, table); !ok {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	sessionState := session.GetSessionState()
 
 	sp := sessionState.Conv.SpSchema[table]
 
@@ -1500,7 +1581,7 @@ func renameIndexes(w http.ResponseWriter, r *http.Request) {
 	sp.Indexes = newIndexes
 
 	sessionState.Conv.SpSchema[table] = sp
-	session.UpdateSessionFile()
+	session.UpdateSessionFile(sessionState)
 	convm := session.ConvWithMetadata{
 		SessionMetadata: sessionState.SessionMetadata,
 		Conv:            *sessionState.Conv,
@@ -1514,7 +1595,7 @@ func renameIndexes(w http.ResponseWriter, r *http.Request) {
 // secondary indexes or foreign key constraints. If above checks passed then new indexes are added to the schema else appropriate
 // error thrown.
 func getSourceDestinationSummary(w http.ResponseWriter, r *http.Request) {
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	var sessionSummary sessionSummary
 	databaseType, err := helpers.GetSourceDatabaseFromDriver(sessionState.Driver)
 	if err != nil {
@@ -1572,7 +1653,7 @@ func getSourceDestinationSummary(w http.ResponseWriter, r *http.Request) {
 func updateProgress(w http.ResponseWriter, r *http.Request) {
 
 	var detail progressDetails
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	if sessionState.Error != nil {
 		detail.ErrorMessage = sessionState.Error.Error()
 	} else {
@@ -1600,9 +1681,12 @@ func migrate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
+	if j, ok := migrationJobs.Active(sessionState.Id); ok {
+		http.Error(w, fmt.Sprintf("Migration job %s of this session is %s", j.Id, j.State), http.StatusConflict)
+		return
+	}
 	sessionState.Error = nil
-	ctx := context.Background()
 	sessionState.Conv.Audit.Progress = internal.Progress{}
 	sourceProfile, targetProfile, ioHelper, dbName, err := getSourceAndTargetProfiles(sessionState, details)
 	if err != nil {
@@ -1616,38 +1700,56 @@ func migrate(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Can't write session file to GCS: %v", err), http.StatusBadRequest)
 		return
 	}
-	sessionState.Conv.ResetStats()
-	sessionState.Conv.Audit.Progress = internal.Progress{}
+	conv := sessionState.Conv
+	conv.ResetStats()
+	conv.Audit.Progress = internal.Progress{}
+	conv.Audit.MigrationProgress = internal.NewMigrationProgress()
 	// Set env variable SKIP_METRICS_POPULATION to true in case of dev testing
-	sessionState.Conv.Audit.SkipMetricsPopulation = os.Getenv("SKIP_METRICS_POPULATION") == "true"
+	conv.Audit.SkipMetricsPopulation = os.Getenv("SKIP_METRICS_POPULATION") == "true"
+	var migrationCmd interface{}
+	var description string
 	if details.MigrationMode == helpers.SCHEMA_ONLY {
-		log.Println("Starting schema only migration")
-		sessionState.Conv.Audit.MigrationType = migration.MigrationData_SCHEMA_ONLY.Enum()
-		go cmd.MigrateDatabase(ctx, targetProfile, sourceProfile, dbName, &ioHelper, &cmd.SchemaCmd{}, sessionState.Conv, &sessionState.Error)
+		description = "Schema only migration"
+		conv.Audit.MigrationType = migration.MigrationData_SCHEMA_ONLY.Enum()
+		migrationCmd = &cmd.SchemaCmd{}
 	} else if details.MigrationMode == helpers.DATA_ONLY {
-		dataCmd := &cmd.DataCmd{
+		description = "Data only migration"
+		conv.Audit.MigrationType = migration.MigrationData_DATA_ONLY.Enum()
+		migrationCmd = &cmd.DataCmd{
 			SkipForeignKeys: false,
 			WriteLimit:      cmd.DefaultWritersLimit,
 		}
-		log.Println("Starting data only migration")
-		sessionState.Conv.Audit.MigrationType = migration.MigrationData_DATA_ONLY.Enum()
-		go cmd.MigrateDatabase(ctx, targetProfile, sourceProfile, dbName, &ioHelper, dataCmd, sessionState.Conv, &sessionState.Error)
 	} else {
-		schemaAndDataCmd := &cmd.SchemaAndDataCmd{
+		description = "Schema and data migration"
+		conv.Audit.MigrationType = migration.MigrationData_SCHEMA_AND_DATA.Enum()
+		migrationCmd = &cmd.SchemaAndDataCmd{
 			SkipForeignKeys: false,
 			WriteLimit:      cmd.DefaultWritersLimit,
 		}
-		log.Println("Starting schema and data migration")
-		sessionState.Conv.Audit.MigrationType = migration.MigrationData_SCHEMA_AND_DATA.Enum()
-		go cmd.MigrateDatabase(ctx, targetProfile, sourceProfile, dbName, &ioHelper, schemaAndDataCmd, sessionState.Conv, &sessionState.Error)
 	}
+	j := migrationJobs.Submit(jobs.Spec{
+		Description: fmt.Sprintf("%s to database %s", description, dbName),
+		SessionId:   sessionState.Id,
+		Run: func(ctx context.Context, logf func(format string, a ...interface{})) error {
+			log.Printf("Starting job: %s", description)
+			logf("Migrating %s source to Spanner database %s", sourceProfile.Driver, dbName)
+			_, err := cmd.MigrateDatabase(ctx, targetProfile, sourceProfile, dbName, &ioHelper, migrationCmd, conv, &sessionState.Error)
+			if err == nil {
+				logf("Migrated %d rows, %d bad rows", conv.Rows(), conv.BadRows())
+			}
+			return err
+		},
+		Progress:  conv.Audit.Progress.ReportProgress,
+		Migration: conv.Audit.MigrationProgress,
+	})
 	w.WriteHeader(http.StatusOK)
-	log.Println("migration completed", "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr)
+	json.NewEncoder(w).Encode(j)
+	log.Println("migration job submitted", "job", j.Id, "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr)
 }
 
 func getGeneratedResources(w http.ResponseWriter, r *http.Request) {
 	var generatedResources GeneratedResources
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	generatedResources.DatabaseName = sessionState.SpannerDatabaseName
 	generatedResources.DatabaseUrl = fmt.Sprintf("https://pantheon.corp.google.com/spanner/instances/%v/databases/%v/details/tables?project=%v", sessionState.SpannerInstanceID, sessionState.SpannerDatabaseName, sessionState.GCPProjectID)
 	generatedResources.BucketName = sessionState.Bucket + sessionState.RootPath
@@ -1791,7 +1893,7 @@ func updateIndexes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 	sp := sessionState.Conv.SpSchema[table]
 
 	st := sessionState.Conv.SrcSchema[table]
@@ -1800,7 +1902,7 @@ func updateIndexes(w http.ResponseWriter, r *http.Request) {
 
 		if ind.TableId == newIndexes[0].TableId && ind.Id == newIndexes[0].Id {
 
-			index.RemoveIndexIssues(table, sp.Indexes[i])
+			index.RemoveIndexIssues(sessionState, table, sp.Indexes[i])
 
 			sp.Indexes[i].Keys = newIndexes[0].Keys
 			sp.Indexes[i].Name = newIndexes[0].Name
@@ -1835,7 +1937,7 @@ func updateIndexes(w http.ResponseWriter, r *http.Request) {
 
 	sessionState.Conv.SrcSchema[table] = st
 
-	session.UpdateSessionFile()
+	session.UpdateSessionFile(sessionState)
 
 	convm := session.ConvWithMetadata{
 		SessionMetadata: sessionState.SessionMetadata,
@@ -1846,7 +1948,7 @@ func updateIndexes(w http.ResponseWriter, r *http.Request) {
 }
 
 func dropSecondaryIndex(w http.ResponseWriter, r *http.Request) {
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(r.Context())
 
 	table := r.FormValue("table")
 	reqBody, err := ioutil.ReadAll(r.Body)
@@ -1867,7 +1969,7 @@ func dropSecondaryIndex(w http.ResponseWriter, r *http.Request) {
 	if table == "" || dropDetail.Id == "" {
 		http.Error(w, fmt.Sprintf("Table name or position is empty"), http.StatusBadRequest)
 	}
-	err = dropSecondaryIndexHelper(table, dropDetail.Id)
+	err = dropSecondaryIndexHelper(sessionState, table, dropDetail.Id)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("%v", err), http.StatusBadRequest)
 		return
@@ -1903,11 +2005,10 @@ func dropSecondaryIndex(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(convm)
 }
 
-func dropSecondaryIndexHelper(tableId, idxId string) error {
+func dropSecondaryIndexHelper(sessionState *session.SessionState, tableId, idxId string) error {
 	if tableId == "" || idxId == "" {
 		return fmt.Errorf("Table id or index id is empty")
 	}
-	sessionState := session.GetSessionState()
 	sp := sessionState.Conv.SpSchema[tableId]
 	position := -1
 	for i, index := range sp.Indexes {
@@ -1922,11 +2023,11 @@ func dropSecondaryIndexHelper(tableId, idxId string) error {
 
 	usedNames := sessionState.Conv.UsedNames
 	delete(usedNames, sp.Indexes[position].Name)
-	index.RemoveIndexIssues(tableId, sp.Indexes[position])
+	index.RemoveIndexIssues(sessionState, tableId, sp.Indexes[position])
 
 	sp.Indexes = utilities.RemoveSecondaryIndex(sp.Indexes, position)
 	sessionState.Conv.SpSchema[tableId] = sp
-	session.UpdateSessionFile()
+	session.UpdateSessionFile(sessionState)
 	return nil
 }
 
@@ -1978,8 +2079,7 @@ func uploadFile(w http.ResponseWriter, r *http.Request) {
 
 // rollback is used to get previous state of conversion in case
 // some unexpected error occurs during update operations.
-func rollback(err error) error {
-	sessionState := session.GetSessionState()
+func rollback(sessionState *session.SessionState, err error) error {
 
 	if sessionState.SessionFile == "" {
 		return fmt.Errorf("encountered error %w. rollback failed because we don't have a session file", err)
@@ -1993,9 +2093,8 @@ func rollback(err error) error {
 	return err
 }
 
-func checkPrimaryKeyPrefix(tableId string, refTableId string, fk ddl.Foreignkey, tableInterleaveStatus *TableInterleaveStatus) bool {
+func checkPrimaryKeyPrefix(sessionState *session.SessionState, tableId string, refTableId string, fk ddl.Foreignkey, tableInterleaveStatus *TableInterleaveStatus) bool {
 
-	sessionState := session.GetSessionState()
 	childTable := sessionState.Conv.SpSchema[tableId]
 	parentTable := sessionState.Conv.SpSchema[refTableId]
 	childPks := sessionState.Conv.SpSchema[tableId].PrimaryKeys
@@ -2013,7 +2112,7 @@ func checkPrimaryKeyPrefix(tableId string, refTableId string, fk ddl.Foreignkey,
 		}
 	}
 	if !possibleInterleave {
-		removeInterleaveSuggestions(fk.ColIds, tableId)
+		removeInterleaveSuggestions(sessionState, fk.ColIds, tableId)
 		return false
 	}
 
@@ -2088,9 +2187,9 @@ func checkPrimaryKeyPrefix(tableId string, refTableId string, fk ddl.Foreignkey,
 	}
 
 	if len(canInterleavedOnRename) > 0 {
-		updateInterleaveSuggestion(canInterleavedOnRename, tableId, internal.InterleavedRenameColumn)
+		updateInterleaveSuggestion(sessionState, canInterleavedOnRename, tableId, internal.InterleavedRenameColumn)
 	} else if len(canInterleavedOnAdd) > 0 {
-		updateInterleaveSuggestion(canInterleavedOnAdd, tableId, internal.InterleavedAddColumn)
+		updateInterleaveSuggestion(sessionState, canInterleavedOnAdd, tableId, internal.InterleavedAddColumn)
 	}
 
 	if len(interleaved) > 0 {
@@ -2100,11 +2199,9 @@ func checkPrimaryKeyPrefix(tableId string, refTableId string, fk ddl.Foreignkey,
 	return false
 }
 
-func updateInterleaveSuggestion(colIds []string, tableId string, issue internal.SchemaIssue) {
+func updateInterleaveSuggestion(sessionState *session.SessionState, colIds []string, tableId string, issue internal.SchemaIssue) {
 	for i := 0; i < len(colIds); i++ {
 
-		sessionState := session.GetSessionState()
-
 		schemaissue := []internal.SchemaIssue{}
 
 		schemaissue = sessionState.Conv.SchemaIssues[tableId][colIds[i]]
@@ -2131,11 +2228,9 @@ func updateInterleaveSuggestion(colIds []string, tableId string, issue internal.
 	}
 }
 
-func removeInterleaveSuggestions(colIds []string, tableId string) {
+func removeInterleaveSuggestions(sessionState *session.SessionState, colIds []string, tableId string) {
 	for i := 0; i < len(colIds); i++ {
 
-		sessionState := session.GetSessionState()
-
 		schemaissue := []internal.SchemaIssue{}
 
 		schemaissue = sessionState.Conv.SchemaIssues[tableId][colIds[i]]
@@ -2178,6 +2273,15 @@ type typeIssue struct {
 	DisplayT string
 }
 
+// typeSuggestion is a narrower Spanner type suggested for a column from
+// a sample of its data.
+type typeSuggestion struct {
+	T        string
+	Len      int64
+	DisplayT string
+	Reason   string
+}
+
 type GeneratedResources struct {
 	DatabaseName      string
 	DatabaseUrl       string
@@ -2204,8 +2308,7 @@ func addTypeToList(convertedType string, spType string, issues []internal.Schema
 	return l
 }
 
-func initializeTypeMap() {
-	sessionState := session.GetSessionState()
+func initializeTypeMap(conv *internal.Conv) {
 	var toddl common.ToDdl
 
 	// Initialize mysqlTypeMap.
@@ -2215,7 +2318,7 @@ func initializeTypeMap() {
 		for _, spType := range []string{ddl.Bool, ddl.Bytes, ddl.Date, ddl.Float64, ddl.Int64, ddl.String, ddl.Timestamp, ddl.Numeric, ddl.JSON} {
 			srcType := schema.MakeType()
 			srcType.Name = srcTypeName
-			ty, issues := toddl.ToSpannerType(sessionState.Conv, spType, srcType)
+			ty, issues := toddl.ToSpannerType(conv, spType, srcType)
 			l = addTypeToList(ty.Name, spType, issues, l)
 		}
 		if srcTypeName == "tinyint" {
@@ -2230,7 +2333,7 @@ func initializeTypeMap() {
 		for _, spType := range []string{ddl.Bool, ddl.Bytes, ddl.Date, ddl.Float64, ddl.Int64, ddl.String, ddl.Timestamp, ddl.Numeric, ddl.JSON} {
 			srcType := schema.MakeType()
 			srcType.Name = srcTypeName
-			ty, issues := toddl.ToSpannerType(sessionState.Conv, spType, srcType)
+			ty, issues := toddl.ToSpannerType(conv, spType, srcType)
 			l = addTypeToList(ty.Name, spType, issues, l)
 		}
 		postgresTypeMap[srcTypeName] = l
@@ -2243,7 +2346,7 @@ func initializeTypeMap() {
 		for _, spType := range []string{ddl.Bool, ddl.Bytes, ddl.Date, ddl.Float64, ddl.Int64, ddl.String, ddl.Timestamp, ddl.Numeric, ddl.JSON} {
 			srcType := schema.MakeType()
 			srcType.Name = srcTypeName
-			ty, issues := toddl.ToSpannerType(sessionState.Conv, spType, srcType)
+			ty, issues := toddl.ToSpannerType(conv, spType, srcType)
 			l = addTypeToList(ty.Name, spType, issues, l)
 		}
 		sqlserverTypeMap[srcTypeName] = l
@@ -2256,32 +2359,133 @@ func initializeTypeMap() {
 		for _, spType := range []string{ddl.Bool, ddl.Bytes, ddl.Date, ddl.Float64, ddl.Int64, ddl.String, ddl.Timestamp, ddl.Numeric, ddl.JSON} {
 			srcType := schema.MakeType()
 			srcType.Name = srcTypeName
-			ty, issues := toddl.ToSpannerType(sessionState.Conv, spType, srcType)
+			ty, issues := toddl.ToSpannerType(conv, spType, srcType)
 			l = addTypeToList(ty.Name, spType, issues, l)
 		}
 		oracleTypeMap[srcTypeName] = l
 	}
+
+	// Initialize sqliteTypeMap.
+	toddl = sqlite.InfoSchemaImpl{}.GetToDdl()
+	for _, srcTypeName := range []string{"integer", "int", "bigint", "smallint", "tinyint", "text", "varchar", "char", "clob", "blob", "real", "double", "float", "numeric", "decimal", "boolean", "date", "datetime", "timestamp", "json"} {
+		var l []typeIssue
+		for _, spType := range []string{ddl.Bool, ddl.Bytes, ddl.Date, ddl.Float64, ddl.Int64, ddl.String, ddl.Timestamp, ddl.Numeric, ddl.JSON} {
+			srcType := schema.MakeType()
+			srcType.Name = srcTypeName
+			ty, issues := toddl.ToSpannerType(conv, spType, srcType)
+			l = addTypeToList(ty.Name, spType, issues, l)
+		}
+		sqliteTypeMap[srcTypeName] = l
+	}
+
+	// Initialize dynamodbTypeMap.
+	toddl = dynamodb.InfoSchemaImpl{}.GetToDdl()
+	// Only List and Map have alternative mappings; all other DynamoDB types
+	// map to a single Spanner type.
+	for _, srcTypeName := range []string{"List", "Map"} {
+		var l []typeIssue
+		for _, spType := range []string{ddl.Bool, ddl.Bytes, ddl.Date, ddl.Float64, ddl.Int64, ddl.String, ddl.Timestamp, ddl.Numeric, ddl.JSON} {
+			srcType := schema.MakeType()
+			srcType.Name = srcTypeName
+			ty, issues := toddl.ToSpannerType(conv, spType, srcType)
+			l = addTypeToList(ty.Name, spType, issues, l)
+		}
+		dynamodbTypeMap[srcTypeName] = l
+	}
+
+	// Initialize spannerTypeMap with the types of both Spanner dialects.
+	toddl = spanner.InfoSchemaImpl{}.GetToDdl()
+	for _, srcTypeName := range []string{"BOOL", "BYTES", "DATE", "FLOAT64", "INT64", "JSON", "NUMERIC", "STRING", "TIMESTAMP", "boolean", "bytea", "date", "double precision", "bigint", "jsonb", "numeric", "character varying", "timestamp with time zone"} {
+		var l []typeIssue
+		for _, spType := range []string{ddl.Bool, ddl.Bytes, ddl.Date, ddl.Float64, ddl.Int64, ddl.String, ddl.Timestamp, ddl.Numeric, ddl.JSON} {
+			srcType := schema.MakeType()
+			srcType.Name = srcTypeName
+			ty, issues := toddl.ToSpannerType(conv, spType, srcType)
+			l = addTypeToList(ty.Name, spType, issues, l)
+		}
+		spannerTypeMap[srcTypeName] = l
+	}
 }
 
 func init() {
-	sessionState := session.GetSessionState()
-	utilities.InitObjectId()
+	sessionState := session.GetSessionState(context.Background())
+	utilities.InitObjectId(sessionState)
 	sessionState.Conv = internal.MakeConv()
 	config := config.TryInitializeSpannerConfig()
 	session.SetSessionStorageConnectionState(config.GCPProjectID, config.SpannerInstanceID)
 }
 
+// migrationJobs runs the migrations started with /Migrate.
+var migrationJobs = jobs.NewManager(defaultMaxRunningJobs)
+
+// defaultMaxRunningJobs is how many migrations run at a time by default.
+const defaultMaxRunningJobs = 2
+
+// ServerOptions configures the web server of App.
+type ServerOptions struct {
+	// Sessions idle for longer than this are evicted to the session store.
+	SessionIdleTimeout time.Duration
+	// How many migration jobs run at a time; the others are queued.
+	MaxRunningJobs int
+	Auth           auth.Config
+	// Origins allowed to send cross-origin requests. If empty, only
+	// same-origin requests are allowed.
+	AllowedOrigins []string
+	// If both are set, the server listens for HTTPS with this certificate
+	// and key.
+	TLSCertFile string
+	TLSKeyFile  string
+	// Whether migration metrics are recorded and served at /metrics.
+	Metrics bool
+}
+
+// corsHandler allows cross-origin requests from origins. With no origins,
+// h is returned as is, so that browsers only allow same-origin requests:
+// handlers.AllowedOrigins treats an empty list as allowing all origins.
+func corsHandler(h http.Handler, origins []string) http.Handler {
+	if len(origins) == 0 {
+		return h
+	}
+	return handlers.CORS(handlers.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization", session.SessionIdHeader}), handlers.AllowedMethods([]string{"GET", "POST", "PUT", "HEAD", "OPTIONS"}), handlers.AllowedOrigins(origins))(h)
+}
+
 // App connects to the web app v2.
-func App(logLevel string, open bool) {
+func App(logLevel string, open bool, opts ServerOptions) {
 	err := logger.InitializeLogger(logLevel)
 	if err != nil {
 		log.Fatal("Error initialising webapp, did you specify a valid log-level? [DEBUG, INFO, WARN, ERROR, FATAL]")
 	}
+	authenticator, err := auth.NewAuthenticator(opts.Auth)
+	if err != nil {
+		log.Fatalf("Error initialising authentication: %v", err)
+	}
+	if (opts.TLSCertFile == "") != (opts.TLSKeyFile == "") {
+		log.Fatal("Error initialising webapp: TLS needs both a certificate and a key file")
+	}
+	if opts.MaxRunningJobs > 0 {
+		migrationJobs = jobs.NewManager(opts.MaxRunningJobs)
+	}
+	if opts.Metrics {
+		telemetry.Enable()
+	}
 	addr := ":8080"
-	router := getRoutes()
-	fmt.Println("Harbourbridge UI started at:", fmt.Sprintf("http://localhost%s", addr))
+	router := getRoutes(authenticator)
+	// The session of a running job holds the Conv the job migrates.
+	session.StartSessionEviction(context.Background(), opts.SessionIdleTimeout, func(id string) bool {
+		_, ok := migrationJobs.Active(id)
+		return ok
+	})
+	scheme := "http"
+	if opts.TLSCertFile != "" {
+		scheme = "https"
+	}
+	fmt.Println("Harbourbridge UI started at:", fmt.Sprintf("%s://localhost%s", scheme, addr))
 	if open {
-		browser.OpenURL(fmt.Sprintf("http://localhost%s", addr))
+		browser.OpenURL(fmt.Sprintf("%s://localhost%s", scheme, addr))
+	}
+	handler := corsHandler(router, opts.AllowedOrigins)
+	if opts.TLSCertFile != "" {
+		log.Fatal(http.ListenAndServeTLS(addr, opts.TLSCertFile, opts.TLSKeyFile, handler))
 	}
-	log.Fatal(http.ListenAndServe(addr, handlers.CORS(handlers.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization"}), handlers.AllowedMethods([]string{"GET", "POST", "PUT", "HEAD", "OPTIONS"}), handlers.AllowedOrigins([]string{"*"}))(router)))
+	log.Fatal(http.ListenAndServe(addr, handler))
 }
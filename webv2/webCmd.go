@@ -22,9 +22,12 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
 	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/auth"
 	"github.com/google/subcommands"
 	"go.uber.org/zap"
 )
@@ -32,9 +35,18 @@ import (
 var FrontendDir embed.FS
 
 type WebCmd struct {
-	DistDir  embed.FS
-	logLevel string
-	open     bool
+	DistDir            embed.FS
+	logLevel           string
+	open               bool
+	sessionIdleTimeout time.Duration
+	maxRunningJobs     int
+	auth               auth.Config
+	viewers            string
+	editors            string
+	allowedOrigins     string
+	tlsCert            string
+	tlsKey             string
+	metrics            bool
 }
 
 // Name returns the name of operation.
@@ -54,6 +66,21 @@ func (cmd *WebCmd) Usage() string {
 func (cmd *WebCmd) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&cmd.logLevel, "log-level", "INFO", "Configure the logging level for the command (INFO, DEBUG), defaults to INFO")
 	f.BoolVar(&cmd.open, "open", false, "Opens the Harbourbridge web interface in the default browser, defaults to false")
+	f.DurationVar(&cmd.sessionIdleTimeout, "session-idle-timeout", 30*time.Minute, "Saves sessions idle for longer than this to the session store and drops them from memory, defaults to 30m")
+	f.IntVar(&cmd.maxRunningJobs, "max-running-jobs", defaultMaxRunningJobs, "Number of migration jobs that run at a time, others are queued, defaults to 2")
+	f.StringVar(&cmd.auth.Mode, "auth", auth.ModeNone, "Authentication of API requests: none, token, basic or oidc, defaults to none")
+	f.StringVar(&cmd.auth.Token, "auth-token", "", "Bearer token of editors, for -auth=token")
+	f.StringVar(&cmd.auth.ViewerToken, "auth-viewer-token", "", "Bearer token of viewers, who can only view the DDL and reports, for -auth=token")
+	f.StringVar(&cmd.auth.HtpasswdFile, "htpasswd-file", "", "htpasswd file with the bcrypt or SHA-1 password hashes of the users, for -auth=basic")
+	f.StringVar(&cmd.auth.OIDCIssuer, "oidc-issuer", "", "Issuer of the ID tokens, for -auth=oidc")
+	f.StringVar(&cmd.auth.OIDCAudience, "oidc-audience", "", "Audience of the ID tokens, for -auth=oidc")
+	f.StringVar(&cmd.auth.OIDCJWKSFile, "oidc-jwks-file", "", "JSON web key set file with the keys that sign the ID tokens, for -auth=oidc")
+	f.StringVar(&cmd.viewers, "auth-viewers", "", "Comma-separated users who can only view the DDL and reports, for -auth=basic")
+	f.StringVar(&cmd.editors, "auth-editors", "", "Comma-separated users who can edit and migrate, for -auth=oidc (by email, or subject if there is none); other users can only view the DDL and reports")
+	f.StringVar(&cmd.allowedOrigins, "allowed-origins", "", "Comma-separated origins allowed to send cross-origin requests, defaults to none")
+	f.StringVar(&cmd.tlsCert, "tls-cert", "", "Certificate file to serve HTTPS with, along with -tls-key")
+	f.StringVar(&cmd.tlsKey, "tls-key", "", "Private key file to serve HTTPS with, along with -tls-cert")
+	f.BoolVar(&cmd.metrics, "metrics", false, "Records migration metrics and serves them for Prometheus at /metrics, defaults to false")
 }
 
 func (cmd *WebCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
@@ -66,6 +93,27 @@ func (cmd *WebCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{
 		}
 	}()
 	defer logger.Log.Sync()
-	App(cmd.logLevel, cmd.open)
+	cmd.auth.Viewers = splitList(cmd.viewers)
+	cmd.auth.Editors = splitList(cmd.editors)
+	App(cmd.logLevel, cmd.open, ServerOptions{
+		SessionIdleTimeout: cmd.sessionIdleTimeout,
+		MaxRunningJobs:     cmd.maxRunningJobs,
+		Auth:               cmd.auth,
+		AllowedOrigins:     splitList(cmd.allowedOrigins),
+		TLSCertFile:        cmd.tlsCert,
+		TLSKeyFile:         cmd.tlsKey,
+		Metrics:            cmd.metrics,
+	})
 	return subcommands.ExitSuccess
 }
+
+// splitList returns the non-empty elements of comma-separated list s.
+func splitList(s string) []string {
+	var l []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			l = append(l, e)
+		}
+	}
+	return l
+}
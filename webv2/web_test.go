@@ -16,23 +16,38 @@ package webv2
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/common/telemetry"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/internal/reports"
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
 	"github.com/cloudspannerecosystem/harbourbridge/proto/migration"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/sqlite"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/auth"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/jobs"
 	"github.com/cloudspannerecosystem/harbourbridge/webv2/session"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 )
 
+func init() {
+	logger.Log = zap.NewNop()
+}
+
 func TestGetTypeMapNoDriver(t *testing.T) {
 	req, err := http.NewRequest("GET", "/typemap", nil)
 	if err != nil {
@@ -52,7 +67,7 @@ func TestGetTypeMapNoDriver(t *testing.T) {
 }
 
 func TestGetTypeMapPostgres(t *testing.T) {
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(context.Background())
 	sessionState.Driver = constants.POSTGRES
 	sessionState.Conv = internal.MakeConv()
 	buildConvPostgres(sessionState.Conv)
@@ -122,7 +137,7 @@ func TestGetTypeMapPostgres(t *testing.T) {
 }
 
 func TestGetConversionPostgres(t *testing.T) {
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(context.Background())
 
 	sessionState.Driver = constants.POSTGRES
 	sessionState.Conv = internal.MakeConv()
@@ -146,7 +161,7 @@ func TestGetConversionPostgres(t *testing.T) {
 }
 
 func TestGetTypeMapMySQL(t *testing.T) {
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(context.Background())
 
 	sessionState.Driver = constants.MYSQL
 	sessionState.Conv = internal.MakeConv()
@@ -218,7 +233,7 @@ func TestGetTypeMapMySQL(t *testing.T) {
 }
 
 func TestGetConversionMySQL(t *testing.T) {
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(context.Background())
 
 	sessionState.Driver = constants.MYSQL
 	sessionState.Conv = internal.MakeConv()
@@ -539,7 +554,7 @@ func TestSetParentTable(t *testing.T) {
 		},
 	}
 	for _, tc := range tests {
-		sessionState := session.GetSessionState()
+		sessionState := session.GetSessionState(context.Background())
 
 		sessionState.Driver = constants.MYSQL
 		sessionState.Conv = tc.ct
@@ -617,7 +632,7 @@ func TestDropForeignKey(t *testing.T) {
 		},
 	}
 	for _, tc := range tc {
-		sessionState := session.GetSessionState()
+		sessionState := session.GetSessionState(context.Background())
 
 		sessionState.Driver = constants.MYSQL
 		sessionState.Conv = tc.conv
@@ -887,7 +902,7 @@ func TestRenameIndexes(t *testing.T) {
 	}
 
 	for _, tc := range tc {
-		sessionState := session.GetSessionState()
+		sessionState := session.GetSessionState(context.Background())
 
 		sessionState.Driver = constants.MYSQL
 		sessionState.Conv = tc.conv
@@ -1146,7 +1161,7 @@ func TestRenameForeignKeys(t *testing.T) {
 		},
 	}
 	for _, tc := range tc {
-		sessionState := session.GetSessionState()
+		sessionState := session.GetSessionState(context.Background())
 
 		sessionState.Driver = constants.MYSQL
 		sessionState.Conv = tc.conv
@@ -1240,7 +1255,7 @@ func TestDropSecondaryIndex(t *testing.T) {
 		},
 	}
 	for _, tc := range tc {
-		sessionState := session.GetSessionState()
+		sessionState := session.GetSessionState(context.Background())
 
 		sessionState.Driver = constants.MYSQL
 		sessionState.Conv = tc.conv
@@ -1361,7 +1376,7 @@ func TestRestoreSecondaryIndex(t *testing.T) {
 		},
 	}
 	for _, tc := range tc {
-		sessionState := session.GetSessionState()
+		sessionState := session.GetSessionState(context.Background())
 
 		sessionState.Driver = constants.MYSQL
 		sessionState.Conv = tc.conv
@@ -1387,7 +1402,7 @@ func TestRestoreSecondaryIndex(t *testing.T) {
 }
 
 func TestDropTable(t *testing.T) {
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(context.Background())
 	sessionState.Driver = constants.MYSQL
 
 	c3 := &internal.Conv{
@@ -1487,7 +1502,7 @@ func TestDropTable(t *testing.T) {
 }
 
 func TestRestoreTable(t *testing.T) {
-	sessionState := session.GetSessionState()
+	sessionState := session.GetSessionState(context.Background())
 
 	sessionState.Driver = constants.MYSQL
 
@@ -1753,7 +1768,7 @@ func TestRemoveParentTable(t *testing.T) {
 	}
 
 	for _, tc := range tc {
-		sessionState := session.GetSessionState()
+		sessionState := session.GetSessionState(context.Background())
 		sessionState.Driver = constants.MYSQL
 
 		sessionState.Conv = tc.conv
@@ -1916,7 +1931,7 @@ func TestApplyRule(t *testing.T) {
 		},
 	}
 	for _, tc := range tcAddIndex {
-		sessionState := session.GetSessionState()
+		sessionState := session.GetSessionState(context.Background())
 
 		sessionState.Driver = constants.MYSQL
 		sessionState.Conv = tc.conv
@@ -2109,7 +2124,7 @@ func TestApplyRule(t *testing.T) {
 	}
 	for _, tc := range tcSetGlobalDataTypePostgres {
 
-		sessionState := session.GetSessionState()
+		sessionState := session.GetSessionState(context.Background())
 
 		sessionState.Driver = constants.POSTGRES
 		sessionState.Conv = internal.MakeConv()
@@ -2273,7 +2288,7 @@ func TestApplyRule(t *testing.T) {
 		},
 	}
 	for _, tc := range tcSetGlobalDataTypeMysql {
-		sessionState := session.GetSessionState()
+		sessionState := session.GetSessionState(context.Background())
 
 		sessionState.Driver = constants.MYSQL
 		sessionState.Conv = internal.MakeConv()
@@ -2505,7 +2520,7 @@ func TestDropRule(t *testing.T) {
 		},
 	}
 	for _, tc := range tc {
-		sessionState := session.GetSessionState()
+		sessionState := session.GetSessionState(context.Background())
 		sessionState.Driver = constants.MYSQL
 		sessionState.Conv = tc.conv
 		payload := `{}`
@@ -2706,3 +2721,167 @@ func buildConvPostgres(conv *internal.Conv) {
 	conv.SyntheticPKeys["t2"] = internal.SyntheticPKey{"c20", 0}
 	conv.Audit.MigrationType = migration.MigrationData_SCHEMA_AND_DATA.Enum()
 }
+
+func TestRoutesAuth(t *testing.T) {
+	a, err := auth.NewTokenAuthenticator("secret", "viewer-secret")
+	assert.Nil(t, err)
+	tests := []struct {
+		name          string
+		authenticator auth.Authenticator
+		method        string
+		path          string
+		token         string
+		statusCode    int
+	}{
+		{name: "no authentication", method: "GET", path: "/IsOffline", statusCode: http.StatusOK},
+		{name: "editor", authenticator: a, method: "GET", path: "/IsOffline", token: "secret", statusCode: http.StatusOK},
+		{name: "viewer", authenticator: a, method: "GET", path: "/IsOffline", token: "viewer-secret", statusCode: http.StatusOK},
+		{name: "viewer connect", authenticator: a, method: "POST", path: "/connect", token: "viewer-secret", statusCode: http.StatusForbidden},
+		{name: "viewer edit by GET", authenticator: a, method: "GET", path: "/setparent?table=t1", token: "viewer-secret", statusCode: http.StatusForbidden},
+		{name: "viewer migrate", authenticator: a, method: "POST", path: "/Migrate", token: "viewer-secret", statusCode: http.StatusForbidden},
+		{name: "unauthenticated", authenticator: a, method: "GET", path: "/IsOffline", statusCode: http.StatusUnauthorized},
+		{name: "editor jobs", authenticator: a, method: "GET", path: "/jobs", token: "secret", statusCode: http.StatusOK},
+		{name: "editor job events", authenticator: a, method: "GET", path: "/jobs/unknown/events", token: "secret", statusCode: http.StatusNotFound},
+		{name: "viewer cancel job", authenticator: a, method: "POST", path: "/jobs/unknown/cancel", token: "viewer-secret", statusCode: http.StatusForbidden},
+		{name: "unauthenticated jobs", authenticator: a, method: "GET", path: "/jobs", statusCode: http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rr := httptest.NewRecorder()
+		getRoutes(tc.authenticator).ServeHTTP(rr, req)
+		assert.Equal(t, tc.statusCode, rr.Code, tc.name)
+	}
+}
+
+func TestRoutesWhileMigrating(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	j := migrationJobs.Submit(jobs.Spec{
+		Description: "migrates",
+		SessionId:   "migrating",
+		Run: func(ctx context.Context, logf func(format string, a ...interface{})) error {
+			close(started)
+			<-release
+			return nil
+		},
+	})
+	<-started
+	defer func() {
+		close(release)
+		for i := 0; i < 500; i++ {
+			if _, ok := migrationJobs.Active("migrating"); !ok {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	tests := []struct {
+		name       string
+		sessionId  string
+		method     string
+		path       string
+		statusCode int
+	}{
+		{name: "undo", sessionId: "migrating", method: "POST", path: "/undo", statusCode: http.StatusConflict},
+		{name: "drop table", sessionId: "migrating", method: "POST", path: "/drop/table?table=t1", statusCode: http.StatusConflict},
+		{name: "edit by GET", sessionId: "migrating", method: "GET", path: "/setparent?table=t1", statusCode: http.StatusConflict},
+		{name: "load session", sessionId: "migrating", method: "POST", path: "/convert/session", statusCode: http.StatusConflict},
+		{name: "read", sessionId: "migrating", method: "GET", path: "/IsOffline", statusCode: http.StatusOK},
+		{name: "job", sessionId: "migrating", method: "GET", path: "/jobs/" + j.Id, statusCode: http.StatusOK},
+		{name: "other session", sessionId: "idle", method: "POST", path: "/undo", statusCode: http.StatusBadRequest},
+		{name: "job of other session", sessionId: "idle", method: "GET", path: "/jobs/" + j.Id, statusCode: http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set(session.SessionIdHeader, tc.sessionId)
+		rr := httptest.NewRecorder()
+		getRoutes(nil).ServeHTTP(rr, req)
+		assert.Equal(t, tc.statusCode, rr.Code, tc.name)
+	}
+}
+
+func TestMetricsRoute(t *testing.T) {
+	a, err := auth.NewTokenAuthenticator("secret", "viewer-secret")
+	assert.Nil(t, err)
+	// /metrics is only served once metrics are enabled.
+	rr := httptest.NewRecorder()
+	getRoutes(nil).ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	assert.NotContains(t, rr.Body.String(), "harbourbridge_rows_read_total")
+
+	telemetry.Enable()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer viewer-secret")
+	rr = httptest.NewRecorder()
+	getRoutes(a).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "harbourbridge_writes_in_flight")
+}
+
+func TestCorsHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		origins     []string
+		origin      string
+		allowOrigin string
+	}{
+		{name: "no origins configured", origin: "https://evil.example.com"},
+		{name: "allowed origin", origins: []string{"https://ui.example.com"}, origin: "https://ui.example.com", allowOrigin: "https://ui.example.com"},
+		{name: "other origin", origins: []string{"https://ui.example.com"}, origin: "https://evil.example.com"},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest("GET", "/IsOffline", nil)
+		req.Header.Set("Origin", tc.origin)
+		rr := httptest.NewRecorder()
+		corsHandler(getRoutes(nil), tc.origins).ServeHTTP(rr, req)
+		assert.Equal(t, tc.allowOrigin, rr.Header().Get("Access-Control-Allow-Origin"), tc.name)
+	}
+}
+
+func TestGetTypeSuggestionsSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.Exec(`
+CREATE TABLE doc (id INTEGER PRIMARY KEY, body TEXT, code TEXT);
+INSERT INTO doc VALUES (1, '{"a": 1}', 'ab'), (2, '[1, 2]', 'cd');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conv := internal.MakeConv()
+	if err := common.ProcessSchema(conv, sqlite.InfoSchemaImpl{DbName: "test", Db: db}, 1); err != nil {
+		t.Fatal(err)
+	}
+	sessionState := session.GetSessionState(context.Background())
+	sessionState.Driver = constants.SQLITE
+	sessionState.DbName = "test"
+	sessionState.SourceDB = db
+	sessionState.Conv = conv
+	defer func() { sessionState.SourceDB = nil }()
+
+	req, err := http.NewRequest("GET", "/typemap/suggestions?sampleRows=10", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(getTypeSuggestions).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var suggestions map[string]map[string]typeSuggestion
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &suggestions))
+
+	tableId, err := internal.GetTableIdFromSpName(conv.SpSchema, "doc")
+	assert.Nil(t, err)
+	bodyId, err := internal.GetColIdFromSpName(conv.SpSchema[tableId].ColDefs, "body")
+	assert.Nil(t, err)
+	codeId, err := internal.GetColIdFromSpName(conv.SpSchema[tableId].ColDefs, "code")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]map[string]typeSuggestion{
+		tableId: {
+			bodyId: {T: ddl.JSON, DisplayT: ddl.JSON, Reason: "all 2 sampled values are JSON objects or arrays"},
+			codeId: {T: ddl.String, Len: 2, DisplayT: "STRING(2)", Reason: "all 2 sampled values have 2 characters"},
+		},
+	}, suggestions)
+}